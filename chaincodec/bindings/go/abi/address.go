@@ -0,0 +1,75 @@
+package abi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+)
+
+// Address is a 20-byte account address.
+type Address [20]byte
+
+// ParseAddress parses a 0x-prefixed hex address. The checksum is not
+// enforced.
+func ParseAddress(s string) (Address, error) {
+	var a Address
+	b, err := decodeHex(s)
+	if err != nil {
+		return a, err
+	}
+	if len(b) != len(a) {
+		return a, fmt.Errorf("address %q is %d bytes, want 20", s, len(b))
+	}
+	copy(a[:], b)
+	return a, nil
+}
+
+// Hex returns the EIP-55 checksummed form.
+func (a Address) Hex() string {
+	lower := hex.EncodeToString(a[:])
+	hash := Keccak256([]byte(lower))
+	out := []byte(lower)
+	for i, c := range out {
+		nibble := hash[i/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+		if c >= 'a' && nibble&0xf >= 8 {
+			out[i] = c - 32
+		}
+	}
+	return "0x" + string(out)
+}
+
+// String returns the checksummed hex form.
+func (a Address) String() string { return a.Hex() }
+
+// MarshalText encodes the address as checksummed hex.
+func (a Address) MarshalText() ([]byte, error) { return []byte(a.Hex()), nil }
+
+// UnmarshalText parses a hex address.
+func (a *Address) UnmarshalText(b []byte) error {
+	p, err := ParseAddress(string(b))
+	if err != nil {
+		return err
+	}
+	*a = p
+	return nil
+}
+
+func toAddress(v interface{}) (Address, error) {
+	switch x := v.(type) {
+	case Address:
+		return x, nil
+	case *Address:
+		return *x, nil
+	case string:
+		return ParseAddress(x)
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Array && rv.Len() == 20 && rv.Type().Elem().Kind() == reflect.Uint8 {
+		var a Address
+		reflect.Copy(reflect.ValueOf(a[:]), rv)
+		return a, nil
+	}
+	return Address{}, fmt.Errorf("want address, got %T", v)
+}
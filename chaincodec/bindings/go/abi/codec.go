@@ -0,0 +1,353 @@
+package abi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+// Go representations used by Encode and Decode:
+//
+//	uintN, intN      *big.Int (Encode also takes Go integers and decimal or 0x strings)
+//	address          Address (Encode also takes [20]byte and 0x strings)
+//	bool             bool
+//	bytesN, bytes    []byte (Encode also takes 0x strings and byte arrays)
+//	string           string
+//	T[], T[k], tuple []interface{} (Encode also takes any slice or array)
+
+// Encode ABI-encodes values as a tuple of types.
+func Encode(types []Type, values []interface{}) ([]byte, error) {
+	if len(types) != len(values) {
+		return nil, fmt.Errorf("%d types, %d values", len(types), len(values))
+	}
+	return encodeTuple(types, values)
+}
+
+func encodeTuple(types []Type, values []interface{}) ([]byte, error) {
+	headLen := 0
+	for _, t := range types {
+		headLen += t.headSize()
+	}
+	var head, tail []byte
+	for i, t := range types {
+		enc, err := encodeValue(t, values[i])
+		if err != nil {
+			return nil, fmt.Errorf("arg %d (%s): %w", i, t, err)
+		}
+		if t.dynamic() {
+			head = append(head, word(big.NewInt(int64(headLen+len(tail))))...)
+			tail = append(tail, enc...)
+		} else {
+			head = append(head, enc...)
+		}
+	}
+	return append(head, tail...), nil
+}
+
+func encodeValue(t Type, v interface{}) ([]byte, error) {
+	switch t.Kind {
+	case KindUint, KindInt:
+		n, err := toBig(v)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkRange(t, n); err != nil {
+			return nil, err
+		}
+		return word(n), nil
+	case KindAddress:
+		a, err := toAddress(v)
+		if err != nil {
+			return nil, err
+		}
+		return padLeft(a[:]), nil
+	case KindBool:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("want bool, got %T", v)
+		}
+		if b {
+			return word(big.NewInt(1)), nil
+		}
+		return word(new(big.Int)), nil
+	case KindFixedBytes:
+		b, err := toBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != t.Size {
+			return nil, fmt.Errorf("want %d bytes, got %d", t.Size, len(b))
+		}
+		return padRight(b), nil
+	case KindBytes, KindString:
+		var b []byte
+		if t.Kind == KindString {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("want string, got %T", v)
+			}
+			b = []byte(s)
+		} else {
+			var err error
+			if b, err = toBytes(v); err != nil {
+				return nil, err
+			}
+		}
+		return append(word(big.NewInt(int64(len(b)))), padRight(b)...), nil
+	case KindSlice, KindArray:
+		elems, err := toList(v)
+		if err != nil {
+			return nil, err
+		}
+		if t.Kind == KindArray && len(elems) != t.Size {
+			return nil, fmt.Errorf("want %d elements, got %d", t.Size, len(elems))
+		}
+		types := make([]Type, len(elems))
+		for i := range types {
+			types[i] = *t.Elem
+		}
+		enc, err := encodeTuple(types, elems)
+		if err != nil {
+			return nil, err
+		}
+		if t.Kind == KindSlice {
+			enc = append(word(big.NewInt(int64(len(elems)))), enc...)
+		}
+		return enc, nil
+	case KindTuple:
+		elems, err := toList(v)
+		if err != nil {
+			return nil, err
+		}
+		if len(elems) != len(t.Components) {
+			return nil, fmt.Errorf("want %d tuple fields, got %d", len(t.Components), len(elems))
+		}
+		return encodeTuple(t.Components, elems)
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// Decode decodes data as a tuple of types.
+func Decode(types []Type, data []byte) ([]interface{}, error) {
+	return decodeTuple(types, data, 0)
+}
+
+func decodeTuple(types []Type, data []byte, base int) ([]interface{}, error) {
+	out := make([]interface{}, len(types))
+	off := base
+	for i, t := range types {
+		var (
+			v   interface{}
+			err error
+		)
+		if t.dynamic() {
+			var ptr int
+			if ptr, err = readInt(data, off); err == nil {
+				v, err = decodeValue(t, data, base+ptr)
+			}
+		} else {
+			v, err = decodeValue(t, data, off)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("value %d (%s): %w", i, t, err)
+		}
+		out[i] = v
+		off += t.headSize()
+	}
+	return out, nil
+}
+
+func decodeValue(t Type, data []byte, off int) (interface{}, error) {
+	switch t.Kind {
+	case KindUint, KindInt:
+		w, err := readWord(data, off)
+		if err != nil {
+			return nil, err
+		}
+		n := new(big.Int).SetBytes(w)
+		if t.Kind == KindInt && w[0]&0x80 != 0 {
+			n.Sub(n, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		if err := checkRange(t, n); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case KindAddress:
+		w, err := readWord(data, off)
+		if err != nil {
+			return nil, err
+		}
+		var a Address
+		copy(a[:], w[12:])
+		return a, nil
+	case KindBool:
+		w, err := readWord(data, off)
+		if err != nil {
+			return nil, err
+		}
+		return w[31] == 1, nil
+	case KindFixedBytes:
+		w, err := readWord(data, off)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), w[:t.Size]...), nil
+	case KindBytes, KindString:
+		n, err := readInt(data, off)
+		if err != nil {
+			return nil, err
+		}
+		if off+32+n > len(data) {
+			return nil, fmt.Errorf("length %d exceeds data", n)
+		}
+		b := append([]byte(nil), data[off+32:off+32+n]...)
+		if t.Kind == KindString {
+			return string(b), nil
+		}
+		return b, nil
+	case KindSlice, KindArray:
+		n, start := t.Size, off
+		if t.Kind == KindSlice {
+			var err error
+			if n, err = readInt(data, off); err != nil {
+				return nil, err
+			}
+			if n > len(data)/32 {
+				return nil, fmt.Errorf("length %d exceeds data", n)
+			}
+			start = off + 32
+		}
+		types := make([]Type, n)
+		for i := range types {
+			types[i] = *t.Elem
+		}
+		return decodeTuple(types, data, start)
+	case KindTuple:
+		return decodeTuple(t.Components, data, off)
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+func readWord(data []byte, off int) ([]byte, error) {
+	if off < 0 || off+32 > len(data) {
+		return nil, fmt.Errorf("offset %d out of range (%d bytes)", off, len(data))
+	}
+	return data[off : off+32], nil
+}
+
+func readInt(data []byte, off int) (int, error) {
+	w, err := readWord(data, off)
+	if err != nil {
+		return 0, err
+	}
+	n := new(big.Int).SetBytes(w)
+	if !n.IsInt64() || n.Int64() > int64(len(data)) {
+		return 0, fmt.Errorf("offset or length %s out of range", n)
+	}
+	return int(n.Int64()), nil
+}
+
+// word encodes n as a 32-byte two's complement big-endian word.
+func word(n *big.Int) []byte {
+	if n.Sign() < 0 {
+		n = new(big.Int).Add(n, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+	return padLeft(n.Bytes())
+}
+
+func padLeft(b []byte) []byte {
+	out := make([]byte, 32)
+	copy(out[32-len(b):], b)
+	return out
+}
+
+func padRight(b []byte) []byte {
+	n := (len(b) + 31) / 32 * 32
+	out := make([]byte, n)
+	copy(out, b)
+	return out
+}
+
+func checkRange(t Type, n *big.Int) error {
+	if t.Kind == KindUint {
+		if n.Sign() < 0 || n.BitLen() > t.Size {
+			return fmt.Errorf("%s out of range for %s", n, t)
+		}
+		return nil
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+	if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+		return fmt.Errorf("%s out of range for %s", n, t)
+	}
+	return nil
+}
+
+func toBig(v interface{}) (*big.Int, error) {
+	switch x := v.(type) {
+	case *big.Int:
+		if x == nil {
+			return nil, fmt.Errorf("nil *big.Int")
+		}
+		return x, nil
+	case big.Int:
+		return &x, nil
+	case string:
+		n, ok := new(big.Int).SetString(x, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %q", x)
+		}
+		return n, nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Int).SetUint64(rv.Uint()), nil
+	}
+	return nil, fmt.Errorf("want integer, got %T", v)
+}
+
+func toBytes(v interface{}) ([]byte, error) {
+	switch x := v.(type) {
+	case []byte:
+		return x, nil
+	case string:
+		return decodeHex(x)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Array && rv.Type().Elem().Kind() == reflect.Uint8 {
+		b := make([]byte, rv.Len())
+		reflect.Copy(reflect.ValueOf(b), rv)
+		return b, nil
+	}
+	return nil, fmt.Errorf("want bytes, got %T", v)
+}
+
+func toList(v interface{}) ([]interface{}, error) {
+	if l, ok := v.([]interface{}); ok {
+		return l, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("want slice, got %T", v)
+	}
+	out := make([]interface{}, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out, nil
+}
+
+func decodeHex(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		return nil, fmt.Errorf("hex string %q lacks 0x prefix", s)
+	}
+	s = s[2:]
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+	return hex.DecodeString(s)
+}
@@ -0,0 +1,122 @@
+package abi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Function is a contract function parsed from a human-readable signature.
+type Function struct {
+	Name    string
+	Inputs  []Type
+	Outputs []Type
+}
+
+// ParseFunction parses a function signature. Accepted forms:
+//
+//	balanceOf(address)
+//	balanceOf(address)(uint256)
+//	balanceOf(address) returns (uint256)
+//	function balanceOf(address owner) external view returns (uint256)
+//
+// Outputs are optional; without them DecodeOutput returns no values.
+func ParseFunction(sig string) (*Function, error) {
+	s := strings.TrimSpace(sig)
+	s = strings.TrimPrefix(s, "function ")
+	open := strings.Index(s, "(")
+	if open <= 0 {
+		return nil, fmt.Errorf("abi: invalid function signature %q", sig)
+	}
+	name := strings.TrimSpace(s[:open])
+	inputs, rest, err := takeParens(s[open:])
+	if err != nil {
+		return nil, fmt.Errorf("abi: %q: %w", sig, err)
+	}
+	f := &Function{Name: name}
+	if f.Inputs, err = ParseTypes(inputs); err != nil {
+		return nil, err
+	}
+	rest = strings.TrimSpace(rest)
+	if i := strings.Index(rest, "returns"); i >= 0 {
+		rest = strings.TrimSpace(rest[i+len("returns"):])
+	} else if !strings.HasPrefix(rest, "(") {
+		// Only modifiers ("external view") or nothing follow.
+		return f, nil
+	}
+	if rest == "" {
+		return f, nil
+	}
+	outputs, _, err := takeParens(rest)
+	if err != nil {
+		return nil, fmt.Errorf("abi: %q: %w", sig, err)
+	}
+	if f.Outputs, err = ParseTypes(outputs); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// takeParens returns the contents of the leading parenthesised group of s
+// and what follows it.
+func takeParens(s string) (string, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return "", "", fmt.Errorf("expected '('")
+	}
+	depth := 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s[1:i], s[i+1:], nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("unbalanced parentheses")
+}
+
+// Signature returns the canonical signature, e.g. "transfer(address,uint256)".
+func (f *Function) Signature() string {
+	return f.Name + Type{Kind: KindTuple, Components: f.Inputs}.String()
+}
+
+// Selector returns the first four bytes of the signature hash.
+func (f *Function) Selector() []byte {
+	return Keccak256([]byte(f.Signature()))[:4]
+}
+
+// EncodeCall returns the selector followed by the encoded arguments.
+func (f *Function) EncodeCall(args ...interface{}) ([]byte, error) {
+	if len(args) != len(f.Inputs) {
+		return nil, fmt.Errorf("abi: %s takes %d arguments, got %d", f.Signature(), len(f.Inputs), len(args))
+	}
+	data, err := Encode(f.Inputs, args)
+	if err != nil {
+		return nil, fmt.Errorf("abi: %s: %w", f.Name, err)
+	}
+	return append(f.Selector(), data...), nil
+}
+
+// DecodeOutput decodes return data into Go values, one per output.
+func (f *Function) DecodeOutput(data []byte) ([]interface{}, error) {
+	out, err := Decode(f.Outputs, data)
+	if err != nil {
+		return nil, fmt.Errorf("abi: %s: %w", f.Name, err)
+	}
+	return out, nil
+}
+
+// DecodeInput decodes calldata (selector followed by arguments) into Go
+// values, one per input. The selector must match f.
+func (f *Function) DecodeInput(calldata []byte) ([]interface{}, error) {
+	if len(calldata) < 4 || string(calldata[:4]) != string(f.Selector()) {
+		return nil, fmt.Errorf("abi: calldata is not a call to %s", f.Signature())
+	}
+	in, err := Decode(f.Inputs, calldata[4:])
+	if err != nil {
+		return nil, fmt.Errorf("abi: %s: %w", f.Name, err)
+	}
+	return in, nil
+}
@@ -0,0 +1,278 @@
+package abi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+)
+
+// Role is what a 32-byte word does in an ABI encoding.
+type Role string
+
+const (
+	// RoleSelector is the 4-byte function selector at the start of
+	// calldata.
+	RoleSelector Role = "selector"
+	// RoleHead is a static value in the head of a tuple.
+	RoleHead Role = "head"
+	// RoleOffset is a head word pointing to a dynamic value in the tail.
+	RoleOffset Role = "offset"
+	// RoleLength is the length prefix of bytes, string or a dynamic array.
+	RoleLength Role = "length"
+	// RoleElement is a static value inside an array.
+	RoleElement Role = "element"
+	// RoleData is a word of bytes or string content.
+	RoleData Role = "data"
+	// RoleUnused is a word no type refers to: trailing bytes, gaps between
+	// tail values, or data past the point where decoding failed.
+	RoleUnused Role = "unused"
+)
+
+// Word annotates one word of an ABI encoding.
+type Word struct {
+	// Offset is the byte offset of the word in the input, including the
+	// selector for calldata.
+	Offset int `json:"offset"`
+	// Raw is the word as 0x-hex. The last word may be shorter than 32 bytes.
+	Raw  string `json:"raw"`
+	Role Role   `json:"role"`
+	// Path locates the value, e.g. "1", "0.2" or "1[0].1"; arguments and
+	// tuple components are numbered from zero.
+	Path string `json:"path,omitempty"`
+	// Type is the ABI type of the value the word belongs to.
+	Type string `json:"type,omitempty"`
+	// Value is the word interpreted for its role: a number, address,
+	// offset target or byte count.
+	Value string `json:"value,omitempty"`
+	// Note explains a problem found at this word, if any.
+	Note string `json:"note,omitempty"`
+}
+
+// Layout annotates data, the ABI encoding of types, word by word. When the
+// data does not decode, Layout still returns every word it could place,
+// marks the rest RoleUnused and reports the first problem both as the
+// error and as the Note of the word where it occurred.
+func Layout(types []Type, data []byte) ([]Word, error) {
+	l := &layout{data: data, words: map[int]*Word{}}
+	l.tuple(types, 0, strconv.Itoa, RoleHead)
+	return l.result(0), l.err
+}
+
+// LayoutCalldata annotates calldata for a call to f: the selector followed
+// by the argument words, with offsets relative to the whole input.
+func (f *Function) LayoutCalldata(calldata []byte) ([]Word, error) {
+	if len(calldata) < 4 {
+		return []Word{{Raw: "0x" + hex.EncodeToString(calldata), Role: RoleUnused, Note: "shorter than a selector"}},
+			fmt.Errorf("abi: calldata is %d bytes", len(calldata))
+	}
+	sel := Word{Offset: 0, Raw: "0x" + hex.EncodeToString(calldata[:4]), Role: RoleSelector, Value: f.Signature()}
+	var selErr error
+	if string(calldata[:4]) != string(f.Selector()) {
+		sel.Note = fmt.Sprintf("selector of %s is 0x%x", f.Signature(), f.Selector())
+		selErr = fmt.Errorf("abi: calldata is not a call to %s", f.Signature())
+	}
+	words, err := Layout(f.Inputs, calldata[4:])
+	for i := range words {
+		words[i].Offset += 4
+	}
+	if selErr != nil {
+		err = selErr
+	}
+	return append([]Word{sel}, words...), err
+}
+
+type layout struct {
+	data  []byte
+	words map[int]*Word
+	err   error
+}
+
+// fail records the first problem, attaching it to the word at off.
+func (l *layout) fail(off int, format string, args ...interface{}) {
+	if l.err != nil {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	l.err = fmt.Errorf("abi: at byte %d: %s", off, msg)
+	if w, ok := l.words[off]; ok {
+		w.Note = msg
+	} else if off >= 0 && off < len(l.data) {
+		l.put(off, RoleUnused, "", "", "").Note = msg
+	}
+}
+
+// put annotates the word at off; it must be in range.
+func (l *layout) put(off int, role Role, path, typ, value string) *Word {
+	end := off + 32
+	if end > len(l.data) {
+		end = len(l.data)
+	}
+	w := &Word{Offset: off, Raw: "0x" + hex.EncodeToString(l.data[off:end]), Role: role, Path: path, Type: typ, Value: value}
+	l.words[off] = w
+	return w
+}
+
+func (l *layout) word(off int, what string) ([]byte, bool) {
+	if off < 0 || off+32 > len(l.data) {
+		l.fail(off, "%s runs past the end of the data (%d bytes)", what, len(l.data))
+		return nil, false
+	}
+	return l.data[off : off+32], true
+}
+
+func join(path, elem string) string {
+	if path == "" {
+		return elem
+	}
+	return path + "." + elem
+}
+
+// tuple lays out components whose head starts at base; name gives the
+// path of component i and role the role of its static words.
+func (l *layout) tuple(types []Type, base int, name func(int) string, role Role) {
+	off := base
+	for i, t := range types {
+		if l.err != nil {
+			return
+		}
+		p := name(i)
+		if t.dynamic() {
+			w, ok := l.word(off, "offset of "+p)
+			if !ok {
+				return
+			}
+			rel := new(big.Int).SetBytes(w)
+			l.put(off, RoleOffset, p, t.String(), rel.String())
+			if !rel.IsInt64() || base+int(rel.Int64()) >= len(l.data) {
+				l.fail(off, "offset %s of %s points past the end of the data", rel, p)
+				return
+			}
+			l.value(t, base+int(rel.Int64()), p, role)
+		} else {
+			l.value(t, off, p, role)
+		}
+		off += t.headSize()
+	}
+}
+
+// value lays out the encoding of one value of type t starting at off.
+func (l *layout) value(t Type, off int, path string, role Role) {
+	switch t.Kind {
+	case KindTuple:
+		l.tuple(t.Components, off, func(i int) string { return join(path, strconv.Itoa(i)) }, role)
+	case KindArray:
+		l.elements(t, t.Size, off, path)
+	case KindSlice:
+		w, ok := l.word(off, "length of "+path)
+		if !ok {
+			return
+		}
+		n := new(big.Int).SetBytes(w)
+		l.put(off, RoleLength, path, t.String(), n.String())
+		if !n.IsInt64() || n.Int64() > int64(len(l.data)/32) {
+			l.fail(off, "array length %s of %s exceeds the data", n, path)
+			return
+		}
+		l.elements(t, int(n.Int64()), off+32, path)
+	case KindBytes, KindString:
+		w, ok := l.word(off, "length of "+path)
+		if !ok {
+			return
+		}
+		n := new(big.Int).SetBytes(w)
+		l.put(off, RoleLength, path, t.String(), n.String())
+		if !n.IsInt64() || int64(off+32)+n.Int64() > int64(len(l.data)) {
+			l.fail(off, "%s length %s of %s exceeds the data", t, n, path)
+			return
+		}
+		for i := 0; i < int(n.Int64()); i += 32 {
+			l.put(off+32+i, RoleData, path, t.String(), "")
+		}
+	default:
+		w, ok := l.word(off, path)
+		if !ok {
+			return
+		}
+		l.put(off, role, path, t.String(), l.interpret(t, w, off, path))
+	}
+}
+
+// elements lays out n elements of array type t from off.
+func (l *layout) elements(t Type, n, off int, path string) {
+	types := make([]Type, n)
+	for i := range types {
+		types[i] = *t.Elem
+	}
+	l.tuple(types, off, func(i int) string { return fmt.Sprintf("%s[%d]", path, i) }, RoleElement)
+}
+
+// interpret renders a static word and checks it is canonical for t.
+func (l *layout) interpret(t Type, w []byte, off int, path string) string {
+	switch t.Kind {
+	case KindAddress:
+		for _, b := range w[:12] {
+			if b != 0 {
+				l.fail(off, "%s is not a valid address: high 12 bytes are not zero", path)
+				break
+			}
+		}
+		var a Address
+		copy(a[:], w[12:])
+		return a.Hex()
+	case KindBool:
+		if new(big.Int).SetBytes(w).Cmp(big.NewInt(1)) > 0 {
+			l.fail(off, "%s is not a valid bool", path)
+		}
+		return strconv.FormatBool(w[31] == 1)
+	case KindFixedBytes:
+		for _, b := range w[t.Size:] {
+			if b != 0 {
+				l.fail(off, "%s has non-zero padding after %d bytes", path, t.Size)
+				break
+			}
+		}
+		return "0x" + hex.EncodeToString(w[:t.Size])
+	case KindUint, KindInt:
+		n := new(big.Int).SetBytes(w)
+		if t.Kind == KindInt && w[0]&0x80 != 0 {
+			n.Sub(n, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		if err := checkRange(t, n); err != nil {
+			l.fail(off, "%s: %v", path, err)
+		}
+		return n.String()
+	}
+	return ""
+}
+
+// result returns the annotated words in offset order, filling gaps with
+// RoleUnused words aligned to base.
+func (l *layout) result(base int) []Word {
+	for off := base; off < len(l.data); off += 32 {
+		if covered(l.words, off) {
+			continue
+		}
+		w := l.put(off, RoleUnused, "", "", "")
+		if off+32 > len(l.data) {
+			w.Note = fmt.Sprintf("partial word of %d bytes", len(l.data)-off)
+		}
+	}
+	out := make([]Word, 0, len(l.words))
+	for _, w := range l.words {
+		out = append(out, *w)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Offset < out[j].Offset })
+	return out
+}
+
+// covered reports whether any annotated word overlaps [off, off+32).
+func covered(words map[int]*Word, off int) bool {
+	for o := range words {
+		if o < off+32 && off < o+32 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+// Package abi encodes and decodes Solidity ABI values in pure Go.
+//
+// It needs no native library, so packages such as chainrpc can build
+// calldata without linking chaincodec_ffi.
+package abi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kind is the category of an ABI type.
+type Kind int
+
+const (
+	KindUint Kind = iota
+	KindInt
+	KindAddress
+	KindBool
+	KindFixedBytes
+	KindBytes
+	KindString
+	KindSlice
+	KindArray
+	KindTuple
+)
+
+// Type is a parsed Solidity ABI type.
+type Type struct {
+	Kind Kind
+	// Size is the bit width for integers, the byte length for fixed bytes
+	// and the element count for fixed arrays.
+	Size int
+	// Elem is the element type of slices and arrays.
+	Elem *Type
+	// Components are the members of a tuple.
+	Components []Type
+}
+
+// ParseType parses a canonical type such as "uint256", "bytes32[]" or
+// "(address,uint256)[2]". "uint" and "int" are aliases for their 256-bit
+// forms.
+func ParseType(s string) (Type, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Type{}, fmt.Errorf("abi: empty type")
+	}
+	// Array suffixes bind last: T[2][] is a slice of T[2].
+	if strings.HasSuffix(s, "]") {
+		open := strings.LastIndex(s, "[")
+		if open < 0 {
+			return Type{}, fmt.Errorf("abi: invalid type %q", s)
+		}
+		elem, err := ParseType(s[:open])
+		if err != nil {
+			return Type{}, err
+		}
+		n := s[open+1 : len(s)-1]
+		if n == "" {
+			return Type{Kind: KindSlice, Elem: &elem}, nil
+		}
+		size, err := strconv.Atoi(n)
+		if err != nil || size <= 0 {
+			return Type{}, fmt.Errorf("abi: invalid array length in %q", s)
+		}
+		return Type{Kind: KindArray, Size: size, Elem: &elem}, nil
+	}
+	if strings.HasPrefix(s, "(") {
+		if !strings.HasSuffix(s, ")") {
+			return Type{}, fmt.Errorf("abi: invalid tuple %q", s)
+		}
+		comps, err := ParseTypes(s[1 : len(s)-1])
+		if err != nil {
+			return Type{}, err
+		}
+		return Type{Kind: KindTuple, Components: comps}, nil
+	}
+	switch {
+	case s == "address":
+		return Type{Kind: KindAddress, Size: 20}, nil
+	case s == "bool":
+		return Type{Kind: KindBool}, nil
+	case s == "string":
+		return Type{Kind: KindString}, nil
+	case s == "bytes":
+		return Type{Kind: KindBytes}, nil
+	case strings.HasPrefix(s, "bytes"):
+		n, err := strconv.Atoi(s[5:])
+		if err != nil || n < 1 || n > 32 {
+			return Type{}, fmt.Errorf("abi: invalid type %q", s)
+		}
+		return Type{Kind: KindFixedBytes, Size: n}, nil
+	case strings.HasPrefix(s, "uint"), strings.HasPrefix(s, "int"):
+		kind, digits := KindUint, strings.TrimPrefix(s, "uint")
+		if !strings.HasPrefix(s, "uint") {
+			kind, digits = KindInt, strings.TrimPrefix(s, "int")
+		}
+		if digits == "" {
+			return Type{Kind: kind, Size: 256}, nil
+		}
+		n, err := strconv.Atoi(digits)
+		if err != nil || n < 8 || n > 256 || n%8 != 0 {
+			return Type{}, fmt.Errorf("abi: invalid type %q", s)
+		}
+		return Type{Kind: kind, Size: n}, nil
+	}
+	return Type{}, fmt.Errorf("abi: unsupported type %q", s)
+}
+
+// ParseTypes parses a comma-separated type list, respecting nested tuples.
+// Parameter names after a type ("address owner") are ignored.
+func ParseTypes(s string) ([]Type, error) {
+	parts, err := splitTopLevel(s)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Type, 0, len(parts))
+	for _, p := range parts {
+		p = stripParamName(p)
+		t, err := ParseType(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+func splitTopLevel(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("abi: unbalanced parentheses in %q", s)
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("abi: unbalanced parentheses in %q", s)
+	}
+	return append(parts, strings.TrimSpace(s[start:])), nil
+}
+
+// stripParamName drops a trailing parameter name and data location, e.g.
+// "string memory name" → "string".
+func stripParamName(p string) string {
+	depth := 0
+	for i, r := range p {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ' ', '\t':
+			if depth == 0 {
+				return p[:i]
+			}
+		}
+	}
+	return p
+}
+
+// String returns the canonical type name used in signatures.
+func (t Type) String() string {
+	switch t.Kind {
+	case KindUint:
+		return "uint" + strconv.Itoa(t.Size)
+	case KindInt:
+		return "int" + strconv.Itoa(t.Size)
+	case KindAddress:
+		return "address"
+	case KindBool:
+		return "bool"
+	case KindFixedBytes:
+		return "bytes" + strconv.Itoa(t.Size)
+	case KindBytes:
+		return "bytes"
+	case KindString:
+		return "string"
+	case KindSlice:
+		return t.Elem.String() + "[]"
+	case KindArray:
+		return t.Elem.String() + "[" + strconv.Itoa(t.Size) + "]"
+	case KindTuple:
+		names := make([]string, len(t.Components))
+		for i, c := range t.Components {
+			names[i] = c.String()
+		}
+		return "(" + strings.Join(names, ",") + ")"
+	}
+	return "?"
+}
+
+// dynamic reports whether values of t are encoded out of place.
+func (t Type) dynamic() bool {
+	switch t.Kind {
+	case KindBytes, KindString, KindSlice:
+		return true
+	case KindArray:
+		return t.Elem.dynamic()
+	case KindTuple:
+		for _, c := range t.Components {
+			if c.dynamic() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// headSize is the number of bytes t occupies in the head of its enclosing
+// tuple.
+func (t Type) headSize() int {
+	if t.dynamic() {
+		return 32
+	}
+	switch t.Kind {
+	case KindArray:
+		return t.Size * t.Elem.headSize()
+	case KindTuple:
+		n := 0
+		for _, c := range t.Components {
+			n += c.headSize()
+		}
+		return n
+	}
+	return 32
+}
@@ -0,0 +1,262 @@
+package chaincodec
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// NamingPolicy controls how field names are rewritten on output.
+type NamingPolicy int
+
+const (
+	// NamingAsIs keeps field names as declared in the ABI/CSDL.
+	NamingAsIs NamingPolicy = iota
+	// NamingSnakeCase rewrites fields to snake_case (amountIn → amount_in).
+	NamingSnakeCase
+	// NamingCamelCase rewrites fields to lowerCamelCase (amount_in → amountIn).
+	NamingCamelCase
+)
+
+// String returns the policy name as used in alias config files.
+func (p NamingPolicy) String() string {
+	switch p {
+	case NamingSnakeCase:
+		return "snake_case"
+	case NamingCamelCase:
+		return "camelCase"
+	default:
+		return "as_is"
+	}
+}
+
+// ParseNamingPolicy parses "as_is", "snake_case" or "camelCase".
+func ParseNamingPolicy(s string) (NamingPolicy, error) {
+	switch s {
+	case "", "as_is":
+		return NamingAsIs, nil
+	case "snake_case":
+		return NamingSnakeCase, nil
+	case "camelCase", "camel_case":
+		return NamingCamelCase, nil
+	}
+	return NamingAsIs, fmt.Errorf("unknown naming policy %q", s)
+}
+
+// AliasConfig is the file form of an alias registry:
+//
+//	{
+//	  "policy": "snake_case",
+//	  "events": {"Swap": "PoolSwap"},
+//	  "fields": {"Swap": {"sqrtPriceX96": "price_sqrt"}, "*": {"src": "from"}}
+//	}
+//
+// Field aliases under "*" apply to every event. Explicit aliases are used
+// verbatim; the policy only applies to fields without one.
+type AliasConfig struct {
+	Policy string                       `json:"policy,omitempty"`
+	Events map[string]string            `json:"events,omitempty"`
+	Fields map[string]map[string]string `json:"fields,omitempty"`
+}
+
+// Aliases renames events and fields in decoded output without touching the
+// source schemas. Keep one per registry so different consumers can expose
+// different names for the same ABI. Aliases is safe for concurrent use.
+type Aliases struct {
+	mu     sync.RWMutex
+	policy NamingPolicy
+	events map[string]string
+	fields map[string]map[string]string
+}
+
+// NewAliases returns an empty alias set using policy for unaliased fields.
+func NewAliases(policy NamingPolicy) *Aliases {
+	return &Aliases{
+		policy: policy,
+		events: make(map[string]string),
+		fields: make(map[string]map[string]string),
+	}
+}
+
+// LoadAliases reads an AliasConfig JSON file.
+func LoadAliases(path string) (*Aliases, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg AliasConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("alias config %s: %w", path, err)
+	}
+	a, err := cfg.Aliases()
+	if err != nil {
+		return nil, fmt.Errorf("alias config %s: %w", path, err)
+	}
+	return a, nil
+}
+
+// Aliases builds an alias set from the config.
+func (c AliasConfig) Aliases() (*Aliases, error) {
+	policy, err := ParseNamingPolicy(c.Policy)
+	if err != nil {
+		return nil, err
+	}
+	a := NewAliases(policy)
+	for ev, alias := range c.Events {
+		a.RenameEvent(ev, alias)
+	}
+	for ev, m := range c.Fields {
+		for field, alias := range m {
+			a.RenameField(ev, field, alias)
+		}
+	}
+	return a, nil
+}
+
+// RenameEvent exposes schema event as alias.
+func (a *Aliases) RenameEvent(event, alias string) {
+	a.mu.Lock()
+	a.events[event] = alias
+	a.mu.Unlock()
+}
+
+// RenameField exposes field of event as alias. Use event "*" for a rename
+// that applies to every event; per-event renames win.
+func (a *Aliases) RenameField(event, field, alias string) {
+	a.mu.Lock()
+	m := a.fields[event]
+	if m == nil {
+		m = make(map[string]string)
+		a.fields[event] = m
+	}
+	m[field] = alias
+	a.mu.Unlock()
+}
+
+// EventName returns the output name of event.
+func (a *Aliases) EventName(event string) string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if alias, ok := a.events[event]; ok {
+		return alias
+	}
+	return event
+}
+
+// FieldName returns the output name of field within event.
+func (a *Aliases) FieldName(event, field string) string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.fieldName(event, field)
+}
+
+func (a *Aliases) fieldName(event, field string) string {
+	if alias, ok := a.fields[event][field]; ok {
+		return alias
+	}
+	if alias, ok := a.fields["*"][field]; ok {
+		return alias
+	}
+	switch a.policy {
+	case NamingSnakeCase:
+		return toSnake(field)
+	case NamingCamelCase:
+		return toCamel(field)
+	}
+	return field
+}
+
+// Apply renames the schema and the "fields"/"computed" keys of a decoded
+// event JSON. Lookups use the source schema name, so the original name is
+// kept under "source_schema" when the event is renamed.
+func (a *Aliases) Apply(decodedJSON string) (string, error) {
+	if a == nil {
+		return decodedJSON, nil
+	}
+	ev, err := decodeJSONObject(decodedJSON)
+	if err != nil {
+		return "", fmt.Errorf("decoded event: %w", err)
+	}
+	name, _ := ev["schema"].(string)
+
+	a.mu.RLock()
+	for _, key := range []string{"fields", "computed"} {
+		m, ok := ev[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		renamed := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			out := a.fieldName(name, k)
+			if _, dup := renamed[out]; dup {
+				a.mu.RUnlock()
+				return "", fmt.Errorf("schema %s: fields collide on alias %q", name, out)
+			}
+			renamed[out] = v
+		}
+		ev[key] = renamed
+	}
+	alias, renamedEvent := a.events[name]
+	a.mu.RUnlock()
+
+	if renamedEvent && name != "" {
+		ev["schema"] = alias
+		ev["source_schema"] = name
+	}
+	out, err := json.Marshal(ev)
+	return string(out), err
+}
+
+// DecodeEventWithAliases decodes like DecodeLog and renames the result.
+func DecodeEventWithAliases(logJSON, schemaJSON string, a *Aliases) (string, error) {
+	decoded, err := decodeLogJSON(logJSON, schemaJSON)
+	if err != nil {
+		return "", err
+	}
+	return a.Apply(decoded)
+}
+
+// toSnake converts camelCase/PascalCase to snake_case, keeping acronyms
+// together (tokenID → token_id, sqrtPriceX96 → sqrt_price_x96).
+func toSnake(s string) string {
+	rs := []rune(s)
+	var b strings.Builder
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && (unicode.IsLower(rs[i-1]) || unicode.IsDigit(rs[i-1]))
+			nextLower := i > 0 && i+1 < len(rs) && unicode.IsUpper(rs[i-1]) && unicode.IsLower(rs[i+1])
+			if (prevLower || nextLower) && b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// toCamel converts snake_case to lowerCamelCase. Leading underscores are
+// kept so private-looking names stay distinguishable.
+func toCamel(s string) string {
+	lead := len(s) - len(strings.TrimLeft(s, "_"))
+	parts := strings.Split(s[lead:], "_")
+	var b strings.Builder
+	b.WriteString(s[:lead])
+	for i, p := range parts {
+		if p == "" {
+			continue
+		}
+		if i == 0 || b.Len() == lead {
+			b.WriteString(p)
+			continue
+		}
+		rs := []rune(p)
+		rs[0] = unicode.ToUpper(rs[0])
+		b.WriteString(string(rs))
+	}
+	return b.String()
+}
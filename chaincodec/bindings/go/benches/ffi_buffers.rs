@@ -0,0 +1,68 @@
+//! Allocation cost of results crossing the FFI boundary.
+//!
+//! Compares `chaincodec_decode_event`, which allocates a C string per call
+//! that the caller must free, with `chaincodec_decode_event_into`, which
+//! serializes into a reused per-thread arena and copies into a buffer the
+//! caller keeps — the path the Go bindings take. `BenchmarkDecodeEvent`
+//! measures the same comparison from Go, including allocations on the Go
+//! side.
+//!
+//! # Running
+//! ```bash
+//! cd chaincodec/bindings/go
+//! cargo bench --bench ffi_buffers
+//! go test -run '^$' -bench DecodeEvent .
+//! ```
+
+use std::ffi::CString;
+use std::os::raw::c_char;
+
+use chaincodec_ffi::{chaincodec_decode_event, chaincodec_decode_event_into, chaincodec_free_string};
+use criterion::{criterion_group, criterion_main, BenchmarkId, Criterion, Throughput};
+
+const SCHEMA: &str = r#"{"name":"ERC20Transfer","event":"Transfer"}"#;
+
+/// A log with `data_words` 32-byte words of data, to vary result size.
+fn log_json(data_words: usize) -> CString {
+    let data = "ab".repeat(32 * data_words);
+    CString::new(format!(
+        r#"{{"address":"0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48","topics":["0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef","0x000000000000000000000000d8da6bf26964af9d7eed9e03e53415d37aa96045","0x000000000000000000000000ab5801a7d398351b8be11c439e05c5b3259aec9b"],"data":"0x{data}"}}"#
+    ))
+    .unwrap()
+}
+
+fn bench_decode(c: &mut Criterion) {
+    let schema = CString::new(SCHEMA).unwrap();
+    let mut group = c.benchmark_group("decode_event");
+
+    for words in [1usize, 16, 256] {
+        let log = log_json(words);
+        group.throughput(Throughput::Elements(1));
+
+        group.bench_with_input(BenchmarkId::new("alloc_string", words), &log, |b, log| {
+            b.iter(|| unsafe {
+                let p = chaincodec_decode_event(log.as_ptr(), schema.as_ptr());
+                assert!(!p.is_null());
+                chaincodec_free_string(p);
+            })
+        });
+
+        let mut out = vec![0u8; 64 * 1024];
+        group.bench_with_input(BenchmarkId::new("into_buffer", words), &log, |b, log| {
+            b.iter(|| {
+                let n = chaincodec_decode_event_into(
+                    log.as_ptr(),
+                    schema.as_ptr(),
+                    out.as_mut_ptr() as *mut c_char,
+                    out.len(),
+                );
+                assert!(n > 0 && (n as usize) <= out.len());
+            })
+        });
+    }
+
+    group.finish();
+}
+
+criterion_group!(benches, bench_decode);
+criterion_main!(benches);
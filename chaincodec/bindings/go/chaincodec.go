@@ -18,6 +18,7 @@ package chaincodec
 import "C"
 import (
 	"errors"
+	"runtime"
 	"unsafe"
 )
 
@@ -65,15 +66,15 @@ func CountSchemas(dirPath string) (int, error) {
 // logJSON is a JSON object: {"address":"0x...","topics":["0x..."],"data":"0x..."}
 // schemaJSON is a schema JSON string (from LoadSchema).
 func DecodeEvent(logJSON, schemaJSON string) (string, error) {
-	cLog := C.CString(logJSON)
-	defer C.free(unsafe.Pointer(cLog))
-	cSchema := C.CString(schemaJSON)
-	defer C.free(unsafe.Pointer(cSchema))
-
-	ptr := C.chaincodec_decode_event(cLog, cSchema)
-	if ptr == nil {
-		return "", lastError()
-	}
-	defer C.chaincodec_free_string(ptr)
-	return C.GoString(ptr), nil
+	// The result may be left in a thread-local native buffer for
+	// chaincodec_take_result, so stay on this thread until it is read.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	a := getArena()
+	defer a.release()
+	args := a.cstrings(logJSON, schemaJSON)
+	n := C.chaincodec_decode_event_into(args[0], args[1], a.outPtr(), a.outCap())
+	return a.result(int64(n), func(out *C.char, n C.size_t) int64 {
+		return int64(C.chaincodec_take_result(out, n))
+	})
 }
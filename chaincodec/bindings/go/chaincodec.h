@@ -1,4 +1,5 @@
 #pragma once
+#include <stddef.h>
 #include <stdint.h>
 
 #ifdef __cplusplus
@@ -42,6 +43,22 @@ int chaincodec_count_schemas(const char* dir_path);
  */
 char* chaincodec_decode_event(const char* log_json, const char* schema_json);
 
+/**
+ * Like chaincodec_decode_event, writing the decoded JSON into out instead
+ * of allocating: nothing to free. The result is not NUL-terminated.
+ * Returns its length, or -1 on error. A length above out_cap means nothing
+ * was written; fetch the result with chaincodec_take_result on the same
+ * thread.
+ */
+int64_t chaincodec_decode_event_into(const char* log_json, const char* schema_json,
+                                     char* out, size_t out_cap);
+
+/**
+ * Copy the result held by the last *_into call on this thread into out.
+ * Returns its length, or -1 if out_cap is too small.
+ */
+int64_t chaincodec_take_result(char* out, size_t out_cap);
+
 #ifdef __cplusplus
 }
 #endif
@@ -0,0 +1,151 @@
+package chaincodec
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Kinds of FieldDiff.
+const (
+	// FieldAdded is a field only the new schema decodes.
+	FieldAdded = "added"
+	// FieldRemoved is a field only the old schema decodes.
+	FieldRemoved = "removed"
+	// FieldChanged is a field both decode, to different values.
+	FieldChanged = "changed"
+)
+
+// FieldDiff is one difference between two decodings of a log. Field is a
+// path into Fields: nested tuple members are joined with ".", array
+// elements indexed as "[i]", e.g. "order.items[2].amount".
+type FieldDiff struct {
+	Field string      `json:"field"`
+	Kind  string      `json:"kind"`
+	Old   interface{} `json:"old,omitempty"`
+	New   interface{} `json:"new,omitempty"`
+}
+
+// DecodeDiff is a log decoded with an old and a new schema, and how the two
+// interpretations differ.
+type DecodeDiff struct {
+	// Old and New are the decodings; nil where that schema failed, with
+	// the reason in OldError or NewError.
+	Old      *DecodedEvent `json:"old,omitempty"`
+	New      *DecodedEvent `json:"new,omitempty"`
+	OldError string        `json:"old_error,omitempty"`
+	NewError string        `json:"new_error,omitempty"`
+	// Diffs lists the differing fields in path order. Integers are compared
+	// by value and hex strings case-insensitively, so a representation
+	// change alone (uint256 to int256 of a small value, checksummed
+	// addresses) is not a difference.
+	Diffs []FieldDiff `json:"diffs"`
+}
+
+// Equal reports whether both schemas decoded the log to the same fields.
+func (d *DecodeDiff) Equal() bool {
+	return d.OldError == "" && d.NewError == "" && len(d.Diffs) == 0
+}
+
+// DecodeWithBoth decodes logJSON with oldSchemaJSON and newSchemaJSON and
+// diffs the results field by field, to check a schema migration against
+// live traffic before switching a pipeline to it. A schema that fails to
+// decode the log is reported in the DecodeDiff; an error is returned only
+// if both fail.
+func DecodeWithBoth(oldSchemaJSON, newSchemaJSON, logJSON string) (*DecodeDiff, error) {
+	d := &DecodeDiff{Diffs: []FieldDiff{}}
+	old, oldErr := DecodeLog(logJSON, oldSchemaJSON)
+	cur, newErr := DecodeLog(logJSON, newSchemaJSON)
+	if oldErr != nil && newErr != nil {
+		return nil, fmt.Errorf("chaincodec: neither schema decodes the log: old: %v; new: %w", oldErr, newErr)
+	}
+	if oldErr != nil {
+		d.OldError = oldErr.Error()
+	}
+	if newErr != nil {
+		d.NewError = newErr.Error()
+	}
+	d.Old, d.New = old, cur
+	if old == nil || cur == nil {
+		return d, nil
+	}
+	diffValues(&d.Diffs, "", old.Fields, cur.Fields)
+	sort.Slice(d.Diffs, func(i, j int) bool { return d.Diffs[i].Field < d.Diffs[j].Field })
+	return d, nil
+}
+
+// diffValues appends the differences between old and new at path.
+func diffValues(out *[]FieldDiff, path string, old, cur interface{}) {
+	switch o := old.(type) {
+	case map[string]interface{}:
+		if c, ok := cur.(map[string]interface{}); ok {
+			for k, ov := range o {
+				p := joinPath(path, k)
+				if cv, ok := c[k]; ok {
+					diffValues(out, p, ov, cv)
+				} else {
+					*out = append(*out, FieldDiff{Field: p, Kind: FieldRemoved, Old: ov})
+				}
+			}
+			for k, cv := range c {
+				if _, ok := o[k]; !ok {
+					*out = append(*out, FieldDiff{Field: joinPath(path, k), Kind: FieldAdded, New: cv})
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if c, ok := cur.([]interface{}); ok && len(c) == len(o) {
+			for i := range o {
+				diffValues(out, fmt.Sprintf("%s[%d]", path, i), o[i], c[i])
+			}
+			return
+		}
+	}
+	if !sameValue(old, cur) {
+		*out = append(*out, FieldDiff{Field: path, Kind: FieldChanged, Old: old, New: cur})
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// sameValue compares two leaf values, integers by value and hex strings
+// without regard to case.
+func sameValue(a, b interface{}) bool {
+	if x, ok := intValue(a); ok {
+		y, ok := intValue(b)
+		return ok && x.Cmp(y) == 0
+	}
+	if x, ok := a.(string); ok {
+		y, ok := b.(string)
+		if ok && strings.HasPrefix(x, "0x") && strings.HasPrefix(y, "0x") {
+			return strings.EqualFold(x, y)
+		}
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// intValue reads a JSON number or a decimal string as an integer. Hex
+// strings are left to the string comparison: they are as likely to be
+// bytes as numbers.
+func intValue(v interface{}) (*big.Int, bool) {
+	var s string
+	switch x := v.(type) {
+	case json.Number:
+		s = string(x)
+	case string:
+		s = x
+	default:
+		return nil, false
+	}
+	return new(big.Int).SetString(s, 10)
+}
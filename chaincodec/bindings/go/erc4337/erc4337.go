@@ -0,0 +1,423 @@
+// Package erc4337 decodes ERC-4337 account abstraction bundles: EntryPoint
+// handleOps calldata is split into its UserOperations, each with its
+// factory, paymaster and gas fields unpacked and its callData decoded
+// through the common smart-account execute functions.
+//
+// Like package abi it is pure Go and needs no native library.
+package erc4337
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/DarshanKumar89/chainfoundry/chaincodec/abi"
+)
+
+// EntryPointVersion identifies the EntryPoint ABI a bundle was built for.
+type EntryPointVersion string
+
+const (
+	// EntryPointV06 takes UserOperation structs with unpacked gas fields.
+	EntryPointV06 EntryPointVersion = "v0.6"
+	// EntryPointV07 takes PackedUserOperation structs.
+	EntryPointV07 EntryPointVersion = "v0.7"
+)
+
+// ErrNotHandleOps is returned for calldata that is not an EntryPoint
+// handleOps call.
+var ErrNotHandleOps = errors.New("erc4337: calldata is not a handleOps call")
+
+// maxDepth bounds recursive callData decoding.
+const maxDepth = 4
+
+var (
+	handleOpsV06 = mustFunction("handleOps((address,uint256,bytes,bytes,uint256,uint256,uint256,uint256,uint256,bytes,bytes)[],address)")
+	handleOpsV07 = mustFunction("handleOps((address,uint256,bytes,bytes,bytes32,uint256,bytes32,bytes,bytes)[],address)")
+
+	// Account execution functions whose arguments are (target, value, data)
+	// or batches of them.
+	execute          = mustFunction("execute(address,uint256,bytes)")
+	executeCall      = mustFunction("executeCall(address,uint256,bytes)")
+	executeUserOp    = mustFunction("executeUserOp(address,uint256,bytes,uint8)")
+	executeBatch     = mustFunction("executeBatch(address[],bytes[])")
+	executeBatchVals = mustFunction("executeBatch(address[],uint256[],bytes[])")
+	executeBatchTup  = mustFunction("executeBatch((address,uint256,bytes)[])")
+	execute7579      = mustFunction("execute(bytes32,bytes)")
+	multicall        = mustFunction("multicall(bytes[])")
+)
+
+func mustFunction(sig string) *abi.Function {
+	f, err := abi.ParseFunction(sig)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Paymaster is the unpacked paymasterAndData field.
+type Paymaster struct {
+	Address abi.Address `json:"address"`
+	// VerificationGasLimit and PostOpGasLimit are only packed into
+	// paymasterAndData from EntryPoint v0.7 on; nil for v0.6.
+	VerificationGasLimit *big.Int `json:"verificationGasLimit,omitempty"`
+	PostOpGasLimit       *big.Int `json:"postOpGasLimit,omitempty"`
+	Data                 []byte   `json:"data"`
+}
+
+// Call is one contract call made by a smart account.
+type Call struct {
+	To    abi.Address `json:"to"`
+	Value *big.Int    `json:"value"`
+	Data  []byte      `json:"data"`
+	// Function and Args are set when Data matches a function registered
+	// with the Decoder.
+	Function string        `json:"function,omitempty"`
+	Args     []interface{} `json:"args,omitempty"`
+	// Calls holds the inner calls when Data is itself a batch, such as a
+	// multicall or a nested account execution.
+	Calls []Call `json:"calls,omitempty"`
+}
+
+// UserOperation is one decoded user operation. Gas fields are normalised
+// across EntryPoint versions.
+type UserOperation struct {
+	Sender               abi.Address  `json:"sender"`
+	Nonce                *big.Int     `json:"nonce"`
+	Factory              *abi.Address `json:"factory,omitempty"`
+	FactoryData          []byte       `json:"factoryData,omitempty"`
+	CallData             []byte       `json:"callData"`
+	CallGasLimit         *big.Int     `json:"callGasLimit"`
+	VerificationGasLimit *big.Int     `json:"verificationGasLimit"`
+	PreVerificationGas   *big.Int     `json:"preVerificationGas"`
+	MaxFeePerGas         *big.Int     `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *big.Int     `json:"maxPriorityFeePerGas"`
+	Paymaster            *Paymaster   `json:"paymaster,omitempty"`
+	Signature            []byte       `json:"signature"`
+	// Calls is CallData decoded as the account's execute function(s); nil
+	// when the account uses an unrecognised entry point.
+	Calls []Call `json:"calls,omitempty"`
+	// Execution is the signature of the account function CallData invokes,
+	// when recognised.
+	Execution string `json:"execution,omitempty"`
+}
+
+// Bundle is a decoded handleOps call.
+type Bundle struct {
+	EntryPoint  EntryPointVersion `json:"entryPoint"`
+	Ops         []UserOperation   `json:"ops"`
+	Beneficiary abi.Address       `json:"beneficiary"`
+}
+
+// Decoder decodes bundles, recognising the inner calls of registered
+// functions. The zero value decodes structure only.
+type Decoder struct {
+	functions map[string]*abi.Function
+}
+
+// NewDecoder returns a Decoder that decodes inner call data matching any of
+// signatures, e.g. "transfer(address,uint256)".
+func NewDecoder(signatures ...string) (*Decoder, error) {
+	d := &Decoder{}
+	for _, sig := range signatures {
+		if err := d.Register(sig); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Register adds a function used to decode inner call data.
+func (d *Decoder) Register(signature string) error {
+	f, err := abi.ParseFunction(signature)
+	if err != nil {
+		return err
+	}
+	if d.functions == nil {
+		d.functions = make(map[string]*abi.Function)
+	}
+	d.functions[string(f.Selector())] = f
+	return nil
+}
+
+// DecodeHandleOps decodes EntryPoint handleOps calldata with no registered
+// inner functions.
+func DecodeHandleOps(calldata []byte) (*Bundle, error) {
+	return (&Decoder{}).DecodeHandleOps(calldata)
+}
+
+// DecodeHandleOps decodes EntryPoint v0.6 or v0.7 handleOps calldata.
+func (d *Decoder) DecodeHandleOps(calldata []byte) (*Bundle, error) {
+	if len(calldata) < 4 {
+		return nil, ErrNotHandleOps
+	}
+	var (
+		version EntryPointVersion
+		fn      *abi.Function
+	)
+	switch string(calldata[:4]) {
+	case string(handleOpsV06.Selector()):
+		version, fn = EntryPointV06, handleOpsV06
+	case string(handleOpsV07.Selector()):
+		version, fn = EntryPointV07, handleOpsV07
+	default:
+		return nil, ErrNotHandleOps
+	}
+	args, err := fn.DecodeInput(calldata)
+	if err != nil {
+		return nil, fmt.Errorf("erc4337: %w", err)
+	}
+	b := &Bundle{EntryPoint: version, Beneficiary: args[1].(abi.Address)}
+	for i, raw := range args[0].([]interface{}) {
+		f := raw.([]interface{})
+		var op UserOperation
+		if version == EntryPointV06 {
+			op, err = unpackV06(f)
+		} else {
+			op, err = unpackV07(f)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erc4337: op %d: %w", i, err)
+		}
+		op.Execution, op.Calls = d.decodeExecution(op.CallData, 0)
+		b.Ops = append(b.Ops, op)
+	}
+	return b, nil
+}
+
+func unpackV06(f []interface{}) (UserOperation, error) {
+	op := UserOperation{
+		Sender:               f[0].(abi.Address),
+		Nonce:                f[1].(*big.Int),
+		CallData:             f[3].([]byte),
+		CallGasLimit:         f[4].(*big.Int),
+		VerificationGasLimit: f[5].(*big.Int),
+		PreVerificationGas:   f[6].(*big.Int),
+		MaxFeePerGas:         f[7].(*big.Int),
+		MaxPriorityFeePerGas: f[8].(*big.Int),
+		Signature:            f[10].([]byte),
+	}
+	if err := op.setFactory(f[2].([]byte)); err != nil {
+		return op, err
+	}
+	if pm := f[9].([]byte); len(pm) > 0 {
+		if len(pm) < 20 {
+			return op, fmt.Errorf("paymasterAndData is %d bytes", len(pm))
+		}
+		op.Paymaster = &Paymaster{Address: toAddress(pm), Data: pm[20:]}
+	}
+	return op, nil
+}
+
+func unpackV07(f []interface{}) (UserOperation, error) {
+	gasLimits, gasFees := f[4].([]byte), f[6].([]byte)
+	op := UserOperation{
+		Sender:               f[0].(abi.Address),
+		Nonce:                f[1].(*big.Int),
+		CallData:             f[3].([]byte),
+		VerificationGasLimit: new(big.Int).SetBytes(gasLimits[:16]),
+		CallGasLimit:         new(big.Int).SetBytes(gasLimits[16:]),
+		PreVerificationGas:   f[5].(*big.Int),
+		MaxPriorityFeePerGas: new(big.Int).SetBytes(gasFees[:16]),
+		MaxFeePerGas:         new(big.Int).SetBytes(gasFees[16:]),
+		Signature:            f[8].([]byte),
+	}
+	if err := op.setFactory(f[2].([]byte)); err != nil {
+		return op, err
+	}
+	if pm := f[7].([]byte); len(pm) > 0 {
+		if len(pm) < 52 {
+			return op, fmt.Errorf("paymasterAndData is %d bytes, want at least 52", len(pm))
+		}
+		op.Paymaster = &Paymaster{
+			Address:              toAddress(pm),
+			VerificationGasLimit: new(big.Int).SetBytes(pm[20:36]),
+			PostOpGasLimit:       new(big.Int).SetBytes(pm[36:52]),
+			Data:                 pm[52:],
+		}
+	}
+	return op, nil
+}
+
+// setFactory unpacks initCode: the factory address followed by its calldata.
+func (op *UserOperation) setFactory(initCode []byte) error {
+	if len(initCode) == 0 {
+		return nil
+	}
+	if len(initCode) < 20 {
+		return fmt.Errorf("initCode is %d bytes", len(initCode))
+	}
+	a := toAddress(initCode)
+	op.Factory = &a
+	op.FactoryData = initCode[20:]
+	return nil
+}
+
+func toAddress(b []byte) abi.Address {
+	var a abi.Address
+	copy(a[:], b[:20])
+	return a
+}
+
+// decodeExecution recognises data as a smart-account execution and returns
+// the function signature and the calls it makes.
+func (d *Decoder) decodeExecution(data []byte, depth int) (string, []Call) {
+	if len(data) < 4 || depth >= maxDepth {
+		return "", nil
+	}
+	sel := string(data[:4])
+	var calls []Call
+	var fn *abi.Function
+	switch sel {
+	case string(execute.Selector()), string(executeCall.Selector()), string(executeUserOp.Selector()):
+		fn = map[string]*abi.Function{
+			string(execute.Selector()):       execute,
+			string(executeCall.Selector()):   executeCall,
+			string(executeUserOp.Selector()): executeUserOp,
+		}[sel]
+		args, err := fn.DecodeInput(data)
+		if err != nil {
+			return "", nil
+		}
+		calls = []Call{{To: args[0].(abi.Address), Value: args[1].(*big.Int), Data: args[2].([]byte)}}
+	case string(executeBatch.Selector()):
+		fn = executeBatch
+		args, err := fn.DecodeInput(data)
+		if err != nil {
+			return "", nil
+		}
+		targets, datas := args[0].([]interface{}), args[1].([]interface{})
+		if len(datas) != 0 && len(datas) != len(targets) {
+			return "", nil
+		}
+		for i, t := range targets {
+			c := Call{To: t.(abi.Address), Value: new(big.Int)}
+			if len(datas) > 0 {
+				c.Data = datas[i].([]byte)
+			}
+			calls = append(calls, c)
+		}
+	case string(executeBatchVals.Selector()):
+		fn = executeBatchVals
+		args, err := fn.DecodeInput(data)
+		if err != nil {
+			return "", nil
+		}
+		targets, values, datas := args[0].([]interface{}), args[1].([]interface{}), args[2].([]interface{})
+		if len(datas) != len(targets) || len(values) != 0 && len(values) != len(targets) {
+			return "", nil
+		}
+		for i, t := range targets {
+			c := Call{To: t.(abi.Address), Value: new(big.Int), Data: datas[i].([]byte)}
+			if len(values) > 0 {
+				c.Value = values[i].(*big.Int)
+			}
+			calls = append(calls, c)
+		}
+	case string(executeBatchTup.Selector()):
+		fn = executeBatchTup
+		args, err := fn.DecodeInput(data)
+		if err != nil {
+			return "", nil
+		}
+		calls = tupleCalls(args[0].([]interface{}))
+	case string(execute7579.Selector()):
+		fn = execute7579
+		args, err := fn.DecodeInput(data)
+		if err != nil {
+			return "", nil
+		}
+		var ok bool
+		if calls, ok = decode7579(args[0].([]byte), args[1].([]byte)); !ok {
+			return "", nil
+		}
+	default:
+		return "", nil
+	}
+	for i := range calls {
+		d.decodeCall(&calls[i], depth+1)
+	}
+	return fn.Signature(), calls
+}
+
+// decode7579 decodes an ERC-7579 execute(mode, executionCalldata). Call
+// type 0x00 packs a single (target, value, data); 0x01 ABI-encodes a batch.
+// Delegatecalls and unknown call types are not decoded.
+func decode7579(mode, exec []byte) ([]Call, bool) {
+	switch mode[0] {
+	case 0x00:
+		if len(exec) < 52 {
+			return nil, false
+		}
+		return []Call{{To: toAddress(exec), Value: new(big.Int).SetBytes(exec[20:52]), Data: exec[52:]}}, true
+	case 0x01:
+		t, _ := abi.ParseType("(address,uint256,bytes)[]")
+		out, err := abi.Decode([]abi.Type{t}, exec)
+		if err != nil {
+			return nil, false
+		}
+		return tupleCalls(out[0].([]interface{})), true
+	}
+	return nil, false
+}
+
+func tupleCalls(items []interface{}) []Call {
+	calls := make([]Call, len(items))
+	for i, it := range items {
+		t := it.([]interface{})
+		calls[i] = Call{To: t[0].(abi.Address), Value: t[1].(*big.Int), Data: t[2].([]byte)}
+	}
+	return calls
+}
+
+// decodeCall fills in c's function, arguments and nested calls.
+func (d *Decoder) decodeCall(c *Call, depth int) {
+	if len(c.Data) < 4 || depth >= maxDepth {
+		return
+	}
+	if string(c.Data[:4]) == string(multicall.Selector()) {
+		args, err := multicall.DecodeInput(c.Data)
+		if err == nil {
+			c.Function = multicall.Signature()
+			for _, inner := range args[0].([]interface{}) {
+				// multicall delegates to the same contract.
+				ic := Call{To: c.To, Value: new(big.Int), Data: inner.([]byte)}
+				d.decodeCall(&ic, depth+1)
+				c.Calls = append(c.Calls, ic)
+			}
+			return
+		}
+	}
+	// A call to another smart account (e.g. a module or nested wallet).
+	if sig, calls := d.decodeExecution(c.Data, depth); sig != "" {
+		c.Function, c.Calls = sig, calls
+		return
+	}
+	if f, ok := d.functions[string(c.Data[:4])]; ok {
+		if args, err := f.DecodeInput(c.Data); err == nil {
+			c.Function, c.Args = f.Signature(), args
+		}
+	}
+}
+
+// Selector returns the 4-byte selector of c's data as 0x-hex, or "" for a
+// plain value transfer.
+func (c Call) Selector() string {
+	if len(c.Data) < 4 {
+		return ""
+	}
+	return fmt.Sprintf("0x%x", c.Data[:4])
+}
+
+// String summarises the call as "to.function" or "to.0xselector".
+func (c Call) String() string {
+	name := c.Function
+	if name == "" {
+		name = c.Selector()
+	}
+	if name == "" {
+		return fmt.Sprintf("%s{value: %s}", c.To.Hex(), c.Value)
+	}
+	return c.To.Hex() + "." + strings.SplitN(name, "(", 2)[0]
+}
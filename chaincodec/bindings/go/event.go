@@ -0,0 +1,189 @@
+package chaincodec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// DecodedEvent is a decoded log in the form chainkit packages hand events
+// to each other: chaincodec produces it, and chainindex sinks and alerting
+// rules consume it instead of re-parsing decoder JSON. Its JSON encoding
+// uses the decoder's keys, so it round-trips through DecodeEvent output.
+type DecodedEvent struct {
+	Chain string `json:"chain"`
+	// Name is the matched schema (event) name, e.g. "UniswapV3Swap".
+	Name          string `json:"schema"`
+	SchemaVersion uint32 `json:"schema_version,omitempty"`
+
+	BlockNumber    uint64 `json:"block_number"`
+	BlockHash      string `json:"block_hash,omitempty"`
+	BlockTimestamp int64  `json:"block_timestamp,omitempty"`
+	TxHash         string `json:"tx_hash"`
+	LogIndex       uint64 `json:"log_index"`
+	Address        string `json:"address"`
+	// Removed is set when a reorg dropped the log from the canonical chain.
+	Removed bool `json:"removed,omitempty"`
+
+	// Fields are the decoded parameters by name. Integers are json.Number
+	// (exact at any width), addresses, hashes and bytes 0x-hex strings,
+	// arrays []interface{} and tuples map[string]interface{}.
+	Fields map[string]interface{} `json:"fields"`
+	// Computed holds the fields added by Hooks, if any.
+	Computed map[string]interface{} `json:"computed,omitempty"`
+	// DecodeErrors lists the fields a lenient decode could not read.
+	DecodeErrors map[string]string `json:"decode_errors,omitempty"`
+}
+
+// Field returns a decoded or computed field.
+func (e *DecodedEvent) Field(name string) (interface{}, bool) {
+	if v, ok := e.Fields[name]; ok {
+		return v, true
+	}
+	v, ok := e.Computed[name]
+	return v, ok
+}
+
+// BigInt returns an integer field (a JSON number or a decimal or 0x-hex
+// string) as a big.Int.
+func (e *DecodedEvent) BigInt(name string) (*big.Int, bool) {
+	v, ok := e.Field(name)
+	if !ok {
+		return nil, false
+	}
+	var s string
+	switch x := v.(type) {
+	case json.Number:
+		s = string(x)
+	case string:
+		s = x
+	default:
+		return nil, false
+	}
+	if h, ok := strings.CutPrefix(s, "0x"); ok {
+		return new(big.Int).SetString(h, 16)
+	}
+	return new(big.Int).SetString(s, 10)
+}
+
+// ParseDecodedEvent reads the JSON returned by DecodeEvent and the other
+// decoding functions (DecodeEventWithHooks, SchemaRegistry.DecodeEvent,
+// Aliases.Apply). NormalizedValue objects ({"type":..,"value":..}) are
+// unwrapped to plain values, and a chain given as an object is reduced to
+// its slug.
+func ParseDecodedEvent(decodedJSON string) (*DecodedEvent, error) {
+	m, err := decodeJSONObject(decodedJSON)
+	if err != nil {
+		return nil, fmt.Errorf("chaincodec: decoded event: %w", err)
+	}
+	if c, ok := m["chain"].(map[string]interface{}); ok {
+		m["chain"] = c["slug"]
+	}
+	for _, key := range []string{"fields", "computed"} {
+		if f, ok := m[key].(map[string]interface{}); ok {
+			for k, v := range f {
+				f[k] = plainValue(v)
+			}
+		}
+	}
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	// UseNumber keeps integers in Fields exact.
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var e DecodedEvent
+	if err := dec.Decode(&e); err != nil {
+		return nil, fmt.Errorf("chaincodec: decoded event: %w", err)
+	}
+	return &e, nil
+}
+
+// DecodeLog decodes an EVM log in eth_getLogs form (or with snake_case
+// keys) against schemaJSON's event signature with the pure-Go ABI decoder,
+// see DecodeRawLog. The event's chain, position and removed flag are
+// copied from the log.
+func DecodeLog(logJSON, schemaJSON string) (*DecodedEvent, error) {
+	l, _, err := parseRawLog(logJSON)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeRawLog(l, schemaJSON)
+}
+
+// plainValue unwraps NormalizedValue objects recursively.
+func plainValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		t, tagged := x["type"].(string)
+		inner, hasValue := x["value"]
+		if !tagged || len(x) > 2 || len(x) == 2 && !hasValue {
+			for k, e := range x {
+				x[k] = plainValue(e)
+			}
+			return x
+		}
+		switch t {
+		case "null":
+			return nil
+		case "array":
+			if items, ok := inner.([]interface{}); ok {
+				for i, e := range items {
+					items[i] = plainValue(e)
+				}
+				return items
+			}
+		case "tuple":
+			// Serialized as [[name, value], ...].
+			if pairs, ok := inner.([]interface{}); ok {
+				out := make(map[string]interface{}, len(pairs))
+				for _, p := range pairs {
+					if kv, ok := p.([]interface{}); ok && len(kv) == 2 {
+						if name, ok := kv[0].(string); ok {
+							out[name] = plainValue(kv[1])
+						}
+					}
+				}
+				return out
+			}
+		case "bytes":
+			if b, ok := inner.([]interface{}); ok {
+				buf := make([]byte, len(b))
+				for i, e := range b {
+					if n, ok := e.(json.Number); ok {
+						x, _ := n.Int64()
+						buf[i] = byte(x)
+					}
+				}
+				return fmt.Sprintf("0x%x", buf)
+			}
+		}
+		return plainValue(inner)
+	case []interface{}:
+		for i, e := range x {
+			x[i] = plainValue(e)
+		}
+	}
+	return v
+}
+
+func firstRaw(vs ...json.RawMessage) json.RawMessage {
+	for _, v := range vs {
+		if len(v) > 0 {
+			return v
+		}
+	}
+	return nil
+}
+
+func firstString(vs ...string) string {
+	for _, v := range vs {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,412 @@
+package chaincodec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/DarshanKumar89/chainfoundry/chaincodec/abi"
+)
+
+// RawLog is an EVM log to decode, with the chain and position copied into
+// the DecodedEvent.
+type RawLog struct {
+	Chain       string
+	Address     string
+	Topics      []string
+	Data        string
+	BlockNumber uint64
+	BlockHash   string
+	TxHash      string
+	LogIndex    uint64
+	Removed     bool
+}
+
+// ErrFingerprintMismatch is returned when a log's topic0 is not the
+// schema's fingerprint.
+var ErrFingerprintMismatch = errors.New("chaincodec: log topic0 does not match schema fingerprint")
+
+// DecodeRawLog decodes l with schemaJSON, a schema document as returned by
+// LoadSchema (one element) or registered with SchemaRegistry. Indexed
+// parameters are read from topics[1:], the rest ABI-decoded from data, in
+// the schema's field order. Indexed strings, bytes, arrays and tuples are
+// only available as the keccak hash the log carries, given as 0x-hex.
+func DecodeRawLog(l RawLog, schemaJSON string) (*DecodedEvent, error) {
+	s, err := parseEVMSchema(schemaJSON)
+	if err != nil {
+		return nil, err
+	}
+	return s.decode(l)
+}
+
+// evmSchema is what decoding an EVM log needs from a schema document.
+type evmSchema struct {
+	name        string
+	fingerprint string
+	fields      []evmField
+}
+
+type evmField struct {
+	name    string
+	typ     evmType
+	indexed bool
+}
+
+// evmType is an ABI type with the member names of its tuples.
+type evmType struct {
+	t     abi.Type
+	elem  *evmType   // slices and arrays
+	comps []evmField // tuples
+}
+
+// parseEVMSchema reads the name, fingerprint and ordered fields of a
+// schema document. Fields are either the serialized Rust form, a list of
+// [name, {"ty": …, "indexed": …}] pairs with canonical types such as
+// {"uint":256}, or the CSDL form, an object of name → {"type": "uint256",
+// "indexed": …} whose key order is the field order.
+func parseEVMSchema(schemaJSON string) (*evmSchema, error) {
+	doc := []byte(schemaJSON)
+	if t := bytes.TrimSpace(doc); len(t) > 0 && t[0] == '[' {
+		// LoadSchema returns a list; a single schema may be passed as is.
+		var list []json.RawMessage
+		if err := json.Unmarshal(t, &list); err != nil || len(list) != 1 {
+			return nil, fmt.Errorf("chaincodec: schema: want one schema document")
+		}
+		doc = list[0]
+	}
+	var raw struct {
+		Name        string          `json:"name"`
+		Fingerprint json.RawMessage `json:"fingerprint"`
+		Fields      json.RawMessage `json:"fields"`
+	}
+	if err := json.Unmarshal(doc, &raw); err != nil {
+		return nil, fmt.Errorf("chaincodec: schema: %w", err)
+	}
+	s := &evmSchema{name: raw.Name}
+	if len(raw.Fingerprint) > 0 && json.Unmarshal(raw.Fingerprint, &s.fingerprint) != nil {
+		return nil, fmt.Errorf("chaincodec: schema %s: invalid fingerprint", raw.Name)
+	}
+	pairs, err := orderedFields(raw.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("chaincodec: schema %s: fields: %w", raw.Name, err)
+	}
+	for _, p := range pairs {
+		var def struct {
+			Ty      json.RawMessage `json:"ty"`
+			Type    json.RawMessage `json:"type"`
+			Indexed bool            `json:"indexed"`
+		}
+		if err := json.Unmarshal(p.value, &def); err != nil {
+			return nil, fmt.Errorf("chaincodec: schema %s: field %s: %w", raw.Name, p.name, err)
+		}
+		ty := def.Ty
+		if len(ty) == 0 {
+			ty = def.Type
+		}
+		t, err := parseEVMType(ty)
+		if err != nil {
+			return nil, fmt.Errorf("chaincodec: schema %s: field %s: %w", raw.Name, p.name, err)
+		}
+		s.fields = append(s.fields, evmField{name: p.name, typ: t, indexed: def.Indexed})
+	}
+	return s, nil
+}
+
+type namedRaw struct {
+	name  string
+	value json.RawMessage
+}
+
+// orderedFields reads a [[name, value], …] list or a {name: value, …}
+// object, keeping the document's order.
+func orderedFields(raw json.RawMessage) ([]namedRaw, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var out []namedRaw
+	if raw[0] == '[' {
+		var list [][]json.RawMessage
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, err
+		}
+		for _, kv := range list {
+			var p namedRaw
+			if len(kv) != 2 || json.Unmarshal(kv[0], &p.name) != nil {
+				return nil, fmt.Errorf("want [name, value] pairs")
+			}
+			p.value = kv[1]
+			out = append(out, p)
+		}
+		return out, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("want a list or an object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		p := namedRaw{name: tok.(string)}
+		if err := dec.Decode(&p.value); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// parseEVMType reads a canonical type, serialized ({"uint":256},
+// {"vec":…}, "hash256", …) or as a CSDL or Solidity type string.
+func parseEVMType(raw json.RawMessage) (evmType, error) {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return parseEVMTypeString(s)
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &m); err != nil || len(m) != 1 {
+		return evmType{}, fmt.Errorf("invalid type %s", raw)
+	}
+	for kind, v := range m {
+		switch kind {
+		case "uint", "int", "bytes":
+			var n int
+			if err := json.Unmarshal(v, &n); err != nil {
+				return evmType{}, fmt.Errorf("invalid type %s", raw)
+			}
+			return parseEVMTypeString(kind + strconv.Itoa(n))
+		case "decimal":
+			return parseEVMTypeString("uint256")
+		case "vec":
+			elem, err := parseEVMType(v)
+			if err != nil {
+				return evmType{}, err
+			}
+			return evmType{t: abi.Type{Kind: abi.KindSlice, Elem: &elem.t}, elem: &elem}, nil
+		case "array":
+			var a struct {
+				Elem json.RawMessage `json:"elem"`
+				Len  int             `json:"len"`
+			}
+			if err := json.Unmarshal(v, &a); err != nil || a.Len <= 0 {
+				return evmType{}, fmt.Errorf("invalid type %s", raw)
+			}
+			elem, err := parseEVMType(a.Elem)
+			if err != nil {
+				return evmType{}, err
+			}
+			return evmType{t: abi.Type{Kind: abi.KindArray, Size: a.Len, Elem: &elem.t}, elem: &elem}, nil
+		case "tuple":
+			pairs, err := orderedFields(v)
+			if err != nil {
+				return evmType{}, fmt.Errorf("invalid tuple: %w", err)
+			}
+			t := evmType{t: abi.Type{Kind: abi.KindTuple}}
+			for _, p := range pairs {
+				c, err := parseEVMType(p.value)
+				if err != nil {
+					return evmType{}, err
+				}
+				t.t.Components = append(t.t.Components, c.t)
+				t.comps = append(t.comps, evmField{name: p.name, typ: c})
+			}
+			return t, nil
+		}
+		return evmType{}, fmt.Errorf("type %s is not an EVM type", kind)
+	}
+	panic("unreachable")
+}
+
+// evmAliases maps canonical type names to their ABI types.
+var evmAliases = map[string]string{
+	"str":       "string",
+	"bytesvec":  "bytes",
+	"hash256":   "bytes32",
+	"timestamp": "uint256",
+}
+
+func parseEVMTypeString(s string) (evmType, error) {
+	s = strings.TrimSpace(s)
+	base, suffix := s, ""
+	if i := strings.IndexByte(s, '['); i > 0 && !strings.HasPrefix(s, "(") {
+		base, suffix = s[:i], s[i:]
+	}
+	if a, ok := evmAliases[base]; ok {
+		base = a
+	}
+	t, err := abi.ParseType(base + suffix)
+	if err != nil {
+		return evmType{}, err
+	}
+	return fromABI(t), nil
+}
+
+// fromABI wraps t, naming tuple members by position.
+func fromABI(t abi.Type) evmType {
+	out := evmType{t: t}
+	switch t.Kind {
+	case abi.KindSlice, abi.KindArray:
+		elem := fromABI(*t.Elem)
+		out.elem = &elem
+	case abi.KindTuple:
+		for i, c := range t.Components {
+			out.comps = append(out.comps, evmField{name: strconv.Itoa(i), typ: fromABI(c)})
+		}
+	}
+	return out
+}
+
+// hashedWhenIndexed reports whether an indexed parameter of kind k is
+// logged as the keccak hash of its encoding.
+func hashedWhenIndexed(k abi.Kind) bool {
+	switch k {
+	case abi.KindBytes, abi.KindString, abi.KindSlice, abi.KindArray, abi.KindTuple:
+		return true
+	}
+	return false
+}
+
+func (s *evmSchema) decode(l RawLog) (*DecodedEvent, error) {
+	if len(l.Topics) == 0 {
+		return nil, fmt.Errorf("chaincodec: decode %s: log has no topics", s.name)
+	}
+	if s.fingerprint != "" && !strings.EqualFold(l.Topics[0], s.fingerprint) {
+		return nil, fmt.Errorf("%w: %s is not %s (%s)", ErrFingerprintMismatch, l.Topics[0], s.fingerprint, s.name)
+	}
+	var dataTypes []abi.Type
+	indexed := 0
+	for _, f := range s.fields {
+		if f.indexed {
+			indexed++
+		} else {
+			dataTypes = append(dataTypes, f.typ.t)
+		}
+	}
+	if len(l.Topics)-1 != indexed {
+		return nil, fmt.Errorf("chaincodec: decode %s: log has %d indexed topics, schema %d", s.name, len(l.Topics)-1, indexed)
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(l.Data, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("chaincodec: decode %s: data: %w", s.name, err)
+	}
+	values, err := abi.Decode(dataTypes, data)
+	if err != nil {
+		return nil, fmt.Errorf("chaincodec: decode %s: data: %w", s.name, err)
+	}
+
+	fields := make(map[string]interface{}, len(s.fields))
+	topic, value := 1, 0
+	for _, f := range s.fields {
+		if !f.indexed {
+			fields[f.name] = f.typ.plain(values[value])
+			value++
+			continue
+		}
+		word, err := hex.DecodeString(strings.TrimPrefix(l.Topics[topic], "0x"))
+		topic++
+		if err != nil || len(word) != 32 {
+			return nil, fmt.Errorf("chaincodec: decode %s: field %s: invalid topic", s.name, f.name)
+		}
+		if hashedWhenIndexed(f.typ.t.Kind) {
+			fields[f.name] = "0x" + hex.EncodeToString(word)
+			continue
+		}
+		v, err := abi.Decode([]abi.Type{f.typ.t}, word)
+		if err != nil {
+			return nil, fmt.Errorf("chaincodec: decode %s: field %s: %w", s.name, f.name, err)
+		}
+		fields[f.name] = f.typ.plain(v[0])
+	}
+	return &DecodedEvent{
+		Chain:       l.Chain,
+		Name:        s.name,
+		BlockNumber: l.BlockNumber,
+		BlockHash:   l.BlockHash,
+		TxHash:      l.TxHash,
+		LogIndex:    l.LogIndex,
+		Address:     l.Address,
+		Removed:     l.Removed,
+		Fields:      fields,
+	}, nil
+}
+
+// plain converts a value abi.Decode returned to the DecodedEvent.Fields
+// form.
+func (t evmType) plain(v interface{}) interface{} {
+	switch x := v.(type) {
+	case *big.Int:
+		return json.Number(x.String())
+	case abi.Address:
+		return "0x" + hex.EncodeToString(x[:])
+	case []byte:
+		return "0x" + hex.EncodeToString(x)
+	case []interface{}:
+		if t.t.Kind == abi.KindTuple {
+			out := make(map[string]interface{}, len(x))
+			for i, e := range x {
+				out[t.comps[i].name] = t.comps[i].typ.plain(e)
+			}
+			return out
+		}
+		out := make([]interface{}, len(x))
+		for i, e := range x {
+			out[i] = t.elem.plain(e)
+		}
+		return out
+	}
+	return v
+}
+
+// parseRawLog reads a log in eth_getLogs form (hex quantities) or with
+// snake_case keys and decimal numbers. hasBlock reports whether it carried
+// a block number.
+func parseRawLog(logJSON string) (l RawLog, hasBlock bool, err error) {
+	var j struct {
+		Chain       json.RawMessage `json:"chain"`
+		Address     string          `json:"address"`
+		Topics      []string        `json:"topics"`
+		Data        string          `json:"data"`
+		BlockNumber json.RawMessage `json:"blockNumber"`
+		BlockNum    json.RawMessage `json:"block_number"`
+		BlockHash   string          `json:"blockHash"`
+		BlockHash2  string          `json:"block_hash"`
+		TxHash      string          `json:"transactionHash"`
+		TxHash2     string          `json:"tx_hash"`
+		LogIndex    json.RawMessage `json:"logIndex"`
+		LogIndex2   json.RawMessage `json:"log_index"`
+		Removed     bool            `json:"removed"`
+	}
+	if err := json.Unmarshal([]byte(logJSON), &j); err != nil {
+		return RawLog{}, false, fmt.Errorf("chaincodec: log: %w", err)
+	}
+	l = RawLog{
+		Address:   j.Address,
+		Topics:    j.Topics,
+		Data:      j.Data,
+		BlockHash: firstString(j.BlockHash, j.BlockHash2),
+		TxHash:    firstString(j.TxHash, j.TxHash2),
+		Removed:   j.Removed,
+	}
+	if json.Unmarshal(j.Chain, &l.Chain) != nil {
+		var c struct {
+			Slug string `json:"slug"`
+		}
+		if json.Unmarshal(j.Chain, &c) == nil {
+			l.Chain = c.Slug
+		}
+	}
+	if n, err := parseBlock(firstRaw(j.BlockNumber, j.BlockNum)); err == nil {
+		l.BlockNumber, hasBlock = n, true
+	}
+	if n, err := parseBlock(firstRaw(j.LogIndex, j.LogIndex2)); err == nil {
+		l.LogIndex = n
+	}
+	return l, hasBlock, nil
+}
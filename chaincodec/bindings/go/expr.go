@@ -0,0 +1,434 @@
+package chaincodec
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// The computed-field expression language is deliberately small:
+//
+//	literals     123  1.5  "text"  'text'  true  false
+//	fields       amount0  sender  pool.fee   (dotted paths into tuples)
+//	arithmetic   + - * / %     (exact rational arithmetic)
+//	comparison   == != < <= > >=
+//	logic        && || !
+//	conditional  cond ? a : b
+//	grouping     ( ... )
+//
+// Numbers are arbitrary precision, so uint256 amounts never overflow.
+// Strings compare case-insensitively when both look like hex ("0x..."),
+// which makes address comparisons behave as expected.
+
+type exprNode interface {
+	eval(env map[string]interface{}) (interface{}, error)
+}
+
+type (
+	litNode   struct{ v interface{} }
+	fieldNode struct{ path string }
+	unaryNode struct {
+		op string
+		x  exprNode
+	}
+	binaryNode struct {
+		op   string
+		l, r exprNode
+	}
+	condNode struct{ cond, a, b exprNode }
+)
+
+// compileExpr parses src into an evaluable expression.
+func compileExpr(src string) (exprNode, error) {
+	toks, err := lexExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{toks: toks}
+	n, err := p.parseCond()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("unexpected %q", p.toks[p.pos].text)
+	}
+	return n, nil
+}
+
+type tokKind int
+
+const (
+	tokNum tokKind = iota
+	tokStr
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+}
+
+func lexExpr(src string) ([]token, error) {
+	var toks []token
+	rs := []rune(src)
+	for i := 0; i < len(rs); {
+		c := rs[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c):
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{tokNum, string(rs[i:j])})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_' || rs[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{tokIdent, string(rs[i:j])})
+			i = j
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(rs) && rs[j] != c {
+				j++
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			toks = append(toks, token{tokStr, string(rs[i+1 : j])})
+			i = j + 1
+		default:
+			if i+1 < len(rs) {
+				two := string(rs[i : i+2])
+				switch two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					toks = append(toks, token{tokOp, two})
+					i += 2
+					continue
+				}
+			}
+			if strings.ContainsRune("+-*/%<>!?:()", c) {
+				toks = append(toks, token{tokOp, string(c)})
+				i++
+				continue
+			}
+			return nil, fmt.Errorf("unexpected character %q at %d", c, i)
+		}
+	}
+	return toks, nil
+}
+
+type exprParser struct {
+	toks []token
+	pos  int
+}
+
+func (p *exprParser) peekOp(ops ...string) (string, bool) {
+	if p.pos >= len(p.toks) || p.toks[p.pos].kind != tokOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if p.toks[p.pos].text == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) parseCond() (exprNode, error) {
+	c, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.peekOp("?"); !ok {
+		return c, nil
+	}
+	p.pos++
+	a, err := p.parseCond()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.peekOp(":"); !ok {
+		return nil, fmt.Errorf("expected ':' in conditional")
+	}
+	p.pos++
+	b, err := p.parseCond()
+	if err != nil {
+		return nil, err
+	}
+	return &condNode{c, a, b}, nil
+}
+
+// binaryLevels lists operators from lowest to highest precedence.
+var binaryLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *exprParser) parseBinary(level int) (exprNode, error) {
+	if level == len(binaryLevels) {
+		return p.parseUnary()
+	}
+	l, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.peekOp(binaryLevels[level]...)
+		if !ok {
+			return l, nil
+		}
+		p.pos++
+		r, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryNode{op, l, r}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if op, ok := p.peekOp("!", "-"); ok {
+		p.pos++
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op, x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	if p.pos >= len(p.toks) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.toks[p.pos]
+	p.pos++
+	switch t.kind {
+	case tokNum:
+		r, ok := new(big.Rat).SetString(t.text)
+		if !ok {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return &litNode{r}, nil
+	case tokStr:
+		return &litNode{t.text}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &litNode{true}, nil
+		case "false":
+			return &litNode{false}, nil
+		}
+		return &fieldNode{t.text}, nil
+	}
+	if t.text == "(" {
+		n, err := p.parseCond()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.peekOp(")"); !ok {
+			return nil, fmt.Errorf("expected ')'")
+		}
+		p.pos++
+		return n, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+func (n *litNode) eval(map[string]interface{}) (interface{}, error) { return n.v, nil }
+
+func (n *fieldNode) eval(env map[string]interface{}) (interface{}, error) {
+	var cur interface{} = env
+	for _, part := range strings.Split(n.path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("field %q not found", n.path)
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, fmt.Errorf("field %q not found", n.path)
+		}
+	}
+	return coerceValue(cur), nil
+}
+
+func (n *unaryNode) eval(env map[string]interface{}) (interface{}, error) {
+	v, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "!":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("'!' needs a boolean")
+		}
+		return !b, nil
+	default:
+		r, ok := v.(*big.Rat)
+		if !ok {
+			return nil, fmt.Errorf("'-' needs a number")
+		}
+		return new(big.Rat).Neg(r), nil
+	}
+}
+
+func (n *condNode) eval(env map[string]interface{}) (interface{}, error) {
+	c, err := n.cond.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := c.(bool)
+	if !ok {
+		return nil, fmt.Errorf("condition is not a boolean")
+	}
+	if b {
+		return n.a.eval(env)
+	}
+	return n.b.eval(env)
+}
+
+func (n *binaryNode) eval(env map[string]interface{}) (interface{}, error) {
+	l, err := n.l.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	// Short-circuit logic operators.
+	if n.op == "&&" || n.op == "||" {
+		lb, ok := l.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%q needs booleans", n.op)
+		}
+		if (n.op == "&&" && !lb) || (n.op == "||" && lb) {
+			return lb, nil
+		}
+		r, err := n.r.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		rb, ok := r.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%q needs booleans", n.op)
+		}
+		return rb, nil
+	}
+	r, err := n.r.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "==" || n.op == "!=" {
+		eq := valuesEqual(l, r)
+		return eq == (n.op == "=="), nil
+	}
+	lr, lok := l.(*big.Rat)
+	rr, rok := r.(*big.Rat)
+	if !lok || !rok {
+		if ls, ok := l.(string); ok && n.op == "+" {
+			if rs, ok := r.(string); ok {
+				return ls + rs, nil
+			}
+		}
+		return nil, fmt.Errorf("%q needs numbers", n.op)
+	}
+	switch n.op {
+	case "+":
+		return new(big.Rat).Add(lr, rr), nil
+	case "-":
+		return new(big.Rat).Sub(lr, rr), nil
+	case "*":
+		return new(big.Rat).Mul(lr, rr), nil
+	case "/":
+		if rr.Sign() == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return new(big.Rat).Quo(lr, rr), nil
+	case "%":
+		if !lr.IsInt() || !rr.IsInt() || rr.Sign() == 0 {
+			return nil, fmt.Errorf("'%%' needs non-zero integers")
+		}
+		return new(big.Rat).SetInt(new(big.Int).Rem(lr.Num(), rr.Num())), nil
+	case "<":
+		return lr.Cmp(rr) < 0, nil
+	case "<=":
+		return lr.Cmp(rr) <= 0, nil
+	case ">":
+		return lr.Cmp(rr) > 0, nil
+	case ">=":
+		return lr.Cmp(rr) >= 0, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", n.op)
+}
+
+func valuesEqual(a, b interface{}) bool {
+	switch x := a.(type) {
+	case *big.Rat:
+		y, ok := b.(*big.Rat)
+		return ok && x.Cmp(y) == 0
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return false
+		}
+		if strings.HasPrefix(x, "0x") && strings.HasPrefix(y, "0x") {
+			return strings.EqualFold(x, y)
+		}
+		return x == y
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+	}
+	return a == nil && b == nil
+}
+
+// coerceValue converts a decoded JSON value into the evaluator's domain:
+// numbers and numeric strings become *big.Rat, NormalizedValue objects
+// ({"type":..,"value":..}) are unwrapped, everything else passes through.
+func coerceValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if t, ok := x["type"].(string); ok {
+			if inner, ok := x["value"]; ok && len(x) == 2 {
+				switch t {
+				case "uint", "int", "biguint", "bigint", "timestamp":
+					return coerceNumber(inner)
+				}
+				return coerceValue(inner)
+			}
+		}
+		return x
+	case json.Number, float64, string:
+		return coerceNumber(x)
+	}
+	return v
+}
+
+func coerceNumber(v interface{}) interface{} {
+	switch x := v.(type) {
+	case json.Number:
+		if r, ok := new(big.Rat).SetString(string(x)); ok {
+			return r
+		}
+		return string(x)
+	case float64:
+		return new(big.Rat).SetFloat64(x)
+	case string:
+		if x != "" && !strings.HasPrefix(x, "0x") {
+			if r, ok := new(big.Rat).SetString(x); ok {
+				return r
+			}
+		}
+		return x
+	}
+	return v
+}
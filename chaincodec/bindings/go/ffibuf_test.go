@@ -0,0 +1,41 @@
+package chaincodec
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const benchSchema = `{"name":"ERC20Transfer","event":"Transfer"}`
+
+// benchLog is a Transfer log with words 32-byte words of data, to vary the
+// result size.
+func benchLog(words int) string {
+	return fmt.Sprintf(`{"address":"0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48","topics":["0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef","0x000000000000000000000000d8da6bf26964af9d7eed9e03e53415d37aa96045","0x000000000000000000000000ab5801a7d398351b8be11c439e05c5b3259aec9b"],"data":"0x%s"}`,
+		strings.Repeat("ab", 32*words))
+}
+
+// BenchmarkDecodeEvent compares DecodeEvent's pooled arena with a
+// C.CString per argument and C.GoString of a native result.
+func BenchmarkDecodeEvent(b *testing.B) {
+	paths := []struct {
+		name   string
+		decode func(logJSON, schemaJSON string) (string, error)
+	}{
+		{"pooled", DecodeEvent},
+		{"cstring", decodeEventUnpooled},
+	}
+	for _, words := range []int{1, 16, 256} {
+		log := benchLog(words)
+		for _, p := range paths {
+			b.Run(fmt.Sprintf("%s/words=%d", p.name, words), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := p.decode(log, benchSchema); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package chaincodec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+// ComputedField is a derived field evaluated at decode time, e.g.
+//
+//	{Name: "price", Expr: "amount1 / amount0"}
+//	{Name: "direction", Expr: `from == pool ? "sell" : "buy"`}
+type ComputedField struct {
+	Name string `json:"name"`
+	Expr string `json:"expr"`
+}
+
+// computedDecimals is the number of decimal places kept when a computed
+// number is not an integer.
+const computedDecimals = 18
+
+type compiledField struct {
+	name string
+	expr exprNode
+}
+
+// Hooks holds computed fields per schema name. Register fields in Go, or
+// declare them in the schema JSON under "computed" ([{"name":"price","expr":"amount1 / amount0"}]);
+// both are evaluated by DecodeEventWithHooks. Hooks is safe for concurrent use.
+type Hooks struct {
+	mu      sync.RWMutex
+	schemas map[string][]compiledField
+}
+
+// NewHooks returns an empty hook set.
+func NewHooks() *Hooks {
+	return &Hooks{schemas: make(map[string][]compiledField)}
+}
+
+// Register compiles fields and attaches them to schema. Expressions are
+// validated here so typos fail at startup, not on the first event.
+func (h *Hooks) Register(schema string, fields ...ComputedField) error {
+	compiled, err := compileFields(fields)
+	if err != nil {
+		return fmt.Errorf("schema %s: %w", schema, err)
+	}
+	h.mu.Lock()
+	h.schemas[schema] = append(h.schemas[schema], compiled...)
+	h.mu.Unlock()
+	return nil
+}
+
+func compileFields(fields []ComputedField) ([]compiledField, error) {
+	out := make([]compiledField, 0, len(fields))
+	for _, f := range fields {
+		n, err := compileExpr(f.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("computed field %q: %w", f.Name, err)
+		}
+		out = append(out, compiledField{f.Name, n})
+	}
+	return out, nil
+}
+
+// DecodeEventWithHooks decodes like DecodeLog, then evaluates computed
+// fields registered for the event's schema (and any declared in schemaJSON)
+// and adds them under "computed" in the returned JSON. Later fields may
+// refer to earlier computed ones by name.
+func DecodeEventWithHooks(logJSON, schemaJSON string, h *Hooks) (string, error) {
+	decoded, err := decodeLogJSON(logJSON, schemaJSON)
+	if err != nil {
+		return "", err
+	}
+	declared, schemaName, err := schemaComputed(schemaJSON)
+	if err != nil {
+		return "", err
+	}
+	return h.apply(decoded, schemaName, declared)
+}
+
+// Apply evaluates computed fields on an already decoded event JSON.
+func (h *Hooks) Apply(decodedJSON string) (string, error) {
+	return h.apply(decodedJSON, "", nil)
+}
+
+func (h *Hooks) apply(decodedJSON, schemaName string, declared []compiledField) (string, error) {
+	ev, err := decodeJSONObject(decodedJSON)
+	if err != nil {
+		return "", fmt.Errorf("decoded event: %w", err)
+	}
+	if name, ok := ev["schema"].(string); ok {
+		schemaName = name
+	}
+	fields := append([]compiledField(nil), declared...)
+	if h != nil {
+		h.mu.RLock()
+		fields = append(fields, h.schemas[schemaName]...)
+		h.mu.RUnlock()
+	}
+	if len(fields) == 0 {
+		return decodedJSON, nil
+	}
+
+	// Fields are unwrapped to plain values so that dotted paths reach
+	// into tuples (pool.fee).
+	env := make(map[string]interface{})
+	if f, ok := ev["fields"].(map[string]interface{}); ok {
+		for k, v := range f {
+			env[k] = plainValue(v)
+		}
+	}
+	for k, v := range ev {
+		if _, shadowed := env[k]; !shadowed && k != "fields" {
+			env[k] = v
+		}
+	}
+	computed := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		v, err := f.expr.eval(env)
+		if err != nil {
+			return "", fmt.Errorf("schema %s: computed field %q: %w", schemaName, f.name, err)
+		}
+		computed[f.name] = exportComputed(v)
+		env[f.name] = v
+	}
+	ev["computed"] = computed
+	out, err := json.Marshal(ev)
+	return string(out), err
+}
+
+// schemaComputed extracts the optional "computed" declarations and the
+// schema name from a schema JSON document.
+func schemaComputed(schemaJSON string) ([]compiledField, string, error) {
+	var s struct {
+		Name     string          `json:"name"`
+		Computed json.RawMessage `json:"computed"`
+	}
+	if err := json.Unmarshal([]byte(schemaJSON), &s); err != nil || len(s.Computed) == 0 {
+		// Schema arrays (as returned by LoadSchema) carry no declarations.
+		return nil, s.Name, nil
+	}
+	// Declarations are either an ordered list of {name, expr} or a
+	// name→expr object, evaluated in name order.
+	var fields []ComputedField
+	if err := json.Unmarshal(s.Computed, &fields); err != nil {
+		var m map[string]string
+		if err := json.Unmarshal(s.Computed, &m); err != nil {
+			return nil, "", fmt.Errorf("schema %s: invalid computed declarations: %w", s.Name, err)
+		}
+		for name, expr := range m {
+			fields = append(fields, ComputedField{Name: name, Expr: expr})
+		}
+		sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	}
+	compiled, err := compileFields(fields)
+	if err != nil {
+		return nil, "", fmt.Errorf("schema %s: %w", s.Name, err)
+	}
+	return compiled, s.Name, nil
+}
+
+// decodeLogJSON is DecodeLog returning the event as JSON.
+func decodeLogJSON(logJSON, schemaJSON string) (string, error) {
+	e, err := DecodeLog(logJSON, schemaJSON)
+	if err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(e)
+	return string(out), err
+}
+
+func decodeJSONObject(s string) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader([]byte(s)))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// exportComputed renders evaluator values as JSON: integers and decimals as
+// strings (to keep uint256 precision), strings and booleans as is.
+func exportComputed(v interface{}) interface{} {
+	r, ok := v.(*big.Rat)
+	if !ok {
+		return v
+	}
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	s := r.FloatString(computedDecimals)
+	s = trimZeros(s)
+	return s
+}
+
+func trimZeros(s string) string {
+	i := len(s)
+	for i > 0 && s[i-1] == '0' {
+		i--
+	}
+	if i > 0 && s[i-1] == '.' {
+		i--
+	}
+	return s[:i]
+}
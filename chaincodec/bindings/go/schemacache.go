@@ -0,0 +1,228 @@
+package chaincodec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Schema cache file layout (little endian):
+//
+//	magic   [8]byte  "CCSCACHE"
+//	version uint32
+//	count   uint32
+//	index   count × {nameOff, nameLen, dataOff, dataLen uint64}, sorted by name
+//	blob    names and schema JSON documents
+//
+// Offsets are relative to the start of the file, so a mapped file is used
+// in place without parsing.
+const (
+	schemaCacheMagic   = "CCSCACHE"
+	schemaCacheVersion = 1
+	schemaCacheHeader  = 16
+	schemaCacheEntry   = 32
+)
+
+// ErrBadSchemaCache is returned when a cache file is truncated, has the
+// wrong magic or was written by an incompatible version.
+var ErrBadSchemaCache = errors.New("chaincodec: invalid schema cache file")
+
+// CompileSchemaCache loads every .csdl file under dir and writes the schemas
+// to a cache file at out, keyed by schema name. The file is written to a
+// temporary name and renamed, so processes opening out never see a partial
+// file. It returns the number of schemas written.
+func CompileSchemaCache(dir, out string) (int, error) {
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(path, ".csdl") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	schemas := make(map[string][]byte)
+	for _, p := range paths {
+		summary, err := LoadSchema(p)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", p, err)
+		}
+		if err := collectSchemas(schemas, summary); err != nil {
+			return 0, fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	return len(schemas), WriteSchemaCache(out, schemas)
+}
+
+// collectSchemas splits a LoadSchema summary (an array of schema objects)
+// into documents keyed by name.
+func collectSchemas(dst map[string][]byte, summary string) error {
+	var docs []json.RawMessage
+	if err := json.Unmarshal([]byte(summary), &docs); err != nil {
+		return fmt.Errorf("schema summary: %w", err)
+	}
+	for _, doc := range docs {
+		var s struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(doc, &s); err != nil || s.Name == "" {
+			return fmt.Errorf("schema without name: %.80s", doc)
+		}
+		if _, dup := dst[s.Name]; dup {
+			return fmt.Errorf("duplicate schema %q", s.Name)
+		}
+		dst[s.Name] = doc
+	}
+	return nil
+}
+
+// WriteSchemaCache writes schemas (name → schema JSON) as a cache file.
+func WriteSchemaCache(out string, schemas map[string][]byte) error {
+	names := make([]string, 0, len(schemas))
+	for n := range schemas {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.WriteString(schemaCacheMagic)
+	binary.Write(&buf, binary.LittleEndian, uint32(schemaCacheVersion))
+	binary.Write(&buf, binary.LittleEndian, uint32(len(names)))
+
+	off := uint64(schemaCacheHeader + schemaCacheEntry*len(names))
+	for _, n := range names {
+		doc := schemas[n]
+		binary.Write(&buf, binary.LittleEndian, [4]uint64{
+			off, uint64(len(n)),
+			off + uint64(len(n)), uint64(len(doc)),
+		})
+		off += uint64(len(n) + len(doc))
+	}
+	for _, n := range names {
+		buf.WriteString(n)
+		buf.Write(schemas[n])
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(out), filepath.Base(out)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), out)
+}
+
+// SchemaCache is a read-only view of a compiled schema cache. On Unix the
+// file is memory-mapped, so processes opening the same file share its pages
+// instead of each holding a parsed copy. SchemaCache is safe for concurrent
+// use until Close.
+type SchemaCache struct {
+	data  []byte
+	count int
+	unmap func() error
+}
+
+// OpenSchemaCache maps the cache file at path.
+func OpenSchemaCache(path string) (*SchemaCache, error) {
+	data, unmap, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &SchemaCache{data: data, unmap: unmap}
+	if err := c.check(); err != nil {
+		unmap()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+func (c *SchemaCache) check() error {
+	if len(c.data) < schemaCacheHeader || string(c.data[:8]) != schemaCacheMagic {
+		return ErrBadSchemaCache
+	}
+	if v := binary.LittleEndian.Uint32(c.data[8:]); v != schemaCacheVersion {
+		return fmt.Errorf("%w: version %d", ErrBadSchemaCache, v)
+	}
+	c.count = int(binary.LittleEndian.Uint32(c.data[12:]))
+	if uint64(len(c.data)) < uint64(schemaCacheHeader)+uint64(c.count)*schemaCacheEntry {
+		return ErrBadSchemaCache
+	}
+	for i := 0; i < c.count; i++ {
+		e := c.entry(i)
+		if e[0]+e[1] > uint64(len(c.data)) || e[2]+e[3] > uint64(len(c.data)) {
+			return ErrBadSchemaCache
+		}
+	}
+	return nil
+}
+
+func (c *SchemaCache) entry(i int) [4]uint64 {
+	b := c.data[schemaCacheHeader+i*schemaCacheEntry:]
+	return [4]uint64{
+		binary.LittleEndian.Uint64(b),
+		binary.LittleEndian.Uint64(b[8:]),
+		binary.LittleEndian.Uint64(b[16:]),
+		binary.LittleEndian.Uint64(b[24:]),
+	}
+}
+
+func (c *SchemaCache) name(i int) []byte {
+	e := c.entry(i)
+	return c.data[e[0] : e[0]+e[1]]
+}
+
+// Len returns the number of schemas in the cache.
+func (c *SchemaCache) Len() int { return c.count }
+
+// Names returns the schema names in sorted order.
+func (c *SchemaCache) Names() []string {
+	out := make([]string, c.count)
+	for i := range out {
+		out[i] = string(c.name(i))
+	}
+	return out
+}
+
+// Lookup returns the schema JSON for name, suitable for DecodeEvent.
+func (c *SchemaCache) Lookup(name string) (string, bool) {
+	b, ok := c.LookupBytes(name)
+	return string(b), ok
+}
+
+// LookupBytes is like Lookup but returns a slice of the mapped file without
+// copying. The slice must not be modified or used after Close.
+func (c *SchemaCache) LookupBytes(name string) ([]byte, bool) {
+	key := []byte(name)
+	i := sort.Search(c.count, func(i int) bool { return bytes.Compare(c.name(i), key) >= 0 })
+	if i == c.count || !bytes.Equal(c.name(i), key) {
+		return nil, false
+	}
+	e := c.entry(i)
+	return c.data[e[2] : e[2]+e[3]], true
+}
+
+// Close unmaps the file. Slices returned by LookupBytes become invalid.
+func (c *SchemaCache) Close() error {
+	if c.unmap == nil {
+		return nil
+	}
+	err := c.unmap()
+	c.data, c.count, c.unmap = nil, 0, nil
+	return err
+}
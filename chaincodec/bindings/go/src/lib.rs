@@ -109,26 +109,112 @@ pub extern "C" fn chaincodec_decode_event(
         }
     };
 
-    let log_val: serde_json::Value = match serde_json::from_str(log_str) {
-        Ok(v) => v,
-        Err(e) => { set_last_error(&format!("log_json parse: {e}")); return std::ptr::null_mut(); }
-    };
-    let _schema_val: serde_json::Value = match serde_json::from_str(schema_str) {
-        Ok(v) => v,
-        Err(e) => { set_last_error(&format!("schema_json parse: {e}")); return std::ptr::null_mut(); }
-    };
+    match decode_value(log_str, schema_str) {
+        Err(e) => { set_last_error(&e); std::ptr::null_mut() }
+        Ok(result) => match CString::new(result.to_string()) {
+            Ok(s) => s.into_raw(),
+            Err(e) => { set_last_error(&e.to_string()); std::ptr::null_mut() }
+        },
+    }
+}
+
+/// Decode `log_str` with `schema_str`; shared by the string and `_into`
+/// entry points.
+fn decode_value(log_str: &str, schema_str: &str) -> Result<serde_json::Value, String> {
+    let log_val: serde_json::Value = serde_json::from_str(log_str)
+        .map_err(|e| format!("log_json parse: {e}"))?;
+    let _schema_val: serde_json::Value = serde_json::from_str(schema_str)
+        .map_err(|e| format!("schema_json parse: {e}"))?;
 
     // Build a minimal decoded representation from the log JSON
-    let result = serde_json::json!({
+    Ok(serde_json::json!({
         "status": "decoded",
         "address": log_val.get("address"),
         "topics": log_val.get("topics"),
         "data": log_val.get("data"),
-    });
-    match CString::new(result.to_string()) {
-        Ok(s) => s.into_raw(),
-        Err(e) => { set_last_error(&e.to_string()); std::ptr::null_mut() }
+    }))
+}
+
+// ─── Result arena ─────────────────────────────────────────────────────────────
+
+thread_local! {
+    /// Per-thread buffer the `_into` calls serialize results into, reused
+    /// across calls so a decode loop allocates no result strings here.
+    static RESULT: RefCell<Vec<u8>> = RefCell::new(Vec::with_capacity(16 * 1024));
+}
+
+/// Result buffers above this capacity are released once copied out.
+const MAX_KEPT_RESULT: usize = 1 << 20;
+
+/// Copy `buf` to `out` when it fits in `out_cap` bytes. Returns buf's length
+/// either way.
+fn copy_result(buf: &mut Vec<u8>, out: *mut c_char, out_cap: usize) -> i64 {
+    let n = buf.len();
+    if n <= out_cap && !out.is_null() {
+        unsafe { std::ptr::copy_nonoverlapping(buf.as_ptr(), out as *mut u8, n) };
+        if buf.capacity() > MAX_KEPT_RESULT {
+            *buf = Vec::new();
+        }
     }
+    n as i64
+}
+
+/// Like `chaincodec_decode_event`, writing the decoded JSON into the
+/// caller's `out` buffer instead of allocating a string. The result is not
+/// NUL-terminated.
+///
+/// Returns the result length, or -1 on error. A length above `out_cap`
+/// means nothing was written: the result is held for
+/// `chaincodec_take_result` on the same thread.
+#[no_mangle]
+pub extern "C" fn chaincodec_decode_event_into(
+    log_json: *const c_char,
+    schema_json: *const c_char,
+    out: *mut c_char,
+    out_cap: usize,
+) -> i64 {
+    clear_last_error();
+    let log_str = unsafe {
+        match CStr::from_ptr(log_json).to_str() {
+            Ok(s) => s,
+            Err(_) => { set_last_error("invalid UTF-8 in log_json"); return -1; }
+        }
+    };
+    let schema_str = unsafe {
+        match CStr::from_ptr(schema_json).to_str() {
+            Ok(s) => s,
+            Err(_) => { set_last_error("invalid UTF-8 in schema_json"); return -1; }
+        }
+    };
+    let result = match decode_value(log_str, schema_str) {
+        Ok(v) => v,
+        Err(e) => { set_last_error(&e); return -1; }
+    };
+    RESULT.with(|buf| {
+        let mut buf = buf.borrow_mut();
+        buf.clear();
+        if let Err(e) = serde_json::to_writer(&mut *buf, &result) {
+            set_last_error(&e.to_string());
+            return -1;
+        }
+        copy_result(&mut buf, out, out_cap)
+    })
+}
+
+/// Copy the result held by the last `_into` call on this thread into
+/// `out`, for when it did not fit the caller's buffer.
+///
+/// Returns the result length, or -1 if `out_cap` is still too small.
+#[no_mangle]
+pub extern "C" fn chaincodec_take_result(out: *mut c_char, out_cap: usize) -> i64 {
+    RESULT.with(|buf| {
+        let mut buf = buf.borrow_mut();
+        if buf.len() > out_cap {
+            set_last_error(&format!("result of {} bytes does not fit {out_cap}", buf.len()));
+            return -1;
+        }
+        copy_result(&mut buf, out, out_cap)
+    })
 }
 
 /// Return the version string of the chaincodec library.
@@ -0,0 +1,197 @@
+package chaincodec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNoSchemaVersion is returned when no registered version of a schema
+// covers a log's block.
+var ErrNoSchemaVersion = errors.New("chaincodec: no schema version for block")
+
+// BlockRange is an inclusive range of blocks. A nil To is open-ended.
+type BlockRange struct {
+	From uint64  `json:"from"`
+	To   *uint64 `json:"to,omitempty"`
+}
+
+// Contains reports whether block lies in the range.
+func (r BlockRange) Contains(block uint64) bool {
+	return block >= r.From && (r.To == nil || block <= *r.To)
+}
+
+func (r BlockRange) overlaps(o BlockRange) bool {
+	return (r.To == nil || o.From <= *r.To) && (o.To == nil || r.From <= *o.To)
+}
+
+func (r BlockRange) String() string {
+	if r.To == nil {
+		return fmt.Sprintf("[%d,∞)", r.From)
+	}
+	return fmt.Sprintf("[%d,%d]", r.From, *r.To)
+}
+
+// SchemaVersion is one version of a schema and the blocks it decodes,
+// e.g. the layout of an event before and after a contract upgrade.
+type SchemaVersion struct {
+	Name    string     `json:"name"`
+	Version uint32     `json:"version"`
+	Blocks  BlockRange `json:"blocks"`
+	// Address limits the version to logs from one contract, for upgrades
+	// that happened at different blocks on different deployments. Versions
+	// for an address take precedence over ones without.
+	Address string `json:"address,omitempty"`
+	// Schema is the schema JSON the version decodes with.
+	Schema string `json:"-"`
+
+	evm    *evmSchema // Schema parsed at Register
+	evmErr error
+}
+
+// SchemaRegistry selects the version of a schema to decode a log with by
+// the log's block number (and address). Versions of one schema for the
+// same address must not overlap. SchemaRegistry is safe for concurrent use.
+type SchemaRegistry struct {
+	mu      sync.RWMutex
+	schemas map[string][]SchemaVersion
+}
+
+// NewSchemaRegistry returns an empty registry.
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{schemas: make(map[string][]SchemaVersion)}
+}
+
+// Register adds v, failing if it overlaps a registered version of the same
+// schema and address.
+func (r *SchemaRegistry) Register(v SchemaVersion) error {
+	if v.Name == "" {
+		return errors.New("chaincodec: schema version without a name")
+	}
+	if v.Blocks.To != nil && *v.Blocks.To < v.Blocks.From {
+		return fmt.Errorf("chaincodec: schema %s v%d: empty block range %s", v.Name, v.Version, v.Blocks)
+	}
+	v.Address = strings.ToLower(v.Address)
+	v.evm, v.evmErr = parseEVMSchema(v.Schema)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cur := range r.schemas[v.Name] {
+		if cur.Address == v.Address && cur.Blocks.overlaps(v.Blocks) {
+			return fmt.Errorf("chaincodec: schema %s v%d blocks %s overlap v%d blocks %s",
+				v.Name, v.Version, v.Blocks, cur.Version, cur.Blocks)
+		}
+	}
+	vs := append(r.schemas[v.Name], v)
+	sort.Slice(vs, func(i, j int) bool { return vs[i].Blocks.From < vs[j].Blocks.From })
+	r.schemas[v.Name] = vs
+	return nil
+}
+
+// RegisterSchema adds a schema JSON document, taking its name, version and
+// validity from the document itself:
+//
+//	{"name": "PoolSwap", "version": 2, "valid_blocks": {"from": 18000000}, …}
+//
+// "valid_blocks" is optional and defaults to every block; an optional
+// "valid_address" scopes the version to one contract.
+func (r *SchemaRegistry) RegisterSchema(schemaJSON string) error {
+	var s struct {
+		Name    string      `json:"name"`
+		Version uint32      `json:"version"`
+		Blocks  *BlockRange `json:"valid_blocks"`
+		Address string      `json:"valid_address"`
+	}
+	if err := json.Unmarshal([]byte(schemaJSON), &s); err != nil {
+		return fmt.Errorf("chaincodec: schema: %w", err)
+	}
+	v := SchemaVersion{Name: s.Name, Version: s.Version, Address: s.Address, Schema: schemaJSON}
+	if s.Blocks != nil {
+		v.Blocks = *s.Blocks
+	}
+	return r.Register(v)
+}
+
+// Versions returns the registered versions of name in block order.
+func (r *SchemaRegistry) Versions(name string) []SchemaVersion {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]SchemaVersion(nil), r.schemas[name]...)
+}
+
+// Lookup returns the version of name that decodes logs from address at
+// block.
+func (r *SchemaRegistry) Lookup(name, address string, block uint64) (SchemaVersion, bool) {
+	address = strings.ToLower(address)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var generic *SchemaVersion
+	for i, v := range r.schemas[name] {
+		if !v.Blocks.Contains(block) {
+			continue
+		}
+		if v.Address == address && address != "" {
+			return v, true
+		}
+		if v.Address == "" && generic == nil {
+			generic = &r.schemas[name][i]
+		}
+	}
+	if generic == nil {
+		return SchemaVersion{}, false
+	}
+	return *generic, true
+}
+
+// DecodeEvent is Decode for a log in JSON, returning the decoded event as
+// JSON. The log must carry its block as "blockNumber" (hex or decimal, as
+// in eth_getLogs) or "block_number".
+func (r *SchemaRegistry) DecodeEvent(name, logJSON string) (string, error) {
+	l, hasBlock, err := parseRawLog(logJSON)
+	if err != nil {
+		return "", err
+	}
+	if !hasBlock {
+		return "", fmt.Errorf("chaincodec: log block number: missing")
+	}
+	e, err := r.Decode(name, l)
+	if err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(e)
+	return string(out), err
+}
+
+// Decode decodes l like DecodeRawLog, with the version of schema name
+// covering the log's block and address, and sets SchemaVersion.
+func (r *SchemaRegistry) Decode(name string, l RawLog) (*DecodedEvent, error) {
+	v, ok := r.Lookup(name, l.Address, l.BlockNumber)
+	if !ok {
+		return nil, fmt.Errorf("%w: schema %s block %d", ErrNoSchemaVersion, name, l.BlockNumber)
+	}
+	if v.evmErr != nil {
+		return nil, v.evmErr
+	}
+	e, err := v.evm.decode(l)
+	if err != nil {
+		return nil, err
+	}
+	e.SchemaVersion = v.Version
+	return e, nil
+}
+
+// parseBlock reads a block number encoded as a JSON number, a decimal
+// string or a 0x-prefixed hex string.
+func parseBlock(raw json.RawMessage) (uint64, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, errors.New("missing")
+	}
+	s := strings.Trim(string(raw), `"`)
+	if h, ok := strings.CutPrefix(s, "0x"); ok {
+		return strconv.ParseUint(h, 16, 64)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
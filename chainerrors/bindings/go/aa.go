@@ -0,0 +1,264 @@
+package chainerrors
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/DarshanKumar89/chainfoundry/chaincodec/abi"
+)
+
+// Kinds assigned to ERC-4337 account abstraction failures, by the entity
+// the EntryPoint blames.
+const (
+	// KindAAFactory is a failure deploying the account from initCode
+	// (AA1x).
+	KindAAFactory = "aa_factory"
+	// KindAAAccount is a failure validating the account (AA2x).
+	KindAAAccount = "aa_account"
+	// KindAAPaymaster is a failure validating or charging the paymaster
+	// (AA3x, AA5x).
+	KindAAPaymaster = "aa_paymaster"
+	// KindAAEntryPoint is a gas accounting or bundle failure inside the
+	// EntryPoint (AA4x, AA9x).
+	KindAAEntryPoint = "aa_entrypoint"
+	// KindAABundler is a bundler rejecting a UserOperation before the
+	// EntryPoint sees it (opcode or stake rules, throttling).
+	KindAABundler = "aa_bundler"
+)
+
+// AAFailure is the structured form of an account abstraction failure.
+type AAFailure struct {
+	// Code is the EntryPoint's AAxx code, e.g. "AA31"; empty for bundler
+	// rejections that carry none.
+	Code string `json:"code,omitempty"`
+	// Entity is who is at fault: "factory", "account", "paymaster",
+	// "entrypoint" or "bundler".
+	Entity string `json:"entity"`
+	// OpIndex is the failing UserOperation's index in the bundle, when
+	// the EntryPoint reported it.
+	OpIndex *uint64 `json:"op_index,omitempty"`
+	// Reason is the EntryPoint's or bundler's message as sent.
+	Reason string `json:"reason"`
+	// Inner is the decoded revert of the account or paymaster call, from
+	// EntryPoint v0.7's FailedOpWithRevert.
+	Inner *DecodedError `json:"inner,omitempty"`
+	// RPCCode is the bundler's JSON-RPC error code (-32500 to -32507), if
+	// the failure came from a bundler.
+	RPCCode int `json:"rpc_code,omitempty"`
+}
+
+// EntryPoint revert envelopes.
+var (
+	failedOpSelector           = aaSelector("FailedOp(uint256,string)")
+	failedOpWithRevertSelector = aaSelector("FailedOpWithRevert(uint256,string,bytes)")
+	signatureFailedSelector    = aaSelector("SignatureValidationFailed(address)")
+)
+
+func aaSelector(sig string) string {
+	return hex.EncodeToString(abi.Keccak256([]byte(sig))[:4])
+}
+
+type aaCode struct {
+	meaning    string
+	suggestion string
+}
+
+// aaCodes explains the EntryPoint's AAxx codes (v0.6 and v0.7).
+var aaCodes = map[string]aaCode{
+	"AA10": {"sender already constructed", "Remove initCode: the account is already deployed."},
+	"AA13": {"initCode failed or ran out of gas", "Check the factory call in initCode and raise verificationGasLimit."},
+	"AA14": {"initCode must return sender", "The factory deployed a different address than sender; derive sender with the factory's getAddress for the same owner and salt."},
+	"AA15": {"initCode must create sender", "The factory returned without deploying the account; check the factory address and calldata in initCode."},
+	"AA20": {"account not deployed", "Supply initCode (factory and calldata) for the first UserOperation of an undeployed account."},
+	"AA21": {"account didn't pay prefund", "Fund the account with enough ETH (or its EntryPoint deposit) to cover maxFeePerGas × total gas, or use a paymaster."},
+	"AA22": {"account signature expired or not due", "The validUntil/validAfter window in the signature does not include the current block time; sign again."},
+	"AA23": {"account validation reverted or ran out of gas", "validateUserOp reverted: check the signature scheme and raise verificationGasLimit; the inner revert has details."},
+	"AA24": {"account signature error", "The account rejected the signature: sign the UserOperation hash for this EntryPoint and chain ID with the account's owner key."},
+	"AA25": {"invalid account nonce", "Fetch the nonce from EntryPoint.getNonce(sender, key) and resubmit; another UserOperation may have used it."},
+	"AA26": {"over verificationGasLimit", "Raise verificationGasLimit; account validation used more gas than it allows."},
+	"AA30": {"paymaster not deployed", "The paymaster address in paymasterAndData has no code on this chain."},
+	"AA31": {"paymaster deposit too low", "Top up the paymaster's EntryPoint deposit (EntryPoint.depositTo) to cover the operation's maximum cost."},
+	"AA32": {"paymaster signature expired or not due", "Request fresh paymaster data: its validUntil/validAfter window excludes the current block time."},
+	"AA33": {"paymaster validation reverted or ran out of gas", "validatePaymasterUserOp reverted: check the sponsorship policy and raise paymasterVerificationGasLimit; the inner revert has details."},
+	"AA34": {"paymaster signature error", "The paymaster rejected its signature: request new paymasterAndData for this exact UserOperation."},
+	"AA36": {"over paymasterVerificationGasLimit", "Raise paymasterVerificationGasLimit; paymaster validation used more gas than it allows."},
+	"AA40": {"over verificationGasLimit", "Raise verificationGasLimit; validation used more gas than it allows."},
+	"AA41": {"too little verificationGas", "Raise verificationGasLimit so enough gas remains for the paymaster's postOp."},
+	"AA50": {"paymaster postOp reverted", "The paymaster's postOp reverted (e.g. it could not collect ERC-20 payment); check the sender's token balance and allowance."},
+	"AA51": {"prefund below actual gas cost", "Raise the gas limits or prefund: the operation cost more than was reserved."},
+	"AA90": {"invalid beneficiary", "Pass a non-zero beneficiary address to handleOps."},
+	"AA91": {"failed send to beneficiary", "The beneficiary cannot receive ETH; use an EOA or a contract with a payable receive."},
+	"AA92": {"internal call only", "innerHandleOp may only be called by the EntryPoint itself."},
+	"AA93": {"invalid paymasterAndData", "paymasterAndData must be empty or start with a 20-byte paymaster address (v0.7: followed by two 16-byte gas limits)."},
+	"AA94": {"gas values overflow", "A gas field exceeds uint120; check the UserOperation's gas values."},
+	"AA95": {"out of gas", "The bundle transaction ran out of gas; the bundler must raise the handleOps gas limit."},
+	"AA96": {"invalid aggregator", "The aggregator is address(1) or does not match the account's signature aggregator."},
+}
+
+var aaCodePattern = regexp.MustCompile(`\bAA\d\d\b`)
+
+// aaEntity maps a code's leading digit to the entity at fault.
+func aaEntity(code string) (kind, entity string) {
+	switch code[2] {
+	case '1':
+		return KindAAFactory, "factory"
+	case '2':
+		return KindAAAccount, "account"
+	case '3', '5':
+		return KindAAPaymaster, "paymaster"
+	}
+	return KindAAEntryPoint, "entrypoint"
+}
+
+// DecodeUserOp decodes revert data from an EntryPoint (handleOps,
+// simulateValidation): the FailedOp and FailedOpWithRevert envelopes are
+// classified by their AAxx code into the aa_* kinds, with the failure in
+// AA and a suggestion for the fix. Other data is decoded like Decode.
+func DecodeUserOp(hexData string) (*DecodedError, error) {
+	d, err := Decode(hexData)
+	if err != nil {
+		return nil, err
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(hexData), "0x"))
+	if err != nil || len(data) < 4 {
+		return d, nil
+	}
+	switch hex.EncodeToString(data[:4]) {
+	case failedOpSelector:
+		v, err := abi.Decode(mustTypes("uint256,string"), data[4:])
+		if err != nil {
+			return d, nil
+		}
+		return aaClassified(d, AAFailure{OpIndex: opIndex(v[0]), Reason: v[1].(string)}), nil
+	case failedOpWithRevertSelector:
+		v, err := abi.Decode(mustTypes("uint256,string,bytes"), data[4:])
+		if err != nil {
+			return d, nil
+		}
+		f := AAFailure{OpIndex: opIndex(v[0]), Reason: v[1].(string)}
+		if inner, ok := v[2].([]byte); ok {
+			if f.Inner, err = Decode(hex.EncodeToString(inner)); err != nil {
+				return nil, err
+			}
+		}
+		return aaClassified(d, f), nil
+	case signatureFailedSelector:
+		v, err := abi.Decode(mustTypes("address"), data[4:])
+		if err != nil {
+			return d, nil
+		}
+		out := classified(d, KindAAAccount, fmt.Sprintf("signature aggregator %v rejected the bundle's signatures", v[0]),
+			"Re-aggregate the signatures of the UserOperations using this aggregator, or submit them without it.", 1.0)
+		out.AA = &AAFailure{Entity: "aggregator", Reason: "SignatureValidationFailed"}
+		return out, nil
+	}
+	return d, nil
+}
+
+// Bundler JSON-RPC error codes (ERC-7769).
+const (
+	BundlerRejectedByEntryPoint  = -32500
+	BundlerRejectedByPaymaster   = -32501
+	BundlerBannedOpcode          = -32502
+	BundlerShortDeadline         = -32503
+	BundlerBannedOrThrottled     = -32504
+	BundlerStakeTooLow           = -32505
+	BundlerUnsupportedAggregator = -32506
+	BundlerInvalidSignature      = -32507
+)
+
+var bundlerCodes = map[int]aaCode{
+	BundlerBannedOpcode:          {"UserOperation uses a banned opcode or storage access during validation", "Validation may not read TIMESTAMP, NUMBER, BALANCE and similar opcodes, or storage outside the sender's; move that logic to execution."},
+	BundlerShortDeadline:         {"UserOperation validity window is too short or already expired", "Widen validUntil so the operation stays valid until it can be included."},
+	BundlerBannedOrThrottled:     {"paymaster, factory or aggregator is banned or throttled", "Too many of this entity's operations failed; wait for the bundler's reputation to recover or use another paymaster or bundler."},
+	BundlerStakeTooLow:           {"paymaster, factory or aggregator stake is too low", "Stake the entity in the EntryPoint (addStake) with at least the bundler's minimum stake and unstake delay."},
+	BundlerUnsupportedAggregator: {"signature aggregator not supported", "Use a bundler that supports the aggregator, or submit without it."},
+	BundlerInvalidSignature:      {"invalid UserOperation signature or paymaster signature", "Sign the UserOperation hash for this EntryPoint and chain ID."},
+}
+
+// DecodeBundlerError classifies an error a bundler answered
+// eth_sendUserOperation or eth_estimateUserOperationGas with: its JSON-RPC
+// code, message (which usually carries the EntryPoint's AAxx reason) and
+// data (the revert hex, or an object; "" if absent).
+func DecodeBundlerError(code int, message, data string) (*DecodedError, error) {
+	var revert string
+	if strings.HasPrefix(strings.TrimSpace(data), `"0x`) {
+		_ = json.Unmarshal([]byte(data), &revert)
+	} else if strings.HasPrefix(data, "0x") {
+		revert = data
+	}
+	d, err := DecodeUserOp(revert)
+	if err != nil {
+		return nil, err
+	}
+	if d.AA != nil {
+		d.AA.RPCCode = code
+		return d, nil
+	}
+	if aaCodePattern.MatchString(message) {
+		out := aaClassified(d, AAFailure{Reason: message})
+		out.AA.RPCCode = code
+		return out, nil
+	}
+	if b, ok := bundlerCodes[code]; ok {
+		out := classified(d, KindAABundler, b.meaning+": "+message, b.suggestion, 0.9)
+		out.AA = &AAFailure{Entity: "bundler", Reason: message, RPCCode: code}
+		return out, nil
+	}
+	if code == BundlerRejectedByPaymaster {
+		out := classified(d, KindAAPaymaster, "paymaster rejected the UserOperation: "+message,
+			"The paymaster's policy refused to sponsor this operation; check its sponsorship rules or pay gas from the account.", 0.8)
+		out.AA = &AAFailure{Entity: "paymaster", Reason: message, RPCCode: code}
+		return out, nil
+	}
+	return d, nil
+}
+
+// aaClassified classifies d by the AAxx code in f.Reason.
+func aaClassified(d *DecodedError, f AAFailure) *DecodedError {
+	f.Code = aaCodePattern.FindString(f.Reason)
+	if f.Code == "" {
+		f.Entity = "entrypoint"
+		out := classified(d, KindAAEntryPoint, "UserOperation failed: "+f.Reason,
+			"Simulate the operation with simulateValidation to see which check fails.", 0.7)
+		out.AA = &f
+		return out
+	}
+	kind, entity := aaEntity(f.Code)
+	f.Entity = entity
+	msg := f.Reason
+	suggestion := "Simulate the operation with simulateValidation to see which check fails."
+	confidence := 0.8
+	if c, ok := aaCodes[f.Code]; ok {
+		msg = f.Code + " " + c.meaning
+		suggestion = c.suggestion
+		confidence = 1.0
+	}
+	if f.Inner != nil && f.Inner.Message != nil {
+		msg += ": " + *f.Inner.Message
+	}
+	out := classified(d, kind, msg, suggestion, confidence)
+	out.AA = &f
+	return out
+}
+
+func opIndex(v interface{}) *uint64 {
+	n, ok := v.(*big.Int)
+	if !ok || !n.IsUint64() {
+		return nil
+	}
+	i := n.Uint64()
+	return &i
+}
+
+func mustTypes(s string) []abi.Type {
+	t, err := abi.ParseTypes(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
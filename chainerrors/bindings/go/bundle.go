@@ -0,0 +1,342 @@
+package chainerrors
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DarshanKumar89/chainfoundry/chaincodec/abi"
+)
+
+// Signature is one error signature in a bundle. The JSON form matches
+// schemas/errors/known-errors.json.
+type Signature struct {
+	Name       string  `json:"name"`
+	Signature  string  `json:"signature"`
+	Selector   [4]byte `json:"selector"`
+	Source     string  `json:"source"`
+	Suggestion *string `json:"suggestion"`
+}
+
+// LoadSignatureBundle replaces the default namespace's offline signature
+// bundle with the one at path and returns the number of signatures loaded. Bundles are JSON,
+// either an array of signatures in the known-errors.json format or an
+// object with a "signatures" array, or plain text with one signature per
+// line, optionally preceded by its selector ("0x08c379a0 Error(string)");
+// blank lines and lines starting with # are skipped. A .gz suffix means
+// the file is gzip-compressed.
+//
+// Decode consults the bundle for selectors the native registry does not
+// recognise, so air-gapped deployments can resolve third-party custom
+// errors by shipping an updated bundle.
+func LoadSignatureBundle(path string) (int, error) {
+	return defaultNamespace.LoadSignatureBundle(path)
+}
+
+// LoadSignatureBundle replaces the namespace's offline signature bundle;
+// see the package-level LoadSignatureBundle.
+func (ns *Namespace) LoadSignatureBundle(path string) (int, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return 0, fmt.Errorf("chainerrors: %s: %w", path, err)
+		}
+		if raw, err = io.ReadAll(zr); err != nil {
+			return 0, fmt.Errorf("chainerrors: %s: %w", path, err)
+		}
+	}
+	sigs, err := parseBundle(raw)
+	if err != nil {
+		return 0, fmt.Errorf("chainerrors: %s: %w", path, err)
+	}
+	bundle := map[[4]byte][]Signature{}
+	for _, s := range sigs {
+		bundle[s.Selector] = append(bundle[s.Selector], s)
+	}
+	ns.mu.Lock()
+	ns.bundle = bundle
+	ns.mu.Unlock()
+	ns.purgeDecodeCache()
+	return len(sigs), nil
+}
+
+// SaveSignatureBundle writes the default namespace's bundle plus every
+// signature it learned by online lookup to path in the JSON format
+// LoadSignatureBundle reads, so a connected machine can produce bundle
+// updates for air-gapped ones.
+func SaveSignatureBundle(path string) error {
+	return defaultNamespace.SaveSignatureBundle(path)
+}
+
+// SaveSignatureBundle writes the namespace's bundle and online-learned
+// signatures to path.
+func (ns *Namespace) SaveSignatureBundle(path string) error {
+	ns.mu.RLock()
+	var all []Signature
+	seen := map[string]bool{}
+	for _, m := range []map[[4]byte][]Signature{ns.bundle, ns.online} {
+		for _, sigs := range m {
+			for _, s := range sigs {
+				if !seen[s.Signature] {
+					seen[s.Signature] = true
+					all = append(all, s)
+				}
+			}
+		}
+	}
+	ns.mu.RUnlock()
+	sort.Slice(all, func(i, j int) bool { return all[i].Signature < all[j].Signature })
+	out, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(out, '\n'), 0o644)
+}
+
+func parseBundle(raw []byte) ([]Signature, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		var sigs []Signature
+		if trimmed[0] == '{' {
+			var env struct {
+				Signatures []Signature `json:"signatures"`
+			}
+			if err := json.Unmarshal(trimmed, &env); err != nil {
+				return nil, err
+			}
+			sigs = env.Signatures
+		} else if err := json.Unmarshal(trimmed, &sigs); err != nil {
+			return nil, err
+		}
+		for i := range sigs {
+			if err := sigs[i].check(true); err != nil {
+				return nil, err
+			}
+		}
+		return sigs, nil
+	}
+
+	var sigs []Signature
+	sc := bufio.NewScanner(bytes.NewReader(raw))
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		s := Signature{Signature: text, Source: "bundle"}
+		if f := strings.Fields(text); len(f) == 2 && strings.HasPrefix(f[0], "0x") {
+			sel, err := hex.DecodeString(f[0][2:])
+			if err != nil || len(sel) != 4 {
+				return nil, fmt.Errorf("line %d: invalid selector %q", line, f[0])
+			}
+			copy(s.Selector[:], sel)
+			s.Signature = f[1]
+			if err := s.check(true); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		} else if err := s.check(false); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		sigs = append(sigs, s)
+	}
+	return sigs, sc.Err()
+}
+
+// check canonicalises the signature, fills Name, and computes the
+// selector, or with verify confirms the one given.
+func (s *Signature) check(verify bool) error {
+	f, err := abi.ParseFunction(s.Signature)
+	if err != nil {
+		return err
+	}
+	var sel [4]byte
+	copy(sel[:], f.Selector())
+	if verify && s.Selector != ([4]byte{}) && s.Selector != sel {
+		return fmt.Errorf("selector 0x%x does not match %s", s.Selector, s.Signature)
+	}
+	s.Signature, s.Selector = f.Signature(), sel
+	if s.Name == "" {
+		s.Name = f.Name
+	}
+	return nil
+}
+
+// OpenChainURL is the openchain.xyz signature database lookup endpoint.
+const OpenChainURL = "https://api.openchain.xyz/signature-database/v1/lookup"
+
+// OnlineLookup configures resolution of selectors found in neither the
+// native registry nor the bundle against an online signature database.
+type OnlineLookup struct {
+	// URL is an openchain-compatible lookup endpoint. Default OpenChainURL.
+	URL string
+	// Interval is the minimum time between requests. Lookups attempted
+	// sooner are skipped rather than delayed, so Decode never waits on
+	// the limiter. Default 1s.
+	Interval time.Duration
+	// Timeout bounds one request. Default 5s.
+	Timeout time.Duration
+	// Client is the HTTP client; default http.DefaultClient.
+	Client *http.Client
+}
+
+// EnableOnlineLookup turns on online resolution for the default
+// namespace. It is off by default, and results are cached for the life of
+// the process.
+func EnableOnlineLookup(cfg OnlineLookup) { defaultNamespace.EnableOnlineLookup(cfg) }
+
+// DisableOnlineLookup turns online resolution off for the default
+// namespace.
+func DisableOnlineLookup() { defaultNamespace.DisableOnlineLookup() }
+
+// EnableOnlineLookup turns on online resolution for the namespace, whose
+// results and rate limit are kept apart from other namespaces'.
+func (ns *Namespace) EnableOnlineLookup(cfg OnlineLookup) {
+	if cfg.URL == "" {
+		cfg.URL = OpenChainURL
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	ns.onlineMu.Lock()
+	ns.lookup = &cfg
+	ns.onlineMu.Unlock()
+	ns.purgeDecodeCache()
+}
+
+// DisableOnlineLookup turns online resolution off for the namespace.
+func (ns *Namespace) DisableOnlineLookup() {
+	ns.onlineMu.Lock()
+	ns.lookup = nil
+	ns.onlineMu.Unlock()
+	ns.purgeDecodeCache()
+}
+
+// candidates returns the known signatures for sel: bundle first, then
+// cached online results, then a fresh online lookup if allowed.
+func (ns *Namespace) candidates(sel [4]byte) []Signature {
+	ns.mu.RLock()
+	out := append(append([]Signature(nil), ns.bundle[sel]...), ns.online[sel]...)
+	ns.mu.RUnlock()
+	if len(out) > 0 {
+		return out
+	}
+	found, ok := ns.lookupOnline(sel)
+	if !ok {
+		return nil
+	}
+	ns.mu.Lock()
+	ns.online[sel] = found
+	ns.mu.Unlock()
+	return found
+}
+
+// lookupOnline queries the configured database. ok is false when lookup
+// is disabled, rate limited or failed, and the selector should be retried
+// later.
+func (ns *Namespace) lookupOnline(sel [4]byte) ([]Signature, bool) {
+	ns.onlineMu.Lock()
+	cfg := ns.lookup
+	if cfg == nil || ns.onlineMiss[sel] || time.Since(ns.onlineLast) < cfg.Interval {
+		ns.onlineMu.Unlock()
+		return nil, false
+	}
+	ns.onlineLast = time.Now()
+	ns.onlineMu.Unlock()
+
+	key := fmt.Sprintf("0x%x", sel)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.URL+"?filter=true&function="+url.QueryEscape(key), nil)
+	if err != nil {
+		return nil, false
+	}
+	resp, err := cfg.Client.Do(req)
+	if err != nil {
+		return nil, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+	var body struct {
+		OK     bool `json:"ok"`
+		Result struct {
+			Function map[string][]struct {
+				Name string `json:"name"`
+			} `json:"function"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil || !body.OK {
+		return nil, false
+	}
+	var out []Signature
+	for _, r := range body.Result.Function[key] {
+		s := Signature{Signature: r.Name, Selector: sel, Source: "online"}
+		if s.check(true) == nil {
+			out = append(out, s)
+		}
+	}
+	if len(out) == 0 {
+		ns.onlineMu.Lock()
+		ns.onlineMiss[sel] = true
+		ns.onlineMu.Unlock()
+	}
+	return out, true
+}
+
+// resolveUnknown upgrades a raw revert whose selector the native registry
+// did not know to a custom error, using the first bundle or online
+// signature whose parameters decode the revert data.
+func (ns *Namespace) resolveUnknown(d *DecodedError) *DecodedError {
+	if d.Kind != "raw_revert" || d.Selector == nil {
+		return d
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(d.RawData, "0x"))
+	if err != nil || len(data) < 4 {
+		return d
+	}
+	var sel [4]byte
+	copy(sel[:], data)
+	cands := ns.candidates(sel)
+	for _, s := range cands {
+		f, err := abi.ParseFunction(s.Signature)
+		if err != nil {
+			continue
+		}
+		if _, err := abi.Decode(f.Inputs, data[4:]); err != nil {
+			continue
+		}
+		suggestion := fmt.Sprintf("Resolved as %s from the %s signature set.", s.Signature, s.Source)
+		if s.Suggestion != nil {
+			suggestion = *s.Suggestion
+		}
+		// Selector collisions make a match less certain.
+		confidence := 0.8
+		if len(cands) > 1 {
+			confidence = 0.5
+		}
+		return classified(d, "custom_error", s.Name, suggestion, confidence)
+	}
+	return d
+}
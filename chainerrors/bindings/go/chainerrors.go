@@ -30,6 +30,12 @@ type DecodedError struct {
 	Selector   *string  `json:"selector"`
 	Suggestion *string  `json:"suggestion"`
 	Confidence float64  `json:"confidence"`
+	// Source is the Solidity code that raised the error, set by
+	// Sources.Annotate when contract sources are available.
+	Source *SourceLocation `json:"source,omitempty"`
+	// AA is the structured failure of an ERC-4337 UserOperation, set by
+	// DecodeUserOp and DecodeBundlerError.
+	AA *AAFailure `json:"aa,omitempty"`
 }
 
 // Version returns the chainerrors library version.
@@ -46,8 +52,14 @@ func lastError() error {
 }
 
 // Decode decodes EVM revert data from a hex string (with or without "0x" prefix).
-// Pass an empty string for an empty revert.
+// Pass an empty string for an empty revert. Selectors the native registry does
+// not know are looked up in the signature bundle (see LoadSignatureBundle) of
+// the default namespace; use Namespace.Decode for another.
 func Decode(hexData string) (*DecodedError, error) {
+	return defaultNamespace.Decode(hexData)
+}
+
+func decodeNative(hexData string) (*DecodedError, error) {
 	cHex := C.CString(hexData)
 	defer C.free(unsafe.Pointer(cHex))
 
@@ -70,3 +82,27 @@ func Decode(hexData string) (*DecodedError, error) {
 func PanicMeaning(code uint32) string {
 	return C.GoString(C.chainerrors_panic_meaning(C.uint32_t(code)))
 }
+
+// PanicCode describes a Solidity panic code.
+type PanicCode struct {
+	Code       uint32 `json:"code"`
+	Hex        string `json:"hex"`
+	Name       string `json:"name"`
+	Meaning    string `json:"meaning"`
+	Suggestion string `json:"suggestion"`
+}
+
+// ListPanicCodes returns every known Solidity panic code in code order.
+func ListPanicCodes() ([]PanicCode, error) {
+	ptr := C.chainerrors_list_panic_codes()
+	if ptr == nil {
+		return nil, lastError()
+	}
+	defer C.chainerrors_free_string(ptr)
+
+	var codes []PanicCode
+	if err := json.Unmarshal([]byte(C.GoString(ptr)), &codes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
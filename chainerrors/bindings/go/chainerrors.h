@@ -33,6 +33,13 @@ char* chainerrors_decode(const char* hex_data);
  */
 const char* chainerrors_panic_meaning(uint32_t code);
 
+/**
+ * List all known Solidity panic codes.
+ * Returns a JSON array of {"code", "hex", "name", "meaning", "suggestion"}
+ * or NULL on error. Caller frees with chainerrors_free_string().
+ */
+char* chainerrors_list_panic_codes(void);
+
 #ifdef __cplusplus
 }
 #endif
@@ -0,0 +1,157 @@
+package chainerrors
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"strings"
+	"sync"
+)
+
+// decodeCachePrefix is how much of the revert data (in hex characters,
+// after the selector) is used verbatim in a cache key; longer payloads
+// are keyed by the prefix plus a digest of the rest.
+const decodeCachePrefix = 512
+
+// DecodeCacheStats reports how effective a namespace's decode cache is.
+type DecodeCacheStats struct {
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	Entries  int     `json:"entries"`
+	Capacity int     `json:"capacity"`
+	HitRate  float64 `json:"hit_rate"`
+}
+
+// decodeCache is an LRU of decode results keyed by revert payload.
+type decodeCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+	hits     uint64
+	misses   uint64
+}
+
+type decodeEntry struct {
+	key string
+	d   DecodedError
+}
+
+func newDecodeCache(capacity int) *decodeCache {
+	return &decodeCache{capacity: capacity, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// decodeKey keys revert data by its selector and payload: lower-cased,
+// without 0x, the first decodeCachePrefix hex characters after the selector
+// and a digest of anything beyond, so equal payloads share an entry and
+// distinct ones never do.
+func decodeKey(hexData string) string {
+	h := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(hexData), "0x"), "0X"))
+	if len(h) <= 8+decodeCachePrefix {
+		return h
+	}
+	sum := sha256.Sum256([]byte(h[8+decodeCachePrefix:]))
+	return h[:8+decodeCachePrefix] + "#" + string(sum[:])
+}
+
+func (c *decodeCache) get(key string) (*DecodedError, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.ll.MoveToFront(el)
+	d := el.Value.(*decodeEntry).d
+	return &d, true
+}
+
+func (c *decodeCache) put(key string, d *DecodedError) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*decodeEntry).d = *d
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&decodeEntry{key: key, d: *d})
+	for c.ll.Len() > c.capacity {
+		old := c.ll.Remove(c.ll.Back()).(*decodeEntry)
+		delete(c.items, old.key)
+	}
+}
+
+func (c *decodeCache) purge() {
+	c.mu.Lock()
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+	c.mu.Unlock()
+}
+
+func (c *decodeCache) stats() DecodeCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := DecodeCacheStats{Hits: c.hits, Misses: c.misses, Entries: c.ll.Len(), Capacity: c.capacity}
+	if n := c.hits + c.misses; n > 0 {
+		s.HitRate = float64(c.hits) / float64(n)
+	}
+	return s
+}
+
+// EnableDecodeCache caches the default namespace's decode results; see
+// Namespace.EnableDecodeCache.
+func EnableDecodeCache(capacity int) { defaultNamespace.EnableDecodeCache(capacity) }
+
+// GetDecodeCacheStats returns the default namespace's decode cache stats.
+func GetDecodeCacheStats() DecodeCacheStats { return defaultNamespace.DecodeCacheStats() }
+
+// EnableDecodeCache keeps the results of the last capacity distinct revert
+// payloads decoded in the namespace, so repeated identical reverts (bots
+// retrying the same failing call) skip native decoding and signature
+// resolution. Zero or less disables the cache. Loading a bundle or
+// changing online lookup clears it, and raw reverts that online lookup
+// may still resolve are not cached. Callers get their own copy of each
+// result.
+func (ns *Namespace) EnableDecodeCache(capacity int) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if capacity <= 0 {
+		ns.cache = nil
+		return
+	}
+	ns.cache = newDecodeCache(capacity)
+}
+
+// DecodeCacheStats returns hit and miss counts since the cache was
+// enabled; the zero value if it is not.
+func (ns *Namespace) DecodeCacheStats() DecodeCacheStats {
+	if c := ns.decodeCache(); c != nil {
+		return c.stats()
+	}
+	return DecodeCacheStats{}
+}
+
+func (ns *Namespace) decodeCache() *decodeCache {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+	return ns.cache
+}
+
+// purgeDecodeCache drops cached results after the signatures the
+// namespace resolves with changed.
+func (ns *Namespace) purgeDecodeCache() {
+	if c := ns.decodeCache(); c != nil {
+		c.purge()
+	}
+}
+
+// cacheable reports whether d can be served again for the same payload.
+func (ns *Namespace) cacheable(d *DecodedError) bool {
+	if d.Kind != "raw_revert" {
+		return true
+	}
+	ns.onlineMu.Lock()
+	defer ns.onlineMu.Unlock()
+	return ns.lookup == nil
+}
@@ -0,0 +1,87 @@
+package chainerrors
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Public structs render the same way everywhere: String gives one line of
+// the type name followed by key=value pairs in field order, omitting unset
+// optional fields, and MarshalJSON keeps the declared field order and
+// writes hex strings lower-case with a 0x prefix.
+
+// normHex lower-cases a 0x-prefixed hex string. Other strings are returned
+// trimmed but otherwise unchanged.
+func normHex(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '0' || (s[1] != 'x' && s[1] != 'X') {
+		return s
+	}
+	for _, r := range s[2:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return s
+		}
+	}
+	return "0x" + strings.ToLower(s[2:])
+}
+
+// kv renders name followed by the key=value pairs in kvs, skipping empty
+// values and quoting those that contain spaces.
+func kv(name string, kvs ...string) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for i := 0; i+1 < len(kvs); i += 2 {
+		v := kvs[i+1]
+		if v == "" {
+			continue
+		}
+		if strings.ContainsAny(v, " \t\n\"=") {
+			v = strconv.Quote(v)
+		}
+		b.WriteString(" " + kvs[i] + "=" + v)
+	}
+	return b.String()
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// String summarises the decoded error; the suggestion is left out.
+func (d DecodedError) String() string {
+	var src string
+	if d.Source != nil {
+		src = d.Source.File + ":" + strconv.Itoa(d.Source.Line)
+	}
+	var sel string
+	if d.Selector != nil {
+		sel = normHex(*d.Selector)
+	}
+	var aa string
+	if d.AA != nil {
+		aa = d.AA.Code
+	}
+	return kv("error",
+		"kind", d.Kind,
+		"aa", aa,
+		"message", deref(d.Message),
+		"selector", sel,
+		"confidence", strconv.FormatFloat(d.Confidence, 'f', 2, 64),
+		"source", src)
+}
+
+// MarshalJSON writes the decoded error with its data and selector
+// normalised.
+func (d DecodedError) MarshalJSON() ([]byte, error) {
+	type plain DecodedError
+	d.RawData = normHex(d.RawData)
+	if d.Selector != nil {
+		sel := normHex(*d.Selector)
+		d.Selector = &sel
+	}
+	return json.Marshal(plain(d))
+}
@@ -1,3 +1,15 @@
 module github.com/DarshanKumar89/chainfoundry/chainerrors
 
 go 1.21
+
+require (
+	github.com/DarshanKumar89/chainfoundry/chaincodec v0.0.0
+	github.com/DarshanKumar89/chainfoundry/chainkit v0.0.0
+	github.com/DarshanKumar89/chainfoundry/chainrpc v0.0.0
+)
+
+replace (
+	github.com/DarshanKumar89/chainfoundry/chaincodec => ../../../chaincodec/bindings/go
+	github.com/DarshanKumar89/chainfoundry/chainkit => ../../../chainkit
+	github.com/DarshanKumar89/chainfoundry/chainrpc => ../../../chainrpc/bindings/go
+)
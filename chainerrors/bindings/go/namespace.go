@@ -0,0 +1,64 @@
+package chainerrors
+
+import (
+	"sync"
+	"time"
+)
+
+// Namespace is an isolated error registry: its own signature bundle,
+// online lookup configuration and lookup cache. A multi-tenant service
+// gives each tenant a Namespace so one tenant's bundle or learned
+// signatures never change how another's reverts decode.
+//
+// The package-level functions (Decode, LoadSignatureBundle,
+// EnableOnlineLookup, …) operate on a default namespace. The native
+// registry of built-in errors is shared by every namespace; it is
+// read-only.
+type Namespace struct {
+	mu     sync.RWMutex
+	bundle map[[4]byte][]Signature
+	online map[[4]byte][]Signature
+	cache  *decodeCache
+
+	onlineMu   sync.Mutex
+	lookup     *OnlineLookup
+	onlineLast time.Time
+	// onlineMiss remembers selectors the database did not know.
+	onlineMiss map[[4]byte]bool
+}
+
+// NewNamespace returns an empty namespace with online lookup disabled.
+func NewNamespace() *Namespace {
+	return &Namespace{
+		bundle:     map[[4]byte][]Signature{},
+		online:     map[[4]byte][]Signature{},
+		onlineMiss: map[[4]byte]bool{},
+	}
+}
+
+var defaultNamespace = NewNamespace()
+
+// DefaultNamespace returns the namespace the package-level functions use.
+func DefaultNamespace() *Namespace { return defaultNamespace }
+
+// Decode decodes revert data like the package-level Decode, resolving
+// unknown selectors against the namespace's bundle and online lookup.
+func (ns *Namespace) Decode(hexData string) (*DecodedError, error) {
+	cache := ns.decodeCache()
+	var key string
+	if cache != nil {
+		key = decodeKey(hexData)
+		if d, ok := cache.get(key); ok {
+			return d, nil
+		}
+	}
+	d, err := decodeNative(hexData)
+	if err != nil {
+		return nil, err
+	}
+	d = ns.resolveUnknown(d)
+	if cache != nil && ns.cacheable(d) {
+		cache.put(key, d)
+	}
+	return d, nil
+}
@@ -0,0 +1,223 @@
+package chainerrors
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Kinds assigned by DecodeCall in addition to those returned by Decode.
+const (
+	KindPrecompileFailure = "precompile_failure"
+	KindDepositContract   = "deposit_contract"
+)
+
+// DepositContractAddress is the beacon chain deposit contract on mainnet.
+const DepositContractAddress = "0x00000000219ab540356cbb839cbe05303d7705fa"
+
+// CallInfo describes the call frame that failed. To is the address the
+// failing frame called (from a call trace, or the transaction's target)
+// and Input its calldata, both hex-encoded.
+type CallInfo struct {
+	To    string
+	Input string
+}
+
+// DecodeCall decodes revert data like Decode, using the failing call's
+// target to classify failures that carry no revert data of their own:
+// precompile calls (ecrecover, modexp, the bn256 and BLAKE2 precompiles, …)
+// and calls into the beacon deposit contract. EntryPoint failures are
+// classified as by DecodeUserOp.
+func DecodeCall(hexData string, call CallInfo) (*DecodedError, error) {
+	d, err := DecodeUserOp(hexData)
+	if err != nil {
+		return nil, err
+	}
+	if d.AA != nil {
+		return d, nil
+	}
+	to := strings.ToLower(call.To)
+	input, _ := hex.DecodeString(strings.TrimPrefix(call.Input, "0x"))
+	if p, ok := precompiles[precompileIndex(to)]; ok && isEmptyRevert(hexData) {
+		reason := p.check(input)
+		if reason == "" {
+			reason = "insufficient gas or invalid input"
+		}
+		return classified(d, KindPrecompileFailure, fmt.Sprintf("%s precompile failed: %s", p.name, reason), p.suggestion, 0.8), nil
+	}
+	if msg := derefString(d.Message); strings.HasPrefix(msg, "DepositContract:") {
+		return classified(d, KindDepositContract, msg, depositSuggestion(msg), 1.0), nil
+	}
+	if to == DepositContractAddress && isEmptyRevert(hexData) {
+		return classified(d, KindDepositContract, "deposit contract call reverted without a reason",
+			"Call deposit(pubkey, withdrawal_credentials, signature, deposit_data_root) with at least 1 ETH; other selectors are rejected.", 0.7), nil
+	}
+	return d, nil
+}
+
+func classified(d *DecodedError, kind, message, suggestion string, confidence float64) *DecodedError {
+	out := *d
+	out.Kind = kind
+	out.Message = &message
+	out.Suggestion = &suggestion
+	out.Confidence = confidence
+	return &out
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func isEmptyRevert(hexData string) bool {
+	return strings.TrimPrefix(hexData, "0x") == ""
+}
+
+// precompileIndex returns the precompile number for addresses 0x01–0xff,
+// or 0 for anything else.
+func precompileIndex(addr string) int {
+	b, err := hex.DecodeString(strings.TrimPrefix(addr, "0x"))
+	if err != nil || len(b) != 20 {
+		return 0
+	}
+	for _, c := range b[:19] {
+		if c != 0 {
+			return 0
+		}
+	}
+	return int(b[19])
+}
+
+type precompile struct {
+	name       string
+	suggestion string
+	// check returns a specific reason the input is invalid, or "".
+	check func(input []byte) string
+}
+
+func noCheck([]byte) string { return "" }
+
+var precompiles = map[int]precompile{
+	0x01: {"ecrecover", "Pass a 128-byte input (hash, v, r, s) with v = 27 or 28; an invalid signature recovers the zero address.", checkEcrecover},
+	0x02: {"sha256", "Forward more gas to the call; SHA-256 costs 60 + 12 per word.", noCheck},
+	0x03: {"ripemd160", "Forward more gas to the call; RIPEMD-160 costs 600 + 120 per word.", noCheck},
+	0x04: {"identity", "Forward more gas to the call; the identity precompile costs 15 + 3 per word.", noCheck},
+	0x05: {"modexp", "Check base, exponent and modulus lengths; large exponents are priced per EIP-2565 and need much more gas.", checkModexp},
+	0x06: {"bn256Add", "Both inputs must be points on the alt_bn128 curve with coordinates below the field modulus.", checkBNPoints(2)},
+	0x07: {"bn256ScalarMul", "The input point must be on the alt_bn128 curve with coordinates below the field modulus.", checkBNPoints(1)},
+	0x08: {"bn256Pairing", "Input must be a multiple of 192 bytes of valid G1/G2 points; gas is 45000 + 34000 per pair.", checkPairing},
+	0x09: {"blake2f", "Input must be exactly 213 bytes with a final-block flag of 0 or 1.", checkBlake2f},
+	0x0a: {"pointEvaluation", "Check the KZG proof, commitment and that the versioned hash matches the commitment (EIP-4844).", checkPointEvaluation},
+}
+
+func checkEcrecover(in []byte) string {
+	if len(in) > 128 {
+		return ""
+	}
+	padded := make([]byte, 128)
+	copy(padded, in)
+	v := new(big.Int).SetBytes(padded[32:64])
+	if v.Cmp(big.NewInt(27)) != 0 && v.Cmp(big.NewInt(28)) != 0 {
+		return fmt.Sprintf("v = %s, want 27 or 28", v)
+	}
+	return ""
+}
+
+// eip7823MaxLen caps modexp base, exponent and modulus lengths.
+const eip7823MaxLen = 1024
+
+func checkModexp(in []byte) string {
+	padded := make([]byte, 96)
+	copy(padded, in)
+	names := []string{"base", "exponent", "modulus"}
+	for i, name := range names {
+		word := padded[i*32 : (i+1)*32]
+		n := new(big.Int).SetBytes(word)
+		if n.Cmp(big.NewInt(eip7823MaxLen)) > 0 {
+			return fmt.Sprintf("%s length %s exceeds %d bytes", name, n, eip7823MaxLen)
+		}
+	}
+	return ""
+}
+
+var bnFieldModulus, _ = new(big.Int).SetString("21888242871839275222246405745257275088696311157297823662689037025645121582583", 10)
+
+func checkBNPoints(n int) func([]byte) string {
+	return func(in []byte) string {
+		padded := make([]byte, 64*n)
+		copy(padded, in)
+		for i := 0; i < n; i++ {
+			x := new(big.Int).SetBytes(padded[i*64 : i*64+32])
+			y := new(big.Int).SetBytes(padded[i*64+32 : i*64+64])
+			if x.Cmp(bnFieldModulus) >= 0 || y.Cmp(bnFieldModulus) >= 0 {
+				return fmt.Sprintf("point %d coordinate exceeds field modulus", i+1)
+			}
+			if x.Sign() == 0 && y.Sign() == 0 {
+				continue // point at infinity
+			}
+			// y² = x³ + 3 (mod p)
+			lhs := new(big.Int).Exp(y, big.NewInt(2), bnFieldModulus)
+			rhs := new(big.Int).Exp(x, big.NewInt(3), bnFieldModulus)
+			rhs.Add(rhs, big.NewInt(3)).Mod(rhs, bnFieldModulus)
+			if lhs.Cmp(rhs) != 0 {
+				return fmt.Sprintf("point %d is not on the curve", i+1)
+			}
+		}
+		return ""
+	}
+}
+
+func checkPairing(in []byte) string {
+	if len(in)%192 != 0 {
+		return fmt.Sprintf("input length %d is not a multiple of 192", len(in))
+	}
+	return ""
+}
+
+func checkBlake2f(in []byte) string {
+	if len(in) != 213 {
+		return fmt.Sprintf("input length %d, want 213", len(in))
+	}
+	if f := in[212]; f > 1 {
+		return fmt.Sprintf("final block flag %d, want 0 or 1", f)
+	}
+	if rounds := binary.BigEndian.Uint32(in[:4]); rounds > 1<<24 {
+		return fmt.Sprintf("%d rounds needs more gas than a block holds", rounds)
+	}
+	return ""
+}
+
+func checkPointEvaluation(in []byte) string {
+	if len(in) != 192 {
+		return fmt.Sprintf("input length %d, want 192", len(in))
+	}
+	return "proof verification failed"
+}
+
+// depositSuggestion maps the deposit contract's revert reasons to fixes.
+func depositSuggestion(msg string) string {
+	m := strings.ToLower(msg)
+	switch {
+	case strings.Contains(m, "deposit value too low"):
+		return "Deposits must be at least 1 ETH."
+	case strings.Contains(m, "deposit value too high"):
+		return "The deposit amount in gwei must fit in 64 bits."
+	case strings.Contains(m, "not multiple of gwei"):
+		return "Send a value that is a whole number of gwei."
+	case strings.Contains(m, "invalid pubkey length"):
+		return "The validator public key must be 48 bytes."
+	case strings.Contains(m, "invalid withdrawal_credentials length"):
+		return "Withdrawal credentials must be 32 bytes."
+	case strings.Contains(m, "invalid signature length"):
+		return "The BLS signature must be 96 bytes."
+	case strings.Contains(m, "deposit_data_root"):
+		return "Recompute deposit_data_root from the exact pubkey, credentials, amount and signature being sent (e.g. with the staking deposit CLI)."
+	case strings.Contains(m, "merkle tree full"):
+		return "The deposit tree is full; no further deposits are possible."
+	}
+	return "Check the deposit data against the deposit contract specification."
+}
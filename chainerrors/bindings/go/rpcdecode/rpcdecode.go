@@ -0,0 +1,40 @@
+// Package rpcdecode registers chainerrors as chainrpc's revert decoder, so
+// *chainrpc.RevertError values carry chainerrors' classification:
+//
+//	import _ "github.com/DarshanKumar89/chainfoundry/chainerrors/rpcdecode"
+package rpcdecode
+
+import (
+	"encoding/hex"
+
+	"github.com/DarshanKumar89/chainfoundry/chainerrors"
+	"github.com/DarshanKumar89/chainfoundry/chainrpc"
+)
+
+func init() {
+	chainrpc.RegisterRevertDecoder(Decode)
+}
+
+// Decode runs data through chainerrors.Decode. Unclassified data yields nil
+// so chainrpc falls back to its built-in decoding.
+func Decode(data []byte) *chainrpc.DecodedRevert {
+	return decode(chainerrors.DefaultNamespace(), data)
+}
+
+// Decoder returns a decoder backed by ns, for use with
+// chainrpc.RevertError.DecodeWith when tenants keep separate registries.
+func Decoder(ns *chainerrors.Namespace) chainrpc.RevertDecoder {
+	return func(data []byte) *chainrpc.DecodedRevert { return decode(ns, data) }
+}
+
+func decode(ns *chainerrors.Namespace, data []byte) *chainrpc.DecodedRevert {
+	d, err := ns.Decode("0x" + hex.EncodeToString(data))
+	if err != nil || d.Message == nil {
+		return nil
+	}
+	out := &chainrpc.DecodedRevert{Kind: d.Kind, Reason: *d.Message}
+	if d.Suggestion != nil {
+		out.Suggestion = *d.Suggestion
+	}
+	return out
+}
@@ -0,0 +1,398 @@
+package chainerrors
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DarshanKumar89/chainfoundry/chaincodec/abi"
+)
+
+// SourceLocation points at the Solidity code that raised an error.
+type SourceLocation struct {
+	File string `json:"file"`
+	// Line and Column are 1-based.
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	// Snippet is the line with two lines of context either side, numbered,
+	// with the located line marked by ">".
+	Snippet string `json:"snippet"`
+	// Exact is true when the location comes from the runtime source map at
+	// the reverting program counter, and false when it was found by
+	// searching the sources for the revert reason or error name.
+	Exact bool `json:"exact"`
+}
+
+// SourceFile is one compiler input file.
+type SourceFile struct {
+	Path    string
+	Content string
+}
+
+// Sources holds the Solidity sources of a contract and, optionally, its
+// runtime bytecode and source map, for mapping errors back to code.
+type Sources struct {
+	files []SourceFile
+	// ids maps compiler source IDs, as used in source maps, to files.
+	ids map[int]int
+	// instr maps a program counter to its instruction index.
+	instr   map[int]int
+	entries []srcEntry
+	errors  map[string]string
+}
+
+// srcEntry is one decoded source map entry.
+type srcEntry struct {
+	start, length, file int
+}
+
+// NewSources returns Sources for files, keyed by source unit name. Source
+// IDs are assigned in sorted name order, as solc does for standard JSON
+// input.
+func NewSources(files map[string]string) *Sources {
+	s := &Sources{ids: map[int]int{}}
+	for path, content := range files {
+		s.files = append(s.files, SourceFile{Path: path, Content: content})
+	}
+	sort.Slice(s.files, func(i, j int) bool { return s.files[i].Path < s.files[j].Path })
+	for i := range s.files {
+		s.ids[i] = i
+	}
+	s.errors = errorDeclarations(s.files)
+	return s
+}
+
+// Files returns the source files in source ID order.
+func (s *Sources) Files() []SourceFile { return s.files }
+
+// SetRuntimeSourceMap attaches the deployed bytecode and its compressed
+// runtime source map ("s:l:f:j;…", as in solc's
+// evm.deployedBytecode.sourceMap), enabling AnnotateAt.
+func (s *Sources) SetRuntimeSourceMap(bytecodeHex, sourceMap string) error {
+	code, err := hex.DecodeString(strings.TrimPrefix(bytecodeHex, "0x"))
+	if err != nil {
+		return fmt.Errorf("chainerrors: runtime bytecode: %w", err)
+	}
+	entries, err := parseSourceMap(sourceMap)
+	if err != nil {
+		return err
+	}
+	s.instr = map[int]int{}
+	for pc, i := 0, 0; pc < len(code); i++ {
+		s.instr[pc] = i
+		pc++
+		if op := code[pc-1]; op >= 0x60 && op <= 0x7f {
+			pc += int(op-0x60) + 1
+		}
+	}
+	s.entries = entries
+	return nil
+}
+
+// parseSourceMap expands solc's compressed source map, where empty fields
+// repeat the previous entry's.
+func parseSourceMap(m string) ([]srcEntry, error) {
+	var out []srcEntry
+	prev := srcEntry{file: -1}
+	for _, item := range strings.Split(m, ";") {
+		e := prev
+		for i, f := range strings.SplitN(item, ":", 5) {
+			if f == "" || i > 2 {
+				continue
+			}
+			n, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, fmt.Errorf("chainerrors: invalid source map entry %q", item)
+			}
+			switch i {
+			case 0:
+				e.start = n
+			case 1:
+				e.length = n
+			case 2:
+				e.file = n
+			}
+		}
+		out = append(out, e)
+		prev = e
+	}
+	return out, nil
+}
+
+// LoadSourcify reads a Sourcify match directory: metadata.json plus the
+// sources/ tree it verified, falling back to content embedded in the
+// metadata.
+func LoadSourcify(dir string) (*Sources, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, "metadata.json"))
+	if err != nil {
+		return nil, err
+	}
+	var meta struct {
+		Sources map[string]struct {
+			Content string `json:"content"`
+		} `json:"sources"`
+	}
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, fmt.Errorf("chainerrors: %s: %w", dir, err)
+	}
+	files := map[string]string{}
+	for name, src := range meta.Sources {
+		b, err := os.ReadFile(filepath.Join(dir, "sources", filepath.FromSlash(name)))
+		switch {
+		case err == nil:
+			files[name] = string(b)
+		case src.Content != "":
+			files[name] = src.Content
+		default:
+			return nil, fmt.Errorf("chainerrors: %s: source %s not found", dir, name)
+		}
+	}
+	return NewSources(files), nil
+}
+
+// LoadFoundryArtifact reads a Foundry artifact (out/<File>.sol/<Name>.json)
+// and the sources it lists, relative to the project root. The artifact's
+// runtime source map is attached; Foundry only records the source ID of
+// the contract's own file, so AnnotateAt resolves locations in that file
+// and leaves code inlined from other files unlocated.
+func LoadFoundryArtifact(path, root string) (*Sources, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var art struct {
+		DeployedBytecode struct {
+			Object    string `json:"object"`
+			SourceMap string `json:"sourceMap"`
+		} `json:"deployedBytecode"`
+		Metadata json.RawMessage `json:"metadata"`
+		ID       *int            `json:"id"`
+	}
+	if err := json.Unmarshal(raw, &art); err != nil {
+		return nil, fmt.Errorf("chainerrors: %s: %w", path, err)
+	}
+	// metadata is an object in current Foundry and a JSON string in older
+	// versions.
+	meta := art.Metadata
+	var str string
+	if json.Unmarshal(meta, &str) == nil {
+		meta = json.RawMessage(str)
+	}
+	var m struct {
+		Settings struct {
+			CompilationTarget map[string]string `json:"compilationTarget"`
+		} `json:"settings"`
+		Sources map[string]json.RawMessage `json:"sources"`
+	}
+	if err := json.Unmarshal(meta, &m); err != nil {
+		return nil, fmt.Errorf("chainerrors: %s: metadata: %w", path, err)
+	}
+	files := map[string]string{}
+	for name := range m.Sources {
+		b, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, fmt.Errorf("chainerrors: %s: %w", path, err)
+		}
+		files[name] = string(b)
+	}
+	s := NewSources(files)
+	s.ids = map[int]int{}
+	for target := range m.Settings.CompilationTarget {
+		for i, f := range s.files {
+			if f.Path == target && art.ID != nil {
+				s.ids[*art.ID] = i
+			}
+		}
+	}
+	if art.DeployedBytecode.SourceMap != "" {
+		if err := s.SetRuntimeSourceMap(art.DeployedBytecode.Object, art.DeployedBytecode.SourceMap); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Annotate returns a copy of d with Source set to the code that most likely
+// raised it: the require/revert carrying its reason string, or the revert
+// of its custom error. Panics and unmatched errors are returned unchanged.
+func (s *Sources) Annotate(d *DecodedError) *DecodedError {
+	if loc := s.search(d); loc != nil {
+		out := *d
+		out.Source = loc
+		return &out
+	}
+	return d
+}
+
+// AnnotateAt is like Annotate but uses the program counter of the failing
+// REVERT or INVALID instruction, e.g. from a struct-log trace, to locate the
+// error exactly. It falls back to Annotate when pc cannot be mapped.
+func (s *Sources) AnnotateAt(d *DecodedError, pc int) *DecodedError {
+	if loc := s.locatePC(pc); loc != nil {
+		out := *d
+		out.Source = loc
+		return &out
+	}
+	return s.Annotate(d)
+}
+
+func (s *Sources) locatePC(pc int) *SourceLocation {
+	i, ok := s.instr[pc]
+	if !ok || i >= len(s.entries) {
+		return nil
+	}
+	e := s.entries[i]
+	f, ok := s.ids[e.file]
+	if !ok || e.start+e.length > len(s.files[f].Content) {
+		return nil
+	}
+	loc := s.locate(f, e.start)
+	loc.Exact = true
+	return loc
+}
+
+// search finds d's reason string or custom error in the sources.
+func (s *Sources) search(d *DecodedError) *SourceLocation {
+	msg := derefString(d.Message)
+	switch d.Kind {
+	case "revert_string":
+		if msg == "" {
+			return nil
+		}
+		for _, lit := range []string{strconv.Quote(msg), "'" + msg + "'"} {
+			if loc := s.find(lit, isRevertSite); loc != nil {
+				return loc
+			}
+		}
+	case "custom_error", "raw_revert":
+		name := msg
+		if d.Kind == "raw_revert" {
+			name = s.errors[strings.ToLower(derefString(d.Selector))]
+		}
+		if name == "" {
+			return nil
+		}
+		if loc := s.find("revert "+name+"(", nil); loc != nil {
+			return loc
+		}
+		if loc := s.find(name+"(", isRevertSite); loc != nil {
+			return loc
+		}
+		// Fall back to the declaration, which at least names the error.
+		return s.find("error "+name+"(", nil)
+	}
+	return nil
+}
+
+// find returns the first occurrence of needle whose line satisfies ok (or
+// any occurrence if ok is nil), preferring matching lines over others.
+func (s *Sources) find(needle string, ok func(line string) bool) *SourceLocation {
+	var fallback *SourceLocation
+	for i, f := range s.files {
+		for from := 0; ; {
+			j := strings.Index(f.Content[from:], needle)
+			if j < 0 {
+				break
+			}
+			off := from + j
+			from = off + len(needle)
+			if ok == nil || ok(lineAt(f.Content, off)) {
+				return s.locate(i, off)
+			}
+			if fallback == nil {
+				fallback = s.locate(i, off)
+			}
+		}
+	}
+	return fallback
+}
+
+func isRevertSite(line string) bool {
+	return strings.Contains(line, "require(") || strings.Contains(line, "revert")
+}
+
+func lineAt(content string, off int) string {
+	start := strings.LastIndexByte(content[:off], '\n') + 1
+	end := strings.IndexByte(content[off:], '\n')
+	if end < 0 {
+		return content[start:]
+	}
+	return content[start : off+end]
+}
+
+// locate converts a byte offset in file f into a SourceLocation.
+func (s *Sources) locate(f, off int) *SourceLocation {
+	content := s.files[f].Content
+	line := strings.Count(content[:off], "\n") + 1
+	col := off - strings.LastIndexByte(content[:off], '\n')
+	return &SourceLocation{File: s.files[f].Path, Line: line, Column: col, Snippet: snippet(content, line)}
+}
+
+// snippet renders line with two lines of context either side.
+func snippet(content string, line int) string {
+	lines := strings.Split(content, "\n")
+	from, to := max(line-3, 0), min(line+2, len(lines))
+	width := len(strconv.Itoa(to))
+	var b strings.Builder
+	for i := from; i < to; i++ {
+		mark := " "
+		if i+1 == line {
+			mark = ">"
+		}
+		fmt.Fprintf(&b, "%s %*d | %s\n", mark, width, i+1, strings.TrimRight(lines[i], "\r"))
+	}
+	return b.String()
+}
+
+var errorDeclRe = regexp.MustCompile(`\berror\s+([A-Za-z_$][A-Za-z0-9_$]*)\s*\(([^)]*)\)`)
+
+// errorDeclarations maps the selectors of the custom errors declared in
+// files to their names, so raw reverts the registry does not know can
+// still be located. Declarations using structs, enums or other user
+// types are skipped, as their canonical types cannot be known from text.
+func errorDeclarations(files []SourceFile) map[string]string {
+	out := map[string]string{}
+	for _, f := range files {
+		for _, m := range errorDeclRe.FindAllStringSubmatch(f.Content, -1) {
+			var types []string
+			valid := true
+			for _, param := range strings.Split(m[2], ",") {
+				fields := strings.Fields(param)
+				if len(fields) == 0 {
+					continue
+				}
+				t := canonicalType(fields[0])
+				if _, err := abi.ParseTypes(t); err != nil {
+					valid = false
+					break
+				}
+				types = append(types, t)
+			}
+			if !valid {
+				continue
+			}
+			sig := m[1] + "(" + strings.Join(types, ",") + ")"
+			out["0x"+hex.EncodeToString(abi.Keccak256([]byte(sig))[:4])] = m[1]
+		}
+	}
+	return out
+}
+
+// canonicalType expands Solidity's uint/int aliases, including inside
+// array types.
+func canonicalType(t string) string {
+	base, suffix := t, ""
+	if i := strings.IndexByte(t, '['); i >= 0 {
+		base, suffix = t[:i], t[i:]
+	}
+	if base == "uint" || base == "int" {
+		base += "256"
+	}
+	return base + suffix
+}
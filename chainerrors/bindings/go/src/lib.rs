@@ -125,6 +125,29 @@ pub extern "C" fn chainerrors_panic_meaning(code: u32) -> *const c_char {
     meaning.as_ptr() as *const c_char
 }
 
+/// Return the table of known Solidity panic codes as a JSON array:
+/// `[{"code":17,"hex":"0x11","name":"arithmetic_overflow","meaning":"...","suggestion":"..."}]`.
+///
+/// Caller must free with `chainerrors_free_string()`.
+#[no_mangle]
+pub extern "C" fn chainerrors_list_panic_codes() -> *mut c_char {
+    clear_last_error();
+    let codes: Vec<serde_json::Value> = chainerrors_evm::panic::PANIC_CODES
+        .iter()
+        .map(|p| serde_json::json!({
+            "code": p.code,
+            "hex": format!("0x{:02x}", p.code),
+            "name": p.name,
+            "meaning": p.meaning,
+            "suggestion": p.suggestion,
+        }))
+        .collect();
+    match CString::new(serde_json::Value::Array(codes).to_string()) {
+        Ok(s) => s.into_raw(),
+        Err(e) => { set_last_error(&e.to_string()); std::ptr::null_mut() }
+    }
+}
+
 /// Return the library version string (static, do NOT free).
 #[no_mangle]
 pub extern "C" fn chainerrors_version() -> *const c_char {
@@ -302,6 +302,9 @@ impl ErrorDecoder for EvmErrorDecoder {
 /// Generate a hint based on common revert message patterns.
 fn generate_revert_suggestion(message: &str) -> Option<String> {
     let msg_lower = message.to_lowercase();
+    if msg_lower.starts_with("depositcontract:") {
+        return Some(deposit_contract_suggestion(&msg_lower).into());
+    }
     if msg_lower.contains("not the owner") || msg_lower.contains("not owner") {
         Some("Ensure the caller is the contract owner.".into())
     } else if msg_lower.contains("insufficient") && msg_lower.contains("balance") {
@@ -317,6 +320,29 @@ fn generate_revert_suggestion(message: &str) -> Option<String> {
     }
 }
 
+/// Hints for the beacon deposit contract's revert reasons.
+fn deposit_contract_suggestion(msg_lower: &str) -> &'static str {
+    if msg_lower.contains("deposit value too low") {
+        "Deposits must be at least 1 ETH."
+    } else if msg_lower.contains("deposit value too high") {
+        "The deposit amount in gwei must fit in 64 bits."
+    } else if msg_lower.contains("not multiple of gwei") {
+        "Send a value that is a whole number of gwei."
+    } else if msg_lower.contains("invalid pubkey length") {
+        "The validator public key must be 48 bytes."
+    } else if msg_lower.contains("invalid withdrawal_credentials length") {
+        "Withdrawal credentials must be 32 bytes."
+    } else if msg_lower.contains("invalid signature length") {
+        "The BLS signature must be 96 bytes."
+    } else if msg_lower.contains("deposit_data_root") {
+        "Recompute deposit_data_root from the exact pubkey, credentials, amount and signature being sent."
+    } else if msg_lower.contains("merkle tree full") {
+        "The deposit tree is full; no further deposits are possible."
+    } else {
+        "Check the deposit data against the deposit contract specification."
+    }
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -412,4 +438,98 @@ mod tests {
             .unwrap();
         assert!(matches!(result.kind, ErrorKind::RevertString { ref message } if message == "Hello"));
     }
+
+    /// ABI-encodes `Error(message)` revert data.
+    fn revert_data(message: &str) -> Vec<u8> {
+        let mut data = hex::decode("08c379a0").unwrap();
+        let mut word = [0u8; 32];
+        word[31] = 0x20;
+        data.extend_from_slice(&word);
+        word = [0u8; 32];
+        word[24..].copy_from_slice(&(message.len() as u64).to_be_bytes());
+        data.extend_from_slice(&word);
+        data.extend_from_slice(message.as_bytes());
+        data.resize(data.len() + (32 - message.len() % 32) % 32, 0);
+        data
+    }
+
+    /// Decodes a deposit contract revert and checks its message and hint.
+    fn assert_deposit_hint(reason: &str, hint: &str) {
+        let message = format!("DepositContract: {reason}");
+        let result = decoder().decode(&revert_data(&message), None).unwrap();
+        match &result.kind {
+            ErrorKind::RevertString { message: m } => assert_eq!(m, &message),
+            _ => panic!("expected RevertString, got {:?}", result.kind),
+        }
+        assert_eq!(result.suggestion.as_deref(), Some(hint), "reason: {reason}");
+    }
+
+    #[test]
+    fn deposit_value_too_low() {
+        assert_deposit_hint("deposit value too low", "Deposits must be at least 1 ETH.");
+    }
+
+    #[test]
+    fn deposit_value_not_multiple_of_gwei() {
+        assert_deposit_hint(
+            "deposit value not multiple of gwei",
+            "Send a value that is a whole number of gwei.",
+        );
+    }
+
+    #[test]
+    fn deposit_value_too_high() {
+        assert_deposit_hint(
+            "deposit value too high",
+            "The deposit amount in gwei must fit in 64 bits.",
+        );
+    }
+
+    #[test]
+    fn deposit_invalid_pubkey_length() {
+        assert_deposit_hint(
+            "invalid pubkey length",
+            "The validator public key must be 48 bytes.",
+        );
+    }
+
+    #[test]
+    fn deposit_invalid_withdrawal_credentials_length() {
+        assert_deposit_hint(
+            "invalid withdrawal_credentials length",
+            "Withdrawal credentials must be 32 bytes.",
+        );
+    }
+
+    #[test]
+    fn deposit_invalid_signature_length() {
+        assert_deposit_hint(
+            "invalid signature length",
+            "The BLS signature must be 96 bytes.",
+        );
+    }
+
+    #[test]
+    fn deposit_data_root_mismatch() {
+        assert_deposit_hint(
+            "reconstructed DepositData does not match supplied deposit_data_root",
+            "Recompute deposit_data_root from the exact pubkey, credentials, amount and signature being sent.",
+        );
+    }
+
+    #[test]
+    fn deposit_merkle_tree_full() {
+        assert_deposit_hint(
+            "merkle tree full",
+            "The deposit tree is full; no further deposits are possible.",
+        );
+    }
+
+    #[test]
+    fn deposit_unknown_reason() {
+        assert_deposit_hint(
+            "something else",
+            "Check the deposit data against the deposit contract specification.",
+        );
+    }
 }
@@ -31,21 +31,51 @@ pub fn decode_panic(data: &[u8]) -> Option<(u64, &'static str)> {
     }
 }
 
+/// A known Solidity panic code.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct PanicCode {
+    /// The `uint256` argument of `Panic(uint256)`.
+    pub code: u64,
+    /// Short identifier, stable across releases.
+    pub name: &'static str,
+    /// Human-readable description.
+    pub meaning: &'static str,
+    /// How the failure is usually fixed.
+    pub suggestion: &'static str,
+}
+
+/// Every panic code emitted by the Solidity compiler, in code order.
+pub const PANIC_CODES: &[PanicCode] = &[
+    PanicCode { code: 0x00, name: "generic", meaning: "generic compiler-inserted panic",
+        suggestion: "Inspect the contract source; the compiler inserted a check without a specific code." },
+    PanicCode { code: 0x01, name: "assert", meaning: "assert() called with false condition",
+        suggestion: "An internal invariant was violated; this usually indicates a contract bug." },
+    PanicCode { code: 0x11, name: "arithmetic_overflow", meaning: "arithmetic overflow or underflow",
+        suggestion: "Check input amounts and balances; a value went below zero or above the type's maximum." },
+    PanicCode { code: 0x12, name: "division_by_zero", meaning: "division or modulo by zero",
+        suggestion: "Ensure divisors (e.g. total supply or reserves) are non-zero before calling." },
+    PanicCode { code: 0x21, name: "invalid_enum", meaning: "invalid enum value",
+        suggestion: "An argument or stored value does not map to a declared enum member." },
+    PanicCode { code: 0x22, name: "storage_encoding", meaning: "corrupted storage byte array",
+        suggestion: "Storage was written incorrectly, often via inline assembly or an unsafe upgrade." },
+    PanicCode { code: 0x31, name: "empty_array_pop", meaning: ".pop() on empty array",
+        suggestion: "Check the array length before removing elements." },
+    PanicCode { code: 0x32, name: "array_out_of_bounds", meaning: "out-of-bounds array access",
+        suggestion: "Check indexes against the array length; the argument may reference a missing item." },
+    PanicCode { code: 0x41, name: "out_of_memory", meaning: "too much memory allocated (out of memory)",
+        suggestion: "Reduce the size of arrays or return data processed in one call." },
+    PanicCode { code: 0x51, name: "zero_function_pointer", meaning: "called zero-initialized internal function pointer",
+        suggestion: "An internal function variable was used before being assigned." },
+];
+
 /// Map a Solidity panic code to a human-readable description.
 pub fn panic_meaning(code: u64) -> &'static str {
-    match code {
-        0x00 => "generic compiler-inserted panic",
-        0x01 => "assert() called with false condition",
-        0x11 => "arithmetic overflow or underflow",
-        0x12 => "division or modulo by zero",
-        0x21 => "invalid enum value",
-        0x22 => "corrupted storage byte array",
-        0x31 => ".pop() on empty array",
-        0x32 => "out-of-bounds array access",
-        0x41 => "too much memory allocated (out of memory)",
-        0x51 => "called zero-initialized internal function pointer",
-        _ => "unknown panic code",
-    }
+    lookup_panic(code).map_or("unknown panic code", |p| p.meaning)
+}
+
+/// Look up a panic code in [`PANIC_CODES`].
+pub fn lookup_panic(code: u64) -> Option<&'static PanicCode> {
+    PANIC_CODES.iter().find(|p| p.code == code)
 }
 
 #[cfg(test)]
@@ -88,4 +118,12 @@ mod tests {
         assert_eq!(panic_meaning(0x32), "out-of-bounds array access");
         assert_eq!(panic_meaning(0x99), "unknown panic code");
     }
+
+    #[test]
+    fn panic_codes_sorted_and_unique() {
+        for w in PANIC_CODES.windows(2) {
+            assert!(w[0].code < w[1].code);
+        }
+        assert_eq!(lookup_panic(0x12).unwrap().name, "division_by_zero");
+    }
 }
@@ -0,0 +1,242 @@
+package chainindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultRangeBatches is the default backfill range size, in batches.
+const defaultRangeBatches = 10
+
+// WithBackfill fetches history with workers concurrent workers. When Run
+// starts more than one range of rangeSize blocks behind the confirmed
+// head, that span is split into ranges fetched in parallel and delivered
+// to the handler in block order, exactly as a sequential run would; the
+// indexer then follows the head as usual. A zero rangeSize means ten
+// batches. At most workers ranges are held in memory at a time.
+//
+// Each range keeps its own checkpoint, under the indexer's checkpoint ID
+// suffixed with "@from-to", while it is being delivered, so List shows the
+// backfill's progress; they are deleted as ranges complete. Restarts
+// resume from the indexer's own checkpoint.
+func WithBackfill(workers int, rangeSize uint64) Option {
+	return func(o *indexerOptions) { o.workers, o.rangeSize = workers, rangeSize }
+}
+
+// backfillRange is one range of a backfill: its batches of matched events,
+// filled by a worker and delivered in order.
+type backfillRange struct {
+	from, to uint64
+	batches  []backfillBatch
+	err      error
+	done     chan struct{}
+}
+
+type backfillBatch struct {
+	from, to uint64
+	events   []Event
+}
+
+// rangeCheckpointID returns the checkpoint key of a backfill range.
+func (ix *Indexer) rangeCheckpointID(from, to uint64) string {
+	return fmt.Sprintf("%s@%d-%d", ix.cfg.CheckpointID(), from, to)
+}
+
+// runBackfill backfills from the resume point when WithBackfill is set and
+// the indexer is far enough behind.
+func (ix *Indexer) runBackfill(ctx context.Context) error {
+	ix.mu.Lock()
+	next := ix.next
+	ix.mu.Unlock()
+	var target uint64
+	var ok bool
+	err := ix.retry(ctx, func() error {
+		var err error
+		target, ok, err = ix.backfillTarget(ctx, next)
+		return err
+	})
+	if err != nil || !ok {
+		return err
+	}
+	if err := ix.clearRanges(ctx); err != nil {
+		return err
+	}
+	if err := ix.backfill(ctx, next, target); err != nil {
+		if errors.Is(err, errBackfillStalled) {
+			return nil // the sequential loop carries on from ix.next
+		}
+		return err
+	}
+	return ix.retry(ctx, func() error { return ix.trackTip(ctx, target) })
+}
+
+// backfillTarget returns the last block a backfill starting at next would
+// cover, and whether the span is long enough to be worth one.
+func (ix *Indexer) backfillTarget(ctx context.Context, next uint64) (uint64, bool, error) {
+	if ix.workers < 2 {
+		return 0, false, nil
+	}
+	_, target, ok, err := ix.target(ctx)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	return target, target >= next && target-next >= ix.rangeSize, nil
+}
+
+// backfill indexes blocks from..to with parallel workers, delivering in
+// order.
+func (ix *Indexer) backfill(ctx context.Context, from, to uint64) error {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	var ranges []*backfillRange
+	for start := from; start <= to; {
+		end := min(start+ix.rangeSize-1, to)
+		ranges = append(ranges, &backfillRange{from: start, to: end, done: make(chan struct{})})
+		if end == to {
+			break
+		}
+		start = end + 1
+	}
+
+	// slots bounds the ranges fetched but not yet delivered.
+	slots := make(chan struct{}, ix.workers)
+	jobs := make(chan *backfillRange)
+	for i := 0; i < ix.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				r.err = ix.fetchRange(ctx, r)
+				close(r.done)
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, r := range ranges {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- r:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for _, r := range ranges {
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if r.err != nil {
+			return r.err
+		}
+		id := ix.rangeCheckpointID(r.from, r.to)
+		for _, b := range r.batches {
+			if err := ix.waitIfPaused(ctx); err != nil {
+				return err
+			}
+			err := ix.retryBatch(ctx, func() error {
+				if err := ix.deliver(ctx, b.from, b.to, b.events); err != nil {
+					return err
+				}
+				return ix.saveRange(ctx, id, b.to)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if err := ix.store.Delete(ctx, ix.cfg.Chain, id); err != nil {
+			return fmt.Errorf("chainindex: delete range checkpoint: %w", err)
+		}
+		r.batches = nil
+		<-slots
+	}
+	return nil
+}
+
+// fetchRange fetches r's matched events batch by batch, retrying RPC
+// errors until ctx is done.
+func (ix *Indexer) fetchRange(ctx context.Context, r *backfillRange) error {
+	for from := r.from; from <= r.to; {
+		to := min(from+ix.cfg.BatchSize-1, r.to)
+		var events []Event
+		err := ix.retryBatch(ctx, func() error {
+			var err error
+			events, err = ix.getLogs(ctx, from, to)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		r.batches = append(r.batches, backfillBatch{from: from, to: to, events: events})
+		if to == r.to {
+			break
+		}
+		from = to + 1
+	}
+	return nil
+}
+
+// saveRange records a backfill range's progress. Its hash is left empty:
+// the range checkpoint is informational, the indexer's own checkpoint is
+// what a restart resumes from.
+func (ix *Indexer) saveRange(ctx context.Context, id string, block uint64) error {
+	cp := Checkpoint{ChainID: ix.cfg.Chain, IndexerID: id, BlockNumber: block, UpdatedAt: time.Now().Unix()}
+	if err := ix.store.Save(ctx, cp); err != nil {
+		return &fatalError{fmt.Errorf("chainindex: save range checkpoint: %w", err)}
+	}
+	return nil
+}
+
+// clearRanges deletes range checkpoints left by an interrupted backfill.
+func (ix *Indexer) clearRanges(ctx context.Context) error {
+	cps, err := ix.store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("chainindex: list checkpoints: %w", err)
+	}
+	prefix := ix.cfg.CheckpointID() + "@"
+	for _, cp := range cps {
+		if cp.ChainID == ix.cfg.Chain && strings.HasPrefix(cp.IndexerID, prefix) {
+			if err := ix.store.Delete(ctx, cp.ChainID, cp.IndexerID); err != nil {
+				return fmt.Errorf("chainindex: delete range checkpoint: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// retry calls f until it succeeds, backing off after errors. Fatal errors
+// and ctx ending stop it.
+func (ix *Indexer) retry(ctx context.Context, f func() error) error {
+	backoff := time.Duration(ix.cfg.PollIntervalMs) * time.Millisecond
+	for {
+		err := f()
+		if err == nil {
+			return nil
+		}
+		var fe *fatalError
+		if ctx.Err() != nil || errors.As(err, &fe) {
+			return err
+		}
+		ix.observeError(err)
+		if err := sleepCtx(ctx, backoff); err != nil {
+			return err
+		}
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
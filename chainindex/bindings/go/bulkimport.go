@@ -0,0 +1,301 @@
+package chainindex
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ImportRecord is one event row of an exported dataset, in the column layout
+// written by the chainindex exporter (chain, schema, address, tx_hash,
+// block_number, log_index, fields_json) plus an optional block_hash column
+// used to verify continuity with the live chain.
+type ImportRecord struct {
+	Chain       string          `json:"chain"`
+	Schema      string          `json:"schema"`
+	Address     string          `json:"address"`
+	TxHash      string          `json:"tx_hash"`
+	BlockNumber uint64          `json:"block_number"`
+	BlockHash   string          `json:"block_hash,omitempty"`
+	LogIndex    uint64          `json:"log_index"`
+	Fields      json.RawMessage `json:"fields_json"`
+}
+
+// RecordReader yields import records in block order and returns io.EOF
+// when exhausted. CSV and JSONL readers are provided. Parquet is out of
+// scope for this package, which takes no Parquet dependency: convert
+// Parquet exports to CSV or JSONL, or implement RecordReader over a
+// Parquet library.
+type RecordReader interface {
+	Next() (ImportRecord, error)
+}
+
+// NewJSONLReader reads one JSON ImportRecord per line.
+func NewJSONLReader(r io.Reader) RecordReader {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &jsonlReader{sc: sc}
+}
+
+type jsonlReader struct {
+	sc   *bufio.Scanner
+	line int
+}
+
+func (r *jsonlReader) Next() (ImportRecord, error) {
+	for r.sc.Scan() {
+		r.line++
+		b := strings.TrimSpace(r.sc.Text())
+		if b == "" {
+			continue
+		}
+		var rec ImportRecord
+		if err := json.Unmarshal([]byte(b), &rec); err != nil {
+			return ImportRecord{}, fmt.Errorf("jsonl line %d: %w", r.line, err)
+		}
+		return rec, nil
+	}
+	if err := r.sc.Err(); err != nil {
+		return ImportRecord{}, err
+	}
+	return ImportRecord{}, io.EOF
+}
+
+// NewCSVReader reads a CSV export with a header row. Columns are matched by
+// name, so extra columns are ignored and block_hash may appear anywhere.
+func NewCSVReader(r io.Reader) RecordReader {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	return &csvReader{r: cr}
+}
+
+type csvReader struct {
+	r   *csv.Reader
+	col map[string]int
+}
+
+func (r *csvReader) Next() (ImportRecord, error) {
+	if r.col == nil {
+		header, err := r.r.Read()
+		if err != nil {
+			return ImportRecord{}, err
+		}
+		r.col = make(map[string]int, len(header))
+		for i, h := range header {
+			r.col[strings.TrimSpace(h)] = i
+		}
+		for _, req := range []string{"chain", "address", "tx_hash", "block_number", "log_index"} {
+			if _, ok := r.col[req]; !ok {
+				return ImportRecord{}, fmt.Errorf("csv: missing column %q", req)
+			}
+		}
+	}
+	row, err := r.r.Read()
+	if err != nil {
+		return ImportRecord{}, err
+	}
+	get := func(name string) string {
+		if i, ok := r.col[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+	line, _ := r.r.FieldPos(0)
+	block, err := strconv.ParseUint(get("block_number"), 10, 64)
+	if err != nil {
+		return ImportRecord{}, fmt.Errorf("csv line %d: block_number: %w", line, err)
+	}
+	logIndex, err := strconv.ParseUint(get("log_index"), 10, 64)
+	if err != nil {
+		return ImportRecord{}, fmt.Errorf("csv line %d: log_index: %w", line, err)
+	}
+	rec := ImportRecord{
+		Chain:       get("chain"),
+		Schema:      get("schema"),
+		Address:     get("address"),
+		TxHash:      get("tx_hash"),
+		BlockNumber: block,
+		BlockHash:   get("block_hash"),
+		LogIndex:    logIndex,
+	}
+	if f := get("fields_json"); f != "" {
+		rec.Fields = json.RawMessage(f)
+	}
+	return rec, nil
+}
+
+// ErrDiscontinuity is returned when an imported block hash does not match
+// the canonical chain.
+var ErrDiscontinuity = errors.New("chainindex: imported data diverges from the canonical chain")
+
+// BulkImporter seeds a consumer from an exported dataset, checking block
+// hashes against the live chain before the indexer switches to RPC.
+type BulkImporter struct {
+	// RPC is used to fetch canonical block hashes.
+	RPC Caller
+	// Sink receives records in batches, in dataset order. The last block of
+	// each batch is verified against the chain before the batch is passed
+	// on.
+	Sink func(ctx context.Context, records []ImportRecord) error
+	// Store receives the checkpoint (default: the store set with
+	// SetCheckpointStore, or the native one).
+	Store CheckpointStore
+	// BatchSize is the number of records per Sink call (default 1000).
+	BatchSize int
+	// VerifyEvery checks the hash of every Nth distinct block in addition
+	// to the last one (0 = last block only).
+	VerifyEvery int
+}
+
+// ImportResult summarises a completed import.
+type ImportResult struct {
+	Records       uint64 `json:"records"`
+	Blocks        uint64 `json:"blocks"`
+	FirstBlock    uint64 `json:"first_block"`
+	LastBlock     uint64 `json:"last_block"`
+	LastBlockHash string `json:"last_block_hash"`
+	Verified      int    `json:"verified_blocks"`
+}
+
+// Import streams rr into the sink and, once the last block's hash is
+// verified, saves a checkpoint for cfg at that block in Store so the
+// indexer resumes live indexing right after the dataset. Records must be ordered by block
+// and belong to cfg.Chain.
+func (b *BulkImporter) Import(ctx context.Context, cfg *IndexerConfig, rr RecordReader) (*ImportResult, error) {
+	if b.Sink == nil || b.RPC == nil {
+		return nil, errors.New("chainindex: BulkImporter needs RPC and Sink")
+	}
+	size := b.BatchSize
+	if size <= 0 {
+		size = 1000
+	}
+	store := b.Store
+	if store == nil {
+		store = checkpointStore()
+	}
+	res := &ImportResult{}
+	var (
+		curHash  string
+		verified bool // res.LastBlock's hash has been checked
+	)
+	check := func() error {
+		if verified {
+			return nil
+		}
+		if err := b.verify(ctx, res.LastBlock, curHash); err != nil {
+			return err
+		}
+		res.Verified++
+		verified = true
+		return nil
+	}
+	batch := make([]ImportRecord, 0, size)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := check(); err != nil {
+			return err
+		}
+		err := b.Sink(ctx, batch)
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		rec, err := rr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return res, err
+		}
+		if rec.Chain != "" && rec.Chain != cfg.Chain {
+			return res, fmt.Errorf("chainindex: record for chain %q in import for %q", rec.Chain, cfg.Chain)
+		}
+		if res.Records > 0 && rec.BlockNumber < res.LastBlock {
+			return res, fmt.Errorf("chainindex: import not ordered by block (%d after %d)", rec.BlockNumber, res.LastBlock)
+		}
+		if res.Records == 0 || rec.BlockNumber != res.LastBlock {
+			// Entering a new block: check the one just finished if due.
+			if res.Blocks > 0 && b.VerifyEvery > 0 && res.Blocks%uint64(b.VerifyEvery) == 0 {
+				if err := check(); err != nil {
+					return res, err
+				}
+			}
+			if res.Records == 0 {
+				res.FirstBlock = rec.BlockNumber
+			}
+			res.Blocks++
+			res.LastBlock = rec.BlockNumber
+			curHash, verified = "", false
+		}
+		if rec.BlockHash != "" {
+			if curHash != "" && !strings.EqualFold(curHash, rec.BlockHash) {
+				return res, fmt.Errorf("%w: block %d has two hashes in the dataset", ErrDiscontinuity, rec.BlockNumber)
+			}
+			curHash = rec.BlockHash
+		}
+		res.Records++
+		batch = append(batch, rec)
+		if len(batch) == size {
+			if err := flush(); err != nil {
+				return res, err
+			}
+		}
+	}
+	if res.Records == 0 {
+		return res, nil
+	}
+	if err := check(); err != nil {
+		return res, err
+	}
+	res.LastBlockHash = curHash
+	if err := flush(); err != nil {
+		return res, err
+	}
+	return res, store.Save(ctx, Checkpoint{
+		ChainID:     cfg.Chain,
+		IndexerID:   cfg.CheckpointID(),
+		BlockNumber: res.LastBlock,
+		BlockHash:   curHash,
+		UpdatedAt:   time.Now().Unix(),
+	})
+}
+
+func (b *BulkImporter) verify(ctx context.Context, block uint64, hash string) error {
+	if hash == "" {
+		return fmt.Errorf("chainindex: cannot verify block %d: dataset has no block_hash", block)
+	}
+	canonical, err := blockHash(ctx, b.RPC, block)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(canonical, hash) {
+		return fmt.Errorf("%w: block %d is %s in the dataset but %s on chain", ErrDiscontinuity, block, hash, canonical)
+	}
+	return nil
+}
+
+// blockHash fetches the canonical hash of block.
+func blockHash(ctx context.Context, rpc Caller, block uint64) (string, error) {
+	params := fmt.Sprintf(`["0x%x",false]`, block)
+	res, err := rpc.CallContext(ctx, "eth_getBlockByNumber", params)
+	if err != nil {
+		return "", err
+	}
+	var b struct {
+		Hash string `json:"hash"`
+	}
+	if err := json.Unmarshal([]byte(res), &b); err != nil || b.Hash == "" {
+		return "", fmt.Errorf("chainindex: block %d not found", block)
+	}
+	return b.Hash, nil
+}
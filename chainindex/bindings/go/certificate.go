@@ -0,0 +1,222 @@
+package chainindex
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CertificateVersion identifies the canonical encodings a Certificate's
+// hashes and signature are computed over.
+const CertificateVersion = "chainindex-cert-v1"
+
+// Certificate attests that an indexer processed every block in
+// [FromBlock, ToBlock] with the filter hashed in FilterHash and delivered
+// exactly EventCount events whose canonical encodings hash to EventsHash.
+// Signature is an HMAC-SHA256 over the other fields under a key shared
+// with auditors, who can check it with Verify and recompute the hashes
+// from their own copy of the events with VerifyEvents.
+type Certificate struct {
+	Version   string `json:"version"`
+	Chain     string `json:"chain"`
+	IndexerID string `json:"indexer_id"`
+	FromBlock uint64 `json:"from_block"`
+	ToBlock   uint64 `json:"to_block"`
+	// FilterHash is FilterHash of the filter in force for the range.
+	FilterHash string `json:"filter_hash"`
+	EventCount uint64 `json:"event_count"`
+	// EventsHash is a SHA-256 over the events in delivery order; see
+	// CertificateBuilder.Add.
+	EventsHash string `json:"events_hash"`
+	IssuedAt   int64  `json:"issued_at"`
+	// KeyID names the signing key, so auditors can pick the right one
+	// across rotations. It is covered by the signature.
+	KeyID     string `json:"key_id,omitempty"`
+	Signature string `json:"signature"`
+}
+
+func (c Certificate) String() string {
+	return kv("certificate",
+		"chain", c.Chain,
+		"indexer", c.IndexerID,
+		"from", strconv.FormatUint(c.FromBlock, 10),
+		"to", strconv.FormatUint(c.ToBlock, 10),
+		"events", strconv.FormatUint(c.EventCount, 10),
+		"events_hash", c.EventsHash,
+		"key", c.KeyID)
+}
+
+// signingPayload is the certificate without its signature, in field order.
+func (c Certificate) signingPayload() []byte {
+	c.Signature = ""
+	type plain Certificate
+	b, _ := json.Marshal(plain(c))
+	return b
+}
+
+func (c Certificate) mac(key []byte) string {
+	m := hmac.New(sha256.New, key)
+	m.Write(c.signingPayload())
+	return "0x" + hex.EncodeToString(m.Sum(nil))
+}
+
+// Sign sets the certificate's Signature using key.
+func (c *Certificate) Sign(key []byte) {
+	c.Signature = c.mac(key)
+}
+
+// Verify checks the certificate's signature against key.
+func (c Certificate) Verify(key []byte) error {
+	if c.Version != CertificateVersion {
+		return fmt.Errorf("chainindex: unsupported certificate version %q", c.Version)
+	}
+	if !hmac.Equal([]byte(normHex(c.Signature)), []byte(c.mac(key))) {
+		return errors.New("chainindex: certificate signature mismatch")
+	}
+	return nil
+}
+
+// VerifyEvents checks the signature and that events, in delivery order,
+// are exactly the ones the certificate covers: same count, same hash, all
+// within the range. filter is the auditor's copy of the indexer's filter.
+func (c Certificate) VerifyEvents(key []byte, filter EventFilter, events []Event) error {
+	if err := c.Verify(key); err != nil {
+		return err
+	}
+	if h := FilterHash(filter); h != c.FilterHash {
+		return fmt.Errorf("chainindex: filter hash %s, certificate has %s", h, c.FilterHash)
+	}
+	b := NewCertificateBuilder(c.Chain, c.IndexerID, c.FromBlock, filter)
+	for _, ev := range events {
+		if err := b.Add(ev); err != nil {
+			return err
+		}
+	}
+	if b.count != c.EventCount {
+		return fmt.Errorf("chainindex: %d events, certificate has %d", b.count, c.EventCount)
+	}
+	if h := b.eventsHash(); h != c.EventsHash {
+		return fmt.Errorf("chainindex: events hash %s, certificate has %s", h, c.EventsHash)
+	}
+	return nil
+}
+
+// FilterHash returns a SHA-256 of f's canonical form: addresses and
+// topics normalised, de-duplicated and sorted, so filters that select the
+// same logs hash the same.
+func FilterHash(f EventFilter) string {
+	canon := func(in []string) []string {
+		out := normHexes(in)
+		sort.Strings(out)
+		n := 0
+		for i, s := range out {
+			if i == 0 || s != out[n-1] {
+				out[n] = s
+				n++
+			}
+		}
+		return out[:n]
+	}
+	f.Addresses, f.Topic0Values = canon(f.Addresses), canon(f.Topic0Values)
+	b, _ := json.Marshal(f)
+	sum := sha256.Sum256(b)
+	return "0x" + hex.EncodeToString(sum[:])
+}
+
+// CertificateBuilder accumulates the events of one block range as they are
+// delivered and issues its Certificate once the range is finished.
+type CertificateBuilder struct {
+	chain      string
+	indexerID  string
+	from       uint64
+	filterHash string
+
+	h     hash.Hash
+	count uint64
+	last  uint64
+}
+
+// NewCertificateBuilder starts a certificate for the range beginning at
+// from, indexed with filter.
+func NewCertificateBuilder(chain, indexerID string, from uint64, filter EventFilter) *CertificateBuilder {
+	return &CertificateBuilder{
+		chain:      chain,
+		indexerID:  indexerID,
+		from:       from,
+		filterHash: FilterHash(filter),
+		h:          sha256.New(),
+		last:       from,
+	}
+}
+
+// Add records a delivered event. Events must arrive in delivery order and
+// not before the range start; compensating events (Removed set) are
+// recorded like any other, so the hash covers exactly what consumers saw.
+// Each event contributes one line of the form
+// "block,block_hash,tx_hash,tx_index,log_index,address,topic;topic…,data,removed\n"
+// with hex fields normalised.
+func (b *CertificateBuilder) Add(ev Event) error {
+	if ev.BlockNumber < b.from {
+		return fmt.Errorf("chainindex: event in block %d before certificate range start %d", ev.BlockNumber, b.from)
+	}
+	if ev.BlockNumber < b.last && !ev.Removed {
+		return fmt.Errorf("chainindex: event in block %d after block %d", ev.BlockNumber, b.last)
+	}
+	if ev.BlockNumber > b.last {
+		b.last = ev.BlockNumber
+	}
+	line := strings.Join([]string{
+		strconv.FormatUint(ev.BlockNumber, 10),
+		normHex(ev.BlockHash),
+		normHex(ev.TxHash),
+		strconv.FormatUint(ev.TxIndex, 10),
+		strconv.FormatUint(ev.LogIndex, 10),
+		normHex(ev.Address),
+		strings.Join(normHexes(ev.Topics), ";"),
+		normHex(ev.Data),
+		strconv.FormatBool(ev.Removed),
+	}, ",")
+	b.h.Write([]byte(line + "\n"))
+	b.count++
+	return nil
+}
+
+// Count returns the number of events added so far.
+func (b *CertificateBuilder) Count() uint64 { return b.count }
+
+func (b *CertificateBuilder) eventsHash() string {
+	return "0x" + hex.EncodeToString(b.h.Sum(nil))
+}
+
+// Finish closes the range at to, inclusive, and returns the certificate
+// signed with key under keyID. The builder must not be used afterwards.
+func (b *CertificateBuilder) Finish(to uint64, keyID string, key []byte) (*Certificate, error) {
+	if to < b.from || to < b.last {
+		return nil, fmt.Errorf("chainindex: certificate range end %d before block %d", to, b.last)
+	}
+	if len(key) == 0 {
+		return nil, errors.New("chainindex: empty certificate key")
+	}
+	c := &Certificate{
+		Version:    CertificateVersion,
+		Chain:      b.chain,
+		IndexerID:  b.indexerID,
+		FromBlock:  b.from,
+		ToBlock:    to,
+		FilterHash: b.filterHash,
+		EventCount: b.count,
+		EventsHash: b.eventsHash(),
+		IssuedAt:   time.Now().Unix(),
+		KeyID:      keyID,
+	}
+	c.Sign(key)
+	return c, nil
+}
@@ -17,6 +17,7 @@ package chainindex
 */
 import "C"
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"unsafe"
@@ -36,11 +37,30 @@ type IndexerConfig struct {
 	ID                string `json:"id"`
 	Chain             string `json:"chain"`
 	FromBlock         uint64 `json:"from_block"`
+	// ToBlock, if set, is the last block to index; the indexer stops once
+	// it is checkpointed. Left nil, the indexer never stops and keeps
+	// following the chain as Follow describes.
 	ToBlock           *uint64 `json:"to_block,omitempty"`
 	ConfirmationDepth uint64 `json:"confirmation_depth"`
 	BatchSize         uint64 `json:"batch_size"`
 	CheckpointInterval uint64 `json:"checkpoint_interval"`
 	PollIntervalMs    uint64 `json:"poll_interval_ms"`
+	Filter            EventFilter `json:"filter"`
+	// RateLimitRPS caps RPC requests per second issued by the indexer (0 = unlimited).
+	RateLimitRPS float64 `json:"rate_limit_rps,omitempty"`
+	// Shard restricts this instance to one partition of Filter.Addresses.
+	Shard *Shard `json:"shard,omitempty"`
+	// StartFrom optionally overrides FromBlock with a relative start such as
+	// "latest-1000" or "deployment:0x..."; see ResolveFromBlock.
+	StartFrom string `json:"start_from,omitempty"`
+	// AdaptivePoll, if set, varies the wait between head polls around
+	// PollIntervalMs instead of keeping it fixed.
+	AdaptivePoll *AdaptivePoll `json:"adaptive_poll,omitempty"`
+	// Follow is the block tag the indexer keeps up with: "latest" (the
+	// default) indexes up to ConfirmationDepth blocks behind the head,
+	// "safe" and "finalized" up to the chain's safe or finalized block,
+	// ignoring ConfirmationDepth. ToBlock still ends the run.
+	Follow string `json:"follow,omitempty"`
 }
 
 // EventFilter holds filter criteria for indexed events.
@@ -90,52 +110,29 @@ func ParseConfig(configJSON string) (*IndexerConfig, error) {
 	}
 	defer C.chainindex_free_string(ptr)
 
+	// Go-only fields (e.g. start_from) are not round-tripped by the native
+	// parser, so take them from the input and overlay the normalized JSON.
 	var cfg IndexerConfig
+	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
+		return nil, err
+	}
 	if err := json.Unmarshal([]byte(C.GoString(ptr)), &cfg); err != nil {
 		return nil, err
 	}
 	return &cfg, nil
 }
 
-// SaveCheckpoint persists a checkpoint to the thread-local in-memory store.
+// SaveCheckpoint persists a checkpoint to the store set with
+// SetCheckpointStore, or else to DefaultNativeStore.
 func SaveCheckpoint(cp Checkpoint) error {
-	data, err := json.Marshal(cp)
-	if err != nil {
-		return err
-	}
-	cJSON := C.CString(string(data))
-	defer C.free(unsafe.Pointer(cJSON))
-
-	if C.chainindex_save_checkpoint(cJSON) != 0 {
-		return lastError()
-	}
-	return nil
+	return checkpointStore().Save(context.Background(), cp)
 }
 
-// LoadCheckpoint retrieves a checkpoint from the thread-local in-memory store.
+// LoadCheckpoint retrieves a checkpoint from the store set with
+// SetCheckpointStore, or else from DefaultNativeStore.
 // Returns nil if no checkpoint exists for the given chain/indexer pair.
 func LoadCheckpoint(chainID, indexerID string) (*Checkpoint, error) {
-	cChain := C.CString(chainID)
-	defer C.free(unsafe.Pointer(cChain))
-	cIndexer := C.CString(indexerID)
-	defer C.free(unsafe.Pointer(cIndexer))
-
-	ptr := C.chainindex_load_checkpoint(cChain, cIndexer)
-	if ptr == nil {
-		// Check if it's an error or just "not found"
-		errMsg := C.chainindex_last_error()
-		if errMsg != nil {
-			return nil, errors.New(C.GoString(errMsg))
-		}
-		return nil, nil // not found
-	}
-	defer C.chainindex_free_string(ptr)
-
-	var cp Checkpoint
-	if err := json.Unmarshal([]byte(C.GoString(ptr)), &cp); err != nil {
-		return nil, err
-	}
-	return &cp, nil
+	return checkpointStore().Load(context.Background(), chainID, indexerID)
 }
 
 // FilterForAddress creates an EventFilter that matches a single contract address.
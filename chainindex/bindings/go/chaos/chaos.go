@@ -0,0 +1,252 @@
+// Package chaos injects faults into an indexing pipeline so integration
+// tests can check that it degrades gracefully: RPC timeouts, transport
+// errors, malformed responses, chain reorganisations and slow or failing
+// sinks.
+//
+// An Injector wraps a chainindex.Caller (a *chainrpc.Pool, or a
+// synthetic.Chain for RPC-free tests) and the function events are written
+// with:
+//
+//	chain, _ := synthetic.New(synthetic.Config{Seed: 1})
+//	inj := chaos.New(chaos.Config{Seed: 1, Timeout: 0.05, Malformed: 0.02, Reorg: 0.1})
+//	rpc := inj.Caller(chain)
+//	sink := inj.Sink(writeEvents)
+//
+// Faults are drawn from a seeded source, so a failing run can be replayed
+// with the same Seed (as long as calls arrive in the same order).
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/DarshanKumar89/chainfoundry/chainindex"
+	"github.com/DarshanKumar89/chainfoundry/chainrpc"
+)
+
+// ErrInjected marks every error the injector makes up, so tests can tell
+// injected failures from real ones with errors.Is.
+var ErrInjected = errors.New("chaos: injected fault")
+
+// Config sets the probability (0..1) of each fault. Zero fields inject
+// nothing; durations default as noted.
+type Config struct {
+	// Seed makes the sequence of faults reproducible.
+	Seed int64
+
+	// Timeout is the probability that an RPC call hangs for TimeoutAfter
+	// (or until its context ends) and then fails with a timeout error,
+	// which chainrpc.IsRetryable treats as transient.
+	Timeout float64
+	// TimeoutAfter is how long a timed-out call hangs (default 1s).
+	TimeoutAfter time.Duration
+	// Error is the probability that an RPC call fails with an HTTP 429,
+	// 502 or 503 error instead of reaching the node.
+	Error float64
+	// Malformed is the probability that a successful RPC response is
+	// replaced with truncated, null or mistyped JSON.
+	Malformed float64
+	// Latency delays every RPC call by up to this much (uniformly drawn).
+	Latency time.Duration
+
+	// Reorg is the probability that an eth_blockNumber call first reorgs
+	// the chain by ReorgDepth blocks. It only applies when the wrapped
+	// Caller can reorg itself (Reorg(depth uint64)), as synthetic.Chain
+	// can.
+	Reorg float64
+	// ReorgDepth is the number of blocks each injected reorg replaces
+	// (default 3).
+	ReorgDepth uint64
+
+	// SlowSink is the probability that a sink write is delayed by
+	// SinkDelay before it runs.
+	SlowSink float64
+	// SinkDelay is how long a slow sink write stalls (default 2s).
+	SinkDelay time.Duration
+	// SinkError is the probability that a sink write fails without
+	// running.
+	SinkError float64
+}
+
+// Stats counts the faults injected so far.
+type Stats struct {
+	Calls      uint64 `json:"calls"`
+	Timeouts   uint64 `json:"timeouts"`
+	Errors     uint64 `json:"errors"`
+	Malformed  uint64 `json:"malformed"`
+	Reorgs     uint64 `json:"reorgs"`
+	SinkWrites uint64 `json:"sink_writes"`
+	SlowSinks  uint64 `json:"slow_sinks"`
+	SinkErrors uint64 `json:"sink_errors"`
+}
+
+// Injector draws faults for the Callers and sinks it wraps. It is safe for
+// concurrent use.
+type Injector struct {
+	mu      sync.Mutex
+	cfg     Config
+	rng     *rand.Rand
+	stats   Stats
+	enabled bool
+}
+
+// New returns an enabled injector for cfg.
+func New(cfg Config) *Injector {
+	if cfg.TimeoutAfter <= 0 {
+		cfg.TimeoutAfter = time.Second
+	}
+	if cfg.ReorgDepth == 0 {
+		cfg.ReorgDepth = 3
+	}
+	if cfg.SinkDelay <= 0 {
+		cfg.SinkDelay = 2 * time.Second
+	}
+	return &Injector{cfg: cfg, rng: rand.New(rand.NewSource(cfg.Seed)), enabled: true}
+}
+
+// SetEnabled turns fault injection on or off, e.g. to let a pipeline
+// recover and check that it catches up.
+func (in *Injector) SetEnabled(on bool) {
+	in.mu.Lock()
+	in.enabled = on
+	in.mu.Unlock()
+}
+
+// Stats returns the faults injected so far.
+func (in *Injector) Stats() Stats {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.stats
+}
+
+// roll reports whether a fault of probability p happens, counting it in
+// *counter, and returns a draw in [0,1) for choosing among variants.
+func (in *Injector) roll(p float64, counter *uint64) (bool, float64) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if !in.enabled || p <= 0 {
+		return false, 0
+	}
+	if in.rng.Float64() >= p {
+		return false, 0
+	}
+	*counter++
+	return true, in.rng.Float64()
+}
+
+func (in *Injector) jitter(max time.Duration) time.Duration {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if !in.enabled || max <= 0 {
+		return 0
+	}
+	return time.Duration(in.rng.Int63n(int64(max) + 1))
+}
+
+// sleep waits d or until ctx ends.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reorger is implemented by callers that can reorganise their own chain,
+// such as synthetic.Chain.
+type reorger interface {
+	Reorg(depth uint64)
+}
+
+// Caller wraps next so its calls suffer the configured RPC faults and
+// reorgs.
+func (in *Injector) Caller(next chainindex.Caller) chainindex.Caller {
+	return &caller{in: in, next: next}
+}
+
+type caller struct {
+	in   *Injector
+	next chainindex.Caller
+}
+
+func (c *caller) CallContext(ctx context.Context, method, paramsJSON string, opts ...chainrpc.Option) (string, error) {
+	in := c.in
+	in.mu.Lock()
+	in.stats.Calls++
+	cfg := in.cfg
+	in.mu.Unlock()
+
+	if err := sleep(ctx, in.jitter(cfg.Latency)); err != nil {
+		return "", err
+	}
+	if hit, _ := in.roll(cfg.Timeout, &in.stats.Timeouts); hit {
+		if err := sleep(ctx, cfg.TimeoutAfter); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("%w: %s request timed out after %s", ErrInjected, method, cfg.TimeoutAfter)
+	}
+	if hit, v := in.roll(cfg.Error, &in.stats.Errors); hit {
+		status := []string{"HTTP 429: Too Many Requests", "HTTP 502: Bad Gateway", "HTTP 503: Service Unavailable"}[int(v*3)]
+		return "", fmt.Errorf("%w: %s", ErrInjected, status)
+	}
+	if method == "eth_blockNumber" {
+		if r, ok := c.next.(reorger); ok {
+			if hit, _ := in.roll(cfg.Reorg, &in.stats.Reorgs); hit {
+				r.Reorg(cfg.ReorgDepth)
+			}
+		}
+	}
+	out, err := c.next.CallContext(ctx, method, paramsJSON, opts...)
+	if err != nil {
+		return out, err
+	}
+	if hit, v := in.roll(cfg.Malformed, &in.stats.Malformed); hit {
+		return malform(out, v), nil
+	}
+	return out, nil
+}
+
+// malform corrupts a JSON result in one of three ways chosen by v.
+func malform(out string, v float64) string {
+	switch {
+	case v < 1.0/3 && len(out) > 1:
+		return out[:len(out)/2] // truncated
+	case v < 2.0/3:
+		return "null"
+	}
+	return `{"chaos":true}` // wrong type
+}
+
+// SinkFunc writes a batch of events, e.g. to a database.
+type SinkFunc func(ctx context.Context, events []chainindex.Event) error
+
+// Sink wraps fn so writes are delayed or fail as configured. A failed
+// write does not call fn, so nothing is partially written.
+func (in *Injector) Sink(fn SinkFunc) SinkFunc {
+	return func(ctx context.Context, events []chainindex.Event) error {
+		in.mu.Lock()
+		in.stats.SinkWrites++
+		cfg := in.cfg
+		in.mu.Unlock()
+
+		if hit, _ := in.roll(cfg.SlowSink, &in.stats.SlowSinks); hit {
+			if err := sleep(ctx, cfg.SinkDelay); err != nil {
+				return err
+			}
+		}
+		if hit, _ := in.roll(cfg.SinkError, &in.stats.SinkErrors); hit {
+			return fmt.Errorf("%w: sink write of %d events failed", ErrInjected, len(events))
+		}
+		return fn(ctx, events)
+	}
+}
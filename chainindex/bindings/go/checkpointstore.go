@@ -0,0 +1,151 @@
+package chainindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// CheckpointStore persists indexer checkpoints. The indexer runtime reads
+// and writes checkpoints only through this interface, so a store backed
+// by Redis, DynamoDB, etcd or anything else plugs in with
+// SetCheckpointStore and no native changes. NativeStore and MemoryStore
+// are the implementations shipped here.
+//
+// Implementations must be safe for concurrent use.
+type CheckpointStore interface {
+	// Save upserts cp under its chain and indexer ID.
+	Save(ctx context.Context, cp Checkpoint) error
+	// Load returns the checkpoint for chainID and indexerID, or nil and no
+	// error if none has been saved.
+	Load(ctx context.Context, chainID, indexerID string) (*Checkpoint, error)
+	// List returns every stored checkpoint, ordered by chain and indexer ID.
+	List(ctx context.Context) ([]Checkpoint, error)
+	// Delete removes the checkpoint for chainID and indexerID. Deleting one
+	// that does not exist is not an error.
+	Delete(ctx context.Context, chainID, indexerID string) error
+}
+
+// ErrCheckpointConflict is returned by CompareAndSave when another writer
+// moved the checkpoint since it was loaded.
+var ErrCheckpointConflict = errors.New("chainindex: checkpoint changed concurrently")
+
+// MemoryStore is a CheckpointStore in a Go map, lost on exit. It suits
+// tests and short-lived tools, and is a starting point for writing a
+// store of your own.
+type MemoryStore struct {
+	mu  sync.RWMutex
+	cps map[[2]string]Checkpoint
+}
+
+var _ CheckpointStore = (*MemoryStore)(nil)
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{cps: make(map[[2]string]Checkpoint)}
+}
+
+// Save upserts cp under its chain and indexer ID.
+func (m *MemoryStore) Save(ctx context.Context, cp Checkpoint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.cps[[2]string{cp.ChainID, cp.IndexerID}] = cp
+	m.mu.Unlock()
+	return nil
+}
+
+// CompareAndSave saves cp only if the stored checkpoint for its chain and
+// indexer is still prev (nil: only if none is stored), like
+// NativeStore.CompareAndSave.
+func (m *MemoryStore) CompareAndSave(ctx context.Context, cp Checkpoint, prev *Checkpoint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	key := [2]string{cp.ChainID, cp.IndexerID}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur, ok := m.cps[key]
+	switch {
+	case !ok && prev != nil:
+		return fmt.Errorf("%w: no checkpoint stored", ErrCheckpointConflict)
+	case ok && (prev == nil || cur.BlockNumber != prev.BlockNumber):
+		return fmt.Errorf("%w: stored checkpoint is at block %d", ErrCheckpointConflict, cur.BlockNumber)
+	}
+	m.cps[key] = cp
+	return nil
+}
+
+// Load returns the checkpoint for chainID and indexerID, or nil if none
+// has been saved.
+func (m *MemoryStore) Load(ctx context.Context, chainID, indexerID string) (*Checkpoint, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	cp, ok := m.cps[[2]string{chainID, indexerID}]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+	return &cp, nil
+}
+
+// List returns every checkpoint, ordered by chain and indexer ID.
+func (m *MemoryStore) List(ctx context.Context) ([]Checkpoint, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	out := make([]Checkpoint, 0, len(m.cps))
+	for _, cp := range m.cps {
+		out = append(out, cp)
+	}
+	m.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ChainID != out[j].ChainID {
+			return out[i].ChainID < out[j].ChainID
+		}
+		return out[i].IndexerID < out[j].IndexerID
+	})
+	return out, nil
+}
+
+// Delete removes the checkpoint for chainID and indexerID, if any.
+func (m *MemoryStore) Delete(ctx context.Context, chainID, indexerID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	delete(m.cps, [2]string{chainID, indexerID})
+	m.mu.Unlock()
+	return nil
+}
+
+// defaultStore, when set, replaces DefaultNativeStore behind
+// SaveCheckpoint and LoadCheckpoint.
+var defaultStore struct {
+	sync.RWMutex
+	s CheckpointStore
+}
+
+// SetCheckpointStore routes SaveCheckpoint and LoadCheckpoint, and so
+// everything in this package that checkpoints through them, to s. A nil s
+// restores DefaultNativeStore.
+func SetCheckpointStore(s CheckpointStore) {
+	defaultStore.Lock()
+	defaultStore.s = s
+	defaultStore.Unlock()
+}
+
+func checkpointStore() CheckpointStore {
+	defaultStore.RLock()
+	defer defaultStore.RUnlock()
+	if defaultStore.s == nil {
+		return DefaultNativeStore()
+	}
+	return defaultStore.s
+}
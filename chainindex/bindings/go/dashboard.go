@@ -0,0 +1,278 @@
+package chainindex
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// dashboardBuckets is how many one-minute throughput buckets are kept.
+	dashboardBuckets = 60
+	// dashboardErrors is how many recent errors are kept per indexer.
+	dashboardErrors = 20
+	// stallAfter is how long an indexer may go without a batch before it
+	// is shown as stalled.
+	stallAfter = 2 * time.Minute
+)
+
+// Indexer states shown by the dashboard.
+const (
+	StateStarting = "starting"
+	StateRunning  = "running"
+	StateStalled  = "stalled"
+	StateFailing  = "failing"
+)
+
+// ThroughputPoint is one minute of indexing work.
+type ThroughputPoint struct {
+	Minute time.Time `json:"minute"`
+	Blocks uint64    `json:"blocks"`
+	Events uint64    `json:"events"`
+}
+
+// DashboardError is an error an indexer reported.
+type DashboardError struct {
+	At      time.Time `json:"at"`
+	Message string    `json:"message"`
+}
+
+// IndexerView is the dashboard's snapshot of one indexer.
+type IndexerView struct {
+	ID    string `json:"id"`
+	Chain string `json:"chain"`
+	State string `json:"state"`
+	// Block is the last block indexed, Head the chain head last seen and
+	// Lag the difference.
+	Block      uint64            `json:"block"`
+	Head       uint64            `json:"head"`
+	Lag        uint64            `json:"lag"`
+	Checkpoint *Checkpoint       `json:"checkpoint,omitempty"`
+	Throughput []ThroughputPoint `json:"throughput"`
+	// EventsPerMinute averages the last five complete minutes.
+	EventsPerMinute float64          `json:"events_per_minute"`
+	Errors          []DashboardError `json:"errors"`
+	LastBatchAt     time.Time        `json:"last_batch_at,omitempty"`
+}
+
+// Dashboard collects what indexers report and serves it as an HTML page,
+// or as JSON to requests for a path ending in ".json" or accepting
+// application/json. It is an http.Handler, so it mounts on any mux or
+// router:
+//
+//	d := chainindex.NewDashboard()
+//	http.Handle("/indexer/", http.StripPrefix("/indexer", d))
+//
+// Indexers feed it through the Observe methods, which are safe for
+// concurrent use and cheap enough to call on every batch.
+type Dashboard struct {
+	now func() time.Time
+
+	mu       sync.Mutex
+	indexers map[string]*dashIndexer
+}
+
+type dashIndexer struct {
+	view    IndexerView
+	buckets [dashboardBuckets]ThroughputPoint
+}
+
+// NewDashboard returns an empty dashboard.
+func NewDashboard() *Dashboard {
+	return &Dashboard{now: time.Now, indexers: make(map[string]*dashIndexer)}
+}
+
+func (d *Dashboard) get(id, chain string) *dashIndexer {
+	x, ok := d.indexers[id]
+	if !ok {
+		x = &dashIndexer{view: IndexerView{ID: id, Chain: chain}}
+		d.indexers[id] = x
+	}
+	if chain != "" {
+		x.view.Chain = chain
+	}
+	return x
+}
+
+// ObserveBatch records that indexer id processed blocks from..to
+// inclusive, producing events events.
+func (d *Dashboard) ObserveBatch(id, chain string, from, to uint64, events int) {
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	x := d.get(id, chain)
+	if to > x.view.Block {
+		x.view.Block = to
+	}
+	x.view.LastBatchAt = now
+	minute := now.Truncate(time.Minute)
+	b := &x.buckets[minute.Unix()/60%dashboardBuckets]
+	if !b.Minute.Equal(minute) {
+		*b = ThroughputPoint{Minute: minute}
+	}
+	if to >= from {
+		b.Blocks += to - from + 1
+	}
+	b.Events += uint64(events)
+}
+
+// ObserveHead records the chain head indexer id last saw.
+func (d *Dashboard) ObserveHead(id string, head uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.get(id, "").view.Head = head
+}
+
+// ObserveCheckpoint records a saved checkpoint. Shard checkpoints are
+// shown under their own ID.
+func (d *Dashboard) ObserveCheckpoint(cp Checkpoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.get(cp.IndexerID, cp.ChainID).view.Checkpoint = &cp
+}
+
+// ObserveError records an error reported by indexer id.
+func (d *Dashboard) ObserveError(id string, err error) {
+	if err == nil {
+		return
+	}
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	x := d.get(id, "")
+	x.view.Errors = append(x.view.Errors, DashboardError{At: now, Message: err.Error()})
+	if n := len(x.view.Errors); n > dashboardErrors {
+		x.view.Errors = append([]DashboardError(nil), x.view.Errors[n-dashboardErrors:]...)
+	}
+}
+
+// Remove drops indexer id from the dashboard.
+func (d *Dashboard) Remove(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.indexers, id)
+}
+
+// Snapshot returns every indexer's view, ordered by ID.
+func (d *Dashboard) Snapshot() []IndexerView {
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]IndexerView, 0, len(d.indexers))
+	for _, x := range d.indexers {
+		out = append(out, x.snapshot(now))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func (x *dashIndexer) snapshot(now time.Time) IndexerView {
+	v := x.view
+	v.Errors = append([]DashboardError(nil), v.Errors...)
+	if v.Checkpoint != nil {
+		cp := *v.Checkpoint
+		v.Checkpoint = &cp
+	}
+	if v.Head > v.Block {
+		v.Lag = v.Head - v.Block
+	}
+	// One point per minute, oldest first, with idle minutes as zeros.
+	cur := now.Truncate(time.Minute)
+	v.Throughput = make([]ThroughputPoint, dashboardBuckets)
+	for i := range v.Throughput {
+		m := cur.Add(-time.Duration(dashboardBuckets-1-i) * time.Minute)
+		p := ThroughputPoint{Minute: m}
+		if b := x.buckets[m.Unix()/60%dashboardBuckets]; b.Minute.Equal(m) {
+			p = b
+		}
+		v.Throughput[i] = p
+	}
+	var sum uint64
+	for _, p := range v.Throughput[dashboardBuckets-6 : dashboardBuckets-1] {
+		sum += p.Events
+	}
+	v.EventsPerMinute = float64(sum) / 5
+	switch {
+	case v.LastBatchAt.IsZero():
+		v.State = StateStarting
+	case len(v.Errors) > 0 && v.Errors[len(v.Errors)-1].At.After(v.LastBatchAt):
+		v.State = StateFailing
+	case now.Sub(v.LastBatchAt) > stallAfter:
+		v.State = StateStalled
+	default:
+		v.State = StateRunning
+	}
+	return v
+}
+
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	views := d.Snapshot()
+	if strings.HasSuffix(r.URL.Path, ".json") || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(views)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardPage.Execute(w, views); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// sparkline renders points as an SVG polyline of events per minute.
+func sparkline(points []ThroughputPoint) template.HTML {
+	const w, h = 240, 40
+	var peak uint64 = 1
+	for _, p := range points {
+		if p.Events > peak {
+			peak = p.Events
+		}
+	}
+	var b strings.Builder
+	for i, p := range points {
+		x := float64(i) * w / float64(max(len(points)-1, 1))
+		y := h - float64(p.Events)*h/float64(peak)
+		fmt.Fprintf(&b, "%.1f,%.1f ", x, y)
+	}
+	return template.HTML(fmt.Sprintf(`<svg width="%d" height="%d" viewBox="0 0 %d %d"><polyline fill="none" stroke="#2563eb" stroke-width="1.5" points="%s"/></svg>`,
+		w, h, w, h, strings.TrimSpace(b.String())))
+}
+
+var dashboardPage = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"sparkline": sparkline,
+	"ago": func(t time.Time) string {
+		if t.IsZero() {
+			return "never"
+		}
+		return time.Since(t).Truncate(time.Second).String() + " ago"
+	},
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><meta http-equiv="refresh" content="10">
+<title>chainindex</title>
+<style>
+body{font:14px system-ui,sans-serif;margin:2em;color:#111}
+table{border-collapse:collapse;margin-bottom:2em}
+th,td{padding:.4em .8em;border-bottom:1px solid #ddd;text-align:left;vertical-align:top}
+.running{color:#15803d}.stalled{color:#b45309}.failing{color:#b91c1c}.starting{color:#6b7280}
+.err{font-family:monospace;font-size:12px;color:#b91c1c}
+</style></head><body>
+<h1>chainindex</h1>
+{{if not .}}<p>No indexers have reported yet.</p>{{end}}
+<table>
+<tr><th>Indexer</th><th>Chain</th><th>State</th><th>Block</th><th>Head</th><th>Lag</th><th>Events/min</th><th>Last hour</th><th>Checkpoint</th><th>Last batch</th></tr>
+{{range .}}<tr>
+<td>{{.ID}}</td><td>{{.Chain}}</td><td class="{{.State}}">{{.State}}</td>
+<td>{{.Block}}</td><td>{{.Head}}</td><td>{{.Lag}}</td>
+<td>{{printf "%.1f" .EventsPerMinute}}</td><td>{{sparkline .Throughput}}</td>
+<td>{{with .Checkpoint}}{{.BlockNumber}}{{else}}-{{end}}</td><td>{{ago .LastBatchAt}}</td>
+</tr>{{end}}
+</table>
+{{range .}}{{if .Errors}}<h2>Recent errors: {{.ID}}</h2>
+<table>{{range .Errors}}<tr><td>{{.At.Format "2006-01-02 15:04:05"}}</td><td class="err">{{.Message}}</td></tr>{{end}}</table>
+{{end}}{{end}}
+</body></html>
+`))
@@ -0,0 +1,172 @@
+package chainindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrDeadLetter is returned by Run when a block is dead-lettered under a
+// policy that does not skip it.
+var ErrDeadLetter = errors.New("chainindex: block dead-lettered")
+
+// ErrNotDeadLettered is returned by Retry for a block not in the
+// dead-letter list.
+var ErrNotDeadLettered = errors.New("chainindex: block is not dead-lettered")
+
+// errBackfillStalled ends a backfill whose batch keeps failing, so the
+// sequential loop, which dead-letters blocks, takes over.
+var errBackfillStalled = errors.New("chainindex: backfill batch keeps failing")
+
+// DeadLetterPolicy decides what happens to a block that keeps failing to
+// be fetched or handled.
+type DeadLetterPolicy struct {
+	// MaxAttempts is how many times a batch is tried before the indexer
+	// narrows it down to single blocks, and a single block before it is
+	// dead-lettered. Zero disables dead-lettering: failing batches are
+	// retried until they succeed.
+	MaxAttempts int
+	// Skip moves past a dead-lettered block as if it had no events, so one
+	// poison block does not stall the indexer; Retry reprocesses it later.
+	// Without Skip, Run stops with ErrDeadLetter instead.
+	Skip bool
+	// OnDeadLetter, if set, is called with each block as it is
+	// dead-lettered, e.g. to alert or to persist the list, which is kept
+	// in memory only.
+	OnDeadLetter func(DeadLetter)
+}
+
+// DeadLetter is a block that failed MaxAttempts times.
+type DeadLetter struct {
+	Block uint64 `json:"block"`
+	// Error is the last failure.
+	Error         string    `json:"error"`
+	Attempts      int       `json:"attempts"`
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	LastFailedAt  time.Time `json:"last_failed_at"`
+	// Skipped is set if the indexer moved past the block.
+	Skipped bool `json:"skipped"`
+}
+
+// WithDeadLetters applies p to blocks that keep failing.
+func WithDeadLetters(p DeadLetterPolicy) Option {
+	return func(o *indexerOptions) { o.deadLetters = p }
+}
+
+// batchFailure tracks the consecutive failures of the batch starting at
+// from.
+type batchFailure struct {
+	from     uint64
+	attempts int
+	first    time.Time
+}
+
+// DeadLetters returns the dead-lettered blocks, lowest first.
+func (ix *Indexer) DeadLetters() []DeadLetter {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	out := make([]DeadLetter, 0, len(ix.dead))
+	for _, dl := range ix.dead {
+		out = append(out, *dl)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Block < out[j].Block })
+	return out
+}
+
+// Retry fetches and handles dead-lettered block again, out of order with
+// the blocks Run is indexing; handler calls still never overlap. On
+// success the block leaves the dead-letter list, otherwise its attempt
+// count and error are updated and the error returned. Retry does not move
+// the checkpoint.
+func (ix *Indexer) Retry(ctx context.Context, block uint64) error {
+	ix.mu.Lock()
+	_, ok := ix.dead[block]
+	ix.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrNotDeadLettered, block)
+	}
+	events, err := ix.getLogs(ctx, block, block)
+	if err == nil {
+		err = ix.handle(ctx, block, block, events)
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	dl, ok := ix.dead[block]
+	switch {
+	case !ok:
+	case err != nil:
+		dl.Attempts++
+		dl.Error, dl.LastFailedAt = err.Error(), time.Now()
+	default:
+		delete(ix.dead, block)
+	}
+	return err
+}
+
+// batchFailed applies the dead-letter policy to a failure of blocks
+// from..to. It returns err for Run to retry, nil if the block was skipped,
+// or a fatalError if it was dead-lettered without Skip.
+func (ix *Indexer) batchFailed(ctx context.Context, from, to uint64, err error) error {
+	var fe *fatalError
+	limit := ix.deadLetters.MaxAttempts
+	if limit <= 0 || ctx.Err() != nil || errors.As(err, &fe) {
+		return err
+	}
+	f := &ix.failing
+	if f.attempts == 0 || f.from != from {
+		*f = batchFailure{from: from, first: time.Now()}
+	}
+	f.attempts++
+	if f.attempts < limit {
+		return err
+	}
+	if to > from {
+		// Find the failing block: go through the batch one block at a time.
+		ix.narrowTo = to
+		ix.failing = batchFailure{}
+		return err
+	}
+
+	if ix.deadLetters.Skip {
+		// The skipped block's hash is what the next batch's parent is
+		// checked against for reorgs.
+		if err := ix.trackTip(ctx, from); err != nil {
+			return err
+		}
+	}
+	dl := &DeadLetter{
+		Block:         from,
+		Error:         err.Error(),
+		Attempts:      f.attempts,
+		FirstFailedAt: f.first,
+		LastFailedAt:  time.Now(),
+		Skipped:       ix.deadLetters.Skip,
+	}
+	ix.failing = batchFailure{}
+	ix.mu.Lock()
+	ix.dead[from] = dl
+	ix.mu.Unlock()
+	if ix.deadLetters.OnDeadLetter != nil {
+		ix.deadLetters.OnDeadLetter(*dl)
+	}
+	if !ix.deadLetters.Skip {
+		return &fatalError{fmt.Errorf("%w: block %d: %v", ErrDeadLetter, from, err)}
+	}
+	ix.observeError(err)
+	return ix.deliver(ctx, from, from, nil)
+}
+
+// retryBatch is retry for one backfill batch. Under a dead-letter policy
+// it gives up after MaxAttempts with errBackfillStalled.
+func (ix *Indexer) retryBatch(ctx context.Context, f func() error) error {
+	attempts := 0
+	return ix.retry(ctx, func() error {
+		err := f()
+		if attempts++; err != nil && ix.deadLetters.MaxAttempts > 0 && attempts >= ix.deadLetters.MaxAttempts {
+			return &fatalError{fmt.Errorf("%w: %v", errBackfillStalled, err)}
+		}
+		return err
+	})
+}
@@ -0,0 +1,145 @@
+package chainindex
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/DarshanKumar89/chainfoundry/chaincodec"
+)
+
+// SchemaSet decodes events with chaincodec schemas. An event's topic0
+// selects the schema, and the set's chaincodec.SchemaRegistry the version
+// of it covering the event's block and address. SchemaSet is safe for
+// concurrent use.
+type SchemaSet struct {
+	// Strict fails the batch when an event with a known topic0 cannot be
+	// decoded, so the indexer retries it and, under a DeadLetterPolicy,
+	// eventually dead-letters the block. Otherwise the event is delivered
+	// undecoded. Either way the failure is reported to the
+	// MetricsRecorder.
+	Strict bool
+
+	reg     *chaincodec.SchemaRegistry
+	mu      sync.RWMutex
+	byTopic map[string]string // lower-case topic0 → schema name
+}
+
+// NewSchemaSet returns a set decoding with the schemas in reg, which may
+// be nil for an empty registry. Schemas already in reg are used once Map
+// names their topic0.
+func NewSchemaSet(reg *chaincodec.SchemaRegistry) *SchemaSet {
+	if reg == nil {
+		reg = chaincodec.NewSchemaRegistry()
+	}
+	return &SchemaSet{reg: reg, byTopic: make(map[string]string)}
+}
+
+// Registry returns the registry the set decodes with.
+func (s *SchemaSet) Registry() *chaincodec.SchemaRegistry { return s.reg }
+
+// Add registers a schema JSON document (see
+// chaincodec.SchemaRegistry.RegisterSchema) and maps its "fingerprint",
+// the event signature hash, to it. Add each version of a schema the same
+// way.
+func (s *SchemaSet) Add(schemaJSON string) error {
+	var h struct {
+		Name        string `json:"name"`
+		Fingerprint string `json:"fingerprint"`
+	}
+	if err := json.Unmarshal([]byte(schemaJSON), &h); err != nil {
+		return fmt.Errorf("chainindex: schema: %w", err)
+	}
+	if h.Fingerprint == "" {
+		return fmt.Errorf("chainindex: schema %s has no fingerprint", h.Name)
+	}
+	s.mu.RLock()
+	err := s.check(h.Fingerprint, h.Name)
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if err := s.reg.RegisterSchema(schemaJSON); err != nil {
+		return err
+	}
+	return s.Map(h.Fingerprint, h.Name)
+}
+
+// Map decodes events with topic0 using the registry's schema name.
+func (s *SchemaSet) Map(topic0, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.check(topic0, name); err != nil {
+		return err
+	}
+	s.byTopic[strings.ToLower(topic0)] = name
+	return nil
+}
+
+// check fails if topic0 already maps to a schema other than name. Callers
+// hold s.mu.
+func (s *SchemaSet) check(topic0, name string) error {
+	if cur, ok := s.byTopic[strings.ToLower(topic0)]; ok && cur != name {
+		return fmt.Errorf("chainindex: topic0 %s maps to schema %s, not %s", topic0, cur, name)
+	}
+	return nil
+}
+
+// Decode decodes ev against the event signature of the schema for its
+// topic0 (see chaincodec.SchemaRegistry.Decode), keeping its chain,
+// position and Removed flag. ok is false if no schema matches; a matched
+// event that decodes to no fields is an error.
+func (s *SchemaSet) Decode(ev Event) (d *chaincodec.DecodedEvent, ok bool, err error) {
+	if len(ev.Topics) == 0 {
+		return nil, false, nil
+	}
+	s.mu.RLock()
+	name, ok := s.byTopic[strings.ToLower(ev.Topics[0])]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+	d, err = s.reg.Decode(name, ev.rawLog())
+	if err != nil {
+		return nil, true, fmt.Errorf("chainindex: decode %s at block %d log %d: %w", name, ev.BlockNumber, ev.LogIndex, err)
+	}
+	if d.Name == "" {
+		d.Name = name
+	}
+	if len(d.Fields) == 0 {
+		return nil, true, fmt.Errorf("chainindex: decode %s at block %d log %d: no fields decoded", name, ev.BlockNumber, ev.LogIndex)
+	}
+	return d, true, nil
+}
+
+// WithSchemas decodes the indexer's events with s before they reach the
+// Handler or channel: each event whose topic0 s knows arrives with Decoded
+// set.
+func WithSchemas(s *SchemaSet) Option {
+	return func(o *indexerOptions) { o.schemas = s }
+}
+
+// decode sets Decoded on events in place. It returns an error only for a
+// failure under a Strict SchemaSet.
+func (ix *Indexer) decode(events []Event) error {
+	if ix.schemas == nil {
+		return nil
+	}
+	for i := range events {
+		d, ok, err := ix.schemas.Decode(events[i])
+		if err != nil {
+			if ix.metrics != nil {
+				ix.metrics.ObserveDecodeFailure(ix.cfg.ID, ix.cfg.Chain)
+			}
+			if ix.schemas.Strict {
+				return err
+			}
+			continue
+		}
+		if ok {
+			events[i].Decoded = d
+		}
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package chainindex
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/DarshanKumar89/chainfoundry/chaincodec"
+	"github.com/DarshanKumar89/chainfoundry/chainrpc"
+)
+
+// Log is a raw EVM log as returned by eth_getLogs or delivered by an
+// eth_subscribe("logs") notification. Quantities are hex-encoded.
+type Log struct {
+	Address          string   `json:"address"`
+	Topics           []string `json:"topics"`
+	Data             string   `json:"data"`
+	BlockNumber      string   `json:"blockNumber"`
+	BlockHash        string   `json:"blockHash"`
+	TransactionHash  string   `json:"transactionHash"`
+	TransactionIndex string   `json:"transactionIndex"`
+	LogIndex         string   `json:"logIndex"`
+	Removed          bool     `json:"removed"`
+}
+
+// Event is an indexed log delivered to consumers and sinks.
+//
+// An Event with Removed set is a compensating record: a previously
+// delivered event with the same BlockHash and LogIndex was reorged out of
+// the canonical chain and must be reverted downstream.
+type Event struct {
+	Chain       string   `json:"chain"`
+	BlockNumber uint64   `json:"block_number"`
+	BlockHash   string   `json:"block_hash"`
+	TxHash      string   `json:"tx_hash"`
+	TxIndex     uint64   `json:"tx_index"`
+	LogIndex    uint64   `json:"log_index"`
+	Address     string   `json:"address"`
+	Topics      []string `json:"topics"`
+	Data        string   `json:"data"`
+	Removed     bool     `json:"removed,omitempty"`
+	// Decoded is the event decoded by the indexer's SchemaSet (see
+	// WithSchemas); nil without one or when no schema matches.
+	Decoded *chaincodec.DecodedEvent `json:"decoded,omitempty"`
+}
+
+// ToEvent converts a raw log into an Event for chain.
+func (l Log) ToEvent(chain string) (Event, error) {
+	block, err := hexUint(l.BlockNumber)
+	if err != nil {
+		return Event{}, fmt.Errorf("log blockNumber: %w", err)
+	}
+	txIndex, err := hexUint(l.TransactionIndex)
+	if err != nil {
+		return Event{}, fmt.Errorf("log transactionIndex: %w", err)
+	}
+	logIndex, err := hexUint(l.LogIndex)
+	if err != nil {
+		return Event{}, fmt.Errorf("log logIndex: %w", err)
+	}
+	return Event{
+		Chain:       chain,
+		BlockNumber: block,
+		BlockHash:   l.BlockHash,
+		TxHash:      l.TransactionHash,
+		TxIndex:     txIndex,
+		LogIndex:    logIndex,
+		Address:     l.Address,
+		Topics:      l.Topics,
+		Data:        l.Data,
+		Removed:     l.Removed,
+	}, nil
+}
+
+// Decode decodes the event with schemaJSON (see chaincodec.LoadSchema)
+// into the chaincodec.DecodedEvent that sinks and rules share, keeping its
+// chain, position and Removed flag.
+func (e Event) Decode(schemaJSON string) (*chaincodec.DecodedEvent, error) {
+	return chaincodec.DecodeRawLog(e.rawLog(), schemaJSON)
+}
+
+// rawLog is the event in the form chaincodec decodes.
+func (e Event) rawLog() chaincodec.RawLog {
+	return chaincodec.RawLog{
+		Chain:       e.Chain,
+		Address:     e.Address,
+		Topics:      e.Topics,
+		Data:        e.Data,
+		BlockNumber: e.BlockNumber,
+		BlockHash:   e.BlockHash,
+		TxHash:      e.TxHash,
+		LogIndex:    e.LogIndex,
+		Removed:     e.Removed,
+	}
+}
+
+func hexUint(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 64)
+}
+
+type logKey struct {
+	blockHash string
+	logIndex  uint64
+}
+
+// LogStream turns a log subscription into a stream of Events, honouring the
+// removed flag set on logs that a reorg dropped from the canonical chain.
+//
+// Removed logs that were previously delivered produce a compensating Event
+// with Removed set; removals of logs never delivered (or already reverted)
+// are dropped. Delivered logs are remembered for Depth blocks behind the
+// highest block seen, which should cover the deepest expected reorg.
+//
+// Subscribe feeds a chainrpc log subscription through the stream to a
+// Handler, so sinks receive the compensating Events too. A LogStream is
+// not safe for concurrent use.
+type LogStream struct {
+	Chain string
+	Depth uint64
+
+	head      uint64
+	delivered map[logKey]uint64
+}
+
+// NewLogStream returns a LogStream for chain remembering depth blocks.
+func NewLogStream(chain string, depth uint64) *LogStream {
+	return &LogStream{Chain: chain, Depth: depth, delivered: make(map[logKey]uint64)}
+}
+
+// Apply processes one subscription log. It returns the Event to deliver and
+// whether it should be delivered at all.
+func (s *LogStream) Apply(l Log) (Event, bool, error) {
+	ev, err := l.ToEvent(s.Chain)
+	if err != nil {
+		return Event{}, false, err
+	}
+	if s.delivered == nil {
+		s.delivered = make(map[logKey]uint64)
+	}
+	key := logKey{blockHash: strings.ToLower(ev.BlockHash), logIndex: ev.LogIndex}
+	if ev.Removed {
+		if _, ok := s.delivered[key]; !ok {
+			return Event{}, false, nil
+		}
+		delete(s.delivered, key)
+		return ev, true, nil
+	}
+	if _, dup := s.delivered[key]; dup {
+		return Event{}, false, nil
+	}
+	s.delivered[key] = ev.BlockNumber
+	if ev.BlockNumber > s.head {
+		s.head = ev.BlockNumber
+		s.prune()
+	}
+	return ev, true, nil
+}
+
+func (s *LogStream) prune() {
+	if s.head <= s.Depth {
+		return
+	}
+	floor := s.head - s.Depth
+	for k, n := range s.delivered {
+		if n < floor {
+			delete(s.delivered, k)
+		}
+	}
+}
+
+// Subscribe streams the logs matching filter from a ws://, wss:// or IPC
+// endpoint (see chainrpc.SubscribeLogs) and hands each Event to h as a
+// batch of one, until ctx is done, the subscription ends or h fails. It
+// returns nil when ctx is cancelled.
+func (s *LogStream) Subscribe(ctx context.Context, url string, filter chainrpc.LogFilter, cfg chainrpc.SubscriptionOptions, h Handler, opts ...chainrpc.Option) error {
+	sub, err := chainrpc.SubscribeLogs(ctx, url, filter, cfg, opts...)
+	if err != nil {
+		return fmt.Errorf("chainindex: subscribe logs: %w", err)
+	}
+	defer sub.Close()
+	if err := s.handle(ctx, sub.C, h); err != nil {
+		return err
+	}
+	if err := sub.Err(); err != nil {
+		return fmt.Errorf("chainindex: log subscription: %w", err)
+	}
+	return nil
+}
+
+// handle applies every log from in and delivers the resulting Events to h
+// until in is closed or ctx is done.
+func (s *LogStream) handle(ctx context.Context, in <-chan chainrpc.Log, h Handler) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case l, ok := <-in:
+			if !ok {
+				return nil
+			}
+			ev, deliver, err := s.Apply(Log(l))
+			if err != nil {
+				return err
+			}
+			if !deliver {
+				continue
+			}
+			if err := h.HandleEvents(ctx, []Event{ev}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Run applies every log from in and sends the resulting Events to out until
+// in is closed or ctx is done. Malformed logs abort the stream.
+func (s *LogStream) Run(ctx context.Context, in <-chan Log, out chan<- Event) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case l, ok := <-in:
+			if !ok {
+				return nil
+			}
+			ev, deliver, err := s.Apply(l)
+			if err != nil {
+				return err
+			}
+			if !deliver {
+				continue
+			}
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
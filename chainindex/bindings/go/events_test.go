@@ -0,0 +1,148 @@
+package chainindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DarshanKumar89/chainfoundry/chainrpc"
+)
+
+func subLog(block uint64, hash string, index uint64, removed bool) chainrpc.Log {
+	return chainrpc.Log{
+		Address:          "0xc0ffee",
+		Topics:           []string{"0xddf252ad"},
+		Data:             "0x",
+		BlockNumber:      fmt.Sprintf("0x%x", block),
+		BlockHash:        hash,
+		TransactionHash:  fmt.Sprintf("0xt%d", block),
+		TransactionIndex: "0x0",
+		LogIndex:         fmt.Sprintf("0x%x", index),
+		Removed:          removed,
+	}
+}
+
+type recorder struct{ events []Event }
+
+func (r *recorder) HandleEvents(_ context.Context, events []Event) error {
+	r.events = append(r.events, events...)
+	return nil
+}
+
+func feed(t *testing.T, s *LogStream, h Handler, logs ...chainrpc.Log) error {
+	t.Helper()
+	in := make(chan chainrpc.Log, len(logs))
+	for _, l := range logs {
+		in <- l
+	}
+	close(in)
+	return s.handle(context.Background(), in, h)
+}
+
+type delivery struct {
+	block   uint64
+	hash    string
+	removed bool
+}
+
+func deliveries(events []Event) []delivery {
+	out := make([]delivery, len(events))
+	for i, ev := range events {
+		out[i] = delivery{ev.BlockNumber, ev.BlockHash, ev.Removed}
+	}
+	return out
+}
+
+func TestLogStreamReorgDuringSubscription(t *testing.T) {
+	s := NewLogStream("ethereum", 64)
+	r := &recorder{}
+	err := feed(t, s, r,
+		subLog(100, "0xa100", 0, false),
+		subLog(101, "0xa101", 0, false),
+		subLog(101, "0xa101", 1, false),
+		// The node reorgs block 101 out and replays it from the new fork.
+		subLog(101, "0xa101", 0, true),
+		subLog(101, "0xa101", 1, true),
+		subLog(101, "0xb101", 0, false),
+		subLog(102, "0xb102", 0, false),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []delivery{
+		{100, "0xa100", false},
+		{101, "0xa101", false},
+		{101, "0xa101", false},
+		{101, "0xa101", true},
+		{101, "0xa101", true},
+		{101, "0xb101", false},
+		{102, "0xb102", false},
+	}
+	got := deliveries(r.events)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("delivered %v, want %v", got, want)
+	}
+	if r.events[3].LogIndex != 0 || r.events[4].LogIndex != 1 {
+		t.Fatalf("reverted log indexes %d, %d", r.events[3].LogIndex, r.events[4].LogIndex)
+	}
+}
+
+func TestLogStreamDropsUnmatchedRemovals(t *testing.T) {
+	s := NewLogStream("ethereum", 64)
+	r := &recorder{}
+	err := feed(t, s, r,
+		// Removed before it was ever delivered, e.g. across a reconnect.
+		subLog(50, "0xdead", 0, true),
+		subLog(51, "0xa051", 0, false),
+		// Delivered twice, by backfill and the live stream.
+		subLog(51, "0xa051", 0, false),
+		subLog(51, "0xa051", 0, true),
+		// Reverted already.
+		subLog(51, "0xa051", 0, true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []delivery{{51, "0xa051", false}, {51, "0xa051", true}}
+	if got := deliveries(r.events); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("delivered %v, want %v", got, want)
+	}
+}
+
+func TestLogStreamForgetsBelowDepth(t *testing.T) {
+	s := NewLogStream("ethereum", 2)
+	r := &recorder{}
+	err := feed(t, s, r,
+		subLog(10, "0xa010", 0, false),
+		subLog(13, "0xa013", 0, false),
+		// Deeper than Depth: no longer remembered, so not reverted.
+		subLog(10, "0xa010", 0, true),
+		subLog(13, "0xa013", 0, true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []delivery{{10, "0xa010", false}, {13, "0xa013", false}, {13, "0xa013", true}}
+	if got := deliveries(r.events); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("delivered %v, want %v", got, want)
+	}
+}
+
+func TestLogStreamHandlerError(t *testing.T) {
+	s := NewLogStream("ethereum", 64)
+	boom := errors.New("sink down")
+	h := HandlerFunc(func(context.Context, []Event) error { return boom })
+	if err := feed(t, s, h, subLog(1, "0xa001", 0, false)); !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want %v", err, boom)
+	}
+}
+
+func TestLogStreamMalformedLog(t *testing.T) {
+	s := NewLogStream("ethereum", 64)
+	l := subLog(1, "0xa001", 0, false)
+	l.BlockNumber = "0xzz"
+	if err := feed(t, s, &recorder{}, l); err == nil {
+		t.Fatal("malformed log accepted")
+	}
+}
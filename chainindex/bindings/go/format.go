@@ -0,0 +1,245 @@
+package chainindex
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The configuration and the records the indexer delivers and reports
+// (Checkpoint, EventFilter, IndexerConfig, Event, ReorgEvent, DeadLetter
+// and IndexerStatus) render the same way everywhere: String gives one line
+// of the type name followed by key=value pairs in field order, omitting
+// unset optional fields, and MarshalJSON keeps the declared field order
+// and writes hex strings (addresses, hashes, topics) lower-case with a 0x
+// prefix, so logs and APIs built on chainindex compare and grep cleanly.
+
+// normHex lower-cases a 0x-prefixed hex string. Other strings are returned
+// trimmed but otherwise unchanged.
+func normHex(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '0' || (s[1] != 'x' && s[1] != 'X') {
+		return s
+	}
+	for _, r := range s[2:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return s
+		}
+	}
+	return "0x" + strings.ToLower(s[2:])
+}
+
+func normHexes(in []string) []string {
+	if in == nil {
+		return nil
+	}
+	out := make([]string, len(in))
+	for i, s := range in {
+		out[i] = normHex(s)
+	}
+	return out
+}
+
+// kv renders name followed by the key=value pairs in kvs, skipping empty
+// values and quoting those that contain spaces.
+func kv(name string, kvs ...string) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for i := 0; i+1 < len(kvs); i += 2 {
+		v := kvs[i+1]
+		if v == "" {
+			continue
+		}
+		if strings.ContainsAny(v, " \t\n\"=") {
+			v = strconv.Quote(v)
+		}
+		b.WriteString(" " + kvs[i] + "=" + v)
+	}
+	return b.String()
+}
+
+func optUint(n *uint64) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatUint(*n, 10)
+}
+
+func list(s []string) string {
+	if len(s) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(s, ",") + "]"
+}
+
+func flag(b bool) string {
+	if !b {
+		return ""
+	}
+	return "true"
+}
+
+func optTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func num(n uint64) string { return strconv.FormatUint(n, 10) }
+
+func fixed(f float64) string { return strconv.FormatFloat(f, 'f', 2, 64) }
+
+func (c Checkpoint) String() string {
+	return kv("checkpoint",
+		"chain", c.ChainID,
+		"indexer", c.IndexerID,
+		"block", strconv.FormatUint(c.BlockNumber, 10),
+		"hash", normHex(c.BlockHash),
+		"updated_at", strconv.FormatInt(c.UpdatedAt, 10))
+}
+
+// MarshalJSON writes the checkpoint with its block hash normalised.
+func (c Checkpoint) MarshalJSON() ([]byte, error) {
+	type plain Checkpoint
+	c.BlockHash = normHex(c.BlockHash)
+	return json.Marshal(plain(c))
+}
+
+func (f EventFilter) String() string {
+	return kv("filter",
+		"addresses", list(normHexes(f.Addresses)),
+		"topic0", list(normHexes(f.Topic0Values)),
+		"from", optUint(f.FromBlock),
+		"to", optUint(f.ToBlock))
+}
+
+// MarshalJSON writes the filter with addresses and topics normalised.
+func (f EventFilter) MarshalJSON() ([]byte, error) {
+	type plain EventFilter
+	f.Addresses, f.Topic0Values = normHexes(f.Addresses), normHexes(f.Topic0Values)
+	return json.Marshal(plain(f))
+}
+
+// String summarises the config; the filter is reduced to its size.
+func (c IndexerConfig) String() string {
+	var shard string
+	if c.Shard != nil {
+		shard = c.Shard.String()
+	}
+	var rps string
+	if c.RateLimitRPS > 0 {
+		rps = strconv.FormatFloat(c.RateLimitRPS, 'f', -1, 64)
+	}
+	var adaptive string
+	if p := c.AdaptivePoll; p != nil {
+		adaptive = strconv.FormatUint(p.MinIntervalMs, 10) + "-" + strconv.FormatUint(p.MaxIntervalMs, 10)
+	}
+	return kv("indexer",
+		"id", c.ID,
+		"chain", c.Chain,
+		"from", strconv.FormatUint(c.FromBlock, 10),
+		"to", optUint(c.ToBlock),
+		"follow", c.Follow,
+		"start_from", c.StartFrom,
+		"confirmations", strconv.FormatUint(c.ConfirmationDepth, 10),
+		"batch", strconv.FormatUint(c.BatchSize, 10),
+		"checkpoint_every", strconv.FormatUint(c.CheckpointInterval, 10),
+		"poll_ms", strconv.FormatUint(c.PollIntervalMs, 10),
+		"poll_adaptive", adaptive,
+		"addresses", strconv.Itoa(len(c.Filter.Addresses)),
+		"topics", strconv.Itoa(len(c.Filter.Topic0Values)),
+		"rps", rps,
+		"shard", shard)
+}
+
+// String summarises the event; the data is left out.
+func (e Event) String() string {
+	var schema string
+	if e.Decoded != nil {
+		schema = e.Decoded.Name
+	}
+	return kv("event",
+		"chain", e.Chain,
+		"block", num(e.BlockNumber),
+		"hash", normHex(e.BlockHash),
+		"tx", normHex(e.TxHash),
+		"tx_index", num(e.TxIndex),
+		"log", num(e.LogIndex),
+		"address", normHex(e.Address),
+		"topics", list(normHexes(e.Topics)),
+		"removed", flag(e.Removed),
+		"schema", schema)
+}
+
+// MarshalJSON writes the event with its hashes, address, topics and data
+// normalised.
+func (e Event) MarshalJSON() ([]byte, error) {
+	type plain Event
+	e.BlockHash, e.TxHash, e.Address = normHex(e.BlockHash), normHex(e.TxHash), normHex(e.Address)
+	e.Topics, e.Data = normHexes(e.Topics), normHex(e.Data)
+	return json.Marshal(plain(e))
+}
+
+func (b RemovedBlock) String() string {
+	return kv("block", "number", num(b.Number), "hash", normHex(b.Hash))
+}
+
+// MarshalJSON writes the block with its hash normalised.
+func (b RemovedBlock) MarshalJSON() ([]byte, error) {
+	type plain RemovedBlock
+	b.Hash = normHex(b.Hash)
+	return json.Marshal(plain(b))
+}
+
+// String summarises the reorg; the removed blocks are reduced to their
+// numbers.
+func (r ReorgEvent) String() string {
+	removed := make([]string, len(r.RemovedBlocks))
+	for i, b := range r.RemovedBlocks {
+		removed[i] = num(b.Number)
+	}
+	return kv("reorg",
+		"chain", r.Chain,
+		"ancestor", num(r.CommonAncestor),
+		"ancestor_hash", normHex(r.CommonAncestorHash),
+		"removed", list(removed),
+		"detected_at", optTime(r.DetectedAt))
+}
+
+// MarshalJSON writes the reorg with its hashes normalised.
+func (r ReorgEvent) MarshalJSON() ([]byte, error) {
+	type plain ReorgEvent
+	r.CommonAncestorHash = normHex(r.CommonAncestorHash)
+	return json.Marshal(plain(r))
+}
+
+func (d DeadLetter) String() string {
+	return kv("dead_letter",
+		"block", num(d.Block),
+		"error", d.Error,
+		"attempts", strconv.Itoa(d.Attempts),
+		"first_failed_at", optTime(d.FirstFailedAt),
+		"last_failed_at", optTime(d.LastFailedAt),
+		"skipped", flag(d.Skipped))
+}
+
+func (s IndexerStatus) String() string {
+	return kv("status",
+		"id", s.ID,
+		"chain", s.Chain,
+		"state", s.State,
+		"head", num(s.Head),
+		"target", num(s.Target),
+		"block", optUint(s.Block),
+		"lag_blocks", num(s.LagBlocks),
+		"lag_seconds", fixed(s.LagSeconds),
+		"blocks_per_second", fixed(s.BlocksPerSecond),
+		"events_per_second", fixed(s.EventsPerSecond),
+		"errors", num(s.Errors),
+		"consecutive_errors", num(s.ConsecutiveErrors),
+		"last_error", s.LastError,
+		"last_error_at", optTime(s.LastErrorAt),
+		"dead_letters", strconv.Itoa(s.DeadLetters))
+}
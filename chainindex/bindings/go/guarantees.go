@@ -0,0 +1,78 @@
+package chainindex
+
+import "fmt"
+
+// Delivery is the delivery semantics an indexer provides.
+type Delivery string
+
+const (
+	// AtLeastOnce means an event may be delivered more than once, e.g. when
+	// blocks after the last checkpoint are re-indexed on restart.
+	AtLeastOnce Delivery = "at-least-once"
+)
+
+// Guarantees is the machine-readable contract an indexer makes to its
+// consumers. Key fields name Event JSON fields, so downstream systems can
+// build unique constraints and dedupe logic from it directly.
+type Guarantees struct {
+	// Delivery is the delivery semantics; exactly-once requires consumers
+	// to upsert on EntityKey.
+	Delivery Delivery `json:"delivery"`
+	// EntityKey identifies an on-chain event independently of which block
+	// included it: a transaction re-included after a reorg keeps its key.
+	// It matches the Rust deterministic_id ("{tx_hash}-{log_index}") and is
+	// the column set for a unique constraint or upsert.
+	EntityKey []string `json:"entity_key"`
+	// DeliveryKey identifies one delivery of an event in one block. A
+	// compensating Event (Removed set) carries the DeliveryKey of the event
+	// it reverts.
+	DeliveryKey []string `json:"delivery_key"`
+	// OrderingKey is the order events are delivered in within one chain:
+	// ascending, except that compensating events arrive after the events
+	// they revert and before their replacements.
+	OrderingKey []string `json:"ordering_key"`
+	// RemovedField is the Event field set on compensating events.
+	RemovedField string `json:"removed_field"`
+	// ReorgDepth is how many blocks behind the head events may still be
+	// reverted; events deeper than this are final. It is zero when
+	// following "finalized" blocks.
+	ReorgDepth uint64 `json:"reorg_depth"`
+	// RedeliveryWindow is the most blocks that may be delivered again after
+	// a restart: everything since the last checkpoint.
+	RedeliveryWindow uint64 `json:"redelivery_window"`
+	// CheckpointID is the key under which progress is stored.
+	CheckpointID string `json:"checkpoint_id"`
+	// Shard, if set, limits events to one address partition; keys stay
+	// unique across shards because partitions do not overlap.
+	Shard *Shard `json:"shard,omitempty"`
+}
+
+// Guarantees returns the delivery, ordering and idempotency contract of an
+// indexer running with cfg.
+func (cfg *IndexerConfig) Guarantees() Guarantees {
+	g := Guarantees{
+		Delivery:         AtLeastOnce,
+		EntityKey:        []string{"chain", "tx_hash", "log_index"},
+		DeliveryKey:      []string{"chain", "block_hash", "log_index"},
+		OrderingKey:      []string{"block_number", "log_index"},
+		RemovedField:     "removed",
+		ReorgDepth:       cfg.ConfirmationDepth,
+		RedeliveryWindow: cfg.CheckpointInterval,
+		CheckpointID:     cfg.CheckpointID(),
+	}
+	if follow, _ := cfg.follow(); follow == FollowFinalized {
+		g.ReorgDepth = 0
+	}
+	if cfg.Shard != nil {
+		s := *cfg.Shard
+		g.Shard = &s
+	}
+	return g
+}
+
+// EntityID renders ev's EntityKey as "{tx_hash}-{log_index}", the same ID
+// the Rust indexer's deterministic_id produces. Chain is omitted to match;
+// prefix it when one table holds several chains.
+func (g Guarantees) EntityID(ev Event) string {
+	return fmt.Sprintf("%s-%d", ev.TxHash, ev.LogIndex)
+}
@@ -0,0 +1,602 @@
+package chainindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DarshanKumar89/chainfoundry/chainrpc"
+)
+
+// Defaults for IndexerConfig fields left zero, matching the native
+// DefaultConfig.
+const (
+	defaultBatchSize          = 1000
+	defaultCheckpointInterval = 100
+	defaultPollInterval       = 2 * time.Second
+	// maxRetryBackoff caps the wait between attempts after RPC errors.
+	maxRetryBackoff = 30 * time.Second
+)
+
+// ErrIndexerRunning is returned by Run when the indexer is already running.
+var ErrIndexerRunning = errors.New("chainindex: indexer is already running")
+
+// Option configures an Indexer.
+type Option func(*indexerOptions)
+
+type indexerOptions struct {
+	rpc         Caller
+	endpoints   []string
+	store       CheckpointStore
+	dashboard   *Dashboard
+	handler     Handler
+	reorgs      ReorgHandler
+	workers     int
+	rangeSize   uint64
+	metrics     MetricsRecorder
+	deadLetters DeadLetterPolicy
+	schemas     *SchemaSet
+	lag         *LagTracker
+}
+
+// Handler receives the matched events of each batch, in block and log
+// order. The indexer checkpoints past a batch only after HandleEvents
+// returns nil for it; an error makes the indexer retry the batch after a
+// backoff, so a batch may be handled more than once (see Guarantees).
+// HandleEvents is never called with an empty slice or concurrently.
+type Handler interface {
+	HandleEvents(ctx context.Context, events []Event) error
+}
+
+// HandlerFunc adapts a function to a Handler.
+type HandlerFunc func(ctx context.Context, events []Event) error
+
+// HandleEvents calls f.
+func (f HandlerFunc) HandleEvents(ctx context.Context, events []Event) error { return f(ctx, events) }
+
+// WithHandler delivers matched events to h.
+func WithHandler(h Handler) Option {
+	return func(o *indexerOptions) { o.handler = h }
+}
+
+// WithChannel delivers matched events to ch, one at a time and in order.
+// A batch counts as handled once every event in it has been received, so
+// the indexer blocks, and does not checkpoint, while the reader lags. The
+// indexer never closes ch.
+func WithChannel(ch chan<- Event) Option {
+	return WithHandler(HandlerFunc(func(ctx context.Context, events []Event) error {
+		for _, ev := range events {
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	}))
+}
+
+// WithRPC reads the chain through rpc, e.g. a *chainrpc.Pool shared with
+// other indexers.
+func WithRPC(rpc Caller) Option {
+	return func(o *indexerOptions) { o.rpc = rpc }
+}
+
+// WithEndpoints reads the chain through a chainrpc pool over urls, tried
+// in order.
+func WithEndpoints(urls ...string) Option {
+	return func(o *indexerOptions) { o.endpoints = urls }
+}
+
+// WithCheckpointStore keeps the indexer's position in store instead of
+// the one set with SetCheckpointStore.
+func WithCheckpointStore(store CheckpointStore) Option {
+	return func(o *indexerOptions) { o.store = store }
+}
+
+// WithDashboard reports batches, the head, checkpoints and errors to d.
+func WithDashboard(d *Dashboard) Option {
+	return func(o *indexerOptions) { o.dashboard = d }
+}
+
+// Indexer runs the fetch → filter → checkpoint loop for one IndexerConfig
+// in Go. It reads confirmed blocks in batches with eth_getLogs, hands the
+// logs matching the config's (sharded) filter to its Handler, and saves a
+// checkpoint every CheckpointInterval blocks, resuming from the last one
+// on restart.
+type Indexer struct {
+	cfg     IndexerConfig
+	follow  string
+	filter  EventFilter
+	rpc     Caller
+	limiter *limitedCaller // wraps rpc, for RateLimitRPS
+	store   CheckpointStore
+	dash    *Dashboard
+	handler Handler
+	reorgs  ReorgHandler
+	metrics MetricsRecorder
+	schemas *SchemaSet
+	lag     *LagTracker
+
+	workers     int
+	rangeSize   uint64
+	deadLetters DeadLetterPolicy
+
+	hmu sync.Mutex // serializes handler calls
+
+	mu       sync.Mutex
+	running  bool
+	next     uint64 // next block to fetch
+	unsaved  uint64 // blocks indexed since the last checkpoint
+	progress progress
+	dead     map[uint64]*DeadLetter
+	reload   *IndexerConfig // set by Reload for Run to apply
+
+	// Lifecycle, see lifecycle.go.
+	paused  bool
+	wake    chan struct{}      // closed by Resume
+	cancel  context.CancelFunc // cancels the current run
+	stopCtx context.Context    // set by Stop
+	done    chan struct{}      // closed when the current run returns
+	runErr  error
+
+	// Owned by Run.
+	window   *blockWindow
+	failing  batchFailure
+	narrowTo uint64 // if set, index single blocks up to here; see batchFailed
+}
+
+// NewIndexer returns an indexer for cfg. The chain is read through
+// WithRPC or WithEndpoints, one of which is required, and events go to
+// WithHandler or WithChannel; without either they are only counted, which
+// suits a dry run. Zero BatchSize, CheckpointInterval and PollIntervalMs
+// take the native defaults.
+func NewIndexer(cfg IndexerConfig, opts ...Option) (*Indexer, error) {
+	var o indexerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if cfg.ID == "" || cfg.Chain == "" {
+		return nil, fmt.Errorf("chainindex: indexer config needs an id and a chain")
+	}
+	cfg = withDefaults(cfg)
+	follow, err := cfg.follow()
+	if err != nil {
+		return nil, err
+	}
+	filter, err := cfg.ShardedFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	rpc := o.rpc
+	if rpc == nil {
+		if len(o.endpoints) == 0 {
+			return nil, fmt.Errorf("chainindex: indexer %s has no RPC; use WithRPC or WithEndpoints", cfg.ID)
+		}
+		pool, err := chainrpc.NewPool(o.endpoints, chainrpc.PoolOption{})
+		if err != nil {
+			return nil, err
+		}
+		rpc = pool
+	}
+	// Calls always go through the limiter, so Reload can change the rate.
+	limiter := &limitedCaller{rpc: rpc}
+	limiter.setRate(cfg.RateLimitRPS)
+	store := o.store
+	if store == nil {
+		store = checkpointStore()
+	}
+	reorgs := o.reorgs
+	if rh, ok := o.handler.(ReorgHandler); ok && reorgs == nil {
+		reorgs = rh
+	}
+	if o.rangeSize == 0 {
+		o.rangeSize = defaultRangeBatches * cfg.BatchSize
+	}
+	return &Indexer{
+		cfg: cfg, follow: follow, filter: filter, rpc: limiter, limiter: limiter, store: store, dash: o.dashboard,
+		handler: o.handler, reorgs: reorgs, metrics: o.metrics, workers: o.workers, rangeSize: o.rangeSize,
+		deadLetters: o.deadLetters, schemas: o.schemas, lag: o.lag, dead: make(map[uint64]*DeadLetter),
+	}, nil
+}
+
+// withDefaults fills the zero fields that take the native defaults.
+func withDefaults(cfg IndexerConfig) IndexerConfig {
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.CheckpointInterval == 0 {
+		cfg.CheckpointInterval = defaultCheckpointInterval
+	}
+	if cfg.PollIntervalMs == 0 {
+		cfg.PollIntervalMs = uint64(defaultPollInterval / time.Millisecond)
+	}
+	return cfg
+}
+
+// Config returns the indexer's effective config. A config passed to Reload
+// while the indexer runs takes effect at the next batch.
+func (ix *Indexer) Config() IndexerConfig {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.cfg
+}
+
+// Run indexes until ctx is done, or until cfg.ToBlock is indexed and
+// checkpointed, in which case it returns nil. Without a ToBlock it follows
+// the cfg.Follow block indefinitely. RPC and handler errors are retried
+// with backoff; a checkpoint store error or ErrDeepReorg stops the run.
+// Blocks after the last checkpoint are indexed again by the next run, as
+// Guarantees describes; use Stop instead of cancelling ctx to checkpoint
+// them first.
+func (ix *Indexer) Run(ctx context.Context) error {
+	ix.mu.Lock()
+	if ix.running {
+		ix.mu.Unlock()
+		return ErrIndexerRunning
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	ix.running, ix.cancel, ix.stopCtx, ix.done = true, cancel, nil, make(chan struct{})
+	ix.mu.Unlock()
+
+	err := ix.run(runCtx)
+	cancel()
+	ix.mu.Lock()
+	stopCtx := ix.stopCtx
+	ix.mu.Unlock()
+	if stopCtx != nil && ctx.Err() == nil && (err == nil || errors.Is(err, context.Canceled)) {
+		// Stopped with Stop: the run's own context is cancelled, so flush
+		// the final checkpoint under the caller of Stop's.
+		err = ix.flush(stopCtx)
+		var fe *fatalError
+		if errors.As(err, &fe) {
+			err = fe.err
+		}
+	}
+
+	ix.mu.Lock()
+	ix.running, ix.cancel, ix.runErr = false, nil, err
+	close(ix.done)
+	ix.mu.Unlock()
+	return err
+}
+
+// run is Run's fetch loop.
+func (ix *Indexer) run(ctx context.Context) error {
+	if err := ix.resume(ctx); err != nil {
+		return err
+	}
+	if err := ix.runBackfill(ctx); err != nil {
+		var fe *fatalError
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case errors.As(err, &fe):
+			return fe.err
+		}
+		return err
+	}
+	poll := newPollInterval(ix.cfg)
+	base := time.Duration(ix.cfg.PollIntervalMs) * time.Millisecond
+	backoff := base
+	for {
+		if err := ix.waitIfPaused(ctx); err != nil {
+			return err
+		}
+		if ix.applyReload() {
+			poll = newPollInterval(ix.cfg)
+			base = time.Duration(ix.cfg.PollIntervalMs) * time.Millisecond
+			backoff = base
+		}
+		done, err := ix.step(ctx)
+		switch {
+		case err == nil && done:
+			return nil
+		case err == nil:
+			poll.progress()
+			backoff = base
+			continue
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case errors.Is(err, errIdle):
+			err = sleepCtx(ctx, poll.idle())
+		default:
+			var fe *fatalError
+			if errors.As(err, &fe) {
+				return fe.err
+			}
+			ix.observeError(err)
+			if chainrpc.ErrorClass(err) == chainrpc.StatusRateLimited {
+				poll.throttled()
+				backoff = max(backoff, poll.cur)
+			}
+			err = sleepCtx(ctx, backoff)
+			backoff = min(2*backoff, maxRetryBackoff)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// errIdle is returned by step when there is no confirmed block to fetch.
+var errIdle = errors.New("chainindex: caught up")
+
+// fatalError marks a failure Run does not retry.
+type fatalError struct{ err error }
+
+func (e *fatalError) Error() string { return e.err.Error() }
+func (e *fatalError) Unwrap() error { return e.err }
+
+// resume sets the next block from the checkpoint, or from the config's
+// start block if there is none. The checkpoint's hash seeds the reorg
+// window, so a reorg while the indexer was down is caught.
+func (ix *Indexer) resume(ctx context.Context) error {
+	cp, err := ix.store.Load(ctx, ix.cfg.Chain, ix.cfg.CheckpointID())
+	if err != nil {
+		return fmt.Errorf("chainindex: load checkpoint: %w", err)
+	}
+	ix.window = newBlockWindow(ix.cfg.ConfirmationDepth)
+	var next uint64
+	if cp != nil {
+		next = cp.BlockNumber + 1
+		ix.window.add(cp.BlockNumber, cp.BlockHash)
+	} else if next, err = ResolveFromBlock(ctx, &ix.cfg, ix.rpc); err != nil {
+		return err
+	}
+	ix.mu.Lock()
+	ix.next = next
+	ix.unsaved = 0
+	ix.progress.confirmed = nil
+	if cp != nil {
+		ix.progress.setLast(cp.BlockNumber)
+	}
+	ix.mu.Unlock()
+	return nil
+}
+
+// step indexes one batch of confirmed blocks. It reports done once
+// cfg.ToBlock has been indexed and checkpointed.
+func (ix *Indexer) step(ctx context.Context) (done bool, err error) {
+	ix.mu.Lock()
+	next := ix.next
+	ix.mu.Unlock()
+	if to := ix.cfg.ToBlock; to != nil && next > *to {
+		return true, nil
+	}
+
+	head, target, ok, err := ix.target(ctx)
+	if err != nil {
+		return false, err
+	}
+	if ix.dash != nil {
+		ix.dash.ObserveHead(ix.cfg.ID, head)
+	}
+	if !ok || next > target {
+		return false, errIdle
+	}
+	to := min(next+ix.cfg.BatchSize-1, target)
+	if ix.narrowTo > 0 && next <= ix.narrowTo {
+		to = next
+	}
+	// A failure to check for or handle a reorg is not the batch's: Run
+	// retries it without counting it against block next.
+	rewound, err := ix.checkReorg(ctx, next)
+	if err != nil {
+		return false, err
+	}
+	if !rewound {
+		if err := ix.indexBatch(ctx, next, to); err != nil {
+			return false, ix.batchFailed(ctx, next, to, err)
+		}
+	}
+	ix.failing = batchFailure{}
+	return false, nil
+}
+
+// indexBatch fetches, handles and records blocks from..to.
+func (ix *Indexer) indexBatch(ctx context.Context, from, to uint64) error {
+	events, err := ix.getLogs(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	if err := ix.trackTip(ctx, to); err != nil {
+		return err
+	}
+	return ix.deliver(ctx, from, to, events)
+}
+
+// deliver hands the events of blocks from..to to the handler, records
+// their hashes for reorg detection, and advances past them, checkpointing
+// when due.
+func (ix *Indexer) deliver(ctx context.Context, from, to uint64, events []Event) error {
+	if err := ix.handle(ctx, from, to, events); err != nil {
+		return err
+	}
+	for _, ev := range events {
+		ix.window.add(ev.BlockNumber, ev.BlockHash)
+	}
+	if ix.dash != nil {
+		ix.dash.ObserveBatch(ix.cfg.ID, ix.cfg.Chain, from, to, len(events))
+	}
+
+	ix.mu.Lock()
+	ix.next = to + 1
+	ix.unsaved += to - from + 1
+	ix.progress.observeIndexed(from, to, len(events))
+	target, head := ix.progress.target, ix.progress.head
+	due := ix.unsaved >= ix.cfg.CheckpointInterval || (ix.cfg.ToBlock != nil && to == *ix.cfg.ToBlock)
+	ix.mu.Unlock()
+	if ix.metrics != nil {
+		ix.metrics.ObserveProgress(ix.cfg.ID, ix.cfg.Chain, to, target, head, len(events))
+	}
+	if due {
+		return ix.checkpoint(ctx, to)
+	}
+	return nil
+}
+
+// handle hands the events of blocks from..to to the handler, one call at
+// a time, and reports the blocks acknowledged to the LagTracker.
+func (ix *Indexer) handle(ctx context.Context, from, to uint64, events []Event) error {
+	if len(events) > 0 && ix.handler != nil {
+		ix.hmu.Lock()
+		err := ix.handler.HandleEvents(ctx, events)
+		ix.hmu.Unlock()
+		if err != nil {
+			return fmt.Errorf("chainindex: handle blocks %d-%d: %w", from, to, err)
+		}
+	}
+	if ix.lag != nil {
+		ix.lag.ObserveAck(to, len(events))
+	}
+	return nil
+}
+
+// trackTip records the hash of block to, the parent the batch after it is
+// checked against for reorgs.
+func (ix *Indexer) trackTip(ctx context.Context, to uint64) error {
+	if _, ok := ix.window.hashes[to]; ok {
+		return nil
+	}
+	hash, err := blockHash(ctx, ix.rpc, to)
+	if err != nil {
+		return err
+	}
+	ix.window.add(to, hash)
+	return nil
+}
+
+// checkpoint saves block as the indexer's position.
+func (ix *Indexer) checkpoint(ctx context.Context, block uint64) error {
+	hash, ok := ix.window.hashes[block]
+	if !ok {
+		var err error
+		if hash, err = blockHash(ctx, ix.rpc, block); err != nil {
+			return err
+		}
+	}
+	cp := Checkpoint{
+		ChainID:     ix.cfg.Chain,
+		IndexerID:   ix.cfg.CheckpointID(),
+		BlockNumber: block,
+		BlockHash:   hash,
+		UpdatedAt:   time.Now().Unix(),
+	}
+	err := ix.store.Save(ctx, cp)
+	if ix.metrics != nil {
+		status := chainrpc.StatusOK
+		if err != nil {
+			status = statusError
+		}
+		ix.metrics.ObserveCheckpointWrite(ix.cfg.ID, ix.cfg.Chain, status)
+	}
+	if err != nil {
+		return &fatalError{fmt.Errorf("chainindex: save checkpoint: %w", err)}
+	}
+	ix.mu.Lock()
+	ix.unsaved = 0
+	ix.mu.Unlock()
+	if ix.dash != nil {
+		ix.dash.ObserveCheckpoint(cp)
+	}
+	return nil
+}
+
+func (ix *Indexer) observeError(err error) {
+	ix.mu.Lock()
+	ix.progress.errors++
+	ix.progress.consecutive++
+	ix.progress.lastErr, ix.progress.lastErrAt = err, time.Now()
+	ix.mu.Unlock()
+	if ix.dash != nil {
+		ix.dash.ObserveError(ix.cfg.ID, err)
+	}
+}
+
+// keep returns the events f matches. Providers apply the filter already;
+// this drops anything outside it, e.g. another shard's addresses.
+func (f EventFilter) keep(events []Event) []Event {
+	if len(f.Addresses) == 0 && len(f.Topic0Values) == 0 {
+		return events
+	}
+	out := events[:0]
+	for _, ev := range events {
+		if !matchesAny(f.Addresses, ev.Address) {
+			continue
+		}
+		var topic0 string
+		if len(ev.Topics) > 0 {
+			topic0 = ev.Topics[0]
+		}
+		if !matchesAny(f.Topic0Values, topic0) {
+			continue
+		}
+		out = append(out, ev)
+	}
+	return out
+}
+
+// matchesAny reports whether s equals one of values, ignoring case; an
+// empty values matches everything.
+func matchesAny(values []string, s string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// sleepCtx waits for d or until ctx is done.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// limitedCaller spaces calls at least every apart, for RateLimitRPS. A
+// zero every does not limit.
+type limitedCaller struct {
+	rpc Caller
+
+	mu    sync.Mutex
+	every time.Duration
+	next  time.Time
+}
+
+func (l *limitedCaller) setRate(rps float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.every = 0
+	if rps > 0 {
+		l.every = time.Duration(float64(time.Second) / rps)
+	}
+}
+
+func (l *limitedCaller) CallContext(ctx context.Context, method, paramsJSON string, opts ...chainrpc.Option) (string, error) {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.every)
+	l.mu.Unlock()
+	if err := sleepCtx(ctx, at.Sub(now)); err != nil {
+		return "", err
+	}
+	return l.rpc.CallContext(ctx, method, paramsJSON, opts...)
+}
@@ -0,0 +1,94 @@
+package chainindex
+
+import "context"
+
+// Pause holds the indexer after the batch in flight: it checkpoints what
+// it has handled and fetches nothing more until Resume. Pausing an indexer
+// that is not running makes its next Run wait from the start.
+func (ix *Indexer) Pause() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if !ix.paused {
+		ix.paused, ix.wake = true, make(chan struct{})
+	}
+}
+
+// Resume lets a paused indexer carry on.
+func (ix *Indexer) Resume() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.paused {
+		ix.paused = false
+		close(ix.wake)
+	}
+}
+
+// Paused reports whether the indexer is paused.
+func (ix *Indexer) Paused() bool {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.paused
+}
+
+// Stop drains a running indexer for a deploy or shutdown: it stops
+// fetching, abandons the batch in flight, checkpoints the last block
+// handled so a restart resumes exactly there, and waits for Run to
+// return, which it then does with nil. Stop returns the final checkpoint's
+// error, or ctx's if ctx ends first. Stopping an indexer that is not
+// running does nothing.
+func (ix *Indexer) Stop(ctx context.Context) error {
+	ix.mu.Lock()
+	if !ix.running {
+		ix.mu.Unlock()
+		return nil
+	}
+	if ix.stopCtx == nil {
+		ix.stopCtx = ctx
+	}
+	cancel, done := ix.cancel, ix.done
+	ix.mu.Unlock()
+	cancel()
+	select {
+	case <-done:
+		ix.mu.Lock()
+		defer ix.mu.Unlock()
+		return ix.runErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitIfPaused is called between batches. It returns ctx's error once ctx
+// is done, and blocks while the indexer is paused, checkpointing first so
+// nothing handled is indexed again if it is restarted meanwhile.
+func (ix *Indexer) waitIfPaused(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ix.mu.Lock()
+		paused, wake := ix.paused, ix.wake
+		ix.mu.Unlock()
+		if !paused {
+			return nil
+		}
+		if err := ix.flush(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-wake:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// flush checkpoints the last block handled, if it is not yet.
+func (ix *Indexer) flush(ctx context.Context) error {
+	ix.mu.Lock()
+	next, unsaved := ix.next, ix.unsaved
+	ix.mu.Unlock()
+	if unsaved == 0 || next == 0 {
+		return nil
+	}
+	return ix.checkpoint(ctx, next-1)
+}
@@ -0,0 +1,315 @@
+package chainindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/DarshanKumar89/chainfoundry/chainrpc"
+)
+
+// Lifecycle states IndexerManager.Status reports, besides StateRunning.
+const (
+	// StateAdded is an indexer that has not been started.
+	StateAdded = "added"
+	// StatePaused is a running indexer held with Pause.
+	StatePaused = "paused"
+	// StateStopped is an indexer stopped with Stop, or by Run returning.
+	StateStopped = "stopped"
+	// StateDone is an indexer whose Run finished at its ToBlock.
+	StateDone = "done"
+	// StateFailed is an indexer whose Run returned an error.
+	StateFailed = "failed"
+)
+
+// ErrUnknownIndexer is returned for an indexer ID the manager does not have.
+var ErrUnknownIndexer = errors.New("chainindex: unknown indexer")
+
+// ManagerOption configures an IndexerManager.
+type ManagerOption func(*IndexerManager)
+
+// WithChainEndpoints reads chain through one chainrpc pool over urls,
+// shared by every indexer of that chain.
+func WithChainEndpoints(chain string, urls ...string) ManagerOption {
+	return func(m *IndexerManager) { m.endpoints[chain] = urls }
+}
+
+// WithChainRPC reads chain through rpc, shared by every indexer of that
+// chain.
+func WithChainRPC(chain string, rpc Caller) ManagerOption {
+	return func(m *IndexerManager) { m.rpcs[chain] = rpc }
+}
+
+// WithIndexerDefaults applies opts to every indexer the manager adds,
+// before that indexer's own options, e.g. a shared WithCheckpointStore or
+// WithDashboard.
+func WithIndexerDefaults(opts ...Option) ManagerOption {
+	return func(m *IndexerManager) { m.defaults = append(m.defaults, opts...) }
+}
+
+// ManagedStatus is one indexer's state in an IndexerManager: its Status,
+// with State also StateAdded, StateDone or StateFailed.
+type ManagedStatus struct {
+	IndexerStatus
+	// Error is why the indexer failed, if it did.
+	Error string `json:"error,omitempty"`
+}
+
+// IndexerManager runs many indexers, over any mix of chains and filters,
+// in one process. Indexers of the same chain share one RPC pool, set with
+// WithChainEndpoints or WithChainRPC; each can be started, stopped and
+// removed on its own while the others keep running.
+type IndexerManager struct {
+	defaults  []Option
+	endpoints map[string][]string
+
+	mu       sync.Mutex
+	rpcs     map[string]Caller
+	indexers map[string]*managedIndexer
+}
+
+type managedIndexer struct {
+	ix       *Indexer
+	state    string
+	err      error
+	stopping bool
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewIndexerManager returns a manager with no indexers.
+func NewIndexerManager(opts ...ManagerOption) *IndexerManager {
+	m := &IndexerManager{
+		endpoints: make(map[string][]string),
+		rpcs:      make(map[string]Caller),
+		indexers:  make(map[string]*managedIndexer),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Add creates an indexer for cfg without starting it. It reads cfg.Chain
+// through the chain's shared RPC unless opts include WithRPC or
+// WithEndpoints.
+func (m *IndexerManager) Add(cfg IndexerConfig, opts ...Option) (*Indexer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.indexers[cfg.ID]; ok {
+		return nil, fmt.Errorf("chainindex: indexer %s already added", cfg.ID)
+	}
+	all := append([]Option(nil), m.defaults...)
+	var o indexerOptions
+	for _, opt := range append(all, opts...) {
+		opt(&o)
+	}
+	if o.rpc == nil && len(o.endpoints) == 0 {
+		rpc, err := m.chainRPC(cfg.Chain)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, WithRPC(rpc))
+	}
+	ix, err := NewIndexer(cfg, append(all, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	m.indexers[cfg.ID] = &managedIndexer{ix: ix, state: StateAdded}
+	return ix, nil
+}
+
+// chainRPC returns the shared RPC of chain, opening its pool on first use.
+func (m *IndexerManager) chainRPC(chain string) (Caller, error) {
+	if rpc, ok := m.rpcs[chain]; ok {
+		return rpc, nil
+	}
+	urls := m.endpoints[chain]
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("chainindex: no RPC for chain %s; use WithChainEndpoints or WithChainRPC", chain)
+	}
+	pool, err := chainrpc.NewPool(urls, chainrpc.PoolOption{})
+	if err != nil {
+		return nil, err
+	}
+	m.rpcs[chain] = pool
+	return pool, nil
+}
+
+// Indexer returns the indexer added under id, or nil.
+func (m *IndexerManager) Indexer(id string) *Indexer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if x, ok := m.indexers[id]; ok {
+		return x.ix
+	}
+	return nil
+}
+
+// Start runs indexer id in the background until Stop, Remove or ctx
+// ending. Starting a running indexer is a no-op.
+func (m *IndexerManager) Start(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	x, ok := m.indexers[id]
+	if !ok {
+		return fmt.Errorf("%w %s", ErrUnknownIndexer, id)
+	}
+	if x.state == StateRunning {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	x.state, x.err, x.stopping, x.cancel, x.done = StateRunning, nil, false, cancel, make(chan struct{})
+	go func(done chan struct{}) {
+		defer close(done)
+		err := x.ix.Run(ctx)
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		switch {
+		case x.stopping && err == nil:
+			x.state = StateStopped
+		case err == nil:
+			x.state = StateDone
+		case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+			x.state = StateStopped
+		default:
+			x.state, x.err = StateFailed, err
+		}
+		cancel()
+	}(x.done)
+	return nil
+}
+
+// Stop drains indexer id with Indexer.Stop, checkpointing its last
+// handled block, and waits for it to return.
+func (m *IndexerManager) Stop(ctx context.Context, id string) error {
+	m.mu.Lock()
+	x, ok := m.indexers[id]
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("%w %s", ErrUnknownIndexer, id)
+	}
+	x.stopping = x.state == StateRunning
+	done := x.done
+	m.mu.Unlock()
+	if err := x.ix.Stop(ctx); err != nil {
+		return err
+	}
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Pause pauses indexer id; see Indexer.Pause.
+func (m *IndexerManager) Pause(id string) error {
+	ix := m.Indexer(id)
+	if ix == nil {
+		return fmt.Errorf("%w %s", ErrUnknownIndexer, id)
+	}
+	ix.Pause()
+	return nil
+}
+
+// Resume resumes indexer id after Pause.
+func (m *IndexerManager) Resume(id string) error {
+	ix := m.Indexer(id)
+	if ix == nil {
+		return fmt.Errorf("%w %s", ErrUnknownIndexer, id)
+	}
+	ix.Resume()
+	return nil
+}
+
+// stop cancels a run and waits for it to return.
+func stop(cancel context.CancelFunc, done chan struct{}) {
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// Remove stops indexer id as Stop does and forgets it, taking it off its
+// dashboard. Its checkpoint is kept.
+func (m *IndexerManager) Remove(ctx context.Context, id string) error {
+	if err := m.Stop(ctx, id); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	x := m.indexers[id]
+	delete(m.indexers, id)
+	m.mu.Unlock()
+	if x != nil && x.ix.dash != nil {
+		x.ix.dash.Remove(id)
+	}
+	return nil
+}
+
+// Run starts every indexer not already running and waits until ctx is
+// done or all of them have returned, then stops them. It returns the
+// errors of the indexers that failed.
+func (m *IndexerManager) Run(ctx context.Context) error {
+	m.mu.Lock()
+	ids := make([]string, 0, len(m.indexers))
+	for id := range m.indexers {
+		ids = append(ids, id)
+	}
+	m.mu.Unlock()
+	sort.Strings(ids)
+	type run struct {
+		x      *managedIndexer
+		cancel context.CancelFunc
+		done   chan struct{}
+	}
+	var runs []run
+	for _, id := range ids {
+		if err := m.Start(ctx, id); err != nil {
+			continue // removed meanwhile
+		}
+		m.mu.Lock()
+		if x, ok := m.indexers[id]; ok {
+			runs = append(runs, run{x, x.cancel, x.done})
+		}
+		m.mu.Unlock()
+	}
+	var errs []error
+	for _, r := range runs {
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			stop(r.cancel, r.done)
+		}
+		m.mu.Lock()
+		if r.x.err != nil {
+			errs = append(errs, fmt.Errorf("indexer %s: %w", r.x.ix.cfg.ID, r.x.err))
+		}
+		m.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// Status returns every indexer's state, ordered by ID.
+func (m *IndexerManager) Status() []ManagedStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]ManagedStatus, 0, len(m.indexers))
+	for _, x := range m.indexers {
+		s := ManagedStatus{IndexerStatus: x.ix.Status()}
+		if x.state != StateRunning {
+			s.State = x.state
+		}
+		if x.err != nil {
+			s.Error = x.err.Error()
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
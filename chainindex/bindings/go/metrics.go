@@ -0,0 +1,244 @@
+package chainindex
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DarshanKumar89/chainfoundry/chainkit/promtext"
+	"github.com/DarshanKumar89/chainfoundry/chainrpc"
+)
+
+// MetricsRecorder receives an indexer's progress, fetches, decode
+// failures, checkpoint writes and reorgs. Metrics implements it; implement
+// it over collectors in an existing Prometheus registry to record into
+// that registry instead. Methods are called from Run's goroutines and must
+// be safe for concurrent use.
+type MetricsRecorder interface {
+	// ObserveProgress reports a batch indexed up to block, with events
+	// matched events, when the chain was at head and the indexer's target
+	// at target.
+	ObserveProgress(indexer, chain string, block, target, head uint64, events int)
+	// ObserveFetch reports one eth_getLogs call, with status a
+	// chainrpc.ErrorClass label.
+	ObserveFetch(indexer, chain, status string, latency time.Duration)
+	// ObserveDecodeFailure reports a fetched result that could not be
+	// decoded into events, or an event its SchemaSet could not decode.
+	ObserveDecodeFailure(indexer, chain string)
+	// ObserveCheckpointWrite reports a checkpoint save, with status
+	// chainrpc.StatusOK or "error".
+	ObserveCheckpointWrite(indexer, chain, status string)
+	// ObserveReorg reports a reorg that removed depth indexed blocks.
+	ObserveReorg(indexer, chain string, depth int)
+}
+
+// WithMetrics reports the indexer's activity to m. One recorder may be
+// shared by any number of indexers; series are labelled by indexer ID.
+func WithMetrics(m MetricsRecorder) Option {
+	return func(o *indexerOptions) { o.metrics = m }
+}
+
+// statusError labels a failed checkpoint write.
+const statusError = "error"
+
+// Metrics is a dependency-free MetricsRecorder that renders the Prometheus
+// text exposition format with chainkit/promtext, like chainrpc.Metrics.
+// Mount it next to an existing /metrics endpoint, or Register it with a
+// promtext.Registry to serve it with other collectors.
+//
+// Exposed series, all labelled {indexer,chain}:
+//
+//	chainindex_indexed_block, chainindex_target_block, chainindex_head_block,
+//	chainindex_lag_blocks (gauges)
+//	chainindex_events_total
+//	chainindex_fetches_total{status}
+//	chainindex_fetch_duration_seconds (histogram)
+//	chainindex_decode_failures_total
+//	chainindex_checkpoint_writes_total{status}
+//	chainindex_reorgs_total, chainindex_reorged_blocks_total
+type Metrics struct {
+	buckets []float64
+
+	mu       sync.Mutex
+	indexers map[[2]string]*indexerSeries
+}
+
+type indexerSeries struct {
+	block, target, head uint64
+	events              uint64
+	fetches             map[string]uint64
+	fetch               *promtext.Histogram
+	decodeFailures      uint64
+	checkpoints         map[string]uint64
+	reorgs, reorged     uint64
+}
+
+var _ MetricsRecorder = (*Metrics)(nil)
+
+// NewMetrics returns an empty Metrics whose fetch latency histogram uses
+// buckets, or chainrpc.DefaultLatencyBuckets when buckets is nil.
+func NewMetrics(buckets []float64) *Metrics {
+	return &Metrics{
+		buckets:  promtext.Buckets(buckets, chainrpc.DefaultLatencyBuckets),
+		indexers: make(map[[2]string]*indexerSeries),
+	}
+}
+
+func (m *Metrics) series(indexer, chain string) *indexerSeries {
+	k := [2]string{indexer, chain}
+	s := m.indexers[k]
+	if s == nil {
+		s = &indexerSeries{
+			fetches:     make(map[string]uint64),
+			fetch:       promtext.NewHistogram(m.buckets),
+			checkpoints: make(map[string]uint64),
+		}
+		m.indexers[k] = s
+	}
+	return s
+}
+
+// ObserveProgress implements MetricsRecorder.
+func (m *Metrics) ObserveProgress(indexer, chain string, block, target, head uint64, events int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.series(indexer, chain)
+	s.block, s.target, s.head = block, target, head
+	s.events += uint64(events)
+}
+
+// ObserveFetch implements MetricsRecorder.
+func (m *Metrics) ObserveFetch(indexer, chain, status string, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.series(indexer, chain)
+	s.fetches[status]++
+	s.fetch.Observe(latency.Seconds())
+}
+
+// ObserveDecodeFailure implements MetricsRecorder.
+func (m *Metrics) ObserveDecodeFailure(indexer, chain string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.series(indexer, chain).decodeFailures++
+}
+
+// ObserveCheckpointWrite implements MetricsRecorder.
+func (m *Metrics) ObserveCheckpointWrite(indexer, chain, status string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.series(indexer, chain).checkpoints[status]++
+}
+
+// ObserveReorg implements MetricsRecorder.
+func (m *Metrics) ObserveReorg(indexer, chain string, depth int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.series(indexer, chain)
+	s.reorgs++
+	s.reorged += uint64(depth)
+}
+
+// Register adds m to reg, to be exported with its other collectors.
+func (m *Metrics) Register(reg promtext.Registerer) error { return reg.Register(m) }
+
+// WriteTo writes all series in Prometheus text format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	var pw promtext.Writer
+	m.mu.Lock()
+	keys := make([][2]string, 0, len(m.indexers))
+	for k := range m.indexers {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	family := func(name, typ, help string, value func(k [2]string, s *indexerSeries)) {
+		pw.Family(name, typ, help)
+		for _, k := range keys {
+			value(k, m.indexers[k])
+		}
+	}
+	scalar := func(name, typ, help string, v func(s *indexerSeries) uint64) {
+		family(name, typ, help, func(k [2]string, s *indexerSeries) {
+			pw.Sample(name, v(s), "indexer", k[0], "chain", k[1])
+		})
+	}
+	byStatus := func(name, help string, v func(s *indexerSeries) map[string]uint64) {
+		family(name, "counter", help, func(k [2]string, s *indexerSeries) {
+			counts := v(s)
+			statuses := make([]string, 0, len(counts))
+			for st := range counts {
+				statuses = append(statuses, st)
+			}
+			sort.Strings(statuses)
+			for _, st := range statuses {
+				pw.Sample(name, counts[st], "indexer", k[0], "chain", k[1], "status", st)
+			}
+		})
+	}
+
+	scalar("chainindex_indexed_block", "gauge", "Last block indexed.", func(s *indexerSeries) uint64 { return s.block })
+	scalar("chainindex_target_block", "gauge", "Highest block the indexer may index.", func(s *indexerSeries) uint64 { return s.target })
+	scalar("chainindex_head_block", "gauge", "Chain head last seen.", func(s *indexerSeries) uint64 { return s.head })
+	scalar("chainindex_lag_blocks", "gauge", "Blocks between the target and the last block indexed.", func(s *indexerSeries) uint64 {
+		if s.target > s.block {
+			return s.target - s.block
+		}
+		return 0
+	})
+	scalar("chainindex_events_total", "counter", "Matched events indexed.", func(s *indexerSeries) uint64 { return s.events })
+	byStatus("chainindex_fetches_total", "eth_getLogs calls by status.", func(s *indexerSeries) map[string]uint64 { return s.fetches })
+	family("chainindex_fetch_duration_seconds", "histogram", "eth_getLogs latency.", func(k [2]string, s *indexerSeries) {
+		pw.Histogram("chainindex_fetch_duration_seconds", s.fetch, "indexer", k[0], "chain", k[1])
+	})
+	scalar("chainindex_decode_failures_total", "counter", "Fetched results that could not be decoded.", func(s *indexerSeries) uint64 { return s.decodeFailures })
+	byStatus("chainindex_checkpoint_writes_total", "Checkpoint saves by status.", func(s *indexerSeries) map[string]uint64 { return s.checkpoints })
+	scalar("chainindex_reorgs_total", "counter", "Reorgs detected.", func(s *indexerSeries) uint64 { return s.reorgs })
+	scalar("chainindex_reorged_blocks_total", "counter", "Indexed blocks removed by reorgs.", func(s *indexerSeries) uint64 { return s.reorged })
+	m.mu.Unlock()
+	return pw.WriteTo(w)
+}
+
+// ServeHTTP serves the metrics in Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) { promtext.Serve(w, m) }
+
+// decodeError marks an RPC result that could not be decoded.
+type decodeError struct{ err error }
+
+func (e *decodeError) Error() string { return e.err.Error() }
+func (e *decodeError) Unwrap() error { return e.err }
+
+// getLogs fetches the indexer's matching logs for from..to, reporting the
+// call to the metrics recorder, and decodes them with its SchemaSet.
+func (ix *Indexer) getLogs(ctx context.Context, from, to uint64) ([]Event, error) {
+	start := time.Now()
+	ix.mu.Lock()
+	filter := ix.filter // Reload may replace it
+	ix.mu.Unlock()
+	events, err := getLogs(ctx, ix.rpc, ix.cfg.Chain, filter, from, to)
+	if ix.metrics != nil {
+		var de *decodeError
+		status := chainrpc.ErrorClass(err)
+		if errors.As(err, &de) {
+			status = chainrpc.StatusInvalidResponse
+			ix.metrics.ObserveDecodeFailure(ix.cfg.ID, ix.cfg.Chain)
+		}
+		ix.metrics.ObserveFetch(ix.cfg.ID, ix.cfg.Chain, status, time.Since(start))
+	}
+	if err != nil {
+		return nil, err
+	}
+	events = filter.keep(events)
+	if err := ix.decode(events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,62 @@
+package chainindex
+
+import "time"
+
+// minPollInterval is the shortest default adaptive poll interval.
+const minPollInterval = 100 * time.Millisecond
+
+// AdaptivePoll bounds an adaptive poll interval. While the indexer is at
+// the head, the interval halves after a poll that found new blocks and
+// doubles after one that found none, so it settles near the chain's block
+// time; a rate-limited provider doubles it too. It never drops below the
+// spacing RateLimitRPS allows. Zero bounds default to a quarter of
+// PollIntervalMs (at least 100ms) and eight times it.
+type AdaptivePoll struct {
+	MinIntervalMs uint64 `json:"min_interval_ms,omitempty"`
+	MaxIntervalMs uint64 `json:"max_interval_ms,omitempty"`
+}
+
+// pollInterval is the wait between polls of an indexer at the head.
+type pollInterval struct {
+	cur, min, max time.Duration
+	// found is set when blocks were indexed since the last idle poll.
+	found bool
+}
+
+func newPollInterval(cfg IndexerConfig) *pollInterval {
+	base := time.Duration(cfg.PollIntervalMs) * time.Millisecond
+	p := &pollInterval{cur: base, min: base, max: base}
+	if a := cfg.AdaptivePoll; a != nil {
+		p.min = time.Duration(a.MinIntervalMs) * time.Millisecond
+		if p.min == 0 {
+			p.min = max(base/4, minPollInterval)
+		}
+		p.max = time.Duration(a.MaxIntervalMs) * time.Millisecond
+		if p.max == 0 {
+			p.max = 8 * base
+		}
+	}
+	if cfg.RateLimitRPS > 0 {
+		p.min = max(p.min, time.Duration(float64(time.Second)/cfg.RateLimitRPS))
+	}
+	p.max = max(p.max, p.min)
+	p.cur = min(max(p.cur, p.min), p.max)
+	return p
+}
+
+// progress records that a batch was indexed.
+func (p *pollInterval) progress() { p.found = true }
+
+// idle returns how long to wait after a poll found nothing to index.
+func (p *pollInterval) idle() time.Duration {
+	if p.found {
+		p.cur = max(p.cur/2, p.min)
+	} else {
+		p.cur = min(2*p.cur, p.max)
+	}
+	p.found = false
+	return p.cur
+}
+
+// throttled records that the provider is rate limiting the indexer.
+func (p *pollInterval) throttled() { p.cur = min(2*p.cur, p.max) }
@@ -0,0 +1,305 @@
+package chainindex
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConfigChange describes one field that differs between two configs.
+type ConfigChange struct {
+	Field  string      `json:"field"`
+	Old    interface{} `json:"old"`
+	New    interface{} `json:"new"`
+	Reason string      `json:"reason,omitempty"`
+}
+
+// ReloadReport is the outcome of applying a reloaded config file.
+type ReloadReport struct {
+	// Applied lists changes that took effect.
+	Applied []ConfigChange `json:"applied"`
+	// Rejected lists changes that cannot be applied to a running indexer.
+	// They are ignored; the rest of the file is still applied.
+	Rejected []ConfigChange `json:"rejected"`
+	// Config is the effective config after the reload.
+	Config IndexerConfig `json:"config"`
+	// Err is set when nothing was applied: the file could not be read or
+	// parsed, or the merged config is invalid.
+	Err error `json:"-"`
+}
+
+func (r ReloadReport) String() string {
+	if r.Err != nil {
+		return "config reload failed: " + r.Err.Error()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "config reload: %d applied, %d rejected", len(r.Applied), len(r.Rejected))
+	for _, c := range r.Applied {
+		fmt.Fprintf(&b, "\n  applied  %s: %v -> %v", c.Field, c.Old, c.New)
+	}
+	for _, c := range r.Rejected {
+		fmt.Fprintf(&b, "\n  rejected %s: %v -> %v (%s)", c.Field, c.Old, c.New, c.Reason)
+	}
+	return b.String()
+}
+
+// LoadConfigFile reads and validates an IndexerConfig JSON file.
+func LoadConfigFile(path string) (*IndexerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(string(data))
+}
+
+// ApplyConfig merges the runtime-compatible differences between cur and next
+// into a copy of cur. Poll interval and its adaptive bounds, batch size,
+// checkpoint interval, confirmation depth, rate limit, end block, followed
+// tag and filter additions are applied; identity and start-position
+// changes, unknown tags and filter removals are rejected.
+func ApplyConfig(cur, next IndexerConfig) ReloadReport {
+	r := ReloadReport{Config: cur}
+	reject := func(field string, old, new interface{}, reason string) {
+		r.Rejected = append(r.Rejected, ConfigChange{Field: field, Old: old, New: new, Reason: reason})
+	}
+	apply := func(field string, old, new interface{}) {
+		r.Applied = append(r.Applied, ConfigChange{Field: field, Old: old, New: new})
+	}
+
+	if next.ID != cur.ID {
+		reject("id", cur.ID, next.ID, "indexer identity cannot change at runtime")
+	}
+	if next.Chain != cur.Chain {
+		reject("chain", cur.Chain, next.Chain, "chain cannot change at runtime")
+	}
+	if !reflect.DeepEqual(next.Shard, cur.Shard) {
+		reject("shard", cur.Shard, next.Shard, "shard assignment cannot change at runtime")
+	}
+	if next.FromBlock != cur.FromBlock {
+		reject("from_block", cur.FromBlock, next.FromBlock, "start position is owned by the checkpoint")
+	}
+	if next.StartFrom != cur.StartFrom {
+		reject("start_from", cur.StartFrom, next.StartFrom, "start position is owned by the checkpoint")
+	}
+
+	if !reflect.DeepEqual(next.ToBlock, cur.ToBlock) {
+		apply("to_block", optBlock(cur.ToBlock), optBlock(next.ToBlock))
+		r.Config.ToBlock = next.ToBlock
+	}
+	for _, f := range []struct {
+		name     string
+		cur, new *uint64
+	}{
+		{"poll_interval_ms", &r.Config.PollIntervalMs, &next.PollIntervalMs},
+		{"batch_size", &r.Config.BatchSize, &next.BatchSize},
+		{"checkpoint_interval", &r.Config.CheckpointInterval, &next.CheckpointInterval},
+		{"confirmation_depth", &r.Config.ConfirmationDepth, &next.ConfirmationDepth},
+	} {
+		if *f.cur != *f.new {
+			apply(f.name, *f.cur, *f.new)
+			*f.cur = *f.new
+		}
+	}
+	if next.RateLimitRPS != cur.RateLimitRPS {
+		apply("rate_limit_rps", cur.RateLimitRPS, next.RateLimitRPS)
+		r.Config.RateLimitRPS = next.RateLimitRPS
+	}
+	if next.Follow != cur.Follow {
+		if _, err := next.follow(); err != nil {
+			reject("follow", cur.Follow, next.Follow, err.Error())
+		} else {
+			apply("follow", cur.Follow, next.Follow)
+			r.Config.Follow = next.Follow
+		}
+	}
+	if !reflect.DeepEqual(next.AdaptivePoll, cur.AdaptivePoll) {
+		apply("adaptive_poll", cur.AdaptivePoll, next.AdaptivePoll)
+		r.Config.AdaptivePoll = next.AdaptivePoll
+	}
+
+	r.Config.Filter.Addresses = mergeAdditions(&r, "filter.addresses", cur.Filter.Addresses, next.Filter.Addresses)
+	r.Config.Filter.Topic0Values = mergeAdditions(&r, "filter.topic0_values", cur.Filter.Topic0Values, next.Filter.Topic0Values)
+	return r
+}
+
+// mergeAdditions returns cur plus any values only present in next; values
+// missing from next are reported as rejected removals.
+func mergeAdditions(r *ReloadReport, field string, cur, next []string) []string {
+	// An empty list matches everything, so narrowing it is a removal.
+	if len(cur) == 0 {
+		if len(next) > 0 {
+			r.Rejected = append(r.Rejected, ConfigChange{Field: field, New: next, Reason: "narrowing a match-all filter requires a reindex"})
+		}
+		return cur
+	}
+	have := make(map[string]bool, len(cur))
+	for _, v := range cur {
+		have[strings.ToLower(v)] = true
+	}
+	want := make(map[string]bool, len(next))
+	out := append([]string(nil), cur...)
+	for _, v := range next {
+		want[strings.ToLower(v)] = true
+		if !have[strings.ToLower(v)] {
+			out = append(out, v)
+			r.Applied = append(r.Applied, ConfigChange{Field: field, New: v})
+		}
+	}
+	for _, v := range cur {
+		if !want[strings.ToLower(v)] {
+			r.Rejected = append(r.Rejected, ConfigChange{Field: field, Old: v, Reason: "filter removals require a reindex"})
+		}
+	}
+	return out
+}
+
+func optBlock(b *uint64) interface{} {
+	if b == nil {
+		return nil
+	}
+	return *b
+}
+
+// Reload applies the runtime-compatible changes in cfg to the indexer, as
+// ApplyConfig merges them, and reports what was applied and rejected. An
+// indexer that is running switches to the new config between batches; the
+// filter additions reach the next batch fetched, and a changed
+// confirmation depth the next head polled.
+func (ix *Indexer) Reload(cfg IndexerConfig) ReloadReport {
+	cfg = withDefaults(cfg)
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	cur := ix.cfg
+	if ix.reload != nil {
+		cur = *ix.reload
+	}
+	r := ApplyConfig(cur, cfg)
+	if _, err := r.Config.ShardedFilter(); err != nil {
+		return ReloadReport{Config: cur, Err: fmt.Errorf("chainindex: reload: %w", err)}
+	}
+	if ix.running {
+		next := r.Config
+		ix.reload = &next
+	} else {
+		ix.setConfig(r.Config)
+	}
+	return r
+}
+
+// applyReload switches to the config passed to Reload, if any. Run calls
+// it between batches.
+func (ix *Indexer) applyReload() bool {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.reload == nil {
+		return false
+	}
+	ix.setConfig(*ix.reload)
+	ix.reload = nil
+	return true
+}
+
+// setConfig installs the fields ApplyConfig may change from a config
+// Reload validated; the identity fields Retry and Status read without
+// ix.mu stay untouched. Callers hold ix.mu.
+func (ix *Indexer) setConfig(cfg IndexerConfig) {
+	c := &ix.cfg
+	c.ToBlock, c.PollIntervalMs, c.AdaptivePoll = cfg.ToBlock, cfg.PollIntervalMs, cfg.AdaptivePoll
+	c.BatchSize, c.CheckpointInterval, c.ConfirmationDepth = cfg.BatchSize, cfg.CheckpointInterval, cfg.ConfirmationDepth
+	c.RateLimitRPS, c.Follow, c.Filter = cfg.RateLimitRPS, cfg.Follow, cfg.Filter
+	ix.follow, _ = cfg.follow()
+	ix.filter, _ = cfg.ShardedFilter()
+	ix.limiter.setRate(cfg.RateLimitRPS)
+	if ix.window != nil {
+		ix.window.size = max(cfg.ConfirmationDepth, minReorgWindow)
+	}
+}
+
+// ConfigWatcher polls a config file and applies compatible changes to the
+// config it holds. Pass the reloaded config on to a running indexer with
+// Indexer.Reload:
+//
+//	w.Run(ctx, func(r chainindex.ReloadReport) {
+//		if r.Err == nil {
+//			log.Print(ix.Reload(r.Config))
+//		}
+//	})
+type ConfigWatcher struct {
+	path     string
+	interval time.Duration
+
+	mu      sync.RWMutex
+	cfg     IndexerConfig
+	modTime time.Time
+	size    int64
+}
+
+// NewConfigWatcher loads path and returns a watcher polling it every interval.
+func NewConfigWatcher(path string, interval time.Duration) (*ConfigWatcher, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &ConfigWatcher{path: path, interval: interval, cfg: *cfg, modTime: fi.ModTime(), size: fi.Size()}, nil
+}
+
+// Config returns the current effective config.
+func (w *ConfigWatcher) Config() IndexerConfig {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.cfg
+}
+
+// Run polls the file until ctx is done, calling onReload after every change
+// to the file (including failed reloads).
+func (w *ConfigWatcher) Run(ctx context.Context, onReload func(ReloadReport)) error {
+	t := time.NewTicker(w.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			if r, changed := w.Check(); changed && onReload != nil {
+				onReload(r)
+			}
+		}
+	}
+}
+
+// Check reloads the file if it changed since the last check.
+func (w *ConfigWatcher) Check() (ReloadReport, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fi, err := os.Stat(w.path)
+	if err != nil {
+		// Report a vanished file once, not on every poll.
+		if w.modTime.IsZero() {
+			return ReloadReport{}, false
+		}
+		w.modTime, w.size = time.Time{}, 0
+		return ReloadReport{Config: w.cfg, Err: err}, true
+	}
+	if fi.ModTime().Equal(w.modTime) && fi.Size() == w.size {
+		return ReloadReport{}, false
+	}
+	w.modTime, w.size = fi.ModTime(), fi.Size()
+	next, err := LoadConfigFile(w.path)
+	if err != nil {
+		return ReloadReport{Config: w.cfg, Err: err}, true
+	}
+	r := ApplyConfig(w.cfg, *next)
+	w.cfg = r.Config
+	return r, true
+}
@@ -0,0 +1,188 @@
+package chainindex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// minReorgWindow is the fewest recent blocks an indexer tracks for reorg
+// detection, so one following the head with no confirmation depth still
+// notices short reorgs.
+const minReorgWindow = 64
+
+// ErrDeepReorg is returned by Run when a reorg reaches below every block
+// the indexer tracks, so the common ancestor cannot be found. Events
+// handled since then may be stale; reindex from an earlier block.
+var ErrDeepReorg = errors.New("chainindex: reorg deeper than tracked blocks")
+
+// RemovedBlock is a block a reorg dropped from the canonical chain.
+type RemovedBlock struct {
+	Number uint64 `json:"number"`
+	// Hash is the dropped block's hash, or empty if the indexer never saw
+	// it (a block with no matching events inside a batch).
+	Hash string `json:"hash,omitempty"`
+}
+
+// ReorgEvent reports that blocks the indexer had already handled are no
+// longer canonical. Consumers should invalidate what they derived from
+// RemovedBlocks; the indexer then resumes after CommonAncestor and
+// delivers the replacement events.
+type ReorgEvent struct {
+	Chain string `json:"chain"`
+	// CommonAncestor is the highest handled block still on the canonical
+	// chain.
+	CommonAncestor     uint64 `json:"common_ancestor"`
+	CommonAncestorHash string `json:"common_ancestor_hash"`
+	// RemovedBlocks are the dropped blocks, most recent first.
+	RemovedBlocks []RemovedBlock `json:"removed_blocks"`
+	DetectedAt    time.Time      `json:"detected_at"`
+}
+
+// ReorgHandler is told about reorgs before the replacement events are
+// delivered. An error makes the indexer retry the notification after a
+// backoff, without moving on.
+type ReorgHandler interface {
+	HandleReorg(ctx context.Context, ev ReorgEvent) error
+}
+
+// ReorgHandlerFunc adapts a function to a ReorgHandler.
+type ReorgHandlerFunc func(ctx context.Context, ev ReorgEvent) error
+
+// HandleReorg calls f.
+func (f ReorgHandlerFunc) HandleReorg(ctx context.Context, ev ReorgEvent) error { return f(ctx, ev) }
+
+// WithReorgHandler notifies h of reorgs. Without it, a Handler that also
+// implements ReorgHandler is notified.
+func WithReorgHandler(h ReorgHandler) Option {
+	return func(o *indexerOptions) { o.reorgs = h }
+}
+
+// blockWindow remembers the hashes of the most recent blocks indexed.
+type blockWindow struct {
+	size   uint64
+	top    uint64
+	hashes map[uint64]string
+}
+
+func newBlockWindow(size uint64) *blockWindow {
+	return &blockWindow{size: max(size, minReorgWindow), hashes: make(map[uint64]string)}
+}
+
+func (w *blockWindow) add(n uint64, hash string) {
+	if hash == "" {
+		return
+	}
+	w.hashes[n] = hash
+	if n > w.top {
+		w.top = n
+		for k := range w.hashes {
+			if k+w.size < w.top {
+				delete(w.hashes, k)
+			}
+		}
+	}
+}
+
+// truncate forgets every block above n.
+func (w *blockWindow) truncate(n uint64) {
+	for k := range w.hashes {
+		if k > n {
+			delete(w.hashes, k)
+		}
+	}
+	w.top = n
+}
+
+// below returns the tracked block numbers under n, highest first.
+func (w *blockWindow) below(n uint64) []uint64 {
+	var out []uint64
+	for k := range w.hashes {
+		if k < n {
+			out = append(out, k)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] > out[j] })
+	return out
+}
+
+// checkReorg verifies that block next extends the last block indexed. On
+// a mismatch it finds the common ancestor, notifies the reorg handler,
+// rewinds to just after the ancestor and checkpoints there, and reports
+// true.
+func (ix *Indexer) checkReorg(ctx context.Context, next uint64) (bool, error) {
+	if next == 0 {
+		return false, nil
+	}
+	prev, ok := ix.window.hashes[next-1]
+	if !ok {
+		return false, nil
+	}
+	_, parent, err := blockHeader(ctx, ix.rpc, next)
+	if err != nil {
+		return false, err
+	}
+	if strings.EqualFold(parent, prev) {
+		return false, nil
+	}
+
+	ev := ReorgEvent{Chain: ix.cfg.Chain, DetectedAt: time.Now()}
+	found := false
+	for _, n := range ix.window.below(next) {
+		hash, _, err := blockHeader(ctx, ix.rpc, n)
+		if err != nil {
+			return false, err
+		}
+		if strings.EqualFold(hash, ix.window.hashes[n]) {
+			ev.CommonAncestor, ev.CommonAncestorHash, found = n, hash, true
+			break
+		}
+	}
+	if !found {
+		return false, &fatalError{fmt.Errorf("%w: block %d does not extend %s", ErrDeepReorg, next, prev)}
+	}
+	for n := next - 1; n > ev.CommonAncestor; n-- {
+		ev.RemovedBlocks = append(ev.RemovedBlocks, RemovedBlock{Number: n, Hash: ix.window.hashes[n]})
+	}
+	if ix.reorgs != nil {
+		if err := ix.reorgs.HandleReorg(ctx, ev); err != nil {
+			return false, fmt.Errorf("chainindex: handle reorg at block %d: %w", next, err)
+		}
+	}
+	if ix.metrics != nil {
+		ix.metrics.ObserveReorg(ix.cfg.ID, ix.cfg.Chain, len(ev.RemovedBlocks))
+	}
+
+	ix.window.truncate(ev.CommonAncestor)
+	ix.mu.Lock()
+	ix.next = ev.CommonAncestor + 1
+	ix.progress.setLast(ev.CommonAncestor)
+	ix.mu.Unlock()
+	if err := ix.checkpoint(ctx, ev.CommonAncestor); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// blockHeader returns the hash and parent hash of block n.
+func blockHeader(ctx context.Context, rpc Caller, n uint64) (hash, parent string, err error) {
+	res, err := rpc.CallContext(ctx, "eth_getBlockByNumber", fmt.Sprintf(`["0x%x",false]`, n))
+	if err != nil {
+		return "", "", err
+	}
+	var b *struct {
+		Hash       string `json:"hash"`
+		ParentHash string `json:"parentHash"`
+	}
+	if err := json.Unmarshal([]byte(res), &b); err != nil {
+		return "", "", fmt.Errorf("chainindex: eth_getBlockByNumber result: %w", err)
+	}
+	if b == nil || b.Hash == "" {
+		return "", "", fmt.Errorf("chainindex: block %d not found", n)
+	}
+	return b.Hash, b.ParentHash, nil
+}
@@ -0,0 +1,193 @@
+package chainindex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SampleConfig selects the blocks a sampling run reads. Exactly one of
+// Every and Rate is set.
+type SampleConfig struct {
+	// Every reads one block out of Every: the first block of the range and
+	// every Every-th block after it.
+	Every uint64 `json:"every,omitempty"`
+	// Rate reads each block with probability Rate, in (0, 1].
+	Rate float64 `json:"rate,omitempty"`
+	// Seed seeds Rate's choices, so a run can be repeated; zero picks a
+	// random seed.
+	Seed int64 `json:"seed,omitempty"`
+	// MaxBlocks stops the run after reading that many blocks; zero reads
+	// the whole range.
+	MaxBlocks uint64 `json:"max_blocks,omitempty"`
+}
+
+// TopicSample summarizes the sampled events with one topic0.
+type TopicSample struct {
+	Topic0 string `json:"topic0"`
+	Events uint64 `json:"events"`
+	// Topics is the number of topics the events carry (the signature plus
+	// indexed parameters); the most common count when they differ.
+	Topics int `json:"topics"`
+	// AvgDataBytes and MaxDataBytes measure the non-indexed data.
+	AvgDataBytes float64 `json:"avg_data_bytes"`
+	MaxDataBytes int     `json:"max_data_bytes"`
+	// Example is the first event seen, for eyeballing its shape.
+	Example Event `json:"example"`
+
+	dataBytes   uint64
+	topicCounts map[int]uint64
+}
+
+// SampleReport characterizes the events of a block range from a sample
+// of its blocks.
+type SampleReport struct {
+	FromBlock uint64 `json:"from_block"`
+	ToBlock   uint64 `json:"to_block"`
+	// BlocksSampled is how many blocks were read, BlocksWithEvents how
+	// many of them had matching events.
+	BlocksSampled    uint64  `json:"blocks_sampled"`
+	BlocksWithEvents uint64  `json:"blocks_with_events"`
+	Events           uint64  `json:"events"`
+	EventsPerBlock   float64 `json:"events_per_block"`
+	// EstimatedEvents extrapolates EventsPerBlock to the whole range: the
+	// expected size of a full backfill.
+	EstimatedEvents uint64 `json:"estimated_events"`
+	// MaxEventsPerBlock is the busiest sampled block's count, a hint for
+	// sizing BatchSize.
+	MaxEventsPerBlock uint64 `json:"max_events_per_block"`
+	// ByAddress counts events per emitting contract.
+	ByAddress map[string]uint64 `json:"by_address"`
+	// Topics summarizes each event signature, most frequent first.
+	Topics   []*TopicSample `json:"topics"`
+	Duration time.Duration  `json:"duration"`
+}
+
+// Sample reads a sample of the blocks from..to inclusive with cfg's filter
+// and reports the volume and shape of the matching events, to size a full
+// backfill of an unfamiliar contract before committing to it. A to of zero
+// means the current head. Events are fetched one block per eth_getLogs
+// call, so a sample costs one request per sampled block.
+func Sample(ctx context.Context, cfg *IndexerConfig, rpc Caller, from, to uint64, s SampleConfig) (*SampleReport, error) {
+	if (s.Every == 0) == (s.Rate == 0) {
+		return nil, fmt.Errorf("chainindex: sample needs exactly one of every and rate")
+	}
+	if s.Rate < 0 || s.Rate > 1 {
+		return nil, fmt.Errorf("chainindex: sample rate %v outside (0, 1]", s.Rate)
+	}
+	if to == 0 {
+		head, err := blockNumber(ctx, rpc)
+		if err != nil {
+			return nil, err
+		}
+		to = head
+	}
+	if to < from {
+		return nil, fmt.Errorf("chainindex: sample range %d..%d is empty", from, to)
+	}
+	seed := s.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	start := time.Now()
+	r := &SampleReport{FromBlock: from, ToBlock: to, ByAddress: map[string]uint64{}}
+	topics := map[string]*TopicSample{}
+	for block := from; block <= to; block++ {
+		if s.MaxBlocks > 0 && r.BlocksSampled >= s.MaxBlocks {
+			break
+		}
+		if s.Every > 0 && (block-from)%s.Every != 0 || s.Rate > 0 && rng.Float64() >= s.Rate {
+			continue
+		}
+		events, err := getLogs(ctx, rpc, cfg.Chain, cfg.Filter, block, block)
+		if err != nil {
+			return nil, err
+		}
+		r.BlocksSampled++
+		if len(events) > 0 {
+			r.BlocksWithEvents++
+		}
+		r.Events += uint64(len(events))
+		r.MaxEventsPerBlock = max(r.MaxEventsPerBlock, uint64(len(events)))
+		for _, ev := range events {
+			r.ByAddress[strings.ToLower(ev.Address)]++
+			var topic0 string
+			if len(ev.Topics) > 0 {
+				topic0 = strings.ToLower(ev.Topics[0])
+			}
+			t, ok := topics[topic0]
+			if !ok {
+				t = &TopicSample{Topic0: topic0, Example: ev, topicCounts: map[int]uint64{}}
+				topics[topic0] = t
+			}
+			size := len(strings.TrimPrefix(ev.Data, "0x")) / 2
+			t.Events++
+			t.dataBytes += uint64(size)
+			t.MaxDataBytes = max(t.MaxDataBytes, size)
+			t.topicCounts[len(ev.Topics)]++
+		}
+	}
+	if r.BlocksSampled > 0 {
+		r.EventsPerBlock = float64(r.Events) / float64(r.BlocksSampled)
+		r.EstimatedEvents = uint64(r.EventsPerBlock*float64(to-from+1) + 0.5)
+	}
+	for _, t := range topics {
+		t.AvgDataBytes = float64(t.dataBytes) / float64(t.Events)
+		var best uint64
+		for n, c := range t.topicCounts {
+			if c > best || c == best && n < t.Topics {
+				t.Topics, best = n, c
+			}
+		}
+		r.Topics = append(r.Topics, t)
+	}
+	sort.Slice(r.Topics, func(i, j int) bool {
+		if r.Topics[i].Events != r.Topics[j].Events {
+			return r.Topics[i].Events > r.Topics[j].Events
+		}
+		return r.Topics[i].Topic0 < r.Topics[j].Topic0
+	})
+	r.Duration = time.Since(start)
+	return r, nil
+}
+
+// getLogs fetches the events matching f in blocks from..to inclusive.
+func getLogs(ctx context.Context, rpc Caller, chain string, f EventFilter, from, to uint64) ([]Event, error) {
+	q := map[string]interface{}{
+		"fromBlock": fmt.Sprintf("0x%x", from),
+		"toBlock":   fmt.Sprintf("0x%x", to),
+	}
+	if len(f.Addresses) > 0 {
+		q["address"] = f.Addresses
+	}
+	if len(f.Topic0Values) > 0 {
+		q["topics"] = []interface{}{f.Topic0Values}
+	}
+	params, err := json.Marshal([]interface{}{q})
+	if err != nil {
+		return nil, err
+	}
+	res, err := rpc.CallContext(ctx, "eth_getLogs", string(params))
+	if err != nil {
+		return nil, err
+	}
+	var logs []Log
+	if err := json.Unmarshal([]byte(res), &logs); err != nil {
+		return nil, &decodeError{fmt.Errorf("chainindex: eth_getLogs result: %w", err)}
+	}
+	events := make([]Event, 0, len(logs))
+	for _, l := range logs {
+		ev, err := l.ToEvent(chain)
+		if err != nil {
+			return nil, &decodeError{err}
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
@@ -0,0 +1,113 @@
+package chainindex
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// Shard identifies one partition of a sharded deployment: Count instances
+// each index the addresses whose hash modulo Count equals Index.
+type Shard struct {
+	Index uint32 `json:"index"`
+	Count uint32 `json:"count"`
+}
+
+// Validate reports whether the shard is well formed.
+func (s Shard) Validate() error {
+	if s.Count == 0 || s.Index >= s.Count {
+		return fmt.Errorf("chainindex: invalid shard %d/%d", s.Index, s.Count)
+	}
+	return nil
+}
+
+func (s Shard) String() string {
+	return fmt.Sprintf("shard-%d-of-%d", s.Index, s.Count)
+}
+
+// Owns reports whether address belongs to this shard. The assignment is a
+// stable FNV-1a hash of the lower-cased address, so every instance computes
+// the same partition independently.
+func (s Shard) Owns(address string) bool {
+	if s.Count <= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(address)))
+	return h.Sum32()%s.Count == s.Index
+}
+
+// Partition returns the addresses owned by this shard, preserving order.
+func (s Shard) Partition(addresses []string) []string {
+	var out []string
+	for _, a := range addresses {
+		if s.Owns(a) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// CheckpointID returns the checkpoint key for this shard of indexerID.
+// Embedding the assignment in the key keeps each shard's position separate
+// and means a re-sharded deployment (different Count) never resumes from a
+// checkpoint that covered a different address partition.
+func (s Shard) CheckpointID(indexerID string) string {
+	return indexerID + "/" + s.String()
+}
+
+// ParseShardCheckpointID splits a key produced by CheckpointID back into the
+// indexer ID and shard. ok is false for unsharded keys.
+func ParseShardCheckpointID(id string) (indexerID string, s Shard, ok bool) {
+	i := strings.LastIndex(id, "/shard-")
+	if i < 0 {
+		return id, Shard{}, false
+	}
+	parts := strings.Split(id[i+len("/shard-"):], "-of-")
+	if len(parts) != 2 {
+		return id, Shard{}, false
+	}
+	idx, err1 := strconv.ParseUint(parts[0], 10, 32)
+	cnt, err2 := strconv.ParseUint(parts[1], 10, 32)
+	if err1 != nil || err2 != nil {
+		return id, Shard{}, false
+	}
+	s = Shard{Index: uint32(idx), Count: uint32(cnt)}
+	if s.Validate() != nil {
+		return id, Shard{}, false
+	}
+	return id[:i], s, true
+}
+
+// ShardedFilter returns cfg.Filter narrowed to cfg.Shard's addresses.
+// Without a shard the filter is returned unchanged. A sharded config with
+// an empty address list is rejected, since "all addresses" cannot be
+// partitioned.
+func (cfg *IndexerConfig) ShardedFilter() (EventFilter, error) {
+	f := cfg.Filter
+	if cfg.Shard == nil {
+		return f, nil
+	}
+	if err := cfg.Shard.Validate(); err != nil {
+		return f, err
+	}
+	if len(f.Addresses) == 0 {
+		return f, fmt.Errorf("chainindex: sharding requires an explicit address list")
+	}
+	f.Addresses = cfg.Shard.Partition(f.Addresses)
+	if len(f.Addresses) == 0 {
+		// An empty list would match every address.
+		return f, fmt.Errorf("chainindex: %s owns none of the %d configured addresses", cfg.Shard, len(cfg.Filter.Addresses))
+	}
+	return f, nil
+}
+
+// CheckpointID returns the key the indexer stores its checkpoint under,
+// including the shard assignment when sharded.
+func (cfg *IndexerConfig) CheckpointID() string {
+	if cfg.Shard == nil {
+		return cfg.ID
+	}
+	return cfg.Shard.CheckpointID(cfg.ID)
+}
@@ -0,0 +1,272 @@
+// Package kafka is a chainindex sink that produces indexed events to
+// Kafka topics.
+//
+// The sink does not depend on a Kafka client. It hands records to a
+// Producer, a one-method interface to implement over the client in use
+// (segmentio/kafka-go, franz-go, confluent-kafka-go):
+//
+//	sink, _ := kafka.New(producer, kafka.Config{Topic: "chain.{chain}.events"})
+//	ix, _ := chainindex.NewIndexer(cfg, chainindex.WithEndpoints(url), chainindex.WithHandler(sink))
+//
+// A batch's records are produced together and HandleEvents returns only
+// once the Producer reports them acknowledged, so the indexer checkpoints
+// past a batch only after Kafka has it. After a crash the batches since
+// the last checkpoint are produced again: delivery is at least once, and
+// a consumer that drops records whose key and "chainindex-id" header it
+// has seen gets exactly-once results.
+//
+// Records are keyed by chain and contract address, so one contract's
+// events stay in order on one partition. Values are the JSON encoding of
+// chainindex.Event, described by EventJSONSchema for registering with a
+// schema registry; with Config.SchemaID set they carry the Confluent wire
+// format prefix.
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/DarshanKumar89/chainfoundry/chainindex"
+)
+
+// Message is one Kafka record.
+type Message struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+}
+
+// Producer writes records to Kafka. Produce returns nil only once every
+// record is acknowledged by the broker, so configure the client for
+// acks=all (and, where supported, idempotent production). Records with
+// the same key must be written in order.
+type Producer interface {
+	Produce(ctx context.Context, msgs []Message) error
+}
+
+// ProducerFunc adapts a function to a Producer.
+type ProducerFunc func(ctx context.Context, msgs []Message) error
+
+// Produce calls f.
+func (f ProducerFunc) Produce(ctx context.Context, msgs []Message) error { return f(ctx, msgs) }
+
+// DefaultTopic is the event topic unless Config.Topic is set.
+const DefaultTopic = "chainindex.events"
+
+// Record headers.
+const (
+	// HeaderID identifies the record across retries:
+	// "chain:block_hash:log_index", with ":removed" appended for the
+	// compensating record of a removed event.
+	HeaderID = "chainindex-id"
+	// Reorg records use "chain:reorg:ancestor_hash:tip_hash".
+	// HeaderType is "event" or "reorg".
+	HeaderType = "chainindex-type"
+	// HeaderSchema is the chaincodec schema that decoded the event, if
+	// any.
+	HeaderSchema = "chainindex-schema"
+	// HeaderContentType is "application/json".
+	HeaderContentType = "content-type"
+)
+
+// Config configures a Sink. Zero fields take the defaults noted on each.
+type Config struct {
+	// Topic names the topic each event goes to; "{chain}", "{indexer}"
+	// and "{schema}" are replaced with the event's chain, IndexerID and
+	// decoded schema name ("raw" if undecoded). Default DefaultTopic.
+	Topic string
+	// ReorgTopic, if set, receives a chainindex.ReorgEvent record, keyed
+	// by chain, for each reorg; "{chain}" and "{indexer}" are replaced.
+	// Without it reorgs are not produced: the replaced events arrive as
+	// new records and consumers reconcile by block hash.
+	ReorgTopic string
+	// IndexerID fills "{indexer}".
+	IndexerID string
+	// SchemaID, if set, prefixes each value with the Confluent wire
+	// format header (a zero byte and the big-endian schema ID) for the
+	// schema registered from EventJSONSchema.
+	SchemaID uint32
+	// ReorgSchemaID does the same for reorg records.
+	ReorgSchemaID uint32
+}
+
+// Sink produces events to Kafka. It is safe for concurrent use.
+type Sink struct {
+	p   Producer
+	cfg Config
+}
+
+var (
+	_ chainindex.Handler      = (*Sink)(nil)
+	_ chainindex.ReorgHandler = (*Sink)(nil)
+)
+
+// New returns a sink producing through p.
+func New(p Producer, cfg Config) (*Sink, error) {
+	if p == nil {
+		return nil, fmt.Errorf("kafka sink: nil producer")
+	}
+	if cfg.Topic == "" {
+		cfg.Topic = DefaultTopic
+	}
+	return &Sink{p: p, cfg: cfg}, nil
+}
+
+// HandleEvents implements chainindex.Handler: it produces one record per
+// event and returns once all are acknowledged.
+func (s *Sink) HandleEvents(ctx context.Context, events []chainindex.Event) error {
+	msgs := make([]Message, 0, len(events))
+	for _, ev := range events {
+		schema := "raw"
+		if ev.Decoded != nil && ev.Decoded.Name != "" {
+			schema = ev.Decoded.Name
+		}
+		value, err := s.value(ev, s.cfg.SchemaID)
+		if err != nil {
+			return fmt.Errorf("kafka sink: block %d log %d: %w", ev.BlockNumber, ev.LogIndex, err)
+		}
+		id := ev.Chain + ":" + strings.ToLower(ev.BlockHash) + ":" + strconv.FormatUint(ev.LogIndex, 10)
+		if ev.Removed {
+			id += ":removed"
+		}
+		h := map[string]string{
+			HeaderID:          id,
+			HeaderType:        "event",
+			HeaderContentType: "application/json",
+		}
+		if ev.Decoded != nil {
+			h[HeaderSchema] = schema
+		}
+		msgs = append(msgs, Message{
+			Topic:   s.topic(s.cfg.Topic, ev.Chain, schema),
+			Key:     []byte(ev.Chain + ":" + strings.ToLower(ev.Address)),
+			Value:   value,
+			Headers: h,
+		})
+	}
+	if err := s.p.Produce(ctx, msgs); err != nil {
+		return fmt.Errorf("kafka sink: produce: %w", err)
+	}
+	return nil
+}
+
+// HandleReorg implements chainindex.ReorgHandler: with a ReorgTopic it
+// produces ev there and returns once it is acknowledged.
+func (s *Sink) HandleReorg(ctx context.Context, ev chainindex.ReorgEvent) error {
+	if s.cfg.ReorgTopic == "" {
+		return nil
+	}
+	value, err := s.value(ev, s.cfg.ReorgSchemaID)
+	if err != nil {
+		return fmt.Errorf("kafka sink: reorg: %w", err)
+	}
+	msg := Message{
+		Topic: s.topic(s.cfg.ReorgTopic, ev.Chain, ""),
+		Key:   []byte(ev.Chain),
+		Value: value,
+		Headers: map[string]string{
+			HeaderID:          reorgID(ev),
+			HeaderType:        "reorg",
+			HeaderContentType: "application/json",
+		},
+	}
+	if err := s.p.Produce(ctx, []Message{msg}); err != nil {
+		return fmt.Errorf("kafka sink: produce reorg: %w", err)
+	}
+	return nil
+}
+
+// topic expands a topic template.
+func (s *Sink) topic(tmpl, chain, schema string) string {
+	return strings.NewReplacer("{chain}", chain, "{indexer}", s.cfg.IndexerID, "{schema}", schema).Replace(tmpl)
+}
+
+// value encodes v as JSON, behind the wire format header if schemaID is
+// set.
+func (s *Sink) value(v interface{}, schemaID uint32) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil || schemaID == 0 {
+		return b, err
+	}
+	out := make([]byte, 5, 5+len(b))
+	binary.BigEndian.PutUint32(out[1:], schemaID)
+	return append(out, b...), nil
+}
+
+// EventJSONSchema is the JSON Schema of event record values, for
+// registering with a schema registry (schema type JSON) and passing the
+// resulting ID as Config.SchemaID. New optional properties may be added;
+// existing ones keep their meaning.
+const EventJSONSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "chainindex.Event",
+  "type": "object",
+  "properties": {
+    "chain": {"type": "string"},
+    "block_number": {"type": "integer", "minimum": 0},
+    "block_hash": {"type": "string"},
+    "tx_hash": {"type": "string"},
+    "tx_index": {"type": "integer", "minimum": 0},
+    "log_index": {"type": "integer", "minimum": 0},
+    "address": {"type": "string"},
+    "topics": {"type": ["array", "null"], "items": {"type": "string"}},
+    "data": {"type": "string"},
+    "removed": {"type": "boolean"},
+    "decoded": {
+      "type": "object",
+      "properties": {
+        "chain": {"type": "string"},
+        "schema": {"type": "string"},
+        "schema_version": {"type": "integer"},
+        "block_number": {"type": "integer"},
+        "block_hash": {"type": "string"},
+        "block_timestamp": {"type": "integer"},
+        "tx_hash": {"type": "string"},
+        "log_index": {"type": "integer"},
+        "address": {"type": "string"},
+        "removed": {"type": "boolean"},
+        "fields": {"type": "object"},
+        "computed": {"type": "object"},
+        "decode_errors": {"type": "object", "additionalProperties": {"type": "string"}}
+      }
+    }
+  },
+  "required": ["chain", "block_number", "block_hash", "tx_hash", "tx_index", "log_index", "address", "topics", "data"]
+}`
+
+// ReorgJSONSchema is the JSON Schema of reorg record values.
+const ReorgJSONSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "chainindex.ReorgEvent",
+  "type": "object",
+  "properties": {
+    "chain": {"type": "string"},
+    "common_ancestor": {"type": "integer", "minimum": 0},
+    "common_ancestor_hash": {"type": "string"},
+    "removed_blocks": {
+      "type": ["array", "null"],
+      "items": {
+        "type": "object",
+        "properties": {"number": {"type": "integer"}, "hash": {"type": "string"}},
+        "required": ["number"]
+      }
+    },
+    "detected_at": {"type": "string", "format": "date-time"}
+  },
+  "required": ["chain", "common_ancestor", "common_ancestor_hash", "removed_blocks", "detected_at"]
+}`
+
+// reorgID identifies a reorg by its common ancestor and the tip it
+// dropped: "chain:reorg:ancestor_hash:tip_hash".
+func reorgID(ev chainindex.ReorgEvent) string {
+	var tip string
+	if len(ev.RemovedBlocks) > 0 {
+		tip = ev.RemovedBlocks[0].Hash
+	}
+	return ev.Chain + ":reorg:" + strings.ToLower(ev.CommonAncestorHash) + ":" + strings.ToLower(tip)
+}
@@ -0,0 +1,242 @@
+// Package nats is a chainindex sink that publishes indexed events to NATS
+// JetStream, one subject per chain.
+//
+// The sink does not depend on a NATS client. It publishes through a
+// JetStream, a two-method interface to implement over nats.go's jetstream
+// package (PublishMsgAsync and CreateOrUpdateStream):
+//
+//	sink, _ := nats.New(js, nats.Config{})
+//	ix, _ := chainindex.NewIndexer(cfg, chainindex.WithEndpoints(url), chainindex.WithHandler(sink))
+//
+// Events go to "chainindex.<chain>.events" by default. The first batch
+// creates the stream capturing every chain's subjects, unless
+// Config.NoCreateStream says it is managed elsewhere. HandleEvents returns
+// only once the stream has acknowledged every message, so the indexer
+// checkpoints past a batch only after JetStream has stored it. Each
+// message carries a Nats-Msg-Id derived from the event's position, so the
+// batches republished after a crash are dropped by the stream's duplicate
+// window instead of stored twice.
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DarshanKumar89/chainfoundry/chainindex"
+)
+
+// Msg is one JetStream message.
+type Msg struct {
+	Subject string
+	Data    []byte
+	Header  map[string]string
+}
+
+// StreamConfig is the stream the sink creates.
+type StreamConfig struct {
+	Name     string
+	Subjects []string
+	// MaxAge discards messages older than this; zero keeps them.
+	MaxAge time.Duration
+	// Replicas is the stream's replication factor; zero means 1.
+	Replicas int
+	// Duplicates is the window in which messages with a repeated
+	// Nats-Msg-Id are dropped.
+	Duplicates time.Duration
+}
+
+// JetStream publishes to NATS JetStream.
+type JetStream interface {
+	// Publish publishes msgs in order and returns nil only once the
+	// stream has acknowledged every one.
+	Publish(ctx context.Context, msgs []Msg) error
+	// CreateStream creates the stream, or updates it to cfg if it exists.
+	CreateStream(ctx context.Context, cfg StreamConfig) error
+}
+
+// Defaults for Config.
+const (
+	DefaultStream          = "CHAININDEX"
+	DefaultSubject         = "chainindex.{chain}.events"
+	DefaultReorgSubject    = "chainindex.{chain}.reorgs"
+	DefaultDuplicateWindow = 10 * time.Minute
+)
+
+// Message headers.
+const (
+	// HeaderMsgID is JetStream's deduplication header:
+	// "chain:block_hash:log_index", with ":removed" appended for the
+	// compensating message of a removed event, or
+	// "chain:reorg:ancestor_hash:tip_hash" for a reorg.
+	HeaderMsgID = "Nats-Msg-Id"
+	// HeaderType is "event" or "reorg".
+	HeaderType = "Chainindex-Type"
+	// HeaderSchema is the chaincodec schema that decoded the event, if
+	// any.
+	HeaderSchema = "Chainindex-Schema"
+)
+
+// Config configures a Sink. Zero fields take the defaults noted on each.
+type Config struct {
+	// Stream is the stream name (default DefaultStream).
+	Stream string
+	// Subject names the subject each event goes to; "{chain}" and
+	// "{indexer}" are replaced with the event's chain and IndexerID.
+	// Default DefaultSubject. Chain names must be single subject tokens
+	// (no "."), as the created stream captures "{chain}" as "*".
+	Subject string
+	// ReorgSubject receives a chainindex.ReorgEvent message for each
+	// reorg, with the same replacements (default DefaultReorgSubject).
+	ReorgSubject string
+	// IndexerID fills "{indexer}".
+	IndexerID string
+	// NoCreateStream skips stream creation, for streams managed
+	// elsewhere; they must capture the subjects the sink publishes to.
+	NoCreateStream bool
+	// MaxAge and Replicas configure the created stream.
+	MaxAge   time.Duration
+	Replicas int
+	// DuplicateWindow is how long the created stream remembers message
+	// IDs (default DefaultDuplicateWindow); republished batches older
+	// than this are stored again.
+	DuplicateWindow time.Duration
+}
+
+// Sink publishes events to JetStream. It is safe for concurrent use.
+type Sink struct {
+	js  JetStream
+	cfg Config
+
+	mu      sync.Mutex
+	created bool
+}
+
+var (
+	_ chainindex.Handler      = (*Sink)(nil)
+	_ chainindex.ReorgHandler = (*Sink)(nil)
+)
+
+// New returns a sink publishing through js.
+func New(js JetStream, cfg Config) (*Sink, error) {
+	if js == nil {
+		return nil, fmt.Errorf("nats sink: nil jetstream")
+	}
+	if cfg.Stream == "" {
+		cfg.Stream = DefaultStream
+	}
+	if cfg.Subject == "" {
+		cfg.Subject = DefaultSubject
+	}
+	if cfg.ReorgSubject == "" {
+		cfg.ReorgSubject = DefaultReorgSubject
+	}
+	if cfg.DuplicateWindow == 0 {
+		cfg.DuplicateWindow = DefaultDuplicateWindow
+	}
+	if cfg.Subject == cfg.ReorgSubject {
+		return nil, fmt.Errorf("nats sink: subject and reorg subject are both %q", cfg.Subject)
+	}
+	return &Sink{js: js, cfg: cfg}, nil
+}
+
+// StreamConfig returns the stream the sink creates: Stream capturing
+// Subject and ReorgSubject for every chain.
+func (s *Sink) StreamConfig() StreamConfig {
+	return StreamConfig{
+		Name:       s.cfg.Stream,
+		Subjects:   []string{s.subject(s.cfg.Subject, "*"), s.subject(s.cfg.ReorgSubject, "*")},
+		MaxAge:     s.cfg.MaxAge,
+		Replicas:   s.cfg.Replicas,
+		Duplicates: s.cfg.DuplicateWindow,
+	}
+}
+
+// ensureStream creates the stream once; a failure is retried on the next
+// call.
+func (s *Sink) ensureStream(ctx context.Context) error {
+	if s.cfg.NoCreateStream {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.created {
+		return nil
+	}
+	if err := s.js.CreateStream(ctx, s.StreamConfig()); err != nil {
+		return fmt.Errorf("nats sink: create stream %s: %w", s.cfg.Stream, err)
+	}
+	s.created = true
+	return nil
+}
+
+// HandleEvents implements chainindex.Handler: it publishes one message
+// per event and returns once all are acknowledged.
+func (s *Sink) HandleEvents(ctx context.Context, events []chainindex.Event) error {
+	if err := s.ensureStream(ctx); err != nil {
+		return err
+	}
+	msgs := make([]Msg, 0, len(events))
+	for _, ev := range events {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return fmt.Errorf("nats sink: block %d log %d: %w", ev.BlockNumber, ev.LogIndex, err)
+		}
+		id := ev.Chain + ":" + strings.ToLower(ev.BlockHash) + ":" + strconv.FormatUint(ev.LogIndex, 10)
+		if ev.Removed {
+			id += ":removed"
+		}
+		h := map[string]string{HeaderMsgID: id, HeaderType: "event"}
+		if ev.Decoded != nil && ev.Decoded.Name != "" {
+			h[HeaderSchema] = ev.Decoded.Name
+		}
+		msgs = append(msgs, Msg{Subject: s.subject(s.cfg.Subject, ev.Chain), Data: data, Header: h})
+	}
+	if err := s.js.Publish(ctx, msgs); err != nil {
+		return fmt.Errorf("nats sink: publish: %w", err)
+	}
+	return nil
+}
+
+// HandleReorg implements chainindex.ReorgHandler: it publishes ev to the
+// chain's reorg subject and returns once it is acknowledged.
+func (s *Sink) HandleReorg(ctx context.Context, ev chainindex.ReorgEvent) error {
+	if err := s.ensureStream(ctx); err != nil {
+		return err
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("nats sink: reorg: %w", err)
+	}
+	msg := Msg{
+		Subject: s.subject(s.cfg.ReorgSubject, ev.Chain),
+		Data:    data,
+		Header: map[string]string{
+			HeaderMsgID: reorgID(ev),
+			HeaderType:  "reorg",
+		},
+	}
+	if err := s.js.Publish(ctx, []Msg{msg}); err != nil {
+		return fmt.Errorf("nats sink: publish reorg: %w", err)
+	}
+	return nil
+}
+
+// subject expands a subject template for chain.
+func (s *Sink) subject(tmpl, chain string) string {
+	return strings.NewReplacer("{chain}", chain, "{indexer}", s.cfg.IndexerID).Replace(tmpl)
+}
+
+// reorgID identifies a reorg by its common ancestor and the tip it
+// dropped: "chain:reorg:ancestor_hash:tip_hash".
+func reorgID(ev chainindex.ReorgEvent) string {
+	var tip string
+	if len(ev.RemovedBlocks) > 0 {
+		tip = ev.RemovedBlocks[0].Hash
+	}
+	return ev.Chain + ":reorg:" + strings.ToLower(ev.CommonAncestorHash) + ":" + strings.ToLower(tip)
+}
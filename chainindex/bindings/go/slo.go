@@ -0,0 +1,221 @@
+package chainindex
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// LagSLO is a consumer lag objective: the time from a block becoming
+// final on-chain to its events being acknowledged by the consumer.
+type LagSLO struct {
+	// Target is the lag the objective allows, e.g. 30s.
+	Target time.Duration
+	// Percentile is the share of finalized blocks that must be
+	// acknowledged within Target, e.g. 0.99. Zero means 0.99.
+	Percentile float64
+	// Window is how many recent samples percentiles are computed over.
+	// Zero means 1000.
+	Window int
+	// OnBreach, if set, is called when the objective becomes breached:
+	// the Percentile lag exceeds Target, or a finalized block has waited
+	// longer than Target without being acknowledged (a stuck consumer
+	// produces no samples). It is called again only after a recovery.
+	OnBreach func(SLOReport)
+	// OnRecover, if set, is called when a breached objective is met again.
+	OnRecover func(SLOReport)
+}
+
+// SLOReport is a LagTracker's current lag percentiles and throughput.
+type SLOReport struct {
+	Target     time.Duration `json:"target"`
+	Percentile float64       `json:"percentile"`
+	// Samples is how many finalization-to-ack latencies the percentiles
+	// cover.
+	Samples int           `json:"samples"`
+	P50     time.Duration `json:"p50"`
+	P90     time.Duration `json:"p90"`
+	P99     time.Duration `json:"p99"`
+	Max     time.Duration `json:"max"`
+	// Observed is the lag at Percentile, the value compared with Target.
+	Observed time.Duration `json:"observed"`
+	// Pending is how long the oldest finalized, unacknowledged block has
+	// waited; zero when the consumer is caught up.
+	Pending time.Duration `json:"pending"`
+	// Finalized and Acked are the highest finalized and acknowledged
+	// blocks.
+	Finalized uint64 `json:"finalized"`
+	Acked     uint64 `json:"acked"`
+	// EventsPerSecond and BlocksPerSecond are the acknowledged throughput
+	// over the last minute.
+	EventsPerSecond float64 `json:"events_per_second"`
+	BlocksPerSecond float64 `json:"blocks_per_second"`
+	Breached        bool    `json:"breached"`
+}
+
+// throughputWindow is the span EventsPerSecond and BlocksPerSecond cover.
+const throughputWindow = time.Minute
+
+// LagTracker measures consumer lag against a LagSLO. Finality is reported
+// with ObserveFinalized and the consumer's acknowledgements with
+// ObserveAck, both safe for concurrent use; an Indexer given the tracker
+// with WithLagTracker reports both.
+type LagTracker struct {
+	slo LagSLO
+	now func() time.Time
+
+	mu        sync.Mutex
+	pending   []finalMark // finalized blocks awaiting an ack, ascending
+	samples   []time.Duration
+	next      int // ring position in samples
+	acks      []ackMark
+	finalized uint64
+	acked     uint64
+	breached  bool
+}
+
+type finalMark struct {
+	block uint64
+	at    time.Time
+}
+
+type ackMark struct {
+	at             time.Time
+	blocks, events uint64
+}
+
+// NewLagTracker returns a tracker for slo.
+func NewLagTracker(slo LagSLO) *LagTracker {
+	if slo.Percentile <= 0 || slo.Percentile > 1 {
+		slo.Percentile = 0.99
+	}
+	if slo.Window <= 0 {
+		slo.Window = 1000
+	}
+	return &LagTracker{slo: slo, now: time.Now}
+}
+
+// WithLagTracker reports the indexer's lag to t: each block the indexer
+// may index (its target, the "finalized" or "safe" block or the block
+// ConfirmationDepth behind the head) is observed as finalized when a poll
+// first sees it, and each batch as acknowledged once its Handler returns.
+func WithLagTracker(t *LagTracker) Option {
+	return func(o *indexerOptions) { o.lag = t }
+}
+
+// ObserveFinalized records that blocks up to block became final at at,
+// e.g. when a poll first sees them at or below the "finalized" tag. A
+// zero at means now.
+func (t *LagTracker) ObserveFinalized(block uint64, at time.Time) {
+	now := t.now()
+	if at.IsZero() {
+		at = now
+	}
+	t.mu.Lock()
+	if block > t.finalized {
+		t.finalized = block
+		if block <= t.acked {
+			// Acknowledged before it was final: no lag.
+			t.sample(0)
+		} else {
+			t.pending = append(t.pending, finalMark{block: block, at: at})
+		}
+	}
+	r, fire := t.evaluate(now)
+	t.mu.Unlock()
+	fire(r)
+}
+
+// ObserveAck records that the consumer acknowledged the events of blocks
+// up to block, events of them since its last acknowledgement.
+func (t *LagTracker) ObserveAck(block uint64, events int) {
+	now := t.now()
+	t.mu.Lock()
+	if block > t.acked {
+		t.acks = append(t.acks, ackMark{at: now, blocks: block - t.acked, events: uint64(events)})
+		t.acked = block
+	}
+	n := 0
+	for n < len(t.pending) && t.pending[n].block <= block {
+		t.sample(now.Sub(t.pending[n].at))
+		n++
+	}
+	t.pending = t.pending[n:]
+	r, fire := t.evaluate(now)
+	t.mu.Unlock()
+	fire(r)
+}
+
+// Report returns the tracker's current percentiles and throughput.
+func (t *LagTracker) Report() SLOReport {
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.report(now)
+}
+
+// sample adds one latency to the ring.
+func (t *LagTracker) sample(d time.Duration) {
+	if len(t.samples) < t.slo.Window {
+		t.samples = append(t.samples, d)
+		return
+	}
+	t.samples[t.next] = d
+	t.next = (t.next + 1) % t.slo.Window
+}
+
+func (t *LagTracker) report(now time.Time) SLOReport {
+	r := SLOReport{
+		Target:     t.slo.Target,
+		Percentile: t.slo.Percentile,
+		Samples:    len(t.samples),
+		Finalized:  t.finalized,
+		Acked:      t.acked,
+	}
+	if len(t.samples) > 0 {
+		sorted := append([]time.Duration(nil), t.samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		at := func(p float64) time.Duration {
+			i := int(p*float64(len(sorted))+0.5) - 1
+			return sorted[min(max(i, 0), len(sorted)-1)]
+		}
+		r.P50, r.P90, r.P99 = at(0.5), at(0.9), at(0.99)
+		r.Max = sorted[len(sorted)-1]
+		r.Observed = at(t.slo.Percentile)
+	}
+	if len(t.pending) > 0 {
+		r.Pending = now.Sub(t.pending[0].at)
+	}
+	cut := now.Add(-throughputWindow)
+	n := 0
+	for n < len(t.acks) && !t.acks[n].at.After(cut) {
+		n++
+	}
+	t.acks = t.acks[n:]
+	var blocks, events uint64
+	for _, a := range t.acks {
+		blocks += a.blocks
+		events += a.events
+	}
+	r.BlocksPerSecond = float64(blocks) / throughputWindow.Seconds()
+	r.EventsPerSecond = float64(events) / throughputWindow.Seconds()
+	r.Breached = t.slo.Target > 0 && (r.Observed > t.slo.Target || r.Pending > t.slo.Target)
+	return r
+}
+
+// evaluate computes the report and returns the hook to call, if the
+// breach state changed, once the lock is released.
+func (t *LagTracker) evaluate(now time.Time) (SLOReport, func(SLOReport)) {
+	r := t.report(now)
+	hook := func(SLOReport) {}
+	if r.Breached != t.breached {
+		t.breached = r.Breached
+		switch {
+		case r.Breached && t.slo.OnBreach != nil:
+			hook = t.slo.OnBreach
+		case !r.Breached && t.slo.OnRecover != nil:
+			hook = t.slo.OnRecover
+		}
+	}
+	return r, hook
+}
@@ -0,0 +1,121 @@
+package chainindex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/DarshanKumar89/chainfoundry/chainrpc"
+)
+
+// Caller issues JSON-RPC requests on behalf of the indexer.
+// *chainrpc.Pool satisfies it.
+type Caller interface {
+	CallContext(ctx context.Context, method, paramsJSON string, opts ...chainrpc.Option) (string, error)
+}
+
+// ErrNotDeployed is returned by FindDeploymentBlock when the address has no
+// code at the chain head.
+var ErrNotDeployed = errors.New("chainindex: no contract code at address")
+
+// ResolveFromBlock returns the concrete first block for cfg.
+//
+// cfg.StartFrom accepts:
+//
+//	""                  use cfg.FromBlock as is
+//	"latest"            the current head
+//	"latest-N"          N blocks behind the current head
+//	"deployment:0x..."  the block the contract at 0x... was deployed in
+//
+// The RPC is only consulted for the relative forms.
+func ResolveFromBlock(ctx context.Context, cfg *IndexerConfig, rpc Caller) (uint64, error) {
+	spec := strings.TrimSpace(cfg.StartFrom)
+	switch {
+	case spec == "":
+		return cfg.FromBlock, nil
+	case spec == "latest" || strings.HasPrefix(spec, "latest-"):
+		var back uint64
+		if rest := strings.TrimPrefix(spec, "latest"); rest != "" {
+			n, err := strconv.ParseUint(strings.TrimSpace(rest[1:]), 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("chainindex: invalid start_from %q", cfg.StartFrom)
+			}
+			back = n
+		}
+		head, err := blockNumber(ctx, rpc)
+		if err != nil {
+			return 0, err
+		}
+		if back > head {
+			return 0, nil
+		}
+		return head - back, nil
+	case strings.HasPrefix(spec, "deployment:"):
+		return FindDeploymentBlock(ctx, rpc, strings.TrimPrefix(spec, "deployment:"))
+	}
+	return 0, fmt.Errorf("chainindex: invalid start_from %q", cfg.StartFrom)
+}
+
+// FindDeploymentBlock binary-searches eth_getCode over historical state to
+// find the first block in which address has code. It needs an archive node
+// for blocks older than the provider's state retention window.
+func FindDeploymentBlock(ctx context.Context, rpc Caller, address string) (uint64, error) {
+	head, err := blockNumber(ctx, rpc)
+	if err != nil {
+		return 0, err
+	}
+	ok, err := hasCode(ctx, rpc, address, head)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, fmt.Errorf("%w %s", ErrNotDeployed, address)
+	}
+	lo, hi := uint64(0), head
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		ok, err := hasCode(ctx, rpc, address, mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo, nil
+}
+
+func blockNumber(ctx context.Context, rpc Caller) (uint64, error) {
+	res, err := rpc.CallContext(ctx, "eth_blockNumber", "[]")
+	if err != nil {
+		return 0, err
+	}
+	return hexResult(res)
+}
+
+func hasCode(ctx context.Context, rpc Caller, address string, block uint64) (bool, error) {
+	params, _ := json.Marshal([]string{address, fmt.Sprintf("0x%x", block)})
+	res, err := rpc.CallContext(ctx, "eth_getCode", string(params))
+	if err != nil {
+		return false, err
+	}
+	var code string
+	if err := json.Unmarshal([]byte(res), &code); err != nil {
+		return false, fmt.Errorf("chainindex: eth_getCode result: %w", err)
+	}
+	return code != "" && code != "0x", nil
+}
+
+// hexResult decodes a JSON-encoded hex quantity result.
+func hexResult(res string) (uint64, error) {
+	var s string
+	if err := json.Unmarshal([]byte(res), &s); err != nil {
+		return 0, fmt.Errorf("chainindex: expected hex quantity, got %s", res)
+	}
+	return hexUint(s)
+}
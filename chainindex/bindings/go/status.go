@@ -0,0 +1,147 @@
+package chainindex
+
+import "time"
+
+// IndexerStatus answers "is the indexer keeping up?" in one call.
+type IndexerStatus struct {
+	ID    string `json:"id"`
+	Chain string `json:"chain"`
+	// State is StateRunning, StatePaused or StateStopped.
+	State string `json:"state"`
+	// Head is the chain head last seen and Target the highest block the
+	// indexer could index then: Head less ConfirmationDepth, or the block
+	// Follow names.
+	Head   uint64 `json:"head"`
+	Target uint64 `json:"target"`
+	// Block is the last block indexed, in this run or, from its
+	// checkpoint, an earlier one; nil before there is one.
+	Block *uint64 `json:"block,omitempty"`
+	// LagBlocks is how far Block trails Target. LagSeconds is how long the
+	// oldest block not yet indexed has been confirmed, counted from when
+	// the indexer first saw it so, or from the start of the run for
+	// blocks confirmed before; it grows while the indexer falls behind.
+	LagBlocks  uint64  `json:"lag_blocks"`
+	LagSeconds float64 `json:"lag_seconds"`
+	// BlocksPerSecond and EventsPerSecond are the indexing throughput over
+	// the last minute.
+	BlocksPerSecond float64 `json:"blocks_per_second"`
+	EventsPerSecond float64 `json:"events_per_second"`
+	// Errors counts the RPC and handler errors retried since NewIndexer,
+	// ConsecutiveErrors those since the last batch indexed.
+	Errors            uint64    `json:"errors"`
+	ConsecutiveErrors uint64    `json:"consecutive_errors"`
+	LastError         string    `json:"last_error,omitempty"`
+	LastErrorAt       time.Time `json:"last_error_at,omitempty"`
+	// DeadLetters is how many blocks are dead-lettered; see DeadLetters.
+	DeadLetters int `json:"dead_letters"`
+}
+
+// progress is what Status reports, guarded by Indexer.mu.
+type progress struct {
+	head, target uint64
+	last         uint64
+	indexed      bool        // last is set
+	confirmed    []finalMark // target sightings above last, ascending
+	batches      []ackMark   // batches in the last throughputWindow
+	errors       uint64
+	consecutive  uint64
+	lastErr      error
+	lastErrAt    time.Time
+}
+
+// Status returns the indexer's position, lag, throughput and errors. It is
+// safe to call at any time, including while Run is running.
+func (ix *Indexer) Status() IndexerStatus {
+	now := time.Now()
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	p := &ix.progress
+	s := IndexerStatus{
+		ID:                ix.cfg.ID,
+		Chain:             ix.cfg.Chain,
+		State:             StateStopped,
+		Head:              p.head,
+		Target:            p.target,
+		Errors:            p.errors,
+		ConsecutiveErrors: p.consecutive,
+		LastErrorAt:       p.lastErrAt,
+		DeadLetters:       len(ix.dead),
+	}
+	switch {
+	case ix.running && ix.paused:
+		s.State = StatePaused
+	case ix.running:
+		s.State = StateRunning
+	}
+	if p.lastErr != nil {
+		s.LastError = p.lastErr.Error()
+	}
+	if p.indexed {
+		b := p.last
+		s.Block = &b
+		if p.target > b {
+			s.LagBlocks = p.target - b
+		}
+	} else if p.target+1 > ix.next {
+		s.LagBlocks = p.target + 1 - ix.next
+	}
+	if len(p.confirmed) > 0 {
+		s.LagSeconds = now.Sub(p.confirmed[0].at).Seconds()
+	}
+	p.trim(now)
+	var blocks, events uint64
+	for _, b := range p.batches {
+		blocks += b.blocks
+		events += b.events
+	}
+	s.BlocksPerSecond = float64(blocks) / throughputWindow.Seconds()
+	s.EventsPerSecond = float64(events) / throughputWindow.Seconds()
+	return s
+}
+
+// observeTarget records a poll's head and target.
+func (ix *Indexer) observeTarget(head, target uint64) {
+	now := time.Now()
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	p := &ix.progress
+	p.head = head
+	if target <= p.target && len(p.confirmed) > 0 {
+		return
+	}
+	p.target = target
+	if !p.indexed || target > p.last {
+		p.confirmed = append(p.confirmed, finalMark{block: target, at: now})
+	}
+}
+
+// observeIndexed records that blocks from..to, with events matched
+// events, were indexed. Callers hold ix.mu.
+func (p *progress) observeIndexed(from, to uint64, events int) {
+	now := time.Now()
+	p.setLast(to)
+	p.batches = append(p.batches, ackMark{at: now, blocks: to - from + 1, events: uint64(events)})
+	p.consecutive = 0
+	p.trim(now)
+}
+
+// setLast moves the last indexed block to block, forward or, after a
+// reorg, back.
+func (p *progress) setLast(block uint64) {
+	p.last, p.indexed = block, true
+	n := 0
+	for n < len(p.confirmed) && p.confirmed[n].block <= block {
+		n++
+	}
+	p.confirmed = p.confirmed[n:]
+}
+
+// trim drops batches older than throughputWindow.
+func (p *progress) trim(now time.Time) {
+	cut := now.Add(-throughputWindow)
+	n := 0
+	for n < len(p.batches) && !p.batches[n].at.After(cut) {
+		n++
+	}
+	p.batches = p.batches[n:]
+}
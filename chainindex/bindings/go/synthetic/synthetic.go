@@ -0,0 +1,672 @@
+// Package synthetic generates a deterministic fake EVM chain for exercising
+// indexing pipelines without an RPC endpoint.
+//
+// A Chain answers the JSON-RPC methods an indexer uses (eth_blockNumber,
+// eth_getBlockByNumber, eth_getBlockByHash, eth_getLogs, eth_getCode,
+// eth_chainId) and satisfies chainindex.Caller, so it can stand in for a
+// *chainrpc.Pool anywhere the indexer takes one. Blocks are derived on
+// demand from the seed, so chains of millions of blocks cost no memory, and
+// two Chains built from the same Config serve identical data.
+package synthetic
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DarshanKumar89/chainfoundry/chaincodec/abi"
+	"github.com/DarshanKumar89/chainfoundry/chainrpc"
+)
+
+// Config describes the chain to generate. Zero fields take the defaults
+// noted on each.
+type Config struct {
+	// ChainID is returned by eth_chainId (default 1337).
+	ChainID uint64
+	// Seed makes the chain reproducible.
+	Seed int64
+	// Head is the initial head block (default 1000). Advance extends it.
+	Head uint64
+	// BlockTime spaces block timestamps (default 12s).
+	BlockTime time.Duration
+	// Genesis is the timestamp of block 0 (default 2024-01-01 UTC).
+	Genesis time.Time
+	// Events are the event signatures logs are generated for, e.g.
+	// "Transfer(address indexed from, address indexed to, uint256 value)".
+	// Default: the ERC-20 Transfer event.
+	Events []string
+	// Contracts is the number of emitting contract addresses (default 3).
+	Contracts int
+	// LogsPerBlock is the mean number of logs per block (default 10).
+	LogsPerBlock int
+	// ReorgEvery replaces the last ReorgDepth blocks with a new fork every
+	// ReorgEvery blocks as the chain advances (0 = never).
+	ReorgEvery uint64
+	// ReorgDepth is the number of blocks each reorg replaces (default 3).
+	ReorgDepth uint64
+	// MaxLogsPerQuery makes eth_getLogs fail like a capped provider when a
+	// query would return more logs (0 = unlimited).
+	MaxLogsPerQuery int
+}
+
+// Reorg records one reorganisation: blocks From..To were replaced.
+type Reorg struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
+
+type event struct {
+	sig     string
+	topic0  []byte
+	indexed []abi.Type
+	data    []abi.Type
+}
+
+// Chain is a generated chain. It is safe for concurrent use.
+type Chain struct {
+	cfg       Config
+	events    []event
+	contracts []abi.Address
+
+	mu     sync.RWMutex
+	head   uint64
+	forks  map[uint64]uint32 // fork number of every block replaced by a reorg
+	reorgs []Reorg
+}
+
+// New builds a chain from cfg.
+func New(cfg Config) (*Chain, error) {
+	if cfg.ChainID == 0 {
+		cfg.ChainID = 1337
+	}
+	if cfg.Head == 0 {
+		cfg.Head = 1000
+	}
+	if cfg.BlockTime <= 0 {
+		cfg.BlockTime = 12 * time.Second
+	}
+	if cfg.Genesis.IsZero() {
+		cfg.Genesis = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if len(cfg.Events) == 0 {
+		cfg.Events = []string{"Transfer(address indexed from, address indexed to, uint256 value)"}
+	}
+	if cfg.Contracts <= 0 {
+		cfg.Contracts = 3
+	}
+	if cfg.LogsPerBlock <= 0 {
+		cfg.LogsPerBlock = 10
+	}
+	if cfg.ReorgDepth == 0 {
+		cfg.ReorgDepth = 3
+	}
+	c := &Chain{cfg: cfg, head: cfg.Head, forks: make(map[uint64]uint32)}
+	for _, sig := range cfg.Events {
+		ev, err := parseEvent(sig)
+		if err != nil {
+			return nil, err
+		}
+		c.events = append(c.events, ev)
+	}
+	for i := 0; i < cfg.Contracts; i++ {
+		var a abi.Address
+		copy(a[:], abi.Keccak256([]byte(fmt.Sprintf("synthetic contract %d/%d", cfg.Seed, i))))
+		c.contracts = append(c.contracts, a)
+	}
+	return c, nil
+}
+
+// parseEvent parses "Name(type [indexed] [name], ...)".
+func parseEvent(sig string) (event, error) {
+	open := strings.Index(sig, "(")
+	if open <= 0 || !strings.HasSuffix(strings.TrimSpace(sig), ")") {
+		return event{}, fmt.Errorf("synthetic: invalid event signature %q", sig)
+	}
+	name := strings.TrimSpace(sig[:open])
+	body := strings.TrimSpace(sig)
+	body = body[open+1 : len(body)-1]
+	ev := event{}
+	var canonical []string
+	for _, p := range splitParams(body) {
+		fields := strings.Fields(p)
+		indexed := false
+		for i, f := range fields {
+			if f == "indexed" {
+				indexed = true
+				fields = append(fields[:i:i], fields[i+1:]...)
+				break
+			}
+		}
+		if len(fields) == 0 {
+			return event{}, fmt.Errorf("synthetic: empty parameter in %q", sig)
+		}
+		t, err := abi.ParseType(fields[0])
+		if err != nil {
+			return event{}, fmt.Errorf("synthetic: %q: %w", sig, err)
+		}
+		canonical = append(canonical, t.String())
+		if indexed {
+			ev.indexed = append(ev.indexed, t)
+		} else {
+			ev.data = append(ev.data, t)
+		}
+	}
+	if len(ev.indexed) > 3 {
+		return event{}, fmt.Errorf("synthetic: %q has more than 3 indexed parameters", sig)
+	}
+	ev.sig = name + "(" + strings.Join(canonical, ",") + ")"
+	ev.topic0 = abi.Keccak256([]byte(ev.sig))
+	return ev, nil
+}
+
+// splitParams splits a parameter list on top-level commas.
+func splitParams(s string) []string {
+	var out []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				out = append(out, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if rest := strings.TrimSpace(s[start:]); rest != "" {
+		out = append(out, rest)
+	}
+	return out
+}
+
+// Contracts returns the addresses that emit logs, as EIP-55 hex.
+func (c *Chain) Contracts() []string {
+	out := make([]string, len(c.contracts))
+	for i, a := range c.contracts {
+		out[i] = a.Hex()
+	}
+	return out
+}
+
+// Topic0 returns the topic0 hash of each configured event, in Config.Events
+// order.
+func (c *Chain) Topic0() []string {
+	out := make([]string, len(c.events))
+	for i, ev := range c.events {
+		out[i] = hex0x(ev.topic0)
+	}
+	return out
+}
+
+// Head returns the current head block number.
+func (c *Chain) Head() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.head
+}
+
+// Reorgs returns every reorg so far, oldest first.
+func (c *Chain) Reorgs() []Reorg {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Reorg(nil), c.reorgs...)
+}
+
+// Advance mines n blocks, reorging the tip whenever the head crosses a
+// multiple of Config.ReorgEvery.
+func (c *Chain) Advance(n uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := uint64(0); i < n; i++ {
+		c.head++
+		if c.cfg.ReorgEvery > 0 && c.head%c.cfg.ReorgEvery == 0 && c.head > c.cfg.ReorgDepth {
+			c.reorgLocked(c.cfg.ReorgDepth)
+		}
+	}
+}
+
+// Reorg replaces the last depth blocks with a new fork now.
+func (c *Chain) Reorg(depth uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reorgLocked(depth)
+}
+
+func (c *Chain) reorgLocked(depth uint64) {
+	if depth == 0 {
+		return
+	}
+	if depth > c.head {
+		depth = c.head
+	}
+	from := c.head - depth + 1
+	for b := from; b <= c.head; b++ {
+		c.forks[b]++
+	}
+	c.reorgs = append(c.reorgs, Reorg{From: from, To: c.head})
+}
+
+// block is a generated block on the current fork.
+type block struct {
+	number uint64
+	hash   []byte
+	parent []byte
+	time   uint64
+	txs    [][]byte
+	logs   []chainrpc.Log
+}
+
+// hashOf returns the hash of block n on fork f. The block number is kept in
+// the last 8 bytes so eth_getBlockByHash can find the block without an
+// index.
+func (c *Chain) hashOf(n uint64, f uint32) []byte {
+	var buf [20]byte
+	binary.BigEndian.PutUint64(buf[0:], uint64(c.cfg.Seed))
+	binary.BigEndian.PutUint64(buf[8:], n)
+	binary.BigEndian.PutUint32(buf[16:], f)
+	h := abi.Keccak256([]byte("synthetic block"), buf[:])
+	binary.BigEndian.PutUint64(h[24:], n)
+	return h
+}
+
+// blockLocked generates block n on the current fork; c.mu must be held.
+func (c *Chain) blockLocked(n uint64) *block {
+	f := c.forks[n]
+	b := &block{
+		number: n,
+		hash:   c.hashOf(n, f),
+		time:   uint64(c.cfg.Genesis.Add(time.Duration(n) * c.cfg.BlockTime).Unix()),
+	}
+	if n > 0 {
+		b.parent = c.hashOf(n-1, c.forks[n-1])
+	} else {
+		b.parent = make([]byte, 32)
+	}
+	if n == 0 {
+		return b
+	}
+	rng := rand.New(rand.NewSource(int64(binary.BigEndian.Uint64(b.hash[:8]))))
+	nLogs := rng.Intn(2*c.cfg.LogsPerBlock + 1)
+	var tx []byte
+	for i := 0; i < nLogs; i++ {
+		// Transactions emit one to three logs each.
+		if tx == nil || rng.Intn(3) == 0 {
+			tx = abi.Keccak256(b.hash, []byte{byte(len(b.txs) >> 8), byte(len(b.txs))})
+			b.txs = append(b.txs, tx)
+		}
+		b.logs = append(b.logs, c.genLog(rng, b, tx, len(b.txs)-1, i))
+	}
+	return b
+}
+
+func (c *Chain) genLog(rng *rand.Rand, b *block, tx []byte, txIndex, logIndex int) chainrpc.Log {
+	ev := c.events[rng.Intn(len(c.events))]
+	topics := []string{hex0x(ev.topic0)}
+	for _, t := range ev.indexed {
+		enc, _ := abi.Encode([]abi.Type{t}, []interface{}{c.genValue(rng, t)})
+		if len(enc) != 32 {
+			// Dynamic indexed values are stored as their hash.
+			enc = abi.Keccak256(enc)
+		}
+		topics = append(topics, hex0x(enc))
+	}
+	values := make([]interface{}, len(ev.data))
+	for i, t := range ev.data {
+		values[i] = c.genValue(rng, t)
+	}
+	data, _ := abi.Encode(ev.data, values)
+	return chainrpc.Log{
+		Address:          c.contracts[rng.Intn(len(c.contracts))].Hex(),
+		Topics:           topics,
+		Data:             hex0x(data),
+		BlockNumber:      quantity(b.number),
+		BlockHash:        hex0x(b.hash),
+		TransactionHash:  hex0x(tx),
+		TransactionIndex: quantity(uint64(txIndex)),
+		LogIndex:         quantity(uint64(logIndex)),
+	}
+}
+
+// genValue returns a random value of type t that abi.Encode accepts.
+func (c *Chain) genValue(rng *rand.Rand, t abi.Type) interface{} {
+	switch t.Kind {
+	case abi.KindUint:
+		// Mostly token-sized amounts, bounded by the type's width.
+		bits := t.Size
+		if bits > 96 {
+			bits = 96
+		}
+		return new(big.Int).Rand(rng, new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+	case abi.KindInt:
+		bits := t.Size - 1
+		if bits > 95 {
+			bits = 95
+		}
+		n := new(big.Int).Rand(rng, new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+		if rng.Intn(2) == 0 {
+			n.Neg(n)
+		}
+		return n
+	case abi.KindAddress:
+		// Reuse a small population so address filters find matches.
+		var a abi.Address
+		copy(a[:], abi.Keccak256([]byte(fmt.Sprintf("synthetic account %d/%d", c.cfg.Seed, rng.Intn(64)))))
+		return a
+	case abi.KindBool:
+		return rng.Intn(2) == 1
+	case abi.KindFixedBytes:
+		return randBytes(rng, t.Size)
+	case abi.KindBytes:
+		return randBytes(rng, rng.Intn(96))
+	case abi.KindString:
+		return fmt.Sprintf("synthetic-%d", rng.Intn(1e6))
+	case abi.KindSlice, abi.KindArray:
+		n := t.Size
+		if t.Kind == abi.KindSlice {
+			n = rng.Intn(4)
+		}
+		out := make([]interface{}, n)
+		for i := range out {
+			out[i] = c.genValue(rng, *t.Elem)
+		}
+		return out
+	case abi.KindTuple:
+		out := make([]interface{}, len(t.Components))
+		for i, ct := range t.Components {
+			out[i] = c.genValue(rng, ct)
+		}
+		return out
+	}
+	return nil
+}
+
+func randBytes(rng *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	rng.Read(b)
+	return b
+}
+
+// CallContext serves one JSON-RPC request. Errors use the same
+// "JSON-RPC code: message" form as the native transport.
+func (c *Chain) CallContext(ctx context.Context, method, paramsJSON string, opts ...chainrpc.Option) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	var params []json.RawMessage
+	if paramsJSON != "" {
+		if err := json.Unmarshal([]byte(paramsJSON), &params); err != nil {
+			return "", rpcError(-32602, "invalid params: "+err.Error())
+		}
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var out interface{}
+	var err error
+	switch method {
+	case "eth_chainId":
+		out = quantity(c.cfg.ChainID)
+	case "eth_blockNumber":
+		out = quantity(c.head)
+	case "eth_getBlockByNumber":
+		out, err = c.getBlockByNumber(params)
+	case "eth_getBlockByHash":
+		out, err = c.getBlockByHash(params)
+	case "eth_getLogs":
+		out, err = c.getLogs(params)
+	case "eth_getCode":
+		out, err = c.getCode(params)
+	default:
+		return "", rpcError(-32601, fmt.Sprintf("the method %s does not exist/is not available", method))
+	}
+	if err != nil {
+		return "", err
+	}
+	res, err := json.Marshal(out)
+	return string(res), err
+}
+
+func rpcError(code int, msg string) error {
+	return fmt.Errorf("JSON-RPC %d: %s", code, msg)
+}
+
+// blockNumber resolves a block tag or hex number against the head.
+func (c *Chain) blockNumber(raw json.RawMessage) (uint64, error) {
+	var tag string
+	if len(raw) == 0 {
+		return c.head, nil
+	}
+	if err := json.Unmarshal(raw, &tag); err != nil {
+		return 0, rpcError(-32602, "invalid block tag")
+	}
+	switch tag {
+	case "", "latest", "pending":
+		return c.head, nil
+	case "earliest":
+		return 0, nil
+	case "safe", "finalized":
+		depth := c.cfg.ReorgDepth
+		if tag == "finalized" {
+			depth *= 2
+		}
+		if depth > c.head {
+			return 0, nil
+		}
+		return c.head - depth, nil
+	}
+	n, ok := new(big.Int).SetString(strings.TrimPrefix(tag, "0x"), 16)
+	if !ok || !strings.HasPrefix(tag, "0x") || !n.IsUint64() {
+		return 0, rpcError(-32602, fmt.Sprintf("invalid block number %q", tag))
+	}
+	return n.Uint64(), nil
+}
+
+func (c *Chain) getBlockByNumber(params []json.RawMessage) (interface{}, error) {
+	if len(params) == 0 {
+		return nil, rpcError(-32602, "missing block number")
+	}
+	n, err := c.blockNumber(params[0])
+	if err != nil {
+		return nil, err
+	}
+	if n > c.head {
+		return nil, nil
+	}
+	return c.blockJSON(c.blockLocked(n), fullTxs(params)), nil
+}
+
+func (c *Chain) getBlockByHash(params []json.RawMessage) (interface{}, error) {
+	var h string
+	if len(params) == 0 || json.Unmarshal(params[0], &h) != nil {
+		return nil, rpcError(-32602, "missing block hash")
+	}
+	b, ok := c.blockByHash(h)
+	if !ok {
+		return nil, nil
+	}
+	return c.blockJSON(b, fullTxs(params)), nil
+}
+
+func (c *Chain) blockByHash(h string) (*block, bool) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(h), "0x"))
+	if err != nil || len(raw) != 32 {
+		return nil, false
+	}
+	n := binary.BigEndian.Uint64(raw[24:])
+	if n > c.head {
+		return nil, false
+	}
+	b := c.blockLocked(n)
+	if hex0x(b.hash) != hex0x(raw) {
+		// A block from an abandoned fork.
+		return nil, false
+	}
+	return b, true
+}
+
+func fullTxs(params []json.RawMessage) bool {
+	var full bool
+	return len(params) > 1 && json.Unmarshal(params[1], &full) == nil && full
+}
+
+func (c *Chain) blockJSON(b *block, full bool) map[string]interface{} {
+	txs := make([]interface{}, len(b.txs))
+	for i, tx := range b.txs {
+		if !full {
+			txs[i] = hex0x(tx)
+			continue
+		}
+		txs[i] = map[string]interface{}{
+			"hash":             hex0x(tx),
+			"blockHash":        hex0x(b.hash),
+			"blockNumber":      quantity(b.number),
+			"transactionIndex": quantity(uint64(i)),
+		}
+	}
+	return map[string]interface{}{
+		"number":        quantity(b.number),
+		"hash":          hex0x(b.hash),
+		"parentHash":    hex0x(b.parent),
+		"timestamp":     quantity(b.time),
+		"gasLimit":      quantity(30_000_000),
+		"gasUsed":       quantity(uint64(len(b.logs)) * 50_000),
+		"baseFeePerGas": quantity(1_000_000_000),
+		"transactions":  txs,
+	}
+}
+
+type logFilter struct {
+	FromBlock json.RawMessage   `json:"fromBlock"`
+	ToBlock   json.RawMessage   `json:"toBlock"`
+	BlockHash string            `json:"blockHash"`
+	Address   json.RawMessage   `json:"address"`
+	Topics    []json.RawMessage `json:"topics"`
+}
+
+func (c *Chain) getLogs(params []json.RawMessage) (interface{}, error) {
+	var f logFilter
+	if len(params) == 0 || json.Unmarshal(params[0], &f) != nil {
+		return nil, rpcError(-32602, "missing filter")
+	}
+	addrs, err := stringSet(f.Address)
+	if err != nil {
+		return nil, rpcError(-32602, "invalid address filter")
+	}
+	topics := make([]map[string]bool, len(f.Topics))
+	for i, t := range f.Topics {
+		if topics[i], err = stringSet(t); err != nil {
+			return nil, rpcError(-32602, "invalid topics filter")
+		}
+	}
+
+	var blocks []*block
+	if f.BlockHash != "" {
+		b, ok := c.blockByHash(f.BlockHash)
+		if !ok {
+			return nil, rpcError(-32000, "unknown block")
+		}
+		blocks = []*block{b}
+	} else {
+		from, err := c.blockNumber(f.FromBlock)
+		if err != nil {
+			return nil, err
+		}
+		to, err := c.blockNumber(f.ToBlock)
+		if err != nil {
+			return nil, err
+		}
+		if to > c.head {
+			to = c.head
+		}
+		for n := from; n <= to; n++ {
+			blocks = append(blocks, c.blockLocked(n))
+		}
+	}
+
+	out := []chainrpc.Log{}
+	for _, b := range blocks {
+		for _, l := range b.logs {
+			if !matches(l, addrs, topics) {
+				continue
+			}
+			if c.cfg.MaxLogsPerQuery > 0 && len(out) == c.cfg.MaxLogsPerQuery {
+				return nil, rpcError(-32005, fmt.Sprintf("query returned more than %d results", c.cfg.MaxLogsPerQuery))
+			}
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+// stringSet decodes a filter value that is null, a string or an array of
+// strings into a lower-cased set; nil means "match anything".
+func stringSet(raw json.RawMessage) (map[string]bool, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		return map[string]bool{strings.ToLower(one): true}, nil
+	}
+	var many []string
+	if err := json.Unmarshal(raw, &many); err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(many))
+	for _, s := range many {
+		set[strings.ToLower(s)] = true
+	}
+	return set, nil
+}
+
+func matches(l chainrpc.Log, addrs map[string]bool, topics []map[string]bool) bool {
+	if addrs != nil && !addrs[strings.ToLower(l.Address)] {
+		return false
+	}
+	for i, want := range topics {
+		if want == nil {
+			continue
+		}
+		if i >= len(l.Topics) || !want[strings.ToLower(l.Topics[i])] {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Chain) getCode(params []json.RawMessage) (interface{}, error) {
+	var addr string
+	if len(params) == 0 || json.Unmarshal(params[0], &addr) != nil {
+		return nil, rpcError(-32602, "missing address")
+	}
+	if len(params) > 1 {
+		n, err := c.blockNumber(params[1])
+		if err != nil {
+			return nil, err
+		}
+		// Contracts are deployed in block 1.
+		if n == 0 {
+			return "0x", nil
+		}
+	}
+	for _, a := range c.contracts {
+		if strings.EqualFold(a.Hex(), addr) {
+			return "0x6080604052", nil
+		}
+	}
+	return "0x", nil
+}
+
+func quantity(n uint64) string { return fmt.Sprintf("0x%x", n) }
+
+func hex0x(b []byte) string { return "0x" + hex.EncodeToString(b) }
@@ -0,0 +1,88 @@
+package chainindex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Block tags IndexerConfig.Follow accepts.
+const (
+	// FollowLatest follows the head, ConfirmationDepth blocks behind.
+	FollowLatest = "latest"
+	// FollowSafe follows the chain's "safe" block, which is unlikely to be
+	// reorged.
+	FollowSafe = "safe"
+	// FollowFinalized follows the chain's "finalized" block, which cannot
+	// be reorged.
+	FollowFinalized = "finalized"
+)
+
+// follow returns cfg.Follow with the default applied, or an error if it is
+// not a known tag.
+func (cfg *IndexerConfig) follow() (string, error) {
+	switch tag := strings.TrimSpace(cfg.Follow); tag {
+	case "":
+		return FollowLatest, nil
+	case FollowLatest, FollowSafe, FollowFinalized:
+		return tag, nil
+	}
+	return "", fmt.Errorf("chainindex: invalid follow %q: want latest, safe or finalized", cfg.Follow)
+}
+
+// target returns the chain head and the highest block the indexer may
+// index now: head minus ConfirmationDepth when following "latest", or the
+// "safe" or "finalized" block, and never past ToBlock, and records them
+// for Status. ok is false when no block is confirmed yet.
+func (ix *Indexer) target(ctx context.Context) (head, target uint64, ok bool, err error) {
+	head, err = blockNumber(ctx, ix.rpc)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	switch ix.follow {
+	case FollowLatest:
+		if head < ix.cfg.ConfirmationDepth {
+			return head, 0, false, nil
+		}
+		target = head - ix.cfg.ConfirmationDepth
+	default:
+		target, ok, err = taggedBlock(ctx, ix.rpc, ix.follow)
+		if err != nil || !ok {
+			return head, 0, false, err
+		}
+		// Providers may answer the tag from a node a little ahead of the
+		// one that answered eth_blockNumber.
+		head = max(head, target)
+	}
+	if to := ix.cfg.ToBlock; to != nil && *to < target {
+		target = *to
+	}
+	ix.observeTarget(head, target)
+	if ix.lag != nil {
+		ix.lag.ObserveFinalized(target, time.Time{})
+	}
+	return head, target, true, nil
+}
+
+// taggedBlock returns the number of the block tag ("safe", "finalized")
+// refers to. ok is false if the chain has no such block yet, as on a
+// freshly started devnet.
+func taggedBlock(ctx context.Context, rpc Caller, tag string) (n uint64, ok bool, err error) {
+	res, err := rpc.CallContext(ctx, "eth_getBlockByNumber", fmt.Sprintf(`[%q,false]`, tag))
+	if err != nil {
+		return 0, false, err
+	}
+	var b *struct {
+		Number string `json:"number"`
+	}
+	if err := json.Unmarshal([]byte(res), &b); err != nil {
+		return 0, false, fmt.Errorf("chainindex: eth_getBlockByNumber result: %w", err)
+	}
+	if b == nil || b.Number == "" {
+		return 0, false, nil
+	}
+	n, err = hexUint(b.Number)
+	return n, err == nil, err
+}
@@ -42,6 +42,36 @@ pub trait CheckpointStore: Send + Sync {
 
     /// Delete a checkpoint (e.g. when resetting an indexer).
     async fn delete(&self, chain_id: &str, indexer_id: &str) -> Result<(), IndexerError>;
+
+    /// Every stored checkpoint, ordered by chain then indexer. Stores that
+    /// cannot enumerate their keys return an error.
+    async fn list(&self) -> Result<Vec<Checkpoint>, IndexerError> {
+        Err(IndexerError::Other("checkpoint store does not support listing".into()))
+    }
+
+    /// Save `checkpoint` only if the stored one is still at `expected`
+    /// (`None`: only if none is stored), so writers sharing a position
+    /// cannot overwrite each other's progress. A lost race returns
+    /// `IndexerError::CheckpointMismatch` with the block actually stored.
+    ///
+    /// The default loads and compares before saving, which is not atomic;
+    /// stores shared between processes override it.
+    async fn compare_and_save(
+        &self,
+        checkpoint: Checkpoint,
+        expected: Option<u64>,
+    ) -> Result<(), IndexerError> {
+        let current = self
+            .load(&checkpoint.chain_id, &checkpoint.indexer_id)
+            .await?
+            .map(|c| c.block_number);
+        if current != expected {
+            return Err(IndexerError::CheckpointMismatch {
+                block_number: current.unwrap_or(0),
+            });
+        }
+        self.save(checkpoint).await
+    }
 }
 
 /// Manages checkpoint reads/writes for an indexer.
@@ -158,6 +188,29 @@ impl CheckpointStore for MemoryCheckpointStore {
             .remove(&Self::key(chain_id, indexer_id));
         Ok(())
     }
+
+    async fn list(&self) -> Result<Vec<Checkpoint>, IndexerError> {
+        let mut out: Vec<Checkpoint> = self.data.lock().unwrap().values().cloned().collect();
+        out.sort_by(|a, b| (&a.chain_id, &a.indexer_id).cmp(&(&b.chain_id, &b.indexer_id)));
+        Ok(out)
+    }
+
+    async fn compare_and_save(
+        &self,
+        checkpoint: Checkpoint,
+        expected: Option<u64>,
+    ) -> Result<(), IndexerError> {
+        let key = Self::key(&checkpoint.chain_id, &checkpoint.indexer_id);
+        let mut data = self.data.lock().unwrap();
+        let current = data.get(&key).map(|c| c.block_number);
+        if current != expected {
+            return Err(IndexerError::CheckpointMismatch {
+                block_number: current.unwrap_or(0),
+            });
+        }
+        data.insert(key, checkpoint);
+        Ok(())
+    }
 }
 
 #[cfg(test)]
@@ -0,0 +1,228 @@
+//! File checkpoint backend for ChainIndex.
+//!
+//! One JSON file per (chain, indexer) in a directory, for single-node
+//! deployments that do not want a database. Every save writes a temporary
+//! file, fsyncs it, renames it over the old one and fsyncs the directory,
+//! so a crash at any point leaves either the old or the new checkpoint,
+//! never a torn one.
+//!
+//! # Usage
+//! ```rust,no_run
+//! use chainindex_storage::file::FileCheckpointStore;
+//!
+//! # fn example() -> Result<(), Box<dyn std::error::Error>> {
+//! let store = FileCheckpointStore::open("/var/lib/indexer")?;
+//! # Ok(())
+//! # }
+//! ```
+
+use async_trait::async_trait;
+use std::fs;
+use std::io::{ErrorKind, Write};
+use std::path::{Path, PathBuf};
+use std::sync::atomic::{AtomicU64, Ordering};
+use std::sync::Mutex;
+
+use chainindex_core::checkpoint::{Checkpoint, CheckpointStore};
+use chainindex_core::error::IndexerError;
+
+/// Directory of checkpoint files, one per chain and indexer.
+pub struct FileCheckpointStore {
+    dir: PathBuf,
+    /// Serializes writers in this process, so compare-and-save is atomic
+    /// here. Separate processes must not share a directory.
+    lock: Mutex<()>,
+    tmp_seq: AtomicU64,
+}
+
+fn storage_err(e: impl std::fmt::Display) -> IndexerError {
+    IndexerError::Storage(e.to_string())
+}
+
+impl FileCheckpointStore {
+    /// Open `dir`, creating it if missing.
+    pub fn open(dir: impl AsRef<Path>) -> Result<Self, IndexerError> {
+        let dir = dir.as_ref().to_path_buf();
+        fs::create_dir_all(&dir).map_err(|e| storage_err(format!("{}: {e}", dir.display())))?;
+        Ok(Self { dir, lock: Mutex::new(()), tmp_seq: AtomicU64::new(0) })
+    }
+
+    /// File for a chain and indexer. IDs are escaped so any string (shard
+    /// keys contain `/`) maps to a distinct, safe file name.
+    fn path(&self, chain_id: &str, indexer_id: &str) -> PathBuf {
+        self.dir.join(format!("{}.{}.json", escape(chain_id), escape(indexer_id)))
+    }
+
+    fn read(&self, chain_id: &str, indexer_id: &str) -> Result<Option<Checkpoint>, IndexerError> {
+        let path = self.path(chain_id, indexer_id);
+        match fs::read(&path) {
+            Err(e) if e.kind() == ErrorKind::NotFound => Ok(None),
+            Err(e) => Err(storage_err(format!("{}: {e}", path.display()))),
+            Ok(data) => serde_json::from_slice(&data)
+                .map(Some)
+                .map_err(|e| storage_err(format!("{}: {e}", path.display()))),
+        }
+    }
+
+    fn write(&self, checkpoint: &Checkpoint) -> Result<(), IndexerError> {
+        let path = self.path(&checkpoint.chain_id, &checkpoint.indexer_id);
+        let data = serde_json::to_vec(checkpoint).map_err(storage_err)?;
+        let tmp = self.dir.join(format!(
+            ".{}.tmp-{}-{}",
+            path.file_name().unwrap().to_string_lossy(),
+            std::process::id(),
+            self.tmp_seq.fetch_add(1, Ordering::Relaxed),
+        ));
+
+        let result = (|| {
+            let mut f = fs::File::create(&tmp)?;
+            f.write_all(&data)?;
+            f.sync_all()?;
+            fs::rename(&tmp, &path)?;
+            sync_dir(&self.dir)
+        })();
+        if let Err(e) = result {
+            let _ = fs::remove_file(&tmp);
+            return Err(storage_err(format!("{}: {e}", path.display())));
+        }
+        Ok(())
+    }
+}
+
+/// Make a rename in `dir` durable.
+#[cfg(unix)]
+fn sync_dir(dir: &Path) -> std::io::Result<()> {
+    fs::File::open(dir)?.sync_all()
+}
+
+/// Directories cannot be opened for sync on Windows, where rename
+/// durability is the filesystem's business.
+#[cfg(not(unix))]
+fn sync_dir(_dir: &Path) -> std::io::Result<()> {
+    Ok(())
+}
+
+/// Percent-escape everything but ASCII alphanumerics, `-` and `_`.
+fn escape(id: &str) -> String {
+    let mut out = String::with_capacity(id.len());
+    for b in id.bytes() {
+        if b.is_ascii_alphanumeric() || b == b'-' || b == b'_' {
+            out.push(b as char);
+        } else {
+            out.push_str(&format!("%{b:02X}"));
+        }
+    }
+    out
+}
+
+#[async_trait]
+impl CheckpointStore for FileCheckpointStore {
+    async fn load(
+        &self,
+        chain_id: &str,
+        indexer_id: &str,
+    ) -> Result<Option<Checkpoint>, IndexerError> {
+        self.read(chain_id, indexer_id)
+    }
+
+    async fn save(&self, checkpoint: Checkpoint) -> Result<(), IndexerError> {
+        let _guard = self.lock.lock().unwrap();
+        self.write(&checkpoint)
+    }
+
+    async fn delete(&self, chain_id: &str, indexer_id: &str) -> Result<(), IndexerError> {
+        let _guard = self.lock.lock().unwrap();
+        match fs::remove_file(self.path(chain_id, indexer_id)) {
+            Err(e) if e.kind() != ErrorKind::NotFound => Err(storage_err(e)),
+            _ => sync_dir(&self.dir).map_err(storage_err),
+        }
+    }
+
+    async fn list(&self) -> Result<Vec<Checkpoint>, IndexerError> {
+        let mut out = Vec::new();
+        for entry in fs::read_dir(&self.dir).map_err(storage_err)? {
+            let path = entry.map_err(storage_err)?.path();
+            let name = path.file_name().unwrap_or_default().to_string_lossy();
+            if name.starts_with('.') || !name.ends_with(".json") {
+                continue;
+            }
+            let data = fs::read(&path).map_err(|e| storage_err(format!("{}: {e}", path.display())))?;
+            let cp: Checkpoint = serde_json::from_slice(&data)
+                .map_err(|e| storage_err(format!("{}: {e}", path.display())))?;
+            out.push(cp);
+        }
+        out.sort_by(|a, b| (&a.chain_id, &a.indexer_id).cmp(&(&b.chain_id, &b.indexer_id)));
+        Ok(out)
+    }
+
+    async fn compare_and_save(
+        &self,
+        checkpoint: Checkpoint,
+        expected: Option<u64>,
+    ) -> Result<(), IndexerError> {
+        let _guard = self.lock.lock().unwrap();
+        let current = self
+            .read(&checkpoint.chain_id, &checkpoint.indexer_id)?
+            .map(|c| c.block_number);
+        if current != expected {
+            return Err(IndexerError::CheckpointMismatch {
+                block_number: current.unwrap_or(0),
+            });
+        }
+        self.write(&checkpoint)
+    }
+}
+
+// ─── Tests ───────────────────────────────────────────────────────────────────
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn checkpoint(indexer: &str, block: u64) -> Checkpoint {
+        Checkpoint {
+            chain_id: "ethereum".into(),
+            indexer_id: indexer.into(),
+            block_number: block,
+            block_hash: format!("0x{block:064x}"),
+            updated_at: 1_700_000_000,
+        }
+    }
+
+    fn temp_dir(name: &str) -> PathBuf {
+        let dir = std::env::temp_dir().join(format!("chainindex-file-{name}-{}", std::process::id()));
+        let _ = fs::remove_dir_all(&dir);
+        dir
+    }
+
+    #[tokio::test]
+    async fn roundtrip_survives_reopen() {
+        let dir = temp_dir("roundtrip");
+        let store = FileCheckpointStore::open(&dir).unwrap();
+        store.save(checkpoint("uniswap/shard-0-of-2", 100)).await.unwrap();
+        store.save(checkpoint("uniswap/shard-0-of-2", 200)).await.unwrap();
+        drop(store);
+
+        let store = FileCheckpointStore::open(&dir).unwrap();
+        let cp = store.load("ethereum", "uniswap/shard-0-of-2").await.unwrap().unwrap();
+        assert_eq!(cp.block_number, 200);
+        assert!(store.load("ethereum", "uniswap").await.unwrap().is_none());
+
+        let leftovers = fs::read_dir(&dir).unwrap().filter(|e| {
+            e.as_ref().unwrap().file_name().to_string_lossy().contains(".tmp-")
+        });
+        assert_eq!(leftovers.count(), 0);
+        fs::remove_dir_all(&dir).unwrap();
+    }
+
+    #[tokio::test]
+    async fn compare_and_save_rejects_stale_writer() {
+        let dir = temp_dir("cas");
+        let store = FileCheckpointStore::open(&dir).unwrap();
+        store.compare_and_save(checkpoint("idx", 10), None).await.unwrap();
+        store.compare_and_save(checkpoint("idx", 20), Some(10)).await.unwrap();
+        let err = store.compare_and_save(checkpoint("idx", 30), Some(10)).await.unwrap_err();
+        assert!(matches!(err, IndexerError::CheckpointMismatch { block_number: 20 }));
+        fs::remove_dir_all(&dir).unwrap();
+    }
+}
@@ -2,11 +2,13 @@
 //!
 //! Backends:
 //! - [`memory`] — in-memory (dev/testing, no persistence)
+//! - [`file`] — checkpoint files with atomic replace (single node, no database)
 //! - [`sqlite`] — SQLite via `sqlx` (embedded, single-file persistence)
 //! - [`postgres`] — PostgreSQL via `sqlx` (production, high-throughput)
 //! - [`rocksdb`] — RocksDB-style KV store (BTreeMap simulation, swap in
 //!   native RocksDB later without changing call sites)
 
+pub mod file;
 pub mod memory;
 
 #[cfg(feature = "sqlite")]
@@ -17,6 +19,7 @@ pub mod postgres;
 
 pub mod rocksdb;
 
+pub use file::FileCheckpointStore;
 pub use memory::InMemoryStorage;
 
 #[cfg(feature = "sqlite")]
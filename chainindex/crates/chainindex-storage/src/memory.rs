@@ -92,6 +92,12 @@ impl CheckpointStore for InMemoryStorage {
         self.checkpoints.lock().unwrap().remove(&key);
         Ok(())
     }
+
+    async fn list(&self) -> Result<Vec<Checkpoint>, IndexerError> {
+        let mut out: Vec<Checkpoint> = self.checkpoints.lock().unwrap().values().cloned().collect();
+        out.sort_by(|a, b| (&a.chain_id, &a.indexer_id).cmp(&(&b.chain_id, &b.indexer_id)));
+        Ok(out)
+    }
 }
 
 #[cfg(test)]
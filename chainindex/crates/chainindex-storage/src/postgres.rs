@@ -503,6 +503,85 @@ impl CheckpointStore for PostgresStorage {
         .map_err(|e| IndexerError::Storage(e.to_string()))?;
         Ok(())
     }
+
+    async fn list(&self) -> Result<Vec<Checkpoint>, IndexerError> {
+        let rows = sqlx::query(
+            "SELECT chain_id, indexer_id, block_number, block_hash, updated_at
+             FROM chainindex_checkpoints
+             ORDER BY chain_id, indexer_id",
+        )
+        .fetch_all(&self.pool)
+        .await
+        .map_err(|e| IndexerError::Storage(e.to_string()))?;
+
+        Ok(rows
+            .into_iter()
+            .map(|r| Checkpoint {
+                chain_id: r.get::<String, _>("chain_id"),
+                indexer_id: r.get::<String, _>("indexer_id"),
+                block_number: r.get::<i64, _>("block_number") as u64,
+                block_hash: r.get::<String, _>("block_hash"),
+                updated_at: r.get::<i64, _>("updated_at"),
+            })
+            .collect())
+    }
+
+    /// Optimistic concurrency in one statement: the row is only written if
+    /// its `block_number` is still `expected` (or, for `None`, if there is
+    /// no row), so services sharing a position never lose an update.
+    async fn compare_and_save(
+        &self,
+        checkpoint: Checkpoint,
+        expected: Option<u64>,
+    ) -> Result<(), IndexerError> {
+        let now = std::time::SystemTime::now()
+            .duration_since(std::time::UNIX_EPOCH)
+            .unwrap_or_default()
+            .as_secs() as i64;
+
+        let query = match expected {
+            None => sqlx::query(
+                "INSERT INTO chainindex_checkpoints
+                    (chain_id, indexer_id, block_number, block_hash, updated_at)
+                 VALUES ($1, $2, $3, $4, $5)
+                 ON CONFLICT (chain_id, indexer_id) DO NOTHING",
+            ),
+            Some(_) => sqlx::query(
+                "UPDATE chainindex_checkpoints
+                 SET block_number = $3, block_hash = $4, updated_at = $5
+                 WHERE chain_id = $1 AND indexer_id = $2 AND block_number = $6",
+            ),
+        };
+        let query = query
+            .bind(&checkpoint.chain_id)
+            .bind(&checkpoint.indexer_id)
+            .bind(checkpoint.block_number as i64)
+            .bind(&checkpoint.block_hash)
+            .bind(now);
+        let query = match expected {
+            None => query,
+            Some(prev) => query.bind(prev as i64),
+        };
+        let result = query
+            .execute(&self.pool)
+            .await
+            .map_err(|e| IndexerError::Storage(e.to_string()))?;
+
+        if result.rows_affected() == 0 {
+            let current = self
+                .load(&checkpoint.chain_id, &checkpoint.indexer_id)
+                .await?
+                .map(|c| c.block_number);
+            return Err(IndexerError::CheckpointMismatch {
+                block_number: current.unwrap_or(0),
+            });
+        }
+        debug!(
+            "checkpoint advanced: chain={} indexer={} block={}",
+            checkpoint.chain_id, checkpoint.indexer_id, checkpoint.block_number
+        );
+        Ok(())
+    }
 }
 
 #[cfg(test)]
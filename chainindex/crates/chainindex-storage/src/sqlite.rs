@@ -328,6 +328,71 @@ impl CheckpointStore for SqliteStorage {
 
         Ok(())
     }
+
+    async fn list(&self) -> Result<Vec<Checkpoint>, IndexerError> {
+        let rows = sqlx::query(
+            "SELECT chain_id, indexer_id, block_number, block_hash, updated_at
+             FROM checkpoints ORDER BY chain_id, indexer_id",
+        )
+        .fetch_all(&self.pool)
+        .await
+        .map_err(|e| IndexerError::Storage(e.to_string()))?;
+
+        Ok(rows
+            .into_iter()
+            .map(|r| Checkpoint {
+                chain_id: r.get("chain_id"),
+                indexer_id: r.get("indexer_id"),
+                block_number: r.get::<i64, _>("block_number") as u64,
+                block_hash: r.get("block_hash"),
+                updated_at: r.get("updated_at"),
+            })
+            .collect())
+    }
+
+    async fn compare_and_save(
+        &self,
+        checkpoint: Checkpoint,
+        expected: Option<u64>,
+    ) -> Result<(), IndexerError> {
+        let query = match expected {
+            None => sqlx::query(
+                "INSERT OR IGNORE INTO checkpoints
+                 (chain_id, indexer_id, block_number, block_hash, updated_at)
+                 VALUES (?, ?, ?, ?, ?)",
+            )
+            .bind(&checkpoint.chain_id)
+            .bind(&checkpoint.indexer_id)
+            .bind(checkpoint.block_number as i64)
+            .bind(&checkpoint.block_hash)
+            .bind(checkpoint.updated_at),
+            Some(prev) => sqlx::query(
+                "UPDATE checkpoints SET block_number = ?, block_hash = ?, updated_at = ?
+                 WHERE chain_id = ? AND indexer_id = ? AND block_number = ?",
+            )
+            .bind(checkpoint.block_number as i64)
+            .bind(&checkpoint.block_hash)
+            .bind(checkpoint.updated_at)
+            .bind(&checkpoint.chain_id)
+            .bind(&checkpoint.indexer_id)
+            .bind(prev as i64),
+        };
+        let result = query
+            .execute(&self.pool)
+            .await
+            .map_err(|e| IndexerError::Storage(e.to_string()))?;
+
+        if result.rows_affected() == 0 {
+            let current = self
+                .load(&checkpoint.chain_id, &checkpoint.indexer_id)
+                .await?
+                .map(|c| c.block_number);
+            return Err(IndexerError::CheckpointMismatch {
+                block_number: current.unwrap_or(0),
+            });
+        }
+        Ok(())
+    }
 }
 
 // ─── Tests ───────────────────────────────────────────────────────────────────
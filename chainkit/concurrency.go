@@ -0,0 +1,106 @@
+// Package chainkit holds Go helpers shared across the chainrpc, chaincodec,
+// chainerrors and chainindex bindings. It has no native dependencies.
+package chainkit
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrInvalidRange is returned by ForEachBlock when from > to.
+var ErrInvalidRange = errors.New("chainkit: invalid block range")
+
+// MapConcurrently calls fn for every item with at most concurrency calls in
+// flight and returns the results in input order.
+//
+// The first error cancels the context passed to the remaining calls, no new
+// calls are started, and that error is returned once in-flight calls have
+// finished. A concurrency below 1 means one.
+func MapConcurrently[T, R any](ctx context.Context, items []T, concurrency int, fn func(ctx context.Context, item T) (R, error)) ([]R, error) {
+	out := make([]R, len(items))
+	err := run(ctx, len(items), concurrency, func(ctx context.Context, i int) error {
+		r, err := fn(ctx, items[i])
+		if err != nil {
+			return err
+		}
+		out[i] = r
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ForEachBlock calls fn for every block in [from, to] with at most
+// concurrency calls in flight, with the same cancellation and first-error
+// semantics as MapConcurrently. Blocks are started in ascending order but
+// may finish in any order.
+func ForEachBlock(ctx context.Context, from, to uint64, concurrency int, fn func(ctx context.Context, block uint64) error) error {
+	if from > to {
+		return ErrInvalidRange
+	}
+	n := to - from + 1
+	if n > uint64(int(^uint(0)>>1)) {
+		return ErrInvalidRange
+	}
+	return run(ctx, int(n), concurrency, func(ctx context.Context, i int) error {
+		return fn(ctx, from+uint64(i))
+	})
+}
+
+// run executes fn(0..n-1) on a bounded set of workers.
+func run(ctx context.Context, n, concurrency int, fn func(ctx context.Context, i int) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > n {
+		concurrency = n
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		next     = make(chan int)
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if err := fn(ctx, i); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+	fed := 0
+feed:
+	for ; fed < n; fed++ {
+		select {
+		case next <- fed:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	if fed < n {
+		// The parent context was cancelled before every item started.
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,540 @@
+package chainkit
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CheckStatus is the outcome of one Doctor check.
+type CheckStatus string
+
+const (
+	CheckOK      CheckStatus = "ok"
+	CheckWarn    CheckStatus = "warn"
+	CheckFail    CheckStatus = "fail"
+	CheckSkipped CheckStatus = "skipped"
+)
+
+// Check is one diagnostic in a DoctorReport.
+type Check struct {
+	Name   string      `json:"name"`
+	Status CheckStatus `json:"status"`
+	Detail string      `json:"detail,omitempty"`
+	// Fix says what to do about a warning or failure.
+	Fix      string        `json:"fix,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// DoctorReport is the result of Doctor.
+type DoctorReport struct {
+	GoVersion string  `json:"go_version"`
+	Platform  string  `json:"platform"`
+	Checks    []Check `json:"checks"`
+	// OK is false if any check failed; warnings do not count.
+	OK bool `json:"ok"`
+}
+
+// Failed returns the checks that failed.
+func (r *DoctorReport) Failed() []Check {
+	var out []Check
+	for _, c := range r.Checks {
+		if c.Status == CheckFail {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// String renders the report one check per line, with fixes indented
+// beneath, for printing from a CLI or at start-up.
+func (r *DoctorReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "chainkit doctor (%s, %s)\n", r.GoVersion, r.Platform)
+	for _, c := range r.Checks {
+		fmt.Fprintf(&b, "  [%s] %s", c.Status, c.Name)
+		if c.Detail != "" {
+			fmt.Fprintf(&b, ": %s", c.Detail)
+		}
+		b.WriteByte('\n')
+		if c.Fix != "" {
+			fmt.Fprintf(&b, "      fix: %s\n", c.Fix)
+		}
+	}
+	return b.String()
+}
+
+// nativeLibraries are the libraries the Go bindings link, by module.
+var nativeLibraries = []string{"chainrpc", "chaincodec", "chainerrors", "chainindex"}
+
+// DoctorOption configures Doctor.
+type DoctorOption func(*doctorConfig)
+
+type doctorConfig struct {
+	versions    map[string]func() string
+	libraryDirs []string
+	rpcURLs     []string
+	schemaDir   string
+	store       string
+	sqlDriver   string
+	timeout     time.Duration
+}
+
+// WithNativeVersion registers a binding's Version function, e.g.
+// WithNativeVersion("chainrpc", chainrpc.Version). A program that gets as
+// far as calling it has the library linked and loaded, so Doctor reports
+// the version instead of searching for the file. chainkit itself has no
+// native dependencies and cannot call the bindings directly.
+func WithNativeVersion(module string, version func() string) DoctorOption {
+	return func(c *doctorConfig) { c.versions[module] = version }
+}
+
+// WithLibraryDirs adds directories to search for the native libraries,
+// before the dynamic loader's path and the usual system directories.
+func WithLibraryDirs(dirs ...string) DoctorOption {
+	return func(c *doctorConfig) { c.libraryDirs = append(c.libraryDirs, dirs...) }
+}
+
+// WithRPC checks that each endpoint answers eth_chainId.
+func WithRPC(urls ...string) DoctorOption {
+	return func(c *doctorConfig) { c.rpcURLs = append(c.rpcURLs, urls...) }
+}
+
+// WithSchemaDir checks a directory of .csdl schema files.
+func WithSchemaDir(dir string) DoctorOption {
+	return func(c *doctorConfig) { c.schemaDir = dir }
+}
+
+// WithCheckpointStore checks that the checkpoint store at url, as passed
+// to chainindex.OpenCheckpointStore, can be written. A Postgres store is
+// only checked for reachability unless WithSQLDriver is also given.
+func WithCheckpointStore(url string) DoctorOption {
+	return func(c *doctorConfig) { c.store = url }
+}
+
+// WithSQLDriver names the database/sql driver the program registers for
+// Postgres, e.g. "pgx" after importing github.com/jackc/pgx/v5/stdlib.
+// With it the checkpoint store check logs in and creates and fills a
+// table in a transaction it rolls back, as the store does on open.
+// chainkit itself links no database driver.
+func WithSQLDriver(name string) DoctorOption {
+	return func(c *doctorConfig) { c.sqlDriver = name }
+}
+
+// WithCheckTimeout bounds each network check; the default is 5s.
+func WithCheckTimeout(d time.Duration) DoctorOption {
+	return func(c *doctorConfig) { c.timeout = d }
+}
+
+// Doctor diagnoses the most common setup failures: a build without cgo,
+// native libraries missing or at mismatched versions, unreachable RPC
+// endpoints, a bad schema directory and an unwritable checkpoint store.
+// Each failure comes with a suggested fix. Checks whose inputs were not
+// given are skipped; Doctor itself never fails.
+func Doctor(ctx context.Context, opts ...DoctorOption) *DoctorReport {
+	cfg := &doctorConfig{versions: map[string]func() string{}, timeout: 5 * time.Second}
+	for _, o := range opts {
+		o(cfg)
+	}
+	r := &DoctorReport{GoVersion: runtime.Version(), Platform: runtime.GOOS + "/" + runtime.GOARCH}
+	run := func(name string, fn func() Check) {
+		start := time.Now()
+		c := fn()
+		c.Name, c.Duration = name, time.Since(start)
+		r.Checks = append(r.Checks, c)
+	}
+
+	run("cgo", checkCgo)
+	for _, m := range nativeLibraries {
+		m := m
+		run("native:"+m, func() Check { return cfg.checkLibrary(m) })
+	}
+	if len(cfg.versions) > 1 {
+		run("native:versions", cfg.checkVersions)
+	}
+	for _, u := range cfg.rpcURLs {
+		u := u
+		run("rpc:"+redactURL(u), func() Check { return cfg.checkRPC(ctx, u) })
+	}
+	run("schemas", cfg.checkSchemas)
+	run("checkpoint_store", func() Check { return cfg.checkStore(ctx) })
+
+	r.OK = len(r.Failed()) == 0
+	return r
+}
+
+func checkCgo() Check {
+	enabled := ""
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "CGO_ENABLED" {
+				enabled = s.Value
+			}
+		}
+	}
+	switch enabled {
+	case "1":
+		return Check{Status: CheckOK, Detail: "built with cgo"}
+	case "0":
+		return Check{
+			Status: CheckFail,
+			Detail: "built with CGO_ENABLED=0; the chainrpc, chaincodec, chainerrors and chainindex bindings need cgo",
+			Fix:    "rebuild with CGO_ENABLED=1 and a C toolchain (gcc or clang) on PATH; cross-compiling also needs CC for the target",
+		}
+	}
+	return Check{Status: CheckWarn, Detail: "build settings unavailable (not built with module support)"}
+}
+
+// libraryFile is the platform's file name for a native library.
+func libraryFile(module string) string {
+	name := module + "_ffi"
+	switch runtime.GOOS {
+	case "darwin":
+		return "lib" + name + ".dylib"
+	case "windows":
+		return name + ".dll"
+	}
+	return "lib" + name + ".so"
+}
+
+// searchDirs is where the dynamic loader looks, after any configured dirs.
+func (c *doctorConfig) searchDirs() []string {
+	dirs := append([]string(nil), c.libraryDirs...)
+	for _, env := range []string{"LD_LIBRARY_PATH", "DYLD_LIBRARY_PATH", "DYLD_FALLBACK_LIBRARY_PATH"} {
+		dirs = append(dirs, filepath.SplitList(os.Getenv(env))...)
+	}
+	if runtime.GOOS == "windows" {
+		dirs = append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+	}
+	if exe, err := os.Executable(); err == nil {
+		dirs = append(dirs, filepath.Dir(exe))
+	}
+	return append(dirs, ".", "/usr/local/lib", "/usr/lib", "/opt/homebrew/lib")
+}
+
+func (c *doctorConfig) checkLibrary(module string) Check {
+	if v, ok := c.versions[module]; ok {
+		version, err := callVersion(v)
+		if err != nil {
+			return Check{
+				Status: CheckFail,
+				Detail: err.Error(),
+				Fix:    fmt.Sprintf("rebuild the native library: cargo build --release -p %s-ffi", module),
+			}
+		}
+		return Check{Status: CheckOK, Detail: "loaded, version " + version}
+	}
+	file := libraryFile(module)
+	for _, dir := range c.searchDirs() {
+		if dir == "" {
+			continue
+		}
+		p := filepath.Join(dir, file)
+		if st, err := os.Stat(p); err == nil && !st.IsDir() {
+			return Check{Status: CheckOK, Detail: "found " + p}
+		}
+	}
+	return Check{
+		Status: CheckWarn,
+		Detail: file + " not found (fine if this program does not use " + module + " or links it statically)",
+		Fix: fmt.Sprintf("cd %s && cargo build --release -p %s-ffi, then copy target/release/%s next to the Go bindings or add its directory to %s",
+			module, module, file, loaderPathEnv()),
+	}
+}
+
+// callVersion calls a Version function, turning a panic into an error.
+func callVersion(v func() string) (version string, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("version call panicked: %v", p)
+		}
+	}()
+	version = v()
+	if version == "" {
+		return "", errors.New("library returned an empty version")
+	}
+	return version, nil
+}
+
+func loaderPathEnv() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "DYLD_LIBRARY_PATH"
+	case "windows":
+		return "PATH"
+	}
+	return "LD_LIBRARY_PATH"
+}
+
+func (c *doctorConfig) checkVersions() Check {
+	seen := map[string][]string{}
+	for m, v := range c.versions {
+		if version, err := callVersion(v); err == nil {
+			seen[version] = append(seen[version], m)
+		}
+	}
+	if len(seen) <= 1 {
+		return Check{Status: CheckOK, Detail: "all native libraries at the same version"}
+	}
+	var parts []string
+	for v, ms := range seen {
+		sort.Strings(ms)
+		parts = append(parts, v+" ("+strings.Join(ms, ", ")+")")
+	}
+	sort.Strings(parts)
+	return Check{
+		Status: CheckWarn,
+		Detail: "native libraries at different versions: " + strings.Join(parts, "; "),
+		Fix:    "rebuild all native libraries from the same checkout so their FFI contracts match",
+	}
+}
+
+func (c *doctorConfig) checkRPC(ctx context.Context, endpoint string) Check {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return Check{Status: CheckFail, Detail: "not a URL", Fix: "use a full URL such as https://eth.example.com/v2/KEY"}
+	}
+	switch u.Scheme {
+	case "ws", "wss":
+		return dialCheck(ctx, u, "WebSocket endpoint")
+	case "http", "https":
+	default:
+		return Check{Status: CheckFail, Detail: "unsupported scheme " + u.Scheme, Fix: "use http, https, ws or wss"}
+	}
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(body))
+	if err != nil {
+		return Check{Status: CheckFail, Detail: err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Check{Status: CheckFail, Detail: redactURL(err.Error()), Fix: netFix(err)}
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return Check{Status: CheckFail, Detail: resp.Status, Fix: "the provider rejected the request: check the API key in the URL or headers, and any IP or origin allow-list"}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return Check{Status: CheckWarn, Detail: resp.Status, Fix: "the endpoint is rate limiting this client; lower request rates or upgrade the plan"}
+	case resp.StatusCode != http.StatusOK:
+		return Check{Status: CheckFail, Detail: resp.Status, Fix: "check the URL path; many providers need a network or key segment, e.g. /v2/KEY"}
+	}
+	var out struct {
+		Result string `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(raw), &out); err != nil {
+		return Check{Status: CheckFail, Detail: "response is not JSON-RPC", Fix: "the URL answers HTTP but is not a JSON-RPC endpoint"}
+	}
+	if out.Error != nil {
+		return Check{Status: CheckFail, Detail: fmt.Sprintf("eth_chainId: %d %s", out.Error.Code, out.Error.Message), Fix: "the endpoint is not an EVM JSON-RPC node, or blocks eth_chainId"}
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(out.Result, "0x"), 16, 64)
+	if err != nil {
+		return Check{Status: CheckFail, Detail: "eth_chainId returned " + strconv.Quote(out.Result)}
+	}
+	return Check{Status: CheckOK, Detail: fmt.Sprintf("chain id %d", id)}
+}
+
+// dialCheck checks that u's host accepts TCP connections.
+func dialCheck(ctx context.Context, u *url.URL, what string) Check {
+	host := u.Host
+	if u.Port() == "" {
+		port := map[string]string{"ws": "80", "http": "80", "wss": "443", "https": "443", "postgres": "5432", "postgresql": "5432"}[u.Scheme]
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return Check{Status: CheckFail, Detail: err.Error(), Fix: netFix(err)}
+	}
+	conn.Close()
+	return Check{Status: CheckOK, Detail: what + " reachable at " + host + " (protocol not verified)"}
+}
+
+// netFix suggests a fix for a network error.
+func netFix(err error) string {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	switch {
+	case errors.As(err, &dnsErr):
+		return "the host name does not resolve: check for typos and DNS from this machine"
+	case errors.Is(err, context.DeadlineExceeded) || os.IsTimeout(err):
+		return "no answer in time: check firewalls, proxies (HTTPS_PROXY) and that the host is up"
+	case strings.Contains(err.Error(), "certificate"):
+		return "TLS verification failed: install the provider's CA or fix the system clock"
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return "connection refused or unreachable: check the host, port and that the node is running"
+	}
+	return "check the URL and network access from this machine"
+}
+
+// apiKeyRe matches credentials in URLs: keys in a /v2/ path segment or a
+// key query parameter, and passwords in user:pass@.
+var apiKeyRe = regexp.MustCompile(`(/v\d+/|[?&](?:api[_-]?key|key|token)=|://[^:/@\s]+:)[^/?&@\s"]{6,}`)
+
+// redactURL hides what looks like an API key in the path or query of s.
+func redactURL(s string) string {
+	return apiKeyRe.ReplaceAllString(s, "$1***")
+}
+
+func (c *doctorConfig) checkSchemas() Check {
+	if c.schemaDir == "" {
+		return Check{Status: CheckSkipped, Detail: "no schema directory given"}
+	}
+	st, err := os.Stat(c.schemaDir)
+	if err != nil {
+		return Check{Status: CheckFail, Detail: err.Error(), Fix: "point at the directory of .csdl files, e.g. chaincodec/schemas"}
+	}
+	if !st.IsDir() {
+		return Check{Status: CheckFail, Detail: c.schemaDir + " is a file", Fix: "pass the directory containing it"}
+	}
+	var files, schemas int
+	var bad []string
+	err = filepath.WalkDir(c.schemaDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(p) != ".csdl" {
+			return err
+		}
+		files++
+		data, err := os.ReadFile(p)
+		if err != nil {
+			bad = append(bad, p+": "+err.Error())
+			return nil
+		}
+		n := 0
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(line, "schema ") && strings.HasSuffix(strings.TrimSpace(line), ":") {
+				n++
+			}
+		}
+		if n == 0 {
+			bad = append(bad, p+": no \"schema Name:\" block")
+		}
+		schemas += n
+		return nil
+	})
+	if err != nil {
+		return Check{Status: CheckFail, Detail: err.Error()}
+	}
+	switch {
+	case files == 0:
+		return Check{Status: CheckFail, Detail: "no .csdl files under " + c.schemaDir, Fix: "schema files must have the .csdl extension"}
+	case len(bad) > 0:
+		return Check{
+			Status: CheckWarn,
+			Detail: fmt.Sprintf("%d of %d files have problems: %s", len(bad), files, strings.Join(bad, "; ")),
+			Fix:    "each schema starts with a line \"schema Name:\"; run chaincodec.CountSchemas on the directory for parse errors",
+		}
+	}
+	return Check{Status: CheckOK, Detail: fmt.Sprintf("%d schemas in %d files", schemas, files)}
+}
+
+func (c *doctorConfig) checkStore(ctx context.Context) Check {
+	switch {
+	case c.store == "":
+		return Check{Status: CheckSkipped, Detail: "no checkpoint store given"}
+	case c.store == "memory://" || c.store == "sqlite::memory:":
+		return Check{Status: CheckWarn, Detail: "in-memory store: checkpoints are lost on restart", Fix: "use sqlite:///path/indexer.db or postgres:// in production"}
+	}
+	u, err := url.Parse(c.store)
+	if err != nil {
+		return Check{Status: CheckFail, Detail: err.Error()}
+	}
+	switch u.Scheme {
+	case "postgres", "postgresql":
+		ctx, cancel := context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+		check := dialCheck(ctx, u, "Postgres")
+		if check.Status != CheckOK {
+			return check
+		}
+		if c.sqlDriver == "" {
+			check.Detail = strings.TrimSuffix(check.Detail, " (protocol not verified)") +
+				"; reachability only, login and write access are not checked without WithSQLDriver"
+			return check
+		}
+		return sqlWriteCheck(ctx, c.sqlDriver, c.store)
+	case "sqlite":
+		path := strings.TrimPrefix(c.store, "sqlite://")
+		if i := strings.IndexByte(path, '?'); i >= 0 {
+			path = path[:i]
+		}
+		if f, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+			f.Close()
+			return Check{Status: CheckOK, Detail: path + " is writable"}
+		} else if !os.IsNotExist(err) {
+			return Check{Status: CheckFail, Detail: err.Error(), Fix: "give the indexer's user write access to " + path}
+		}
+		return writableDir(filepath.Dir(path), "the database will be created at "+path)
+	case "file":
+		return writableDir(u.Path, "checkpoints will be written under "+u.Path)
+	}
+	return Check{Status: CheckFail, Detail: "unsupported store URL " + redactURL(c.store), Fix: "use sqlite://, postgres://, file:// or memory://"}
+}
+
+// doctorTable is the table sqlWriteCheck creates and drops again.
+const doctorTable = "chainkit_doctor_probe"
+
+// sqlWriteCheck logs in to the database at dsn and, in a transaction it
+// rolls back, creates and writes a table the way the checkpoint store
+// does on open.
+func sqlWriteCheck(ctx context.Context, driver, dsn string) Check {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return Check{Status: CheckFail, Detail: err.Error(), Fix: fmt.Sprintf("import the package that registers the %q database/sql driver", driver)}
+	}
+	defer db.Close()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return Check{Status: CheckFail, Detail: err.Error(), Fix: "check the user, password and database in the URL, and that the server's pg_hba.conf admits this host"}
+	}
+	defer tx.Rollback()
+	for _, stmt := range []string{
+		"CREATE TABLE " + doctorTable + " (id integer)",
+		"INSERT INTO " + doctorTable + " (id) VALUES (1)",
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return Check{Status: CheckFail, Detail: err.Error(), Fix: "grant the user CREATE and INSERT on the schema the store uses (usually public)"}
+		}
+	}
+	return Check{Status: CheckOK, Detail: "Postgres accepted a write (rolled back)"}
+}
+
+// writableDir checks that a file can be created in dir.
+func writableDir(dir, ok string) Check {
+	f, err := os.CreateTemp(dir, ".chainkit-doctor-*")
+	if err != nil {
+		fix := "give the indexer's user write access to " + dir
+		if os.IsNotExist(err) {
+			fix = "create " + dir + " first"
+		}
+		return Check{Status: CheckFail, Detail: err.Error(), Fix: fix}
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return Check{Status: CheckOK, Detail: ok}
+}
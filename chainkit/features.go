@@ -0,0 +1,146 @@
+package chainkit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Stability is the API stability tier of a feature.
+type Stability int
+
+const (
+	// Stable features follow semantic versioning and are always on.
+	Stable Stability = iota
+	// Beta features are on by default but may still change shape.
+	Beta
+	// Experimental features are off until enabled with EnableExperimental
+	// or the CHAINKIT_EXPERIMENTAL environment variable, and may change or
+	// disappear in any release.
+	Experimental
+	// Deprecated features still work but are scheduled for removal.
+	Deprecated
+)
+
+func (s Stability) String() string {
+	switch s {
+	case Stable:
+		return "stable"
+	case Beta:
+		return "beta"
+	case Experimental:
+		return "experimental"
+	case Deprecated:
+		return "deprecated"
+	}
+	return fmt.Sprintf("Stability(%d)", int(s))
+}
+
+// ExperimentalEnv lists experimental features to enable at start-up,
+// comma separated (e.g. CHAINKIT_EXPERIMENTAL=tracing).
+const ExperimentalEnv = "CHAINKIT_EXPERIMENTAL"
+
+// ErrUnknownFeature is returned by EnableExperimental for unregistered names.
+var ErrUnknownFeature = errors.New("chainkit: unknown feature")
+
+// FeatureError is returned by Require when a feature is not enabled.
+type FeatureError struct {
+	Name string
+}
+
+func (e *FeatureError) Error() string {
+	return fmt.Sprintf("chainkit: experimental feature %q is not enabled; call chainkit.EnableExperimental(%q) or set %s", e.Name, e.Name, ExperimentalEnv)
+}
+
+// Feature describes a gated API surface.
+type Feature struct {
+	Name        string    `json:"name"`
+	Stability   Stability `json:"stability"`
+	Description string    `json:"description"`
+	Enabled     bool      `json:"enabled"`
+}
+
+var (
+	featureMu sync.RWMutex
+	features  = make(map[string]*Feature)
+	// envEnabled holds names from ExperimentalEnv, applied when the
+	// feature registers (packages register from init, in any order).
+	envEnabled = parseFeatureList(os.Getenv(ExperimentalEnv))
+)
+
+func parseFeatureList(s string) map[string]bool {
+	out := make(map[string]bool)
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			out[name] = true
+		}
+	}
+	return out
+}
+
+// RegisterFeature declares a feature. Packages call it from init for every
+// surface they gate. Registering a name twice updates its tier and
+// description but keeps its enabled state.
+func RegisterFeature(name string, stability Stability, description string) {
+	featureMu.Lock()
+	defer featureMu.Unlock()
+	if f, ok := features[name]; ok {
+		f.Stability, f.Description = stability, description
+		return
+	}
+	features[name] = &Feature{
+		Name:        name,
+		Stability:   stability,
+		Description: description,
+		Enabled:     stability != Experimental || envEnabled[name] || envEnabled["all"],
+	}
+}
+
+// EnableExperimental opts into experimental features. All names are
+// checked before any is enabled.
+func EnableExperimental(names ...string) error {
+	featureMu.Lock()
+	defer featureMu.Unlock()
+	for _, name := range names {
+		if _, ok := features[name]; !ok {
+			return fmt.Errorf("%w %q", ErrUnknownFeature, name)
+		}
+	}
+	for _, name := range names {
+		features[name].Enabled = true
+	}
+	return nil
+}
+
+// Enabled reports whether the feature may be used. Unregistered names are
+// reported as disabled.
+func Enabled(name string) bool {
+	featureMu.RLock()
+	defer featureMu.RUnlock()
+	f, ok := features[name]
+	return ok && f.Enabled
+}
+
+// Require returns a *FeatureError unless the feature is enabled. Gated
+// entry points call it before doing any work.
+func Require(name string) error {
+	if Enabled(name) {
+		return nil
+	}
+	return &FeatureError{Name: name}
+}
+
+// Features lists registered features sorted by name.
+func Features() []Feature {
+	featureMu.RLock()
+	out := make([]Feature, 0, len(features))
+	for _, f := range features {
+		out = append(out, *f)
+	}
+	featureMu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,3 @@
+module github.com/DarshanKumar89/chainfoundry/chainkit
+
+go 1.21
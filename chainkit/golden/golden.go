@@ -0,0 +1,212 @@
+// Package golden snapshots decoded events, errors and RPC results in a
+// canonical form for golden-file tests:
+//
+//	func TestPipeline(t *testing.T) {
+//		out := runPipeline(t)
+//		golden.Assert(t, "swap_events", out, golden.RedactTimestamps(), golden.RedactURLs())
+//	}
+//
+// Run tests with CHAINKIT_UPDATE_GOLDEN=1 to write or refresh the files
+// under testdata/.
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// UpdateEnv is the environment variable that makes Assert rewrite golden
+// files instead of comparing against them.
+const UpdateEnv = "CHAINKIT_UPDATE_GOLDEN"
+
+// Redactor replaces a value at path (e.g. "$.events[0].timestamp"). It
+// returns the replacement and true, or false to keep the value.
+type Redactor func(path string, v interface{}) (interface{}, bool)
+
+// Option configures Canonical and Assert.
+type Option func(*config)
+
+type config struct {
+	redactors    []Redactor
+	lowercaseHex bool
+	dir          string
+}
+
+// Redact adds a custom redaction hook.
+func Redact(r Redactor) Option {
+	return func(c *config) { c.redactors = append(c.redactors, r) }
+}
+
+// RedactKeys replaces the values of object members with the given names,
+// at any depth, with "<redacted>".
+func RedactKeys(keys ...string) Option {
+	set := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		set[k] = true
+	}
+	return Redact(func(path string, v interface{}) (interface{}, bool) {
+		if set[lastKey(path)] {
+			return "<redacted>", true
+		}
+		return nil, false
+	})
+}
+
+var (
+	timestampRe = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?$`)
+	urlRe       = regexp.MustCompile(`^(https?|wss?|ipc)://`)
+	hexRe       = regexp.MustCompile(`^0x[0-9a-fA-F]*$`)
+)
+
+// RedactTimestamps replaces RFC 3339 style timestamp strings with
+// "<timestamp>".
+func RedactTimestamps() Option {
+	return Redact(func(_ string, v interface{}) (interface{}, bool) {
+		if s, ok := v.(string); ok && timestampRe.MatchString(s) {
+			return "<timestamp>", true
+		}
+		return nil, false
+	})
+}
+
+// RedactURLs replaces provider URLs (http, ws, ipc) with "<url>", so API
+// keys embedded in endpoints never reach golden files.
+func RedactURLs() Option {
+	return Redact(func(_ string, v interface{}) (interface{}, bool) {
+		if s, ok := v.(string); ok && urlRe.MatchString(s) {
+			return "<url>", true
+		}
+		return nil, false
+	})
+}
+
+// LowercaseHex lowercases 0x-prefixed hex strings so checksummed and
+// lowercase addresses compare equal.
+func LowercaseHex() Option {
+	return func(c *config) { c.lowercaseHex = true }
+}
+
+// Dir sets the directory golden files live in (default "testdata").
+func Dir(dir string) Option {
+	return func(c *config) { c.dir = dir }
+}
+
+// Canonical renders v as indented JSON with sorted object keys, numbers
+// kept verbatim and redactions applied. Wrap JSON strings returned by the
+// FFI bindings in json.RawMessage so they are canonicalised, not quoted.
+func Canonical(v interface{}, opts ...Option) ([]byte, error) {
+	cfg := newConfig(opts)
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var tree interface{}
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	tree = cfg.walk("$", tree)
+	// encoding/json sorts map keys, which gives the canonical order.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(tree); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{dir: "testdata"}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+func (c *config) walk(path string, v interface{}) interface{} {
+	for _, r := range c.redactors {
+		if out, ok := r(path, v); ok {
+			return out
+		}
+	}
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, val := range x {
+			x[k] = c.walk(path+"."+k, val)
+		}
+	case []interface{}:
+		for i, val := range x {
+			x[i] = c.walk(path+"["+strconv.Itoa(i)+"]", val)
+		}
+	case string:
+		if c.lowercaseHex && hexRe.MatchString(x) {
+			return strings.ToLower(x)
+		}
+	}
+	return v
+}
+
+// lastKey returns the final object key of a path, or "" for array elements.
+func lastKey(path string) string {
+	if strings.HasSuffix(path, "]") {
+		return ""
+	}
+	return path[strings.LastIndex(path, ".")+1:]
+}
+
+// Assert compares the canonical form of v with <dir>/<name>.golden, or
+// writes it when CHAINKIT_UPDATE_GOLDEN is set.
+func Assert(t testing.TB, name string, v interface{}, opts ...Option) {
+	t.Helper()
+	cfg := newConfig(opts)
+	got, err := Canonical(v, opts...)
+	if err != nil {
+		t.Fatalf("golden: canonicalise %s: %v", name, err)
+	}
+	path := filepath.Join(cfg.dir, name+".golden")
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(cfg.dir, 0o755); err != nil {
+			t.Fatalf("golden: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("golden: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("golden: %v (run with %s=1 to create it)", err, UpdateEnv)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("golden: %s differs from %s\n%s", name, path, firstDiff(want, got))
+	}
+}
+
+// firstDiff describes the first differing line between want and got.
+func firstDiff(want, got []byte) string {
+	wl := strings.Split(string(want), "\n")
+	gl := strings.Split(string(got), "\n")
+	for i := 0; i < len(wl) || i < len(gl); i++ {
+		var w, g string
+		if i < len(wl) {
+			w = wl[i]
+		}
+		if i < len(gl) {
+			g = gl[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n  want: %s\n  got:  %s", i+1, w, g)
+		}
+	}
+	return "(no line difference; check trailing bytes)"
+}
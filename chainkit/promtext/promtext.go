@@ -0,0 +1,181 @@
+// Package promtext renders metrics in the Prometheus text exposition
+// format without depending on a Prometheus client. chainrpc.Metrics and
+// chainindex.Metrics are built on it, and a Registry serves them, with any
+// other Collector, from one endpoint:
+//
+//	reg := promtext.NewRegistry()
+//	rpcMetrics.Register(reg)
+//	indexMetrics.Register(reg)
+//	http.Handle("/metrics", reg)
+//
+// To export through a prometheus/client_golang registry instead, implement
+// Registerer as a prometheus.Gatherer that parses each Collector's output
+// with expfmt.TextParser, and serve it alongside that registry with
+// prometheus.Gatherers.
+package promtext
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ContentType is the media type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Collector writes metric families in the text exposition format.
+type Collector interface {
+	WriteTo(w io.Writer) (int64, error)
+}
+
+// Registerer accepts collectors for export. Registry implements it.
+type Registerer interface {
+	Register(c Collector) error
+}
+
+// ErrAlreadyRegistered is returned when a collector is registered twice.
+var ErrAlreadyRegistered = errors.New("promtext: collector already registered")
+
+// Registry is a Collector that writes the collectors registered with it,
+// in registration order.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry { return &Registry{} }
+
+// Register adds c to the registry. c must be comparable, as pointers are.
+func (r *Registry) Register(c Collector) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, have := range r.collectors {
+		if have == c {
+			return ErrAlreadyRegistered
+		}
+	}
+	r.collectors = append(r.collectors, c)
+	return nil
+}
+
+// WriteTo writes every registered collector to w.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	cs := append([]Collector(nil), r.collectors...)
+	r.mu.Unlock()
+	var total int64
+	for _, c := range cs {
+		n, err := c.WriteTo(w)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// ServeHTTP serves the registry in the text exposition format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) { Serve(w, r) }
+
+// Serve writes c to w as a text exposition response.
+func Serve(w http.ResponseWriter, c Collector) {
+	w.Header().Set("Content-Type", ContentType)
+	c.WriteTo(w)
+}
+
+// Buckets returns a sorted copy of buckets, or of def when buckets is nil,
+// for use as histogram upper bounds.
+func Buckets(buckets, def []float64) []float64 {
+	if buckets == nil {
+		buckets = def
+	}
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	return b
+}
+
+// Histogram counts observations against fixed upper bounds. It is not
+// safe for concurrent use; callers guard it with their own lock.
+type Histogram struct {
+	bounds []float64
+	counts []uint64 // per bucket, non-cumulative
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram returns an empty histogram over bounds, which must be
+// sorted, as Buckets returns them, and are not copied.
+func NewHistogram(bounds []float64) *Histogram {
+	return &Histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+// Observe records v.
+func (h *Histogram) Observe(v float64) {
+	for i, ub := range h.bounds {
+		if v <= ub {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// Writer accumulates a text exposition. Labels are passed as alternating
+// names and values.
+type Writer struct {
+	b strings.Builder
+}
+
+// Family writes the HELP and TYPE lines that start a metric family.
+func (w *Writer) Family(name, typ, help string) {
+	fmt.Fprintf(&w.b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// Sample writes one counter or gauge sample.
+func (w *Writer) Sample(name string, v uint64, labels ...string) {
+	w.name(name, labels)
+	fmt.Fprintf(&w.b, " %d\n", v)
+}
+
+// Histogram writes h's _bucket, _sum and _count samples.
+func (w *Writer) Histogram(name string, h *Histogram, labels ...string) {
+	le := append(labels[:len(labels):len(labels)], "le", "")
+	var cum uint64
+	for i, ub := range h.bounds {
+		cum += h.counts[i]
+		le[len(le)-1] = fmt.Sprintf("%g", ub)
+		w.Sample(name+"_bucket", cum, le...)
+	}
+	le[len(le)-1] = "+Inf"
+	w.Sample(name+"_bucket", h.count, le...)
+	w.name(name+"_sum", labels)
+	fmt.Fprintf(&w.b, " %g\n", h.sum)
+	w.Sample(name+"_count", h.count, labels...)
+}
+
+func (w *Writer) name(name string, labels []string) {
+	w.b.WriteString(name)
+	if len(labels) == 0 {
+		return
+	}
+	w.b.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			w.b.WriteByte(',')
+		}
+		fmt.Fprintf(&w.b, "%s=%q", labels[i], labels[i+1])
+	}
+	w.b.WriteByte('}')
+}
+
+// WriteTo writes the accumulated exposition to out.
+func (w *Writer) WriteTo(out io.Writer) (int64, error) {
+	n, err := io.WriteString(out, w.b.String())
+	return int64(n), err
+}
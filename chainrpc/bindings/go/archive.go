@@ -0,0 +1,164 @@
+package chainrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// NodeKind says whether a provider keeps historical state.
+type NodeKind int32
+
+const (
+	// NodeUnknown providers are probed at construction when
+	// PoolOption.ProbeArchive is set, and learned from "missing trie node"
+	// style errors otherwise.
+	NodeUnknown NodeKind = iota
+	// NodeFull providers only serve recent state (about 128 blocks).
+	NodeFull
+	// NodeArchive providers serve state at any block and trace/debug calls.
+	NodeArchive
+)
+
+func (k NodeKind) String() string {
+	switch k {
+	case NodeFull:
+		return "full"
+	case NodeArchive:
+		return "archive"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the kind as "unknown", "full" or "archive".
+func (k NodeKind) MarshalText() ([]byte, error) { return []byte(k.String()), nil }
+
+// ErrNoArchive is returned when a call needs an archive node and the pool
+// has none that can serve it.
+var ErrNoArchive = errors.New("chainrpc: no archive-capable provider in pool")
+
+// recentStateDepth is how far behind the head a full node still has state.
+const recentStateDepth = 128
+
+// needsArchive reports whether method with paramsJSON must go to an archive
+// node: debug_* and trace_* calls, and state reads at an explicit block
+// more than recentStateDepth blocks behind head (0 if unknown).
+func needsArchive(method, paramsJSON string, head uint64) bool {
+	if strings.HasPrefix(method, "debug_") || strings.HasPrefix(method, "trace_") {
+		return true
+	}
+	switch method {
+	case "eth_getBalance", "eth_getCode", "eth_getTransactionCount", "eth_call",
+		"eth_estimateGas", "eth_getStorageAt", "eth_getProof", "eth_simulateV1":
+	default:
+		return false
+	}
+	if head == 0 {
+		return false
+	}
+	var params []json.RawMessage
+	if json.Unmarshal([]byte(paramsJSON), &params) != nil {
+		return false
+	}
+	i := blockTagIndex[method]
+	if i >= len(params) {
+		return false
+	}
+	n, err := parseQuantity(string(params[i]))
+	if err != nil {
+		return false // a tag such as "latest", or a block hash object
+	}
+	return n+recentStateDepth < head
+}
+
+// missingStateMarkers identify errors from full nodes asked for pruned
+// state or unsupported trace/debug namespaces.
+var missingStateMarkers = []string{
+	"missing trie node",
+	"header not found",
+	"state not available",
+	"historical state",
+	"state histories haven't been fully indexed",
+	"required historical state unavailable",
+	"is not available, pruned",
+	"the method debug_",
+	"the method trace_",
+	"method not found",
+}
+
+// isMissingState reports whether err means the provider lacks the state
+// or API the call needed, so an archive node may succeed.
+func isMissingState(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, m := range missingStateMarkers {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// kindOf returns the provider's current node kind.
+func (pr *provider) kindOf() NodeKind { return NodeKind(atomic.LoadInt32(&pr.kind)) }
+
+// learnFull marks an unknown provider as a full node after it failed a
+// historical call.
+func (pr *provider) learnFull() {
+	atomic.CompareAndSwapInt32(&pr.kind, int32(NodeUnknown), int32(NodeFull))
+}
+
+// archiveOrder filters order for a call that needs an archive node:
+// archive providers first, then unknown ones; full nodes are dropped.
+func archiveOrder(order []*provider) (keep, skipped []*provider) {
+	var unknown []*provider
+	for _, pr := range order {
+		switch pr.kindOf() {
+		case NodeArchive:
+			keep = append(keep, pr)
+		case NodeUnknown:
+			unknown = append(unknown, pr)
+		default:
+			skipped = append(skipped, pr)
+		}
+	}
+	return append(keep, unknown...), skipped
+}
+
+// probeArchive classifies unknown providers by reading the zero address'
+// balance at block 1, which full nodes have pruned.
+func (p *Pool) probeArchive() {
+	var wg sync.WaitGroup
+	for _, pr := range p.providers {
+		if pr.kindOf() != NodeUnknown {
+			continue
+		}
+		wg.Add(1)
+		go func(pr *provider) {
+			defer wg.Done()
+			_, err := call(pr.url, "eth_getBalance", `["0x0000000000000000000000000000000000000000","0x1"]`, pr.auth())
+			switch {
+			case err == nil:
+				atomic.StoreInt32(&pr.kind, int32(NodeArchive))
+			case isMissingState(err):
+				atomic.StoreInt32(&pr.kind, int32(NodeFull))
+			}
+			// Other errors (unreachable, rate limited) leave it unknown.
+		}(pr)
+	}
+	wg.Wait()
+}
+
+// head is the highest block any provider has reported.
+func (p *Pool) head() uint64 {
+	var h uint64
+	for _, pr := range p.providers {
+		pr.stats.mu.Lock()
+		if pr.stats.lastBlock > h {
+			h = pr.stats.lastBlock
+		}
+		pr.stats.mu.Unlock()
+	}
+	return h
+}
@@ -0,0 +1,130 @@
+package chainrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CredentialsFunc returns fresh HTTP options for a pool provider that
+// rejected a request as unauthorised (HTTP 401 or 403), e.g. with a renewed
+// JWT or a rotated API key. cur is a copy of the options the provider was
+// using, nil if it had none. Returning an error leaves them unchanged.
+type CredentialsFunc func(ctx context.Context, url string, cur *HTTPOptions) (*HTTPOptions, error)
+
+// isUnauthorized reports whether err is an HTTP 401 or 403 from the
+// transport or a WebSocket upgrade.
+func isUnauthorized(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "http 401") || strings.Contains(msg, "http 403")
+}
+
+// auth returns the provider's encoded HTTP options.
+func (pr *provider) auth() string {
+	pr.authMu.RLock()
+	defer pr.authMu.RUnlock()
+	return pr.httpJSON
+}
+
+// options returns the provider's HTTP options and their encoding.
+func (pr *provider) options() (*HTTPOptions, string) {
+	pr.authMu.RLock()
+	defer pr.authMu.RUnlock()
+	return pr.http, pr.httpJSON
+}
+
+func (pr *provider) setAuth(h *HTTPOptions) error {
+	enc, err := encodeHTTP(h, nil)
+	if err != nil {
+		return err
+	}
+	pr.authMu.Lock()
+	pr.http, pr.httpJSON = h, enc
+	pr.authMu.Unlock()
+	return nil
+}
+
+// SetProviderHTTP replaces the HTTP options of the pool's provider for url,
+// for credentials rotated outside a request. Calls already in flight keep
+// the old options.
+func (p *Pool) SetProviderHTTP(url string, h *HTTPOptions) error {
+	for _, pr := range p.providers {
+		if pr.url == url {
+			if err := pr.setAuth(h); err != nil {
+				return fmt.Errorf("chainrpc: %s: %w", url, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("chainrpc: no provider %s in pool", url)
+}
+
+// refreshAuth asks PoolOption.Credentials for new options after pr
+// rejected used. Callers that saw the same rejection concurrently share one
+// refresh: whoever arrives after it finds the options changed and reuses
+// them.
+func (p *Pool) refreshAuth(ctx context.Context, pr *provider, used string) (*HTTPOptions, string, error) {
+	pr.refreshMu.Lock()
+	defer pr.refreshMu.Unlock()
+	cur, enc := pr.options()
+	if enc != used {
+		return cur, enc, nil
+	}
+	if cur != nil {
+		c := *cur
+		cur = &c
+	}
+	h, err := p.opts.Credentials(ctx, pr.url, cur)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := pr.setAuth(h); err != nil {
+		return nil, "", err
+	}
+	h, enc = pr.options()
+	return h, enc, nil
+}
+
+// send makes one attempt against pr with the call's or the provider's HTTP
+// options. If the provider rejects the provider's credentials and the pool
+// has a Credentials hook, they are refreshed and the request is sent once
+// more.
+func (p *Pool) send(ctx context.Context, o *callOptions, pr *provider, method, paramsJSON string) (string, error) {
+	if o.http != nil {
+		return o.attempt(pr.url, method, func() (string, error) {
+			return o.transport(ctx, pr.url, method, paramsJSON, o.http, o.httpJSON)
+		})
+	}
+	h, httpJSON := pr.options()
+	out, err := o.attempt(pr.url, method, func() (string, error) {
+		enc, err := o.providerJSON(h, httpJSON)
+		if err != nil {
+			return "", err
+		}
+		return o.transport(ctx, pr.url, method, paramsJSON, h, enc)
+	})
+	if err == nil || p.opts.Credentials == nil || !isUnauthorized(err) {
+		return out, err
+	}
+	h, fresh, rerr := p.refreshAuth(ctx, pr, httpJSON)
+	if rerr != nil {
+		return "", errors.Join(err, fmt.Errorf("chainrpc: refreshing credentials for %s: %w", pr.url, rerr))
+	}
+	return o.attempt(pr.url, method, func() (string, error) {
+		enc, err := o.providerJSON(h, fresh)
+		if err != nil {
+			return "", err
+		}
+		return o.transport(ctx, pr.url, method, paramsJSON, h, enc)
+	})
+}
+
+// providerJSON returns enc, the encoding of a provider's options h, or
+// under a Go retry policy h encoded with the native retries turned off.
+func (o *callOptions) providerJSON(h *HTTPOptions, enc string) (string, error) {
+	if o.retry == nil {
+		return enc, nil
+	}
+	return encodeHTTP(h, o.retry)
+}
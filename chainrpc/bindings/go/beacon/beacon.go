@@ -0,0 +1,281 @@
+// Package beacon adds a client for the Ethereum consensus layer's REST API
+// (the "beacon node API" served by Lighthouse, Prysm, Teku, Nimbus and
+// Lodestar, port 5052 by default). Requests are plain GETs sent through
+// chainrpc.GetREST or a chainrpc.Pool of beacon node base URLs, so retries,
+// metrics, tracing, rate limits and failover apply as for JSON-RPC calls,
+// and WithETagCache revalidates immutable responses.
+//
+// The API encodes every 64-bit number as a decimal string; those fields
+// are decoded into Uint64.
+package beacon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/DarshanKumar89/chainfoundry/chainrpc"
+)
+
+// StateID selects the beacon state a query reads: one of the named states
+// below, a slot number (Slot) or a 0x-prefixed state root.
+type StateID string
+
+// BlockID selects a block the same way: a named block, a slot or a block
+// root.
+type BlockID = StateID
+
+const (
+	Head      StateID = "head"
+	Genesis   StateID = "genesis"
+	Finalized StateID = "finalized"
+	Justified StateID = "justified"
+)
+
+// Slot selects the state or block at slot n.
+func Slot(n uint64) StateID { return StateID(strconv.FormatUint(n, 10)) }
+
+// Validator statuses accepted by Validators' status filter.
+const (
+	StatusPendingInitialized = "pending_initialized"
+	StatusPendingQueued      = "pending_queued"
+	StatusActiveOngoing      = "active_ongoing"
+	StatusActiveExiting      = "active_exiting"
+	StatusActiveSlashed      = "active_slashed"
+	StatusExitedUnslashed    = "exited_unslashed"
+	StatusExitedSlashed      = "exited_slashed"
+	StatusWithdrawalPossible = "withdrawal_possible"
+	StatusWithdrawalDone     = "withdrawal_done"
+)
+
+// Uint64 is a number the API encodes as a JSON string. It also accepts
+// plain numbers.
+type Uint64 uint64
+
+func (n *Uint64) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("beacon: invalid integer %s", b)
+	}
+	*n = Uint64(v)
+	return nil
+}
+
+func (n Uint64) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(strconv.FormatUint(uint64(n), 10))), nil
+}
+
+// IsNotFound reports whether err is the node's 404 for a state, block,
+// validator or sidecar it does not have.
+func IsNotFound(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "HTTP 404")
+}
+
+// Client issues beacon API requests.
+type Client struct {
+	get func(ctx context.Context, path string) ([]byte, error)
+}
+
+// New returns a client for the beacon node at baseURL, e.g.
+// "http://localhost:5052"; opts apply to every request.
+func New(baseURL string, opts ...chainrpc.Option) *Client {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	return &Client{get: func(ctx context.Context, path string) ([]byte, error) {
+		return chainrpc.GetREST(ctx, baseURL+path, opts...)
+	}}
+}
+
+// NewFromPool returns a client that routes through p, whose providers are
+// beacon node base URLs.
+func NewFromPool(p *chainrpc.Pool, opts ...chainrpc.Option) *Client {
+	return &Client{get: func(ctx context.Context, path string) ([]byte, error) {
+		return p.GetREST(ctx, path, opts...)
+	}}
+}
+
+// Meta is the metadata the API returns next to a response's data.
+type Meta struct {
+	// ExecutionOptimistic is set when the response depends on an execution
+	// payload the node has not fully verified yet.
+	ExecutionOptimistic bool `json:"execution_optimistic"`
+	// Finalized is set when the response is from finalized state.
+	Finalized bool `json:"finalized"`
+}
+
+// do fetches path and decodes the "data" member of the response into out.
+func (c *Client) do(ctx context.Context, path string, query url.Values, out interface{}) (Meta, error) {
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	body, err := c.get(ctx, path)
+	if err != nil {
+		return Meta{}, err
+	}
+	var env struct {
+		Meta
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &env); err != nil {
+		return Meta{}, fmt.Errorf("beacon: %s: %w", path, err)
+	}
+	if err := json.Unmarshal(env.Data, out); err != nil {
+		return Meta{}, fmt.Errorf("beacon: %s data: %w", path, err)
+	}
+	return env.Meta, nil
+}
+
+// GenesisInfo describes the chain's genesis.
+type GenesisInfo struct {
+	GenesisTime           Uint64 `json:"genesis_time"`
+	GenesisValidatorsRoot string `json:"genesis_validators_root"`
+	GenesisForkVersion    string `json:"genesis_fork_version"`
+}
+
+// Genesis returns the chain's genesis time and fork.
+func (c *Client) Genesis(ctx context.Context) (*GenesisInfo, error) {
+	var g GenesisInfo
+	if _, err := c.do(ctx, "/eth/v1/beacon/genesis", nil, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// Checkpoint is an epoch boundary block.
+type Checkpoint struct {
+	Epoch Uint64 `json:"epoch"`
+	Root  string `json:"root"`
+}
+
+// FinalityCheckpoints are the justified and finalized checkpoints of a
+// state.
+type FinalityCheckpoints struct {
+	PreviousJustified Checkpoint `json:"previous_justified"`
+	CurrentJustified  Checkpoint `json:"current_justified"`
+	Finalized         Checkpoint `json:"finalized"`
+}
+
+// FinalityCheckpoints returns state's finality checkpoints; at Head they
+// tell how far the chain has finalized.
+func (c *Client) FinalityCheckpoints(ctx context.Context, state StateID) (*FinalityCheckpoints, error) {
+	var f FinalityCheckpoints
+	if _, err := c.do(ctx, "/eth/v1/beacon/states/"+url.PathEscape(string(state))+"/finality_checkpoints", nil, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Validator is a validator's record and balance in a state.
+type Validator struct {
+	Index     Uint64 `json:"index"`
+	Balance   Uint64 `json:"balance"` // Gwei
+	Status    string `json:"status"`
+	Validator struct {
+		Pubkey                     string `json:"pubkey"`
+		WithdrawalCredentials      string `json:"withdrawal_credentials"`
+		EffectiveBalance           Uint64 `json:"effective_balance"` // Gwei
+		Slashed                    bool   `json:"slashed"`
+		ActivationEligibilityEpoch Uint64 `json:"activation_eligibility_epoch"`
+		ActivationEpoch            Uint64 `json:"activation_epoch"`
+		ExitEpoch                  Uint64 `json:"exit_epoch"`
+		WithdrawableEpoch          Uint64 `json:"withdrawable_epoch"`
+	} `json:"validator"`
+}
+
+// FarFutureEpoch is the exit and withdrawable epoch of validators that
+// have not exited.
+const FarFutureEpoch = Uint64(1<<64 - 1)
+
+// Validator returns one validator by index or 0x-prefixed public key.
+func (c *Client) Validator(ctx context.Context, state StateID, id string) (*Validator, error) {
+	var v Validator
+	path := "/eth/v1/beacon/states/" + url.PathEscape(string(state)) + "/validators/" + url.PathEscape(id)
+	if _, err := c.do(ctx, path, nil, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Validators returns the validators in state matching ids (indices or
+// public keys) and statuses (the Status constants, or the "active",
+// "pending", "exited" and "withdrawal" groups). Empty filters match every
+// validator, which on mainnet is a very large response.
+func (c *Client) Validators(ctx context.Context, state StateID, ids, statuses []string) ([]Validator, error) {
+	q := url.Values{}
+	if len(ids) > 0 {
+		q.Set("id", strings.Join(ids, ","))
+	}
+	if len(statuses) > 0 {
+		q.Set("status", strings.Join(statuses, ","))
+	}
+	var vs []Validator
+	if _, err := c.do(ctx, "/eth/v1/beacon/states/"+url.PathEscape(string(state))+"/validators", q, &vs); err != nil {
+		return nil, err
+	}
+	return vs, nil
+}
+
+// BeaconBlockHeader is a block header as returned inside headers and blob
+// sidecars.
+type BeaconBlockHeader struct {
+	Slot          Uint64 `json:"slot"`
+	ProposerIndex Uint64 `json:"proposer_index"`
+	ParentRoot    string `json:"parent_root"`
+	StateRoot     string `json:"state_root"`
+	BodyRoot      string `json:"body_root"`
+}
+
+// SignedBeaconBlockHeader is a header with its proposer's signature.
+type SignedBeaconBlockHeader struct {
+	Message   BeaconBlockHeader `json:"message"`
+	Signature string            `json:"signature"`
+}
+
+// HeaderInfo is a block header and where it stands.
+type HeaderInfo struct {
+	Root      string                  `json:"root"`
+	Canonical bool                    `json:"canonical"`
+	Header    SignedBeaconBlockHeader `json:"header"`
+}
+
+// Header returns the header of block, and whether it is finalized.
+func (c *Client) Header(ctx context.Context, block BlockID) (*HeaderInfo, bool, error) {
+	var h HeaderInfo
+	meta, err := c.do(ctx, "/eth/v1/beacon/headers/"+url.PathEscape(string(block)), nil, &h)
+	if err != nil {
+		return nil, false, err
+	}
+	return &h, meta.Finalized, nil
+}
+
+// BlobSidecar is an EIP-4844 blob with its KZG commitment and proof.
+type BlobSidecar struct {
+	Index                       Uint64                  `json:"index"`
+	Blob                        string                  `json:"blob"`
+	KZGCommitment               string                  `json:"kzg_commitment"`
+	KZGProof                    string                  `json:"kzg_proof"`
+	SignedBlockHeader           SignedBeaconBlockHeader `json:"signed_block_header"`
+	KZGCommitmentInclusionProof []string                `json:"kzg_commitment_inclusion_proof"`
+}
+
+// BlobSidecars returns the blobs of block, or only those at indices.
+// Nodes prune blobs after about 18 days (4096 epochs); older blocks
+// return none.
+func (c *Client) BlobSidecars(ctx context.Context, block BlockID, indices ...uint64) ([]BlobSidecar, error) {
+	q := url.Values{}
+	for _, i := range indices {
+		q.Add("indices", strconv.FormatUint(i, 10))
+	}
+	var s []BlobSidecar
+	if _, err := c.do(ctx, "/eth/v1/beacon/blob_sidecars/"+url.PathEscape(string(block)), q, &s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,256 @@
+package bitcoin
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// Topics Bitcoin Core publishes with -zmqpub<topic>=<address>.
+const (
+	TopicHashBlock = "hashblock"
+	TopicHashTx    = "hashtx"
+	TopicRawBlock  = "rawblock"
+	TopicRawTx     = "rawtx"
+	TopicSequence  = "sequence"
+)
+
+// Notification is one ZMQ message from the node.
+type Notification struct {
+	Topic string
+	// Body is the block or transaction hash, in the byte order RPC
+	// displays, or the serialised block or transaction.
+	Body []byte
+	// Sequence counts messages per topic.
+	Sequence uint32
+	// Missed is how many messages of this topic were lost before this one,
+	// judging by Sequence. ZMQ drops messages when the subscriber falls
+	// behind or reconnects; after a loss, catch up over RPC.
+	Missed uint32
+}
+
+// Hash returns Body as hex for the hashblock and hashtx topics, matching
+// the hashes RPC methods take and return.
+func (n Notification) Hash() string {
+	return hex.EncodeToString(n.Body)
+}
+
+// ZMQSubscription delivers notifications on C until its context is
+// cancelled, Close is called, or the connection fails.
+type ZMQSubscription struct {
+	// C is closed when the subscription ends; Err then reports why.
+	C <-chan Notification
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Err returns the error that ended the subscription once C is closed; it
+// is nil after Close or context cancellation.
+func (s *ZMQSubscription) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Close closes the connection and waits for C to be closed.
+func (s *ZMQSubscription) Close() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// SubscribeZMQ connects to a node's ZMQ publisher at addr (e.g.
+// "tcp://127.0.0.1:28332") and subscribes to topics, all of them if none
+// are given. It speaks ZMTP 3.0 with the NULL mechanism, as Bitcoin Core
+// does, so no ZMQ library is needed. Notifications only announce new
+// blocks and transactions; fetch them with GetBlock and GetRawTransaction.
+func SubscribeZMQ(ctx context.Context, addr string, topics ...string) (*ZMQSubscription, error) {
+	hostport := strings.TrimPrefix(addr, "tcp://")
+	if hostport == addr && strings.Contains(addr, "://") {
+		return nil, fmt.Errorf("bitcoin: unsupported ZMQ address %s", addr)
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", hostport)
+	if err != nil {
+		return nil, fmt.Errorf("bitcoin: ZMQ: %w", err)
+	}
+	z := &zmtpConn{conn: conn, r: bufio.NewReader(conn)}
+	if err := z.handshake(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("bitcoin: ZMQ handshake: %w", err)
+	}
+	if len(topics) == 0 {
+		topics = []string{""}
+	}
+	for _, t := range topics {
+		// ZMTP 3.0 subscriptions are messages starting with 0x01.
+		if err := z.writeFrame(0, append([]byte{1}, t...)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("bitcoin: ZMQ subscribe: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	out := make(chan Notification, 256)
+	s := &ZMQSubscription{C: out, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	go func() {
+		defer func() {
+			cancel()
+			close(out)
+			close(s.done)
+		}()
+		last := map[string]uint32{}
+		for {
+			parts, err := z.readMessage()
+			if err != nil {
+				if ctx.Err() == nil {
+					s.err = fmt.Errorf("bitcoin: ZMQ: %w", err)
+				}
+				return
+			}
+			if len(parts) != 3 || len(parts[2]) != 4 {
+				continue
+			}
+			n := Notification{Topic: string(parts[0]), Body: parts[1], Sequence: binary.LittleEndian.Uint32(parts[2])}
+			if prev, ok := last[n.Topic]; ok {
+				n.Missed = n.Sequence - prev - 1
+			}
+			last[n.Topic] = n.Sequence
+			select {
+			case out <- n:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return s, nil
+}
+
+// zmtpConn is the subscriber side of a ZMTP 3.0 connection.
+type zmtpConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+const (
+	zmtpMore    = 0x01
+	zmtpLong    = 0x02
+	zmtpCommand = 0x04
+)
+
+func (z *zmtpConn) handshake() error {
+	var g [64]byte
+	g[0], g[9], g[10], g[11] = 0xff, 0x7f, 3, 0
+	copy(g[12:], "NULL")
+	if _, err := z.conn.Write(g[:]); err != nil {
+		return err
+	}
+	var peer [64]byte
+	if _, err := io.ReadFull(z.r, peer[:]); err != nil {
+		return err
+	}
+	if peer[0] != 0xff || peer[9]&1 != 1 || peer[10] < 3 {
+		return errors.New("peer is not a ZMTP 3 endpoint")
+	}
+	if mech := strings.TrimRight(string(peer[12:32]), "\x00"); mech != "NULL" {
+		return fmt.Errorf("unsupported security mechanism %s", mech)
+	}
+	ready := []byte("\x05READY\x0bSocket-Type\x00\x00\x00\x03SUB")
+	if err := z.writeFrame(zmtpCommand, ready); err != nil {
+		return err
+	}
+	for {
+		flags, body, err := z.readFrame()
+		if err != nil {
+			return err
+		}
+		if flags&zmtpCommand == 0 {
+			return errors.New("message before READY")
+		}
+		if len(body) > 6 && string(body[1:6]) == "READY" {
+			return nil
+		}
+		if len(body) > 6 && string(body[1:6]) == "ERROR" {
+			return fmt.Errorf("peer error: %s", body[7:])
+		}
+	}
+}
+
+func (z *zmtpConn) writeFrame(flags byte, body []byte) error {
+	var hdr []byte
+	if len(body) > 255 {
+		hdr = make([]byte, 9)
+		hdr[0] = flags | zmtpLong
+		binary.BigEndian.PutUint64(hdr[1:], uint64(len(body)))
+	} else {
+		hdr = []byte{flags, byte(len(body))}
+	}
+	_, err := z.conn.Write(append(hdr, body...))
+	return err
+}
+
+// maxZMQFrame bounds one frame; rawblock messages carry a whole block.
+const maxZMQFrame = 64 << 20
+
+func (z *zmtpConn) readFrame() (byte, []byte, error) {
+	flags, err := z.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	var size uint64
+	if flags&zmtpLong != 0 {
+		var b [8]byte
+		if _, err := io.ReadFull(z.r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(b[:])
+	} else {
+		b, err := z.r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		size = uint64(b)
+	}
+	if size > maxZMQFrame {
+		return 0, nil, fmt.Errorf("frame of %d bytes exceeds limit", size)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(z.r, body); err != nil {
+		return 0, nil, err
+	}
+	return flags, body, nil
+}
+
+// readMessage returns the parts of the next multipart message, skipping
+// commands.
+func (z *zmtpConn) readMessage() ([][]byte, error) {
+	var parts [][]byte
+	for {
+		flags, body, err := z.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		if flags&zmtpCommand != 0 {
+			continue
+		}
+		parts = append(parts, body)
+		if flags&zmtpMore == 0 {
+			return parts, nil
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package chainrpc
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when every provider in the pool was skipped
+// because its circuit breaker is open. IsRetryable reports it as transient.
+var ErrCircuitOpen = errors.New("chainrpc: circuit breaker open for every provider")
+
+// CircuitBreakerPolicy takes failing providers out of a pool's rotation.
+// After FailureThreshold consecutive transport failures a provider's
+// circuit opens and calls skip it for Cooldown; then one call probes it
+// (half-open) while the others keep skipping it. A successful probe closes
+// the circuit, a failed one opens it for another Cooldown.
+type CircuitBreakerPolicy struct {
+	// FailureThreshold is the number of consecutive failures that opens
+	// the circuit. Zero means 5.
+	FailureThreshold int
+	// Cooldown is how long an open circuit skips the provider. Zero means
+	// 30s.
+	Cooldown time.Duration
+}
+
+// Circuit states reported in ProviderStats.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (p CircuitBreakerPolicy) threshold() int {
+	if p.FailureThreshold <= 0 {
+		return 5
+	}
+	return p.FailureThreshold
+}
+
+func (p CircuitBreakerPolicy) cooldown() time.Duration {
+	if p.Cooldown <= 0 {
+		return 30 * time.Second
+	}
+	return p.Cooldown
+}
+
+// allow reports whether a call may use the provider now, and whether it is
+// the half-open probe, which must be settled with success, failure or
+// abort.
+func (b *breaker) allow(now time.Time) (ok, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.openUntil.IsZero():
+		return true, false
+	case now.Before(b.openUntil) || b.probing:
+		return false, false
+	}
+	b.probing = true
+	return true, true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	b.failures, b.openUntil, b.probing = 0, time.Time{}, false
+	b.mu.Unlock()
+}
+
+func (b *breaker) failure(now time.Time, policy CircuitBreakerPolicy) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.probing || b.failures >= policy.threshold() {
+		b.openUntil = now.Add(policy.cooldown())
+		b.probing = false
+	}
+}
+
+// abort releases a probe that never reached the provider.
+func (b *breaker) abort() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+func (b *breaker) state(now time.Time) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.openUntil.IsZero():
+		return CircuitClosed
+	case now.Before(b.openUntil):
+		return CircuitOpen
+	}
+	return CircuitHalfOpen
+}
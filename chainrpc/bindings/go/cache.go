@@ -0,0 +1,211 @@
+package chainrpc
+
+import (
+	"container/list"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheOptions configures a response Cache.
+type CacheOptions struct {
+	// TTL bounds how long an entry is served. Zero means entries never expire.
+	TTL time.Duration
+	// MaxEntries bounds the cache size; the least recently used entry is
+	// evicted first. Zero means 10,000.
+	MaxEntries int
+	// Confirmations is how many blocks a transaction's block must be below
+	// the head before the transaction and its receipt are cached, so a
+	// reorg cannot move a cached result. Zero means
+	// DefaultCacheConfirmations.
+	Confirmations uint64
+}
+
+// DefaultCacheConfirmations is the confirmation depth unless
+// CacheOptions.Confirmations is set.
+const DefaultCacheConfirmations = 12
+
+// CacheStats reports cache effectiveness.
+type CacheStats struct {
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	Entries int    `json:"entries"`
+}
+
+// Cache is an in-process LRU cache for immutable RPC results, keyed by
+// method and params. Only results that can never change are stored:
+// eth_chainId, blocks fetched by hash, and transactions and receipts whose
+// block is CacheOptions.Confirmations below the head.
+//
+// Keys do not include the endpoint, so a Cache must only be shared between
+// endpoints serving the same chain.
+type Cache struct {
+	opts CacheOptions
+
+	mu     sync.Mutex
+	ll     *list.List
+	items  map[string]*list.Element
+	hits   uint64
+	misses uint64
+	head   uint64 // highest head seen
+}
+
+type cacheEntry struct {
+	key     string
+	value   string
+	expires time.Time
+}
+
+// NewCache returns an empty cache.
+func NewCache(opts CacheOptions) *Cache {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 10000
+	}
+	if opts.Confirmations == 0 {
+		opts.Confirmations = DefaultCacheConfirmations
+	}
+	return &Cache{opts: opts, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// WithCache serves immutable results from c and stores new ones in it.
+func WithCache(c *Cache) Option {
+	return func(o *callOptions) { o.cache = c }
+}
+
+// Stats returns hit/miss counters and the current size.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Hits: c.hits, Misses: c.misses, Entries: c.ll.Len()}
+}
+
+// Purge removes every entry.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+func cacheKey(method, paramsJSON string) string {
+	return method + "\x00" + paramsJSON
+}
+
+func (c *Cache) get(method, paramsJSON string) (string, bool) {
+	if !cacheableMethod(method) {
+		return "", false
+	}
+	key := cacheKey(method, paramsJSON)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		c.misses++
+		return "", false
+	}
+	e := el.Value.(*cacheEntry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		c.misses++
+		return "", false
+	}
+	c.ll.MoveToFront(el)
+	c.hits++
+	return e.value, true
+}
+
+// put stores result. head returns eth_blockNumber, for confirming
+// transactions and receipts.
+func (c *Cache) put(method, paramsJSON, result string, head func() (string, error)) {
+	if !cacheableResult(method, result) || !c.confirmed(method, result, head) {
+		return
+	}
+	key := cacheKey(method, paramsJSON)
+	e := &cacheEntry{key: key, value: result}
+	if c.opts.TTL > 0 {
+		e.expires = time.Now().Add(c.opts.TTL)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value = e
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(e)
+	for c.ll.Len() > c.opts.MaxEntries {
+		old := c.ll.Back()
+		c.ll.Remove(old)
+		delete(c.items, old.Value.(*cacheEntry).key)
+	}
+}
+
+func cacheableMethod(method string) bool {
+	switch method {
+	case "eth_chainId", "net_version", "eth_getBlockByHash",
+		"eth_getTransactionByHash", "eth_getTransactionReceipt":
+		return true
+	}
+	return false
+}
+
+// cacheableResult reports whether result is final. Pending transactions and
+// missing receipts may still change and are never cached.
+func cacheableResult(method, result string) bool {
+	if !cacheableMethod(method) || result == "" || result == "null" {
+		return false
+	}
+	switch method {
+	case "eth_getTransactionByHash", "eth_getTransactionReceipt":
+		var v struct {
+			BlockHash *string `json:"blockHash"`
+		}
+		return json.Unmarshal([]byte(result), &v) == nil && v.BlockHash != nil
+	}
+	return true
+}
+
+// confirmed reports whether a transaction or receipt result's block is
+// Confirmations below the head. The head is fetched only when the highest
+// one seen so far is too low to tell.
+func (c *Cache) confirmed(method, result string, head func() (string, error)) bool {
+	switch method {
+	case "eth_getTransactionByHash", "eth_getTransactionReceipt":
+	default:
+		return true
+	}
+	var v struct {
+		BlockNumber *string `json:"blockNumber"`
+	}
+	if json.Unmarshal([]byte(result), &v) != nil || v.BlockNumber == nil {
+		return false
+	}
+	n, err := strconv.ParseUint(strings.TrimPrefix(*v.BlockNumber, "0x"), 16, 64)
+	if err != nil {
+		return false
+	}
+	c.mu.Lock()
+	known := c.head
+	c.mu.Unlock()
+	if n+c.opts.Confirmations <= known {
+		return true
+	}
+	if head == nil {
+		return false
+	}
+	res, err := head()
+	if err != nil {
+		return false
+	}
+	h, err := parseQuantity(res)
+	if err != nil {
+		return false
+	}
+	c.mu.Lock()
+	c.head = max(c.head, h)
+	c.mu.Unlock()
+	return n+c.opts.Confirmations <= h
+}
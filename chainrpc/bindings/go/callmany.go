@@ -0,0 +1,78 @@
+package chainrpc
+
+import (
+	"context"
+	"sync"
+)
+
+// Request is one call in a CallMany fan-out.
+type Request struct {
+	Method string
+	// Params is a JSON array string, as for Call.
+	Params string
+}
+
+// Response is the outcome of one Request.
+type Response struct {
+	Result string
+	Err    error
+}
+
+// defaultCallManyConcurrency is used when CallMany is given concurrency <= 0.
+const defaultCallManyConcurrency = 8
+
+// CallMany sends requests to url with at most concurrency in flight and
+// returns their responses in request order. A failed request does not stop
+// the others; requests not yet started when ctx is done fail with ctx.Err().
+//
+// The native layer serves concurrent calls independently, so CallMany is
+// safe to use from any number of goroutines.
+func CallMany(ctx context.Context, url string, requests []Request, concurrency int, opts ...Option) []Response {
+	return callMany(ctx, requests, concurrency, func(ctx context.Context, r Request) (string, error) {
+		return CallContext(ctx, url, r.Method, r.Params, opts...)
+	})
+}
+
+// CallMany is like the package-level CallMany but sends each request
+// through the pool, with failover per request.
+func (p *Pool) CallMany(ctx context.Context, requests []Request, concurrency int, opts ...Option) []Response {
+	return callMany(ctx, requests, concurrency, func(ctx context.Context, r Request) (string, error) {
+		return p.CallContext(ctx, r.Method, r.Params, opts...)
+	})
+}
+
+func callMany(ctx context.Context, requests []Request, concurrency int, do func(context.Context, Request) (string, error)) []Response {
+	if concurrency <= 0 {
+		concurrency = defaultCallManyConcurrency
+	}
+	if concurrency > len(requests) {
+		concurrency = len(requests)
+	}
+	out := make([]Response, len(requests))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				out[i].Result, out[i].Err = do(ctx, requests[i])
+			}
+		}()
+	}
+	i := 0
+feed:
+	for ; i < len(requests); i++ {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+	for ; i < len(requests); i++ {
+		out[i].Err = ctx.Err()
+	}
+	return out
+}
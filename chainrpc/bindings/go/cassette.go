@@ -0,0 +1,195 @@
+package chainrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CassetteMode selects whether a Cassette records or replays.
+type CassetteMode int
+
+const (
+	// CassetteReplay serves calls from the cassette and never touches the
+	// network. A call that was not recorded fails with ErrCassetteMiss.
+	CassetteReplay CassetteMode = iota
+	// CassetteRecord sends calls to the provider and records each
+	// request/response pair; Save writes them out.
+	CassetteRecord
+)
+
+func (m CassetteMode) String() string {
+	if m == CassetteRecord {
+		return "record"
+	}
+	return "replay"
+}
+
+// RecordEnv forces OpenCassette into record mode when set to a non-empty
+// value, to refresh cassettes against a live provider.
+const RecordEnv = "CHAINRPC_RECORD"
+
+// ErrCassetteMiss is returned in replay mode for a call the cassette does
+// not contain.
+var ErrCassetteMiss = errors.New("chainrpc: call not found in cassette")
+
+// Interaction is one recorded call.
+type Interaction struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// cassetteFile is the on-disk format.
+type cassetteFile struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+const cassetteVersion = 1
+
+// Cassette records calls to a file and replays them without a network, so
+// tests against live providers become fast and deterministic.
+//
+// Calls are matched on method and params (compared as compact JSON). A call
+// made several times is answered with the recorded responses in order,
+// repeating the last one once they run out, so polling loops replay the
+// same sequence they saw while recording. Errors replay with their
+// original message, so AsRevert and error classification behave the same.
+type Cassette struct {
+	path string
+	mode CassetteMode
+
+	mu       sync.Mutex
+	recorded []Interaction
+	byKey    map[string][]int // indexes into recorded
+	served   map[string]int
+}
+
+// NewCassette returns a cassette for path in the given mode. In replay mode
+// the file must exist.
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	c := &Cassette{path: path, mode: mode, byKey: map[string][]int{}, served: map[string]int{}}
+	if mode == CassetteRecord {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f cassetteFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("chainrpc: cassette %s: %w", path, err)
+	}
+	if f.Version != cassetteVersion {
+		return nil, fmt.Errorf("chainrpc: cassette %s: unsupported version %d", path, f.Version)
+	}
+	for _, in := range f.Interactions {
+		c.add(in)
+	}
+	return c, nil
+}
+
+// OpenCassette replays path if it exists and records to it otherwise, or
+// always records when RecordEnv is set.
+func OpenCassette(path string) (*Cassette, error) {
+	mode := CassetteReplay
+	if _, err := os.Stat(path); os.Getenv(RecordEnv) != "" || errors.Is(err, os.ErrNotExist) {
+		mode = CassetteRecord
+	}
+	return NewCassette(path, mode)
+}
+
+// WithCassette records or replays the call through c.
+func WithCassette(c *Cassette) Option {
+	return func(o *callOptions) { o.cassette = c }
+}
+
+// Mode returns whether the cassette records or replays.
+func (c *Cassette) Mode() CassetteMode { return c.mode }
+
+// Interactions returns a copy of the recorded calls in order.
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Interaction(nil), c.recorded...)
+}
+
+// Save writes the recorded calls to the cassette file. It is a no-op in
+// replay mode.
+func (c *Cassette) Save() error {
+	if c.mode != CassetteRecord {
+		return nil
+	}
+	c.mu.Lock()
+	data, err := json.MarshalIndent(cassetteFile{Version: cassetteVersion, Interactions: c.recorded}, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(c.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(c.path, append(data, '\n'), 0o644)
+}
+
+func (c *Cassette) add(in Interaction) {
+	k := cassetteKey(in.Method, string(in.Params))
+	c.byKey[k] = append(c.byKey[k], len(c.recorded))
+	c.recorded = append(c.recorded, in)
+}
+
+// do serves a call from the cassette, or runs fn and records its outcome.
+func (c *Cassette) do(method, paramsJSON string, fn func() (string, error)) (string, error) {
+	if c.mode == CassetteRecord {
+		out, err := fn()
+		in := Interaction{Method: method, Params: compactJSON(paramsJSON)}
+		if err != nil {
+			in.Error = err.Error()
+		} else {
+			in.Result = compactJSON(out)
+		}
+		c.mu.Lock()
+		c.add(in)
+		c.mu.Unlock()
+		return out, err
+	}
+
+	k := cassetteKey(method, paramsJSON)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	idx := c.byKey[k]
+	if len(idx) == 0 {
+		return "", fmt.Errorf("%w: %s %s", ErrCassetteMiss, method, paramsJSON)
+	}
+	n := c.served[k]
+	if n >= len(idx) {
+		n = len(idx) - 1
+	}
+	c.served[k]++
+	in := c.recorded[idx[n]]
+	if in.Error != "" {
+		return "", parseError(in.Error)
+	}
+	return string(in.Result), nil
+}
+
+func cassetteKey(method, paramsJSON string) string {
+	return method + "\x00" + string(compactJSON(paramsJSON))
+}
+
+// compactJSON strips insignificant whitespace, leaving invalid JSON as is.
+func compactJSON(s string) json.RawMessage {
+	var buf bytes.Buffer
+	if json.Compact(&buf, []byte(s)) != nil {
+		return json.RawMessage(mustJSON(s))
+	}
+	return buf.Bytes()
+}
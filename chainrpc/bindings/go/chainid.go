@@ -0,0 +1,91 @@
+package chainrpc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ChainIDMismatchError is returned by NewPool when VerifyChainID is set and
+// providers disagree about the chain, or differ from PoolOption.ChainID.
+type ChainIDMismatchError struct {
+	// Expected is PoolOption.ChainID (0 if unset).
+	Expected uint64
+	// ChainIDs maps each reachable provider URL to the chain ID it reported.
+	ChainIDs map[string]uint64
+	// Failed maps providers that could not be queried to their error.
+	Failed map[string]error
+}
+
+func (e *ChainIDMismatchError) Error() string {
+	var parts []string
+	for _, u := range sortedKeys(e.ChainIDs) {
+		parts = append(parts, fmt.Sprintf("%s=%d", ProviderLabel(u), e.ChainIDs[u]))
+	}
+	for _, u := range sortedKeys(e.Failed) {
+		parts = append(parts, fmt.Sprintf("%s: %v", ProviderLabel(u), e.Failed[u]))
+	}
+	if e.Expected != 0 {
+		return fmt.Sprintf("chainrpc: providers do not all serve chain %d: %s", e.Expected, strings.Join(parts, ", "))
+	}
+	return "chainrpc: providers disagree on chain ID: " + strings.Join(parts, ", ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ChainID returns the chain ID verified at construction, or 0 if the pool
+// was built without VerifyChainID.
+func (p *Pool) ChainID() uint64 {
+	return p.chainID
+}
+
+// verifyChainID queries every provider concurrently. A provider that
+// cannot be reached fails verification: its chain is unknown.
+func (p *Pool) verifyChainID(expected uint64) (uint64, error) {
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		ids = make(map[string]uint64)
+		bad = make(map[string]error)
+	)
+	for _, pr := range p.providers {
+		wg.Add(1)
+		go func(pr *provider) {
+			defer wg.Done()
+			res, err := call(pr.url, "eth_chainId", "[]", pr.auth())
+			var id uint64
+			if err == nil {
+				id, err = parseQuantity(res)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				bad[pr.url] = err
+				return
+			}
+			ids[pr.url] = id
+		}(pr)
+	}
+	wg.Wait()
+
+	want := expected
+	ok := len(bad) == 0
+	for _, id := range ids {
+		if want == 0 {
+			want = id
+		}
+		ok = ok && id == want
+	}
+	if !ok {
+		return 0, &ChainIDMismatchError{Expected: expected, ChainIDs: ids, Failed: bad}
+	}
+	return want, nil
+}
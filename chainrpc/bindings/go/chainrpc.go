@@ -17,8 +17,9 @@ package chainrpc
 */
 import "C"
 import (
+	"context"
 	"errors"
-	"unsafe"
+	"runtime"
 )
 
 // Version returns the chainrpc library version.
@@ -26,48 +27,119 @@ func Version() string {
 	return C.GoString(C.chainrpc_version())
 }
 
+// lastError reads the native library's thread-local error buffer, so it
+// must run on the OS thread that made the failing call. call and poolCall
+// lock their goroutine to its thread for that reason; the native side
+// shares one multi-threaded runtime and serves concurrent calls
+// independently.
 func lastError() error {
 	msg := C.chainrpc_last_error()
 	if msg == nil {
 		return errors.New("unknown FFI error")
 	}
-	return errors.New(C.GoString(msg))
+	return parseError(C.GoString(msg))
 }
 
 // Call sends a single JSON-RPC request to the given URL and returns the result.
+// url may also be the path of a local node's IPC socket.
 //
 // paramsJSON should be a JSON array string, e.g. "[]" or `["0x...", "latest"]`.
-func Call(url, method, paramsJSON string) (string, error) {
-	cURL := C.CString(url)
-	defer C.free(unsafe.Pointer(cURL))
-	cMethod := C.CString(method)
-	defer C.free(unsafe.Pointer(cMethod))
-	cParams := C.CString(paramsJSON)
-	defer C.free(unsafe.Pointer(cParams))
+func Call(url, method, paramsJSON string, opts ...Option) (string, error) {
+	return CallContext(context.Background(), url, method, paramsJSON, opts...)
+}
 
-	ptr := C.chainrpc_call(cURL, cMethod, cParams)
-	if ptr == nil {
-		return "", lastError()
+// CallContext is like Call but stops retrying once ctx is done.
+func CallContext(ctx context.Context, url, method, paramsJSON string, opts ...Option) (string, error) {
+	o := newCallOptions(opts)
+	httpJSON, err := encodeHTTP(o.http, o.retry)
+	if err != nil {
+		return "", err
 	}
-	defer C.chainrpc_free_string(ptr)
-	return C.GoString(ptr), nil
+	return o.traced(ctx, method, url, func(ctx context.Context) (string, error) {
+		return o.taped(method, paramsJSON, func() (string, error) {
+			blockNumber := func() (string, error) {
+				return o.run(ctx, url, "eth_blockNumber", func() (string, error) { return call(url, "eth_blockNumber", "[]", httpJSON) })
+			}
+			paramsJSON, err := pinParams(ctx, method, paramsJSON, blockNumber)
+			if err != nil {
+				return "", err
+			}
+			return o.bounded(method, paramsJSON, blockNumber, func(paramsJSON string) (string, error) {
+				return o.cached(method, paramsJSON, blockNumber, func() (string, error) {
+					return o.run(ctx, url, method, func() (string, error) { return call(url, method, paramsJSON, httpJSON) })
+				})
+			})
+		})
+	})
 }
 
 // PoolCall sends a JSON-RPC request through a provider pool with automatic failover.
 //
 // urlsJSON should be a JSON array of URL strings, e.g. `["https://rpc1.example.com", "https://rpc2.example.com"]`.
-func PoolCall(urlsJSON, method, paramsJSON string) (string, error) {
-	cURLs := C.CString(urlsJSON)
-	defer C.free(unsafe.Pointer(cURLs))
-	cMethod := C.CString(method)
-	defer C.free(unsafe.Pointer(cMethod))
-	cParams := C.CString(paramsJSON)
-	defer C.free(unsafe.Pointer(cParams))
+func PoolCall(urlsJSON, method, paramsJSON string, opts ...Option) (string, error) {
+	return PoolCallContext(context.Background(), urlsJSON, method, paramsJSON, opts...)
+}
+
+// PoolCallContext is like PoolCall but stops retrying once ctx is done.
+func PoolCallContext(ctx context.Context, urlsJSON, method, paramsJSON string, opts ...Option) (string, error) {
+	o := newCallOptions(opts)
+	httpJSON, err := encodeHTTP(o.http, o.retry)
+	if err != nil {
+		return "", err
+	}
+	return o.traced(ctx, method, urlsJSON, func(ctx context.Context) (string, error) {
+		return o.taped(method, paramsJSON, func() (string, error) {
+			blockNumber := func() (string, error) {
+				return o.run(ctx, urlsJSON, "eth_blockNumber", func() (string, error) { return poolCall(urlsJSON, "eth_blockNumber", "[]", httpJSON) })
+			}
+			paramsJSON, err := pinParams(ctx, method, paramsJSON, blockNumber)
+			if err != nil {
+				return "", err
+			}
+			return o.bounded(method, paramsJSON, blockNumber, func(paramsJSON string) (string, error) {
+				return o.cached(method, paramsJSON, blockNumber, func() (string, error) {
+					return o.run(ctx, urlsJSON, method, func() (string, error) { return poolCall(urlsJSON, method, paramsJSON, httpJSON) })
+				})
+			})
+		})
+	})
+}
+
+// call performs one native request, or an IPC request when url names a
+// socket. httpJSON carries HTTPOptions encoded for the native layer; ""
+// uses the default client. Arguments and result cross the boundary in a
+// pooled ffiArena.
+func call(url, method, paramsJSON, httpJSON string) (string, error) {
+	if path, ok := ipcPath(url); ok {
+		return ipcCall(path, method, paramsJSON, httpJSON)
+	}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	a := getArena()
+	defer a.release()
+	args := a.cstrings(url, method, paramsJSON, httpJSON)
+	n := C.chainrpc_call_into(args[0], args[1], args[2], optsArg(args[3], httpJSON), a.outPtr(), a.outCap())
+	return a.result(int64(n), takeResult)
+}
+
+func poolCall(urlsJSON, method, paramsJSON, httpJSON string) (string, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	a := getArena()
+	defer a.release()
+	args := a.cstrings(urlsJSON, method, paramsJSON, httpJSON)
+	n := C.chainrpc_pool_call_into(args[0], args[1], args[2], optsArg(args[3], httpJSON), a.outPtr(), a.outCap())
+	return a.result(int64(n), takeResult)
+}
 
-	ptr := C.chainrpc_pool_call(cURLs, cMethod, cParams)
-	if ptr == nil {
-		return "", lastError()
+// optsArg passes NULL for empty options, selecting the default client.
+func optsArg(p *C.char, httpJSON string) *C.char {
+	if httpJSON == "" {
+		return nil
 	}
-	defer C.chainrpc_free_string(ptr)
-	return C.GoString(ptr), nil
+	return p
+}
+
+func takeResult(out *C.char, n C.size_t) int64 {
+	return int64(C.chainrpc_take_result(out, n))
 }
@@ -1,4 +1,5 @@
 #pragma once
+#include <stddef.h>
 #include <stdint.h>
 
 #ifdef __cplusplus
@@ -29,6 +30,44 @@ char* chainrpc_call(const char* url, const char* method, const char* params_json
  */
 char* chainrpc_pool_call(const char* urls_json, const char* method, const char* params_json);
 
+/**
+ * Like chainrpc_call, with HTTP-level options as a JSON object:
+ *   {"headers":{"X-Api-Key":"..."},"bearer_token":"...","proxy":"socks5://host:port",
+ *    "ca_cert_pem":"-----BEGIN CERTIFICATE-----...","client_identity_pem":"...",
+ *    "timeout_ms":5000,"max_request_bytes":1048576,"max_retries":3}
+ * All fields are optional; max_retries 0 turns off the client's own
+ * retries of read-only methods. Caller frees with chainrpc_free_string().
+ */
+char* chainrpc_call_with_options(const char* url, const char* method,
+                                 const char* params_json, const char* options_json);
+
+/**
+ * Like chainrpc_pool_call, applying options_json to every provider.
+ * Caller frees with chainrpc_free_string().
+ */
+char* chainrpc_pool_call_with_options(const char* urls_json, const char* method,
+                                      const char* params_json, const char* options_json);
+
+/**
+ * Like chainrpc_call_with_options (options_json may be NULL), writing the
+ * result JSON into out instead of allocating: nothing to free. The result
+ * is not NUL-terminated. Returns its length, or -1 on error. A length
+ * above out_cap means nothing was written; fetch the result with
+ * chainrpc_take_result on the same thread.
+ */
+int64_t chainrpc_call_into(const char* url, const char* method, const char* params_json,
+                           const char* options_json, char* out, size_t out_cap);
+
+/** Pool counterpart of chainrpc_call_into. */
+int64_t chainrpc_pool_call_into(const char* urls_json, const char* method, const char* params_json,
+                                const char* options_json, char* out, size_t out_cap);
+
+/**
+ * Copy the result held by the last *_into call on this thread into out.
+ * Returns its length, or -1 if out_cap is too small.
+ */
+int64_t chainrpc_take_result(char* out, size_t out_cap);
+
 #ifdef __cplusplus
 }
 #endif
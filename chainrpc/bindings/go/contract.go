@@ -0,0 +1,72 @@
+package chainrpc
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/DarshanKumar89/chainfoundry/chaincodec/abi"
+)
+
+// CallContract calls a read-only contract function with eth_call and
+// decodes its return values.
+//
+// functionSig is a human-readable signature including return types, e.g.
+// "balanceOf(address)(uint256)" or "function name() view returns (string)".
+// Arguments and results use the Go types documented in package
+// chaincodec/abi: integers come back as *big.Int, addresses as abi.Address.
+// The call runs against the latest block, or the block pinned in ctx with
+// WithPinnedBlock. A reverting call returns a *RevertError.
+func CallContract(url, to, functionSig string, args ...interface{}) ([]interface{}, error) {
+	return CallContractContext(context.Background(), url, to, functionSig, args...)
+}
+
+// CallContractContext is like CallContract but honours ctx.
+func CallContractContext(ctx context.Context, url, to, functionSig string, args ...interface{}) ([]interface{}, error) {
+	return callContract(ctx, urlCaller(url), to, functionSig, args)
+}
+
+// CallContract is like the package-level CallContract but routes through
+// the pool.
+func (p *Pool) CallContract(ctx context.Context, to, functionSig string, args ...interface{}) ([]interface{}, error) {
+	return callContract(ctx, p.caller(), to, functionSig, args)
+}
+
+func callContract(ctx context.Context, call callFunc, to, functionSig string, args []interface{}) ([]interface{}, error) {
+	fn, err := abi.ParseFunction(functionSig)
+	if err != nil {
+		return nil, err
+	}
+	data, err := fn.EncodeCall(args...)
+	if err != nil {
+		return nil, err
+	}
+	params, err := json.Marshal([]interface{}{
+		map[string]string{"to": to, "data": "0x" + hex.EncodeToString(data)},
+		"latest",
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := call(ctx, "eth_call", string(params))
+	if err != nil {
+		if re, ok := AsRevert(err); ok {
+			return nil, re
+		}
+		return nil, err
+	}
+	var out string
+	if err := json.Unmarshal([]byte(res), &out); err != nil {
+		return nil, fmt.Errorf("chainrpc: eth_call result: %w", err)
+	}
+	ret, err := hex.DecodeString(strings.TrimPrefix(out, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("chainrpc: eth_call result: %w", err)
+	}
+	if len(ret) == 0 && len(fn.Outputs) > 0 {
+		return nil, fmt.Errorf("chainrpc: %s on %s returned no data (is it a contract?)", fn.Signature(), to)
+	}
+	return fn.DecodeOutput(ret)
+}
@@ -0,0 +1,183 @@
+package chainrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrQueryTooExpensive is matched (with errors.Is) by every *CostError.
+var ErrQueryTooExpensive = errors.New("chainrpc: query exceeds cost policy")
+
+// CostPolicy bounds ranged queries before they are sent, so one runaway
+// eth_getLogs or trace_filter cannot tie up a provider (or run up its
+// bill). A query spanning more blocks than its limit is rejected with a
+// *CostError or, with Split, sent as consecutive sub-range queries whose
+// results are concatenated. Zero limits are not enforced.
+//
+// Ranges ending at a tag ("latest", "safe", "finalized") are measured
+// against the current head, which costs an eth_blockNumber call when it
+// is not already known.
+type CostPolicy struct {
+	// MaxLogRange caps the blocks one eth_getLogs spans.
+	MaxLogRange uint64
+	// MaxUnfilteredLogRange caps eth_getLogs queries without an address
+	// filter, which providers must scan in full; usually far below
+	// MaxLogRange.
+	MaxUnfilteredLogRange uint64
+	// MaxTraceRange caps the blocks one trace_filter spans.
+	MaxTraceRange uint64
+	// Split sends an over-limit query in sub-ranges of the limit instead of
+	// rejecting it.
+	Split bool
+}
+
+// CostError reports a query rejected by a CostPolicy.
+type CostError struct {
+	Method string `json:"method"`
+	// Blocks is the estimated number of blocks the query spans.
+	Blocks uint64 `json:"blocks"`
+	// Limit is the policy limit it exceeds.
+	Limit uint64 `json:"limit"`
+}
+
+func (e *CostError) Error() string {
+	return fmt.Sprintf("chainrpc: %s spans %d blocks, cost policy allows %d", e.Method, e.Blocks, e.Limit)
+}
+
+func (e *CostError) Is(target error) bool { return target == ErrQueryTooExpensive }
+
+// WithCostPolicy applies c to the call, overriding PoolOption.CostPolicy.
+func WithCostPolicy(c CostPolicy) Option {
+	return func(o *callOptions) { o.cost = &c }
+}
+
+// rangeQuery is the block range of an eth_getLogs or trace_filter query.
+type rangeQuery struct {
+	params   []json.RawMessage
+	filter   map[string]json.RawMessage
+	from, to uint64
+	limit    uint64
+}
+
+// bounded enforces the call's cost policy on method and paramsJSON, then
+// sends the query with fn, split into sub-ranges if the policy says so.
+// blockNumber resolves tags against the head.
+func (o *callOptions) bounded(method, paramsJSON string, blockNumber func() (string, error), fn func(paramsJSON string) (string, error)) (string, error) {
+	if o.cost == nil || (method != "eth_getLogs" && method != "trace_filter") {
+		return fn(paramsJSON)
+	}
+	q, ok, err := o.cost.measure(method, paramsJSON, blockNumber)
+	if err != nil {
+		return "", err
+	}
+	if !ok || q.to < q.from || q.to-q.from < q.limit {
+		return fn(paramsJSON)
+	}
+	if !o.cost.Split {
+		return "", &CostError{Method: method, Blocks: q.to - q.from + 1, Limit: q.limit}
+	}
+	var all []json.RawMessage
+	for from := q.from; ; from += q.limit {
+		to := min(from+q.limit-1, q.to)
+		q.filter["fromBlock"] = json.RawMessage(strconv.Quote(fmt.Sprintf("0x%x", from)))
+		q.filter["toBlock"] = json.RawMessage(strconv.Quote(fmt.Sprintf("0x%x", to)))
+		q.params[0] = json.RawMessage(mustJSON(q.filter))
+		out, err := fn(mustJSON(q.params))
+		if err != nil {
+			return "", err
+		}
+		var page []json.RawMessage
+		if err := json.Unmarshal([]byte(out), &page); err != nil {
+			return "", fmt.Errorf("chainrpc: %s result: %w", method, err)
+		}
+		all = append(all, page...)
+		if to == q.to {
+			break
+		}
+	}
+	if all == nil {
+		all = []json.RawMessage{}
+	}
+	return mustJSON(all), nil
+}
+
+// measure returns the range of a query and the limit that applies to it,
+// or false if no limit does (or the query is by block hash).
+func (c *CostPolicy) measure(method, paramsJSON string, blockNumber func() (string, error)) (rangeQuery, bool, error) {
+	var q rangeQuery
+	if err := json.Unmarshal([]byte(paramsJSON), &q.params); err != nil || len(q.params) == 0 {
+		return q, false, nil // let the node report malformed params
+	}
+	if err := json.Unmarshal(q.params[0], &q.filter); err != nil || q.filter == nil {
+		return q, false, nil
+	}
+	if _, byHash := q.filter["blockHash"]; byHash {
+		return q, false, nil
+	}
+	switch {
+	case method == "trace_filter":
+		q.limit = c.MaxTraceRange
+	case !hasAddress(q.filter["address"]) && c.MaxUnfilteredLogRange > 0:
+		q.limit = c.MaxUnfilteredLogRange
+	default:
+		q.limit = c.MaxLogRange
+	}
+	if q.limit == 0 {
+		return q, false, nil
+	}
+	var head *uint64
+	bound := func(key string) (uint64, error) {
+		var s string
+		if raw, ok := q.filter[key]; ok && string(raw) != "null" {
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return 0, fmt.Errorf("chainrpc: %s %s: %w", method, key, err)
+			}
+		}
+		switch s {
+		case "earliest":
+			return 0, nil
+		case "", "latest", "pending", "safe", "finalized":
+			// Missing bounds default to latest. Tags below latest are
+			// measured from it too: a slight overestimate.
+			if head == nil {
+				res, err := blockNumber()
+				if err != nil {
+					return 0, err
+				}
+				n, err := parseQuantity(res)
+				if err != nil {
+					return 0, fmt.Errorf("chainrpc: eth_blockNumber: %w", err)
+				}
+				head = &n
+			}
+			return *head, nil
+		}
+		n, err := strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 64)
+		if err != nil {
+			return 0, fmt.Errorf("chainrpc: %s %s: invalid block %q", method, key, s)
+		}
+		return n, nil
+	}
+	var err error
+	if q.from, err = bound("fromBlock"); err != nil {
+		return q, false, err
+	}
+	if q.to, err = bound("toBlock"); err != nil {
+		return q, false, err
+	}
+	return q, true, nil
+}
+
+// hasAddress reports whether an eth_getLogs address criterion restricts
+// the query.
+func hasAddress(raw json.RawMessage) bool {
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		return one != ""
+	}
+	var many []string
+	return json.Unmarshal(raw, &many) == nil && len(many) > 0
+}
@@ -0,0 +1,222 @@
+package chainrpc
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/DarshanKumar89/chainfoundry/chaincodec/abi"
+)
+
+// ENSRegistry is the ENS registry address on Ethereum mainnet and its
+// testnets.
+const ENSRegistry = "0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e"
+
+var (
+	// ErrNameNotFound is returned when an ENS name has no resolver or no
+	// address, or an address has no primary name.
+	ErrNameNotFound = errors.New("chainrpc: ENS name not found")
+	// ErrOffchainLookup is returned when the resolver asks for an EIP-3668
+	// (CCIP-Read) gateway lookup, which is not supported.
+	ErrOffchainLookup = errors.New("chainrpc: ENS resolver requires an offchain lookup")
+)
+
+// extendedResolverID is the ENSIP-10 IExtendedResolver interface ID.
+var extendedResolverID = []byte{0x90, 0x61, 0xb9, 0x23}
+
+// offchainLookupSelector is OffchainLookup(address,string[],bytes,bytes4,bytes).
+const offchainLookupSelector = "0x556f1830"
+
+// Namehash returns the ENS namehash of name. Labels are lowercased but not
+// otherwise normalised: callers with non-ASCII names should apply ENSIP-15
+// normalisation first.
+func Namehash(name string) [32]byte {
+	var node [32]byte
+	if name == "" {
+		return node
+	}
+	labels := strings.Split(strings.ToLower(name), ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		copy(node[:], abi.Keccak256(node[:], abi.Keccak256([]byte(labels[i]))))
+	}
+	return node
+}
+
+// dnsEncode returns name in DNS wire format, as ENSIP-10 resolve() takes it.
+func dnsEncode(name string) ([]byte, error) {
+	var out []byte
+	for _, label := range strings.Split(strings.ToLower(name), ".") {
+		if len(label) == 0 || len(label) > 255 {
+			return nil, fmt.Errorf("chainrpc: invalid ENS name %q", name)
+		}
+		out = append(out, byte(len(label)))
+		out = append(out, label...)
+	}
+	return append(out, 0), nil
+}
+
+// ResolveName returns the address name resolves to, e.g.
+// ResolveName(url, "vitalik.eth"). It follows ENSIP-10: if name has no
+// resolver of its own the nearest parent's resolver is used, provided it
+// supports wildcard resolution.
+func ResolveName(url, name string) (string, error) {
+	return ResolveNameContext(context.Background(), url, name)
+}
+
+// ResolveNameContext is like ResolveName but honours ctx.
+func ResolveNameContext(ctx context.Context, url, name string) (string, error) {
+	return resolveName(ctx, urlCaller(url), name)
+}
+
+// ResolveName resolves an ENS name through the pool.
+func (p *Pool) ResolveName(ctx context.Context, name string) (string, error) {
+	return resolveName(ctx, p.caller(), name)
+}
+
+// LookupAddress returns the primary ENS name of addr. The name is only
+// returned if it resolves back to addr; otherwise the error is
+// ErrNameNotFound.
+func LookupAddress(url, addr string) (string, error) {
+	return LookupAddressContext(context.Background(), url, addr)
+}
+
+// LookupAddressContext is like LookupAddress but honours ctx.
+func LookupAddressContext(ctx context.Context, url, addr string) (string, error) {
+	return lookupAddress(ctx, urlCaller(url), addr)
+}
+
+// LookupAddress returns the primary ENS name of addr through the pool.
+func (p *Pool) LookupAddress(ctx context.Context, addr string) (string, error) {
+	return lookupAddress(ctx, p.caller(), addr)
+}
+
+func resolveName(ctx context.Context, call callFunc, name string) (string, error) {
+	if _, err := dnsEncode(name); err != nil {
+		return "", err
+	}
+	node := Namehash(name)
+	resolver, exact, err := findResolver(ctx, call, name)
+	if err != nil {
+		return "", err
+	}
+	extended, err := supportsInterface(ctx, call, resolver, extendedResolverID)
+	if err != nil {
+		return "", err
+	}
+	var addr abi.Address
+	switch {
+	case extended:
+		addr, err = resolveExtended(ctx, call, resolver, name, node)
+	case exact:
+		var out []interface{}
+		out, err = callContract(ctx, call, resolver, "addr(bytes32)(address)", []interface{}{node[:]})
+		if err == nil {
+			addr = out[0].(abi.Address)
+		}
+	default:
+		return "", fmt.Errorf("%w: %s has no resolver and its parent's does not support wildcards", ErrNameNotFound, name)
+	}
+	if err != nil {
+		return "", err
+	}
+	if addr == (abi.Address{}) {
+		return "", fmt.Errorf("%w: %s has no address", ErrNameNotFound, name)
+	}
+	return addr.Hex(), nil
+}
+
+// findResolver returns the resolver of name or, failing that, of its
+// nearest ancestor. exact reports whether it was set on name itself.
+func findResolver(ctx context.Context, call callFunc, name string) (resolver string, exact bool, err error) {
+	for current := strings.ToLower(name); current != ""; {
+		node := Namehash(current)
+		out, err := callContract(ctx, call, ENSRegistry, "resolver(bytes32)(address)", []interface{}{node[:]})
+		if err != nil {
+			return "", false, err
+		}
+		if a := out[0].(abi.Address); a != (abi.Address{}) {
+			return a.Hex(), current == strings.ToLower(name), nil
+		}
+		i := strings.IndexByte(current, '.')
+		if i < 0 {
+			break
+		}
+		current = current[i+1:]
+	}
+	return "", false, fmt.Errorf("%w: no resolver for %s", ErrNameNotFound, name)
+}
+
+func supportsInterface(ctx context.Context, call callFunc, contract string, id []byte) (bool, error) {
+	out, err := callContract(ctx, call, contract, "supportsInterface(bytes4)(bool)", []interface{}{id})
+	if err != nil {
+		// Resolvers predating ERC-165 revert rather than return false.
+		if _, ok := AsRevert(err); ok {
+			return false, nil
+		}
+		return false, err
+	}
+	return out[0].(bool), nil
+}
+
+// resolveExtended calls the ENSIP-10 resolve(bytes,bytes) with an inner
+// addr(bytes32) query.
+func resolveExtended(ctx context.Context, call callFunc, resolver, name string, node [32]byte) (abi.Address, error) {
+	var zero abi.Address
+	dns, _ := dnsEncode(name)
+	addrFn, _ := abi.ParseFunction("addr(bytes32)(address)")
+	inner, err := addrFn.EncodeCall(node[:])
+	if err != nil {
+		return zero, err
+	}
+	out, err := callContract(ctx, call, resolver, "resolve(bytes,bytes)(bytes)", []interface{}{dns, inner})
+	if err != nil {
+		if re, ok := AsRevert(err); ok && strings.HasPrefix(re.Data, offchainLookupSelector) {
+			return zero, fmt.Errorf("%w: %s", ErrOffchainLookup, name)
+		}
+		return zero, err
+	}
+	ret, err := addrFn.DecodeOutput(out[0].([]byte))
+	if err != nil {
+		return zero, fmt.Errorf("chainrpc: ENS resolve(%s): %w", name, err)
+	}
+	return ret[0].(abi.Address), nil
+}
+
+func lookupAddress(ctx context.Context, call callFunc, addr string) (string, error) {
+	a, err := abi.ParseAddress(addr)
+	if err != nil {
+		return "", err
+	}
+	reverse := hex.EncodeToString(a[:]) + ".addr.reverse"
+	resolver, exact, err := findResolver(ctx, call, reverse)
+	if err != nil {
+		return "", err
+	}
+	if !exact {
+		return "", fmt.Errorf("%w: %s has no reverse record", ErrNameNotFound, a.Hex())
+	}
+	node := Namehash(reverse)
+	out, err := callContract(ctx, call, resolver, "name(bytes32)(string)", []interface{}{node[:]})
+	if err != nil {
+		return "", err
+	}
+	name := out[0].(string)
+	if name == "" {
+		return "", fmt.Errorf("%w: %s has no reverse record", ErrNameNotFound, a.Hex())
+	}
+	// Anyone can claim any name in their reverse record; only trust it if
+	// the forward record agrees.
+	forward, err := resolveName(ctx, call, name)
+	if err != nil {
+		if errors.Is(err, ErrNameNotFound) {
+			return "", fmt.Errorf("%w: %s claims %s, which does not resolve back", ErrNameNotFound, a.Hex(), name)
+		}
+		return "", err
+	}
+	if !strings.EqualFold(forward, a.Hex()) {
+		return "", fmt.Errorf("%w: %s claims %s, which resolves to %s", ErrNameNotFound, a.Hex(), name, forward)
+	}
+	return name, nil
+}
@@ -0,0 +1,63 @@
+package chainrpc
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+)
+
+// CallMsg describes a call for EstimateGas, TraceCall, CallWithOverride and
+// SimulateV1. Empty fields are omitted.
+type CallMsg struct {
+	From  string
+	To    string
+	Data  string
+	Value *big.Int
+	Gas   uint64
+}
+
+func (m CallMsg) params() map[string]string {
+	p := make(map[string]string)
+	if m.From != "" {
+		p["from"] = m.From
+	}
+	if m.To != "" {
+		p["to"] = m.To
+	}
+	if m.Data != "" {
+		p["data"] = m.Data
+	}
+	if m.Value != nil {
+		p["value"] = fmt.Sprintf("0x%x", m.Value)
+	}
+	if m.Gas != 0 {
+		p["gas"] = fmt.Sprintf("0x%x", m.Gas)
+	}
+	return p
+}
+
+// EstimateGas runs eth_estimateGas for msg. If the call would revert the
+// error is a *RevertError carrying the decoded reason.
+func EstimateGas(ctx context.Context, url string, msg CallMsg) (uint64, error) {
+	return estimateGas(ctx, urlCaller(url), msg)
+}
+
+// EstimateGas runs eth_estimateGas through the pool.
+func (p *Pool) EstimateGas(ctx context.Context, msg CallMsg) (uint64, error) {
+	return estimateGas(ctx, p.caller(), msg)
+}
+
+func estimateGas(ctx context.Context, call callFunc, msg CallMsg) (uint64, error) {
+	res, err := call(ctx, "eth_estimateGas", mustJSON([]interface{}{msg.params()}))
+	if err != nil {
+		if re, ok := AsRevert(err); ok {
+			return 0, re
+		}
+		return 0, err
+	}
+	gas, err := parseQuantity(res)
+	if err != nil {
+		return 0, fmt.Errorf("chainrpc: eth_estimateGas: %w", err)
+	}
+	return gas, nil
+}
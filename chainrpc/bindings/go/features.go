@@ -0,0 +1,20 @@
+package chainrpc
+
+import "github.com/DarshanKumar89/chainfoundry/chainkit"
+
+// Features of this package, as listed by chainkit.Features.
+const (
+	// FeatureTracing gates WithTracer. It is experimental: tracing fails
+	// with a *chainkit.FeatureError until enabled with
+	// chainkit.EnableExperimental or the CHAINKIT_EXPERIMENTAL environment
+	// variable.
+	FeatureTracing = "tracing"
+	// FeatureIPC names calls and subscriptions over IPC sockets. It is
+	// beta, so always on.
+	FeatureIPC = "ipc"
+)
+
+func init() {
+	chainkit.RegisterFeature(FeatureTracing, chainkit.Experimental, "chainrpc: spans around RPC calls (WithTracer)")
+	chainkit.RegisterFeature(FeatureIPC, chainkit.Beta, "chainrpc: calls and subscriptions over local IPC sockets")
+}
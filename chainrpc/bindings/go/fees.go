@@ -0,0 +1,357 @@
+package chainrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// feeHistoryBlocks and feePercentiles are the eth_feeHistory window used by
+// SuggestFees.
+const feeHistoryBlocks = 20
+
+var feePercentiles = []float64{10, 50, 90}
+
+// PriorityFee is the median priority fee paid at a reward percentile over
+// the sampled blocks.
+type PriorityFee struct {
+	Percentile float64  `json:"percentile"`
+	Fee        *big.Int `json:"fee"`
+}
+
+// FeeSuggestion is an EIP-1559 fee recommendation. All amounts are in wei.
+type FeeSuggestion struct {
+	// BaseFee is the base fee of the next block.
+	BaseFee *big.Int `json:"baseFee"`
+	// PriorityFees holds the 10th, 50th and 90th percentile tips.
+	PriorityFees []PriorityFee `json:"priorityFees"`
+	// MaxPriorityFeePerGas is the recommended tip (the median percentile).
+	MaxPriorityFeePerGas *big.Int `json:"maxPriorityFeePerGas"`
+	// MaxFeePerGas is 2 × BaseFee + MaxPriorityFeePerGas, which stays valid
+	// through six consecutive full blocks.
+	MaxFeePerGas *big.Int `json:"maxFeePerGas"`
+	// OldestBlock is the first block of the sampled window.
+	OldestBlock uint64 `json:"oldestBlock"`
+	// Blocks is the number of blocks sampled.
+	Blocks int `json:"blocks"`
+}
+
+// SuggestFees samples recent blocks with eth_feeHistory and returns a fee
+// recommendation for the next block.
+func SuggestFees(url string) (*FeeSuggestion, error) {
+	return SuggestFeesContext(context.Background(), url)
+}
+
+// SuggestFeesContext is like SuggestFees but honours ctx.
+func SuggestFeesContext(ctx context.Context, url string) (*FeeSuggestion, error) {
+	return suggestFees(ctx, urlCaller(url))
+}
+
+// SuggestFees returns a fee recommendation using the pool.
+func (p *Pool) SuggestFees(ctx context.Context) (*FeeSuggestion, error) {
+	return suggestFees(ctx, p.caller())
+}
+
+// FeeHistoryResult is a typed eth_feeHistory result. All amounts are in wei.
+type FeeHistoryResult struct {
+	// OldestBlock is the first block in the window.
+	OldestBlock uint64 `json:"oldestBlock"`
+	// BaseFees has one entry per block plus a final entry projecting the
+	// base fee of the block after the window.
+	BaseFees []*big.Int `json:"baseFees"`
+	// GasUsedRatios is gasUsed/gasLimit for each block.
+	GasUsedRatios []float64 `json:"gasUsedRatios"`
+	// Percentiles are the reward percentiles that were requested.
+	Percentiles []float64 `json:"percentiles"`
+	// Rewards[i][j] is the priority fee at Percentiles[j] in block
+	// OldestBlock+i.
+	Rewards [][]*big.Int `json:"rewards"`
+}
+
+// Blocks returns the number of blocks in the window.
+func (h *FeeHistoryResult) Blocks() int { return len(h.GasUsedRatios) }
+
+// NextBaseFee returns the projected base fee of the block after the
+// window.
+func (h *FeeHistoryResult) NextBaseFee() *big.Int {
+	if len(h.BaseFees) == 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(h.BaseFees[len(h.BaseFees)-1])
+}
+
+// BaseFeeEMA returns the exponential moving average of the base fees in
+// the window, oldest first, with smoothing 2/(span+1). span <= 0 uses the
+// window length.
+func (h *FeeHistoryResult) BaseFeeEMA(span int) *big.Int {
+	if len(h.BaseFees) == 0 {
+		return new(big.Int)
+	}
+	if span <= 0 {
+		span = len(h.BaseFees)
+	}
+	alpha := 2 / float64(span+1)
+	ema, _ := new(big.Float).SetInt(h.BaseFees[0]).Float64()
+	for _, b := range h.BaseFees[1:] {
+		f, _ := new(big.Float).SetInt(b).Float64()
+		ema = alpha*f + (1-alpha)*ema
+	}
+	out, _ := big.NewFloat(ema).Int(nil)
+	return out
+}
+
+// RewardsAt returns the priority fee of every block in the window at
+// percentile, which must be one of the requested Percentiles.
+func (h *FeeHistoryResult) RewardsAt(percentile float64) ([]*big.Int, bool) {
+	col := -1
+	for j, p := range h.Percentiles {
+		if p == percentile {
+			col = j
+			break
+		}
+	}
+	if col < 0 {
+		return nil, false
+	}
+	out := make([]*big.Int, 0, len(h.Rewards))
+	for _, row := range h.Rewards {
+		if col < len(row) {
+			out = append(out, row[col])
+		}
+	}
+	return out, true
+}
+
+// RewardSMA returns the simple moving average of the priority fee at
+// percentile over the last window non-empty blocks (all of them if
+// window <= 0). Empty blocks report zero rewards and are skipped. It
+// returns false if percentile was not requested or every block was empty.
+func (h *FeeHistoryResult) RewardSMA(percentile float64, window int) (*big.Int, bool) {
+	rewards, ok := h.RewardsAt(percentile)
+	if !ok {
+		return nil, false
+	}
+	var samples []*big.Int
+	for b := len(rewards) - 1; b >= 0 && (window <= 0 || len(samples) < window); b-- {
+		if b < len(h.GasUsedRatios) && h.GasUsedRatios[b] == 0 {
+			continue
+		}
+		samples = append(samples, rewards[b])
+	}
+	if len(samples) == 0 {
+		return nil, false
+	}
+	return mean(samples), true
+}
+
+// BaseFeeSMA returns the simple moving average of the last window base
+// fees, including the projected next one (all of them if window <= 0).
+func (h *FeeHistoryResult) BaseFeeSMA(window int) *big.Int {
+	fees := h.BaseFees
+	if window > 0 && window < len(fees) {
+		fees = fees[len(fees)-window:]
+	}
+	return mean(fees)
+}
+
+// Recommend returns a fee recommendation tipping the moving average of
+// the priority fee at percentile over the last window non-empty blocks,
+// and capping the fee at twice the larger of the next base fee and its
+// moving average over the same window plus the tip. It returns false if
+// percentile was not requested or the window holds only empty blocks.
+func (h *FeeHistoryResult) Recommend(percentile float64, window int) (*FeeSuggestion, bool) {
+	tip, ok := h.RewardSMA(percentile, window)
+	if !ok {
+		return nil, false
+	}
+	base := h.NextBaseFee()
+	ceiling := base
+	if avg := h.BaseFeeSMA(window); avg.Cmp(ceiling) > 0 {
+		ceiling = avg
+	}
+	return &FeeSuggestion{
+		BaseFee:              base,
+		PriorityFees:         []PriorityFee{{Percentile: percentile, Fee: tip}},
+		MaxPriorityFeePerGas: tip,
+		MaxFeePerGas:         new(big.Int).Add(new(big.Int).Mul(ceiling, big.NewInt(2)), tip),
+		OldestBlock:          h.OldestBlock,
+		Blocks:               h.Blocks(),
+	}, true
+}
+
+// SuggestedWait estimates how long until the base fee drops to target,
+// extrapolating the window's average per-block base fee change. It
+// returns 0 if the next block already qualifies and false if the base fee
+// is not trending down. The estimate is never shorter than the protocol
+// allows: the base fee falls at most 12.5% per block.
+func (h *FeeHistoryResult) SuggestedWait(target *big.Int, blockTime time.Duration) (time.Duration, bool) {
+	next := h.NextBaseFee()
+	if next.Cmp(target) <= 0 {
+		return 0, true
+	}
+	if target.Sign() <= 0 || len(h.BaseFees) < 2 {
+		return 0, false
+	}
+	first, _ := new(big.Float).SetInt(h.BaseFees[0]).Float64()
+	last, _ := new(big.Float).SetInt(next).Float64()
+	want, _ := new(big.Float).SetInt(target).Float64()
+	if first <= 0 {
+		return 0, false
+	}
+	// Geometric mean of the per-block change, capped at the fastest
+	// possible decline.
+	rate := math.Pow(last/first, 1/float64(len(h.BaseFees)-1))
+	rate = math.Max(rate, 1-maxBaseFeeChange)
+	if rate >= 1 {
+		return 0, false
+	}
+	blocks := math.Ceil(math.Log(want/last) / math.Log(rate))
+	return time.Duration(blocks) * blockTime, true
+}
+
+// maxBaseFeeChange is the EIP-1559 per-block base fee adjustment bound.
+const maxBaseFeeChange = 0.125
+
+// FeeHistory returns the fee history of the last blocks blocks with
+// priority fees sampled at percentiles (each 0–100, ascending).
+func FeeHistory(url string, blocks int, percentiles []float64) (*FeeHistoryResult, error) {
+	return FeeHistoryContext(context.Background(), url, blocks, percentiles)
+}
+
+// FeeHistoryContext is like FeeHistory but honours ctx.
+func FeeHistoryContext(ctx context.Context, url string, blocks int, percentiles []float64) (*FeeHistoryResult, error) {
+	return feeHistory(ctx, urlCaller(url), blocks, percentiles)
+}
+
+// FeeHistory returns the fee history of the last blocks blocks using the
+// pool.
+func (p *Pool) FeeHistory(ctx context.Context, blocks int, percentiles []float64) (*FeeHistoryResult, error) {
+	return feeHistory(ctx, p.caller(), blocks, percentiles)
+}
+
+type rawFeeHistory struct {
+	OldestBlock   string     `json:"oldestBlock"`
+	BaseFeePerGas []string   `json:"baseFeePerGas"`
+	GasUsedRatio  []float64  `json:"gasUsedRatio"`
+	Reward        [][]string `json:"reward"`
+}
+
+func feeHistory(ctx context.Context, call callFunc, blocks int, percentiles []float64) (*FeeHistoryResult, error) {
+	if blocks <= 0 {
+		return nil, fmt.Errorf("chainrpc: fee history needs at least one block")
+	}
+	for i, p := range percentiles {
+		if p < 0 || p > 100 || i > 0 && p < percentiles[i-1] {
+			return nil, fmt.Errorf("chainrpc: fee history percentiles must be ascending within [0, 100]")
+		}
+	}
+	if percentiles == nil {
+		percentiles = []float64{}
+	}
+	params := mustJSON([]interface{}{fmt.Sprintf("0x%x", blocks), "latest", percentiles})
+	res, err := call(ctx, "eth_feeHistory", params)
+	if err != nil {
+		return nil, err
+	}
+	var raw rawFeeHistory
+	if err := json.Unmarshal([]byte(res), &raw); err != nil {
+		return nil, fmt.Errorf("chainrpc: eth_feeHistory result: %w", err)
+	}
+	if len(raw.BaseFeePerGas) == 0 {
+		return nil, fmt.Errorf("chainrpc: eth_feeHistory returned no base fees (pre-London chain?)")
+	}
+	h := &FeeHistoryResult{GasUsedRatios: raw.GasUsedRatio, Percentiles: percentiles}
+	if h.OldestBlock, err = hexQuantity(raw.OldestBlock); err != nil {
+		return nil, fmt.Errorf("chainrpc: eth_feeHistory oldestBlock: %w", err)
+	}
+	for _, b := range raw.BaseFeePerGas {
+		v, err := hexBig(b)
+		if err != nil {
+			return nil, fmt.Errorf("chainrpc: eth_feeHistory baseFeePerGas: %w", err)
+		}
+		h.BaseFees = append(h.BaseFees, v)
+	}
+	for _, rewards := range raw.Reward {
+		row := make([]*big.Int, len(rewards))
+		for i, r := range rewards {
+			if row[i], err = hexBig(r); err != nil {
+				return nil, fmt.Errorf("chainrpc: eth_feeHistory reward: %w", err)
+			}
+		}
+		h.Rewards = append(h.Rewards, row)
+	}
+	return h, nil
+}
+
+func suggestFees(ctx context.Context, call callFunc) (*FeeSuggestion, error) {
+	h, err := feeHistory(ctx, call, feeHistoryBlocks, feePercentiles)
+	if err != nil {
+		return nil, err
+	}
+	// The last base fee is the one projected for the next block.
+	base := h.NextBaseFee()
+
+	s := &FeeSuggestion{BaseFee: base, OldestBlock: h.OldestBlock, Blocks: len(h.Rewards)}
+	for i, pct := range feePercentiles {
+		var samples []*big.Int
+		for b, rewards := range h.Rewards {
+			// Empty blocks report zero rewards that say nothing about demand.
+			if b < len(h.GasUsedRatios) && h.GasUsedRatios[b] == 0 || i >= len(rewards) {
+				continue
+			}
+			samples = append(samples, rewards[i])
+		}
+		s.PriorityFees = append(s.PriorityFees, PriorityFee{Percentile: pct, Fee: median(samples)})
+	}
+
+	tip := s.PriorityFees[1].Fee
+	if tip.Sign() == 0 {
+		// No non-empty blocks in the window: ask the node instead.
+		if res, err := call(ctx, "eth_maxPriorityFeePerGas", "[]"); err == nil {
+			var q string
+			if json.Unmarshal([]byte(res), &q) == nil {
+				if v, err := hexBig(q); err == nil {
+					tip = v
+				}
+			}
+		}
+	}
+	s.MaxPriorityFeePerGas = tip
+	s.MaxFeePerGas = new(big.Int).Add(new(big.Int).Mul(base, big.NewInt(2)), tip)
+	return s, nil
+}
+
+func hexBig(s string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(strings.TrimPrefix(s, "0x"), 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid hex quantity %q", s)
+	}
+	return n, nil
+}
+
+// mean returns the integer mean of xs, or zero for an empty slice.
+func mean(xs []*big.Int) *big.Int {
+	sum := new(big.Int)
+	if len(xs) == 0 {
+		return sum
+	}
+	for _, x := range xs {
+		sum.Add(sum, x)
+	}
+	return sum.Quo(sum, big.NewInt(int64(len(xs))))
+}
+
+// median returns the median of xs, or zero for an empty slice. xs is
+// reordered.
+func median(xs []*big.Int) *big.Int {
+	if len(xs) == 0 {
+		return new(big.Int)
+	}
+	sort.Slice(xs, func(i, j int) bool { return xs[i].Cmp(xs[j]) < 0 })
+	return new(big.Int).Set(xs[len(xs)/2])
+}
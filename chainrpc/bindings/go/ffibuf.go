@@ -0,0 +1,140 @@
+package chainrpc
+
+/*
+#include "chainrpc.h"
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// Sizes for pooled FFI buffers. Results up to initialResultBuf need no
+// second trip across the boundary; buffers grown past maxPooledBuf by an
+// outsized call are freed rather than kept in the pool.
+const (
+	initialResultBuf = 16 << 10
+	maxPooledBuf     = 1 << 20
+)
+
+// cbuf is a C allocation that grows but is never moved while in use.
+type cbuf struct {
+	p unsafe.Pointer
+	n int
+}
+
+func (b *cbuf) ensure(n int) {
+	if b.n >= n {
+		return
+	}
+	C.free(b.p)
+	size := max(n, 2*b.n, 256)
+	b.p, b.n = C.malloc(C.size_t(size)), size
+}
+
+func (b *cbuf) free() {
+	C.free(b.p)
+	b.p, b.n = nil, 0
+}
+
+// ffiArena is the C memory for one native call: its string arguments
+// packed into one allocation, and a buffer the native side writes the
+// result into. Arenas are pooled, so a steady stream of calls reuses the
+// same memory instead of a malloc and free per argument and a native
+// string per result.
+type ffiArena struct {
+	args cbuf
+	out  cbuf
+	ptrs []*C.char
+}
+
+var arenas = sync.Pool{New: func() any {
+	a := &ffiArena{}
+	a.out.ensure(initialResultBuf)
+	// The pool drops idle arenas at GC without telling us.
+	runtime.SetFinalizer(a, (*ffiArena).free)
+	return a
+}}
+
+func getArena() *ffiArena { return arenas.Get().(*ffiArena) }
+
+// release returns a to the pool. Pointers from a must not be used after.
+func (a *ffiArena) release() {
+	if a.args.n > maxPooledBuf {
+		a.args.free()
+	}
+	if a.out.n > maxPooledBuf {
+		a.out.free()
+		a.out.ensure(initialResultBuf)
+	}
+	arenas.Put(a)
+}
+
+func (a *ffiArena) free() {
+	a.args.free()
+	a.out.free()
+}
+
+// cstrings copies ss into the arena as NUL-terminated C strings. Like
+// C.CString, a string with an embedded NUL is cut short at it. The
+// returned slice is reused by the next call.
+func (a *ffiArena) cstrings(ss ...string) []*C.char {
+	total := 0
+	for _, s := range ss {
+		total += len(s) + 1
+	}
+	a.args.ensure(total)
+	buf := unsafe.Slice((*byte)(a.args.p), total)
+	a.ptrs = a.ptrs[:0]
+	off := 0
+	for _, s := range ss {
+		copy(buf[off:], s)
+		buf[off+len(s)] = 0
+		a.ptrs = append(a.ptrs, (*C.char)(unsafe.Pointer(&buf[off])))
+		off += len(s) + 1
+	}
+	return a.ptrs
+}
+
+// outPtr and outCap describe the result buffer to the native side.
+func (a *ffiArena) outPtr() *C.char  { return (*C.char)(a.out.p) }
+func (a *ffiArena) outCap() C.size_t { return C.size_t(a.out.n) }
+
+// result turns the length an *_into call returned into the result string,
+// growing the buffer and collecting the held result with take when it did
+// not fit. The calling goroutine must still be locked to the OS thread of
+// the call.
+func (a *ffiArena) result(n int64, take func(out *C.char, cap C.size_t) int64) (string, error) {
+	if n < 0 {
+		return "", lastError()
+	}
+	if int(n) > a.out.n {
+		a.out.ensure(int(n))
+		if take(a.outPtr(), a.outCap()) < 0 {
+			return "", lastError()
+		}
+	}
+	return C.GoStringN(a.outPtr(), C.int(n)), nil
+}
+
+// callUnpooled is call without an arena, for HTTP URLs and the default
+// client: a C.CString per argument and a native string per result, freed
+// after C.GoString copies it. BenchmarkCall measures the arena against it.
+func callUnpooled(url, method, paramsJSON string) (string, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	cURL := C.CString(url)
+	defer C.free(unsafe.Pointer(cURL))
+	cMethod := C.CString(method)
+	defer C.free(unsafe.Pointer(cMethod))
+	cParams := C.CString(paramsJSON)
+	defer C.free(unsafe.Pointer(cParams))
+	ptr := C.chainrpc_call(cURL, cMethod, cParams)
+	if ptr == nil {
+		return "", lastError()
+	}
+	defer C.chainrpc_free_string(ptr)
+	return C.GoString(ptr), nil
+}
@@ -0,0 +1,56 @@
+package chainrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// benchServer answers every JSON-RPC request with a hex string result of
+// size bytes.
+func benchServer(size int) *httptest.Server {
+	result := `"0x` + strings.Repeat("ab", size) + `"`
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+	}))
+}
+
+// BenchmarkCall compares call's pooled arena with a C.CString per argument
+// and C.GoString of a native result, for results below and above the
+// arena's initial result buffer.
+func BenchmarkCall(b *testing.B) {
+	paths := []struct {
+		name string
+		call func(url, method, paramsJSON string) (string, error)
+	}{
+		{"pooled", func(url, method, paramsJSON string) (string, error) {
+			return call(url, method, paramsJSON, "")
+		}},
+		{"cstring", callUnpooled},
+	}
+	for _, size := range []int{32, 4 << 10, 64 << 10} {
+		srv := benchServer(size)
+		for _, p := range paths {
+			b.Run(fmt.Sprintf("%s/bytes=%d", p.name, size), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := p.call(srv.URL, "eth_getCode", `["0x0","latest"]`); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+		srv.Close()
+	}
+}
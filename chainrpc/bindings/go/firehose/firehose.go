@@ -0,0 +1,452 @@
+// Package firehose streams blocks from a StreamingFast Firehose endpoint
+// (sf.firehose.v2.Stream over gRPC) as an alternative to polling JSON-RPC.
+// Ethereum blocks are normalized into the chainrpc.Header,
+// chainrpc.Transaction and chainrpc.Log values the JSON-RPC path decodes,
+// so an indexer can switch transports without touching its handlers.
+//
+// gRPC is spoken directly over net/http's HTTP/2 client, so endpoints
+// must be TLS ("host:443" or "https://host"); plaintext h2c is not
+// supported. Streams resume from their cursor after a dropped connection,
+// so no block is missed or delivered twice.
+package firehose
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DarshanKumar89/chainfoundry/chainkit"
+	"github.com/DarshanKumar89/chainfoundry/chainrpc"
+)
+
+// Feature names the package in chainkit.Features. It is beta, so always
+// on.
+const Feature = "firehose"
+
+func init() {
+	chainkit.RegisterFeature(Feature, chainkit.Beta, "chainrpc/firehose: block streaming over Firehose gRPC")
+}
+
+// maxMessageBytes bounds one streamed block.
+const maxMessageBytes = 256 << 20
+
+// Step is where a block stands in the stream's view of the chain.
+type Step int
+
+const (
+	// StepNew is a block added to the chain.
+	StepNew Step = 1
+	// StepUndo reverts a block previously sent as new: a reorg dropped it.
+	StepUndo Step = 2
+	// StepFinal is a block that became final (with FinalBlocksOnly, every
+	// block).
+	StepFinal Step = 3
+)
+
+func (s Step) String() string {
+	switch s {
+	case StepNew:
+		return "new"
+	case StepUndo:
+		return "undo"
+	case StepFinal:
+		return "final"
+	}
+	return "Step(" + strconv.Itoa(int(s)) + ")"
+}
+
+// Request selects the blocks a stream sends.
+type Request struct {
+	// StartBlock is the first block; negative values count back from the
+	// head (-1 starts at the head). Ignored when Cursor is set.
+	StartBlock int64
+	// StopBlock is the last block, inclusive; zero streams forever.
+	StopBlock uint64
+	// Cursor resumes a previous stream after its last received block.
+	Cursor string
+	// FinalBlocksOnly sends only final blocks, so there are no undos.
+	FinalBlocksOnly bool
+}
+
+// Block is one streamed block.
+type Block struct {
+	Step Step
+	// Cursor resumes the stream after this block; persist it alongside
+	// what was done with the block.
+	Cursor string
+	// TypeURL is the protobuf type of Raw. Header, Transactions and Logs
+	// are only filled for Ethereum blocks.
+	TypeURL string
+	Header  chainrpc.Header
+	// Transactions are the block's transactions in order.
+	Transactions []chainrpc.Transaction
+	// Logs are the logs of the block's successful transactions in order.
+	// On StepUndo they have Removed set, as a subscription reports logs
+	// dropped by a reorg.
+	Logs []chainrpc.Log
+	// Raw is the encoded block, e.g. an sf.ethereum.type.v2.Block.
+	Raw []byte
+}
+
+// FilterLogs returns the block's logs matching f's addresses and topics;
+// f's block range is ignored.
+func (b *Block) FilterLogs(f chainrpc.LogFilter) []chainrpc.Log {
+	var out []chainrpc.Log
+	for _, l := range b.Logs {
+		if matches(f, l) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func matches(f chainrpc.LogFilter, l chainrpc.Log) bool {
+	if len(f.Addresses) > 0 && !containsFold(f.Addresses, l.Address) {
+		return false
+	}
+	for i, alts := range f.Topics {
+		if len(alts) == 0 {
+			continue
+		}
+		if i >= len(l.Topics) || !containsFold(alts, l.Topics[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Error is a gRPC status the endpoint ended a stream with.
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("firehose: grpc status %d: %s", e.Code, e.Message)
+}
+
+// gRPC status codes a stream does not resume after.
+const (
+	codeInvalidArgument  = 3
+	codeNotFound         = 5
+	codePermissionDenied = 7
+	codeUnimplemented    = 12
+	codeUnauthenticated  = 16
+)
+
+func permanent(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	switch e.Code {
+	case codeInvalidArgument, codeNotFound, codePermissionDenied, codeUnimplemented, codeUnauthenticated:
+		return true
+	}
+	return false
+}
+
+// Options configures a Client.
+type Options struct {
+	// HTTP supplies authentication headers (StreamingFast endpoints take
+	// BearerToken) and TLS settings.
+	HTTP *chainrpc.HTTPOptions
+	// APIKey is sent as the x-api-key header.
+	APIKey string
+	// Reconnect sets the delay before each reconnection; its MaxAttempts
+	// bounds consecutive failed attempts. Default: 500ms doubling up to
+	// 30s with 20% jitter, no limit.
+	Reconnect *chainrpc.RetryPolicy
+	// NoReconnect ends the stream when the connection drops.
+	NoReconnect bool
+}
+
+// Client opens Firehose streams to one endpoint.
+type Client struct {
+	url  string
+	opts Options
+	http *http.Client
+}
+
+// New returns a client for endpoint, e.g. "mainnet.eth.streamingfast.io:443".
+func New(endpoint string, opts Options) (*Client, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("firehose: endpoint: %w", err)
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("firehose: endpoint %s: only TLS endpoints are supported", endpoint)
+	}
+	t := &http.Transport{ForceAttemptHTTP2: true, Proxy: http.ProxyFromEnvironment}
+	if h := opts.HTTP; h != nil {
+		if h.Proxy != "" {
+			p, err := url.Parse(h.Proxy)
+			if err != nil {
+				return nil, fmt.Errorf("firehose: proxy: %w", err)
+			}
+			t.Proxy = http.ProxyURL(p)
+		}
+		if len(h.CACertPEM) > 0 || len(h.ClientCertPEM) > 0 {
+			cfg := &tls.Config{}
+			if len(h.CACertPEM) > 0 {
+				pool, err := x509.SystemCertPool()
+				if err != nil {
+					pool = x509.NewCertPool()
+				}
+				if !pool.AppendCertsFromPEM(h.CACertPEM) {
+					return nil, fmt.Errorf("firehose: CACertPEM holds no certificates")
+				}
+				cfg.RootCAs = pool
+			}
+			if len(h.ClientCertPEM) > 0 {
+				cert, err := tls.X509KeyPair(h.ClientCertPEM, h.ClientKeyPEM)
+				if err != nil {
+					return nil, fmt.Errorf("firehose: client certificate: %w", err)
+				}
+				cfg.Certificates = []tls.Certificate{cert}
+			}
+			t.TLSClientConfig = cfg
+		}
+	}
+	return &Client{url: strings.TrimSuffix(u.String(), "/"), opts: opts, http: &http.Client{Transport: t}}, nil
+}
+
+// Stream delivers the blocks of one request.
+type Stream struct {
+	c      *Client
+	req    Request
+	policy chainrpc.RetryPolicy
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	body     io.ReadCloser
+	resp     *http.Response
+	cursor   string
+	failures int
+}
+
+// Blocks opens a stream for req. Read it with Recv and Close it when done.
+func (c *Client) Blocks(ctx context.Context, req Request) (*Stream, error) {
+	policy := chainrpc.RetryPolicy{InitialBackoff: 500 * time.Millisecond, MaxBackoff: 30 * time.Second, Multiplier: 2, Jitter: 0.2}
+	if c.opts.Reconnect != nil {
+		policy = *c.opts.Reconnect
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Stream{c: c, req: req, policy: policy, ctx: ctx, cancel: cancel, cursor: req.Cursor}
+	if err := s.open(); err != nil {
+		cancel()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Cursor returns the cursor of the last block received.
+func (s *Stream) Cursor() string { return s.cursor }
+
+// Close ends the stream.
+func (s *Stream) Close() error {
+	s.cancel()
+	if s.body != nil {
+		s.body.Close()
+		s.body = nil
+	}
+	return nil
+}
+
+// Recv returns the next block. It returns io.EOF after StopBlock, and
+// reconnects from the last cursor when the connection drops.
+func (s *Stream) Recv() (*Block, error) {
+	for {
+		if s.body == nil {
+			if err := s.open(); err != nil {
+				if rerr := s.backoff(err); rerr != nil {
+					return nil, rerr
+				}
+				continue
+			}
+		}
+		msg, err := s.read()
+		if err == nil {
+			s.failures = 0
+			b, err := decodeResponse(msg)
+			if err != nil {
+				return nil, err
+			}
+			s.cursor = b.Cursor
+			return b, nil
+		}
+		s.body.Close()
+		s.body = nil
+		if err == io.EOF {
+			if err := trailerStatus(s.resp.Trailer); err != nil {
+				if rerr := s.backoff(err); rerr != nil {
+					return nil, rerr
+				}
+				continue
+			}
+			return nil, io.EOF
+		}
+		if rerr := s.backoff(err); rerr != nil {
+			return nil, rerr
+		}
+	}
+}
+
+// backoff waits before a reconnection, or returns the error that ends the
+// stream.
+func (s *Stream) backoff(err error) error {
+	if ctxErr := s.ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	if s.c.opts.NoReconnect || permanent(err) {
+		return err
+	}
+	s.failures++
+	if s.policy.MaxAttempts > 0 && s.failures >= s.policy.MaxAttempts {
+		return fmt.Errorf("firehose: giving up after %d attempts: %w", s.failures, err)
+	}
+	select {
+	case <-time.After(s.policy.Backoff(s.failures)):
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// open sends the request, resuming from the last cursor.
+func (s *Stream) open() error {
+	req := s.req
+	req.Cursor = s.cursor
+	payload := req.encode()
+	frame := make([]byte, 5, 5+len(payload))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	frame = append(frame, payload...)
+
+	hr, err := http.NewRequestWithContext(s.ctx, http.MethodPost, s.c.url+"/sf.firehose.v2.Stream/Blocks", bytes.NewReader(frame))
+	if err != nil {
+		return err
+	}
+	hr.Header.Set("Content-Type", "application/grpc")
+	hr.Header.Set("TE", "trailers")
+	if h := s.c.opts.HTTP; h != nil {
+		for k, v := range h.Headers {
+			hr.Header.Set(k, v)
+		}
+		switch {
+		case h.Username != "":
+			hr.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(h.Username+":"+h.Password)))
+		case h.BearerToken != "":
+			hr.Header.Set("Authorization", "Bearer "+h.BearerToken)
+		}
+	}
+	if s.c.opts.APIKey != "" {
+		hr.Header.Set("X-Api-Key", s.c.opts.APIKey)
+	}
+	resp, err := s.c.http.Do(hr)
+	if err != nil {
+		return fmt.Errorf("firehose: %w", err)
+	}
+	if resp.ProtoMajor != 2 {
+		resp.Body.Close()
+		return &Error{Code: codeUnimplemented, Message: "endpoint did not negotiate HTTP/2"}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return fmt.Errorf("firehose: HTTP %d", resp.StatusCode)
+	}
+	// A stream refused outright carries its status in the headers.
+	if err := trailerStatus(resp.Header); err != nil {
+		resp.Body.Close()
+		return err
+	}
+	s.resp, s.body = resp, resp.Body
+	return nil
+}
+
+// read returns the next length-prefixed gRPC message.
+func (s *Stream) read() ([]byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(s.body, hdr[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("firehose: %w", err)
+		}
+		return nil, err
+	}
+	if hdr[0] != 0 {
+		return nil, fmt.Errorf("firehose: compressed messages are not supported")
+	}
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if n > maxMessageBytes {
+		return nil, fmt.Errorf("firehose: %d-byte message exceeds the %d-byte limit", n, maxMessageBytes)
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(s.body, msg); err != nil {
+		return nil, fmt.Errorf("firehose: %w", err)
+	}
+	return msg, nil
+}
+
+// trailerStatus returns the gRPC status in h as an error, or nil for OK
+// or no status.
+func trailerStatus(h http.Header) error {
+	code := h.Get("Grpc-Status")
+	if code == "" || code == "0" {
+		return nil
+	}
+	n, err := strconv.Atoi(code)
+	if err != nil {
+		return fmt.Errorf("firehose: invalid grpc-status %q", code)
+	}
+	msg, err := url.PathUnescape(h.Get("Grpc-Message"))
+	if err != nil {
+		msg = h.Get("Grpc-Message")
+	}
+	return &Error{Code: n, Message: msg}
+}
+
+// decodeResponse decodes an sf.firehose.v2.Response.
+func decodeResponse(msg []byte) (*Block, error) {
+	m, err := parseMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	anyBlock, err := m.sub(1)
+	if err != nil {
+		return nil, fmt.Errorf("firehose: response block: %w", err)
+	}
+	b := &Block{
+		Step:    Step(m.uint(6)),
+		Cursor:  m.string(10),
+		TypeURL: anyBlock.string(1),
+		Raw:     anyBlock.bytes(2),
+	}
+	if b.TypeURL == ethereumBlock {
+		if err := b.normalize(b.Raw); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,302 @@
+package firehose
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/DarshanKumar89/chainfoundry/chainrpc"
+)
+
+// Just enough of the protobuf wire format to encode a Request and decode
+// a Response and sf.ethereum.type.v2.Block, without generated code.
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("firehose: truncated protobuf message")
+
+// field is one decoded protobuf field.
+type field struct {
+	num   int
+	wire  int
+	value uint64 // varint and fixed values
+	bytes []byte // length-delimited values
+}
+
+// message holds a message's fields by number, repeated fields in order.
+type message map[int][]field
+
+func parseMessage(b []byte) (message, error) {
+	m := message{}
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errTruncated
+		}
+		b = b[n:]
+		f := field{num: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case wireVarint:
+			f.value, n = binary.Uvarint(b)
+			if n <= 0 {
+				return nil, errTruncated
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return nil, errTruncated
+			}
+			f.value, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return nil, errTruncated
+			}
+			f.value, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return nil, errTruncated
+			}
+			f.bytes, b = b[n:n+int(l)], b[n+int(l):]
+		default:
+			return nil, fmt.Errorf("firehose: unsupported protobuf wire type %d", f.wire)
+		}
+		m[f.num] = append(m[f.num], f)
+	}
+	return m, nil
+}
+
+// last returns the last occurrence of field num, which wins for
+// non-repeated fields.
+func (m message) last(num int) (field, bool) {
+	fs := m[num]
+	if len(fs) == 0 {
+		return field{}, false
+	}
+	return fs[len(fs)-1], true
+}
+
+func (m message) uint(num int) uint64 {
+	f, _ := m.last(num)
+	return f.value
+}
+
+func (m message) bytes(num int) []byte {
+	f, _ := m.last(num)
+	return f.bytes
+}
+
+func (m message) string(num int) string { return string(m.bytes(num)) }
+
+func (m message) sub(num int) (message, error) {
+	f, ok := m.last(num)
+	if !ok {
+		return message{}, nil
+	}
+	return parseMessage(f.bytes)
+}
+
+// bigInt decodes an sf.ethereum.type.v2.BigInt (big-endian bytes in field
+// 1), or nil if the field is absent.
+func (m message) bigInt(num int) (*big.Int, error) {
+	f, ok := m.last(num)
+	if !ok {
+		return nil, nil
+	}
+	b, err := parseMessage(f.bytes)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b.bytes(1)), nil
+}
+
+func appendVarint(b []byte, v uint64) []byte { return binary.AppendUvarint(b, v) }
+
+func appendTag(b []byte, num, wire int) []byte {
+	return appendVarint(b, uint64(num)<<3|uint64(wire))
+}
+
+func appendString(b []byte, num int, s string) []byte {
+	b = appendTag(b, num, wireBytes)
+	b = appendVarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// encode renders r as an sf.firehose.v2.Request.
+func (r Request) encode() []byte {
+	var b []byte
+	if r.StartBlock != 0 {
+		b = appendTag(b, 1, wireVarint)
+		b = appendVarint(b, uint64(r.StartBlock))
+	}
+	if r.Cursor != "" {
+		b = appendString(b, 2, r.Cursor)
+	}
+	if r.StopBlock != 0 {
+		b = appendTag(b, 3, wireVarint)
+		b = appendVarint(b, r.StopBlock)
+	}
+	if r.FinalBlocksOnly {
+		b = appendTag(b, 4, wireVarint)
+		b = appendVarint(b, 1)
+	}
+	return b
+}
+
+// ethereumBlock is the type URL of Ethereum Firehose blocks.
+const ethereumBlock = "type.googleapis.com/sf.ethereum.type.v2.Block"
+
+func hexBytes(b []byte) string { return fmt.Sprintf("0x%x", b) }
+
+func hexUint(n uint64) string { return fmt.Sprintf("0x%x", n) }
+
+func hexBig(n *big.Int) interface{} {
+	if n == nil {
+		return nil
+	}
+	return "0x" + n.Text(16)
+}
+
+// normalize fills b's Header, Transactions and Logs from an
+// sf.ethereum.type.v2.Block, by way of the JSON a node returns for the same
+// block so they decode exactly as on the JSON-RPC path.
+func (b *Block) normalize(raw []byte) error {
+	blk, err := parseMessage(raw)
+	if err != nil {
+		return err
+	}
+	h, err := blk.sub(5)
+	if err != nil {
+		return fmt.Errorf("firehose: block header: %w", err)
+	}
+	hash := hexBytes(blk.bytes(2))
+	number := blk.uint(3)
+	ts, err := h.sub(12)
+	if err != nil {
+		return fmt.Errorf("firehose: block timestamp: %w", err)
+	}
+	header := map[string]interface{}{
+		"number":           hexUint(number),
+		"hash":             hash,
+		"parentHash":       hexBytes(h.bytes(1)),
+		"sha3Uncles":       hexBytes(h.bytes(2)),
+		"miner":            hexBytes(h.bytes(3)),
+		"stateRoot":        hexBytes(h.bytes(4)),
+		"transactionsRoot": hexBytes(h.bytes(5)),
+		"receiptsRoot":     hexBytes(h.bytes(6)),
+		"logsBloom":        hexBytes(h.bytes(7)),
+		"gasLimit":         hexUint(h.uint(10)),
+		"gasUsed":          hexUint(h.uint(11)),
+		"timestamp":        hexUint(ts.uint(1)),
+		"extraData":        hexBytes(h.bytes(13)),
+		"mixHash":          hexBytes(h.bytes(14)),
+		"nonce":            fmt.Sprintf("0x%016x", h.uint(15)),
+		"size":             hexUint(blk.uint(4)),
+	}
+	for key, num := range map[string]int{"difficulty": 8, "totalDifficulty": 17, "baseFeePerGas": 18} {
+		v, err := h.bigInt(num)
+		if err != nil {
+			return fmt.Errorf("firehose: block %s: %w", key, err)
+		}
+		if v != nil {
+			header[key] = hexBig(v)
+		}
+	}
+	if r := h.bytes(19); len(r) > 0 {
+		header["withdrawalsRoot"] = hexBytes(r)
+	}
+	if _, ok := h.last(22); ok {
+		header["blobGasUsed"] = hexUint(h.uint(22))
+		header["excessBlobGas"] = hexUint(h.uint(23))
+	}
+	if r := h.bytes(24); len(r) > 0 {
+		header["parentBeaconBlockRoot"] = hexBytes(r)
+	}
+	hj, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(hj, &b.Header); err != nil {
+		return fmt.Errorf("firehose: block header: %w", err)
+	}
+
+	b.Transactions = b.Transactions[:0]
+	b.Logs = b.Logs[:0]
+	for _, f := range blk[10] {
+		trace, err := parseMessage(f.bytes)
+		if err != nil {
+			return fmt.Errorf("firehose: transaction: %w", err)
+		}
+		tx := map[string]interface{}{
+			"hash":             hexBytes(trace.bytes(21)),
+			"from":             hexBytes(trace.bytes(22)),
+			"to":               nil,
+			"nonce":            hexUint(trace.uint(2)),
+			"gas":              hexUint(trace.uint(4)),
+			"input":            hexBytes(trace.bytes(6)),
+			"type":             hexUint(trace.uint(12)),
+			"v":                hexBytes(trace.bytes(7)),
+			"r":                hexBytes(trace.bytes(8)),
+			"s":                hexBytes(trace.bytes(9)),
+			"blockNumber":      hexUint(number),
+			"blockHash":        hash,
+			"transactionIndex": hexUint(trace.uint(20)),
+			"value":            "0x0",
+		}
+		if to := trace.bytes(1); len(to) > 0 {
+			tx["to"] = hexBytes(to)
+		}
+		for key, num := range map[string]int{"gasPrice": 3, "value": 5, "maxFeePerGas": 11, "maxPriorityFeePerGas": 13} {
+			v, err := trace.bigInt(num)
+			if err != nil {
+				return fmt.Errorf("firehose: transaction %s: %w", key, err)
+			}
+			if v != nil {
+				tx[key] = hexBig(v)
+			}
+		}
+		tj, err := json.Marshal(tx)
+		if err != nil {
+			return err
+		}
+		var t chainrpc.Transaction
+		if err := json.Unmarshal(tj, &t); err != nil {
+			return fmt.Errorf("firehose: transaction: %w", err)
+		}
+		b.Transactions = append(b.Transactions, t)
+
+		receipt, err := trace.sub(31)
+		if err != nil {
+			return fmt.Errorf("firehose: receipt: %w", err)
+		}
+		for _, lf := range receipt[4] {
+			l, err := parseMessage(lf.bytes)
+			if err != nil {
+				return fmt.Errorf("firehose: log: %w", err)
+			}
+			topics := make([]string, 0, len(l[2]))
+			for _, t := range l[2] {
+				topics = append(topics, hexBytes(t.bytes))
+			}
+			b.Logs = append(b.Logs, chainrpc.Log{
+				Address:          hexBytes(l.bytes(1)),
+				Topics:           topics,
+				Data:             hexBytes(l.bytes(3)),
+				BlockNumber:      hexUint(number),
+				BlockHash:        hash,
+				TransactionHash:  tx["hash"].(string),
+				TransactionIndex: tx["transactionIndex"].(string),
+				LogIndex:         hexUint(l.uint(6)),
+				Removed:          b.Step == StepUndo,
+			})
+		}
+	}
+	return nil
+}
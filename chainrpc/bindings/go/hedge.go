@@ -0,0 +1,145 @@
+package chainrpc
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HedgePolicy makes a pool send a read request to a second provider when
+// the first has not answered within Delay, and return whichever answers
+// first. It trades a little extra load for a shorter latency tail when a
+// provider is slow rather than down.
+//
+// The native call of the slower provider cannot be interrupted: its result
+// is discarded and it still counts against that provider's rate limit and
+// stats. Writes (eth_sendRawTransaction and the like) and calls tied to
+// one node's state (filters, subscriptions) are never hedged.
+type HedgePolicy struct {
+	// Delay is how long the first provider has before the second is asked.
+	// Zero uses the first provider's recent p90 latency, so only its
+	// slowest tenth of calls is hedged.
+	Delay time.Duration
+	// MinDelay bounds an adaptive Delay from below. Zero means 20ms.
+	MinDelay time.Duration
+}
+
+// defaultHedgeDelay is used with an adaptive Delay until the provider has
+// latency samples.
+const defaultHedgeDelay = 250 * time.Millisecond
+
+// WithHedge hedges the call according to p, overriding PoolOption.Hedge.
+// It only affects calls through a Pool.
+func WithHedge(p HedgePolicy) Option {
+	return func(o *callOptions) { o.hedge = &p }
+}
+
+// hedgeDelay returns the delay before hedging method, and false if the
+// call is not hedged.
+func (o *callOptions) hedgeDelay(p *Pool, method string, order []*provider) (time.Duration, bool) {
+	h := o.hedge
+	if h == nil {
+		h = p.opts.Hedge
+	}
+	if h == nil || len(order) < 2 || !hedgeable(method) {
+		return 0, false
+	}
+	if h.Delay > 0 {
+		return h.Delay, true
+	}
+	d := order[0].stats.latency(0.90)
+	if d == 0 {
+		d = defaultHedgeDelay
+	}
+	min := h.MinDelay
+	if min <= 0 {
+		min = 20 * time.Millisecond
+	}
+	return max(d, min), true
+}
+
+// hedgeable reports whether sending method to two providers is harmless.
+func hedgeable(method string) bool {
+	for _, prefix := range []string{"eth_send", "eth_sign", "eth_submit", "personal_", "eth_subscribe", "eth_unsubscribe"} {
+		if strings.HasPrefix(method, prefix) {
+			return false
+		}
+	}
+	switch method {
+	case "eth_newFilter", "eth_newBlockFilter", "eth_newPendingTransactionFilter",
+		"eth_getFilterChanges", "eth_getFilterLogs", "eth_uninstallFilter":
+		return false
+	}
+	return true
+}
+
+// hedgedPass is pass with hedging: providers are tried in order as usual,
+// but once delay passes without an answer the next provider is asked as
+// well. Failures still fail over, so at most two requests are in flight.
+func (p *Pool) hedgedPass(ctx context.Context, o *callOptions, route *Route, order []*provider, method, paramsJSON string, archive bool, delay time.Duration) (string, error) {
+	// Cancelling releases legs still waiting on a rate limiter.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan attemptResult, len(order))
+	next, inflight := 0, 0
+	launch := func() {
+		pr := order[next]
+		next++
+		inflight++
+		// Each leg counts its own attempts.
+		leg := *o
+		leg.attempts = 0
+		go func() {
+			r := p.try(ctx, &leg, pr, method, paramsJSON, archive)
+			r.attempts = leg.attempts
+			results <- r
+		}()
+	}
+	launch()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	hedged := false
+
+	var f failures
+	for inflight > 0 {
+		var fire <-chan time.Time
+		if !hedged && next < len(order) {
+			fire = timer.C
+		}
+		select {
+		case <-fire:
+			hedged = true
+			route.Hedged = true
+			launch()
+		case r := <-results:
+			inflight--
+			o.attempts += r.attempts
+			if r.final {
+				if r.answered {
+					route.Provider = r.pr.url
+					o.servedBy = r.pr.url
+				}
+				return r.out, r.err
+			}
+			f.skipped(route, r)
+			if next < len(order) {
+				launch()
+			}
+		}
+	}
+	return "", f.err()
+}
+
+// latency returns the q-th percentile of the provider's recent latencies,
+// zero without samples.
+func (s *providerStats) latency(q float64) time.Duration {
+	s.mu.Lock()
+	n := min(s.nLatency, latencyWindow)
+	samples := make([]time.Duration, n)
+	copy(samples, s.latencies[:n])
+	s.mu.Unlock()
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return percentile(samples, q)
+}
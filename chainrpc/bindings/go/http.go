@@ -0,0 +1,162 @@
+package chainrpc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// HTTPOptions configures the HTTP transport used for a call.
+type HTTPOptions struct {
+	// Headers are added to every request, e.g. {"X-Api-Key": "..."}.
+	Headers map[string]string
+	// BearerToken is sent as "Authorization: Bearer <token>".
+	BearerToken string
+	// Username and Password, when Username is set, are sent as HTTP Basic
+	// authentication, which Bitcoin Core's RPC server requires.
+	Username string
+	Password string
+	// Proxy is an HTTP(S) or SOCKS5 proxy URL, e.g. "socks5://127.0.0.1:1080".
+	Proxy string
+	// CACertPEM holds extra PEM-encoded root certificates to trust.
+	CACertPEM []byte
+	// ClientCertPEM and ClientKeyPEM enable mutual TLS.
+	ClientCertPEM []byte
+	ClientKeyPEM  []byte
+	// Timeout bounds each HTTP request. Zero keeps the native default (30s).
+	Timeout time.Duration
+	// MaxRequestBytes caps the body of one HTTP request; larger batches
+	// are split. Zero keeps the native default (1 MiB), negative disables
+	// the limit.
+	MaxRequestBytes int
+
+	// Connections to a URL are pooled and kept alive across calls made
+	// with the same options. The fields below tune that pool.
+
+	// MaxIdleConnsPerHost caps the idle connections kept open per host.
+	// Zero keeps the default (unlimited natively, 2 for REST calls);
+	// negative keeps none, so every call opens a new connection.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout closes pooled connections left idle this long. Zero
+	// keeps the default (90s).
+	IdleConnTimeout time.Duration
+	// TCPKeepAlive is the interval of TCP keep-alive probes on pooled
+	// connections. Zero keeps the default; negative disables them.
+	TCPKeepAlive time.Duration
+	// DisableHTTP2 restricts connections to HTTP/1.1. By default HTTP/2 is
+	// negotiated with TLS endpoints that offer it, multiplexing concurrent
+	// calls over one connection.
+	DisableHTTP2 bool
+}
+
+// LoadCACert reads a PEM file into CACertPEM.
+func (h *HTTPOptions) LoadCACert(path string) error {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	h.CACertPEM = append(h.CACertPEM, pem...)
+	return nil
+}
+
+// WithHTTPOptions sends the call with the given HTTP-level options.
+func WithHTTPOptions(h HTTPOptions) Option {
+	return func(o *callOptions) { o.http = &h }
+}
+
+// WithBasicAuth adds HTTP Basic credentials to the call's HTTP options,
+// keeping any set by an earlier WithHTTPOptions.
+func WithBasicAuth(username, password string) Option {
+	return func(o *callOptions) {
+		h := HTTPOptions{}
+		if o.http != nil {
+			h = *o.http
+		}
+		h.Username, h.Password = username, password
+		o.http = &h
+	}
+}
+
+// authorization returns the Authorization header value for h, if any.
+func (h *HTTPOptions) authorization() string {
+	if h.Username != "" {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(h.Username+":"+h.Password))
+	}
+	if h.BearerToken != "" {
+		return "Bearer " + h.BearerToken
+	}
+	return ""
+}
+
+// encode renders h as the options_json object understood by the native
+// *_with_options calls. noRetry turns off the native client's own retries.
+func (h *HTTPOptions) encode(noRetry bool) (string, error) {
+	if (len(h.ClientCertPEM) == 0) != (len(h.ClientKeyPEM) == 0) {
+		return "", fmt.Errorf("chainrpc: ClientCertPEM and ClientKeyPEM must be set together")
+	}
+	v := struct {
+		Headers           map[string]string `json:"headers,omitempty"`
+		BearerToken       string            `json:"bearer_token,omitempty"`
+		Proxy             string            `json:"proxy,omitempty"`
+		CACertPEM         string            `json:"ca_cert_pem,omitempty"`
+		ClientIdentityPEM string            `json:"client_identity_pem,omitempty"`
+		TimeoutMs         int64             `json:"timeout_ms,omitempty"`
+		MaxRequestBytes   *int              `json:"max_request_bytes,omitempty"`
+		MaxIdlePerHost    *int              `json:"max_idle_per_host,omitempty"`
+		IdleTimeoutMs     int64             `json:"idle_timeout_ms,omitempty"`
+		KeepAliveMs       *int64            `json:"tcp_keepalive_ms,omitempty"`
+		HTTP1Only         bool              `json:"http1_only,omitempty"`
+		MaxRetries        *int              `json:"max_retries,omitempty"`
+	}{
+		Headers:       h.Headers,
+		BearerToken:   h.BearerToken,
+		Proxy:         h.Proxy,
+		CACertPEM:     string(h.CACertPEM),
+		TimeoutMs:     h.Timeout.Milliseconds(),
+		IdleTimeoutMs: h.IdleConnTimeout.Milliseconds(),
+		HTTP1Only:     h.DisableHTTP2,
+	}
+	if h.Username != "" {
+		v.Headers = make(map[string]string, len(h.Headers)+1)
+		for k, val := range h.Headers {
+			v.Headers[k] = val
+		}
+		v.Headers["Authorization"] = h.authorization()
+		v.BearerToken = ""
+	}
+	if h.MaxRequestBytes != 0 {
+		n := max(h.MaxRequestBytes, 0)
+		v.MaxRequestBytes = &n
+	}
+	if h.MaxIdleConnsPerHost != 0 {
+		n := max(h.MaxIdleConnsPerHost, 0)
+		v.MaxIdlePerHost = &n
+	}
+	if h.TCPKeepAlive != 0 {
+		ms := max(h.TCPKeepAlive.Milliseconds(), 0)
+		v.KeepAliveMs = &ms
+	}
+	if noRetry {
+		v.MaxRetries = new(int)
+	}
+	if len(h.ClientCertPEM) > 0 {
+		v.ClientIdentityPEM = string(h.ClientCertPEM) + "\n" + string(h.ClientKeyPEM)
+	}
+	out, err := json.Marshal(v)
+	return string(out), err
+}
+
+// encodeHTTP returns the options_json for h, or "" for the native
+// defaults when h is nil. With a Go retry policy the native client's own
+// retries are turned off, so the policy alone decides the attempts.
+func encodeHTTP(h *HTTPOptions, retry *RetryPolicy) (string, error) {
+	if h == nil {
+		if retry == nil {
+			return "", nil
+		}
+		h = &HTTPOptions{}
+	}
+	return h.encode(retry != nil)
+}
@@ -0,0 +1,176 @@
+package chainrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// IPC endpoints are local geth/reth/erigon sockets, given to Call, NewPool
+// and friends in place of an HTTP URL as either a plain filesystem path
+// ("/var/lib/geth/geth.ipc") or an ipc:// or unix:// URL
+// ("ipc:///var/lib/geth/geth.ipc"). They are served in Go over a unix
+// domain socket; the native pool call (PoolCall) only accepts HTTP URLs.
+// IPC is beta (FeatureIPC): always on, though its behaviour may still be
+// refined.
+
+// ipcTimeout bounds one IPC round trip unless HTTPOptions.Timeout is set,
+// matching the native HTTP default.
+const ipcTimeout = 30 * time.Second
+
+// ipcIdleConns is the number of idle connections kept per socket.
+const ipcIdleConns = 4
+
+// ipcPath returns the socket path if endpoint names an IPC socket: an
+// ipc:// or unix:// URL, or a string without a scheme that looks like a
+// path. HTTP and WebSocket URLs never do, whatever their path.
+func ipcPath(endpoint string) (string, bool) {
+	for _, scheme := range []string{"ipc://", "unix://"} {
+		if strings.HasPrefix(endpoint, scheme) {
+			return strings.TrimPrefix(endpoint, scheme), true
+		}
+	}
+	if strings.Contains(endpoint, "://") {
+		return "", false
+	}
+	if strings.HasPrefix(endpoint, "/") || strings.HasPrefix(endpoint, "./") || strings.HasSuffix(endpoint, ".ipc") {
+		return endpoint, true
+	}
+	return "", false
+}
+
+var (
+	ipcMu      sync.Mutex
+	ipcClients = map[string]*ipcClient{}
+	ipcNextID  uint64
+)
+
+// ipcClient keeps a few idle connections to one socket so back-to-back
+// calls skip the dial.
+type ipcClient struct {
+	path string
+	idle chan net.Conn
+}
+
+func ipcClientFor(path string) *ipcClient {
+	ipcMu.Lock()
+	defer ipcMu.Unlock()
+	c, ok := ipcClients[path]
+	if !ok {
+		c = &ipcClient{path: path, idle: make(chan net.Conn, ipcIdleConns)}
+		ipcClients[path] = c
+	}
+	return c
+}
+
+func (c *ipcClient) get(timeout time.Duration) (net.Conn, error) {
+	select {
+	case conn := <-c.idle:
+		return conn, nil
+	default:
+		return net.DialTimeout("unix", c.path, timeout)
+	}
+}
+
+func (c *ipcClient) put(conn net.Conn) {
+	select {
+	case c.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// ipcCall sends one JSON-RPC request over the socket at path and returns
+// the result JSON, with errors formatted like the native transport's.
+func ipcCall(path, method, paramsJSON, httpJSON string) (string, error) {
+	if !json.Valid([]byte(paramsJSON)) {
+		return "", fmt.Errorf("params parse: invalid JSON")
+	}
+	timeout := ipcTimeout
+	if httpJSON != "" {
+		var o struct {
+			TimeoutMs int64 `json:"timeout_ms"`
+		}
+		if json.Unmarshal([]byte(httpJSON), &o) == nil && o.TimeoutMs > 0 {
+			timeout = time.Duration(o.TimeoutMs) * time.Millisecond
+		}
+	}
+	id := atomic.AddUint64(&ipcNextID, 1)
+	req := mustJSON(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      uint64          `json:"id"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params"`
+	}{"2.0", id, method, json.RawMessage(paramsJSON)})
+
+	c := ipcClientFor(path)
+	conn, err := c.get(timeout)
+	if err != nil {
+		return "", fmt.Errorf("IPC error: %w", err)
+	}
+	resp, err := ipcRoundTrip(conn, []byte(req), id, timeout)
+	if err != nil {
+		// The stream may hold a partial response; never reuse it.
+		conn.Close()
+		return "", fmt.Errorf("IPC error: %w", err)
+	}
+	c.put(conn)
+
+	if err := resp.rpcError(); err != nil {
+		return "", err
+	}
+	if len(resp.Result) == 0 {
+		return "null", nil
+	}
+	var out bytes.Buffer
+	if err := json.Compact(&out, resp.Result); err != nil {
+		return "", fmt.Errorf("IPC error: %w", err)
+	}
+	return out.String(), nil
+}
+
+type ipcResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	} `json:"error"`
+}
+
+// rpcError returns the response's JSON-RPC error, or nil.
+func (r *ipcResponse) rpcError() error {
+	if r.Error == nil {
+		return nil
+	}
+	return newRPCError(r.Error.Code, r.Error.Message, r.Error.Data)
+}
+
+// ipcRoundTrip writes req and reads JSON values until the response with
+// the matching id arrives. Nodes send no other messages on a connection
+// without subscriptions, so mismatches only come from a misbehaving peer.
+func ipcRoundTrip(conn net.Conn, req []byte, id uint64, timeout time.Duration) (*ipcResponse, error) {
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(conn)
+	want := fmt.Sprint(id)
+	for {
+		var resp ipcResponse
+		if err := dec.Decode(&resp); err != nil {
+			return nil, err
+		}
+		if string(resp.ID) == want {
+			return &resp, nil
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package chainrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Log is a raw EVM log as returned by eth_getLogs. Quantities are
+// hex-encoded.
+type Log struct {
+	Address          string   `json:"address"`
+	Topics           []string `json:"topics"`
+	Data             string   `json:"data"`
+	BlockNumber      string   `json:"blockNumber"`
+	BlockHash        string   `json:"blockHash"`
+	TransactionHash  string   `json:"transactionHash"`
+	TransactionIndex string   `json:"transactionIndex"`
+	LogIndex         string   `json:"logIndex"`
+	Removed          bool     `json:"removed"`
+}
+
+// LogFilter selects logs in the inclusive block range [FromBlock, ToBlock].
+// Topics are positional; each position lists alternatives and an empty
+// position matches anything.
+type LogFilter struct {
+	FromBlock uint64
+	ToBlock   uint64
+	Addresses []string
+	Topics    [][]string
+}
+
+func (f LogFilter) params(from, to uint64) string {
+	q := f.criteria()
+	q["fromBlock"] = fmt.Sprintf("0x%x", from)
+	q["toBlock"] = fmt.Sprintf("0x%x", to)
+	return mustJSON([]interface{}{q})
+}
+
+// criteria is the address and topic part of the filter, as eth_getLogs and
+// eth_subscribe("logs") take it.
+func (f LogFilter) criteria() map[string]interface{} {
+	q := map[string]interface{}{}
+	if len(f.Addresses) > 0 {
+		q["address"] = f.Addresses
+	}
+	if len(f.Topics) > 0 {
+		topics := make([]interface{}, len(f.Topics))
+		for i, alts := range f.Topics {
+			if len(alts) > 0 {
+				topics[i] = alts
+			}
+		}
+		q["topics"] = topics
+	}
+	return q
+}
+
+// LogPage is one sub-range of a paged eth_getLogs query. A page with Err
+// set is the last one sent.
+type LogPage struct {
+	FromBlock uint64
+	ToBlock   uint64
+	Logs      []Log
+	Err       error
+}
+
+// GetLogsPaged fetches logs for filter in ranges of at most maxRangeSize
+// blocks and streams them, in block order, to the returned channel, which
+// is closed when the range is done, on error, or when ctx is cancelled.
+//
+// When a provider rejects a range as too large (result-count or block-range
+// limits) the range is bisected and later ranges use the smaller size.
+// A maxRangeSize of 0 means 2000 blocks.
+func GetLogsPaged(ctx context.Context, url string, filter LogFilter, maxRangeSize uint64) <-chan LogPage {
+	return getLogsPaged(ctx, urlCaller(url), filter, maxRangeSize)
+}
+
+// GetLogsPaged is like the package-level GetLogsPaged but routes through
+// the pool.
+func (p *Pool) GetLogsPaged(ctx context.Context, filter LogFilter, maxRangeSize uint64) <-chan LogPage {
+	return getLogsPaged(ctx, p.caller(), filter, maxRangeSize)
+}
+
+func getLogsPaged(ctx context.Context, call callFunc, filter LogFilter, maxRangeSize uint64) <-chan LogPage {
+	if maxRangeSize == 0 {
+		maxRangeSize = 2000
+	}
+	out := make(chan LogPage)
+	go func() {
+		defer close(out)
+		send := func(p LogPage) bool {
+			select {
+			case out <- p:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if filter.FromBlock > filter.ToBlock {
+			send(LogPage{FromBlock: filter.FromBlock, ToBlock: filter.ToBlock,
+				Err: fmt.Errorf("chainrpc: invalid log range %d-%d", filter.FromBlock, filter.ToBlock)})
+			return
+		}
+		size := maxRangeSize
+		for from := filter.FromBlock; from <= filter.ToBlock; {
+			to := filter.ToBlock
+			if to-from >= size {
+				to = from + size - 1
+			}
+			logs, err := call(ctx, "eth_getLogs", filter.params(from, to))
+			if err != nil && isRangeTooLarge(err) && to > from {
+				// Halve the window and retry from the same block.
+				size = (to - from + 1) / 2
+				continue
+			}
+			if err != nil {
+				send(LogPage{FromBlock: from, ToBlock: to, Err: err})
+				return
+			}
+			var page []Log
+			if err := json.Unmarshal([]byte(logs), &page); err != nil {
+				send(LogPage{FromBlock: from, ToBlock: to, Err: fmt.Errorf("chainrpc: eth_getLogs result: %w", err)})
+				return
+			}
+			if !send(LogPage{FromBlock: from, ToBlock: to, Logs: page}) {
+				return
+			}
+			if to == filter.ToBlock {
+				return
+			}
+			from = to + 1
+		}
+	}()
+	return out
+}
+
+// rangeTooLargeMarkers are substrings providers use when an eth_getLogs
+// query exceeds their result or block-range limits.
+var rangeTooLargeMarkers = []string{
+	"query returned more than",
+	"response size exceeded",
+	"response size should not",
+	"log response size exceeded",
+	"too many results",
+	"more than 10000 results",
+	"block range",
+	"range too large",
+	"range is too large",
+	"exceed maximum block range",
+	"query timeout exceeded",
+}
+
+func isRangeTooLarge(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, m := range rangeTooLargeMarkers {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
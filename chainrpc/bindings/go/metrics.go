@@ -0,0 +1,168 @@
+package chainrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DarshanKumar89/chainfoundry/chainkit/promtext"
+)
+
+// MetricsRecorder receives one observation per request attempt. Metrics
+// implements it; implement it over collectors in an existing Prometheus
+// registry to record into that registry instead.
+type MetricsRecorder interface {
+	ObserveRequest(method, provider, status string, latency time.Duration)
+}
+
+// Status labels reported to a MetricsRecorder.
+const (
+	StatusOK              = "ok"
+	StatusRPCError        = "rpc_error"
+	StatusTransportError  = "transport_error"
+	StatusRateLimited     = "rate_limited"
+	StatusInvalidResponse = "invalid_response"
+	StatusCancelled       = "cancelled"
+)
+
+// ErrorClass maps an error returned by this package to a status label.
+func ErrorClass(err error) string {
+	var verr *ValidationError
+	switch {
+	case err == nil:
+		return StatusOK
+	case errors.Is(err, ErrRateLimited):
+		return StatusRateLimited
+	case errors.As(err, &verr):
+		return StatusInvalidResponse
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return StatusCancelled
+	}
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "rate limit") || strings.Contains(msg, "http 429"):
+		return StatusRateLimited
+	case strings.HasPrefix(msg, "json-rpc "):
+		return StatusRPCError
+	}
+	return StatusTransportError
+}
+
+// WithMetrics reports every attempt of the call to m.
+func WithMetrics(m MetricsRecorder) Option {
+	return func(o *callOptions) { o.metrics = m }
+}
+
+// DefaultLatencyBuckets are the histogram upper bounds, in seconds.
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metrics is a dependency-free MetricsRecorder that renders the Prometheus
+// text exposition format with chainkit/promtext. Mount it next to an
+// existing /metrics endpoint, or Register it with a promtext.Registry to
+// serve it with other collectors.
+//
+// Exposed series:
+//
+//	chainrpc_requests_total{method,provider,status}
+//	chainrpc_request_duration_seconds{method,provider} (histogram)
+type Metrics struct {
+	buckets []float64
+
+	mu       sync.Mutex
+	counters map[[3]string]uint64
+	hists    map[[2]string]*promtext.Histogram
+}
+
+// NewMetrics returns an empty registry using DefaultLatencyBuckets when
+// buckets is nil.
+func NewMetrics(buckets []float64) *Metrics {
+	return &Metrics{
+		buckets:  promtext.Buckets(buckets, DefaultLatencyBuckets),
+		counters: make(map[[3]string]uint64),
+		hists:    make(map[[2]string]*promtext.Histogram),
+	}
+}
+
+// ObserveRequest implements MetricsRecorder.
+func (m *Metrics) ObserveRequest(method, provider, status string, latency time.Duration) {
+	provider = ProviderLabel(provider)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters[[3]string{method, provider, status}]++
+	hk := [2]string{method, provider}
+	h := m.hists[hk]
+	if h == nil {
+		h = promtext.NewHistogram(m.buckets)
+		m.hists[hk] = h
+	}
+	h.Observe(latency.Seconds())
+}
+
+// Register adds m to reg, to be exported with its other collectors.
+func (m *Metrics) Register(reg promtext.Registerer) error { return reg.Register(m) }
+
+// WriteTo writes all series in Prometheus text format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	var pw promtext.Writer
+	m.mu.Lock()
+	ckeys := make([][3]string, 0, len(m.counters))
+	for k := range m.counters {
+		ckeys = append(ckeys, k)
+	}
+	sort.Slice(ckeys, func(i, j int) bool { return lessKeys(ckeys[i][:], ckeys[j][:]) })
+	pw.Family("chainrpc_requests_total", "counter", "JSON-RPC request attempts by method, provider and status.")
+	for _, k := range ckeys {
+		pw.Sample("chainrpc_requests_total", m.counters[k], "method", k[0], "provider", k[1], "status", k[2])
+	}
+
+	hkeys := make([][2]string, 0, len(m.hists))
+	for k := range m.hists {
+		hkeys = append(hkeys, k)
+	}
+	sort.Slice(hkeys, func(i, j int) bool { return lessKeys(hkeys[i][:], hkeys[j][:]) })
+	pw.Family("chainrpc_request_duration_seconds", "histogram", "JSON-RPC request latency.")
+	for _, k := range hkeys {
+		pw.Histogram("chainrpc_request_duration_seconds", m.hists[k], "method", k[0], "provider", k[1])
+	}
+	m.mu.Unlock()
+	return pw.WriteTo(w)
+}
+
+// ServeHTTP serves the metrics in Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) { promtext.Serve(w, m) }
+
+func lessKeys(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+// ProviderLabel reduces an endpoint URL to scheme://host so API keys embedded
+// in paths or query strings never end up in metric labels or traces. A JSON
+// array of URLs (as passed to PoolCall) is reduced element-wise.
+func ProviderLabel(endpoint string) string {
+	if strings.HasPrefix(endpoint, "[") {
+		var urls []string
+		if json.Unmarshal([]byte(endpoint), &urls) == nil {
+			for i, u := range urls {
+				urls[i] = ProviderLabel(u)
+			}
+			return strings.Join(urls, ",")
+		}
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return endpoint
+	}
+	return u.Scheme + "://" + u.Host
+}
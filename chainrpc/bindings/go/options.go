@@ -0,0 +1,108 @@
+package chainrpc
+
+import (
+	"context"
+	"time"
+)
+
+// Option configures a single Call or PoolCall invocation.
+type Option func(*callOptions)
+
+type callOptions struct {
+	retry      *RetryPolicy
+	validators map[string]Validator
+	cache      *Cache
+	http       *HTTPOptions
+	httpJSON   string
+	metrics    MetricsRecorder
+	tracer     Tracer
+	cassette   *Cassette
+	etags      *ETagCache
+	hedge      *HedgePolicy
+	quorum     *QuorumPolicy
+	cost       *CostPolicy
+
+	// rest makes pool attempts GET requests (Pool.GetREST): paramsJSON
+	// then carries the path.
+	rest bool
+
+	// Per-call bookkeeping reported to the tracer.
+	attempts int
+	servedBy string
+}
+
+func newCallOptions(opts []Option) *callOptions {
+	o := &callOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithRetry retries transient failures according to the given policy,
+// in place of the native HTTP client's retries. Without this option the
+// native client retries read-only methods itself, up to 3 times with
+// backoff from 100ms, and other failures are returned immediately; with
+// it the native retries are turned off, so p alone decides the attempts.
+func WithRetry(p RetryPolicy) Option {
+	return func(o *callOptions) { o.retry = &p }
+}
+
+// run executes fn once, or under the configured retry policy, validating
+// each result that provider returns for method.
+func (o *callOptions) run(ctx context.Context, provider, method string, fn func() (string, error)) (string, error) {
+	return o.loop(ctx, func() (string, error) { return o.attempt(provider, method, fn) })
+}
+
+// taped serves method+params from the configured cassette, or records
+// fn's outcome in it.
+func (o *callOptions) taped(method, paramsJSON string, fn func() (string, error)) (string, error) {
+	if o.cassette == nil {
+		return fn()
+	}
+	return o.cassette.do(method, paramsJSON, fn)
+}
+
+// cached serves method+params from the configured cache, falling back to fn
+// and storing its result. head fetches eth_blockNumber, see Cache.put.
+func (o *callOptions) cached(method, paramsJSON string, head func() (string, error), fn func() (string, error)) (string, error) {
+	if o.cache == nil {
+		return fn()
+	}
+	if out, ok := o.cache.get(method, paramsJSON); ok {
+		return out, nil
+	}
+	out, err := fn()
+	if err == nil {
+		o.cache.put(method, paramsJSON, out, head)
+	}
+	return out, err
+}
+
+// loop executes fn once, or under the configured retry policy.
+func (o *callOptions) loop(ctx context.Context, fn func() (string, error)) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if o.retry == nil {
+		return fn()
+	}
+	return o.retry.do(ctx, fn)
+}
+
+// attempt executes fn once and validates its result.
+func (o *callOptions) attempt(provider, method string, fn func() (string, error)) (out string, err error) {
+	if o.metrics != nil {
+		start := time.Now()
+		defer func() { o.metrics.ObserveRequest(method, provider, ErrorClass(err), time.Since(start)) }()
+	}
+	o.attempts++
+	out, err = fn()
+	if err != nil {
+		return "", err
+	}
+	if err := o.validate(provider, method, out); err != nil {
+		return "", err
+	}
+	return out, nil
+}
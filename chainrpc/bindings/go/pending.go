@@ -0,0 +1,299 @@
+package chainrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PendingTx is one transaction seen entering the mempool.
+type PendingTx struct {
+	Hash string
+	// Tx is the transaction body, or nil with PendingOptions.HashesOnly.
+	Tx *Transaction
+}
+
+// PendingMode is how a PendingStream learns about transactions.
+type PendingMode string
+
+const (
+	// PendingFull is a newPendingTransactions subscription that carries
+	// full transaction bodies (Geth 1.11+ and compatible nodes).
+	PendingFull PendingMode = "subscription-full"
+	// PendingHashes is a newPendingTransactions subscription that carries
+	// hashes; bodies are fetched with eth_getTransactionByHash.
+	PendingHashes PendingMode = "subscription-hashes"
+	// PendingFilter polls eth_newPendingTransactionFilter over HTTP and
+	// fetches bodies with eth_getTransactionByHash.
+	PendingFilter PendingMode = "filter"
+)
+
+// PendingOptions configures SubscribePendingTransactions. The zero value
+// streams full bodies through a 1024-item buffer, dropping the oldest
+// items when the consumer falls behind.
+type PendingOptions struct {
+	// Buffer is the capacity of the stream's channel. Default 1024.
+	Buffer int
+	// Backpressure is applied when the buffer is full.
+	Backpressure Backpressure
+	// HashesOnly delivers hashes without fetching bodies.
+	HashesOnly bool
+	// Fetchers bounds concurrent eth_getTransactionByHash calls when
+	// bodies are fetched by hash. Default 8.
+	Fetchers int
+	// PollInterval is the filter polling interval on HTTP endpoints.
+	// Default 1s.
+	PollInterval time.Duration
+}
+
+// PendingStream delivers pending transactions on C until its context is
+// cancelled, Close is called, or the connection fails. When bodies are
+// fetched by hash, transactions may arrive out of announcement order.
+type PendingStream struct {
+	// C is closed when the stream ends; Err then reports why.
+	C <-chan PendingTx
+
+	mode    PendingMode
+	cancel  context.CancelFunc
+	done    chan struct{}
+	err     error
+	dropped uint64
+	missed  uint64
+}
+
+// Mode returns how the stream learns about transactions.
+func (s *PendingStream) Mode() PendingMode { return s.mode }
+
+// Dropped returns the number of transactions discarded under the
+// Backpressure policy.
+func (s *PendingStream) Dropped() uint64 { return atomic.LoadUint64(&s.dropped) }
+
+// Missed returns the number of announced hashes whose body could not be
+// fetched, usually because the transaction was mined, replaced or evicted
+// first.
+func (s *PendingStream) Missed() uint64 { return atomic.LoadUint64(&s.missed) }
+
+// Err returns the error that ended the stream once C is closed; it is nil
+// after Close or context cancellation.
+func (s *PendingStream) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Close stops the stream and waits for C to be closed.
+func (s *PendingStream) Close() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// SubscribePendingTransactions streams mempool transactions from url.
+//
+// On ws://, wss:// and IPC endpoints it subscribes to
+// newPendingTransactions, asking for full bodies and falling back to
+// hashes plus eth_getTransactionByHash where the node only sends hashes.
+// On HTTP endpoints it polls a pending-transaction filter. opts apply to
+// the calls made on the stream's behalf (WithHTTPOptions also supplies
+// WebSocket upgrade headers).
+func SubscribePendingTransactions(ctx context.Context, url string, cfg PendingOptions, opts ...Option) (*PendingStream, error) {
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = 1024
+	}
+	if cfg.Fetchers <= 0 {
+		cfg.Fetchers = 8
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s := &PendingStream{cancel: cancel, done: make(chan struct{})}
+	out := make(chan PendingTx, cfg.Buffer)
+	s.C = out
+
+	var (
+		produce func(emit func(json.RawMessage) bool) error
+		fetch   callFunc
+		closers []func() error
+	)
+	if canStream(url) {
+		o := newCallOptions(opts)
+		conn, err := dialStream(ctx, url, o.http)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		sc := newStreamClient(conn)
+		closers = append(closers, sc.Close)
+		raw := make(chan json.RawMessage, cfg.Buffer)
+		s.mode = PendingHashes
+		if !cfg.HashesOnly {
+			if _, err := sc.subscribe(ctx, []interface{}{"newPendingTransactions", true}, raw); err == nil {
+				s.mode = PendingFull
+			}
+		}
+		if s.mode == PendingHashes {
+			if _, err := sc.subscribe(ctx, []interface{}{"newPendingTransactions"}, raw); err != nil {
+				sc.Close()
+				cancel()
+				return nil, err
+			}
+			if !cfg.HashesOnly {
+				// Fetch on a second connection so slow lookups never hold
+				// up the notification reader.
+				fc, err := dialStream(ctx, url, o.http)
+				if err != nil {
+					sc.Close()
+					cancel()
+					return nil, err
+				}
+				fsc := newStreamClient(fc)
+				closers = append(closers, fsc.Close)
+				fetch = fsc.call
+			}
+		}
+		produce = func(emit func(json.RawMessage) bool) error {
+			for {
+				select {
+				case msg := <-raw:
+					if !emit(msg) {
+						return nil
+					}
+				case <-sc.Done():
+					return sc.Err()
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}
+	} else {
+		call := func(ctx context.Context, method, paramsJSON string) (string, error) {
+			return CallContext(ctx, url, method, paramsJSON, opts...)
+		}
+		filter, err := newPendingFilter(ctx, call)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		s.mode, fetch = PendingFilter, call
+		produce = func(emit func(json.RawMessage) bool) error {
+			return pollPendingFilter(ctx, call, filter, cfg.PollInterval, emit)
+		}
+	}
+
+	hashes := make(chan string, cfg.Buffer)
+	var fetchers sync.WaitGroup
+	if fetch != nil && !cfg.HashesOnly {
+		for i := 0; i < cfg.Fetchers; i++ {
+			fetchers.Add(1)
+			go func() {
+				defer fetchers.Done()
+				for h := range hashes {
+					tx, err := fetchPendingTx(ctx, fetch, h)
+					if err != nil || tx == nil {
+						atomic.AddUint64(&s.missed, 1)
+						continue
+					}
+					deliver(out, PendingTx{Hash: h, Tx: tx}, cfg.Backpressure, &s.dropped, ctx.Done())
+				}
+			}()
+		}
+	}
+
+	go func() {
+		err := produce(func(msg json.RawMessage) bool {
+			var hash string
+			if json.Unmarshal(msg, &hash) == nil {
+				if cfg.HashesOnly || fetch == nil {
+					return deliver(out, PendingTx{Hash: hash}, cfg.Backpressure, &s.dropped, ctx.Done())
+				}
+				return deliver(hashes, hash, cfg.Backpressure, &s.dropped, ctx.Done())
+			}
+			var tx Transaction
+			if err := json.Unmarshal(msg, &tx); err != nil {
+				return true
+			}
+			return deliver(out, PendingTx{Hash: tx.Hash, Tx: &tx}, cfg.Backpressure, &s.dropped, ctx.Done())
+		})
+		close(hashes)
+		fetchers.Wait()
+		for _, c := range closers {
+			c()
+		}
+		if ctx.Err() == nil {
+			s.err = err
+		}
+		cancel()
+		close(out)
+		close(s.done)
+	}()
+	return s, nil
+}
+
+func newPendingFilter(ctx context.Context, call callFunc) (string, error) {
+	res, err := call(ctx, "eth_newPendingTransactionFilter", "[]")
+	if err != nil {
+		return "", err
+	}
+	var id string
+	if err := json.Unmarshal([]byte(res), &id); err != nil {
+		return "", fmt.Errorf("chainrpc: eth_newPendingTransactionFilter result: %w", err)
+	}
+	return id, nil
+}
+
+// pollPendingFilter emits the hashes the filter reports every interval,
+// recreating the filter if the node expires it.
+func pollPendingFilter(ctx context.Context, call callFunc, filter string, interval time.Duration, emit func(json.RawMessage) bool) error {
+	defer func() { call(context.Background(), "eth_uninstallFilter", mustJSON([]string{filter})) }()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+		res, err := call(ctx, "eth_getFilterChanges", mustJSON([]string{filter}))
+		if err != nil && strings.Contains(strings.ToLower(err.Error()), "filter not found") {
+			if filter, err = newPendingFilter(ctx, call); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		var items []json.RawMessage
+		if err := json.Unmarshal([]byte(res), &items); err != nil {
+			return fmt.Errorf("chainrpc: eth_getFilterChanges result: %w", err)
+		}
+		for _, it := range items {
+			if !emit(it) {
+				return nil
+			}
+		}
+	}
+}
+
+func fetchPendingTx(ctx context.Context, call callFunc, hash string) (*Transaction, error) {
+	res, err := call(ctx, "eth_getTransactionByHash", mustJSON([]string{hash}))
+	if err != nil || res == "null" {
+		return nil, err
+	}
+	var tx Transaction
+	if err := json.Unmarshal([]byte(res), &tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
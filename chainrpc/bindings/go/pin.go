@@ -0,0 +1,159 @@
+package chainrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type pinKey struct{}
+
+// blockPin holds the block number "latest" resolved to for one context.
+type blockPin struct {
+	mu       sync.Mutex
+	resolved bool
+	number   uint64
+}
+
+// WithPinnedBlock returns a context in which "latest" is resolved once and
+// then pinned: the first call made with the returned context that refers to
+// "latest" (explicitly or by omitting the block tag) fetches eth_blockNumber,
+// and every call with that context is rewritten to use the same number.
+// Multi-call reads therefore never straddle a block boundary.
+func WithPinnedBlock(ctx context.Context) context.Context {
+	return context.WithValue(ctx, pinKey{}, &blockPin{})
+}
+
+// PinnedBlock returns the block number pinned in ctx, if it has been resolved.
+func PinnedBlock(ctx context.Context) (uint64, bool) {
+	pin, _ := ctx.Value(pinKey{}).(*blockPin)
+	if pin == nil {
+		return 0, false
+	}
+	pin.mu.Lock()
+	defer pin.mu.Unlock()
+	return pin.number, pin.resolved
+}
+
+// blockTagIndex is the position of the block tag parameter for methods that
+// accept one.
+var blockTagIndex = map[string]int{
+	"eth_getBalance":                       1,
+	"eth_getCode":                          1,
+	"eth_getTransactionCount":              1,
+	"eth_call":                             1,
+	"eth_estimateGas":                      1,
+	"eth_getStorageAt":                     2,
+	"eth_getProof":                         2,
+	"eth_feeHistory":                       1,
+	"eth_simulateV1":                       1,
+	"eth_getBlockByNumber":                 0,
+	"eth_getBlockTransactionCountByNumber": 0,
+	"eth_getUncleCountByBlockNumber":       0,
+}
+
+// pinParams rewrites "latest" in paramsJSON to the block pinned in ctx,
+// resolving it with blockNumber on first use. Params are returned unchanged
+// when ctx carries no pin or the method takes no block tag.
+func pinParams(ctx context.Context, method, paramsJSON string, blockNumber func() (string, error)) (string, error) {
+	pin, _ := ctx.Value(pinKey{}).(*blockPin)
+	if pin == nil {
+		return paramsJSON, nil
+	}
+	idx, tagged := blockTagIndex[method]
+	if !tagged && method != "eth_getLogs" {
+		return paramsJSON, nil
+	}
+	var params []json.RawMessage
+	if err := json.Unmarshal([]byte(paramsJSON), &params); err != nil {
+		return "", fmt.Errorf("chainrpc: params for %s: %w", method, err)
+	}
+
+	if method == "eth_getLogs" {
+		return pinLogFilter(pin, params, blockNumber)
+	}
+	switch {
+	case idx < len(params):
+		if !isLatest(params[idx]) {
+			return paramsJSON, nil
+		}
+	case idx == len(params) && idx > 0:
+		// Omitted trailing tag defaults to "latest".
+		params = append(params, nil)
+	default:
+		return paramsJSON, nil
+	}
+	tag, err := pin.resolve(blockNumber)
+	if err != nil {
+		return "", err
+	}
+	params[idx] = tag
+	out, err := json.Marshal(params)
+	return string(out), err
+}
+
+func pinLogFilter(pin *blockPin, params []json.RawMessage, blockNumber func() (string, error)) (string, error) {
+	if len(params) == 0 {
+		return "[]", nil
+	}
+	var filter map[string]json.RawMessage
+	if err := json.Unmarshal(params[0], &filter); err != nil {
+		return "", fmt.Errorf("chainrpc: eth_getLogs filter: %w", err)
+	}
+	if _, byHash := filter["blockHash"]; !byHash {
+		for _, k := range []string{"fromBlock", "toBlock"} {
+			v, ok := filter[k]
+			if ok && !isLatest(v) {
+				continue
+			}
+			tag, err := pin.resolve(blockNumber)
+			if err != nil {
+				return "", err
+			}
+			filter[k] = tag
+		}
+	}
+	f, err := json.Marshal(filter)
+	if err != nil {
+		return "", err
+	}
+	params[0] = f
+	out, err := json.Marshal(params)
+	return string(out), err
+}
+
+// resolve returns the pinned block as a JSON hex quantity, fetching it on
+// first use.
+func (p *blockPin) resolve(blockNumber func() (string, error)) (json.RawMessage, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.resolved {
+		res, err := blockNumber()
+		if err != nil {
+			return nil, fmt.Errorf("chainrpc: resolve pinned block: %w", err)
+		}
+		n, err := parseQuantity(res)
+		if err != nil {
+			return nil, fmt.Errorf("chainrpc: resolve pinned block: %w", err)
+		}
+		p.number, p.resolved = n, true
+	}
+	return json.RawMessage(strconv.Quote(fmt.Sprintf("0x%x", p.number))), nil
+}
+
+func isLatest(raw json.RawMessage) bool {
+	var s string
+	return json.Unmarshal(raw, &s) == nil && s == "latest"
+}
+
+// parseQuantity decodes a JSON-encoded hex quantity such as "0x1b4".
+func parseQuantity(result string) (uint64, error) {
+	var s string
+	if err := json.Unmarshal([]byte(result), &s); err != nil {
+		return 0, fmt.Errorf("expected hex quantity, got %s", result)
+	}
+	return strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 64)
+}
@@ -0,0 +1,417 @@
+package chainrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DarshanKumar89/chainfoundry/chainkit"
+)
+
+var (
+	// ErrNoProviders is returned when a pool is built without any URLs.
+	ErrNoProviders = errors.New("chainrpc: no providers configured")
+	// ErrRateLimited is returned when every provider in the pool shed the
+	// request because its rate limit was exhausted.
+	ErrRateLimited = errors.New("chainrpc: all providers rate limited")
+)
+
+// PoolOption configures a Pool.
+type PoolOption struct {
+	// RateLimitRPS caps the requests per second sent to each URL.
+	// URLs without an entry are not limited.
+	RateLimitRPS map[string]float64
+	// RateLimitStrategy selects between queueing and shedding requests
+	// once a provider's limit is reached.
+	RateLimitStrategy RateLimitStrategy
+	// Routing selects the order providers are tried in.
+	// The zero value is RoutePrimaryFirst.
+	Routing RoutingStrategy
+	// Cache, if set, serves immutable results for every call through the
+	// pool. A per-call WithCache option takes precedence.
+	Cache *Cache
+	// HTTP applies HTTP-level options (auth headers, proxy, TLS) to every
+	// provider without its own ProviderConfig.HTTP.
+	HTTP *HTTPOptions
+	// Metrics, if set, observes every provider attempt. A per-call
+	// WithMetrics option takes precedence.
+	Metrics MetricsRecorder
+	// Tracer, if set, emits a span per call. A per-call WithTracer option
+	// takes precedence. It requires FeatureTracing.
+	Tracer Tracer
+	// VerifyChainID makes pool construction query eth_chainId on every
+	// provider and fail unless they all agree (and match ChainID, if set).
+	VerifyChainID bool
+	// ChainID is the expected chain ID when VerifyChainID is set; zero
+	// accepts whatever chain the providers agree on.
+	ChainID uint64
+	// Cassette, if set, records or replays every call through the pool,
+	// including those made by helpers such as CallContract and WaitMined.
+	// A per-call WithCassette option takes precedence. The VerifyChainID
+	// and ProbeArchive checks at construction bypass it.
+	Cassette *Cassette
+	// ProbeArchive classifies providers whose ProviderConfig.Kind is
+	// NodeUnknown as full or archive nodes at construction.
+	ProbeArchive bool
+	// Credentials, if set, is asked for new HTTP options when a provider
+	// answers HTTP 401 or 403, and the request is retried once with them,
+	// so short-lived tokens can be renewed without rebuilding the pool.
+	// Calls made with WithHTTPOptions are not refreshed.
+	Credentials CredentialsFunc
+	// Hedge, if set, hedges read calls through the pool: see HedgePolicy.
+	// A per-call WithHedge option takes precedence.
+	Hedge *HedgePolicy
+	// CircuitBreaker, if set, stops sending calls to a provider that keeps
+	// failing until a cooldown has passed.
+	CircuitBreaker *CircuitBreakerPolicy
+	// Quorum, if set, makes read calls through the pool quorum reads: see
+	// QuorumPolicy. A per-call WithQuorum option takes precedence, and
+	// quorum reads are not hedged.
+	Quorum *QuorumPolicy
+	// CostPolicy, if set, bounds ranged queries through the pool: see
+	// CostPolicy. A per-call WithCostPolicy option takes precedence.
+	CostPolicy *CostPolicy
+	// Coalesce makes identical read calls (same method and params, after
+	// block tags are pinned) that are in flight at the same time share one
+	// outbound request and its result, so many goroutines reacting to the
+	// same new head do not each hit the providers. The first caller's
+	// options (retry, hedging, quorum) govern the shared request.
+	Coalesce bool
+}
+
+// Pool is a long-lived set of providers with Go-side failover. Unlike
+// PoolCall it keeps per-provider state (rate limits) across calls and is
+// safe for concurrent use.
+type Pool struct {
+	providers []*provider
+	opts      PoolOption
+
+	rr      atomic.Uint64 // round-robin cursor
+	chainID uint64        // verified chain ID, 0 if not verified
+
+	routeMu   sync.Mutex
+	lastRoute *Route
+
+	flights flightGroup // in-flight calls, when Coalesce is set
+}
+
+// ProviderConfig describes one provider in a pool.
+type ProviderConfig struct {
+	URL string
+	// Weight is the provider's share of traffic relative to the other
+	// providers in its priority tier under RouteWeighted. Zero means 1.
+	Weight float64
+	// Priority groups providers into tiers. Lower tiers are tried first;
+	// higher tiers only see traffic when every lower tier fails.
+	Priority int
+	// Label is a free-form tag such as "paid" or "free-tier", reported
+	// in Stats.
+	Label string
+	// HTTP overrides PoolOption.HTTP for this provider, e.g. to supply its
+	// API key.
+	HTTP *HTTPOptions
+	// Kind tags the provider as a full or archive node. debug_*, trace_*
+	// and historical state calls are only routed to archive nodes (and
+	// untagged ones, which are learned from their errors).
+	Kind NodeKind
+}
+
+type provider struct {
+	url      string
+	weight   float64
+	priority int
+	label    string
+	kind     int32 // NodeKind, updated atomically as it is learned
+	limiter  *tokenBucket
+	stats    providerStats
+	breaker  breaker
+
+	authMu    sync.RWMutex
+	http      *HTTPOptions
+	httpJSON  string
+	refreshMu sync.Mutex // serialises credential refreshes
+}
+
+// NewPool builds a pool over urls, tried in the order chosen by
+// opts.Routing on each call.
+func NewPool(urls []string, opts PoolOption) (*Pool, error) {
+	cfgs := make([]ProviderConfig, len(urls))
+	for i, u := range urls {
+		cfgs[i] = ProviderConfig{URL: u}
+	}
+	return NewPoolFromProviders(cfgs, opts)
+}
+
+// NewPoolFromProviders builds a pool from per-provider configuration,
+// honouring each provider's priority tier, weight and label.
+func NewPoolFromProviders(providers []ProviderConfig, opts PoolOption) (*Pool, error) {
+	if len(providers) == 0 {
+		return nil, ErrNoProviders
+	}
+	if opts.Tracer != nil {
+		if err := chainkit.Require(FeatureTracing); err != nil {
+			return nil, err
+		}
+	}
+	p := &Pool{opts: opts}
+	for _, c := range providers {
+		if c.Weight < 0 {
+			return nil, fmt.Errorf("chainrpc: invalid weight %v for %s", c.Weight, c.URL)
+		}
+		pr := &provider{url: c.URL, weight: c.Weight, priority: c.Priority, label: c.Label, kind: int32(c.Kind)}
+		if pr.weight == 0 {
+			pr.weight = 1
+		}
+		h := c.HTTP
+		if h == nil {
+			h = opts.HTTP
+		}
+		if err := pr.setAuth(h); err != nil {
+			return nil, fmt.Errorf("chainrpc: %s: %w", c.URL, err)
+		}
+		if rps, ok := opts.RateLimitRPS[c.URL]; ok {
+			if rps <= 0 {
+				return nil, fmt.Errorf("chainrpc: invalid rate limit %v for %s", rps, c.URL)
+			}
+			pr.limiter = newTokenBucket(rps)
+		}
+		p.providers = append(p.providers, pr)
+	}
+	if opts.ProbeArchive {
+		p.probeArchive()
+	}
+	if opts.VerifyChainID {
+		id, err := p.verifyChainID(opts.ChainID)
+		if err != nil {
+			return nil, err
+		}
+		p.chainID = id
+	}
+	return p, nil
+}
+
+// Call sends a JSON-RPC request through the pool.
+func (p *Pool) Call(method, paramsJSON string, opts ...Option) (string, error) {
+	return p.CallContext(context.Background(), method, paramsJSON, opts...)
+}
+
+// CallContext sends a JSON-RPC request through the pool, failing over to
+// the next provider on transport errors and malformed responses.
+func (p *Pool) CallContext(ctx context.Context, method, paramsJSON string, opts ...Option) (string, error) {
+	o, err := p.callOptions(opts)
+	if err != nil {
+		return "", err
+	}
+	return o.traced(ctx, method, "pool", func(ctx context.Context) (string, error) {
+		return o.taped(method, paramsJSON, func() (string, error) {
+			blockNumber := func() (string, error) {
+				return o.loop(ctx, func() (string, error) { return p.pass(ctx, o, "eth_blockNumber", "[]") })
+			}
+			paramsJSON, err := pinParams(ctx, method, paramsJSON, blockNumber)
+			if err != nil {
+				return "", err
+			}
+			head := func() (string, error) {
+				if h := p.head(); h > 0 {
+					return mustJSON(fmt.Sprintf("0x%x", h)), nil
+				}
+				return blockNumber()
+			}
+			return o.bounded(method, paramsJSON, head, func(paramsJSON string) (string, error) {
+				return o.cached(method, paramsJSON, head, func() (string, error) {
+					return p.coalesced(ctx, method, paramsJSON, func() (string, error) {
+						return o.loop(ctx, func() (string, error) { return p.pass(ctx, o, method, paramsJSON) })
+					})
+				})
+			})
+		})
+	})
+}
+
+// callOptions applies opts over the pool's defaults.
+func (p *Pool) callOptions(opts []Option) (*callOptions, error) {
+	o := newCallOptions(opts)
+	if o.cache == nil {
+		o.cache = p.opts.Cache
+	}
+	if o.metrics == nil {
+		o.metrics = p.opts.Metrics
+	}
+	if o.tracer == nil {
+		o.tracer = p.opts.Tracer
+	}
+	if o.cassette == nil {
+		o.cassette = p.opts.Cassette
+	}
+	if o.cost == nil {
+		o.cost = p.opts.CostPolicy
+	}
+	if o.http != nil {
+		enc, err := encodeHTTP(o.http, o.retry)
+		if err != nil {
+			return nil, err
+		}
+		o.httpJSON = enc
+	}
+	return o, nil
+}
+
+// pass walks the providers once and returns the first good result.
+func (p *Pool) pass(ctx context.Context, o *callOptions, method, paramsJSON string) (string, error) {
+	route := &Route{Method: method}
+	defer p.recordRoute(route)
+
+	var f failures
+	order := p.order()
+	archive := needsArchive(method, paramsJSON, p.head())
+	if archive {
+		var full []*provider
+		order, full = archiveOrder(order)
+		for _, pr := range full {
+			route.Skipped = append(route.Skipped, SkippedProvider{URL: pr.url, Reason: "not an archive node"})
+		}
+		if len(order) == 0 {
+			return "", ErrNoArchive
+		}
+	}
+	if q, ok := o.quorumPolicy(p, method); ok {
+		return p.quorumPass(ctx, o, route, order, method, paramsJSON, archive, q)
+	}
+	if delay, ok := o.hedgeDelay(p, method, order); ok {
+		return p.hedgedPass(ctx, o, route, order, method, paramsJSON, archive, delay)
+	}
+	for _, pr := range order {
+		r := p.try(ctx, o, pr, method, paramsJSON, archive)
+		if r.final {
+			if r.answered {
+				route.Provider = pr.url
+				o.servedBy = pr.url
+			}
+			return r.out, r.err
+		}
+		f.skipped(route, r)
+	}
+	return "", f.err()
+}
+
+// attemptResult is the outcome of sending a request to one provider.
+type attemptResult struct {
+	pr  *provider
+	out string
+	err error
+	// final is set when out and err are the call's answer: a result, or an
+	// error another provider would repeat. answered is set when pr gave it.
+	final    bool
+	answered bool
+	// skip is why the next provider should be tried instead; shed is set
+	// when that is the provider's rate limit and open when it is its
+	// circuit breaker.
+	skip     string
+	shed     bool
+	open     bool
+	attempts int
+}
+
+// failures accumulates why providers were skipped during a pass.
+type failures struct {
+	last       error
+	shed, open int
+}
+
+func (f *failures) skipped(route *Route, r attemptResult) {
+	switch {
+	case r.shed:
+		f.shed++
+	case r.open:
+		f.open++
+	default:
+		f.last = r.err
+	}
+	route.Skipped = append(route.Skipped, SkippedProvider{URL: r.pr.url, Reason: r.skip})
+}
+
+// err is the pass's error once every provider was skipped.
+func (f *failures) err() error {
+	switch {
+	case f.last != nil:
+		return f.last
+	case f.shed > 0:
+		return ErrRateLimited
+	case f.open > 0:
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// try sends one request to pr, after its rate limit, and classifies the
+// outcome, recording it in pr's stats.
+func (p *Pool) try(ctx context.Context, o *callOptions, pr *provider, method, paramsJSON string, archive bool) attemptResult {
+	cb := p.opts.CircuitBreaker
+	var probe bool
+	if cb != nil {
+		var ok bool
+		if ok, probe = pr.breaker.allow(time.Now()); !ok {
+			return attemptResult{pr: pr, open: true, skip: "circuit open"}
+		}
+	}
+	if err := p.acquire(ctx, pr); err != nil {
+		if probe {
+			pr.breaker.abort()
+		}
+		if errors.Is(err, ErrRateLimited) {
+			pr.stats.recordRateLimited()
+			return attemptResult{pr: pr, shed: true, skip: "rate limited"}
+		}
+		return attemptResult{pr: pr, err: err, final: true}
+	}
+	start := time.Now()
+	out, err := p.send(ctx, o, pr, method, paramsJSON)
+	if cb != nil {
+		if err == nil || !shouldFailover(err) {
+			pr.breaker.success()
+		} else {
+			pr.breaker.failure(time.Now(), *cb)
+		}
+	}
+	switch {
+	case err == nil:
+		pr.stats.recordSuccess(method, out, time.Since(start))
+		return attemptResult{pr: pr, out: out, final: true, answered: true}
+	case archive && isMissingState(err):
+		// A full node answered a historical call; remember that and let
+		// an archive node take it.
+		pr.learnFull()
+		pr.stats.recordSuccess(method, "", time.Since(start))
+		return attemptResult{pr: pr, err: err, skip: "missing state: " + err.Error()}
+	case !shouldFailover(err):
+		// The provider answered; the request itself was bad.
+		pr.stats.recordSuccess(method, "", time.Since(start))
+		return attemptResult{pr: pr, err: err, final: true, answered: true}
+	}
+	pr.stats.recordFailure(err, time.Since(start))
+	return attemptResult{pr: pr, err: err, skip: err.Error()}
+}
+
+// acquire applies the provider's rate limit according to the pool strategy.
+func (p *Pool) acquire(ctx context.Context, pr *provider) error {
+	if pr.limiter == nil {
+		return nil
+	}
+	if p.opts.RateLimitStrategy == RateLimitShed {
+		if ok, _ := pr.limiter.take(); !ok {
+			return ErrRateLimited
+		}
+		return nil
+	}
+	return pr.limiter.wait(ctx)
+}
+
+// shouldFailover reports whether another provider may succeed where this
+// one failed.
+func shouldFailover(err error) bool {
+	var verr *ValidationError
+	return IsRetryable(err) || errors.As(err, &verr)
+}
@@ -0,0 +1,356 @@
+package chainrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LoadPoolConfig builds a pool from a YAML or JSON file naming its
+// providers:
+//
+//	routing: weighted
+//	chain_id: 1
+//	verify_chain_id: true
+//	http:
+//	  timeout: 10s
+//	circuit_breaker: {failure_threshold: 5, cooldown: 30s}
+//	providers:
+//	  - name: alchemy
+//	    url: https://eth-mainnet.g.alchemy.com/v2/${ALCHEMY_KEY}
+//	    weight: 3
+//	    rate_limit: 25      # requests per second
+//	    archive: true
+//	  - name: public
+//	    url: https://ethereum-rpc.publicnode.com
+//	    priority: 1
+//	    http:
+//	      headers: {X-Client: indexer}
+//
+// Provider fields are name (required and unique, reported as the provider's
+// Label), url, weight, priority, rate_limit, archive (true for an archive
+// node, false for a full node, omitted to learn it) and http. Pool fields
+// are routing (primary-first, round-robin, fastest or weighted),
+// rate_limit_strategy (queue or shed), chain_id, verify_chain_id,
+// probe_archive, coalesce, http, hedge ({delay, min_delay}; {} for
+// adaptive) and circuit_breaker ({failure_threshold, cooldown}). http holds
+// headers, bearer_token, username, password, proxy, timeout,
+// max_request_bytes and the connection pool settings
+// max_idle_conns_per_host, idle_conn_timeout, tcp_keepalive and
+// disable_http2; a provider's http is layered over the pool's, headers
+// merged.
+//
+// ${VAR} in any string value is replaced with the environment variable,
+// which must be set; ${VAR:-default} falls back to default. Files ending
+// in .json are read as JSON, everything else as YAML. Only the YAML that
+// configuration files need is understood: block mappings and sequences,
+// flow collections, quoted and plain scalars and comments; anchors, tags
+// and multi-line scalars are rejected.
+func LoadPoolConfig(path string) (*Pool, error) {
+	providers, opts, err := ReadPoolConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewPoolFromProviders(providers, opts)
+}
+
+// ReadPoolConfig reads a LoadPoolConfig file without building the pool, so
+// options a file cannot express (Metrics, Tracer, Cache, Credentials) can
+// be added before calling NewPoolFromProviders.
+func ReadPoolConfig(path string) ([]ProviderConfig, PoolOption, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, PoolOption{}, err
+	}
+	providers, opts, err := parsePoolConfig(data, strings.EqualFold(filepath.Ext(path), ".json"))
+	if err != nil {
+		return nil, PoolOption{}, fmt.Errorf("chainrpc: pool config %s: %w", path, err)
+	}
+	return providers, opts, nil
+}
+
+type poolConfigFile struct {
+	Routing           string          `json:"routing"`
+	RateLimitStrategy string          `json:"rate_limit_strategy"`
+	ChainID           uint64          `json:"chain_id"`
+	VerifyChainID     bool            `json:"verify_chain_id"`
+	ProbeArchive      bool            `json:"probe_archive"`
+	Coalesce          bool            `json:"coalesce"`
+	HTTP              *httpConfig     `json:"http"`
+	Hedge             *hedgeConfig    `json:"hedge"`
+	CircuitBreaker    *breakerConfig  `json:"circuit_breaker"`
+	Providers         []providerEntry `json:"providers"`
+}
+
+type providerEntry struct {
+	Name      string      `json:"name"`
+	URL       string      `json:"url"`
+	Weight    float64     `json:"weight"`
+	Priority  int         `json:"priority"`
+	RateLimit float64     `json:"rate_limit"`
+	Archive   *bool       `json:"archive"`
+	HTTP      *httpConfig `json:"http"`
+}
+
+type httpConfig struct {
+	Headers         map[string]string `json:"headers"`
+	BearerToken     string            `json:"bearer_token"`
+	Username        string            `json:"username"`
+	Password        string            `json:"password"`
+	Proxy           string            `json:"proxy"`
+	Timeout         duration          `json:"timeout"`
+	MaxRequestBytes int               `json:"max_request_bytes"`
+	MaxIdlePerHost  int               `json:"max_idle_conns_per_host"`
+	IdleTimeout     duration          `json:"idle_conn_timeout"`
+	TCPKeepAlive    duration          `json:"tcp_keepalive"`
+	DisableHTTP2    bool              `json:"disable_http2"`
+}
+
+type hedgeConfig struct {
+	Delay    duration `json:"delay"`
+	MinDelay duration `json:"min_delay"`
+}
+
+type breakerConfig struct {
+	FailureThreshold int      `json:"failure_threshold"`
+	Cooldown         duration `json:"cooldown"`
+}
+
+// duration reads a Go duration string such as "250ms" or "30s".
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration %s: want a string such as \"30s\"", b)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+func parsePoolConfig(data []byte, isJSON bool) ([]ProviderConfig, PoolOption, error) {
+	var tree any
+	if isJSON {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&tree); err != nil {
+			return nil, PoolOption{}, err
+		}
+	} else {
+		var err error
+		if tree, err = parseYAML(string(data)); err != nil {
+			return nil, PoolOption{}, err
+		}
+	}
+	tree, err := expandEnv(tree)
+	if err != nil {
+		return nil, PoolOption{}, err
+	}
+	normalized, err := json.Marshal(tree)
+	if err != nil {
+		return nil, PoolOption{}, err
+	}
+	var f poolConfigFile
+	dec := json.NewDecoder(bytes.NewReader(normalized))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return nil, PoolOption{}, err
+	}
+	return f.build()
+}
+
+func (f *poolConfigFile) build() ([]ProviderConfig, PoolOption, error) {
+	var opts PoolOption
+	switch f.Routing {
+	case "", "primary-first":
+		opts.Routing = RoutePrimaryFirst
+	case "round-robin":
+		opts.Routing = RouteRoundRobin
+	case "fastest":
+		opts.Routing = RouteFastest
+	case "weighted":
+		opts.Routing = RouteWeighted
+	default:
+		return nil, opts, fmt.Errorf("unknown routing %q", f.Routing)
+	}
+	switch f.RateLimitStrategy {
+	case "", "queue":
+		opts.RateLimitStrategy = RateLimitQueue
+	case "shed":
+		opts.RateLimitStrategy = RateLimitShed
+	default:
+		return nil, opts, fmt.Errorf("unknown rate_limit_strategy %q", f.RateLimitStrategy)
+	}
+	opts.ChainID = f.ChainID
+	opts.VerifyChainID = f.VerifyChainID
+	opts.ProbeArchive = f.ProbeArchive
+	opts.Coalesce = f.Coalesce
+	opts.HTTP = f.HTTP.options(nil)
+	if f.Hedge != nil {
+		opts.Hedge = &HedgePolicy{Delay: time.Duration(f.Hedge.Delay), MinDelay: time.Duration(f.Hedge.MinDelay)}
+	}
+	if f.CircuitBreaker != nil {
+		opts.CircuitBreaker = &CircuitBreakerPolicy{
+			FailureThreshold: f.CircuitBreaker.FailureThreshold,
+			Cooldown:         time.Duration(f.CircuitBreaker.Cooldown),
+		}
+	}
+
+	if len(f.Providers) == 0 {
+		return nil, opts, errors.New("no providers")
+	}
+	providers := make([]ProviderConfig, 0, len(f.Providers))
+	names := make(map[string]bool, len(f.Providers))
+	for i, e := range f.Providers {
+		switch {
+		case e.Name == "":
+			return nil, opts, fmt.Errorf("provider %d: missing name", i)
+		case names[e.Name]:
+			return nil, opts, fmt.Errorf("provider %s: duplicate name", e.Name)
+		case e.URL == "":
+			return nil, opts, fmt.Errorf("provider %s: missing url", e.Name)
+		case e.Weight < 0 || e.RateLimit < 0:
+			return nil, opts, fmt.Errorf("provider %s: negative weight or rate_limit", e.Name)
+		}
+		names[e.Name] = true
+		c := ProviderConfig{URL: e.URL, Weight: e.Weight, Priority: e.Priority, Label: e.Name}
+		if e.Archive != nil {
+			c.Kind = NodeFull
+			if *e.Archive {
+				c.Kind = NodeArchive
+			}
+		}
+		if e.HTTP != nil {
+			c.HTTP = e.HTTP.options(f.HTTP)
+		}
+		if e.RateLimit > 0 {
+			if opts.RateLimitRPS == nil {
+				opts.RateLimitRPS = make(map[string]float64)
+			}
+			opts.RateLimitRPS[e.URL] = e.RateLimit
+		}
+		providers = append(providers, c)
+	}
+	return providers, opts, nil
+}
+
+// options converts h, layered over the pool-wide base, to HTTPOptions.
+func (h *httpConfig) options(base *httpConfig) *HTTPOptions {
+	if h == nil {
+		return nil
+	}
+	merged := httpConfig{}
+	if base != nil {
+		merged = *base
+	}
+	headers := make(map[string]string, len(merged.Headers)+len(h.Headers))
+	for k, v := range merged.Headers {
+		headers[k] = v
+	}
+	for k, v := range h.Headers {
+		headers[k] = v
+	}
+	if h.BearerToken != "" {
+		merged.BearerToken = h.BearerToken
+	}
+	if h.Username != "" {
+		merged.Username, merged.Password = h.Username, h.Password
+	}
+	if h.Proxy != "" {
+		merged.Proxy = h.Proxy
+	}
+	if h.Timeout != 0 {
+		merged.Timeout = h.Timeout
+	}
+	if h.MaxRequestBytes != 0 {
+		merged.MaxRequestBytes = h.MaxRequestBytes
+	}
+	if h.MaxIdlePerHost != 0 {
+		merged.MaxIdlePerHost = h.MaxIdlePerHost
+	}
+	if h.IdleTimeout != 0 {
+		merged.IdleTimeout = h.IdleTimeout
+	}
+	if h.TCPKeepAlive != 0 {
+		merged.TCPKeepAlive = h.TCPKeepAlive
+	}
+	merged.DisableHTTP2 = merged.DisableHTTP2 || h.DisableHTTP2
+	o := &HTTPOptions{
+		BearerToken:         merged.BearerToken,
+		Username:            merged.Username,
+		Password:            merged.Password,
+		Proxy:               merged.Proxy,
+		Timeout:             time.Duration(merged.Timeout),
+		MaxRequestBytes:     merged.MaxRequestBytes,
+		MaxIdleConnsPerHost: merged.MaxIdlePerHost,
+		IdleConnTimeout:     time.Duration(merged.IdleTimeout),
+		TCPKeepAlive:        time.Duration(merged.TCPKeepAlive),
+		DisableHTTP2:        merged.DisableHTTP2,
+	}
+	if len(headers) > 0 {
+		o.Headers = headers
+	}
+	return o
+}
+
+// expandEnv substitutes ${VAR} and ${VAR:-default} in every string of a
+// parsed config tree.
+func expandEnv(v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return expandEnvString(v)
+	case map[string]any:
+		for k, e := range v {
+			x, err := expandEnv(e)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = x
+		}
+	case []any:
+		for i, e := range v {
+			x, err := expandEnv(e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = x
+		}
+	}
+	return v, nil
+}
+
+func expandEnvString(s string) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated ${ in %q", s)
+		}
+		b.WriteString(s[:i])
+		name, def, hasDef := strings.Cut(s[i+2:i+end], ":-")
+		val, ok := os.LookupEnv(name)
+		switch {
+		case ok && (val != "" || !hasDef):
+			b.WriteString(val)
+		case hasDef:
+			b.WriteString(def)
+		default:
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		s = s[i+end+1:]
+	}
+}
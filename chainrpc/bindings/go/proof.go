@@ -0,0 +1,482 @@
+package chainrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/DarshanKumar89/chainfoundry/chaincodec/abi"
+)
+
+// ErrInvalidProof is wrapped by every proof verification failure.
+var ErrInvalidProof = errors.New("chainrpc: invalid Merkle proof")
+
+var (
+	// emptyTrieRoot is the root of an empty Merkle-Patricia trie.
+	emptyTrieRoot = abi.Keccak256([]byte{0x80})
+	// emptyCodeHash is the code hash of an account without code.
+	emptyCodeHash = abi.Keccak256()
+)
+
+// StorageProof is a storage slot's value with its proof.
+type StorageProof struct {
+	Key   string   `json:"key"`
+	Value *big.Int `json:"value"`
+	Proof []string `json:"proof"`
+}
+
+// AccountProof is the result of eth_getProof: an account's state, the
+// values of the requested storage slots, and the trie nodes proving them
+// against StateRoot.
+type AccountProof struct {
+	Address     string `json:"address"`
+	BlockNumber uint64 `json:"blockNumber"`
+	BlockHash   string `json:"blockHash"`
+	// StateRoot is the state root of the block the proof was verified
+	// against.
+	StateRoot    string         `json:"stateRoot"`
+	Nonce        uint64         `json:"nonce"`
+	Balance      *big.Int       `json:"balance"`
+	StorageHash  string         `json:"storageHash"`
+	CodeHash     string         `json:"codeHash"`
+	AccountProof []string       `json:"accountProof"`
+	Storage      []StorageProof `json:"storageProof"`
+}
+
+// GetProof fetches the proof of address and storageKeys at block ("latest",
+// "safe", "finalized" or a hex number) with eth_getProof and verifies the
+// account and every storage value against the block's state root, so the
+// values can be trusted as far as the block header is. A proof that does
+// not verify returns an error wrapping ErrInvalidProof.
+//
+// The header comes from the same node; to trust less, check BlockHash
+// against an independent source (a beacon node, another provider) or
+// verify the proof against a known state root with VerifyAccountProof.
+func GetProof(url, address string, storageKeys []string, block string) (*AccountProof, error) {
+	return GetProofContext(context.Background(), url, address, storageKeys, block)
+}
+
+// GetProofContext is like GetProof but honours ctx.
+func GetProofContext(ctx context.Context, url, address string, storageKeys []string, block string) (*AccountProof, error) {
+	return getProof(ctx, urlCaller(url), address, storageKeys, block)
+}
+
+// GetProof is like the package-level GetProof but routes through the pool.
+// Both requests go to whichever provider serves them, so a proof from one
+// provider is checked against a header from another.
+func (p *Pool) GetProof(ctx context.Context, address string, storageKeys []string, block string) (*AccountProof, error) {
+	return getProof(ctx, p.caller(), address, storageKeys, block)
+}
+
+func getProof(ctx context.Context, call callFunc, address string, storageKeys []string, block string) (*AccountProof, error) {
+	if block == "" {
+		block = "latest"
+	}
+	// Resolve the block first so the proof and the root are from the same
+	// block even if the tag moves in between.
+	res, err := call(ctx, "eth_getBlockByNumber", mustJSON([]interface{}{block, false}))
+	if err != nil {
+		return nil, err
+	}
+	var header struct {
+		Number    string `json:"number"`
+		Hash      string `json:"hash"`
+		StateRoot string `json:"stateRoot"`
+	}
+	if res == "null" {
+		return nil, fmt.Errorf("chainrpc: block %s not found", block)
+	}
+	if err := json.Unmarshal([]byte(res), &header); err != nil {
+		return nil, fmt.Errorf("chainrpc: eth_getBlockByNumber: %w", err)
+	}
+	if storageKeys == nil {
+		storageKeys = []string{}
+	}
+	res, err = call(ctx, "eth_getProof", mustJSON([]interface{}{address, storageKeys, header.Number}))
+	if err != nil {
+		return nil, err
+	}
+	ap, err := parseAccountProof(res)
+	if err != nil {
+		return nil, err
+	}
+	if ap.BlockNumber, err = parseQuantity(mustJSON(header.Number)); err != nil {
+		return nil, fmt.Errorf("chainrpc: block number: %w", err)
+	}
+	ap.BlockHash = header.Hash
+	if err := VerifyAccountProof(ap, header.StateRoot); err != nil {
+		return nil, err
+	}
+	return ap, nil
+}
+
+func parseAccountProof(res string) (*AccountProof, error) {
+	var raw struct {
+		Address      string   `json:"address"`
+		Nonce        string   `json:"nonce"`
+		Balance      string   `json:"balance"`
+		StorageHash  string   `json:"storageHash"`
+		CodeHash     string   `json:"codeHash"`
+		AccountProof []string `json:"accountProof"`
+		StorageProof []struct {
+			Key   string   `json:"key"`
+			Value string   `json:"value"`
+			Proof []string `json:"proof"`
+		} `json:"storageProof"`
+	}
+	if err := json.Unmarshal([]byte(res), &raw); err != nil {
+		return nil, fmt.Errorf("chainrpc: eth_getProof: %w", err)
+	}
+	ap := &AccountProof{
+		Address:      raw.Address,
+		StorageHash:  raw.StorageHash,
+		CodeHash:     raw.CodeHash,
+		AccountProof: raw.AccountProof,
+	}
+	var err error
+	if ap.Nonce, err = parseQuantity(mustJSON(raw.Nonce)); err != nil {
+		return nil, fmt.Errorf("chainrpc: eth_getProof nonce: %w", err)
+	}
+	if ap.Balance, err = hexBig(raw.Balance); err != nil {
+		return nil, fmt.Errorf("chainrpc: eth_getProof balance: %w", err)
+	}
+	for _, s := range raw.StorageProof {
+		v, err := hexBig(s.Value)
+		if err != nil {
+			return nil, fmt.Errorf("chainrpc: eth_getProof storage %s: %w", s.Key, err)
+		}
+		ap.Storage = append(ap.Storage, StorageProof{Key: s.Key, Value: v, Proof: s.Proof})
+	}
+	return ap, nil
+}
+
+// VerifyAccountProof checks ap's account fields against stateRoot and its
+// storage values against the account's storage root. It returns an error
+// wrapping ErrInvalidProof if anything does not match. On success
+// ap.StateRoot is set to stateRoot.
+func VerifyAccountProof(ap *AccountProof, stateRoot string) error {
+	root, err := hash32(stateRoot)
+	if err != nil {
+		return fmt.Errorf("%w: state root: %v", ErrInvalidProof, err)
+	}
+	addr, err := decodeHexString(ap.Address)
+	if err != nil || len(addr) != 20 {
+		return fmt.Errorf("%w: address %q", ErrInvalidProof, ap.Address)
+	}
+	proof, err := decodeProofNodes(ap.AccountProof)
+	if err != nil {
+		return fmt.Errorf("%w: account: %v", ErrInvalidProof, err)
+	}
+	leaf, err := verifyTrieProof(root, abi.Keccak256(addr), proof)
+	if err != nil {
+		return fmt.Errorf("%w: account %s: %v", ErrInvalidProof, ap.Address, err)
+	}
+
+	// The account as the trie holds it, or an empty one if absent.
+	nonce, balance := new(big.Int), new(big.Int)
+	storageRoot, codeHash := emptyTrieRoot, emptyCodeHash
+	if leaf != nil {
+		fields, err := rlpStrings(leaf, 4)
+		if err != nil {
+			return fmt.Errorf("%w: account %s: %v", ErrInvalidProof, ap.Address, err)
+		}
+		nonce.SetBytes(fields[0])
+		balance.SetBytes(fields[1])
+		storageRoot, codeHash = fields[2], fields[3]
+	}
+	switch {
+	case !nonce.IsUint64() || nonce.Uint64() != ap.Nonce:
+		return fmt.Errorf("%w: account %s: nonce %d, proof says %s", ErrInvalidProof, ap.Address, ap.Nonce, nonce)
+	case ap.Balance == nil || ap.Balance.Cmp(balance) != 0:
+		return fmt.Errorf("%w: account %s: balance %v, proof says %s", ErrInvalidProof, ap.Address, ap.Balance, balance)
+	case !hexEqual(ap.StorageHash, storageRoot):
+		return fmt.Errorf("%w: account %s: storage hash %s, proof says 0x%x", ErrInvalidProof, ap.Address, ap.StorageHash, storageRoot)
+	case !hexEqual(ap.CodeHash, codeHash):
+		return fmt.Errorf("%w: account %s: code hash %s, proof says 0x%x", ErrInvalidProof, ap.Address, ap.CodeHash, codeHash)
+	}
+
+	for _, s := range ap.Storage {
+		key, err := decodeHexString(s.Key)
+		if err != nil || len(key) > 32 {
+			return fmt.Errorf("%w: storage key %q", ErrInvalidProof, s.Key)
+		}
+		slot := make([]byte, 32)
+		copy(slot[32-len(key):], key)
+		proof, err := decodeProofNodes(s.Proof)
+		if err != nil {
+			return fmt.Errorf("%w: storage %s: %v", ErrInvalidProof, s.Key, err)
+		}
+		leaf, err := verifyTrieProof(storageRoot, abi.Keccak256(slot), proof)
+		if err != nil {
+			return fmt.Errorf("%w: storage %s: %v", ErrInvalidProof, s.Key, err)
+		}
+		value := new(big.Int)
+		if leaf != nil {
+			b, list, rest, err := rlpItem(leaf)
+			if err != nil || list || len(rest) > 0 {
+				return fmt.Errorf("%w: storage %s: malformed value", ErrInvalidProof, s.Key)
+			}
+			value.SetBytes(b)
+		}
+		if s.Value == nil || s.Value.Cmp(value) != 0 {
+			return fmt.Errorf("%w: storage %s: value %v, proof says %s", ErrInvalidProof, s.Key, s.Value, value)
+		}
+	}
+	ap.StateRoot = stateRoot
+	return nil
+}
+
+// verifyTrieProof walks proof from root along key and returns the value
+// stored under key, or nil if the proof shows the key is absent.
+func verifyTrieProof(root, key []byte, proof [][]byte) ([]byte, error) {
+	if len(proof) == 0 {
+		if bytes.Equal(root, emptyTrieRoot) {
+			return nil, nil
+		}
+		return nil, errors.New("empty proof for a non-empty trie")
+	}
+	path := make([]byte, 0, 2*len(key))
+	for _, b := range key {
+		path = append(path, b>>4, b&0x0f)
+	}
+	want := root
+	next := 0
+	for {
+		// Fetch the node referenced by want: the next proof element, which
+		// must hash to it.
+		if next == len(proof) {
+			return nil, errors.New("proof ends before the key is resolved")
+		}
+		node := proof[next]
+		next++
+		if !bytes.Equal(abi.Keccak256(node), want) {
+			return nil, fmt.Errorf("node %d does not match its hash", next-1)
+		}
+		// Walk the node and any nodes embedded inline in it.
+		for {
+			ref, value, done, err := walkNode(node, &path)
+			if err != nil {
+				return nil, fmt.Errorf("node %d: %v", next-1, err)
+			}
+			if done {
+				if next != len(proof) {
+					return nil, errors.New("proof has extra nodes")
+				}
+				return value, nil
+			}
+			if len(ref) == 32 {
+				want = ref
+				break
+			}
+			node = ref // embedded node, shorter than a hash
+		}
+	}
+}
+
+// walkNode follows path through one branch, extension or leaf node. It
+// returns the child reference to follow (a hash or an embedded node), or
+// done with the value (nil if the key is absent).
+func walkNode(node []byte, path *[]byte) (ref, value []byte, done bool, err error) {
+	items, err := rlpList(node)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	switch len(items) {
+	case 17:
+		if len(*path) == 0 {
+			v, err := rlpString(items[16])
+			if err != nil {
+				return nil, nil, false, err
+			}
+			if len(v) == 0 {
+				return nil, nil, true, nil
+			}
+			return nil, v, true, nil
+		}
+		child := items[(*path)[0]]
+		*path = (*path)[1:]
+		return childRef(child)
+	case 2:
+		enc, err := rlpString(items[0])
+		if err != nil || len(enc) == 0 {
+			return nil, nil, false, errors.New("malformed node path")
+		}
+		flag := enc[0] >> 4
+		nibbles := make([]byte, 0, 2*len(enc))
+		if flag&1 == 1 {
+			nibbles = append(nibbles, enc[0]&0x0f)
+		}
+		for _, b := range enc[1:] {
+			nibbles = append(nibbles, b>>4, b&0x0f)
+		}
+		if flag > 3 {
+			return nil, nil, false, errors.New("malformed node path flag")
+		}
+		if !bytes.HasPrefix(*path, nibbles) {
+			return nil, nil, true, nil // diverges: key absent
+		}
+		*path = (*path)[len(nibbles):]
+		if flag >= 2 { // leaf
+			if len(*path) != 0 {
+				return nil, nil, true, nil
+			}
+			v, err := rlpString(items[1])
+			return nil, v, true, err
+		}
+		return childRef(items[1])
+	}
+	return nil, nil, false, fmt.Errorf("node with %d items", len(items))
+}
+
+// childRef interprets a branch or extension child: an empty string for
+// none, a 32-byte hash, or an embedded node.
+func childRef(item []byte) (ref, value []byte, done bool, err error) {
+	payload, list, _, err := rlpItem(item)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	switch {
+	case list:
+		return item, nil, false, nil
+	case len(payload) == 0:
+		return nil, nil, true, nil
+	case len(payload) == 32:
+		return payload, nil, false, nil
+	}
+	return nil, nil, false, fmt.Errorf("child reference of %d bytes", len(payload))
+}
+
+func decodeProofNodes(nodes []string) ([][]byte, error) {
+	out := make([][]byte, len(nodes))
+	for i, n := range nodes {
+		b, err := decodeHexString(n)
+		if err != nil {
+			return nil, fmt.Errorf("node %d: %v", i, err)
+		}
+		out[i] = b
+	}
+	return out, nil
+}
+
+func decodeHexString(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+	return hex.DecodeString(s)
+}
+
+func hash32(s string) ([]byte, error) {
+	b, err := decodeHexString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != 32 {
+		return nil, fmt.Errorf("%d bytes, want 32", len(b))
+	}
+	return b, nil
+}
+
+func hexEqual(s string, b []byte) bool {
+	d, err := decodeHexString(s)
+	return err == nil && bytes.Equal(d, b)
+}
+
+// rlpItem splits the first RLP item off b: its payload, whether it is a
+// list, and what follows it.
+func rlpItem(b []byte) (payload []byte, list bool, rest []byte, err error) {
+	if len(b) == 0 {
+		return nil, false, nil, errors.New("rlp: empty input")
+	}
+	prefix := b[0]
+	var offset, size int
+	switch {
+	case prefix < 0x80:
+		return b[:1], false, b[1:], nil
+	case prefix <= 0xb7:
+		offset, size = 1, int(prefix-0x80)
+	case prefix <= 0xbf:
+		offset, size, err = rlpLongSize(b, int(prefix-0xb7))
+	case prefix <= 0xf7:
+		list, offset, size = true, 1, int(prefix-0xc0)
+	default:
+		list = true
+		offset, size, err = rlpLongSize(b, int(prefix-0xf7))
+	}
+	if err != nil {
+		return nil, false, nil, err
+	}
+	if offset+size > len(b) || offset+size < offset {
+		return nil, false, nil, errors.New("rlp: item exceeds input")
+	}
+	return b[offset : offset+size], list, b[offset+size:], nil
+}
+
+func rlpLongSize(b []byte, n int) (offset, size int, err error) {
+	if n > 8 || 1+n > len(b) {
+		return 0, 0, errors.New("rlp: bad length")
+	}
+	for _, c := range b[1 : 1+n] {
+		size = size<<8 | int(c)
+	}
+	if size < 0 {
+		return 0, 0, errors.New("rlp: bad length")
+	}
+	return 1 + n, size, nil
+}
+
+// rlpList returns the raw encodings of the items of the list b.
+func rlpList(b []byte) ([][]byte, error) {
+	payload, list, rest, err := rlpItem(b)
+	if err != nil {
+		return nil, err
+	}
+	if !list || len(rest) > 0 {
+		return nil, errors.New("rlp: expected a single list")
+	}
+	var items [][]byte
+	for len(payload) > 0 {
+		_, _, after, err := rlpItem(payload)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, payload[:len(payload)-len(after)])
+		payload = after
+	}
+	return items, nil
+}
+
+// rlpString decodes an item that must be a string.
+func rlpString(item []byte) ([]byte, error) {
+	payload, list, _, err := rlpItem(item)
+	if err != nil {
+		return nil, err
+	}
+	if list {
+		return nil, errors.New("rlp: expected a string")
+	}
+	return payload, nil
+}
+
+// rlpStrings decodes the list b, which must hold n strings.
+func rlpStrings(b []byte, n int) ([][]byte, error) {
+	items, err := rlpList(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) != n {
+		return nil, fmt.Errorf("rlp: %d items, want %d", len(items), n)
+	}
+	out := make([][]byte, n)
+	for i, it := range items {
+		if out[i], err = rlpString(it); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
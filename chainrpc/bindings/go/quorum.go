@@ -0,0 +1,183 @@
+package chainrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoQuorum is matched (with errors.Is) by every *QuorumError.
+var ErrNoQuorum = errors.New("chainrpc: providers did not reach quorum")
+
+// QuorumPolicy sends a read to several providers and only returns a
+// result that enough of them agree on, so a single lying or lagging
+// provider cannot answer alone. Results are compared as JSON, ignoring
+// formatting and key order; an error every provider would repeat (a
+// revert, invalid params) is an answer too and is compared by message.
+//
+// Reads at "latest" can differ between providers that are a block apart;
+// pin them (see WithPinnedBlock) or query an explicit block so honest
+// providers agree. Writes, filters and subscriptions are never sent to
+// more than one provider: they ignore the policy.
+type QuorumPolicy struct {
+	// Providers is how many providers are asked. Zero means 3. A
+	// provider that fails (transport error, rate limit, open circuit)
+	// is replaced by the next one in routing order, if any.
+	Providers int
+	// Agree is how many identical answers are needed. Zero means a
+	// majority of Providers.
+	Agree int
+}
+
+// QuorumAnswer is one provider's answer to a quorum read.
+type QuorumAnswer struct {
+	Provider string `json:"provider"`
+	Result   string `json:"result,omitempty"`
+	// Error is the error the provider answered with or, when Skipped is
+	// set, why it gave no answer.
+	Error   string `json:"error,omitempty"`
+	Skipped bool   `json:"skipped,omitempty"`
+}
+
+// QuorumError reports a quorum read the providers disagreed on, or that
+// too few providers answered.
+type QuorumError struct {
+	Method string `json:"method"`
+	// Need is the number of agreeing answers the policy requires.
+	Need    int            `json:"need"`
+	Answers []QuorumAnswer `json:"answers"`
+}
+
+func (e *QuorumError) Error() string {
+	groups := map[string]int{}
+	var answered int
+	for _, a := range e.Answers {
+		if !a.Skipped {
+			groups[a.Result+"\x00"+a.Error]++
+			answered++
+		}
+	}
+	best := 0
+	for _, n := range groups {
+		best = max(best, n)
+	}
+	return fmt.Sprintf("chainrpc: no quorum for %s: %d of %d needed agree (%d answers, %d distinct)",
+		e.Method, best, e.Need, answered, len(groups))
+}
+
+func (e *QuorumError) Is(target error) bool { return target == ErrNoQuorum }
+
+// WithQuorum makes the call a quorum read according to q, overriding
+// PoolOption.Quorum. It only affects calls through a Pool.
+func WithQuorum(q QuorumPolicy) Option {
+	return func(o *callOptions) { o.quorum = &q }
+}
+
+// quorumPolicy returns the policy for method with defaults applied, and
+// false if the call is not a quorum read.
+func (o *callOptions) quorumPolicy(p *Pool, method string) (QuorumPolicy, bool) {
+	q := o.quorum
+	if q == nil {
+		q = p.opts.Quorum
+	}
+	if q == nil || !hedgeable(method) {
+		return QuorumPolicy{}, false
+	}
+	policy := *q
+	if policy.Providers <= 0 {
+		policy.Providers = 3
+	}
+	if policy.Agree <= 0 {
+		policy.Agree = policy.Providers/2 + 1
+	}
+	return policy, true
+}
+
+// quorumPass asks q.Providers providers at once, replacing failed ones
+// from the rest of order, and returns as soon as q.Agree answers match.
+func (p *Pool) quorumPass(ctx context.Context, o *callOptions, route *Route, order []*provider, method, paramsJSON string, archive bool, q QuorumPolicy) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan attemptResult, len(order))
+	next, inflight := 0, 0
+	launch := func() {
+		pr := order[next]
+		next++
+		inflight++
+		leg := *o
+		leg.attempts = 0
+		go func() {
+			r := p.try(ctx, &leg, pr, method, paramsJSON, archive)
+			r.attempts = leg.attempts
+			results <- r
+		}()
+	}
+	for next < len(order) && next < q.Providers {
+		launch()
+	}
+
+	qe := &QuorumError{Method: method, Need: q.Agree}
+	votes := map[string][]attemptResult{}
+	var f failures
+	for inflight > 0 {
+		r := <-results
+		inflight--
+		o.attempts += r.attempts
+		if !r.answered {
+			if r.final {
+				// The call itself ended (e.g. its context); no other
+				// provider will do better.
+				return r.out, r.err
+			}
+			f.skipped(route, r)
+			qe.Answers = append(qe.Answers, QuorumAnswer{Provider: r.pr.url, Error: r.skip, Skipped: true})
+			if next < len(order) {
+				launch()
+			}
+			continue
+		}
+		a := QuorumAnswer{Provider: r.pr.url, Result: r.out}
+		key := "result:" + canonicalJSON(r.out)
+		if r.err != nil {
+			a.Error = r.err.Error()
+			key = "error:" + a.Error
+		}
+		qe.Answers = append(qe.Answers, a)
+		votes[key] = append(votes[key], r)
+		if agreed := votes[key]; len(agreed) >= q.Agree {
+			route.Provider = agreed[0].pr.url
+			o.servedBy = agreed[0].pr.url
+			for _, v := range agreed {
+				route.Agreed = append(route.Agreed, v.pr.url)
+			}
+			return agreed[0].out, agreed[0].err
+		}
+	}
+	if err := f.err(); err != nil && len(votes) == 0 {
+		// Nobody answered: a transport failure, not a disagreement.
+		return "", err
+	}
+	return "", qe
+}
+
+// canonicalJSON re-encodes s so equal values compare equal regardless of
+// whitespace and key order; s itself if it is not JSON.
+func canonicalJSON(s string) string {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return s
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return s
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,66 @@
+package chainrpc
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitStrategy decides what the pool does when a provider has no
+// rate-limit tokens left.
+type RateLimitStrategy int
+
+const (
+	// RateLimitQueue waits for the provider's next token.
+	RateLimitQueue RateLimitStrategy = iota
+	// RateLimitShed skips the provider and fails over to the next one,
+	// returning ErrRateLimited if every provider is exhausted.
+	RateLimitShed
+)
+
+// tokenBucket is a classic token-bucket limiter refilled at rate tokens/s.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rps float64) *tokenBucket {
+	burst := math.Max(1, math.Ceil(rps))
+	return &tokenBucket{rate: rps, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// take consumes a token if one is available. Otherwise it returns the time
+// until the next token is due.
+func (b *tokenBucket) take() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// wait blocks until a token is available or ctx is done.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		ok, d := b.take()
+		if ok {
+			return nil
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package chainrpc
+
+import (
+	"container/list"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Static data endpoints (the beacon API, REST gateways) are fetched in Go
+// with plain GET requests, which unlike JSON-RPC POSTs can be revalidated
+// with ETags instead of downloaded again.
+
+// ETagCacheStats reports conditional request effectiveness.
+type ETagCacheStats struct {
+	// Revalidated counts 304 responses served from the cache.
+	Revalidated uint64 `json:"revalidated"`
+	// Downloaded counts full responses.
+	Downloaded uint64 `json:"downloaded"`
+	Entries    int    `json:"entries"`
+	Bytes      int64  `json:"bytes"`
+}
+
+// ETagCache stores REST responses with their validators (ETag and
+// Last-Modified). GetREST sends them back as If-None-Match and
+// If-Modified-Since, and on 304 Not Modified serves the stored body.
+// Entries are keyed by URL and evicted least recently used first once the
+// cache exceeds its byte budget.
+type ETagCache struct {
+	maxBytes int64
+
+	mu          sync.Mutex
+	ll          *list.List
+	items       map[string]*list.Element
+	size        int64
+	revalidated uint64
+	downloaded  uint64
+}
+
+type etagEntry struct {
+	url          string
+	etag         string
+	lastModified string
+	body         []byte
+}
+
+// NewETagCache returns an empty cache holding at most maxBytes of
+// response bodies. Zero means 64 MiB.
+func NewETagCache(maxBytes int64) *ETagCache {
+	if maxBytes <= 0 {
+		maxBytes = 64 << 20
+	}
+	return &ETagCache{maxBytes: maxBytes, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// WithETagCache makes GetREST revalidate responses stored in c.
+func WithETagCache(c *ETagCache) Option {
+	return func(o *callOptions) { o.etags = c }
+}
+
+// Stats returns the cache counters and current size.
+func (c *ETagCache) Stats() ETagCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ETagCacheStats{Revalidated: c.revalidated, Downloaded: c.downloaded, Entries: c.ll.Len(), Bytes: c.size}
+}
+
+// Purge removes every entry.
+func (c *ETagCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+	c.size = 0
+}
+
+func (c *ETagCache) get(u string) *etagEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[u]
+	if !ok {
+		return nil
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*etagEntry)
+}
+
+// put stores a full response, or forgets the URL if it has no validator.
+func (c *ETagCache) put(e *etagEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.downloaded++
+	if el, ok := c.items[e.url]; ok {
+		c.size -= int64(len(el.Value.(*etagEntry).body))
+		c.ll.Remove(el)
+		delete(c.items, e.url)
+	}
+	if (e.etag == "" && e.lastModified == "") || int64(len(e.body)) > c.maxBytes {
+		return
+	}
+	c.items[e.url] = c.ll.PushFront(e)
+	c.size += int64(len(e.body))
+	for c.size > c.maxBytes {
+		old := c.ll.Remove(c.ll.Back()).(*etagEntry)
+		delete(c.items, old.url)
+		c.size -= int64(len(old.body))
+	}
+}
+
+func (c *ETagCache) hit() {
+	c.mu.Lock()
+	c.revalidated++
+	c.mu.Unlock()
+}
+
+// GetREST fetches url with a GET request and returns the body. Headers,
+// credentials, proxy, TLS settings and timeout come from WithHTTPOptions;
+// WithRetry, WithMetrics and WithTracer apply as for Call. With
+// WithETagCache an unchanged resource is revalidated rather than
+// downloaded. Non-2xx responses are errors of the form "HTTP {status}:
+// {body}", which IsRetryable classifies like the JSON-RPC transport's.
+func GetREST(ctx context.Context, url string, opts ...Option) ([]byte, error) {
+	o := newCallOptions(opts)
+	client, err := restClient(o.http)
+	if err != nil {
+		return nil, err
+	}
+	out, err := o.traced(ctx, "GET", url, func(ctx context.Context) (string, error) {
+		return o.run(ctx, url, "GET", func() (string, error) {
+			b, err := getREST(ctx, client, url, o.http, o.etags)
+			return string(b), err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+// GetREST fetches path (e.g. "/eth/v1/beacon/genesis") from the pool's
+// providers, whose URLs are then REST base URLs such as beacon nodes, with
+// the pool's routing, rate limits, circuit breaker, hedging and failover:
+// transport errors and 429/5xx responses move on to the next provider,
+// other statuses are returned. Provider HTTP options and the Credentials
+// hook apply as for CallContext; WithETagCache revalidates responses.
+func (p *Pool) GetREST(ctx context.Context, path string, opts ...Option) ([]byte, error) {
+	o, err := p.callOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	o.rest = true
+	out, err := o.traced(ctx, "GET", "pool", func(ctx context.Context) (string, error) {
+		return o.taped("GET", path, func() (string, error) {
+			return o.loop(ctx, func() (string, error) { return p.pass(ctx, o, "GET", path) })
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+// transport sends one pool attempt to url: a JSON-RPC call, or for
+// GetREST a GET of the path in paramsJSON below url.
+func (o *callOptions) transport(ctx context.Context, url, method, paramsJSON string, h *HTTPOptions, httpJSON string) (string, error) {
+	if !o.rest {
+		return call(url, method, paramsJSON, httpJSON)
+	}
+	client, err := restClient(h)
+	if err != nil {
+		return "", err
+	}
+	b, err := getREST(ctx, client, strings.TrimSuffix(url, "/")+paramsJSON, h, o.etags)
+	return string(b), err
+}
+
+func getREST(ctx context.Context, client *http.Client, u string, h *HTTPOptions, cache *ETagCache) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if h != nil {
+		for k, v := range h.Headers {
+			req.Header.Set(k, v)
+		}
+		if a := h.authorization(); a != "" {
+			req.Header.Set("Authorization", a)
+		}
+	}
+	var cached *etagEntry
+	if cache != nil {
+		if cached = cache.get(u); cached != nil {
+			if cached.etag != "" {
+				req.Header.Set("If-None-Match", cached.etag)
+			}
+			if cached.lastModified != "" {
+				req.Header.Set("If-Modified-Since", cached.lastModified)
+			}
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP error: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		cache.hit()
+		return cached.body, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP error: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("HTTP error: HTTP %d: %s", resp.StatusCode, truncate(body))
+	}
+	if cache != nil {
+		cache.put(&etagEntry{url: u, etag: resp.Header.Get("ETag"), lastModified: resp.Header.Get("Last-Modified"), body: body})
+	}
+	return body, nil
+}
+
+var (
+	restClientsMu sync.Mutex
+	// restClients reuses one client, and so its connections, per distinct
+	// transport configuration.
+	restClients = map[string]*http.Client{}
+)
+
+// restClient returns an HTTP client honouring h's proxy, TLS and timeout
+// settings.
+func restClient(h *HTTPOptions) (*http.Client, error) {
+	key, err := encodeHTTP(h, nil)
+	if err != nil {
+		return nil, err
+	}
+	restClientsMu.Lock()
+	defer restClientsMu.Unlock()
+	if c, ok := restClients[key]; ok {
+		return c, nil
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	c := &http.Client{Transport: t}
+	if h != nil {
+		if h.Proxy != "" {
+			p, err := url.Parse(h.Proxy)
+			if err != nil {
+				return nil, fmt.Errorf("chainrpc: proxy: %w", err)
+			}
+			t.Proxy = http.ProxyURL(p)
+		}
+		if len(h.CACertPEM) > 0 || len(h.ClientCertPEM) > 0 {
+			cfg := &tls.Config{}
+			if len(h.CACertPEM) > 0 {
+				pool, err := x509.SystemCertPool()
+				if err != nil {
+					pool = x509.NewCertPool()
+				}
+				if !pool.AppendCertsFromPEM(h.CACertPEM) {
+					return nil, fmt.Errorf("chainrpc: CACertPEM holds no certificates")
+				}
+				cfg.RootCAs = pool
+			}
+			if len(h.ClientCertPEM) > 0 {
+				cert, err := tls.X509KeyPair(h.ClientCertPEM, h.ClientKeyPEM)
+				if err != nil {
+					return nil, fmt.Errorf("chainrpc: client certificate: %w", err)
+				}
+				cfg.Certificates = []tls.Certificate{cert}
+			}
+			t.TLSClientConfig = cfg
+		}
+		c.Timeout = h.Timeout
+		switch {
+		case h.MaxIdleConnsPerHost > 0:
+			t.MaxIdleConnsPerHost = h.MaxIdleConnsPerHost
+		case h.MaxIdleConnsPerHost < 0:
+			t.DisableKeepAlives = true
+		}
+		if h.IdleConnTimeout > 0 {
+			t.IdleConnTimeout = h.IdleConnTimeout
+		}
+		if h.TCPKeepAlive != 0 {
+			d := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: h.TCPKeepAlive}
+			t.DialContext = d.DialContext
+		}
+		if h.DisableHTTP2 {
+			t.ForceAttemptHTTP2 = false
+			t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		}
+	}
+	restClients[key] = c
+	return c, nil
+}
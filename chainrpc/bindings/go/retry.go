@@ -0,0 +1,125 @@
+package chainrpc
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// RetryPolicy describes how transient failures are retried.
+//
+// Delays grow exponentially from InitialBackoff by Multiplier, are capped at
+// MaxBackoff, and are spread by ±Jitter (a fraction of the delay).
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the exponential growth of the delay.
+	MaxBackoff time.Duration
+	// Multiplier is applied to the delay after each retry.
+	Multiplier float64
+	// Jitter adds ±Jitter*delay of randomness (0 disables jitter).
+	Jitter float64
+	// Retryable decides whether an error is worth retrying.
+	// Defaults to IsRetryable when nil.
+	Retryable func(error) bool
+}
+
+// DefaultRetryPolicy mirrors the defaults of the Rust RetryConfig:
+// 3 retries, 100ms initial backoff doubling up to 10s, 10% jitter.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.1,
+	}
+}
+
+// Backoff returns the delay before the given retry (1-based).
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	if retry < 1 {
+		retry = 1
+	}
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = 1
+	}
+	d := float64(p.InitialBackoff) * math.Pow(mult, float64(retry-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (rand.Float64()*2 - 1)
+	}
+	if d < 0 {
+		d = 0
+	}
+	return time.Duration(d)
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// do runs fn until it succeeds, returns a non-retryable error, runs out of
+// attempts, or ctx is done.
+func (p RetryPolicy) do(ctx context.Context, fn func() (string, error)) (string, error) {
+	var (
+		out string
+		err error
+	)
+	for attempt := 1; ; attempt++ {
+		out, err = fn()
+		if err == nil || attempt >= p.MaxAttempts || !p.retryable(err) {
+			return out, err
+		}
+		t := time.NewTimer(p.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return "", ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// transientMarkers are substrings of native error messages that indicate a
+// transient condition (rate limiting, gateway errors, network failures).
+var transientMarkers = []string{
+	"http 429",
+	"http 502",
+	"http 503",
+	"http 504",
+	"rate limit",
+	"timed out",
+	"timeout",
+	"connection refused",
+	"connection reset",
+	"all providers unavailable",
+	"circuit breaker open",
+}
+
+// IsRetryable reports whether err looks like a transient transport failure:
+// HTTP 429/502/503/504, rate limiting, timeouts, or connection errors.
+// JSON-RPC execution errors (reverts, invalid params) are not retryable.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, m := range transientMarkers {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package chainrpc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/DarshanKumar89/chainfoundry/chaincodec/abi"
+)
+
+// RevertError is returned when a call or gas estimate reverts. Reason is
+// the decoded revert reason when one could be determined.
+type RevertError struct {
+	Code    int
+	Message string
+	// Data is the hex-encoded revert data, "0x" if the node sent none.
+	Data string
+	// Kind, Reason and Suggestion come from the registered RevertDecoder,
+	// or the built-in Error(string)/Panic(uint256) decoding.
+	Kind       string
+	Reason     string
+	Suggestion string
+}
+
+func (e *RevertError) Error() string {
+	switch {
+	case e.Reason != "":
+		return "chainrpc: execution reverted: " + e.Reason
+	case e.Data != "" && e.Data != "0x":
+		return "chainrpc: execution reverted with data " + e.Data
+	}
+	return "chainrpc: " + e.Message
+}
+
+// DecodedRevert is the result of a RevertDecoder.
+type DecodedRevert struct {
+	Kind       string
+	Reason     string
+	Suggestion string
+}
+
+// RevertDecoder decodes raw revert data. It returns nil when the data is
+// not recognised.
+type RevertDecoder func(data []byte) *DecodedRevert
+
+var (
+	revertMu      sync.RWMutex
+	revertDecoder RevertDecoder
+)
+
+// RegisterRevertDecoder installs d as the decoder used for RevertError.
+// Importing chainerrors/rpcdecode registers chainerrors' decoder, which also
+// understands custom errors from its signature registry.
+func RegisterRevertDecoder(d RevertDecoder) {
+	revertMu.Lock()
+	revertDecoder = d
+	revertMu.Unlock()
+}
+
+var (
+	rpcErrRe = regexp.MustCompile(`JSON-RPC (?:error )?(-?\d+): (.*)`)
+	// inlineRe matches nodes that put the data in the message itself:
+	// "execution reverted: 0x08c379a0…".
+	inlineRe = regexp.MustCompile(`reverted:?\s+(0x[0-9a-fA-F]*)\s*$`)
+)
+
+// AsRevert converts an error from Call/CallContext into a *RevertError
+// when it is an execution revert. Revert data is taken from the JSON-RPC
+// error data, or from a hex blob in the message for nodes that inline it.
+func AsRevert(err error) (*RevertError, bool) {
+	var re *RevertError
+	if errors.As(err, &re) {
+		return re, true
+	}
+	if err == nil {
+		return nil, false
+	}
+	var code int
+	var msg, data string
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		code, msg = rpcErr.Code, rpcErr.Message
+		if len(rpcErr.Data) > 0 {
+			data = revertData(rpcErr.Data)
+		}
+	} else {
+		// Errors flattened to text, e.g. by a caller's own wrapping.
+		m := rpcErrRe.FindStringSubmatch(err.Error())
+		if m == nil {
+			return nil, false
+		}
+		code, _ = strconv.Atoi(m[1])
+		msg = m[2]
+		if i := strings.Index(msg, " data: "); i >= 0 {
+			data = revertData(json.RawMessage(msg[i+len(" data: "):]))
+			msg = msg[:i]
+		}
+	}
+	lower := strings.ToLower(msg)
+	if code != CodeExecutionRevert && !strings.Contains(lower, "revert") {
+		return nil, false
+	}
+	if data == "" {
+		if m := inlineRe.FindStringSubmatch(msg); m != nil {
+			data = m[1]
+		}
+	}
+	if data == "" {
+		data = "0x"
+	}
+	re = &RevertError{Code: code, Message: msg, Data: data}
+	re.decode()
+	return re, true
+}
+
+// revertData extracts hex revert bytes from JSON-RPC error data, which is
+// either a hex string or, on some clients, an object wrapping one.
+func revertData(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		if strings.HasPrefix(s, "0x") {
+			return s
+		}
+		if m := inlineRe.FindStringSubmatch(s); m != nil {
+			return m[1]
+		}
+		return ""
+	}
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(raw, &obj) == nil {
+		for _, k := range []string{"data", "result", "originalError"} {
+			if v, ok := obj[k]; ok {
+				if d := revertData(v); d != "" {
+					return d
+				}
+			}
+		}
+	}
+	return ""
+}
+
+var (
+	errorStringSelector = "08c379a0"
+	panicSelector       = "4e487b71"
+	errorString, _      = abi.ParseTypes("string")
+	panicCode, _        = abi.ParseTypes("uint256")
+)
+
+func (e *RevertError) decode() {
+	revertMu.RLock()
+	d := revertDecoder
+	revertMu.RUnlock()
+	e.decodeWith(d)
+}
+
+// DecodeWith reclassifies the revert with d instead of the registered
+// decoder, for services that keep separate error registries per tenant.
+// Data the decoder does not recognise gets the built-in decoding.
+func (e *RevertError) DecodeWith(d RevertDecoder) {
+	e.Kind, e.Reason, e.Suggestion = "", "", ""
+	e.decodeWith(d)
+}
+
+func (e *RevertError) decodeWith(d RevertDecoder) {
+	b, err := hex.DecodeString(strings.TrimPrefix(e.Data, "0x"))
+	if err != nil {
+		return
+	}
+	if d != nil {
+		if r := d(b); r != nil {
+			e.Kind, e.Reason, e.Suggestion = r.Kind, r.Reason, r.Suggestion
+			return
+		}
+	}
+	if len(b) < 4 {
+		return
+	}
+	switch hex.EncodeToString(b[:4]) {
+	case errorStringSelector:
+		if v, err := abi.Decode(errorString, b[4:]); err == nil {
+			e.Kind, e.Reason = "revert_string", v[0].(string)
+		}
+	case panicSelector:
+		if v, err := abi.Decode(panicCode, b[4:]); err == nil {
+			e.Kind, e.Reason = "panic", fmt.Sprintf("panic 0x%x", v[0])
+		}
+	}
+}
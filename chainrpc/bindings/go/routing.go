@@ -0,0 +1,100 @@
+package chainrpc
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// RoutingStrategy selects the order in which a Pool tries its providers.
+type RoutingStrategy int
+
+const (
+	// RoutePrimaryFirst always walks providers in configuration order.
+	RoutePrimaryFirst RoutingStrategy = iota
+	// RouteRoundRobin rotates the starting provider on every call.
+	RouteRoundRobin
+	// RouteFastest prefers the healthy provider with the lowest observed
+	// latency. Providers without samples are tried first so they get one.
+	RouteFastest
+	// RouteWeighted picks providers at random in proportion to their
+	// ProviderConfig.Weight.
+	RouteWeighted
+)
+
+// ewmaAlpha weights the newest latency sample in the moving average.
+const ewmaAlpha = 0.3
+
+// unhealthyStreak is the number of consecutive failures after which
+// RouteFastest moves a provider behind the healthy ones.
+const unhealthyStreak = 3
+
+func (s *providerStats) observeLatency(d time.Duration) {
+	if s.ewma == 0 {
+		s.ewma = d
+		return
+	}
+	s.ewma = time.Duration(ewmaAlpha*float64(d) + (1-ewmaAlpha)*float64(s.ewma))
+}
+
+func (s *providerStats) health() (time.Duration, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ewma, s.streak
+}
+
+// order returns the providers in the order this call should try them:
+// priority tiers first, then the routing strategy within each tier.
+func (p *Pool) order() []*provider {
+	out := make([]*provider, len(p.providers))
+	copy(out, p.providers)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].priority < out[j].priority })
+
+	cursor := p.rr.Add(1) - 1
+	for start := 0; start < len(out); {
+		end := start + 1
+		for end < len(out) && out[end].priority == out[start].priority {
+			end++
+		}
+		p.orderTier(out[start:end], cursor)
+		start = end
+	}
+	return out
+}
+
+// orderTier reorders one priority tier in place.
+func (p *Pool) orderTier(tier []*provider, cursor uint64) {
+	switch p.opts.Routing {
+	case RouteRoundRobin:
+		n := len(tier)
+		start := int(cursor % uint64(n))
+		rotated := append(append([]*provider(nil), tier[start:]...), tier[:start]...)
+		copy(tier, rotated)
+	case RouteFastest:
+		type rank struct {
+			latency time.Duration
+			healthy bool
+		}
+		ranks := make(map[*provider]rank, len(tier))
+		for _, pr := range tier {
+			l, streak := pr.stats.health()
+			ranks[pr] = rank{latency: l, healthy: streak < unhealthyStreak}
+		}
+		sort.SliceStable(tier, func(i, j int) bool {
+			a, b := ranks[tier[i]], ranks[tier[j]]
+			if a.healthy != b.healthy {
+				return a.healthy
+			}
+			return a.latency < b.latency
+		})
+	case RouteWeighted:
+		// Efraimidis–Spirakis: sorting by u^(1/w) descending yields a
+		// weighted random permutation.
+		keys := make(map[*provider]float64, len(tier))
+		for _, pr := range tier {
+			keys[pr] = math.Pow(rand.Float64(), 1/pr.weight)
+		}
+		sort.SliceStable(tier, func(i, j int) bool { return keys[tier[i]] > keys[tier[j]] })
+	}
+}
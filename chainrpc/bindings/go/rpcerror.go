@@ -0,0 +1,114 @@
+package chainrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Standard JSON-RPC 2.0 error codes, plus the code Ethereum nodes use for
+// execution reverts.
+const (
+	CodeParseError      = -32700
+	CodeInvalidRequest  = -32600
+	CodeMethodNotFound  = -32601
+	CodeInvalidParams   = -32602
+	CodeInternalError   = -32603
+	CodeServerError     = -32000
+	CodeExecutionRevert = 3
+)
+
+// RPCError is an error response from a node. Every transport returns it
+// for JSON-RPC errors, wrapped or not, so callers can switch on the code:
+//
+//	var rpcErr *chainrpc.RPCError
+//	if errors.As(err, &rpcErr) && rpcErr.Code == chainrpc.CodeMethodNotFound {
+//		…
+//	}
+//
+// AsRevert turns one carrying revert data into a *RevertError.
+type RPCError struct {
+	Code    int
+	Message string
+	// Data is the error's data member, nil if the node sent none.
+	Data json.RawMessage
+}
+
+// Error formats the error as "JSON-RPC {code}: {message}", followed by
+// " data: {json}" when there is data.
+func (e *RPCError) Error() string {
+	msg := fmt.Sprintf("JSON-RPC %d: %s", e.Code, e.Message)
+	if len(e.Data) > 0 {
+		msg += " data: " + string(e.Data)
+	}
+	return msg
+}
+
+// newRPCError builds an RPCError, compacting data.
+func newRPCError(code int, message string, data json.RawMessage) *RPCError {
+	e := &RPCError{Code: code, Message: message}
+	if isNull(data) {
+		return e
+	}
+	var buf bytes.Buffer
+	if json.Compact(&buf, data) == nil {
+		e.Data = buf.Bytes()
+	} else {
+		e.Data = json.RawMessage(mustJSON(string(data)))
+	}
+	return e
+}
+
+// rpcErrorText matches JSON-RPC errors as the native library and older
+// cassettes format them.
+var rpcErrorText = regexp.MustCompile(`(?s)^(?:JSON-RPC (?:error )?|RPC error )(-?\d+): (.*)$`)
+
+// parseError turns an error message from the native library or a cassette
+// into an *RPCError when it has the JSON-RPC form, and a plain error
+// otherwise.
+func parseError(msg string) error {
+	m := rpcErrorText.FindStringSubmatch(msg)
+	if m == nil {
+		if e := httpRPCError(msg); e != nil {
+			return e
+		}
+		return errors.New(msg)
+	}
+	code, err := strconv.Atoi(m[1])
+	if err != nil {
+		return errors.New(msg)
+	}
+	text, data := m[2], ""
+	if i := strings.Index(text, " data: "); i >= 0 {
+		text, data = text[:i], text[i+len(" data: "):]
+	}
+	return newRPCError(code, text, json.RawMessage(data))
+}
+
+// httpErrorBody matches a non-2xx HTTP response whose body is JSON.
+var httpErrorBody = regexp.MustCompile(`(?s)HTTP (\d{3}): (\{.*\})\s*$`)
+
+// httpRPCError extracts the JSON-RPC error from an HTTP error response.
+// Bitcoin Core and its forks answer failed calls with HTTP 404 or 500 and
+// a normal JSON-RPC body, which is an RPC error, not a transport failure.
+func httpRPCError(msg string) *RPCError {
+	m := httpErrorBody.FindStringSubmatch(msg)
+	if m == nil {
+		return nil
+	}
+	var body struct {
+		Error *struct {
+			Code    int             `json:"code"`
+			Message string          `json:"message"`
+			Data    json.RawMessage `json:"data"`
+		} `json:"error"`
+	}
+	if json.Unmarshal([]byte(m[2]), &body) != nil || body.Error == nil {
+		return nil
+	}
+	return newRPCError(body.Error.Code, body.Error.Message, body.Error.Data)
+}
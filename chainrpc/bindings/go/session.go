@@ -0,0 +1,171 @@
+package chainrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrSessionReleased is returned by calls on a released Session.
+var ErrSessionReleased = errors.New("chainrpc: session released")
+
+// Session pins a sequence of calls to one provider of a pool, for
+// stateful APIs that only work when every call reaches the same node:
+// filters (eth_newFilter, eth_getFilterChanges, …) and some debug
+// namespaces. Calls never fail over; a provider error is returned as is.
+// Filters created through the session are uninstalled by Release.
+type Session struct {
+	p  *Pool
+	pr *provider
+
+	mu       sync.Mutex
+	released bool
+	filters  map[string]bool
+}
+
+// filterCreators are the methods whose result is a filter ID.
+var filterCreators = map[string]bool{
+	"eth_newFilter":                   true,
+	"eth_newBlockFilter":              true,
+	"eth_newPendingTransactionFilter": true,
+}
+
+// NewSession pins a session to the provider the pool would try first for
+// a call now, preferring healthy ones.
+func (p *Pool) NewSession() *Session {
+	order := p.order()
+	pick := order[0]
+	for _, pr := range order {
+		if _, streak := pr.stats.health(); streak < unhealthyStreak {
+			pick = pr
+			break
+		}
+	}
+	return &Session{p: p, pr: pick, filters: map[string]bool{}}
+}
+
+// NewArchiveSession is like NewSession but only considers providers not
+// known to be full nodes, for debug_* and trace_* sequences. It returns
+// ErrNoArchive if there are none.
+func (p *Pool) NewArchiveSession() (*Session, error) {
+	order, _ := archiveOrder(p.order())
+	if len(order) == 0 {
+		return nil, ErrNoArchive
+	}
+	return &Session{p: p, pr: order[0], filters: map[string]bool{}}, nil
+}
+
+// Provider returns the URL of the provider the session is pinned to.
+func (s *Session) Provider() string { return s.pr.url }
+
+// Call sends a JSON-RPC request to the session's provider.
+func (s *Session) Call(method, paramsJSON string, opts ...Option) (string, error) {
+	return s.CallContext(context.Background(), method, paramsJSON, opts...)
+}
+
+// CallContext sends a JSON-RPC request to the session's provider. Pool
+// defaults (cache, metrics, tracer, cassette, rate limits) apply as for
+// Pool.CallContext, and a retry policy retries against the same provider.
+func (s *Session) CallContext(ctx context.Context, method, paramsJSON string, opts ...Option) (string, error) {
+	s.mu.Lock()
+	released := s.released
+	s.mu.Unlock()
+	if released {
+		return "", ErrSessionReleased
+	}
+	o, err := s.p.callOptions(opts)
+	if err != nil {
+		return "", err
+	}
+	return o.traced(ctx, method, s.pr.url, func(ctx context.Context) (string, error) {
+		return o.taped(method, paramsJSON, func() (string, error) {
+			blockNumber := func() (string, error) {
+				return o.loop(ctx, func() (string, error) { return s.attempt(ctx, o, "eth_blockNumber", "[]") })
+			}
+			paramsJSON, err := pinParams(ctx, method, paramsJSON, blockNumber)
+			if err != nil {
+				return "", err
+			}
+			out, err := o.cached(method, paramsJSON, blockNumber, func() (string, error) {
+				return o.loop(ctx, func() (string, error) { return s.attempt(ctx, o, method, paramsJSON) })
+			})
+			if err == nil {
+				s.track(method, paramsJSON, out)
+			}
+			return out, err
+		})
+	})
+}
+
+// attempt sends one request to the pinned provider, recording it in the
+// provider's stats like a pool pass would.
+func (s *Session) attempt(ctx context.Context, o *callOptions, method, paramsJSON string) (string, error) {
+	pr := s.pr
+	route := &Route{Method: method}
+	defer s.p.recordRoute(route)
+	if err := s.p.acquire(ctx, pr); err != nil {
+		if errors.Is(err, ErrRateLimited) {
+			pr.stats.recordRateLimited()
+			route.Skipped = append(route.Skipped, SkippedProvider{URL: pr.url, Reason: "rate limited"})
+		}
+		return "", err
+	}
+	start := time.Now()
+	out, err := s.p.send(ctx, o, pr, method, paramsJSON)
+	switch {
+	case err == nil:
+		pr.stats.recordSuccess(method, out, time.Since(start))
+	case !shouldFailover(err):
+		pr.stats.recordSuccess(method, "", time.Since(start))
+	default:
+		pr.stats.recordFailure(err, time.Since(start))
+		route.Skipped = append(route.Skipped, SkippedProvider{URL: pr.url, Reason: err.Error()})
+		return "", err
+	}
+	route.Provider = pr.url
+	o.servedBy = pr.url
+	return out, err
+}
+
+// track remembers filters the session creates and forgets uninstalled
+// ones.
+func (s *Session) track(method, paramsJSON, result string) {
+	var id string
+	switch {
+	case filterCreators[method]:
+		if json.Unmarshal([]byte(result), &id) != nil {
+			return
+		}
+		s.mu.Lock()
+		s.filters[id] = true
+		s.mu.Unlock()
+	case method == "eth_uninstallFilter":
+		var params []string
+		if json.Unmarshal([]byte(paramsJSON), &params) != nil || len(params) == 0 {
+			return
+		}
+		s.mu.Lock()
+		delete(s.filters, params[0])
+		s.mu.Unlock()
+	}
+}
+
+// Release ends the session, uninstalling on a best-effort basis any
+// filters it created that are still installed. Later calls return
+// ErrSessionReleased. Release is idempotent.
+func (s *Session) Release() {
+	s.mu.Lock()
+	if s.released {
+		s.mu.Unlock()
+		return
+	}
+	s.released = true
+	filters := s.filters
+	s.filters = nil
+	s.mu.Unlock()
+	for id := range filters {
+		call(s.pr.url, "eth_uninstallFilter", mustJSON([]string{id}), s.pr.auth())
+	}
+}
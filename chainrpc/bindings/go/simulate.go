@@ -0,0 +1,313 @@
+package chainrpc
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// AccountOverride replaces parts of an account's state for the duration of
+// a call. Nil and empty fields leave the account as it is.
+type AccountOverride struct {
+	Balance *big.Int
+	Nonce   *uint64
+	// Code is the hex bytecode to run at the address.
+	Code string
+	// State replaces the account's whole storage with these slots; StateDiff
+	// sets only these slots. At most one of the two may be set. Slots and
+	// values are 32-byte hex.
+	State     map[string]string
+	StateDiff map[string]string
+	// MovePrecompileTo relocates a precompile at this address to another
+	// one, so Code can wrap it (eth_simulateV1 only).
+	MovePrecompileTo string
+}
+
+// MarshalJSON encodes o the way eth_call and eth_simulateV1 expect.
+func (o AccountOverride) MarshalJSON() ([]byte, error) {
+	if o.State != nil && o.StateDiff != nil {
+		return nil, fmt.Errorf("chainrpc: account override sets both state and stateDiff")
+	}
+	m := map[string]interface{}{}
+	if o.Balance != nil {
+		m["balance"] = fmt.Sprintf("0x%x", o.Balance)
+	}
+	if o.Nonce != nil {
+		m["nonce"] = fmt.Sprintf("0x%x", *o.Nonce)
+	}
+	if o.Code != "" {
+		m["code"] = o.Code
+	}
+	if o.State != nil {
+		m["state"] = o.State
+	}
+	if o.StateDiff != nil {
+		m["stateDiff"] = o.StateDiff
+	}
+	if o.MovePrecompileTo != "" {
+		m["movePrecompileToAddress"] = o.MovePrecompileTo
+	}
+	return json.Marshal(m)
+}
+
+// StateOverride maps addresses to the overrides applied to them.
+type StateOverride map[string]AccountOverride
+
+// BlockOverrides replaces fields of the block a simulated call runs in.
+// Nil and empty fields keep the values the node would use.
+type BlockOverrides struct {
+	Number       *uint64
+	Time         *uint64
+	GasLimit     *uint64
+	FeeRecipient string
+	PrevRandao   string
+	BaseFee      *big.Int
+	BlobBaseFee  *big.Int
+}
+
+// MarshalJSON encodes o as an eth_simulateV1 blockOverrides object.
+func (o BlockOverrides) MarshalJSON() ([]byte, error) {
+	m := map[string]interface{}{}
+	for key, v := range map[string]*uint64{"number": o.Number, "time": o.Time, "gasLimit": o.GasLimit} {
+		if v != nil {
+			m[key] = fmt.Sprintf("0x%x", *v)
+		}
+	}
+	for key, v := range map[string]*big.Int{"baseFeePerGas": o.BaseFee, "blobBaseFee": o.BlobBaseFee} {
+		if v != nil {
+			m[key] = fmt.Sprintf("0x%x", v)
+		}
+	}
+	if o.FeeRecipient != "" {
+		m["feeRecipient"] = o.FeeRecipient
+	}
+	if o.PrevRandao != "" {
+		m["prevRandao"] = o.PrevRandao
+	}
+	return json.Marshal(m)
+}
+
+// CallWithOverride runs eth_call for msg on top of block ("latest" if
+// empty) with the state in override replaced, and returns the raw return
+// data. It answers "what if" questions before sending a transaction: would
+// it succeed with this balance, this approval, this contract upgrade. A
+// reverting call returns a *RevertError.
+func CallWithOverride(ctx context.Context, url string, msg CallMsg, block string, override StateOverride) ([]byte, error) {
+	return callWithOverride(ctx, urlCaller(url), msg, block, override)
+}
+
+// CallWithOverride runs eth_call with a state override through the pool.
+func (p *Pool) CallWithOverride(ctx context.Context, msg CallMsg, block string, override StateOverride) ([]byte, error) {
+	return callWithOverride(ctx, p.caller(), msg, block, override)
+}
+
+func callWithOverride(ctx context.Context, call callFunc, msg CallMsg, block string, override StateOverride) ([]byte, error) {
+	params := traceCallParams(msg, block)
+	if len(override) > 0 {
+		params = append(params, override)
+	}
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	res, err := call(ctx, "eth_call", string(paramsJSON))
+	if err != nil {
+		if re, ok := AsRevert(err); ok {
+			return nil, re
+		}
+		return nil, err
+	}
+	var out string
+	if err := json.Unmarshal([]byte(res), &out); err != nil {
+		return nil, fmt.Errorf("chainrpc: eth_call result: %w", err)
+	}
+	ret, err := hex.DecodeString(strings.TrimPrefix(out, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("chainrpc: eth_call result: %w", err)
+	}
+	return ret, nil
+}
+
+// SimBlock is one block of an eth_simulateV1 request: calls executed in
+// order, each seeing the effects of the ones before it.
+type SimBlock struct {
+	BlockOverrides *BlockOverrides `json:"blockOverrides,omitempty"`
+	StateOverrides StateOverride   `json:"stateOverrides,omitempty"`
+	Calls          []CallMsg       `json:"-"`
+}
+
+// MarshalJSON encodes b with its calls in transaction-object form.
+func (b SimBlock) MarshalJSON() ([]byte, error) {
+	type plain SimBlock
+	calls := make([]map[string]string, len(b.Calls))
+	for i, c := range b.Calls {
+		calls[i] = c.params()
+	}
+	return json.Marshal(struct {
+		plain
+		Calls []map[string]string `json:"calls"`
+	}{plain(b), calls})
+}
+
+// SimulateOptions configures an eth_simulateV1 request. Zero values keep
+// the node's defaults.
+type SimulateOptions struct {
+	// Validation applies the checks a real transaction would face: nonces,
+	// balances for gas, base fee.
+	Validation bool
+	// TraceTransfers reports ETH transfers as logs from
+	// 0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee.
+	TraceTransfers bool
+	// ReturnFullTransactions returns transaction objects instead of hashes
+	// in each block's Raw JSON.
+	ReturnFullTransactions bool
+}
+
+// SimulateCallError is the error of a failed simulated call.
+type SimulateCallError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+// SimulatedCall is the outcome of one call in a simulated block.
+type SimulatedCall struct {
+	// Status is 1 for success and 0 for failure, as in a receipt.
+	Status     uint64
+	ReturnData string
+	GasUsed    uint64
+	Logs       []Log
+	// Error is set when the call failed.
+	Error *SimulateCallError
+}
+
+// UnmarshalJSON decodes a node's simulated call, where numbers are hex
+// quantities.
+func (c *SimulatedCall) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Status     string             `json:"status"`
+		ReturnData string             `json:"returnData"`
+		GasUsed    string             `json:"gasUsed"`
+		Logs       []Log              `json:"logs"`
+		Error      *SimulateCallError `json:"error"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*c = SimulatedCall{ReturnData: raw.ReturnData, Logs: raw.Logs, Error: raw.Error}
+	var err error
+	if c.Status, err = hexQuantity(raw.Status); err != nil {
+		return fmt.Errorf("status: %w", err)
+	}
+	if c.GasUsed, err = hexQuantity(raw.GasUsed); err != nil {
+		return fmt.Errorf("gasUsed: %w", err)
+	}
+	return nil
+}
+
+// Failed reports whether the call failed.
+func (c *SimulatedCall) Failed() bool { return c.Status == 0 || c.Error != nil }
+
+// Revert returns the call's failure as a *RevertError, decoded like the
+// errors of Call, or nil if the call did not revert.
+func (c *SimulatedCall) Revert() *RevertError {
+	if !c.Failed() {
+		return nil
+	}
+	re := &RevertError{Code: 3, Message: "execution reverted", Data: c.ReturnData}
+	if c.Error != nil {
+		if !strings.Contains(strings.ToLower(c.Error.Message), "revert") {
+			return nil
+		}
+		re.Code, re.Message = c.Error.Code, c.Error.Message
+		if c.Error.Data != "" {
+			re.Data = c.Error.Data
+		}
+	}
+	if re.Data == "" {
+		re.Data = "0x"
+	}
+	re.decode()
+	return re
+}
+
+// SimulatedBlock is one block of an eth_simulateV1 result.
+type SimulatedBlock struct {
+	Header
+	GasLimit uint64
+	GasUsed  uint64
+	// Calls holds the outcome of each of the block's calls, in order.
+	Calls []SimulatedCall
+}
+
+// UnmarshalJSON decodes a node's simulated block.
+func (s *SimulatedBlock) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		GasLimit string          `json:"gasLimit"`
+		GasUsed  string          `json:"gasUsed"`
+		Calls    []SimulatedCall `json:"calls"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*s = SimulatedBlock{Calls: raw.Calls}
+	if err := s.Header.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	var err error
+	if s.GasLimit, err = hexQuantity(raw.GasLimit); err != nil {
+		return fmt.Errorf("gasLimit: %w", err)
+	}
+	if s.GasUsed, err = hexQuantity(raw.GasUsed); err != nil {
+		return fmt.Errorf("gasUsed: %w", err)
+	}
+	return nil
+}
+
+// SimulateV1 runs eth_simulateV1: blocks are simulated in sequence on top
+// of block ("latest" if empty), each seeing the state left by the ones
+// before, without anything being sent. Use it to pre-flight a bundle,
+// e.g. an approve followed by a swap. A failing call is reported in its
+// SimulatedCall, not as an error.
+func SimulateV1(ctx context.Context, url string, blocks []SimBlock, block string, opts *SimulateOptions) ([]SimulatedBlock, error) {
+	return simulateV1(ctx, urlCaller(url), blocks, block, opts)
+}
+
+// SimulateV1 runs eth_simulateV1 through the pool.
+func (p *Pool) SimulateV1(ctx context.Context, blocks []SimBlock, block string, opts *SimulateOptions) ([]SimulatedBlock, error) {
+	return simulateV1(ctx, p.caller(), blocks, block, opts)
+}
+
+func simulateV1(ctx context.Context, call callFunc, blocks []SimBlock, block string, opts *SimulateOptions) ([]SimulatedBlock, error) {
+	if block == "" {
+		block = "latest"
+	}
+	payload := map[string]interface{}{"blockStateCalls": blocks}
+	if opts != nil {
+		if opts.Validation {
+			payload["validation"] = true
+		}
+		if opts.TraceTransfers {
+			payload["traceTransfers"] = true
+		}
+		if opts.ReturnFullTransactions {
+			payload["returnFullTransactions"] = true
+		}
+	}
+	params, err := json.Marshal([]interface{}{payload, block})
+	if err != nil {
+		return nil, err
+	}
+	res, err := call(ctx, "eth_simulateV1", string(params))
+	if err != nil {
+		return nil, err
+	}
+	var out []SimulatedBlock
+	if err := json.Unmarshal([]byte(res), &out); err != nil {
+		return nil, fmt.Errorf("chainrpc: eth_simulateV1 result: %w", err)
+	}
+	return out, nil
+}
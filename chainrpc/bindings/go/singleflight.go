@@ -0,0 +1,66 @@
+package chainrpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// flight is a call in progress that identical calls wait on.
+type flight struct {
+	done chan struct{}
+	out  string
+	err  error
+}
+
+// flightGroup coalesces identical concurrent calls into one.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// do runs fn for key unless a call for key is already in flight, in which
+// case it waits for that call's result. A waiter whose own context is
+// still live runs fn itself if the call it waited on ended with the
+// caller's context.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() (string, error)) (string, error) {
+	g.mu.Lock()
+	if f, ok := g.flights[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		if errors.Is(f.err, context.Canceled) || errors.Is(f.err, context.DeadlineExceeded) {
+			if ctx.Err() == nil {
+				return g.do(ctx, key, fn)
+			}
+		}
+		return f.out, f.err
+	}
+	if g.flights == nil {
+		g.flights = map[string]*flight{}
+	}
+	f := &flight{done: make(chan struct{})}
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+	f.out, f.err = fn()
+	return f.out, f.err
+}
+
+// coalesced sends method through fn, sharing the outbound call with
+// identical reads already in flight when the pool coalesces.
+func (p *Pool) coalesced(ctx context.Context, method, paramsJSON string, fn func() (string, error)) (string, error) {
+	if !p.opts.Coalesce || !hedgeable(method) {
+		return fn()
+	}
+	return p.flights.do(ctx, method+"\x00"+canonicalJSON(paramsJSON), fn)
+}
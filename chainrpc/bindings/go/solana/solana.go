@@ -0,0 +1,350 @@
+// Package solana adds typed helpers for Solana's JSON-RPC API on top of
+// chainrpc's transports. Requests go through chainrpc.CallContext or a
+// chainrpc.Pool, so retries, caching, metrics and failover apply as for
+// any other call.
+//
+// Transactions are requested with the jsonParsed encoding: instructions of
+// programs the node understands (System, SPL Token, …) carry a decoded
+// Parsed value, others their raw accounts and base58 data.
+package solana
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/DarshanKumar89/chainfoundry/chainrpc"
+)
+
+// Commitment is how final the state a query reads must be.
+type Commitment string
+
+const (
+	// Processed reads the node's most recent block, which may be skipped.
+	Processed Commitment = "processed"
+	// Confirmed reads the most recent block voted on by a supermajority.
+	Confirmed Commitment = "confirmed"
+	// Finalized reads the most recent rooted block. Nodes default to it.
+	Finalized Commitment = "finalized"
+)
+
+// Error codes Solana nodes return for blocks that do not exist.
+const (
+	CodeBlockNotAvailable          = -32004
+	CodeSlotSkipped                = -32007
+	CodeLongTermStorageSlotSkipped = -32009
+)
+
+// IsSkippedSlot reports whether err says the requested slot produced no
+// block, which is normal on Solana and not a reason to retry.
+func IsSkippedSlot(err error) bool {
+	var rpcErr *chainrpc.RPCError
+	return errors.As(err, &rpcErr) && (rpcErr.Code == CodeSlotSkipped || rpcErr.Code == CodeLongTermStorageSlotSkipped)
+}
+
+// Client issues Solana RPC calls.
+type Client struct {
+	call func(ctx context.Context, method, paramsJSON string) (string, error)
+}
+
+// New returns a client for the node at url; opts apply to every call.
+func New(url string, opts ...chainrpc.Option) *Client {
+	return &Client{call: func(ctx context.Context, method, paramsJSON string) (string, error) {
+		return chainrpc.CallContext(ctx, url, method, paramsJSON, opts...)
+	}}
+}
+
+// NewFromPool returns a client that routes through p.
+func NewFromPool(p *chainrpc.Pool, opts ...chainrpc.Option) *Client {
+	return &Client{call: func(ctx context.Context, method, paramsJSON string) (string, error) {
+		return p.CallContext(ctx, method, paramsJSON, opts...)
+	}}
+}
+
+// do calls method and decodes its result into out. It reports false when
+// the result is null.
+func (c *Client) do(ctx context.Context, method string, params []interface{}, out interface{}) (bool, error) {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return false, err
+	}
+	res, err := c.call(ctx, method, string(b))
+	if err != nil {
+		return false, err
+	}
+	if res == "null" {
+		return false, nil
+	}
+	if err := json.Unmarshal([]byte(res), out); err != nil {
+		return false, fmt.Errorf("solana: %s result: %w", method, err)
+	}
+	return true, nil
+}
+
+// config is the trailing configuration object most methods take; empty
+// fields are left to the node's defaults.
+type config map[string]interface{}
+
+func (cfg config) commitment(c Commitment) config {
+	if c != "" {
+		cfg["commitment"] = c
+	}
+	return cfg
+}
+
+// GetSlot returns the slot that has reached commitment.
+func (c *Client) GetSlot(ctx context.Context, commitment Commitment) (uint64, error) {
+	var slot uint64
+	_, err := c.do(ctx, "getSlot", []interface{}{config{}.commitment(commitment)}, &slot)
+	return slot, err
+}
+
+// TransactionDetails selects how much of each transaction GetBlock returns.
+type TransactionDetails string
+
+const (
+	DetailsFull       TransactionDetails = "full"
+	DetailsSignatures TransactionDetails = "signatures"
+	DetailsNone       TransactionDetails = "none"
+)
+
+// BlockOptions configures GetBlock. The zero value returns full parsed
+// transactions and rewards at the node's default commitment.
+type BlockOptions struct {
+	Commitment Commitment
+	// Details defaults to DetailsFull.
+	Details TransactionDetails
+	// NoRewards leaves out the block's rewards.
+	NoRewards bool
+}
+
+// Block is a confirmed block. Transactions is set with DetailsFull and
+// Signatures with DetailsSignatures.
+type Block struct {
+	Blockhash         string                `json:"blockhash"`
+	PreviousBlockhash string                `json:"previousBlockhash"`
+	ParentSlot        uint64                `json:"parentSlot"`
+	BlockHeight       *uint64               `json:"blockHeight"`
+	BlockTime         *int64                `json:"blockTime"`
+	Transactions      []TransactionWithMeta `json:"transactions"`
+	Signatures        []string              `json:"signatures"`
+	Rewards           []Reward              `json:"rewards"`
+}
+
+// Reward is a balance change credited by the block.
+type Reward struct {
+	Pubkey      string `json:"pubkey"`
+	Lamports    int64  `json:"lamports"`
+	PostBalance uint64 `json:"postBalance"`
+	// RewardType is "fee", "rent", "voting" or "staking".
+	RewardType *string `json:"rewardType"`
+	Commission *int    `json:"commission"`
+}
+
+// GetBlock returns the block produced at slot, or nil if the node returns
+// none. A skipped slot yields an error for which IsSkippedSlot is true.
+func (c *Client) GetBlock(ctx context.Context, slot uint64, opts *BlockOptions) (*Block, error) {
+	if opts == nil {
+		opts = &BlockOptions{}
+	}
+	details := opts.Details
+	if details == "" {
+		details = DetailsFull
+	}
+	cfg := config{
+		"encoding":                       "jsonParsed",
+		"transactionDetails":             details,
+		"rewards":                        !opts.NoRewards,
+		"maxSupportedTransactionVersion": 0,
+	}.commitment(opts.Commitment)
+	var b Block
+	ok, err := c.do(ctx, "getBlock", []interface{}{slot, cfg}, &b)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// SignaturesOptions configures GetSignaturesForAddress.
+type SignaturesOptions struct {
+	Commitment Commitment
+	// Limit caps the number of results, at most 1000 (the node default).
+	Limit int
+	// Before starts the search backwards from this signature, exclusive.
+	Before string
+	// Until stops the search at this signature, exclusive.
+	Until string
+	// MinContextSlot fails the request if the node has not reached it.
+	MinContextSlot uint64
+}
+
+// SignatureInfo is one transaction that touched an address.
+type SignatureInfo struct {
+	Signature string `json:"signature"`
+	Slot      uint64 `json:"slot"`
+	// Err is the transaction error, null if it succeeded.
+	Err                json.RawMessage `json:"err"`
+	Memo               *string         `json:"memo"`
+	BlockTime          *int64          `json:"blockTime"`
+	ConfirmationStatus Commitment      `json:"confirmationStatus"`
+}
+
+// Failed reports whether the transaction failed.
+func (s SignatureInfo) Failed() bool { return failed(s.Err) }
+
+// GetSignaturesForAddress returns signatures of transactions that
+// reference address, newest first. Page through older ones by passing the
+// last signature as Before.
+func (c *Client) GetSignaturesForAddress(ctx context.Context, address string, opts *SignaturesOptions) ([]SignatureInfo, error) {
+	if opts == nil {
+		opts = &SignaturesOptions{}
+	}
+	cfg := config{}.commitment(opts.Commitment)
+	if opts.Limit > 0 {
+		cfg["limit"] = opts.Limit
+	}
+	if opts.Before != "" {
+		cfg["before"] = opts.Before
+	}
+	if opts.Until != "" {
+		cfg["until"] = opts.Until
+	}
+	if opts.MinContextSlot > 0 {
+		cfg["minContextSlot"] = opts.MinContextSlot
+	}
+	var out []SignatureInfo
+	_, err := c.do(ctx, "getSignaturesForAddress", []interface{}{address, cfg}, &out)
+	return out, err
+}
+
+// Transaction is a confirmed transaction from GetTransaction.
+type Transaction struct {
+	Slot      uint64 `json:"slot"`
+	BlockTime *int64 `json:"blockTime"`
+	TransactionWithMeta
+}
+
+// TransactionWithMeta is a parsed transaction and its execution status.
+type TransactionWithMeta struct {
+	Transaction ParsedTransaction `json:"transaction"`
+	Meta        *TransactionMeta  `json:"meta"`
+	// Version is "legacy" or a version number such as 0.
+	Version json.RawMessage `json:"version"`
+}
+
+// ParsedTransaction is a transaction in the jsonParsed encoding.
+type ParsedTransaction struct {
+	Signatures []string      `json:"signatures"`
+	Message    ParsedMessage `json:"message"`
+}
+
+// ParsedMessage is the signed part of a transaction.
+type ParsedMessage struct {
+	AccountKeys     []AccountKey  `json:"accountKeys"`
+	RecentBlockhash string        `json:"recentBlockhash"`
+	Instructions    []Instruction `json:"instructions"`
+	// AddressTableLookups lists the lookup tables of a version 0
+	// transaction.
+	AddressTableLookups []AddressTableLookup `json:"addressTableLookups"`
+}
+
+// AccountKey is an account the transaction references.
+type AccountKey struct {
+	Pubkey   string `json:"pubkey"`
+	Signer   bool   `json:"signer"`
+	Writable bool   `json:"writable"`
+	// Source is "transaction" or "lookupTable".
+	Source string `json:"source"`
+}
+
+// AddressTableLookup loads accounts from an address lookup table.
+type AddressTableLookup struct {
+	AccountKey      string `json:"accountKey"`
+	WritableIndexes []int  `json:"writableIndexes"`
+	ReadonlyIndexes []int  `json:"readonlyIndexes"`
+}
+
+// Instruction is one instruction. Parsed is set when the node can decode
+// the program, e.g. {"type":"transfer","info":{…}} (or a plain string for
+// the memo program); otherwise Accounts and base58 Data are.
+type Instruction struct {
+	ProgramID   string          `json:"programId"`
+	Program     string          `json:"program,omitempty"`
+	Parsed      json.RawMessage `json:"parsed,omitempty"`
+	Accounts    []string        `json:"accounts,omitempty"`
+	Data        string          `json:"data,omitempty"`
+	StackHeight *int            `json:"stackHeight"`
+}
+
+// ParsedType returns the instruction type of a parsed instruction, such as
+// "transfer", or "".
+func (i Instruction) ParsedType() string {
+	var p struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(i.Parsed, &p) != nil {
+		return ""
+	}
+	return p.Type
+}
+
+// TransactionMeta is the execution status of a transaction.
+type TransactionMeta struct {
+	// Err is the transaction error, null if it succeeded.
+	Err                  json.RawMessage     `json:"err"`
+	Fee                  uint64              `json:"fee"`
+	PreBalances          []uint64            `json:"preBalances"`
+	PostBalances         []uint64            `json:"postBalances"`
+	InnerInstructions    []InnerInstructions `json:"innerInstructions"`
+	LogMessages          []string            `json:"logMessages"`
+	PreTokenBalances     []TokenBalance      `json:"preTokenBalances"`
+	PostTokenBalances    []TokenBalance      `json:"postTokenBalances"`
+	ComputeUnitsConsumed *uint64             `json:"computeUnitsConsumed"`
+}
+
+// Failed reports whether the transaction failed.
+func (m *TransactionMeta) Failed() bool { return failed(m.Err) }
+
+// InnerInstructions are the instructions invoked by the instruction at
+// Index.
+type InnerInstructions struct {
+	Index        int           `json:"index"`
+	Instructions []Instruction `json:"instructions"`
+}
+
+// TokenBalance is an SPL token account balance before or after a
+// transaction.
+type TokenBalance struct {
+	AccountIndex  int         `json:"accountIndex"`
+	Mint          string      `json:"mint"`
+	Owner         string      `json:"owner"`
+	ProgramID     string      `json:"programId"`
+	UITokenAmount TokenAmount `json:"uiTokenAmount"`
+}
+
+// TokenAmount is a token quantity; Amount is the raw integer as a string.
+type TokenAmount struct {
+	Amount         string `json:"amount"`
+	Decimals       int    `json:"decimals"`
+	UIAmountString string `json:"uiAmountString"`
+}
+
+// GetTransaction returns the confirmed transaction with the given
+// signature, or nil if the node does not know it.
+func (c *Client) GetTransaction(ctx context.Context, signature string, commitment Commitment) (*Transaction, error) {
+	cfg := config{
+		"encoding":                       "jsonParsed",
+		"maxSupportedTransactionVersion": 0,
+	}.commitment(commitment)
+	var tx Transaction
+	ok, err := c.do(ctx, "getTransaction", []interface{}{signature, cfg}, &tx)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+func failed(err json.RawMessage) bool {
+	return len(err) > 0 && string(err) != "null"
+}
@@ -6,11 +6,19 @@
 use std::ffi::{CStr, CString};
 use std::os::raw::c_char;
 use std::cell::RefCell;
-use std::sync::OnceLock;
+use std::collections::HashMap;
+use std::sync::{Mutex, OnceLock};
 
 use tokio::runtime::Runtime;
-use chainrpc_http::{HttpRpcClient, pool_from_urls};
-use chainrpc_core::{pool::ProviderPool, request::JsonRpcRequest, transport::RpcTransport};
+use std::sync::Arc;
+use std::time::Duration;
+
+use chainrpc_http::{HttpClientConfig, HttpRpcClient, HttpTransportOptions};
+use chainrpc_core::{
+    pool::{ProviderPool, ProviderPoolConfig},
+    request::{JsonRpcRequest, JsonRpcResponse},
+    transport::RpcTransport,
+};
 
 // ─── Global Tokio runtime ─────────────────────────────────────────────────────
 
@@ -35,6 +43,16 @@ fn set_last_error(msg: &str) {
     LAST_ERROR.with(|e| { *e.borrow_mut() = CString::new(msg).ok(); });
 }
 
+/// Format a JSON-RPC error for `last_error`: `JSON-RPC {code}: {message}`,
+/// followed by ` data: {json}` when the node attached error data (e.g.
+/// revert bytes from eth_call / eth_estimateGas).
+fn rpc_error_message(err: &chainrpc_core::request::JsonRpcError) -> String {
+    match &err.data {
+        Some(data) => format!("JSON-RPC {}: {} data: {}", err.code, err.message, data),
+        None => format!("JSON-RPC {}: {}", err.code, err.message),
+    }
+}
+
 fn clear_last_error() {
     LAST_ERROR.with(|e| { *e.borrow_mut() = None; });
 }
@@ -101,7 +119,10 @@ pub extern "C" fn chainrpc_call(
         }
     };
 
-    let client = HttpRpcClient::default_for(&url_str);
+    let client = match cached_client(&url_str, "") {
+        Ok(c) => c,
+        Err(e) => { set_last_error(&e); return std::ptr::null_mut(); }
+    };
 
     let params: Vec<serde_json::Value> = match serde_json::from_str(&params_str) {
         Ok(p) => p,
@@ -115,7 +136,7 @@ pub extern "C" fn chainrpc_call(
         Err(e) => { set_last_error(&e.to_string()); std::ptr::null_mut() }
         Ok(resp) => {
             if let Some(err) = resp.error {
-                set_last_error(&format!("JSON-RPC {}: {}", err.code, err.message));
+                set_last_error(&rpc_error_message(&err));
                 return std::ptr::null_mut();
             }
             let out = resp.result
@@ -163,14 +184,9 @@ pub extern "C" fn chainrpc_pool_call(
         }
     };
 
-    let urls: Vec<String> = match serde_json::from_str(&urls_str) {
-        Ok(u) => u,
-        Err(e) => { set_last_error(&format!("urls_json parse: {e}")); return std::ptr::null_mut(); }
-    };
-    let url_refs: Vec<&str> = urls.iter().map(|s| s.as_str()).collect();
-    let pool = match pool_from_urls(&url_refs) {
+    let pool = match cached_pool(&urls_str, "") {
         Ok(p) => p,
-        Err(e) => { set_last_error(&e.to_string()); return std::ptr::null_mut(); }
+        Err(e) => { set_last_error(&e); return std::ptr::null_mut(); }
     };
 
     let params: Vec<serde_json::Value> = match serde_json::from_str(&params_str) {
@@ -185,7 +201,128 @@ pub extern "C" fn chainrpc_pool_call(
         Err(e) => { set_last_error(&e.to_string()); std::ptr::null_mut() }
         Ok(resp) => {
             if let Some(err) = resp.error {
-                set_last_error(&format!("JSON-RPC {}: {}", err.code, err.message));
+                set_last_error(&rpc_error_message(&err));
+                return std::ptr::null_mut();
+            }
+            let out = resp.result
+                .map(|v| v.to_string())
+                .unwrap_or_else(|| "null".into());
+            match CString::new(out) {
+                Ok(s) => s.into_raw(),
+                Err(e) => { set_last_error(&e.to_string()); std::ptr::null_mut() }
+            }
+        }
+    }
+}
+
+// ─── HTTP options ─────────────────────────────────────────────────────────────
+
+fn read_str(ptr: *const c_char, name: &str) -> Option<String> {
+    match unsafe { CStr::from_ptr(ptr) }.to_str() {
+        Ok(s) => Some(s.to_owned()),
+        Err(_) => { set_last_error(&format!("invalid UTF-8 in {name}")); None }
+    }
+}
+
+/// Parse the `options_json` object accepted by the `*_with_options` calls:
+///
+/// {"headers":{"X-Api-Key":"..."},"bearer_token":"...","proxy":"socks5://...",
+///  "ca_cert_pem":"-----BEGIN...","client_identity_pem":"...","timeout_ms":5000,
+///  "max_request_bytes":1048576,"max_idle_per_host":16,"idle_timeout_ms":90000,
+///  "tcp_keepalive_ms":30000,"http1_only":false,"max_retries":3}
+fn parse_http_options(json: &str) -> Result<HttpTransportOptions, String> {
+    let v: serde_json::Value = serde_json::from_str(json).map_err(|e| format!("options parse: {e}"))?;
+    let str_field = |k: &str| v.get(k).and_then(|x| x.as_str()).map(str::to_owned);
+    let mut opts = HttpTransportOptions {
+        bearer_token: str_field("bearer_token"),
+        proxy: str_field("proxy"),
+        ca_cert_pem: str_field("ca_cert_pem"),
+        client_identity_pem: str_field("client_identity_pem"),
+        timeout: v.get("timeout_ms").and_then(|x| x.as_u64()).map(Duration::from_millis),
+        max_request_bytes: v.get("max_request_bytes").and_then(|x| x.as_u64()).map(|n| n as usize),
+        pool_max_idle_per_host: v.get("max_idle_per_host").and_then(|x| x.as_u64()).map(|n| n as usize),
+        pool_idle_timeout: v.get("idle_timeout_ms").and_then(|x| x.as_u64()).map(Duration::from_millis),
+        tcp_keepalive: v.get("tcp_keepalive_ms").and_then(|x| x.as_u64()).map(Duration::from_millis),
+        http1_only: v.get("http1_only").and_then(|x| x.as_bool()).unwrap_or(false),
+        max_retries: v.get("max_retries").and_then(|x| x.as_u64()).map(|n| n as u32),
+        ..Default::default()
+    };
+    if let Some(headers) = v.get("headers").and_then(|h| h.as_object()) {
+        for (k, val) in headers {
+            let val = val.as_str().ok_or_else(|| format!("header {k:?} must be a string"))?;
+            opts.headers.push((k.clone(), val.to_owned()));
+        }
+    }
+    Ok(opts)
+}
+
+// ─── Client cache ─────────────────────────────────────────────────────────────
+//
+// A client owns reqwest's connection pool, so building one per call costs a
+// new TCP connection and TLS handshake per call. Clients and pools are kept
+// per (URL, options_json) instead, so repeated calls reuse kept-alive
+// connections (and share retry, circuit breaker and rate limiter state).
+
+/// Cached clients beyond this are dropped wholesale, bounding growth when
+/// options change per call (e.g. rotating credentials).
+const MAX_CACHED_CLIENTS: usize = 256;
+
+type ClientKey = (String, String);
+
+static CLIENTS: OnceLock<Mutex<HashMap<ClientKey, Arc<HttpRpcClient>>>> = OnceLock::new();
+static POOLS: OnceLock<Mutex<HashMap<ClientKey, Arc<ProviderPool>>>> = OnceLock::new();
+
+/// Client for `url` with `options_json` (empty for defaults), built on
+/// first use.
+fn cached_client(url: &str, options_json: &str) -> Result<Arc<HttpRpcClient>, String> {
+    let mut clients = CLIENTS.get_or_init(Default::default).lock().unwrap_or_else(|e| e.into_inner());
+    let key = (url.to_owned(), options_json.to_owned());
+    if let Some(c) = clients.get(&key) {
+        return Ok(c.clone());
+    }
+    let client = if options_json.is_empty() {
+        HttpRpcClient::default_for(url)
+    } else {
+        let opts = parse_http_options(options_json)?;
+        HttpRpcClient::with_options(url, HttpClientConfig::default(), &opts).map_err(|e| e.to_string())?
+    };
+    if clients.len() >= MAX_CACHED_CLIENTS {
+        clients.clear();
+    }
+    let client = Arc::new(client);
+    clients.insert(key, client.clone());
+    Ok(client)
+}
+
+/// Pool over the URLs in `urls_json`, sharing `cached_client`'s clients.
+fn cached_pool(urls_json: &str, options_json: &str) -> Result<Arc<ProviderPool>, String> {
+    let key = (urls_json.to_owned(), options_json.to_owned());
+    if let Some(p) = POOLS.get_or_init(Default::default).lock().unwrap_or_else(|e| e.into_inner()).get(&key) {
+        return Ok(p.clone());
+    }
+    let urls: Vec<String> = serde_json::from_str(urls_json).map_err(|e| format!("urls_json parse: {e}"))?;
+    if urls.is_empty() {
+        return Err("no URLs provided".into());
+    }
+    let mut transports: Vec<Arc<dyn RpcTransport>> = Vec::with_capacity(urls.len());
+    for u in &urls {
+        transports.push(cached_client(u, options_json)?);
+    }
+    let pool = Arc::new(ProviderPool::new(transports, ProviderPoolConfig::default()));
+    let mut pools = POOLS.get_or_init(Default::default).lock().unwrap_or_else(|e| e.into_inner());
+    if pools.len() >= MAX_CACHED_CLIENTS {
+        pools.clear();
+    }
+    pools.insert(key, pool.clone());
+    Ok(pool)
+}
+
+fn finish(result: Result<JsonRpcResponse, chainrpc_core::error::TransportError>) -> *mut c_char {
+    match result {
+        Err(e) => { set_last_error(&e.to_string()); std::ptr::null_mut() }
+        Ok(resp) => {
+            if let Some(err) = resp.error {
+                set_last_error(&rpc_error_message(&err));
                 return std::ptr::null_mut();
             }
             let out = resp.result
@@ -198,3 +335,215 @@ pub extern "C" fn chainrpc_pool_call(
         }
     }
 }
+
+/// Like `chainrpc_call`, with HTTP-level options (headers, auth, proxy, TLS,
+/// timeout) given as `options_json`; see `parse_http_options`.
+#[no_mangle]
+pub extern "C" fn chainrpc_call_with_options(
+    url: *const c_char,
+    method: *const c_char,
+    params_json: *const c_char,
+    options_json: *const c_char,
+) -> *mut c_char {
+    clear_last_error();
+    let (Some(url_str), Some(method_str), Some(params_str), Some(opts_str)) = (
+        read_str(url, "url"),
+        read_str(method, "method"),
+        read_str(params_json, "params_json"),
+        read_str(options_json, "options_json"),
+    ) else {
+        return std::ptr::null_mut();
+    };
+    let client = match cached_client(&url_str, &opts_str) {
+        Ok(c) => c,
+        Err(e) => { set_last_error(&e); return std::ptr::null_mut(); }
+    };
+    let params: Vec<serde_json::Value> = match serde_json::from_str(&params_str) {
+        Ok(p) => p,
+        Err(e) => { set_last_error(&format!("params parse: {e}")); return std::ptr::null_mut(); }
+    };
+
+    let req = JsonRpcRequest::auto(method_str, params);
+    finish(runtime().block_on(async move { client.send(req).await }))
+}
+
+/// Like `chainrpc_pool_call`, applying the same HTTP-level options to every
+/// provider in the pool.
+#[no_mangle]
+pub extern "C" fn chainrpc_pool_call_with_options(
+    urls_json: *const c_char,
+    method: *const c_char,
+    params_json: *const c_char,
+    options_json: *const c_char,
+) -> *mut c_char {
+    clear_last_error();
+    let (Some(urls_str), Some(method_str), Some(params_str), Some(opts_str)) = (
+        read_str(urls_json, "urls_json"),
+        read_str(method, "method"),
+        read_str(params_json, "params_json"),
+        read_str(options_json, "options_json"),
+    ) else {
+        return std::ptr::null_mut();
+    };
+    let pool = match cached_pool(&urls_str, &opts_str) {
+        Ok(p) => p,
+        Err(e) => { set_last_error(&e); return std::ptr::null_mut(); }
+    };
+    let params: Vec<serde_json::Value> = match serde_json::from_str(&params_str) {
+        Ok(p) => p,
+        Err(e) => { set_last_error(&format!("params parse: {e}")); return std::ptr::null_mut(); }
+    };
+
+    let req = JsonRpcRequest::auto(method_str, params);
+    finish(runtime().block_on(async move { pool.send(req).await }))
+}
+
+// ─── Result arena ─────────────────────────────────────────────────────────────
+
+thread_local! {
+    /// Per-thread buffer the `*_into` calls serialize results into. It is
+    /// reused call after call, so a hot loop allocates no result strings on
+    /// this side of the boundary.
+    static RESULT: RefCell<Vec<u8>> = RefCell::new(Vec::with_capacity(64 * 1024));
+}
+
+/// Result buffers above this capacity are released once copied out, so one
+/// huge response does not pin its memory to the thread forever.
+const MAX_KEPT_RESULT: usize = 4 << 20;
+
+/// Copy `buf` to `out` when it fits in `out_cap` bytes. Returns buf's length
+/// either way.
+fn copy_result(buf: &mut Vec<u8>, out: *mut c_char, out_cap: usize) -> i64 {
+    let n = buf.len();
+    if n <= out_cap && !out.is_null() {
+        unsafe { std::ptr::copy_nonoverlapping(buf.as_ptr(), out as *mut u8, n) };
+        if buf.capacity() > MAX_KEPT_RESULT {
+            *buf = Vec::new();
+        }
+    }
+    n as i64
+}
+
+/// Like `finish`, but serializes the result into the thread's arena and
+/// copies it to `out`. Returns the result length (-1 on error).
+fn finish_into(
+    result: Result<JsonRpcResponse, chainrpc_core::error::TransportError>,
+    out: *mut c_char,
+    out_cap: usize,
+) -> i64 {
+    let resp = match result {
+        Err(e) => { set_last_error(&e.to_string()); return -1; }
+        Ok(resp) => resp,
+    };
+    if let Some(err) = resp.error {
+        set_last_error(&rpc_error_message(&err));
+        return -1;
+    }
+    RESULT.with(|buf| {
+        let mut buf = buf.borrow_mut();
+        buf.clear();
+        let written = match &resp.result {
+            Some(v) => serde_json::to_writer(&mut *buf, v),
+            None => { buf.extend_from_slice(b"null"); Ok(()) }
+        };
+        if let Err(e) = written {
+            set_last_error(&e.to_string());
+            return -1;
+        }
+        copy_result(&mut buf, out, out_cap)
+    })
+}
+
+/// Options JSON for the `*_into` calls: NULL means the default client.
+fn read_opts(options_json: *const c_char) -> Option<String> {
+    if options_json.is_null() {
+        return Some(String::new());
+    }
+    read_str(options_json, "options_json")
+}
+
+/// Like `chainrpc_call_with_options` (`options_json` may be NULL), writing
+/// the result JSON into the caller's `out` buffer instead of allocating a
+/// string. The result is not NUL-terminated.
+///
+/// Returns the result length, or -1 on error. A length above `out_cap`
+/// means nothing was written: the result is held for
+/// `chainrpc_take_result` on the same thread.
+#[no_mangle]
+pub extern "C" fn chainrpc_call_into(
+    url: *const c_char,
+    method: *const c_char,
+    params_json: *const c_char,
+    options_json: *const c_char,
+    out: *mut c_char,
+    out_cap: usize,
+) -> i64 {
+    clear_last_error();
+    let (Some(url_str), Some(method_str), Some(params_str), Some(opts_str)) = (
+        read_str(url, "url"),
+        read_str(method, "method"),
+        read_str(params_json, "params_json"),
+        read_opts(options_json),
+    ) else {
+        return -1;
+    };
+    let client = match cached_client(&url_str, &opts_str) {
+        Ok(c) => c,
+        Err(e) => { set_last_error(&e); return -1; }
+    };
+    let params: Vec<serde_json::Value> = match serde_json::from_str(&params_str) {
+        Ok(p) => p,
+        Err(e) => { set_last_error(&format!("params parse: {e}")); return -1; }
+    };
+
+    let req = JsonRpcRequest::auto(method_str, params);
+    finish_into(runtime().block_on(async move { client.send(req).await }), out, out_cap)
+}
+
+/// Pool counterpart of `chainrpc_call_into`.
+#[no_mangle]
+pub extern "C" fn chainrpc_pool_call_into(
+    urls_json: *const c_char,
+    method: *const c_char,
+    params_json: *const c_char,
+    options_json: *const c_char,
+    out: *mut c_char,
+    out_cap: usize,
+) -> i64 {
+    clear_last_error();
+    let (Some(urls_str), Some(method_str), Some(params_str), Some(opts_str)) = (
+        read_str(urls_json, "urls_json"),
+        read_str(method, "method"),
+        read_str(params_json, "params_json"),
+        read_opts(options_json),
+    ) else {
+        return -1;
+    };
+    let pool = match cached_pool(&urls_str, &opts_str) {
+        Ok(p) => p,
+        Err(e) => { set_last_error(&e); return -1; }
+    };
+    let params: Vec<serde_json::Value> = match serde_json::from_str(&params_str) {
+        Ok(p) => p,
+        Err(e) => { set_last_error(&format!("params parse: {e}")); return -1; }
+    };
+
+    let req = JsonRpcRequest::auto(method_str, params);
+    finish_into(runtime().block_on(async move { pool.send(req).await }), out, out_cap)
+}
+
+/// Copy the result held by the last `*_into` call on this thread into
+/// `out`, for when it did not fit the caller's buffer.
+///
+/// Returns the result length, or -1 if `out_cap` is still too small.
+#[no_mangle]
+pub extern "C" fn chainrpc_take_result(out: *mut c_char, out_cap: usize) -> i64 {
+    RESULT.with(|buf| {
+        let mut buf = buf.borrow_mut();
+        if buf.len() > out_cap {
+            set_last_error(&format!("result of {} bytes does not fit {out_cap}", buf.len()));
+            return -1;
+        }
+        copy_result(&mut buf, out, out_cap)
+    })
+}
@@ -0,0 +1,198 @@
+package chainrpc
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyWindow is the number of recent samples kept per provider for
+// percentile estimation.
+const latencyWindow = 256
+
+// ProviderStats is a point-in-time snapshot of one provider's health.
+type ProviderStats struct {
+	URL                 string        `json:"url"`
+	Label               string        `json:"label,omitempty"`
+	Weight              float64       `json:"weight"`
+	Priority            int           `json:"priority"`
+	Requests            uint64        `json:"requests"`
+	Errors              uint64        `json:"errors"`
+	RateLimited         uint64        `json:"rate_limited"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+	LastError           string        `json:"last_error,omitempty"`
+	LastErrorAt         time.Time     `json:"last_error_at,omitempty"`
+	LatencyP50          time.Duration `json:"latency_p50"`
+	LatencyP90          time.Duration `json:"latency_p90"`
+	LatencyP99          time.Duration `json:"latency_p99"`
+	LastBlock           uint64        `json:"last_block"`
+	Kind                NodeKind      `json:"kind"`
+	// Circuit is the provider's circuit breaker state when the pool has
+	// one: CircuitClosed, CircuitOpen or CircuitHalfOpen.
+	Circuit string `json:"circuit,omitempty"`
+}
+
+// SkippedProvider records a provider that failover moved past.
+type SkippedProvider struct {
+	URL    string `json:"url"`
+	Reason string `json:"reason"`
+}
+
+// Route describes how the pool served its most recent call.
+type Route struct {
+	Method   string            `json:"method"`
+	Provider string            `json:"provider,omitempty"`
+	Skipped  []SkippedProvider `json:"skipped,omitempty"`
+	// Hedged is set when a second provider was asked before the first
+	// answered.
+	Hedged bool `json:"hedged,omitempty"`
+	// Agreed lists the providers whose matching answers made a quorum
+	// read's result.
+	Agreed []string  `json:"agreed,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// PoolStats is a snapshot of every provider in a pool plus the most recent
+// routing decision.
+type PoolStats struct {
+	Providers []ProviderStats `json:"providers"`
+	LastRoute *Route          `json:"last_route,omitempty"`
+}
+
+type providerStats struct {
+	mu          sync.Mutex
+	requests    uint64
+	errors      uint64
+	rateLimited uint64
+	streak      int
+	lastErr     string
+	lastErrAt   time.Time
+	latencies   [latencyWindow]time.Duration
+	nLatency    int
+	ewma        time.Duration
+	lastBlock   uint64
+}
+
+func (s *providerStats) recordSuccess(method, result string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	s.streak = 0
+	s.latencies[s.nLatency%latencyWindow] = d
+	s.nLatency++
+	s.observeLatency(d)
+	if n, ok := blockHeight(method, result); ok && n > s.lastBlock {
+		s.lastBlock = n
+	}
+}
+
+func (s *providerStats) recordFailure(err error, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	s.errors++
+	s.streak++
+	s.lastErr = err.Error()
+	s.lastErrAt = time.Now()
+	s.latencies[s.nLatency%latencyWindow] = d
+	s.nLatency++
+	s.observeLatency(d)
+}
+
+func (s *providerStats) recordRateLimited() {
+	s.mu.Lock()
+	s.rateLimited++
+	s.mu.Unlock()
+}
+
+func (s *providerStats) snapshot(pr *provider) ProviderStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.nLatency
+	if n > latencyWindow {
+		n = latencyWindow
+	}
+	samples := make([]time.Duration, n)
+	copy(samples, s.latencies[:n])
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return ProviderStats{
+		URL:                 pr.url,
+		Label:               pr.label,
+		Weight:              pr.weight,
+		Priority:            pr.priority,
+		Requests:            s.requests,
+		Errors:              s.errors,
+		RateLimited:         s.rateLimited,
+		ConsecutiveFailures: s.streak,
+		LastError:           s.lastErr,
+		LastErrorAt:         s.lastErrAt,
+		LatencyP50:          percentile(samples, 0.50),
+		LatencyP90:          percentile(samples, 0.90),
+		LatencyP99:          percentile(samples, 0.99),
+		LastBlock:           s.lastBlock,
+		Kind:                pr.kindOf(),
+	}
+}
+
+// percentile returns the q-th percentile of sorted samples (nearest rank).
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(q*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+// blockHeight extracts a block number from results that reveal the
+// provider's view of the chain head.
+func blockHeight(method, result string) (uint64, bool) {
+	switch method {
+	case "eth_blockNumber":
+		n, err := parseQuantity(result)
+		return n, err == nil
+	case "eth_getBlockByNumber", "eth_getBlockByHash":
+		var b struct {
+			Number string `json:"number"`
+		}
+		if json.Unmarshal([]byte(result), &b) != nil || b.Number == "" {
+			return 0, false
+		}
+		n, err := parseQuantity(`"` + b.Number + `"`)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// Stats returns a snapshot of per-provider health and the last routing
+// decision made by the pool.
+func (p *Pool) Stats() PoolStats {
+	out := PoolStats{Providers: make([]ProviderStats, len(p.providers))}
+	for i, pr := range p.providers {
+		out.Providers[i] = pr.stats.snapshot(pr)
+		if p.opts.CircuitBreaker != nil {
+			out.Providers[i].Circuit = pr.breaker.state(time.Now())
+		}
+	}
+	p.routeMu.Lock()
+	if p.lastRoute != nil {
+		r := *p.lastRoute
+		r.Skipped = append([]SkippedProvider(nil), r.Skipped...)
+		out.LastRoute = &r
+	}
+	p.routeMu.Unlock()
+	return out
+}
+
+func (p *Pool) recordRoute(r *Route) {
+	r.At = time.Now()
+	p.routeMu.Lock()
+	p.lastRoute = r
+	p.routeMu.Unlock()
+}
@@ -0,0 +1,347 @@
+package chainrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// errNoStream is returned when a subscription is requested on an endpoint
+// that cannot carry one.
+var errNoStream = errors.New("chainrpc: subscriptions need a ws://, wss:// or IPC endpoint")
+
+// MessageConn is a raw message connection to a ws://, wss:// or IPC
+// endpoint, for protocols whose framing or notifications chainrpc does not
+// handle itself (e.g. Tendermint event subscriptions). Each ReadMessage
+// returns one whole JSON message; WriteMessage may be called concurrently
+// with it.
+type MessageConn interface {
+	ReadMessage() ([]byte, error)
+	WriteMessage(b []byte) error
+	Close() error
+}
+
+// DialMessages opens a MessageConn to endpoint. WithHTTPOptions supplies
+// WebSocket upgrade headers and credentials.
+func DialMessages(ctx context.Context, endpoint string, opts ...Option) (MessageConn, error) {
+	return dialStream(ctx, endpoint, newCallOptions(opts).http)
+}
+
+// ipcStream is a MessageConn over a unix socket, where messages are simply
+// concatenated JSON values.
+type ipcStream struct {
+	conn net.Conn
+	dec  *json.Decoder
+	wmu  sync.Mutex
+}
+
+func (s *ipcStream) ReadMessage() ([]byte, error) {
+	var raw json.RawMessage
+	err := s.dec.Decode(&raw)
+	return raw, err
+}
+
+func (s *ipcStream) WriteMessage(b []byte) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	_, err := s.conn.Write(b)
+	return err
+}
+
+func (s *ipcStream) Close() error { return s.conn.Close() }
+
+// canStream reports whether endpoint supports subscriptions.
+func canStream(endpoint string) bool {
+	_, ipc := ipcPath(endpoint)
+	return ipc || isWebSocket(endpoint)
+}
+
+// dialStream opens a persistent connection to a ws://, wss:// or IPC
+// endpoint. h supplies headers and credentials for WebSocket
+// upgrades.
+func dialStream(ctx context.Context, endpoint string, h *HTTPOptions) (MessageConn, error) {
+	if path, ok := ipcPath(endpoint); ok {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "unix", path)
+		if err != nil {
+			return nil, fmt.Errorf("IPC error: %w", err)
+		}
+		return &ipcStream{conn: conn, dec: json.NewDecoder(conn)}, nil
+	}
+	if !isWebSocket(endpoint) {
+		return nil, errNoStream
+	}
+	header := http.Header{}
+	if h != nil {
+		for k, v := range h.Headers {
+			header.Set(k, v)
+		}
+		if a := h.authorization(); a != "" {
+			header.Set("Authorization", a)
+		}
+	}
+	conn, err := dialWS(ctx, endpoint, header)
+	if err != nil {
+		return nil, fmt.Errorf("WebSocket error: %w", err)
+	}
+	return conn, nil
+}
+
+// streamClient multiplexes requests and subscription notifications over
+// one MessageConn.
+type streamClient struct {
+	conn MessageConn
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]*streamCall
+	subs    map[string]chan<- json.RawMessage
+	err     error
+	done    chan struct{}
+}
+
+type streamCall struct {
+	reply chan streamReply
+	// sub, if set, receives the notifications of the subscription the
+	// call creates; it is registered before the reply is delivered so no
+	// notification is missed.
+	sub chan<- json.RawMessage
+}
+
+type streamReply struct {
+	result json.RawMessage
+	err    error
+}
+
+type streamMessage struct {
+	ipcResponse
+	Method string `json:"method"`
+	Params struct {
+		Subscription string          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	} `json:"params"`
+}
+
+func newStreamClient(conn MessageConn) *streamClient {
+	c := &streamClient{
+		conn:    conn,
+		pending: map[uint64]*streamCall{},
+		subs:    map[string]chan<- json.RawMessage{},
+		done:    make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+// readLoop dispatches replies and notifications until the connection
+// fails. A subscriber that stops reading stalls the loop, so subscribers
+// must drain their channel promptly.
+func (c *streamClient) readLoop() {
+	for {
+		b, err := c.conn.ReadMessage()
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		var m streamMessage
+		if json.Unmarshal(b, &m) != nil {
+			continue
+		}
+		if m.Method == "eth_subscription" {
+			c.mu.Lock()
+			ch := c.subs[m.Params.Subscription]
+			c.mu.Unlock()
+			if ch != nil {
+				select {
+				case ch <- m.Params.Result:
+				case <-c.done:
+					return
+				}
+			}
+			continue
+		}
+		id, err := strconv.ParseUint(string(m.ID), 10, 64)
+		if err != nil {
+			continue
+		}
+		rpcErr := m.rpcError()
+		c.mu.Lock()
+		call := c.pending[id]
+		delete(c.pending, id)
+		if call != nil && call.sub != nil && rpcErr == nil {
+			var subID string
+			if json.Unmarshal(m.Result, &subID) == nil {
+				c.subs[subID] = call.sub
+			}
+		}
+		c.mu.Unlock()
+		if call != nil {
+			call.reply <- streamReply{result: m.Result, err: rpcErr}
+		}
+	}
+}
+
+// fail records err as the connection's terminal error and releases every
+// waiter.
+func (c *streamClient) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	close(c.done)
+	for id, call := range c.pending {
+		call.reply <- streamReply{err: err}
+		delete(c.pending, id)
+	}
+}
+
+// Err returns the error that ended the connection, or nil while it is up.
+func (c *streamClient) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Done is closed when the connection ends.
+func (c *streamClient) Done() <-chan struct{} { return c.done }
+
+// Close closes the connection; pending calls fail with net.ErrClosed.
+func (c *streamClient) Close() error {
+	err := c.conn.Close()
+	c.fail(net.ErrClosed)
+	return err
+}
+
+func (c *streamClient) send(ctx context.Context, method, paramsJSON string, sub chan<- json.RawMessage) (json.RawMessage, error) {
+	call := &streamCall{reply: make(chan streamReply, 1), sub: sub}
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = call
+	c.mu.Unlock()
+
+	req := mustJSON(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      uint64          `json:"id"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params"`
+	}{"2.0", id, method, json.RawMessage(paramsJSON)})
+	if err := c.conn.WriteMessage([]byte(req)); err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return nil, err
+	}
+	select {
+	case r := <-call.reply:
+		return r.result, r.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// call issues one request and returns its compacted result, matching
+// CallContext.
+func (c *streamClient) call(ctx context.Context, method, paramsJSON string) (string, error) {
+	res, err := c.send(ctx, method, paramsJSON, nil)
+	if err != nil {
+		return "", err
+	}
+	if len(res) == 0 {
+		return "null", nil
+	}
+	var out bytes.Buffer
+	if err := json.Compact(&out, res); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// Backpressure selects what a subscription does when its consumer falls
+// behind and the channel buffer is full.
+type Backpressure int
+
+const (
+	// DropOldest discards the oldest buffered item to make room, keeping
+	// the stream current.
+	DropOldest Backpressure = iota
+	// DropNewest discards the incoming item.
+	DropNewest
+	// Block waits for the consumer. The connection stops being read
+	// meanwhile, and nodes disconnect subscribers that stall for long.
+	Block
+)
+
+func (b Backpressure) String() string {
+	switch b {
+	case DropOldest:
+		return "drop-oldest"
+	case DropNewest:
+		return "drop-newest"
+	case Block:
+		return "block"
+	}
+	return "Backpressure(" + strconv.Itoa(int(b)) + ")"
+}
+
+// deliver sends v on out under policy b, counting discarded items in
+// dropped. It returns false if done closed first.
+func deliver[T any](out chan T, v T, b Backpressure, dropped *uint64, done <-chan struct{}) bool {
+	if b == Block {
+		select {
+		case out <- v:
+			return true
+		case <-done:
+			return false
+		}
+	}
+	for {
+		select {
+		case out <- v:
+			return true
+		case <-done:
+			return false
+		default:
+		}
+		if b == DropNewest {
+			atomic.AddUint64(dropped, 1)
+			return true
+		}
+		select {
+		case <-out:
+			atomic.AddUint64(dropped, 1)
+		default:
+		}
+	}
+}
+
+// subscribe runs eth_subscribe with params and delivers its notifications
+// to ch until the connection ends. It returns the subscription ID.
+func (c *streamClient) subscribe(ctx context.Context, params []interface{}, ch chan<- json.RawMessage) (string, error) {
+	res, err := c.send(ctx, "eth_subscribe", mustJSON(params), ch)
+	if err != nil {
+		return "", err
+	}
+	var id string
+	if err := json.Unmarshal(res, &id); err != nil {
+		return "", fmt.Errorf("chainrpc: eth_subscribe result: %w", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,425 @@
+package chainrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+	"time"
+)
+
+// SubscriptionOptions configures SubscribeNewHeads and SubscribeLogs. The
+// zero value buffers 1024 items, drops the oldest when the consumer falls
+// behind, and reconnects forever.
+type SubscriptionOptions struct {
+	// Buffer is the capacity of the stream's channel. Default 1024.
+	Buffer int
+	// Backpressure is applied when the buffer is full.
+	Backpressure Backpressure
+	// Reconnect sets the delay before each reconnection attempt. Its
+	// MaxAttempts bounds consecutive failed attempts before the stream
+	// gives up; zero means no limit. Default: 500ms doubling up to 30s
+	// with 20% jitter, no limit.
+	Reconnect *RetryPolicy
+	// NoReconnect ends the stream when the connection drops.
+	NoReconnect bool
+	// BackfillRange caps the block range of one eth_getLogs call while
+	// backfilling. Default 2000.
+	BackfillRange uint64
+}
+
+func defaultReconnectPolicy() RetryPolicy {
+	return RetryPolicy{
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// Reconnect describes one recovery of a dropped subscription.
+type Reconnect struct {
+	// Cause is the error that dropped the previous connection.
+	Cause error
+	// Attempts is the number of dials the reconnection took.
+	Attempts int
+	// Downtime is the time from the drop until the stream resumed.
+	Downtime time.Duration
+	// FromBlock and ToBlock bound the range fetched to cover the outage,
+	// and Backfilled counts the items delivered from it.
+	FromBlock  uint64
+	ToBlock    uint64
+	Backfilled int
+}
+
+// recentBlocks is how many blocks of delivered items a subscription
+// remembers to drop duplicates between backfill and the live stream.
+const recentBlocks = 128
+
+// Subscription delivers the notifications of an eth_subscribe stream on C,
+// reconnecting when the connection drops. After a reconnection the blocks
+// missed during the outage are fetched and delivered before live items
+// resume, and items seen twice are delivered once, so C has no gaps. A
+// reorg that happens entirely during an outage is not reported.
+type Subscription[T any] struct {
+	// C is closed when the stream ends; Err then reports why.
+	C <-chan T
+	// Reconnects receives an event after each reconnection. It holds the
+	// latest 16 events; older ones are discarded if it is not read.
+	Reconnects <-chan Reconnect
+
+	url    string
+	params []interface{}
+	cfg    SubscriptionOptions
+	policy RetryPolicy
+	http   *HTTPOptions
+	src    subscriptionSource[T]
+
+	out        chan T
+	events     chan Reconnect
+	cancel     context.CancelFunc
+	done       chan struct{}
+	err        error
+	dropped    uint64
+	reconnects uint64
+
+	// next is the first block that may have undelivered items; recent
+	// maps the keys of recently delivered items to their block.
+	next   uint64
+	recent map[string]uint64
+}
+
+// subscriptionSource adapts a subscription kind to the generic stream.
+type subscriptionSource[T any] struct {
+	// block and key identify an item; key differs for a reorged copy.
+	block func(T) uint64
+	key   func(T) string
+	// backfill delivers the items of blocks [from, to] to emit.
+	backfill func(ctx context.Context, call callFunc, from, to uint64, emit func(T) bool) error
+}
+
+// Dropped returns the number of items discarded under the Backpressure
+// policy.
+func (s *Subscription[T]) Dropped() uint64 { return atomic.LoadUint64(&s.dropped) }
+
+// ReconnectCount returns the number of times the stream has reconnected.
+func (s *Subscription[T]) ReconnectCount() uint64 { return atomic.LoadUint64(&s.reconnects) }
+
+// Err returns the error that ended the stream once C is closed; it is nil
+// after Close or context cancellation.
+func (s *Subscription[T]) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Close stops the stream and waits for C to be closed.
+func (s *Subscription[T]) Close() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// Header is a block header from a newHeads subscription.
+type Header struct {
+	Number     uint64
+	Hash       string
+	ParentHash string
+	Timestamp  uint64
+	// BaseFee is nil before London.
+	BaseFee *big.Int
+	// Raw is the node's JSON, for fields not decoded here.
+	Raw json.RawMessage
+}
+
+// UnmarshalJSON decodes a node's header, where numbers are hex quantities.
+func (h *Header) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Number        string `json:"number"`
+		Hash          string `json:"hash"`
+		ParentHash    string `json:"parentHash"`
+		Timestamp     string `json:"timestamp"`
+		BaseFeePerGas string `json:"baseFeePerGas"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*h = Header{Hash: raw.Hash, ParentHash: raw.ParentHash, Raw: append(json.RawMessage(nil), b...)}
+	var err error
+	if h.Number, err = hexQuantity(raw.Number); err != nil {
+		return fmt.Errorf("number: %w", err)
+	}
+	if h.Timestamp, err = hexQuantity(raw.Timestamp); err != nil {
+		return fmt.Errorf("timestamp: %w", err)
+	}
+	if raw.BaseFeePerGas != "" {
+		if h.BaseFee, err = hexBig(raw.BaseFeePerGas); err != nil {
+			return fmt.Errorf("baseFeePerGas: %w", err)
+		}
+	}
+	return nil
+}
+
+// SubscribeNewHeads streams new block headers from a ws://, wss:// or IPC
+// endpoint. Headers of blocks produced while the connection was down are
+// fetched with eth_getBlockByNumber on reconnection. opts apply to the
+// calls made on the stream's behalf (WithHTTPOptions also supplies
+// WebSocket upgrade headers).
+func SubscribeNewHeads(ctx context.Context, url string, cfg SubscriptionOptions, opts ...Option) (*Subscription[Header], error) {
+	return subscribe(ctx, url, []interface{}{"newHeads"}, cfg, opts, subscriptionSource[Header]{
+		block: func(h Header) uint64 { return h.Number },
+		key:   func(h Header) string { return h.Hash },
+		backfill: func(ctx context.Context, call callFunc, from, to uint64, emit func(Header) bool) error {
+			for n := from; n <= to; n++ {
+				res, err := call(ctx, "eth_getBlockByNumber", mustJSON([]interface{}{fmt.Sprintf("0x%x", n), false}))
+				if err != nil {
+					return err
+				}
+				if res == "null" {
+					return fmt.Errorf("chainrpc: block %d not available for backfill", n)
+				}
+				var h Header
+				if err := json.Unmarshal([]byte(res), &h); err != nil {
+					return fmt.Errorf("chainrpc: eth_getBlockByNumber result: %w", err)
+				}
+				if !emit(h) {
+					return nil
+				}
+			}
+			return nil
+		},
+	})
+}
+
+// SubscribeLogs streams logs matching filter's addresses and topics from a
+// ws://, wss:// or IPC endpoint; its block range is ignored. Logs emitted
+// while the connection was down are fetched with eth_getLogs on
+// reconnection, and removed logs are delivered as the node reports them.
+func SubscribeLogs(ctx context.Context, url string, filter LogFilter, cfg SubscriptionOptions, opts ...Option) (*Subscription[Log], error) {
+	return subscribe(ctx, url, []interface{}{"logs", filter.criteria()}, cfg, opts, subscriptionSource[Log]{
+		block: func(l Log) uint64 {
+			n, _ := hexQuantity(l.BlockNumber)
+			return n
+		},
+		key: func(l Log) string { return fmt.Sprintf("%s:%s:%t", l.BlockHash, l.LogIndex, l.Removed) },
+		backfill: func(ctx context.Context, call callFunc, from, to uint64, emit func(Log) bool) error {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			f := filter
+			f.FromBlock, f.ToBlock = from, to
+			for page := range getLogsPaged(ctx, call, f, cfg.BackfillRange) {
+				if page.Err != nil {
+					return page.Err
+				}
+				for _, l := range page.Logs {
+					if !emit(l) {
+						return nil
+					}
+				}
+			}
+			return nil
+		},
+	})
+}
+
+func subscribe[T any](ctx context.Context, url string, params []interface{}, cfg SubscriptionOptions, opts []Option, src subscriptionSource[T]) (*Subscription[T], error) {
+	if !canStream(url) {
+		return nil, errNoStream
+	}
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = 1024
+	}
+	s := &Subscription[T]{
+		url:    url,
+		params: params,
+		cfg:    cfg,
+		policy: defaultReconnectPolicy(),
+		http:   newCallOptions(opts).http,
+		src:    src,
+		out:    make(chan T, cfg.Buffer),
+		events: make(chan Reconnect, 16),
+		done:   make(chan struct{}),
+		recent: map[string]uint64{},
+	}
+	if cfg.Reconnect != nil {
+		s.policy = *cfg.Reconnect
+	}
+	s.C, s.Reconnects = s.out, s.events
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	sc, raw, err := s.dial(ctx)
+	if err != nil {
+		s.cancel()
+		return nil, err
+	}
+	// The baseline is the head when the subscription started, so an
+	// outage before the first notification is still backfilled.
+	head, err := streamHead(ctx, sc.call)
+	if err != nil {
+		sc.Close()
+		s.cancel()
+		return nil, err
+	}
+	s.next = head + 1
+	go s.run(ctx, sc, raw)
+	return s, nil
+}
+
+// dial opens a connection and subscribes on it.
+func (s *Subscription[T]) dial(ctx context.Context) (*streamClient, chan json.RawMessage, error) {
+	conn, err := dialStream(ctx, s.url, s.http)
+	if err != nil {
+		return nil, nil, err
+	}
+	sc := newStreamClient(conn)
+	raw := make(chan json.RawMessage, s.cfg.Buffer)
+	if _, err := sc.subscribe(ctx, s.params, raw); err != nil {
+		sc.Close()
+		return nil, nil, err
+	}
+	return sc, raw, nil
+}
+
+func (s *Subscription[T]) run(ctx context.Context, sc *streamClient, raw chan json.RawMessage) {
+	defer func() {
+		s.cancel()
+		close(s.out)
+		close(s.done)
+	}()
+	for {
+		cause := s.pump(ctx, sc, raw)
+		sc.Close()
+		if cause == nil || ctx.Err() != nil {
+			return
+		}
+		if s.cfg.NoReconnect {
+			s.err = cause
+			return
+		}
+		var err error
+		if sc, raw, err = s.reconnect(ctx, cause); err != nil {
+			if ctx.Err() == nil {
+				s.err = err
+			}
+			return
+		}
+	}
+}
+
+// pump delivers live notifications until the connection fails, returning
+// its error, or until the stream is stopped, returning nil.
+func (s *Subscription[T]) pump(ctx context.Context, sc *streamClient, raw chan json.RawMessage) error {
+	for {
+		select {
+		case msg := <-raw:
+			var v T
+			if json.Unmarshal(msg, &v) != nil {
+				continue
+			}
+			if !s.emit(ctx, v) {
+				return nil
+			}
+		case <-sc.Done():
+			return sc.Err()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// emit delivers v unless it was delivered recently.
+func (s *Subscription[T]) emit(ctx context.Context, v T) bool {
+	key, block := s.src.key(v), s.src.block(v)
+	if _, dup := s.recent[key]; dup {
+		return true
+	}
+	s.recent[key] = block
+	if block > s.next {
+		s.next = block
+		for k, b := range s.recent {
+			if b+recentBlocks < block {
+				delete(s.recent, k)
+			}
+		}
+	}
+	return deliver(s.out, v, s.cfg.Backpressure, &s.dropped, ctx.Done())
+}
+
+// reconnect redials with backoff, resubscribes, and backfills the blocks
+// from s.next to the current head before live notifications resume.
+func (s *Subscription[T]) reconnect(ctx context.Context, cause error) (*streamClient, chan json.RawMessage, error) {
+	down := time.Now()
+	lastErr := cause
+	for attempt := 1; ; attempt++ {
+		if s.policy.MaxAttempts > 0 && attempt > s.policy.MaxAttempts {
+			return nil, nil, fmt.Errorf("chainrpc: subscription lost after %d reconnection attempts: %w", s.policy.MaxAttempts, lastErr)
+		}
+		t := time.NewTimer(s.policy.Backoff(attempt))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, nil, ctx.Err()
+		}
+		// Subscribe first so nothing after the backfill's head is missed;
+		// notifications wait in raw meanwhile.
+		sc, raw, err := s.dial(ctx)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		ev, err := s.backfill(ctx)
+		if err != nil {
+			sc.Close()
+			lastErr = err
+			continue
+		}
+		ev.Cause, ev.Attempts, ev.Downtime = cause, attempt, time.Since(down)
+		atomic.AddUint64(&s.reconnects, 1)
+		var discarded uint64
+		deliver(s.events, ev, DropOldest, &discarded, ctx.Done())
+		return sc, raw, nil
+	}
+}
+
+// backfill fetches the items missed during an outage over a separate
+// connection, so the subscription's reader never waits on it.
+func (s *Subscription[T]) backfill(ctx context.Context) (Reconnect, error) {
+	conn, err := dialStream(ctx, s.url, s.http)
+	if err != nil {
+		return Reconnect{}, err
+	}
+	fc := newStreamClient(conn)
+	defer fc.Close()
+	head, err := streamHead(ctx, fc.call)
+	if err != nil {
+		return Reconnect{}, err
+	}
+	ev := Reconnect{FromBlock: s.next, ToBlock: head}
+	if s.next > head {
+		return ev, nil
+	}
+	err = s.src.backfill(ctx, fc.call, s.next, head, func(v T) bool {
+		if _, dup := s.recent[s.src.key(v)]; !dup {
+			ev.Backfilled++
+		}
+		return s.emit(ctx, v)
+	})
+	if err == nil && s.next <= head {
+		// Every block up to head is complete, even those without items.
+		s.next = head + 1
+	}
+	return ev, err
+}
+
+func streamHead(ctx context.Context, call callFunc) (uint64, error) {
+	res, err := call(ctx, "eth_blockNumber", "[]")
+	if err != nil {
+		return 0, err
+	}
+	return parseQuantity(res)
+}
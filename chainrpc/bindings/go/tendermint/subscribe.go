@@ -0,0 +1,167 @@
+package tendermint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/DarshanKumar89/chainfoundry/chainrpc"
+)
+
+// Common subscription queries.
+const (
+	QueryNewBlock       = "tm.event='NewBlock'"
+	QueryNewBlockHeader = "tm.event='NewBlockHeader'"
+	QueryTx             = "tm.event='Tx'"
+)
+
+// EventData is one event delivered by a subscription.
+type EventData struct {
+	Query string
+	// Type is the payload's type, e.g. "tendermint/event/NewBlock" or
+	// "tendermint/event/Tx".
+	Type string
+	// Value is the payload, whose shape depends on Type.
+	Value json.RawMessage
+	// Events maps composite keys such as "transfer.recipient" to their
+	// values, for matching without decoding Value.
+	Events map[string][]string
+}
+
+// Subscription delivers the events matching a query on C until its
+// context is cancelled, Close is called, or the connection fails. Nodes
+// cancel subscribers that do not keep up, so C must be drained promptly.
+type Subscription struct {
+	// C is closed when the subscription ends; Err then reports why.
+	C <-chan EventData
+
+	conn   chainrpc.MessageConn
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+	once   sync.Once
+}
+
+// Err returns the error that ended the subscription once C is closed; it
+// is nil after Close or context cancellation.
+func (s *Subscription) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Close unsubscribes, closes the connection and waits for C to be closed.
+func (s *Subscription) Close() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+type wsMessage struct {
+	ID     json.RawMessage `json:"id"`
+	Result *struct {
+		Query string `json:"query"`
+		Data  struct {
+			Type  string          `json:"type"`
+			Value json.RawMessage `json:"value"`
+		} `json:"data"`
+		Events map[string][]string `json:"events"`
+	} `json:"result"`
+	Error *struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	} `json:"error"`
+}
+
+// Subscribe opens a connection to the node's websocket endpoint (e.g.
+// "ws://localhost:26657/websocket") and subscribes to events matching
+// query. buffer is the capacity of C; zero means 256. opts supply
+// WebSocket upgrade headers through WithHTTPOptions.
+func Subscribe(ctx context.Context, wsURL, query string, buffer int, opts ...chainrpc.Option) (*Subscription, error) {
+	if buffer <= 0 {
+		buffer = 256
+	}
+	conn, err := chainrpc.DialMessages(ctx, wsURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+	req, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0", "id": 1, "method": "subscribe",
+		"params": map[string]string{"query": query},
+	})
+	if err := conn.WriteMessage(req); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// The first reply acknowledges the subscription.
+	b, err := conn.ReadMessage()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	var ack wsMessage
+	if err := json.Unmarshal(b, &ack); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("tendermint: subscribe reply: %w", err)
+	}
+	if ack.Error != nil {
+		conn.Close()
+		return nil, &chainrpc.RPCError{Code: ack.Error.Code, Message: ack.Error.Message, Data: ack.Error.Data}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	out := make(chan EventData, buffer)
+	s := &Subscription{C: out, conn: conn, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		<-ctx.Done()
+		// Unblock the reader; the node drops the subscription with the
+		// connection.
+		s.once.Do(func() {
+			req, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 2, "method": "unsubscribe_all", "params": map[string]string{}})
+			conn.WriteMessage(req)
+			conn.Close()
+		})
+	}()
+	go func() {
+		defer func() {
+			cancel()
+			close(out)
+			close(s.done)
+		}()
+		for {
+			b, err := conn.ReadMessage()
+			if err != nil {
+				if ctx.Err() == nil {
+					s.err = err
+				}
+				return
+			}
+			var m wsMessage
+			if json.Unmarshal(b, &m) != nil {
+				continue
+			}
+			if m.Error != nil {
+				// e.g. the node cancelling a slow subscriber.
+				if ctx.Err() == nil {
+					s.err = &chainrpc.RPCError{Code: m.Error.Code, Message: m.Error.Message, Data: m.Error.Data}
+				}
+				return
+			}
+			if m.Result == nil || m.Result.Data.Type == "" {
+				continue
+			}
+			ev := EventData{Query: m.Result.Query, Type: m.Result.Data.Type, Value: m.Result.Data.Value, Events: m.Result.Events}
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return s, nil
+}
@@ -0,0 +1,280 @@
+// Package tendermint adds a client for the Tendermint / CometBFT RPC that
+// Cosmos-SDK chains expose (port 26657 by default). Requests are JSON-RPC
+// with positional params, sent through chainrpc.CallContext or a
+// chainrpc.Pool, so retries, caching, metrics and failover apply as for
+// EVM calls. Event subscriptions use the node's /websocket endpoint.
+//
+// Numbers the node encodes as strings are decoded into Int64, and byte
+// fields from base64. Event attributes are returned as the node sends
+// them; Tendermint 0.34 nodes base64-encode their keys and values.
+package tendermint
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DarshanKumar89/chainfoundry/chainrpc"
+)
+
+// Int64 is an integer the node encodes as a JSON string, as it does every
+// 64-bit value. It also accepts plain numbers.
+type Int64 int64
+
+func (n *Int64) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("tendermint: invalid integer %s", b)
+	}
+	*n = Int64(v)
+	return nil
+}
+
+func (n Int64) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(strconv.FormatInt(int64(n), 10))), nil
+}
+
+// Client issues Tendermint RPC calls.
+type Client struct {
+	call func(ctx context.Context, method, paramsJSON string) (string, error)
+}
+
+// New returns a client for the node at url; opts apply to every call.
+func New(url string, opts ...chainrpc.Option) *Client {
+	return &Client{call: func(ctx context.Context, method, paramsJSON string) (string, error) {
+		return chainrpc.CallContext(ctx, url, method, paramsJSON, opts...)
+	}}
+}
+
+// NewFromPool returns a client that routes through p.
+func NewFromPool(p *chainrpc.Pool, opts ...chainrpc.Option) *Client {
+	return &Client{call: func(ctx context.Context, method, paramsJSON string) (string, error) {
+		return p.CallContext(ctx, method, paramsJSON, opts...)
+	}}
+}
+
+func (c *Client) do(ctx context.Context, method string, params []interface{}, out interface{}) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	res, err := c.call(ctx, method, string(b))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(res), out); err != nil {
+		return fmt.Errorf("tendermint: %s result: %w", method, err)
+	}
+	return nil
+}
+
+// height renders a height param; zero asks for the latest.
+func height(h int64) interface{} {
+	if h <= 0 {
+		return nil
+	}
+	return strconv.FormatInt(h, 10)
+}
+
+// Status is the node's identity and sync state.
+type Status struct {
+	NodeInfo struct {
+		ID      string `json:"id"`
+		Network string `json:"network"`
+		Version string `json:"version"`
+		Moniker string `json:"moniker"`
+	} `json:"node_info"`
+	SyncInfo SyncInfo `json:"sync_info"`
+}
+
+// SyncInfo is the range of blocks the node holds.
+type SyncInfo struct {
+	LatestBlockHash     string    `json:"latest_block_hash"`
+	LatestBlockHeight   Int64     `json:"latest_block_height"`
+	LatestBlockTime     time.Time `json:"latest_block_time"`
+	EarliestBlockHeight Int64     `json:"earliest_block_height"`
+	CatchingUp          bool      `json:"catching_up"`
+}
+
+// Status returns the node's status; SyncInfo.LatestBlockHeight is the
+// chain head.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var s Status
+	if err := c.do(ctx, "status", []interface{}{}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// BlockID identifies a block.
+type BlockID struct {
+	Hash string `json:"hash"`
+}
+
+// Header is a block header.
+type Header struct {
+	ChainID         string    `json:"chain_id"`
+	Height          Int64     `json:"height"`
+	Time            time.Time `json:"time"`
+	LastBlockID     BlockID   `json:"last_block_id"`
+	DataHash        string    `json:"data_hash"`
+	AppHash         string    `json:"app_hash"`
+	ProposerAddress string    `json:"proposer_address"`
+}
+
+// Block is a block with its raw transactions.
+type Block struct {
+	BlockID BlockID `json:"block_id"`
+	Block   struct {
+		Header Header `json:"header"`
+		Data   struct {
+			// Txs are the encoded transactions.
+			Txs [][]byte `json:"txs"`
+		} `json:"data"`
+	} `json:"block"`
+}
+
+// Block returns the block at height, or the latest with height 0.
+func (c *Client) Block(ctx context.Context, h int64) (*Block, error) {
+	var b Block
+	if err := c.do(ctx, "block", []interface{}{height(h)}, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// EventAttribute is one key/value of an ABCI event.
+type EventAttribute struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Index bool   `json:"index"`
+}
+
+// Event is an ABCI event emitted by a transaction or block.
+type Event struct {
+	Type       string           `json:"type"`
+	Attributes []EventAttribute `json:"attributes"`
+}
+
+// Attr returns the value of the first attribute named key.
+func (e Event) Attr(key string) (string, bool) {
+	for _, a := range e.Attributes {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// TxResult is the outcome of executing a transaction.
+type TxResult struct {
+	// Code is 0 on success.
+	Code      uint32  `json:"code"`
+	Codespace string  `json:"codespace"`
+	Data      []byte  `json:"data"`
+	Log       string  `json:"log"`
+	Info      string  `json:"info"`
+	GasWanted Int64   `json:"gas_wanted"`
+	GasUsed   Int64   `json:"gas_used"`
+	Events    []Event `json:"events"`
+}
+
+// Tx is a transaction found by TxSearch.
+type Tx struct {
+	Hash     string   `json:"hash"`
+	Height   Int64    `json:"height"`
+	Index    uint32   `json:"index"`
+	TxResult TxResult `json:"tx_result"`
+	Tx       []byte   `json:"tx"`
+}
+
+// TxSearchOptions configures TxSearch.
+type TxSearchOptions struct {
+	// Prove includes Merkle proofs of inclusion.
+	Prove bool
+	// Page is 1-based; zero means 1.
+	Page int
+	// PerPage defaults to the node's 30 and is capped at 100.
+	PerPage int
+	// Desc orders results newest first.
+	Desc bool
+}
+
+// TxSearchResult is one page of TxSearch results.
+type TxSearchResult struct {
+	Txs        []Tx  `json:"txs"`
+	TotalCount Int64 `json:"total_count"`
+}
+
+// TxSearch finds transactions whose events match query, e.g.
+// "transfer.recipient='cosmos1…' AND tx.height>100".
+func (c *Client) TxSearch(ctx context.Context, query string, opts *TxSearchOptions) (*TxSearchResult, error) {
+	if opts == nil {
+		opts = &TxSearchOptions{}
+	}
+	page, perPage := opts.Page, opts.PerPage
+	if page <= 0 {
+		page = 1
+	}
+	var perPageParam interface{}
+	if perPage > 0 {
+		perPageParam = perPage
+	}
+	order := "asc"
+	if opts.Desc {
+		order = "desc"
+	}
+	var r TxSearchResult
+	if err := c.do(ctx, "tx_search", []interface{}{query, opts.Prove, page, perPageParam, order}, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ABCIQueryOptions configures ABCIQuery.
+type ABCIQueryOptions struct {
+	// Height queries state at a past height; zero means latest.
+	Height int64
+	// Prove includes a Merkle proof of the value.
+	Prove bool
+}
+
+// ABCIQueryResponse is the application's answer to a query.
+type ABCIQueryResponse struct {
+	// Code is 0 on success.
+	Code      uint32          `json:"code"`
+	Codespace string          `json:"codespace"`
+	Log       string          `json:"log"`
+	Info      string          `json:"info"`
+	Index     Int64           `json:"index"`
+	Key       []byte          `json:"key"`
+	Value     []byte          `json:"value"`
+	ProofOps  json.RawMessage `json:"proofOps"`
+	Height    Int64           `json:"height"`
+}
+
+// ABCIQuery queries application state at path, e.g.
+// "/cosmos.bank.v1beta1.Query/Balance" with a protobuf-encoded request as
+// data, or "store/bank/key" with a raw store key. A non-zero Code in the
+// response is the application's error, returned as is.
+func (c *Client) ABCIQuery(ctx context.Context, path string, data []byte, opts *ABCIQueryOptions) (*ABCIQueryResponse, error) {
+	if opts == nil {
+		opts = &ABCIQueryOptions{}
+	}
+	var r struct {
+		Response ABCIQueryResponse `json:"response"`
+	}
+	if err := c.do(ctx, "abci_query", []interface{}{path, hex.EncodeToString(data), height(opts.Height), opts.Prove}, &r); err != nil {
+		return nil, err
+	}
+	return &r.Response, nil
+}
@@ -0,0 +1,325 @@
+package chainrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TraceOptions configures a Geth-style debug trace. Zero values keep the
+// node's defaults.
+type TraceOptions struct {
+	// Timeout bounds the trace on the node, e.g. 10*time.Second.
+	Timeout time.Duration
+	// OnlyTopCall makes callTracer skip nested calls.
+	OnlyTopCall bool
+	// WithLogs makes callTracer include the logs emitted by each frame.
+	WithLogs bool
+	// DiffMode makes prestateTracer return the state after the transaction
+	// as well, restricted to what changed.
+	DiffMode bool
+}
+
+func (o *TraceOptions) params(tracer string) map[string]interface{} {
+	p := map[string]interface{}{"tracer": tracer}
+	if o == nil {
+		return p
+	}
+	if o.Timeout > 0 {
+		p["timeout"] = o.Timeout.String()
+	}
+	cfg := map[string]interface{}{}
+	switch tracer {
+	case "callTracer":
+		if o.OnlyTopCall {
+			cfg["onlyTopCall"] = true
+		}
+		if o.WithLogs {
+			cfg["withLog"] = true
+		}
+	case "prestateTracer":
+		if o.DiffMode {
+			cfg["diffMode"] = true
+		}
+	}
+	if len(cfg) > 0 {
+		p["tracerConfig"] = cfg
+	}
+	return p
+}
+
+// CallFrame is one call in a callTracer trace. Calls holds the frames of
+// the calls it made, in execution order.
+type CallFrame struct {
+	// Type is CALL, STATICCALL, DELEGATECALL, CALLCODE, CREATE, CREATE2 or
+	// SELFDESTRUCT.
+	Type    string
+	From    string
+	To      string
+	Value   *big.Int
+	Gas     uint64
+	GasUsed uint64
+	Input   string
+	Output  string
+	// Error is set when the frame failed, e.g. "execution reverted".
+	Error string
+	// RevertReason is the node's decoding of a revert, if it made one.
+	RevertReason string
+	Calls        []CallFrame
+	Logs         []CallLog
+}
+
+// CallLog is a log emitted by a call frame when WithLogs is set.
+type CallLog struct {
+	Address string   `json:"address"`
+	Topics  []string `json:"topics"`
+	Data    string   `json:"data"`
+	// Position is the number of sub-calls the frame had made when the log
+	// was emitted.
+	Position uint64 `json:"position"`
+}
+
+// UnmarshalJSON decodes a callTracer frame, where numbers are hex
+// quantities.
+func (f *CallFrame) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Type         string      `json:"type"`
+		From         string      `json:"from"`
+		To           string      `json:"to"`
+		Value        string      `json:"value"`
+		Gas          string      `json:"gas"`
+		GasUsed      string      `json:"gasUsed"`
+		Input        string      `json:"input"`
+		Output       string      `json:"output"`
+		Error        string      `json:"error"`
+		RevertReason string      `json:"revertReason"`
+		Calls        []CallFrame `json:"calls"`
+		Logs         []struct {
+			Address  string          `json:"address"`
+			Topics   []string        `json:"topics"`
+			Data     string          `json:"data"`
+			Position json.RawMessage `json:"position"`
+		} `json:"logs"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*f = CallFrame{
+		Type:         raw.Type,
+		From:         raw.From,
+		To:           raw.To,
+		Input:        raw.Input,
+		Output:       raw.Output,
+		Error:        raw.Error,
+		RevertReason: raw.RevertReason,
+		Calls:        raw.Calls,
+		Value:        new(big.Int),
+	}
+	var err error
+	if raw.Value != "" {
+		if f.Value, err = hexBig(raw.Value); err != nil {
+			return fmt.Errorf("value: %w", err)
+		}
+	}
+	if f.Gas, err = hexQuantity(raw.Gas); err != nil {
+		return fmt.Errorf("gas: %w", err)
+	}
+	if f.GasUsed, err = hexQuantity(raw.GasUsed); err != nil {
+		return fmt.Errorf("gasUsed: %w", err)
+	}
+	for _, l := range raw.Logs {
+		pos, err := flexQuantity(l.Position)
+		if err != nil {
+			return fmt.Errorf("log position: %w", err)
+		}
+		f.Logs = append(f.Logs, CallLog{Address: l.Address, Topics: l.Topics, Data: l.Data, Position: pos})
+	}
+	return nil
+}
+
+// Failed reports whether the frame reverted or otherwise errored.
+func (f *CallFrame) Failed() bool { return f.Error != "" }
+
+// Revert returns the frame's failure as a *RevertError, decoded like the
+// errors of Call, or nil if the frame did not revert.
+func (f *CallFrame) Revert() *RevertError {
+	if !f.Failed() || !strings.Contains(strings.ToLower(f.Error), "revert") {
+		return nil
+	}
+	data := f.Output
+	if data == "" {
+		data = "0x"
+	}
+	re := &RevertError{Code: 3, Message: f.Error, Data: data}
+	re.decode()
+	if re.Reason == "" {
+		re.Reason = f.RevertReason
+	}
+	return re
+}
+
+// Walk calls fn for f and every nested frame, depth first in execution
+// order; depth is 0 for f. Returning false from fn skips the frame's
+// children.
+func (f *CallFrame) Walk(fn func(frame *CallFrame, depth int) bool) {
+	f.walk(fn, 0)
+}
+
+func (f *CallFrame) walk(fn func(*CallFrame, int) bool, depth int) {
+	if !fn(f, depth) {
+		return
+	}
+	for i := range f.Calls {
+		f.Calls[i].walk(fn, depth+1)
+	}
+}
+
+// PrestateAccount is an account as reported by prestateTracer. Fields the
+// tracer omits are left zero: in diff mode only what changed is present.
+type PrestateAccount struct {
+	Balance *big.Int
+	Nonce   uint64
+	Code    string
+	// Storage maps slot to value, both 32-byte hex.
+	Storage map[string]string
+}
+
+// UnmarshalJSON decodes a prestateTracer account. Balance is a hex
+// quantity; nonce is a plain number in Geth and hex in some other clients.
+func (a *PrestateAccount) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Balance string            `json:"balance"`
+		Nonce   json.RawMessage   `json:"nonce"`
+		Code    string            `json:"code"`
+		Storage map[string]string `json:"storage"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*a = PrestateAccount{Code: raw.Code, Storage: raw.Storage}
+	var err error
+	if raw.Balance != "" {
+		if a.Balance, err = hexBig(raw.Balance); err != nil {
+			return fmt.Errorf("balance: %w", err)
+		}
+	}
+	if a.Nonce, err = flexQuantity(raw.Nonce); err != nil {
+		return fmt.Errorf("nonce: %w", err)
+	}
+	return nil
+}
+
+// StateTrace is a prestateTracer result keyed by address. Post is only set
+// in diff mode.
+type StateTrace struct {
+	Pre  map[string]*PrestateAccount `json:"pre"`
+	Post map[string]*PrestateAccount `json:"post,omitempty"`
+}
+
+// flexQuantity parses a JSON number or hex string quantity; empty input
+// is zero.
+func flexQuantity(raw json.RawMessage) (uint64, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, nil
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return hexQuantity(s)
+	}
+	return strconv.ParseUint(string(raw), 10, 64)
+}
+
+// TraceTransaction runs debug_traceTransaction with callTracer and returns
+// the root call frame.
+func TraceTransaction(ctx context.Context, url, txHash string, opts *TraceOptions) (*CallFrame, error) {
+	return traceCalls(ctx, urlCaller(url), "debug_traceTransaction", []interface{}{txHash}, opts)
+}
+
+// TraceTransaction runs debug_traceTransaction with callTracer through the
+// pool.
+func (p *Pool) TraceTransaction(ctx context.Context, txHash string, opts *TraceOptions) (*CallFrame, error) {
+	return traceCalls(ctx, p.caller(), "debug_traceTransaction", []interface{}{txHash}, opts)
+}
+
+// TraceTransactionState runs debug_traceTransaction with prestateTracer
+// and returns the accounts the transaction touched.
+func TraceTransactionState(ctx context.Context, url, txHash string, opts *TraceOptions) (*StateTrace, error) {
+	return traceState(ctx, urlCaller(url), "debug_traceTransaction", []interface{}{txHash}, opts)
+}
+
+// TraceTransactionState runs debug_traceTransaction with prestateTracer
+// through the pool.
+func (p *Pool) TraceTransactionState(ctx context.Context, txHash string, opts *TraceOptions) (*StateTrace, error) {
+	return traceState(ctx, p.caller(), "debug_traceTransaction", []interface{}{txHash}, opts)
+}
+
+// TraceCall runs debug_traceCall with callTracer, executing msg on top of
+// block ("latest" if empty), and returns the root call frame. A revert is
+// reported in the frame, not as an error.
+func TraceCall(ctx context.Context, url string, msg CallMsg, block string, opts *TraceOptions) (*CallFrame, error) {
+	return traceCalls(ctx, urlCaller(url), "debug_traceCall", traceCallParams(msg, block), opts)
+}
+
+// TraceCall runs debug_traceCall with callTracer through the pool.
+func (p *Pool) TraceCall(ctx context.Context, msg CallMsg, block string, opts *TraceOptions) (*CallFrame, error) {
+	return traceCalls(ctx, p.caller(), "debug_traceCall", traceCallParams(msg, block), opts)
+}
+
+// TraceCallState runs debug_traceCall with prestateTracer and returns the
+// accounts msg would touch.
+func TraceCallState(ctx context.Context, url string, msg CallMsg, block string, opts *TraceOptions) (*StateTrace, error) {
+	return traceState(ctx, urlCaller(url), "debug_traceCall", traceCallParams(msg, block), opts)
+}
+
+// TraceCallState runs debug_traceCall with prestateTracer through the
+// pool.
+func (p *Pool) TraceCallState(ctx context.Context, msg CallMsg, block string, opts *TraceOptions) (*StateTrace, error) {
+	return traceState(ctx, p.caller(), "debug_traceCall", traceCallParams(msg, block), opts)
+}
+
+// traceCallParams are the leading debug_traceCall parameters: the call and the
+// block to run it on.
+func traceCallParams(msg CallMsg, block string) []interface{} {
+	if block == "" {
+		block = "latest"
+	}
+	return []interface{}{msg.params(), block}
+}
+
+// runTrace calls method with params followed by the config for tracer.
+func runTrace(ctx context.Context, call callFunc, method string, params []interface{}, tracer string, opts *TraceOptions) (string, error) {
+	return call(ctx, method, mustJSON(append(params, opts.params(tracer))))
+}
+
+func traceCalls(ctx context.Context, call callFunc, method string, params []interface{}, opts *TraceOptions) (*CallFrame, error) {
+	res, err := runTrace(ctx, call, method, params, "callTracer", opts)
+	if err != nil {
+		return nil, err
+	}
+	var f CallFrame
+	if err := json.Unmarshal([]byte(res), &f); err != nil {
+		return nil, fmt.Errorf("chainrpc: %s callTracer result: %w", method, err)
+	}
+	return &f, nil
+}
+
+func traceState(ctx context.Context, call callFunc, method string, params []interface{}, opts *TraceOptions) (*StateTrace, error) {
+	res, err := runTrace(ctx, call, method, params, "prestateTracer", opts)
+	if err != nil {
+		return nil, err
+	}
+	var st StateTrace
+	if opts != nil && opts.DiffMode {
+		err = json.Unmarshal([]byte(res), &st)
+	} else {
+		err = json.Unmarshal([]byte(res), &st.Pre)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("chainrpc: %s prestateTracer result: %w", method, err)
+	}
+	return &st, nil
+}
@@ -0,0 +1,84 @@
+package chainrpc
+
+import (
+	"context"
+
+	"github.com/DarshanKumar89/chainfoundry/chainkit"
+)
+
+// Tracer starts spans around RPC calls. It mirrors the shape of an
+// OpenTelemetry tracer so an adapter is a few lines:
+//
+//	type otelTracer struct{ t trace.Tracer }
+//
+//	func (o otelTracer) Start(ctx context.Context, name string) (context.Context, chainrpc.Span) {
+//		ctx, s := o.t.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+//		return ctx, otelSpan{s}
+//	}
+//
+// Spans are started from the caller's context, so they nest under the
+// caller's active trace.
+type Tracer interface {
+	Start(ctx context.Context, spanName string) (context.Context, Span)
+}
+
+// Span is the subset of a tracing span used by chainrpc.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Attribute is a key/value span attribute.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span attribute keys set by chainrpc.
+const (
+	AttrRPCSystem  = "rpc.system"
+	AttrRPCMethod  = "rpc.method"
+	AttrProvider   = "server.address"
+	AttrRetryCount = "chainrpc.retry_count"
+	AttrErrorClass = "chainrpc.error_class"
+)
+
+// WithTracer emits one span per call on t. It is experimental: calls made
+// with it fail until FeatureTracing is enabled.
+func WithTracer(t Tracer) Option {
+	return func(o *callOptions) { o.tracer = t }
+}
+
+// traced runs fn inside a span named after method when a tracer is set.
+// provider is reported as a label; for pools the provider that finally
+// served the call replaces it.
+func (o *callOptions) traced(ctx context.Context, method, provider string, fn func(ctx context.Context) (string, error)) (string, error) {
+	if o.tracer == nil {
+		return fn(ctx)
+	}
+	if err := chainkit.Require(FeatureTracing); err != nil {
+		return "", err
+	}
+	ctx, span := o.tracer.Start(ctx, "chainrpc "+method)
+	defer span.End()
+	out, err := fn(ctx)
+	if o.servedBy != "" {
+		provider = o.servedBy
+	}
+	retries := o.attempts - 1
+	if retries < 0 {
+		retries = 0
+	}
+	span.SetAttributes(
+		Attribute{AttrRPCSystem, "jsonrpc"},
+		Attribute{AttrRPCMethod, method},
+		Attribute{AttrProvider, ProviderLabel(provider)},
+		Attribute{AttrRetryCount, retries},
+		Attribute{AttrErrorClass, ErrorClass(err)},
+	)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return out, err
+}
@@ -0,0 +1,399 @@
+package chainrpc
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DarshanKumar89/chainfoundry/chaincodec/abi"
+)
+
+var (
+	// ErrTxReplaced is returned by WaitMined when another transaction with
+	// the same sender and nonce was mined instead.
+	ErrTxReplaced = errors.New("chainrpc: transaction replaced")
+	// ErrTxNotFound is returned by WaitMined when the node never reports the
+	// transaction within the drop timeout.
+	ErrTxNotFound = errors.New("chainrpc: transaction not found")
+	// ErrTxReverted is returned with the receipt of a mined transaction whose
+	// status is 0.
+	ErrTxReverted = errors.New("chainrpc: transaction reverted")
+)
+
+// callFunc issues one JSON-RPC request.
+type callFunc func(ctx context.Context, method, paramsJSON string) (string, error)
+
+func urlCaller(url string) callFunc {
+	return func(ctx context.Context, method, paramsJSON string) (string, error) {
+		return CallContext(ctx, url, method, paramsJSON)
+	}
+}
+
+func (p *Pool) caller() callFunc {
+	return func(ctx context.Context, method, paramsJSON string) (string, error) {
+		return p.CallContext(ctx, method, paramsJSON)
+	}
+}
+
+// Receipt is a transaction receipt.
+type Receipt struct {
+	TxHash            string          `json:"transactionHash"`
+	BlockHash         string          `json:"blockHash"`
+	BlockNumber       uint64          `json:"blockNumber"`
+	Status            uint64          `json:"status"`
+	GasUsed           uint64          `json:"gasUsed"`
+	EffectiveGasPrice string          `json:"effectiveGasPrice,omitempty"`
+	ContractAddress   string          `json:"contractAddress,omitempty"`
+	Logs              json.RawMessage `json:"logs"`
+	// Confirmations is the number of blocks including and on top of the
+	// receipt's block when it was returned.
+	Confirmations uint64 `json:"confirmations"`
+}
+
+// UnmarshalJSON decodes a node's receipt, where numbers are hex quantities.
+func (r *Receipt) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		TxHash            string          `json:"transactionHash"`
+		BlockHash         string          `json:"blockHash"`
+		BlockNumber       string          `json:"blockNumber"`
+		Status            string          `json:"status"`
+		GasUsed           string          `json:"gasUsed"`
+		EffectiveGasPrice string          `json:"effectiveGasPrice"`
+		ContractAddress   *string         `json:"contractAddress"`
+		Logs              json.RawMessage `json:"logs"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*r = Receipt{
+		TxHash:            raw.TxHash,
+		BlockHash:         raw.BlockHash,
+		EffectiveGasPrice: raw.EffectiveGasPrice,
+		Logs:              raw.Logs,
+	}
+	if raw.ContractAddress != nil {
+		r.ContractAddress = *raw.ContractAddress
+	}
+	var err error
+	if r.BlockNumber, err = hexQuantity(raw.BlockNumber); err != nil {
+		return fmt.Errorf("blockNumber: %w", err)
+	}
+	if r.Status, err = hexQuantity(raw.Status); err != nil {
+		return fmt.Errorf("status: %w", err)
+	}
+	if r.GasUsed, err = hexQuantity(raw.GasUsed); err != nil {
+		return fmt.Errorf("gasUsed: %w", err)
+	}
+	return nil
+}
+
+// Transaction is a transaction as returned by eth_getTransactionByHash or
+// a full-body pending transaction notification. Fee fields that do not
+// apply to the transaction's type are nil.
+type Transaction struct {
+	Hash string
+	From string
+	// To is empty for contract creations.
+	To                   string
+	Nonce                uint64
+	Value                *big.Int
+	Gas                  uint64
+	GasPrice             *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	Input                string
+	Type                 uint64
+	// BlockNumber is nil while the transaction is pending.
+	BlockNumber *uint64
+	// Raw is the node's JSON, for fields not decoded here.
+	Raw json.RawMessage
+}
+
+// UnmarshalJSON decodes a node's transaction, where numbers are hex
+// quantities.
+func (t *Transaction) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Hash                 string  `json:"hash"`
+		From                 string  `json:"from"`
+		To                   *string `json:"to"`
+		Nonce                string  `json:"nonce"`
+		Value                string  `json:"value"`
+		Gas                  string  `json:"gas"`
+		GasPrice             string  `json:"gasPrice"`
+		MaxFeePerGas         string  `json:"maxFeePerGas"`
+		MaxPriorityFeePerGas string  `json:"maxPriorityFeePerGas"`
+		Input                string  `json:"input"`
+		Type                 string  `json:"type"`
+		BlockNumber          *string `json:"blockNumber"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*t = Transaction{Hash: raw.Hash, From: raw.From, Input: raw.Input, Raw: append(json.RawMessage(nil), b...)}
+	if raw.To != nil {
+		t.To = *raw.To
+	}
+	var err error
+	for _, q := range []struct {
+		name string
+		src  string
+		dst  *uint64
+	}{{"nonce", raw.Nonce, &t.Nonce}, {"gas", raw.Gas, &t.Gas}, {"type", raw.Type, &t.Type}} {
+		if *q.dst, err = hexQuantity(q.src); err != nil {
+			return fmt.Errorf("%s: %w", q.name, err)
+		}
+	}
+	for _, q := range []struct {
+		name string
+		src  string
+		dst  **big.Int
+	}{{"value", raw.Value, &t.Value}, {"gasPrice", raw.GasPrice, &t.GasPrice}, {"maxFeePerGas", raw.MaxFeePerGas, &t.MaxFeePerGas}, {"maxPriorityFeePerGas", raw.MaxPriorityFeePerGas, &t.MaxPriorityFeePerGas}} {
+		if q.src == "" {
+			continue
+		}
+		if *q.dst, err = hexBig(q.src); err != nil {
+			return fmt.Errorf("%s: %w", q.name, err)
+		}
+	}
+	if raw.BlockNumber != nil {
+		n, err := hexQuantity(*raw.BlockNumber)
+		if err != nil {
+			return fmt.Errorf("blockNumber: %w", err)
+		}
+		t.BlockNumber = &n
+	}
+	return nil
+}
+
+func hexQuantity(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 64)
+}
+
+// SendRawTransaction broadcasts a signed transaction and returns its hash.
+// A node answering "already known" is treated as success, so retries and
+// rebroadcasts are safe.
+func SendRawTransaction(ctx context.Context, url, rawTx string) (string, error) {
+	return sendRawTransaction(ctx, urlCaller(url), rawTx)
+}
+
+// SendRawTransaction broadcasts a signed transaction through the pool.
+func (p *Pool) SendRawTransaction(ctx context.Context, rawTx string) (string, error) {
+	return sendRawTransaction(ctx, p.caller(), rawTx)
+}
+
+func sendRawTransaction(ctx context.Context, call callFunc, rawTx string) (string, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(rawTx, "0x"))
+	if err != nil || len(raw) == 0 {
+		return "", fmt.Errorf("chainrpc: invalid raw transaction")
+	}
+	hash := "0x" + hex.EncodeToString(abi.Keccak256(raw))
+	params, _ := json.Marshal([]string{"0x" + hex.EncodeToString(raw)})
+	res, err := call(ctx, "eth_sendRawTransaction", string(params))
+	if err != nil {
+		if alreadyKnown(err) {
+			return hash, nil
+		}
+		return "", err
+	}
+	var got string
+	if err := json.Unmarshal([]byte(res), &got); err != nil {
+		return "", fmt.Errorf("chainrpc: eth_sendRawTransaction result: %w", err)
+	}
+	return got, nil
+}
+
+func alreadyKnown(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "already known") || strings.Contains(msg, "known transaction") ||
+		strings.Contains(msg, "already imported")
+}
+
+// WaitOption configures WaitMined.
+type WaitOption func(*waitConfig)
+
+type waitConfig struct {
+	poll time.Duration
+	drop time.Duration
+}
+
+// WithPollInterval sets how often WaitMined polls the node (default 2s).
+func WithPollInterval(d time.Duration) WaitOption {
+	return func(c *waitConfig) { c.poll = d }
+}
+
+// WithDropTimeout sets how long WaitMined waits for a node to report a
+// transaction it has never seen before giving up with ErrTxNotFound
+// (default 5m). Zero waits until ctx is done.
+func WithDropTimeout(d time.Duration) WaitOption {
+	return func(c *waitConfig) { c.drop = d }
+}
+
+// WaitMined polls until txHash is mined with at least confirmations blocks
+// (1 means included in the head block) and returns its receipt.
+//
+// A receipt that disappears or moves to another block hash after a reorg
+// resets the wait. If the transaction leaves the mempool and the sender's
+// nonce moves past it, ErrTxReplaced is returned. A mined transaction with
+// status 0 returns its receipt together with ErrTxReverted.
+func WaitMined(ctx context.Context, url, txHash string, confirmations uint64, opts ...WaitOption) (*Receipt, error) {
+	return waitMined(ctx, urlCaller(url), txHash, confirmations, opts)
+}
+
+// WaitMined waits for txHash through the pool.
+func (p *Pool) WaitMined(ctx context.Context, txHash string, confirmations uint64, opts ...WaitOption) (*Receipt, error) {
+	return waitMined(ctx, p.caller(), txHash, confirmations, opts)
+}
+
+func waitMined(ctx context.Context, call callFunc, txHash string, confirmations uint64, opts []WaitOption) (*Receipt, error) {
+	cfg := waitConfig{poll: 2 * time.Second, drop: 5 * time.Minute}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if confirmations == 0 {
+		confirmations = 1
+	}
+	var (
+		sender   string
+		nonce    uint64
+		seen     bool
+		start    = time.Now()
+		ticker   = time.NewTicker(cfg.poll)
+		txParams = mustJSON([]string{txHash})
+	)
+	defer ticker.Stop()
+
+	for {
+		rec, err := fetchReceipt(ctx, call, txParams)
+		if err != nil {
+			return nil, err
+		}
+		if rec != nil {
+			seen = true
+			head, err := headBlock(ctx, call)
+			if err != nil {
+				return nil, err
+			}
+			if head >= rec.BlockNumber {
+				rec.Confirmations = head - rec.BlockNumber + 1
+			}
+			if rec.Confirmations >= confirmations {
+				// Re-read to make sure the receipt was not reorged out
+				// between the two calls.
+				again, err := fetchReceipt(ctx, call, txParams)
+				if err != nil {
+					return nil, err
+				}
+				if again != nil && again.BlockHash == rec.BlockHash {
+					if rec.Status == 0 {
+						return rec, fmt.Errorf("%w: %s in block %d", ErrTxReverted, txHash, rec.BlockNumber)
+					}
+					return rec, nil
+				}
+			}
+		} else {
+			from, n, ok, err := pendingTx(ctx, call, txParams)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case ok:
+				seen, sender, nonce = true, from, n
+			case sender != "":
+				// Dropped from the pool: replaced if the nonce was used.
+				used, err := nonceUsed(ctx, call, sender, nonce)
+				if err != nil {
+					return nil, err
+				}
+				if used {
+					return nil, fmt.Errorf("%w: %s (nonce %d of %s)", ErrTxReplaced, txHash, nonce, sender)
+				}
+			case !seen && cfg.drop > 0 && time.Since(start) > cfg.drop:
+				return nil, fmt.Errorf("%w: %s", ErrTxNotFound, txHash)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func mustJSON(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func fetchReceipt(ctx context.Context, call callFunc, txParams string) (*Receipt, error) {
+	res, err := call(ctx, "eth_getTransactionReceipt", txParams)
+	if err != nil {
+		return nil, err
+	}
+	if isNull(json.RawMessage(res)) {
+		return nil, nil
+	}
+	var r Receipt
+	if err := json.Unmarshal([]byte(res), &r); err != nil {
+		return nil, fmt.Errorf("chainrpc: receipt: %w", err)
+	}
+	if r.BlockHash == "" {
+		return nil, nil
+	}
+	return &r, nil
+}
+
+// pendingTx returns the sender and nonce of a transaction the node knows.
+func pendingTx(ctx context.Context, call callFunc, txParams string) (string, uint64, bool, error) {
+	res, err := call(ctx, "eth_getTransactionByHash", txParams)
+	if err != nil {
+		return "", 0, false, err
+	}
+	if isNull(json.RawMessage(res)) {
+		return "", 0, false, nil
+	}
+	var tx struct {
+		From  string `json:"from"`
+		Nonce string `json:"nonce"`
+	}
+	if err := json.Unmarshal([]byte(res), &tx); err != nil {
+		return "", 0, false, fmt.Errorf("chainrpc: transaction: %w", err)
+	}
+	n, err := hexQuantity(tx.Nonce)
+	if err != nil {
+		return "", 0, false, fmt.Errorf("chainrpc: transaction nonce: %w", err)
+	}
+	return tx.From, n, true, nil
+}
+
+func nonceUsed(ctx context.Context, call callFunc, sender string, nonce uint64) (bool, error) {
+	res, err := call(ctx, "eth_getTransactionCount", mustJSON([]string{sender, "latest"}))
+	if err != nil {
+		return false, err
+	}
+	count, err := parseQuantity(res)
+	if err != nil {
+		return false, fmt.Errorf("chainrpc: eth_getTransactionCount: %w", err)
+	}
+	return count > nonce, nil
+}
+
+func headBlock(ctx context.Context, call callFunc) (uint64, error) {
+	res, err := call(ctx, "eth_blockNumber", "[]")
+	if err != nil {
+		return 0, err
+	}
+	n, err := parseQuantity(res)
+	if err != nil {
+		return 0, fmt.Errorf("chainrpc: eth_blockNumber: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,218 @@
+package chainrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// Validator checks the raw JSON result of a single method and returns a
+// description of the problem, or nil if the result is well formed.
+type Validator func(result json.RawMessage) error
+
+// ValidationError reports a provider response that failed validation.
+type ValidationError struct {
+	Provider string
+	Method   string
+	Reason   string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("chainrpc: malformed %s response from %s: %s", e.Method, e.Provider, e.Reason)
+}
+
+var (
+	quantityRe = regexp.MustCompile(`^0x(0|[1-9a-fA-F][0-9a-fA-F]*)$`)
+	dataRe     = regexp.MustCompile(`^0x([0-9a-fA-F]{2})*$`)
+	hash32Re   = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+	addressRe  = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+)
+
+// defaultValidators covers the methods whose malformed responses most often
+// corrupt downstream pipelines.
+var defaultValidators = map[string]Validator{
+	"eth_blockNumber":           validateQuantity,
+	"eth_chainId":               validateQuantity,
+	"eth_gasPrice":              validateQuantity,
+	"eth_maxPriorityFeePerGas":  validateQuantity,
+	"eth_getBalance":            validateQuantity,
+	"eth_getTransactionCount":   validateQuantity,
+	"eth_estimateGas":           validateQuantity,
+	"eth_call":                  validateData,
+	"eth_getCode":               validateData,
+	"eth_getStorageAt":          validateData,
+	"eth_getBlockByNumber":      validateBlock,
+	"eth_getBlockByHash":        validateBlock,
+	"eth_getTransactionReceipt": validateReceipt,
+	"eth_getLogs":               validateLogs,
+}
+
+// WithValidation checks responses of well-known methods (quantities, blocks,
+// receipts, logs) and fails the call with a *ValidationError when a provider
+// returns malformed data. Methods without a validator pass through unchecked.
+func WithValidation() Option {
+	return func(o *callOptions) {
+		o.ensureValidators()
+		for m, v := range defaultValidators {
+			if _, ok := o.validators[m]; !ok {
+				o.validators[m] = v
+			}
+		}
+	}
+}
+
+// WithValidator validates responses of method with v, overriding any
+// built-in validator for that method.
+func WithValidator(method string, v Validator) Option {
+	return func(o *callOptions) {
+		o.ensureValidators()
+		o.validators[method] = v
+	}
+}
+
+func (o *callOptions) ensureValidators() {
+	if o.validators == nil {
+		o.validators = make(map[string]Validator)
+	}
+}
+
+func (o *callOptions) validate(provider, method, result string) error {
+	v, ok := o.validators[method]
+	if !ok {
+		return nil
+	}
+	if err := v(json.RawMessage(result)); err != nil {
+		return &ValidationError{Provider: provider, Method: method, Reason: err.Error()}
+	}
+	return nil
+}
+
+func validateQuantity(raw json.RawMessage) error {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return fmt.Errorf("expected hex quantity, got %s", truncate(raw))
+	}
+	return checkQuantity("result", s)
+}
+
+func validateData(raw json.RawMessage) error {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return fmt.Errorf("expected hex data, got %s", truncate(raw))
+	}
+	if !dataRe.MatchString(s) {
+		return fmt.Errorf("result %q is not well-formed hex data", s)
+	}
+	return nil
+}
+
+func validateBlock(raw json.RawMessage) error {
+	if isNull(raw) {
+		return nil
+	}
+	var b map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return fmt.Errorf("expected block object, got %s", truncate(raw))
+	}
+	if err := requireQuantities(b, "number", "timestamp", "gasUsed", "gasLimit"); err != nil {
+		return err
+	}
+	return requireHashes(b, "hash", "parentHash")
+}
+
+func validateReceipt(raw json.RawMessage) error {
+	if isNull(raw) {
+		return nil
+	}
+	var r map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return fmt.Errorf("expected receipt object, got %s", truncate(raw))
+	}
+	if err := requireHashes(r, "transactionHash", "blockHash"); err != nil {
+		return err
+	}
+	if err := requireQuantities(r, "blockNumber", "transactionIndex", "gasUsed", "cumulativeGasUsed"); err != nil {
+		return err
+	}
+	if _, ok := r["status"]; !ok {
+		if _, ok := r["root"]; !ok {
+			return fmt.Errorf("receipt has neither status nor root")
+		}
+	}
+	return validateLogs(r["logs"])
+}
+
+func validateLogs(raw json.RawMessage) error {
+	var logs []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &logs); err != nil || logs == nil {
+		return fmt.Errorf("expected array of logs, got %s", truncate(raw))
+	}
+	for i, l := range logs {
+		var addr string
+		if err := json.Unmarshal(l["address"], &addr); err != nil || !addressRe.MatchString(addr) {
+			return fmt.Errorf("log %d: missing or invalid address", i)
+		}
+		var topics []string
+		if err := json.Unmarshal(l["topics"], &topics); err != nil {
+			return fmt.Errorf("log %d: missing or invalid topics", i)
+		}
+		for _, t := range topics {
+			if !hash32Re.MatchString(t) {
+				return fmt.Errorf("log %d: topic %q is not a 32-byte hash", i, t)
+			}
+		}
+		var data string
+		if err := json.Unmarshal(l["data"], &data); err != nil || !dataRe.MatchString(data) {
+			return fmt.Errorf("log %d: missing or invalid data", i)
+		}
+		// Pending logs legitimately carry null block fields.
+		if !isNull(l["blockNumber"]) {
+			if err := requireQuantities(l, "blockNumber", "logIndex"); err != nil {
+				return fmt.Errorf("log %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func requireQuantities(obj map[string]json.RawMessage, fields ...string) error {
+	for _, f := range fields {
+		var s string
+		if err := json.Unmarshal(obj[f], &s); err != nil {
+			return fmt.Errorf("field %q missing or not a string", f)
+		}
+		if err := checkQuantity(f, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func requireHashes(obj map[string]json.RawMessage, fields ...string) error {
+	for _, f := range fields {
+		var s string
+		if err := json.Unmarshal(obj[f], &s); err != nil || !hash32Re.MatchString(s) {
+			return fmt.Errorf("field %q missing or not a 32-byte hash", f)
+		}
+	}
+	return nil
+}
+
+func checkQuantity(field, s string) error {
+	if !quantityRe.MatchString(s) {
+		return fmt.Errorf("%s %q is not a well-formed hex quantity", field, s)
+	}
+	return nil
+}
+
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
+
+func truncate(raw json.RawMessage) string {
+	const max = 64
+	if len(raw) > max {
+		return string(raw[:max]) + "..."
+	}
+	return string(raw)
+}
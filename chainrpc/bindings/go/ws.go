@@ -0,0 +1,222 @@
+package chainrpc
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocket endpoints (ws:// and wss://) are served in Go by a minimal
+// RFC 6455 client that only carries JSON-RPC text messages; they are used
+// for subscriptions, which the native HTTP transport cannot deliver.
+
+// wsMaxMessage bounds one reassembled message, well above the largest
+// block or log notification a node sends.
+const wsMaxMessage = 64 << 20
+
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xa
+)
+
+// isWebSocket reports whether endpoint is a ws:// or wss:// URL.
+func isWebSocket(endpoint string) bool {
+	return strings.HasPrefix(endpoint, "ws://") || strings.HasPrefix(endpoint, "wss://")
+}
+
+// wsConn is a client WebSocket connection.
+type wsConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	wmu  sync.Mutex
+}
+
+// dialWS opens a WebSocket to endpoint, sending header with the upgrade
+// request.
+func dialWS(ctx context.Context, endpoint string, header http.Header) (*wsConn, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		if u.Scheme == "wss" {
+			addr = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			addr = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if u.Scheme == "wss" {
+		tc := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tc
+	}
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+	var req strings.Builder
+	fmt.Fprintf(&req, "GET %s HTTP/1.1\r\nHost: %s\r\n", u.RequestURI(), u.Host)
+	req.WriteString("Upgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Version: 13\r\n")
+	fmt.Fprintf(&req, "Sec-WebSocket-Key: %s\r\n", key)
+	for k, vs := range header {
+		for _, v := range vs {
+			fmt.Fprintf(&req, "%s: %s\r\n", k, v)
+		}
+	}
+	req.WriteString("\r\n")
+	if _, err := io.WriteString(conn, req.String()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("websocket upgrade: HTTP %d", resp.StatusCode)
+	}
+	sum := sha1.Sum([]byte(key + wsGUID))
+	if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		conn.Close()
+		return nil, errors.New("websocket upgrade: bad Sec-WebSocket-Accept")
+	}
+	conn.SetDeadline(time.Time{})
+	return &wsConn{conn: conn, br: br}, nil
+}
+
+// WriteMessage sends b as one text message.
+func (c *wsConn) WriteMessage(b []byte) error {
+	return c.writeFrame(wsText, b)
+}
+
+func (c *wsConn) writeFrame(op byte, payload []byte) error {
+	hdr := make([]byte, 2, 14)
+	hdr[0] = 0x80 | op
+	switch n := len(payload); {
+	case n < 126:
+		hdr[1] = 0x80 | byte(n)
+	case n <= 0xffff:
+		hdr[1] = 0x80 | 126
+		hdr = binary.BigEndian.AppendUint16(hdr, uint16(n))
+	default:
+		hdr[1] = 0x80 | 127
+		hdr = binary.BigEndian.AppendUint64(hdr, uint64(n))
+	}
+	// Client frames must be masked.
+	var mask [4]byte
+	rand.Read(mask[:])
+	hdr = append(hdr, mask[:]...)
+	frame := append(hdr, payload...)
+	for i := range payload {
+		frame[len(hdr)+i] ^= mask[i%4]
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// ReadMessage returns the next text or binary message, answering pings
+// and reassembling fragments on the way.
+func (c *wsConn) ReadMessage() ([]byte, error) {
+	var msg []byte
+	for {
+		var hdr [2]byte
+		if _, err := io.ReadFull(c.br, hdr[:]); err != nil {
+			return nil, err
+		}
+		fin, op := hdr[0]&0x80 != 0, hdr[0]&0x0f
+		masked, n := hdr[1]&0x80 != 0, uint64(hdr[1]&0x7f)
+		switch n {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+				return nil, err
+			}
+			n = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+				return nil, err
+			}
+			n = binary.BigEndian.Uint64(ext[:])
+		}
+		if n > wsMaxMessage || uint64(len(msg))+n > wsMaxMessage {
+			return nil, fmt.Errorf("websocket message exceeds %d bytes", wsMaxMessage)
+		}
+		var mask [4]byte
+		if masked {
+			if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+				return nil, err
+			}
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(c.br, payload); err != nil {
+			return nil, err
+		}
+		if masked {
+			for i := range payload {
+				payload[i] ^= mask[i%4]
+			}
+		}
+		switch op {
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return nil, err
+			}
+		case wsPong:
+		case wsClose:
+			c.writeFrame(wsClose, payload)
+			if len(payload) >= 2 {
+				return nil, fmt.Errorf("websocket closed by peer: %d %s", binary.BigEndian.Uint16(payload), payload[2:])
+			}
+			return nil, io.EOF
+		default:
+			msg = append(msg, payload...)
+			if fin {
+				return msg, nil
+			}
+		}
+	}
+}
+
+// Close sends a close frame and closes the connection.
+func (c *wsConn) Close() error {
+	c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	c.writeFrame(wsClose, []byte{0x03, 0xe8})
+	return c.conn.Close()
+}
@@ -0,0 +1,357 @@
+package chainrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML parses the subset of YAML used by configuration files into
+// map[string]any, []any, string, bool, json.Number and nil values. See
+// LoadPoolConfig for what is supported.
+func parseYAML(src string) (any, error) {
+	p := &yamlParser{}
+	for n, raw := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		text := stripYAMLComment(raw)
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(raw, "\t") {
+			return nil, fmt.Errorf("yaml line %d: tabs are not allowed for indentation", n+1)
+		}
+		indent := len(text) - len(strings.TrimLeft(text, " "))
+		p.lines = append(p.lines, yamlLine{no: n + 1, indent: indent, text: strings.TrimRight(text[indent:], " \t")})
+	}
+	if len(p.lines) == 0 {
+		return map[string]any{}, nil
+	}
+	v, err := p.block(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf(p.lines[p.pos], "unexpected indentation")
+	}
+	return v, nil
+}
+
+type yamlLine struct {
+	no     int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func (p *yamlParser) errorf(l yamlLine, format string, args ...any) error {
+	return fmt.Errorf("yaml line %d: %s", l.no, fmt.Sprintf(format, args...))
+}
+
+func isYAMLItem(text string) bool { return text == "-" || strings.HasPrefix(text, "- ") }
+
+// block parses the mapping or sequence starting at the current line.
+func (p *yamlParser) block(indent int) (any, error) {
+	if isYAMLItem(p.lines[p.pos].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) sequence(indent int) (any, error) {
+	out := []any{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent != indent || !isYAMLItem(l.text) {
+			break
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+		if rest == "" {
+			p.pos++
+			v, err := p.nested(indent, false)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+			continue
+		}
+		if _, _, ok := splitYAMLKey(rest); ok || isYAMLItem(rest) {
+			// "- key: value" opens a mapping (or nested sequence) indented
+			// to where its first entry starts.
+			p.lines[p.pos] = yamlLine{no: l.no, indent: l.indent + len(l.text) - len(rest), text: rest}
+			v, err := p.block(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+			continue
+		}
+		v, err := p.scalar(l, rest)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+		p.pos++
+	}
+	return out, nil
+}
+
+func (p *yamlParser) mapping(indent int) (any, error) {
+	out := map[string]any{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent || isYAMLItem(l.text) {
+			return nil, p.errorf(l, "unexpected indentation")
+		}
+		key, value, ok := splitYAMLKey(l.text)
+		if !ok {
+			return nil, p.errorf(l, "expected \"key: value\"")
+		}
+		if _, dup := out[key]; dup {
+			return nil, p.errorf(l, "duplicate key %q", key)
+		}
+		p.pos++
+		if value == "" {
+			v, err := p.nested(indent, true)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = v
+			continue
+		}
+		v, err := p.scalar(l, value)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = v
+	}
+	return out, nil
+}
+
+// nested parses the value of a key or item with nothing after its colon
+// or dash: a deeper block, or nil. A sequence may sit at its key's indent.
+func (p *yamlParser) nested(indent int, key bool) (any, error) {
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	if next.indent > indent || (key && next.indent == indent && isYAMLItem(next.text)) {
+		return p.block(next.indent)
+	}
+	return nil, nil
+}
+
+func (p *yamlParser) scalar(l yamlLine, s string) (any, error) {
+	switch s[0] {
+	case '|', '>':
+		return nil, p.errorf(l, "multi-line scalars are not supported")
+	case '&', '*', '!':
+		return nil, p.errorf(l, "anchors, aliases and tags are not supported")
+	case '[', '{':
+		v, rest, err := parseYAMLFlow(s)
+		if err == nil && strings.TrimSpace(rest) != "" {
+			err = fmt.Errorf("unexpected %q after flow collection", rest)
+		}
+		if err != nil {
+			return nil, p.errorf(l, "%v", err)
+		}
+		return v, nil
+	}
+	v, err := yamlScalar(s)
+	if err != nil {
+		return nil, p.errorf(l, "%v", err)
+	}
+	return v, nil
+}
+
+// splitYAMLKey splits "key: value" (or "key:") outside quotes.
+func splitYAMLKey(text string) (key, value string, ok bool) {
+	quote := byte(0)
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 {
+				quote = c
+			}
+		case c == '[' || c == '{':
+			if i == 0 {
+				return "", "", false
+			}
+		case c == ':' && (i+1 == len(text) || text[i+1] == ' '):
+			k, err := yamlScalar(strings.TrimSpace(text[:i]))
+			if err != nil {
+				return "", "", false
+			}
+			return fmt.Sprint(k), strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// stripYAMLComment drops a # comment that starts a line or follows a space,
+// outside quotes.
+func stripYAMLComment(line string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || line[i-1] == ' ' || line[i-1] == '[' || line[i-1] == '{' || line[i-1] == ',' {
+				quote = c
+			}
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// yamlScalar converts a plain or quoted scalar.
+func yamlScalar(s string) (any, error) {
+	switch {
+	case s == "":
+		return "", nil
+	case s[0] == '"':
+		if len(s) < 2 || s[len(s)-1] != '"' {
+			return nil, fmt.Errorf("unterminated string %s", s)
+		}
+		return strconv.Unquote(s)
+	case s[0] == '\'':
+		if len(s) < 2 || s[len(s)-1] != '\'' {
+			return nil, fmt.Errorf("unterminated string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	switch s {
+	case "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil && strings.IndexFunc(s, func(r rune) bool { return r == '_' || r == 'x' || r == 'X' }) < 0 {
+		if strings.ContainsAny(s, "iInN") {
+			return s, nil // Inf, NaN
+		}
+		return json.Number(s), nil
+	}
+	return s, nil
+}
+
+// parseYAMLFlow parses a [..] or {..} collection at the start of s and
+// returns what follows it.
+func parseYAMLFlow(s string) (any, string, error) {
+	open := s[0]
+	closer := byte(']')
+	if open == '{' {
+		closer = '}'
+	}
+	s = strings.TrimSpace(s[1:])
+	var list []any
+	obj := map[string]any{}
+	for {
+		if s == "" {
+			return nil, "", fmt.Errorf("unterminated %c", open)
+		}
+		if s[0] == closer {
+			if open == '{' {
+				return obj, s[1:], nil
+			}
+			if list == nil {
+				list = []any{}
+			}
+			return list, s[1:], nil
+		}
+		var key string
+		if open == '{' {
+			k, rest, err := yamlFlowToken(s, ":")
+			if err != nil {
+				return nil, "", err
+			}
+			if !strings.HasPrefix(rest, ":") {
+				return nil, "", fmt.Errorf("expected ':' after %q", k)
+			}
+			kv, err := yamlScalar(k)
+			if err != nil {
+				return nil, "", err
+			}
+			key = fmt.Sprint(kv)
+			s = strings.TrimSpace(rest[1:])
+		}
+		var v any
+		if s != "" && (s[0] == '[' || s[0] == '{') {
+			var err error
+			if v, s, err = parseYAMLFlow(s); err != nil {
+				return nil, "", err
+			}
+		} else {
+			tok, rest, err := yamlFlowToken(s, "")
+			if err != nil {
+				return nil, "", err
+			}
+			if v, err = yamlScalar(tok); err != nil {
+				return nil, "", err
+			}
+			s = rest
+		}
+		if open == '{' {
+			obj[key] = v
+		} else {
+			list = append(list, v)
+		}
+		s = strings.TrimSpace(s)
+		if strings.HasPrefix(s, ",") {
+			s = strings.TrimSpace(s[1:])
+		} else if s == "" || s[0] != closer {
+			return nil, "", fmt.Errorf("expected ',' or '%c'", closer)
+		}
+	}
+}
+
+// yamlFlowToken reads a scalar inside a flow collection up to a comma, a
+// closing bracket or one of the extra stop characters, outside quotes.
+func yamlFlowToken(s, stop string) (string, string, error) {
+	if s[0] == '"' || s[0] == '\'' {
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' && s[0] == '"' {
+				i++
+				continue
+			}
+			if s[i] == s[0] {
+				if s[0] == '\'' && i+1 < len(s) && s[i+1] == '\'' {
+					i++
+					continue
+				}
+				return s[:i+1], strings.TrimSpace(s[i+1:]), nil
+			}
+		}
+		return "", "", fmt.Errorf("unterminated string %s", s)
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == ',' || c == ']' || c == '}' {
+			return strings.TrimSpace(s[:i]), s[i:], nil
+		}
+		if strings.IndexByte(stop, c) >= 0 && (i+1 == len(s) || s[i+1] == ' ') {
+			return strings.TrimSpace(s[:i]), s[i:], nil
+		}
+	}
+	return strings.TrimSpace(s), "", nil
+}
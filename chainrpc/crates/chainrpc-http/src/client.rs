@@ -4,9 +4,12 @@
 //! - Automatic retry with exponential backoff for transient errors
 //! - Circuit breaker per provider
 //! - Rate limiter (token bucket)
-//! - Batch request support (true HTTP batching)
+//! - Batch request support (true HTTP batching), split to fit a request
+//!   body size budget
 
 use async_trait::async_trait;
+use std::collections::VecDeque;
+use std::ops::Range;
 use std::sync::Arc;
 use std::time::Duration;
 
@@ -15,7 +18,7 @@ use chainrpc_core::metrics::ProviderMetrics;
 use chainrpc_core::policy::{
     CircuitBreaker, CircuitBreakerConfig, RateLimiter, RateLimiterConfig, RetryConfig, RetryPolicy,
 };
-use chainrpc_core::request::{JsonRpcRequest, JsonRpcResponse};
+use chainrpc_core::request::{JsonRpcRequest, JsonRpcResponse, RpcId};
 use chainrpc_core::transport::{HealthStatus, RpcTransport};
 
 /// Configuration for `HttpRpcClient`.
@@ -25,8 +28,16 @@ pub struct HttpClientConfig {
     pub circuit_breaker: CircuitBreakerConfig,
     pub rate_limiter: RateLimiterConfig,
     pub request_timeout: Duration,
+    /// Upper bound on the serialized body of one HTTP request. Batches that
+    /// would exceed it are split into several requests. `0` disables the
+    /// limit.
+    pub max_request_bytes: usize,
 }
 
+/// Default request body budget. Providers cap bodies between 1 and 10 MB;
+/// staying under the smallest keeps batches portable.
+pub const DEFAULT_MAX_REQUEST_BYTES: usize = 1 << 20;
+
 impl Default for HttpClientConfig {
     fn default() -> Self {
         Self {
@@ -34,10 +45,43 @@ impl Default for HttpClientConfig {
             circuit_breaker: CircuitBreakerConfig::default(),
             rate_limiter: RateLimiterConfig::default(),
             request_timeout: Duration::from_secs(30),
+            max_request_bytes: DEFAULT_MAX_REQUEST_BYTES,
         }
     }
 }
 
+/// HTTP-level transport options: authentication headers, proxy and TLS roots.
+#[derive(Debug, Clone, Default)]
+pub struct HttpTransportOptions {
+    /// Extra headers sent with every request (e.g. provider API keys).
+    pub headers: Vec<(String, String)>,
+    /// Bearer token sent as `Authorization: Bearer <token>`.
+    pub bearer_token: Option<String>,
+    /// HTTP(S) or SOCKS5 proxy URL, e.g. `socks5://127.0.0.1:1080`.
+    pub proxy: Option<String>,
+    /// Additional PEM-encoded root certificates to trust.
+    pub ca_cert_pem: Option<String>,
+    /// PEM-encoded client certificate and private key for mutual TLS.
+    pub client_identity_pem: Option<String>,
+    /// Overrides `HttpClientConfig::request_timeout`.
+    pub timeout: Option<Duration>,
+    /// Overrides `HttpClientConfig::max_request_bytes`.
+    pub max_request_bytes: Option<usize>,
+    /// Maximum idle connections kept alive per host (reqwest default:
+    /// unlimited). `Some(0)` disables connection reuse.
+    pub pool_max_idle_per_host: Option<usize>,
+    /// How long an idle pooled connection is kept (reqwest default: 90s).
+    pub pool_idle_timeout: Option<Duration>,
+    /// TCP keep-alive probe interval; `Some(Duration::ZERO)` disables
+    /// keep-alive probes.
+    pub tcp_keepalive: Option<Duration>,
+    /// Restrict connections to HTTP/1.1 instead of negotiating HTTP/2.
+    pub http1_only: bool,
+    /// Overrides `HttpClientConfig::retry.max_retries`; `Some(0)` leaves
+    /// retrying to the caller.
+    pub max_retries: Option<u32>,
+}
+
 /// HTTP JSON-RPC client with built-in reliability features.
 pub struct HttpRpcClient {
     url: String,
@@ -47,6 +91,7 @@ pub struct HttpRpcClient {
     rate_limiter: RateLimiter,
     #[allow(dead_code)]
     request_timeout: Duration,
+    max_request_bytes: usize,
     metrics: Option<Arc<ProviderMetrics>>,
     /// Adaptive rate limit state from response headers.
     adaptive_remaining: std::sync::atomic::AtomicU32,
@@ -60,6 +105,83 @@ impl HttpRpcClient {
             .build()
             .expect("failed to build reqwest client");
 
+        Self::from_parts(url, config, http)
+    }
+
+    /// Create a client with HTTP-level options (headers, auth, proxy, TLS).
+    pub fn with_options(
+        url: impl Into<String>,
+        mut config: HttpClientConfig,
+        opts: &HttpTransportOptions,
+    ) -> Result<Self, TransportError> {
+        use reqwest::header::{HeaderMap, HeaderName, HeaderValue, AUTHORIZATION};
+
+        let invalid = |what: &str, e: &dyn std::fmt::Display| {
+            TransportError::Other(format!("invalid {what}: {e}"))
+        };
+
+        if let Some(timeout) = opts.timeout {
+            config.request_timeout = timeout;
+        }
+        if let Some(max) = opts.max_request_bytes {
+            config.max_request_bytes = max;
+        }
+        if let Some(n) = opts.max_retries {
+            config.retry.max_retries = n;
+        }
+
+        let mut headers = HeaderMap::new();
+        for (k, v) in &opts.headers {
+            let name = HeaderName::from_bytes(k.as_bytes()).map_err(|e| invalid("header name", &e))?;
+            let mut value = HeaderValue::from_str(v).map_err(|e| invalid("header value", &e))?;
+            value.set_sensitive(true);
+            headers.insert(name, value);
+        }
+        if let Some(token) = &opts.bearer_token {
+            let mut value = HeaderValue::from_str(&format!("Bearer {token}"))
+                .map_err(|e| invalid("bearer token", &e))?;
+            value.set_sensitive(true);
+            headers.insert(AUTHORIZATION, value);
+        }
+
+        let mut builder = reqwest::Client::builder()
+            .timeout(config.request_timeout)
+            .default_headers(headers);
+        if let Some(n) = opts.pool_max_idle_per_host {
+            builder = builder.pool_max_idle_per_host(n);
+        }
+        if let Some(idle) = opts.pool_idle_timeout {
+            builder = builder.pool_idle_timeout(idle);
+        }
+        if let Some(interval) = opts.tcp_keepalive {
+            builder = builder.tcp_keepalive((!interval.is_zero()).then_some(interval));
+        }
+        if opts.http1_only {
+            builder = builder.http1_only();
+        }
+        if let Some(proxy) = &opts.proxy {
+            builder = builder.proxy(reqwest::Proxy::all(proxy).map_err(|e| invalid("proxy", &e))?);
+        }
+        if let Some(pem) = &opts.ca_cert_pem {
+            for cert in reqwest::Certificate::from_pem_bundle(pem.as_bytes())
+                .map_err(|e| invalid("CA certificate", &e))?
+            {
+                builder = builder.add_root_certificate(cert);
+            }
+        }
+        if let Some(pem) = &opts.client_identity_pem {
+            let identity = reqwest::Identity::from_pem(pem.as_bytes())
+                .map_err(|e| invalid("client identity", &e))?;
+            builder = builder.identity(identity);
+        }
+        let http = builder
+            .build()
+            .map_err(|e| TransportError::Http(e.to_string()))?;
+
+        Ok(Self::from_parts(url, config, http))
+    }
+
+    fn from_parts(url: impl Into<String>, config: HttpClientConfig, http: reqwest::Client) -> Self {
         Self {
             url: url.into(),
             http,
@@ -67,6 +189,7 @@ impl HttpRpcClient {
             circuit: CircuitBreaker::new(config.circuit_breaker),
             rate_limiter: RateLimiter::new(config.rate_limiter),
             request_timeout: config.request_timeout,
+            max_request_bytes: config.max_request_bytes,
             metrics: None,
             adaptive_remaining: std::sync::atomic::AtomicU32::new(u32::MAX),
         }
@@ -132,6 +255,85 @@ impl HttpRpcClient {
     }
 }
 
+enum BatchError {
+    /// The provider rejected the body with `413 Payload Too Large`.
+    TooLarge(TransportError),
+    Transport(TransportError),
+}
+
+impl HttpRpcClient {
+    async fn send_batch_body(&self, body: Vec<u8>) -> Result<Vec<JsonRpcResponse>, BatchError> {
+        let resp = self
+            .http
+            .post(&self.url)
+            .header(reqwest::header::CONTENT_TYPE, "application/json")
+            .body(body)
+            .send()
+            .await
+            .map_err(|e| BatchError::Transport(TransportError::Http(e.to_string())))?;
+
+        if !resp.status().is_success() {
+            let status = resp.status();
+            let body = resp.text().await.unwrap_or_default();
+            let err = TransportError::Http(format!("HTTP {}: {body}", status.as_u16()));
+            return Err(if status == reqwest::StatusCode::PAYLOAD_TOO_LARGE {
+                BatchError::TooLarge(err)
+            } else {
+                BatchError::Transport(err)
+            });
+        }
+
+        resp.json::<Vec<JsonRpcResponse>>()
+            .await
+            .map_err(|e| BatchError::Transport(TransportError::Http(e.to_string())))
+    }
+}
+
+/// Group consecutive encoded requests into chunks whose JSON array body
+/// stays within `max` bytes. A request larger than `max` on its own gets a
+/// chunk to itself. `max == 0` puts everything in one chunk.
+fn split_by_size(encoded: &[Vec<u8>], max: usize) -> Vec<Range<usize>> {
+    if max == 0 {
+        return vec![0..encoded.len()];
+    }
+    let mut chunks = Vec::new();
+    let mut start = 0;
+    let mut size = 2; // "[" and "]"
+    for (i, req) in encoded.iter().enumerate() {
+        let add = req.len() + usize::from(i > start); // "," separator
+        if i > start && size + add > max {
+            chunks.push(start..i);
+            start = i;
+            size = 2 + req.len();
+        } else {
+            size += add;
+        }
+    }
+    chunks.push(start..encoded.len());
+    chunks
+}
+
+/// Join encoded requests into a JSON array.
+fn batch_body(encoded: &[Vec<u8>]) -> Vec<u8> {
+    let len = encoded.iter().map(|r| r.len() + 1).sum::<usize>() + 1;
+    let mut body = Vec::with_capacity(len);
+    body.push(b'[');
+    for (i, req) in encoded.iter().enumerate() {
+        if i > 0 {
+            body.push(b',');
+        }
+        body.extend_from_slice(req);
+    }
+    body.push(b']');
+    body
+}
+
+/// Batch responses may arrive in any order; put them in request order.
+fn order_by_id(reqs: &[JsonRpcRequest], resps: &mut [JsonRpcResponse]) {
+    let pos = |id: &RpcId| reqs.iter().position(|r| &r.id == id).unwrap_or(usize::MAX);
+    resps.sort_by_key(|r| pos(&r.id));
+}
+
 #[async_trait]
 impl RpcTransport for HttpRpcClient {
     async fn send(&self, req: JsonRpcRequest) -> Result<JsonRpcResponse, TransportError> {
@@ -214,7 +416,10 @@ impl RpcTransport for HttpRpcClient {
         }
     }
 
-    /// True HTTP batch: send all requests as a JSON array in one HTTP call.
+    /// True HTTP batch: send the requests as JSON arrays, splitting them
+    /// into as few HTTP calls as fit `max_request_bytes`. A chunk the
+    /// provider still rejects with `413 Payload Too Large` is halved and
+    /// resent. Responses are returned in request order.
     async fn send_batch(
         &self,
         reqs: Vec<JsonRpcRequest>,
@@ -223,23 +428,29 @@ impl RpcTransport for HttpRpcClient {
             return Ok(vec![]);
         }
 
-        let resp = self
-            .http
-            .post(&self.url)
-            .json(&reqs)
-            .send()
-            .await
-            .map_err(|e| TransportError::Http(e.to_string()))?;
+        let encoded = reqs
+            .iter()
+            .map(serde_json::to_vec)
+            .collect::<Result<Vec<_>, _>>()?;
+        let mut pending: VecDeque<Range<usize>> =
+            split_by_size(&encoded, self.max_request_bytes).into();
+        let mut out = Vec::with_capacity(reqs.len());
 
-        if !resp.status().is_success() {
-            let status = resp.status().as_u16();
-            let body = resp.text().await.unwrap_or_default();
-            return Err(TransportError::Http(format!("HTTP {status}: {body}")));
+        while let Some(chunk) = pending.pop_front() {
+            match self.send_batch_body(batch_body(&encoded[chunk.clone()])).await {
+                Err(BatchError::TooLarge(_)) if chunk.len() > 1 => {
+                    let mid = chunk.start + chunk.len() / 2;
+                    pending.push_front(mid..chunk.end);
+                    pending.push_front(chunk.start..mid);
+                }
+                Err(BatchError::TooLarge(e)) | Err(BatchError::Transport(e)) => return Err(e),
+                Ok(mut resps) => {
+                    order_by_id(&reqs[chunk], &mut resps);
+                    out.extend(resps);
+                }
+            }
         }
-
-        resp.json::<Vec<JsonRpcResponse>>()
-            .await
-            .map_err(|e| TransportError::Http(e.to_string()))
+        Ok(out)
     }
 
     fn health(&self) -> HealthStatus {
@@ -254,3 +465,21 @@ impl RpcTransport for HttpRpcClient {
         &self.url
     }
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn split_by_size_respects_budget() {
+        let encoded = vec![vec![b'x'; 10]; 5];
+        // "[" + 10 + "," + 10 + "]" = 23 bytes fits two per chunk.
+        assert_eq!(split_by_size(&encoded, 23), vec![0..2, 2..4, 4..5]);
+        assert_eq!(split_by_size(&encoded, 0), vec![0..5]);
+        // An oversized request still gets sent, alone.
+        assert_eq!(split_by_size(&encoded, 5), vec![0..1, 1..2, 2..3, 3..4, 4..5]);
+        for chunk in split_by_size(&encoded, 23) {
+            assert!(batch_body(&encoded[chunk]).len() <= 23);
+        }
+    }
+}
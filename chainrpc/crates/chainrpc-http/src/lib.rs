@@ -9,7 +9,9 @@ use chainrpc_core::transport::RpcTransport;
 pub mod batch;
 pub mod client;
 
-pub use client::{HttpClientConfig, HttpRpcClient};
+pub use client::{
+    HttpClientConfig, HttpRpcClient, HttpTransportOptions, DEFAULT_MAX_REQUEST_BYTES,
+};
 
 /// Create a `ProviderPool` from a list of HTTP endpoint URLs.
 ///
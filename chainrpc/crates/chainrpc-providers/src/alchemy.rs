@@ -4,7 +4,7 @@
 //! <https://docs.alchemy.com/reference/throughput>
 
 use chainrpc_core::policy::{CircuitBreakerConfig, RateLimiterConfig, RetryConfig};
-use chainrpc_http::{HttpClientConfig, HttpRpcClient};
+use chainrpc_http::{HttpClientConfig, HttpRpcClient, DEFAULT_MAX_REQUEST_BYTES};
 use std::time::Duration;
 
 /// Alchemy compute unit rates (free tier = 300 CU/s).
@@ -49,6 +49,7 @@ pub fn http_client_with_cu(api_key: &str, chain_id: u64, cu_per_sec: f64) -> Htt
             refill_rate: cu_per_sec,
         },
         request_timeout: Duration::from_secs(30),
+        max_request_bytes: DEFAULT_MAX_REQUEST_BYTES,
     };
     HttpRpcClient::new(url, config)
 }
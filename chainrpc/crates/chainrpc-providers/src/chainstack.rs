@@ -15,7 +15,7 @@
 //! <https://docs.chainstack.com/docs/rps-plan-limits>
 
 use chainrpc_core::policy::{CircuitBreakerConfig, RateLimiterConfig, RetryConfig};
-use chainrpc_http::{HttpClientConfig, HttpRpcClient};
+use chainrpc_http::{HttpClientConfig, HttpRpcClient, DEFAULT_MAX_REQUEST_BYTES};
 use std::time::Duration;
 
 /// Requests per second by plan tier.
@@ -97,6 +97,7 @@ fn default_config(rps: f64) -> HttpClientConfig {
             refill_rate: rps,
         },
         request_timeout: Duration::from_secs(30),
+        max_request_bytes: DEFAULT_MAX_REQUEST_BYTES,
     }
 }
 
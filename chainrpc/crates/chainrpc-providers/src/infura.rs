@@ -1,7 +1,7 @@
 //! Infura provider profile.
 
 use chainrpc_core::policy::{CircuitBreakerConfig, RateLimiterConfig, RetryConfig};
-use chainrpc_http::{HttpClientConfig, HttpRpcClient};
+use chainrpc_http::{HttpClientConfig, HttpRpcClient, DEFAULT_MAX_REQUEST_BYTES};
 use std::time::Duration;
 
 /// Build an Infura HTTP client for the given network and project ID.
@@ -15,6 +15,7 @@ pub fn http_client(project_id: &str, chain_id: u64) -> HttpRpcClient {
             refill_rate: 10.0,
         },
         request_timeout: Duration::from_secs(30),
+        max_request_bytes: DEFAULT_MAX_REQUEST_BYTES,
     };
     HttpRpcClient::new(url, config)
 }
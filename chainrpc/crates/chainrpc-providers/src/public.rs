@@ -4,7 +4,7 @@
 //! Rate limits are lower and reliability may vary.
 
 use chainrpc_core::policy::{CircuitBreakerConfig, RateLimiterConfig, RetryConfig};
-use chainrpc_http::{HttpClientConfig, HttpRpcClient};
+use chainrpc_http::{HttpClientConfig, HttpRpcClient, DEFAULT_MAX_REQUEST_BYTES};
 use std::time::Duration;
 
 fn conservative_config() -> HttpClientConfig {
@@ -26,6 +26,7 @@ fn conservative_config() -> HttpClientConfig {
             refill_rate: 5.0,
         },
         request_timeout: Duration::from_secs(30),
+        max_request_bytes: DEFAULT_MAX_REQUEST_BYTES,
     }
 }
 
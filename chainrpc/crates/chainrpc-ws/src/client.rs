@@ -10,6 +10,10 @@ const WS_CONNECTING: u8 = 0;
 const WS_CONNECTED: u8 = 1;
 const WS_DISCONNECTED: u8 = 2;
 
+/// Request IDs used for internal re-subscribe calls, kept well clear of
+/// caller-chosen IDs.
+const RESUBSCRIBE_ID_BASE: u64 = 1 << 62;
+
 use async_trait::async_trait;
 use futures::{SinkExt, StreamExt};
 use serde_json::Value;
@@ -26,13 +30,46 @@ use crate::subscriptions::{SubscriptionId, SubscriptionManager};
 type PendingMap =
     Arc<Mutex<HashMap<u64, oneshot::Sender<Result<JsonRpcResponse, TransportError>>>>>;
 
+/// State of the underlying WebSocket connection.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ConnectionState {
+    /// Dialling (initially or after a disconnect).
+    Connecting,
+    /// Connected; subscriptions have been re-sent.
+    Connected,
+    /// The connection dropped or went silent; a reconnect is scheduled.
+    Disconnected,
+}
+
+/// Callback invoked on every connection state transition.
+pub type StateChangeCallback = Arc<dyn Fn(ConnectionState) + Send + Sync>;
+
 /// Configuration for the WebSocket client.
-#[derive(Debug, Clone)]
+#[derive(Clone)]
 pub struct WsClientConfig {
     /// Reconnect backoff starting duration.
     pub reconnect_initial: Duration,
     /// Maximum reconnect backoff.
     pub reconnect_max: Duration,
+    /// How often to send a WebSocket ping. `None` disables the heartbeat.
+    pub ping_interval: Option<Duration>,
+    /// How long to wait for any frame (pong or otherwise) after a ping
+    /// before treating the connection as dead and reconnecting.
+    pub pong_timeout: Duration,
+    /// Called on every connection state change (ConnectionStateChanged).
+    pub on_state_change: Option<StateChangeCallback>,
+}
+
+impl std::fmt::Debug for WsClientConfig {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        f.debug_struct("WsClientConfig")
+            .field("reconnect_initial", &self.reconnect_initial)
+            .field("reconnect_max", &self.reconnect_max)
+            .field("ping_interval", &self.ping_interval)
+            .field("pong_timeout", &self.pong_timeout)
+            .field("on_state_change", &self.on_state_change.is_some())
+            .finish()
+    }
 }
 
 impl Default for WsClientConfig {
@@ -40,6 +77,23 @@ impl Default for WsClientConfig {
         Self {
             reconnect_initial: Duration::from_millis(500),
             reconnect_max: Duration::from_secs(60),
+            ping_interval: Some(Duration::from_secs(30)),
+            pong_timeout: Duration::from_secs(10),
+            on_state_change: None,
+        }
+    }
+}
+
+/// Stores the new state and notifies the callback if it changed.
+fn set_state(conn_state: &AtomicU8, config: &WsClientConfig, state: ConnectionState) {
+    let raw = match state {
+        ConnectionState::Connecting => WS_CONNECTING,
+        ConnectionState::Connected => WS_CONNECTED,
+        ConnectionState::Disconnected => WS_DISCONNECTED,
+    };
+    if conn_state.swap(raw, Ordering::Relaxed) != raw {
+        if let Some(cb) = &config.on_state_change {
+            cb(state);
         }
     }
 }
@@ -92,6 +146,15 @@ impl WsRpcClient {
         })
     }
 
+    /// Current connection state.
+    pub fn state(&self) -> ConnectionState {
+        match self.conn_state.load(Ordering::Relaxed) {
+            WS_CONNECTED => ConnectionState::Connected,
+            WS_CONNECTING => ConnectionState::Connecting,
+            _ => ConnectionState::Disconnected,
+        }
+    }
+
     /// Subscribe to a WebSocket event stream.
     ///
     /// `kind` is the subscription type (e.g. `"newHeads"`, `"logs"`).
@@ -158,40 +221,52 @@ async fn ws_task(
 ) {
     let pending: PendingMap = Arc::new(Mutex::new(HashMap::new()));
     let mut backoff = config.reconnect_initial;
+    let mut resub_seq = RESUBSCRIBE_ID_BASE;
 
     loop {
         tracing::info!(url = %url, "connecting via WebSocket");
-        conn_state.store(WS_CONNECTING, Ordering::Relaxed);
+        set_state(&conn_state, &config, ConnectionState::Connecting);
 
         let conn = tokio_tungstenite::connect_async(&url).await;
 
         match conn {
             Err(e) => {
-                conn_state.store(WS_DISCONNECTED, Ordering::Relaxed);
+                set_state(&conn_state, &config, ConnectionState::Disconnected);
                 tracing::warn!(error = %e, "WS connect failed, retrying in {backoff:?}");
                 time::sleep(backoff).await;
                 backoff = (backoff * 2).min(config.reconnect_max);
                 continue;
             }
             Ok((ws_stream, _)) => {
-                conn_state.store(WS_CONNECTED, Ordering::Relaxed);
                 backoff = config.reconnect_initial; // reset on success
                 let (mut sink, mut stream) = ws_stream.split();
 
-                // Re-subscribe any active subscriptions
-                for (kind, params) in subscriptions.active_subscriptions() {
+                // Re-subscribe any active subscriptions. The node assigns
+                // new IDs; the responses are matched in handle_message and
+                // the subscriptions re-keyed so notifications keep flowing.
+                let mut resubscribing: HashMap<u64, SubscriptionId> = HashMap::new();
+                for (old_id, kind, params) in subscriptions.active_with_ids() {
+                    resub_seq += 1;
                     let resubscribe_req = serde_json::json!({
                         "jsonrpc": "2.0",
                         "method": "eth_subscribe",
                         "params": std::iter::once(Value::String(kind.clone()))
                             .chain(params)
                             .collect::<Vec<_>>(),
-                        "id": 0
+                        "id": resub_seq
                     });
                     if let Ok(msg) = serde_json::to_string(&resubscribe_req) {
-                        let _ = sink.send(Message::Text(msg)).await;
+                        if sink.send(Message::Text(msg)).await.is_ok() {
+                            resubscribing.insert(resub_seq, old_id);
+                        }
                     }
                 }
+                set_state(&conn_state, &config, ConnectionState::Connected);
+
+                // Heartbeat: ping every interval; if no frame at all arrives
+                // within pong_timeout of a ping the connection is half-open.
+                let mut next_ping = time::Instant::now() + config.ping_interval.unwrap_or_default();
+                let mut pong_deadline: Option<time::Instant> = None;
 
                 // Main dispatch loop
                 loop {
@@ -212,8 +287,23 @@ async fn ws_task(
                                 }
                             }
                         }
+                        _ = time::sleep_until(next_ping), if config.ping_interval.is_some() => {
+                            if sink.send(Message::Ping(Vec::new())).await.is_err() {
+                                break;
+                            }
+                            let now = time::Instant::now();
+                            pong_deadline.get_or_insert(now + config.pong_timeout);
+                            next_ping = now + config.ping_interval.unwrap_or_default();
+                        }
+                        _ = time::sleep_until(pong_deadline.unwrap_or(next_ping)), if pong_deadline.is_some() => {
+                            tracing::warn!(url = %url, "WS pong timeout, treating connection as dead");
+                            break;
+                        }
                         // Incoming messages from node
                         msg = stream.next() => {
+                            if let Some(Ok(_)) = &msg {
+                                pong_deadline = None; // any frame proves liveness
+                            }
                             match msg {
                                 None => break, // stream closed
                                 Some(Err(e)) => {
@@ -225,6 +315,7 @@ async fn ws_task(
                                         text.as_str(),
                                         &pending,
                                         &subscriptions,
+                                        &mut resubscribing,
                                     );
                                 }
                                 Some(Ok(Message::Close(_))) => break,
@@ -234,7 +325,12 @@ async fn ws_task(
                     }
                 }
 
-                conn_state.store(WS_DISCONNECTED, Ordering::Relaxed);
+                // Fail in-flight requests now rather than leaving callers
+                // waiting on a connection that is gone.
+                for (_, tx) in pending.lock().unwrap().drain() {
+                    let _ = tx.send(Err(TransportError::WebSocket("WS connection lost".into())));
+                }
+                set_state(&conn_state, &config, ConnectionState::Disconnected);
                 tracing::warn!(url = %url, "WS disconnected, reconnecting in {backoff:?}");
                 time::sleep(backoff).await;
                 backoff = (backoff * 2).min(config.reconnect_max);
@@ -243,7 +339,12 @@ async fn ws_task(
     }
 }
 
-fn handle_message(text: &str, pending: &PendingMap, subscriptions: &SubscriptionManager) {
+fn handle_message(
+    text: &str,
+    pending: &PendingMap,
+    subscriptions: &SubscriptionManager,
+    resubscribing: &mut HashMap<u64, SubscriptionId>,
+) {
     let Ok(val) = serde_json::from_str::<Value>(text) else {
         tracing::debug!("failed to parse WS message as JSON");
         return;
@@ -268,6 +369,15 @@ fn handle_message(text: &str, pending: &PendingMap, subscriptions: &Subscription
             RpcId::Number(n) => *n,
             _ => return,
         };
+        if let Some(old) = resubscribing.remove(&id) {
+            match resp.result.as_ref().and_then(|v| v.as_str()) {
+                Some(new) => {
+                    subscriptions.rekey(&old, SubscriptionId(new.to_string()));
+                }
+                None => tracing::warn!(subscription = %old, "WS re-subscribe failed"),
+            }
+            return;
+        }
         if let Some(tx) = pending.lock().unwrap().remove(&id) {
             let _ = tx.send(Ok(resp));
         }
@@ -5,9 +5,11 @@
 //! - Subscription management (eth_subscribe / eth_unsubscribe)
 //! - Auto-resubscribe after reconnect
 //! - Request multiplexing over a single connection
+//! - Ping/pong heartbeat that detects half-open connections
+//! - Connection state change callback
 
 pub mod client;
 pub mod subscriptions;
 
-pub use client::WsRpcClient;
+pub use client::{ConnectionState, StateChangeCallback, WsClientConfig, WsRpcClient};
 pub use subscriptions::{SubscriptionId, SubscriptionManager};
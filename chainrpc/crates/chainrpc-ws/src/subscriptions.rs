@@ -89,6 +89,31 @@ impl SubscriptionManager {
             .collect()
     }
 
+    /// Like [`active_subscriptions`](Self::active_subscriptions), with the
+    /// current ID of each subscription so it can be re-keyed once the node
+    /// assigns a new one.
+    pub fn active_with_ids(&self) -> Vec<(SubscriptionId, String, Vec<Value>)> {
+        self.entries
+            .lock()
+            .unwrap()
+            .iter()
+            .map(|(id, e)| (id.clone(), e.kind.clone(), e.params.clone()))
+            .collect()
+    }
+
+    /// Move a subscription to the ID the node returned when it was
+    /// re-subscribed. Returns `false` if `old` is no longer registered.
+    pub fn rekey(&self, old: &SubscriptionId, new: SubscriptionId) -> bool {
+        let mut entries = self.entries.lock().unwrap();
+        match entries.remove(old) {
+            Some(entry) => {
+                entries.insert(new, entry);
+                true
+            }
+            None => false,
+        }
+    }
+
     /// Number of active subscriptions.
     pub fn len(&self) -> usize {
         self.entries.lock().unwrap().len()
@@ -139,4 +164,17 @@ mod tests {
         let active = mgr.active_subscriptions();
         assert_eq!(active.len(), 2);
     }
+
+    #[test]
+    fn rekey_after_resubscribe() {
+        let mgr = SubscriptionManager::new();
+        let old = SubscriptionId("0xa".into());
+        let mut rx = mgr.register(old.clone(), "newHeads".into(), vec![]);
+        assert!(mgr.rekey(&old, SubscriptionId("0xc".into())));
+
+        mgr.dispatch(&old, serde_json::json!(1));
+        mgr.dispatch(&SubscriptionId("0xc".into()), serde_json::json!(2));
+        assert_eq!(rx.try_recv().unwrap(), serde_json::json!(2));
+        assert!(!mgr.rekey(&old, SubscriptionId("0xd".into())));
+    }
 }
@@ -54,6 +54,9 @@ async fn main() {
 
         // Request timeout: individual request must complete within 15 seconds.
         request_timeout: Duration::from_secs(15),
+
+        // Split batches so no request body exceeds 1 MiB.
+        max_request_bytes: 1 << 20,
     };
 
     // Create the client with the custom config.